@@ -267,6 +267,17 @@ func CacheMiddleware(cache *RedisCache, ttl time.Duration, skipPaths []string) e
 				return next(c)
 			}
 
+			// API key requests must reach the API key middleware to be metered
+			if c.Request().Header.Get("X-API-Key") != "" {
+				return next(c)
+			}
+
+			// Signed-in requests carry personal data and must reach JWTAuth,
+			// which runs after this middleware, to check the session
+			if c.Request().Header.Get(echo.HeaderAuthorization) != "" {
+				return next(c)
+			}
+
 			// Generate cache key
 			cacheKey := generateCacheKey(c)
 
@@ -348,10 +359,5 @@ func generateCacheKey(c echo.Context) string {
 		c.Request().URL.RawQuery,
 	)
 
-	// Add user context if available
-	if userID := c.Get("user_id"); userID != nil {
-		key += fmt.Sprintf(":user:%v", userID)
-	}
-
 	return key
 }
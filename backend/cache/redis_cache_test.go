@@ -3,9 +3,12 @@ package cache
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -376,6 +379,32 @@ func TestCacheMiddleware_HitMiss(t *testing.T) {
 	assert.Equal(t, value["data"], retrievedMap["data"])
 }
 
+func TestCacheMiddleware_SkipsSignedInRequests(t *testing.T) {
+	redisCache, err := NewRedisCache("localhost:6379", "", "test", 5*time.Minute)
+	if err != nil {
+		t.Skip("Redis not available, skipping test")
+	}
+	defer redisCache.Close()
+	require.NoError(t, redisCache.Clear(context.Background()))
+
+	e := echo.New()
+	calls := 0
+	e.GET("/api/v1/users/me/consents", func(c echo.Context) error {
+		calls++
+		return c.JSON(http.StatusOK, map[string]int{"calls": calls})
+	}, CacheMiddleware(redisCache, time.Minute, nil))
+
+	for _, user := range []string{"Bearer token-1", "Bearer token-2"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/consents", nil)
+		req.Header.Set(echo.HeaderAuthorization, user)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-Cache"))
+	}
+	assert.Equal(t, 2, calls)
+}
+
 func TestRedisCache_ConcurrentAccess(t *testing.T) {
 	redisCache, err := NewRedisCache("localhost:6379", "", "test", 5*time.Minute)
 	if err != nil {
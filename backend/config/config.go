@@ -16,6 +16,7 @@ type Config struct {
 	ReadTimeout       int
 	WriteTimeout      int
 	KeepAliveTimeout  int
+	DataDir           string // directory of the nutrition, disease, injury and medication JSON files
 	FileStorage       FileStorageConfig
 	EmailConfig       EmailConfig
 	PushConfig        PushConfig
@@ -29,6 +30,9 @@ type FileStorageConfig struct {
 	S3Bucket    string
 	S3Region    string
 	S3URL       string
+	S3AccessKey string
+	S3SecretKey string
+	SigningKey  string // signs short-lived file URLs; defaults to the JWT secret
 }
 
 // EmailConfig holds email service configuration
@@ -40,6 +44,9 @@ type EmailConfig struct {
 	SMTPPass   string
 	FromEmail  string
 	FromName   string
+	OutboxPath string // directory used by the "outbox" provider instead of sending mail
+	ResetURL   string // frontend page that receives password reset tokens
+	ErasureURL string // frontend page that receives account erasure tokens
 }
 
 // PushConfig holds push notification configuration
@@ -60,6 +67,7 @@ func LoadConfig() *Config {
 		ReadTimeout:      getEnvAsInt("READ_TIMEOUT", 30),
 		WriteTimeout:     getEnvAsInt("WRITE_TIMEOUT", 30),
 		KeepAliveTimeout: getEnvAsInt("KEEP_ALIVE_TIMEOUT", 60),
+		DataDir:          getEnv("NUTRITION_DATA_DIR", "../../nutrition data json"),
 		FileStorage: FileStorageConfig{
 			StorageType: getEnv("STORAGE_TYPE", "local"),
 			BasePath:    getEnv("FILE_STORAGE_PATH", "./uploads"),
@@ -67,15 +75,21 @@ func LoadConfig() *Config {
 			S3Bucket:    getEnv("S3_BUCKET", ""),
 			S3Region:    getEnv("S3_REGION", "us-east-1"),
 			S3URL:       getEnv("S3_URL", ""),
+			S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+			SigningKey:  getEnv("FILE_URL_SIGNING_KEY", ""),
 		},
 		EmailConfig: EmailConfig{
-			Provider:  getEnv("EMAIL_PROVIDER", "smtp"),
-			SMTPHost:  getEnv("SMTP_HOST", "localhost"),
-			SMTPPort:  getEnvAsInt("SMTP_PORT", 587),
-			SMTPUser:  getEnv("SMTP_USER", ""),
-			SMTPPass:  getEnv("SMTP_PASS", ""),
-			FromEmail: getEnv("FROM_EMAIL", "noreply@nutrition-platform.com"),
-			FromName:  getEnv("FROM_NAME", "Nutrition Platform"),
+			Provider:   getEnv("EMAIL_PROVIDER", "smtp"),
+			SMTPHost:   getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:   getEnvAsInt("SMTP_PORT", 587),
+			SMTPUser:   getEnv("SMTP_USER", ""),
+			SMTPPass:   getEnv("SMTP_PASS", ""),
+			FromEmail:  getEnv("FROM_EMAIL", "noreply@nutrition-platform.com"),
+			FromName:   getEnv("FROM_NAME", "Nutrition Platform"),
+			OutboxPath: getEnv("EMAIL_OUTBOX_PATH", "./data/outbox"),
+			ResetURL:   getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+			ErasureURL: getEnv("ACCOUNT_ERASURE_URL", "http://localhost:3000/confirm-account-deletion"),
 		},
 		PushConfig: PushConfig{
 			FCMServerKey: getEnv("FCM_SERVER_KEY", ""),
@@ -85,6 +99,10 @@ func LoadConfig() *Config {
 		},
 	}
 
+	if config.FileStorage.SigningKey == "" {
+		config.FileStorage.SigningKey = config.JWTSecret
+	}
+
 	// Validate required configuration
 	if config.JWTSecret == "your-secret-key-change-in-production" && config.Environment == "production" {
 		panic("JWT_SECRET must be set in production")
@@ -89,6 +89,7 @@ type AuthConfig struct {
 	LockoutDuration        time.Duration `json:"lockout_duration"`
 	SessionTimeout         time.Duration `json:"session_timeout"`
 	TwoFactorEnabled       bool          `json:"two_factor_enabled"`
+	AdminTwoFactorRequired bool          `json:"admin_two_factor_required"`
 }
 
 // EncryptionConfig holds encryption settings
@@ -269,6 +270,7 @@ func loadAuthConfig() AuthConfig {
 		LockoutDuration:        getDurationEnv("LOCKOUT_DURATION", 15*time.Minute),
 		SessionTimeout:         getDurationEnv("SESSION_TIMEOUT", 30*time.Minute),
 		TwoFactorEnabled:       getBoolEnv("TWO_FACTOR_ENABLED", false),
+		AdminTwoFactorRequired: getBoolEnv("ADMIN_TWO_FACTOR_REQUIRED", true),
 	}
 }
 
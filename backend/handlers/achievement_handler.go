@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AchievementHandler handles personal records and milestones
+type AchievementHandler struct {
+	recordService    *services.PersonalRecordService
+	milestoneService *services.MilestoneService
+}
+
+// NewAchievementHandler creates a new achievement handler
+func NewAchievementHandler(db *sql.DB) *AchievementHandler {
+	return &AchievementHandler{
+		recordService:    services.NewPersonalRecordService(db),
+		milestoneService: services.NewMilestoneService(db),
+	}
+}
+
+// ListPersonalRecords returns the user's personal records, newest first
+// GET /api/v1/progress/personal-records?exercise_id=&record_type=&page=1&limit=20
+func (h *AchievementHandler) ListPersonalRecords(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.ListPersonalRecordsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	records, err := h.recordService.ListRecords(c.Request().Context(), userID, req)
+	if err != nil {
+		return achievementError(c, err, "Failed to fetch personal records")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   records,
+	})
+}
+
+// GetPersonalRecordStats returns the current bests per exercise and the
+// records of the last 30 days
+// GET /api/v1/progress/personal-records/stats
+func (h *AchievementHandler) GetPersonalRecordStats(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	stats, err := h.recordService.Stats(c.Request().Context(), userID)
+	if err != nil {
+		return achievementError(c, err, "Failed to fetch personal record stats")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   stats,
+	})
+}
+
+// DeletePersonalRecord removes a personal record
+// DELETE /api/v1/progress/personal-records/:id
+func (h *AchievementHandler) DeletePersonalRecord(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid personal record ID",
+		})
+	}
+
+	if err := h.recordService.DeleteRecord(c.Request().Context(), userID, uint(id)); err != nil {
+		return achievementError(c, err, "Failed to delete personal record")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Personal record deleted successfully",
+	})
+}
+
+// GetMilestones returns the reached milestones and the progress towards the
+// others
+// GET /api/v1/progress/milestones
+func (h *AchievementHandler) GetMilestones(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	stats, err := h.milestoneService.Stats(c.Request().Context(), userID)
+	if err != nil {
+		return achievementError(c, err, "Failed to fetch milestones")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   stats,
+	})
+}
+
+// GetAchievements returns achievement analytics
+// GET /api/v1/progress/achievements
+func (h *AchievementHandler) GetAchievements(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	analytics, err := h.milestoneService.Analytics(c.Request().Context(), userID)
+	if err != nil {
+		return achievementError(c, err, "Failed to fetch achievements")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   analytics,
+	})
+}
+
+func achievementError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrPersonalRecordNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
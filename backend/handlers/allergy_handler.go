@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AllergyHandler handles user allergies and allergen checks
+type AllergyHandler struct {
+	allergyService *services.AllergyService
+}
+
+// NewAllergyHandler creates a new allergy handler
+func NewAllergyHandler(allergyService *services.AllergyService) *AllergyHandler {
+	return &AllergyHandler{
+		allergyService: allergyService,
+	}
+}
+
+// ListAllergens returns the allergens of the registry with the regulations
+// listing them
+// GET /api/v1/allergies/allergens
+func (h *AllergyHandler) ListAllergens(c echo.Context) error {
+	registry := h.allergyService.Registry()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"version":   registry.Version(),
+			"allergens": registry.Allergens(),
+		},
+	})
+}
+
+// GetAllergies returns the allergens the user must avoid
+// GET /api/v1/allergies
+func (h *AllergyHandler) GetAllergies(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	allergies, err := h.allergyService.Allergies(c.Request().Context(), userID)
+	if err != nil {
+		return allergyError(c, err, "Failed to fetch allergies")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   models.UserAllergies{UserID: userID, Allergens: allergies},
+	})
+}
+
+// UpdateAllergies replaces the user's allergies
+// PUT /api/v1/allergies
+func (h *AllergyHandler) UpdateAllergies(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.UpdateAllergiesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	allergies, err := h.allergyService.UpdateAllergies(c.Request().Context(), userID, req.Allergens)
+	if err != nil {
+		return allergyError(c, err, "Failed to update allergies")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Allergies updated successfully",
+		"data":    allergies,
+	})
+}
+
+// CheckIngredients checks ingredients against the user's allergies
+// POST /api/v1/allergies/check
+func (h *AllergyHandler) CheckIngredients(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.AllergenCheckRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	check, err := h.allergyService.Check(c.Request().Context(), userID, req.Ingredients)
+	if err != nil {
+		return allergyError(c, err, "Failed to check allergens")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"check":     check,
+			"detection": h.allergyService.Registry().Detect(req.Ingredients),
+		},
+	})
+}
+
+func allergyError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAllergen):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AnswerHandler handles free-text nutrition questions
+type AnswerHandler struct {
+	answerPipeline *services.AnswerPipeline
+}
+
+func NewAnswerHandler(answerPipeline *services.AnswerPipeline) *AnswerHandler {
+	return &AnswerHandler{
+		answerPipeline: answerPipeline,
+	}
+}
+
+// GenerateAnswer classifies the question, extracts the foods, drugs and
+// conditions it mentions and answers with cited source records
+// POST /api/v1/nutrition-data/generate-answer
+func (h *AnswerHandler) GenerateAnswer(c echo.Context) error {
+	var req models.AnswerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	answer, err := h.answerPipeline.Answer(c.Request().Context(), req)
+	if err != nil {
+		return answerError(c, err, "Failed to generate answer")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   answer,
+	})
+}
+
+func answerError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAnswerRequest):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrAnswerUnavailable):
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Answer generation is temporarily unavailable",
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
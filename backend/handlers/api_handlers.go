@@ -5,6 +5,9 @@ import (
 	"strconv"
 	"time"
 
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -30,8 +33,22 @@ type APIMeta struct {
 	TotalPages    int    `json:"total_pages,omitempty"`
 }
 
+// MealsAPIHandler serves the meals API to partners with an API key and to
+// signed-in users of the app
+type MealsAPIHandler struct {
+	halalService *services.HalalService
+}
+
+// NewMealsAPIHandler creates a new meals API handler. Created and updated
+// meals are checked by halalService with their owner's preferences.
+func NewMealsAPIHandler(halalService *services.HalalService) *MealsAPIHandler {
+	return &MealsAPIHandler{
+		halalService: halalService,
+	}
+}
+
 // GetMealsAPI returns meals data for external API consumers
-func GetMealsAPI(c echo.Context) error {
+func (h *MealsAPIHandler) GetMealsAPI(c echo.Context) error {
 	// Validate API key access
 	if err := validateNutritionAccess(c, "read"); err != nil {
 		return err
@@ -155,7 +172,7 @@ func GetMealsAPI(c echo.Context) error {
 }
 
 // CreateMealAPI creates a new meal via API
-func CreateMealAPI(c echo.Context) error {
+func (h *MealsAPIHandler) CreateMealAPI(c echo.Context) error {
 	// Validate API key access
 	if err := validateNutritionAccess(c, "write"); err != nil {
 		return err
@@ -182,6 +199,10 @@ func CreateMealAPI(c echo.Context) error {
 	mealData["created_by_api"] = true
 	mealData["api_key_id"] = c.Get("api_key_id")
 
+	if err := h.checkHalal(c, mealData); err != nil {
+		return err
+	}
+
 	// In production, this would save to database
 	// For now, return the created meal data
 
@@ -200,7 +221,7 @@ func CreateMealAPI(c echo.Context) error {
 }
 
 // GetMealAPI retrieves a specific meal by ID
-func GetMealAPI(c echo.Context) error {
+func (h *MealsAPIHandler) GetMealAPI(c echo.Context) error {
 	// Validate API key access
 	if err := validateNutritionAccess(c, "read"); err != nil {
 		return err
@@ -270,7 +291,7 @@ func GetMealAPI(c echo.Context) error {
 }
 
 // UpdateMealAPI updates an existing meal
-func UpdateMealAPI(c echo.Context) error {
+func (h *MealsAPIHandler) UpdateMealAPI(c echo.Context) error {
 	// Validate API key access
 	if err := validateNutritionAccess(c, "write"); err != nil {
 		return err
@@ -292,6 +313,10 @@ func UpdateMealAPI(c echo.Context) error {
 	updateData["updated_by_api"] = true
 	updateData["api_key_id"] = c.Get("api_key_id")
 
+	if err := h.checkHalal(c, updateData); err != nil {
+		return err
+	}
+
 	// In production, this would update the database record
 	// For now, return the updated data
 	updateData["id"] = mealID
@@ -311,7 +336,7 @@ func UpdateMealAPI(c echo.Context) error {
 }
 
 // DeleteMealAPI deletes a meal
-func DeleteMealAPI(c echo.Context) error {
+func (h *MealsAPIHandler) DeleteMealAPI(c echo.Context) error {
 	// Validate API key access
 	if err := validateNutritionAccess(c, "write"); err != nil {
 		return err
@@ -341,6 +366,50 @@ func DeleteMealAPI(c echo.Context) error {
 
 // Helper functions
 
+// checkHalal sets is_halal and halal_compliance on mealData from a check of
+// its ingredients with the owner's halal preferences. The owner is the
+// signed-in user or the API key's user. Substitutions the owner opted into
+// replace the ingredients.
+func (h *MealsAPIHandler) checkHalal(c echo.Context, mealData map[string]interface{}) error {
+	if h.halalService == nil {
+		return nil
+	}
+
+	meal := &services.Meal{
+		UserID:      nutritionAccessUserID(c),
+		Ingredients: mealIngredientNames(mealData["ingredients"]),
+	}
+	result, err := h.halalService.CheckMeal(c.Request().Context(), meal)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check halal compliance")
+	}
+
+	if result.SubstitutionsApplied {
+		mealData["ingredients"] = meal.Ingredients
+	}
+	mealData["is_halal"] = meal.IsHalal
+	mealData["halal_compliance"] = result
+	return nil
+}
+
+// mealIngredientNames reads ingredients sent either as names or as objects
+// with a name
+func mealIngredientNames(value interface{}) []string {
+	items, _ := value.([]interface{})
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		switch ingredient := item.(type) {
+		case string:
+			names = append(names, ingredient)
+		case map[string]interface{}:
+			if name, ok := ingredient["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
 // filterMealsByAPI applies filters to meals data for API responses
 func filterMealsByAPI(meals []map[string]interface{}, category, cuisine, dietary string) []map[string]interface{} {
 	filtered := make([]map[string]interface{}, 0)
@@ -404,31 +473,33 @@ func generateID() string {
 	return strconv.FormatInt(time.Now().UnixNano(), 36)
 }
 
-// validateNutritionAccess validates API key access for nutrition endpoints
+// validateNutritionAccess checks that the request was authenticated either by
+// middleware.APIKeyAuth, which has already enforced the key's scopes for the
+// endpoint and method, or by JWTAuth for a signed-in user of the app. Only
+// read-write keys may change meals.
 func validateNutritionAccess(c echo.Context, permission string) error {
-	// Get API key from header
-	apiKey := c.Request().Header.Get("X-API-Key")
-	if apiKey == "" {
-		apiKey = c.QueryParam("api_key")
-	}
-
-	if apiKey == "" {
-		return echo.NewHTTPError(http.StatusUnauthorized, "API key required")
+	if apiKey, ok := c.Get("api_key").(*models.APIKey); ok && apiKey != nil {
+		if permission != "read" && !apiKey.HasScope(models.ScopeReadWrite) {
+			return echo.NewHTTPError(http.StatusForbidden, "API key is read-only")
+		}
+		return nil
 	}
 
-	// In production, validate API key against database
-	// For now, accept any non-empty key for demo purposes
-	if apiKey != "demo-nutrition-key" && apiKey != "prod-nutrition-key" {
-		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid API key")
+	if userID, _ := c.Get("user_id").(string); userID != "" {
+		return nil
 	}
 
-	// Set API key info in context
-	c.Set("api_key_id", "key_12345")
-	c.Set("rate_limit", 1000)
-	c.Set("rate_remaining", 999)
-	c.Set("rate_reset", time.Now().Add(time.Hour).Unix())
+	return echo.NewHTTPError(http.StatusUnauthorized, "API key or sign-in required")
+}
 
-	return nil
+// nutritionAccessUserID returns the user a request passing
+// validateNutritionAccess acts for
+func nutritionAccessUserID(c echo.Context) string {
+	if apiKey, ok := c.Get("api_key").(*models.APIKey); ok && apiKey != nil {
+		return apiKey.UserID
+	}
+	userID, _ := c.Get("user_id").(string)
+	return userID
 }
 
 // contains checks if a string slice contains a specific string
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	customMiddleware "nutrition-platform/middleware"
+	"nutrition-platform/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMealsAPI_SignedInUserRoutes(t *testing.T) {
+	e := echo.New()
+	nutritionAPI := e.Group("/api/v1/nutrition")
+	nutritionAPI.Use(customMiddleware.JWTAuth())
+	h := NewMealsAPIHandler(nil)
+	nutritionAPI.GET("/meals", h.GetMealsAPI)
+	nutritionAPI.POST("/meals", h.CreateMealAPI)
+
+	token, err := customMiddleware.GenerateToken("user-1", "user@example.com", models.RoleUser, false)
+	require.NoError(t, err)
+	serve := func(method, body, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/nutrition/meals", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "", "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "", "Bearer "+token).Code)
+
+	meal := `{"name":"Lentil soup","description":"Red lentils","category":"soup","prep_time":10,"cook_time":30,"servings":4}`
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, meal, "Bearer "+token).Code)
+}
+
+func TestMealsAPI_CreateMealChecksHalal(t *testing.T) {
+	halal, _, userID := newTestHalalService(t)
+	h := NewMealsAPIHandler(halal)
+	e := echo.New()
+	serve := func(body string) map[string]interface{} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/nutrition/meals", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c := e.NewContext(req, rec)
+		c.Set("user_id", userID)
+		require.NoError(t, h.CreateMealAPI(c))
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var response APIResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response.Data.(map[string]interface{})
+	}
+	meal := `{"name":"Fry-up","description":"Bacon and eggs","category":"breakfast","prep_time":5,"cook_time":10,"servings":1,
+		"ingredients":[{"name":"200g bacon"},"2 eggs"]}`
+
+	data := serve(meal)
+	assert.Equal(t, false, data["is_halal"])
+	compliance := data["halal_compliance"].(map[string]interface{})
+	assert.NotEmpty(t, compliance["violations"])
+
+	// Substitutions the user opted into replace the ingredients
+	autoSubstitute := true
+	_, err := halal.UpdatePreferences(context.Background(), userID, models.UpdateHalalPreferencesRequest{AutoSubstitute: &autoSubstitute})
+	require.NoError(t, err)
+	data = serve(meal)
+	assert.Equal(t, true, data["is_halal"])
+	assert.Equal(t, []interface{}{"200g turkey bacon", "2 eggs"}, data["ingredients"])
+}
+
+func TestValidateNutritionAccess_APIKeyScopes(t *testing.T) {
+	e := echo.New()
+	newContext := func(apiKey *models.APIKey) echo.Context {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/meals", nil), httptest.NewRecorder())
+		c.Set("api_key", apiKey)
+		return c
+	}
+
+	readOnly := &models.APIKey{Scopes: []models.APIKeyScope{models.ScopeMeals, models.ScopeReadOnly}}
+	assert.NoError(t, validateNutritionAccess(newContext(readOnly), "read"))
+	err := validateNutritionAccess(newContext(readOnly), "write")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+
+	readWrite := &models.APIKey{Scopes: []models.APIKeyScope{models.ScopeMeals, models.ScopeReadWrite}}
+	assert.NoError(t, validateNutritionAccess(newContext(readWrite), "write"))
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+
+	"nutrition-platform/models"
 	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
@@ -9,57 +14,269 @@ import (
 
 // APIKeyHandler handles API key-related requests
 type APIKeyHandler struct {
-	apiKeyService *services.APIKeyService
+	apiKeyService    *services.APIKeyService
+	analyticsService *services.AnalyticsService
 }
 
 // NewAPIKeyHandler creates a new APIKeyHandler instance
-func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService, analyticsService *services.AnalyticsService) *APIKeyHandler {
 	return &APIKeyHandler{
-		apiKeyService: apiKeyService,
+		apiKeyService:    apiKeyService,
+		analyticsService: analyticsService,
 	}
 }
 
-// Stub implementations
+// GetAPIKeys lists the caller's API keys. Plaintext keys are never included.
 func (h *APIKeyHandler) GetAPIKeys(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "GetAPIKeys - stub implementation",
-	})
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request().Context(), userID, page, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch API keys",
+		})
+	}
+
+	return c.JSON(http.StatusOK, keys)
 }
 
+// CreateAPIKey issues a new API key and returns the plaintext key once
 func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"message": "CreateAPIKey - stub implementation",
-	})
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.RateLimit == 0 {
+		req.RateLimit = 100
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	resp, err := h.apiKeyService.CreateAPIKey(c.Request().Context(), userID, req)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to create API key")
+	}
+
+	return c.JSON(http.StatusCreated, resp)
 }
 
+// GetAPIKey returns one of the caller's API keys
 func (h *APIKeyHandler) GetAPIKey(c echo.Context) error {
-	id := c.Param("id")
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "GetAPIKey - stub implementation",
-		"id":      id,
-	})
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	apiKey, err := h.apiKeyService.GetAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to fetch API key")
+	}
+
+	return c.JSON(http.StatusOK, apiKey)
 }
 
+// UpdateAPIKey changes the name, scopes, rate limit or metadata of a key
 func (h *APIKeyHandler) UpdateAPIKey(c echo.Context) error {
-	id := c.Param("id")
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "UpdateAPIKey - stub implementation",
-		"id":      id,
-	})
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.UpdateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	apiKey, err := h.apiKeyService.UpdateAPIKey(c.Request().Context(), userID, c.Param("id"), req)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to update API key")
+	}
+
+	return c.JSON(http.StatusOK, apiKey)
 }
 
+// DeleteAPIKey revokes an API key. Requests made with it are rejected from now on.
 func (h *APIKeyHandler) DeleteAPIKey(c echo.Context) error {
-	id := c.Param("id")
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "DeleteAPIKey - stub implementation",
-		"id":      id,
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return apiKeyError(c, err, "Failed to revoke API key")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "API key revoked",
 	})
 }
 
+// RegenerateAPIKey rotates the secret of a key and returns the new plaintext key once
 func (h *APIKeyHandler) RegenerateAPIKey(c echo.Context) error {
-	id := c.Param("id")
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	resp, err := h.apiKeyService.RotateAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to rotate API key")
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetUsageStatement returns the monthly usage statement of one of the
+// caller's keys. ?month=YYYY-MM selects the month (default: current) and
+// ?format=csv returns CSV instead of JSON.
+func (h *APIKeyHandler) GetUsageStatement(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	apiKey, err := h.apiKeyService.GetAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to fetch API key")
+	}
+
+	return h.writeUsageStatement(c, apiKey)
+}
+
+// AdminGetUsageStatement returns the monthly usage statement of any key, for invoicing
+func (h *APIKeyHandler) AdminGetUsageStatement(c echo.Context) error {
+	apiKey, err := h.apiKeyService.GetAPIKeyByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to fetch API key")
+	}
+
+	return h.writeUsageStatement(c, apiKey)
+}
+
+// UpdateAPIKeyTier moves a key to another plan tier
+func (h *APIKeyHandler) UpdateAPIKeyTier(c echo.Context) error {
+	var req models.UpdateAPIKeyTierRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	apiKey, err := h.apiKeyService.SetAPIKeyTier(c.Request().Context(), c.Param("id"), req.Tier)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to update API key tier")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "RegenerateAPIKey - stub implementation",
-		"id":      id,
+		"api_key": apiKey,
+		"quota":   apiKey.Quota(),
 	})
 }
+
+func (h *APIKeyHandler) writeUsageStatement(c echo.Context, apiKey *models.APIKey) error {
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "csv" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "format must be json or csv",
+		})
+	}
+
+	month := time.Now().UTC()
+	if value := c.QueryParam("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "month must be formatted as YYYY-MM",
+			})
+		}
+		month = parsed
+	}
+
+	statement, err := h.analyticsService.GetMonthlyStatement(apiKey, month)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to build usage statement",
+		})
+	}
+
+	if format == "csv" {
+		filename := "usage-" + statement.APIKeyID + "-" + statement.Period + ".csv"
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+		c.Response().WriteHeader(http.StatusOK)
+		return statement.WriteCSV(c.Response())
+	}
+
+	return c.JSON(http.StatusOK, statement)
+}
+
+func apiKeyError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "API key not found",
+		})
+	case errors.Is(err, services.ErrAPIKeyInactive):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "API key has been revoked",
+		})
+	case errors.Is(err, services.ErrInvalidScopes), errors.Is(err, services.ErrInvalidTier):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrAdminScopeForbidden):
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
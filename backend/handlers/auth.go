@@ -1,11 +1,12 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"strings"
 
 	"nutrition-platform/middleware"
+	"nutrition-platform/models"
 	"nutrition-platform/security"
 	"nutrition-platform/services"
 
@@ -74,39 +75,27 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation - database operations will be added in Priority 2
-	fmt.Printf("Register user: %s %s (%s)\n", req.FirstName, req.LastName, req.Email)
-	
-	// Create stub user response
-	user := map[string]interface{}{
-		"id":         "stub-user-id",
-		"email":      req.Email,
-		"first_name": req.FirstName,
-		"last_name":  req.LastName,
-		"password":   "", // Never return password
-	}
-
-	// Generate tokens (stub)
-	accessToken, err := middleware.GenerateToken("stub-user-id", req.Email, "user", false)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate access token",
-		})
-	}
-
-	refreshToken, err := middleware.GenerateRefreshToken("stub-user-id")
+	user, err := h.userService.CreateUser(c.Request().Context(), services.CreateUserInput{
+		Email:       req.Email,
+		Password:    req.Password,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		DateOfBirth: req.DateOfBirth,
+		Gender:      req.Gender,
+		Language:    req.Language,
+	})
 	if err != nil {
+		if errors.Is(err, services.ErrEmailAlreadyRegistered) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "An account with this email already exists",
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate refresh token",
+			"error": "Failed to create account",
 		})
 	}
 
-	return c.JSON(http.StatusCreated, AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         user,
-		ExpiresIn:    15 * 60, // 15 minutes in seconds
-	})
+	return h.issueTokens(c, http.StatusCreated, user, false)
 }
 
 // Login handles user authentication
@@ -125,38 +114,62 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation
-	fmt.Printf("Login user: %s\n", req.Email)
-	
-	// Create stub user response
-	user := map[string]interface{}{
-		"id":         "stub-user-id",
-		"email":      req.Email,
-		"first_name": "Stub",
-		"last_name":  "User",
-		"password":   "", // Never return password
+	user, err := h.userService.Authenticate(c.Request().Context(), req.Email, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid email or password",
+			})
+		case errors.Is(err, services.ErrAccountLocked):
+			return c.JSON(http.StatusLocked, map[string]string{
+				"error": "Account temporarily locked due to too many failed login attempts",
+			})
+		case errors.Is(err, services.ErrAccountDisabled):
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Account is disabled",
+			})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to authenticate",
+			})
+		}
+	}
+
+	if user.TwoFactorEnabled {
+		return h.issueTwoFactorChallenge(c, user)
 	}
 
-	// Generate tokens (stub)
-	accessToken, err := middleware.GenerateToken("stub-user-id", req.Email, "user", false)
+	return h.issueTokens(c, http.StatusOK, user, false)
+}
+
+// issueTokens starts a new session for the account and returns its token pair.
+// twoFactor records whether the login completed a second factor.
+func (h *AuthHandler) issueTokens(c echo.Context, status int, user *models.UserAccount, twoFactor bool) error {
+	session, refreshToken, err := h.userService.CreateSession(c.Request().Context(), user.ID, services.SessionClient{
+		DeviceInfo: c.Request().Header.Get("X-Device-Info"),
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+		TwoFactor:  twoFactor,
+	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate access token",
+			"error": "Failed to create session",
 		})
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken("stub-user-id")
+	accessToken, err := middleware.GenerateSessionToken(user.ID, user.Email, user.Role, user.IsAdmin(), session.ID, session.TwoFactor)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate refresh token",
+			"error": "Failed to generate access token",
 		})
 	}
 
-	return c.JSON(http.StatusOK, AuthResponse{
+	return c.JSON(status, AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User:         user,
-		ExpiresIn:    15 * 60, // 15 minutes in seconds
+		ExpiresIn:    int64(middleware.AccessTokenTTL.Seconds()),
 	})
 }
 
@@ -178,16 +191,27 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation - just log the logout
-	accessToken := tokenParts[1]
-	fmt.Printf("Logout user with token: %s...\n", accessToken[:10]+"...")
+	userID, _ := c.Get("user_id").(string)
+	sessionID, _ := c.Get("session_id").(string)
+	if userID == "" || sessionID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	err := h.userService.RevokeSession(c.Request().Context(), userID, sessionID, services.SessionRevokedLogout)
+	if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to logout",
+		})
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Logged out successfully",
 	})
 }
 
-// RefreshToken handles token refresh
+// RefreshToken rotates a refresh token and issues a new access token for its session
 func (h *AuthHandler) RefreshToken(c echo.Context) error {
 	var req RefreshTokenRequest
 	if err := c.Bind(&req); err != nil {
@@ -196,50 +220,75 @@ func (h *AuthHandler) RefreshToken(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation
-	fmt.Printf("Refresh token: %s...\n", req.RefreshToken[:10]+"...")
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
 
-	// Generate new tokens (stub)
-	newAccessToken, err := middleware.GenerateToken("stub-user-id", "refreshed@example.com", "user", false)
+	ctx := c.Request().Context()
+	session, newRefreshToken, err := h.userService.RotateRefreshToken(ctx, req.RefreshToken)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate access token",
-		})
+		switch {
+		case errors.Is(err, services.ErrInvalidRefreshToken):
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid or expired refresh token",
+			})
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Refresh token has already been used; the session has been revoked",
+			})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to refresh token",
+			})
+		}
 	}
 
-	newRefreshToken, err := middleware.GenerateRefreshToken("stub-user-id")
+	user, err := h.userService.GetUserByID(ctx, session.UserID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate refresh token",
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid or expired refresh token",
+		})
+	}
+	if !user.IsActive {
+		_ = h.userService.RevokeSession(ctx, user.ID, session.ID, services.SessionRevokedLogout)
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Account is disabled",
 		})
 	}
 
-	// Create stub user response
-	user := map[string]interface{}{
-		"id":         "stub-user-id",
-		"email":      "refreshed@example.com",
-		"first_name": "Refreshed",
-		"last_name":  "User",
+	newAccessToken, err := middleware.GenerateSessionToken(user.ID, user.Email, user.Role, user.IsAdmin(), session.ID, session.TwoFactor)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate access token",
+		})
 	}
 
 	return c.JSON(http.StatusOK, AuthResponse{
 		AccessToken:  newAccessToken,
 		RefreshToken: newRefreshToken,
 		User:         user,
-		ExpiresIn:    15 * 60, // 15 minutes in seconds
+		ExpiresIn:    int64(middleware.AccessTokenTTL.Seconds()),
 	})
 }
 
 // LogoutAll handles logout from all devices
-func LogoutAll(c echo.Context) error {
+func (h *AuthHandler) LogoutAll(c echo.Context) error {
 	// Get user from context (set by auth middleware)
-	userID := c.Get("user_id")
-	if userID == nil {
-		userID = "stub-user-id"
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
 	}
 
-	// Stub implementation
-	fmt.Printf("LogoutAll user: %v\n", userID)
+	if err := h.userService.RevokeAllUserSessions(c.Request().Context(), userID, services.SessionRevokedLogoutAll); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to logout from all devices",
+		})
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Logged out from all devices successfully",
@@ -256,14 +305,16 @@ func (h *AuthHandler) GetProfile(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation - in production, fetch from database
-	user := map[string]interface{}{
-		"id":         userID,
-		"email":      "user@example.com",
-		"first_name": "User",
-		"last_name":  "Name",
-		"role":       "user",
-		"created_at": "2024-01-01T00:00:00Z",
+	user, err := h.userService.GetUserByID(c.Request().Context(), userID.(string))
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "User not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch profile",
+		})
 	}
 
 	return c.JSON(http.StatusOK, user)
@@ -274,17 +325,66 @@ func (h *AuthHandler) GetMe(c echo.Context) error {
 	return h.GetProfile(c)
 }
 
+// sessionResponse is a session as listed to its owner
+type sessionResponse struct {
+	services.Session
+	Current bool `json:"current"`
+}
+
 // GetSessions returns the current user's active sessions
 func (h *AuthHandler) GetSessions(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	sessions, err := h.userService.GetUserSessions(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch sessions",
+		})
+	}
+
+	currentID, _ := c.Get("session_id").(string)
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			Session: session,
+			Current: session.ID == currentID,
+		})
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "GetSessions - stub implementation",
+		"sessions": response,
+		"total":    len(response),
 	})
 }
 
 // DeleteSession handles invalidating a specific session
 func (h *AuthHandler) DeleteSession(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "DeleteSession - stub implementation",
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	err := h.userService.RevokeSession(c.Request().Context(), userID, c.Param("id"), services.SessionRevokedByUser)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Session not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to revoke session",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Session revoked successfully",
 	})
 }
 
@@ -348,14 +448,11 @@ func (h *AuthHandler) ForgotPassword(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation - in production:
-	// 1. Check if user exists
-	// 2. Generate reset token
-	// 3. Store token with expiration
-	// 4. Send email with reset link
-	// 5. Return success (don't reveal if email exists)
-
-	fmt.Printf("Forgot password request for: %s\n", req.Email)
+	if err := h.userService.RequestPasswordReset(c.Request().Context(), req.Email); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to process password reset request",
+		})
+	}
 
 	// Always return success to prevent email enumeration
 	return c.JSON(http.StatusOK, map[string]string{
@@ -382,20 +479,14 @@ func (h *AuthHandler) ResetPassword(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation - in production:
-	// 1. Validate reset token
-	// 2. Check token expiration
-	// 3. Hash new password
-	// 4. Update user password
-	// 5. Invalidate reset token
-	// 6. Invalidate all user sessions
-
-	fmt.Printf("Reset password with token: %s...\n", req.Token[:10]+"...")
-
-	// Check if token is valid (stub - always valid for testing)
-	if len(req.Token) < 10 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid or expired reset token",
+	if err := h.userService.ResetPassword(c.Request().Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid or expired reset token",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to reset password",
 		})
 	}
 
@@ -1,13 +1,10 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
 
+	"nutrition-platform/services"
 	"nutrition-platform/utils"
 
 	"github.com/labstack/echo/v4"
@@ -15,13 +12,13 @@ import (
 
 // DiseaseHandler handles disease nutrition data API requests
 type DiseaseHandler struct {
-	dataDir string
+	knowledgeBase *services.KnowledgeBase
 }
 
 // NewDiseaseHandler creates a new disease handler
-func NewDiseaseHandler(dataDir string) *DiseaseHandler {
+func NewDiseaseHandler(knowledgeBase *services.KnowledgeBase) *DiseaseHandler {
 	return &DiseaseHandler{
-		dataDir: dataDir,
+		knowledgeBase: knowledgeBase,
 	}
 }
 
@@ -30,7 +27,6 @@ func (h *DiseaseHandler) GetDiseases(c echo.Context) error {
 	// Parse query parameters
 	page := 1
 	limit := 20
-	search := ""
 
 	if pageStr := c.QueryParam("page"); pageStr != "" {
 		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
@@ -44,97 +40,22 @@ func (h *DiseaseHandler) GetDiseases(c echo.Context) error {
 		}
 	}
 
-	if search = c.QueryParam("search"); search != "" {
-		search = strings.ToLower(search)
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeDiseases) {
+		return knowledgeUnavailable(c, "Disease")
 	}
 
-	// Read disease directory
-	diseasesDir := filepath.Join(h.dataDir, "../disease-nutrition-easy-json-files")
-	files, err := os.ReadDir(diseasesDir)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "Failed to read diseases directory",
-			"message": err.Error(),
-		})
-	}
-
-	// Process disease files
-	var diseases []map[string]interface{}
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".json") {
-			continue
-		}
-
-		// Read disease file via loader
-		filePath := filepath.Join(diseasesDir, file.Name())
-		loaded, err := utils.LoadJSONFile(filePath)
-		if err != nil {
-			continue // Skip files that can't be read
-		}
-
-		var disease map[string]interface{}
-		if arr, ok := loaded.([]interface{}); ok && len(arr) > 0 {
-			if d, ok := arr[0].(map[string]interface{}); ok {
-				disease = d
-			} else {
-				continue
-			}
-		} else if d, ok := loaded.(map[string]interface{}); ok {
-			disease = d
-		} else {
-			continue
-		}
-
-		// Extract basic info
-		diseaseInfo := map[string]interface{}{
-			"filename": strings.TrimSuffix(file.Name(), ".json"),
-		}
-
-		// Extract disease name if available
-		if diseaseName, ok := disease["disease_name"].(map[string]interface{}); ok {
-			if nameEn, ok := diseaseName["en"].(string); ok {
-				diseaseInfo["name_en"] = nameEn
-			}
-			if nameAr, ok := diseaseName["ar"].(string); ok {
-				diseaseInfo["name_ar"] = nameAr
-			}
-		}
-
-		// Extract description if available
-		if description, ok := disease["description"].(map[string]interface{}); ok {
-			if descEn, ok := description["en"].(string); ok {
-				diseaseInfo["description_en"] = descEn
-				// Truncate long descriptions for list view
-				if len(descEn) > 200 {
-					diseaseInfo["description_en"] = descEn[:200] + "..."
-				}
-			}
-		}
-
-		// Apply search filter
-		if search != "" {
-			nameMatch := false
-			if nameEn, ok := diseaseInfo["name_en"].(string); ok {
-				if strings.Contains(strings.ToLower(nameEn), search) {
-					nameMatch = true
-				}
-			}
-			if nameAr, ok := diseaseInfo["name_ar"].(string); ok {
-				if strings.Contains(strings.ToLower(nameAr), search) {
-					nameMatch = true
-				}
-			}
-			if descEn, ok := diseaseInfo["description_en"].(string); ok {
-				if strings.Contains(strings.ToLower(descEn), search) {
-					nameMatch = true
-				}
-			}
-			if !nameMatch {
-				continue
-			}
+	matches := snapshot.Diseases
+	if search := c.QueryParam("search"); search != "" {
+		matches = nil
+		for _, hit := range snapshot.SearchDiseases(search) {
+			matches = append(matches, snapshot.Diseases[hit.Index])
 		}
+	}
 
-		diseases = append(diseases, diseaseInfo)
+	diseases := []map[string]interface{}{}
+	for _, disease := range matches {
+		diseases = append(diseases, diseaseSummary(disease))
 	}
 
 	// Apply pagination
@@ -173,78 +94,34 @@ func (h *DiseaseHandler) GetDisease(c echo.Context) error {
 		})
 	}
 
-	// Construct file path
-	fileName := diseaseName + ".json"
-	filePath := filepath.Join(h.dataDir, "../disease-nutrition-easy-json-files", fileName)
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeDiseases) {
+		return knowledgeUnavailable(c, "Disease")
+	}
 
-	// Read disease file via loader
-	loaded, err := utils.LoadJSONFile(filePath)
-	if err != nil {
+	entry, ok := snapshot.Disease(diseaseName)
+	if !ok {
 		return utils.Error(c, http.StatusNotFound, "Disease not found")
 	}
 
-	var disease map[string]interface{}
-	if d, ok := loaded.(map[string]interface{}); ok {
-		disease = d
-	} else if arr, ok := loaded.([]interface{}); ok && len(arr) > 0 {
-		if m, ok := arr[0].(map[string]interface{}); ok {
-			disease = m
-		} else {
-			return utils.Error(c, http.StatusInternalServerError, "Invalid disease data format")
-		}
-	} else {
-		return utils.Error(c, http.StatusInternalServerError, "Invalid disease data format")
+	// Copy so the shared snapshot is never modified
+	disease := make(map[string]interface{}, len(entry.Data)+1)
+	for key, value := range entry.Data {
+		disease[key] = value
 	}
-
-	// Add metadata
 	disease["filename"] = diseaseName
-	disease["file_path"] = filePath
 
 	return utils.Success(c, disease)
 }
 
 // GetDiseaseCategories returns available disease categories
 func (h *DiseaseHandler) GetDiseaseCategories(c echo.Context) error {
-	// Read disease directory
-	diseasesDir := filepath.Join(h.dataDir, "../disease-nutrition-easy-json-files")
-	files, err := os.ReadDir(diseasesDir)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "Failed to read diseases directory",
-			"message": err.Error(),
-		})
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeDiseases) {
+		return knowledgeUnavailable(c, "Disease")
 	}
 
-	// Extract categories from filenames
-	categories := make(map[string][]string)
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".json") {
-			continue
-		}
-
-		name := strings.TrimSuffix(file.Name(), ".json")
-
-		// Categorize based on filename patterns
-		if strings.Contains(name, "diabetes") || strings.Contains(name, "sugar") {
-			categories["Metabolic"] = append(categories["Metabolic"], name)
-		} else if strings.Contains(name, "heart") || strings.Contains(name, "cardio") {
-			categories["Cardiovascular"] = append(categories["Cardiovascular"], name)
-		} else if strings.Contains(name, "brain") || strings.Contains(name, "neuro") || strings.Contains(name, "migraine") || strings.Contains(name, "dementia") || strings.Contains(name, "alzheimer") {
-			categories["Neurological"] = append(categories["Neurological"], name)
-		} else if strings.Contains(name, "cancer") {
-			categories["Oncology"] = append(categories["Oncology"], name)
-		} else if strings.Contains(name, "nutrition") || strings.Contains(name, "diet") {
-			categories["Nutritional"] = append(categories["Nutritional"], name)
-		} else if strings.Contains(name, "child") || strings.Contains(name, "pregnanc") {
-			categories["Life Stage"] = append(categories["Life Stage"], name)
-		} else if strings.Contains(name, "pain") || strings.Contains(name, "ache") {
-			categories["Pain Management"] = append(categories["Pain Management"], name)
-		} else {
-			categories["General"] = append(categories["General"], name)
-		}
-	}
-
-	return utils.Success(c, categories)
+	return utils.Success(c, snapshot.DiseaseCategories)
 }
 
 // SearchDiseases searches for diseases based on various criteria
@@ -272,103 +149,17 @@ func (h *DiseaseHandler) SearchDiseases(c echo.Context) error {
 		}
 	}
 
-	// Read disease directory
-	diseasesDir := filepath.Join(h.dataDir, "../disease-nutrition-easy-json-files")
-	files, err := os.ReadDir(diseasesDir)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "Failed to read diseases directory",
-			"message": err.Error(),
-		})
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeDiseases) {
+		return knowledgeUnavailable(c, "Disease")
 	}
 
-	// Search in disease files
-	var results []map[string]interface{}
-	searchLower := strings.ToLower(query)
-
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".json") {
-			continue
-		}
-
-		// Read disease file
-		filePath := filepath.Join(diseasesDir, file.Name())
-		data, err := os.ReadFile(filePath)
-		if err != nil {
-			continue
-		}
-
-		var disease map[string]interface{}
-		if err := json.Unmarshal(data, &disease); err != nil {
-			continue
-		}
-
-		// Search in various fields
-		score := 0.0
-		result := map[string]interface{}{
-			"filename": strings.TrimSuffix(file.Name(), ".json"),
-			"score":    0.0,
-		}
-
-		// Search in disease name
-		if diseaseName, ok := disease["disease_name"].(map[string]interface{}); ok {
-			if nameEn, ok := diseaseName["en"].(string); ok {
-				if strings.Contains(strings.ToLower(nameEn), searchLower) {
-					score += 10.0
-					result["name_en"] = nameEn
-				}
-			}
-			if nameAr, ok := diseaseName["ar"].(string); ok {
-				if strings.Contains(strings.ToLower(nameAr), searchLower) {
-					score += 10.0
-					result["name_ar"] = nameAr
-				}
-			}
-		}
-
-		// Search in description
-		if description, ok := disease["description"].(map[string]interface{}); ok {
-			if descEn, ok := description["en"].(string); ok {
-				if strings.Contains(strings.ToLower(descEn), searchLower) {
-					score += 5.0
-					result["description_en"] = descEn
-					if len(descEn) > 200 {
-						result["description_en"] = descEn[:200] + "..."
-					}
-				}
-			}
-		}
-
-		// Search in nutritional recommendations
-		if nutrition, ok := disease["nutritional_recommendations"].(map[string]interface{}); ok {
-			if nutritionEn, ok := nutrition["en"].(map[string]interface{}); ok {
-				if beneficial, ok := nutritionEn["beneficial_foods"].([]interface{}); ok {
-					for _, food := range beneficial {
-						if foodStr, ok := food.(string); ok {
-							if strings.Contains(strings.ToLower(foodStr), searchLower) {
-								score += 3.0
-							}
-						}
-					}
-				}
-			}
-		}
-
-		if score > 0 {
-			result["score"] = score
-			results = append(results, result)
-		}
-	}
-
-	// Sort by score (descending)
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			scoreI := results[i]["score"].(float64)
-			scoreJ := results[j]["score"].(float64)
-			if scoreI < scoreJ {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
+	// Results come ranked from the disease index
+	results := []map[string]interface{}{}
+	for _, hit := range snapshot.SearchDiseases(query) {
+		result := diseaseSummary(snapshot.Diseases[hit.Index])
+		result["score"] = hit.Score
+		results = append(results, result)
 	}
 
 	// Apply pagination
@@ -402,3 +193,29 @@ func (h *DiseaseHandler) SearchDiseases(c echo.Context) error {
 		"pagination": pagination,
 	})
 }
+
+func diseaseSummary(disease *services.KnowledgeDisease) map[string]interface{} {
+	summary := map[string]interface{}{
+		"filename": disease.Slug,
+		"category": disease.Category,
+	}
+	if disease.NameEn != "" {
+		summary["name_en"] = disease.NameEn
+	}
+	if disease.NameAr != "" {
+		summary["name_ar"] = disease.NameAr
+	}
+	if description := disease.DescriptionEn; description != "" {
+		// Truncate long descriptions for list view
+		if len(description) > 200 {
+			description = description[:200] + "..."
+		}
+		summary["description_en"] = description
+	}
+	return summary
+}
+
+// knowledgeUnavailable reports a dataset that failed to load; details are in the health check
+func knowledgeUnavailable(c echo.Context, dataset string) error {
+	return utils.Error(c, http.StatusServiceUnavailable, dataset+" data is temporarily unavailable")
+}
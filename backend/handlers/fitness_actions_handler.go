@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	"nutrition-platform/database"
+	"nutrition-platform/models"
 	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
@@ -16,62 +18,88 @@ type FitnessActionsHandler struct {
 	fitnessService *services.FitnessService
 }
 
-func NewFitnessActionsHandler(db *database.Database) *FitnessActionsHandler {
+func NewFitnessActionsHandler(db *sql.DB, gdpr *services.GDPRCompliance) *FitnessActionsHandler {
+	fitnessService := services.NewFitnessService(db)
+	fitnessService.SetConsent(gdpr)
 	return &FitnessActionsHandler{
-		fitnessService: services.NewFitnessService(db),
+		fitnessService: fitnessService,
 	}
 }
 
 // GenerateWorkout - Action: User clicks "Generate Workout" button
 // POST /api/v1/actions/generate-workout
 func (h *FitnessActionsHandler) GenerateWorkout(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	var req struct {
-		Goal         string   `json:"goal"`       // weight_loss, muscle_gain, endurance, flexibility
-		Duration     int      `json:"duration"`   // minutes
-		Difficulty   string   `json:"difficulty"` // beginner, intermediate, advanced
-		Equipment    []string `json:"equipment"`
-		MuscleGroups []string `json:"muscle_groups"`
-		Restrictions []string `json:"restrictions"`
-	}
-
+	var req models.GenerateWorkoutProgramRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid request format: " + err.Error(),
 		})
 	}
 
-	// Default duration to 30 minutes if not provided
-	if req.Duration == 0 {
-		req.Duration = 30
-	}
-
-	// Default difficulty to intermediate if not provided
-	if req.Difficulty == "" {
-		req.Difficulty = "intermediate"
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
 	}
 
-	workoutPlan := map[string]interface{}{
-		"goal":          req.Goal,
-		"duration":      req.Duration,
-		"difficulty":    req.Difficulty,
-		"equipment":     req.Equipment,
-		"muscle_groups": req.MuscleGroups,
-		"restrictions":  req.Restrictions,
-		"generated_at":  time.Now().Format(time.RFC3339),
-		"exercises":     []map[string]interface{}{}, // Will be populated by service
+	program, err := h.fitnessService.GenerateWorkoutPlan(c.Request().Context(), userID, req)
+	if err != nil {
+		return workoutProgramError(c, err, "Failed to generate workout program")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"status":  "success",
 		"message": "Workout plan generated successfully",
-		"data":    workoutPlan,
+		"data":    program,
+	})
+}
+
+// GetWorkoutPrograms - Action: User opens their generated workout programs
+// GET /api/v1/actions/workout-programs
+func (h *FitnessActionsHandler) GetWorkoutPrograms(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	programs, err := h.fitnessService.ListWorkoutPrograms(c.Request().Context(), userID)
+	if err != nil {
+		return workoutProgramError(c, err, "Failed to fetch workout programs")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   programs,
+	})
+}
+
+// GetWorkoutProgram - Action: User opens one generated workout program
+// GET /api/v1/actions/workout-programs/:id
+func (h *FitnessActionsHandler) GetWorkoutProgram(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	program, err := h.fitnessService.GetWorkoutProgram(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return workoutProgramError(c, err, "Failed to fetch workout program")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   program,
 	})
 }
 
@@ -204,3 +232,24 @@ func (h *FitnessActionsHandler) GetWorkoutRecommendations(c echo.Context) error
 		"recommendations": recommendations,
 	})
 }
+
+func workoutProgramError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrWorkoutProgramNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Workout program not found",
+		})
+	case errors.Is(err, services.ErrInvalidWorkoutProgram):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrNoEligibleExercises):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
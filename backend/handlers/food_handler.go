@@ -371,6 +371,46 @@ func (h *FoodHandler) DeleteFood(c echo.Context) error {
 	})
 }
 
+// VerifyFood marks a food as verified (or clears the flag with {"verified": false}).
+// Access is controlled by the foods:verify permission.
+func (h *FoodHandler) VerifyFood(c echo.Context) error {
+	req := struct {
+		Verified *bool `json:"verified"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	verified := true
+	if req.Verified != nil {
+		verified = *req.Verified
+	}
+
+	foodID := c.Param("id")
+	err := h.foodRepo.SetFoodVerified(foodID, verified)
+	if err != nil {
+		if err.Error() == "food not found" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Food not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to verify food: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Food verification updated",
+		"data": map[string]interface{}{
+			"id":       foodID,
+			"verified": verified,
+		},
+	})
+}
+
 // SearchFoods searches for foods
 func (h *FoodHandler) SearchFoods(c echo.Context) error {
 	return h.GetFoods(c) // Reuse GetFoods which already supports search
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GDPRHandler handles the signed-in user's data export, account erasure and
+// consents
+type GDPRHandler struct {
+	gdpr *services.GDPRCompliance
+}
+
+// NewGDPRHandler creates a new GDPR handler
+func NewGDPRHandler(gdpr *services.GDPRCompliance) *GDPRHandler {
+	return &GDPRHandler{
+		gdpr: gdpr,
+	}
+}
+
+// ExportData downloads a ZIP archive of the user's personal data as JSON and
+// CSV files with their progress photos
+// GET /api/v1/users/me/export
+func (h *GDPRHandler) ExportData(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var archive bytes.Buffer
+	metadata, err := h.gdpr.ExportArchive(c.Request().Context(), userID, gdprClient(c), &archive)
+	if err != nil {
+		return gdprError(c, err, "Failed to export data")
+	}
+
+	c.Response().Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="personal-data-%s.zip"`, metadata.ExportedAt.Format("20060102")))
+	c.Response().Header().Set("X-Export-Checksum", metadata.Checksum)
+	return c.Blob(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// RequestErasure emails the user a token confirming the erasure of their
+// account and data
+// POST /api/v1/users/me/erasure
+func (h *GDPRHandler) RequestErasure(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.RequestErasureRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	request, err := h.gdpr.RequestErasure(c.Request().Context(), userID, req.Reason, gdprClient(c))
+	if err != nil {
+		return gdprError(c, err, "Failed to request erasure")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"status":  "success",
+		"message": "Check your email to confirm deleting your account",
+		"data":    request,
+	})
+}
+
+// VerifyErasure confirms the erasure with the emailed token and schedules the
+// hard delete for the end of the grace period
+// POST /api/v1/users/me/erasure/verify
+func (h *GDPRHandler) VerifyErasure(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.VerifyErasureRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	request, err := h.gdpr.VerifyErasure(c.Request().Context(), userID, req.Token, gdprClient(c))
+	if err != nil {
+		return gdprError(c, err, "Failed to verify erasure")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Account deletion scheduled",
+		"data":    request,
+	})
+}
+
+// GetErasure returns the user's most recent erasure request
+// GET /api/v1/users/me/erasure
+func (h *GDPRHandler) GetErasure(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	request, err := h.gdpr.ErasureStatus(c.Request().Context(), userID)
+	if err != nil {
+		return gdprError(c, err, "Failed to fetch erasure request")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   request,
+	})
+}
+
+// CancelErasure cancels a pending or scheduled erasure during its grace period
+// DELETE /api/v1/users/me/erasure
+func (h *GDPRHandler) CancelErasure(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	request, err := h.gdpr.CancelErasure(c.Request().Context(), userID, gdprClient(c))
+	if err != nil {
+		return gdprError(c, err, "Failed to cancel erasure")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Account deletion cancelled",
+		"data":    request,
+	})
+}
+
+// GetConsents returns the user's current consent to every purpose
+// GET /api/v1/users/me/consents
+func (h *GDPRHandler) GetConsents(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	statuses, err := h.gdpr.ConsentStatuses(c.Request().Context(), userID)
+	if err != nil {
+		return gdprError(c, err, "Failed to fetch consents")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   statuses,
+	})
+}
+
+// GetConsentHistory returns every grant and withdrawal of the user's
+// consents, newest first
+// GET /api/v1/users/me/consents/history
+func (h *GDPRHandler) GetConsentHistory(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	history, err := h.gdpr.GetUserConsents(c.Request().Context(), userID)
+	if err != nil {
+		return gdprError(c, err, "Failed to fetch consent history")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   history,
+	})
+}
+
+// GrantConsent records the user's consent to the current version of a purpose
+// POST /api/v1/users/me/consents/:purpose
+func (h *GDPRHandler) GrantConsent(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	consent, err := h.gdpr.GrantConsent(c.Request().Context(), userID, c.Param("purpose"), gdprClient(c))
+	if err != nil {
+		return gdprError(c, err, "Failed to record consent")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  "success",
+		"message": "Consent granted",
+		"data":    consent,
+	})
+}
+
+// WithdrawConsent withdraws the user's consent to a purpose and erases the
+// data kept under it
+// DELETE /api/v1/users/me/consents/:purpose
+func (h *GDPRHandler) WithdrawConsent(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	consent, err := h.gdpr.WithdrawConsent(c.Request().Context(), userID, c.Param("purpose"), gdprClient(c))
+	if err != nil {
+		return gdprError(c, err, "Failed to withdraw consent")
+	}
+
+	message := "Consent withdrawn"
+	for _, purpose := range services.ConsentPurposes() {
+		if purpose.ID == consent.ConsentType {
+			message += ". " + purpose.OnWithdrawal
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": message,
+		"data":    consent,
+	})
+}
+
+// GetAuditLog returns GDPR audit log entries, optionally for one user
+// GET /api/v1/auth/admin/gdpr/audit?user_id=...&limit=100
+func (h *GDPRHandler) GetAuditLog(c echo.Context) error {
+	limit := 100
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "limit must be a number",
+			})
+		}
+		limit = parsed
+	}
+
+	entries, err := h.gdpr.GetAuditLog(c.Request().Context(), limit, c.QueryParam("user_id"))
+	if err != nil {
+		return gdprError(c, err, "Failed to fetch audit log")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   entries,
+	})
+}
+
+func gdprClient(c echo.Context) services.SessionClient {
+	return services.SessionClient{
+		IPAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
+
+func gdprError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidErasureToken), errors.Is(err, services.ErrInvalidAuditLogQuery):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrErasureNotFound), errors.Is(err, services.ErrGDPRAccountNotFound),
+		errors.Is(err, services.ErrUnknownConsentPurpose):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrErasureScheduled):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrMailerNotConfigured):
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HalalHandler handles halal preferences and compliance checks
+type HalalHandler struct {
+	halalService *services.HalalService
+}
+
+// NewHalalHandler creates a new halal handler
+func NewHalalHandler(halalService *services.HalalService) *HalalHandler {
+	return &HalalHandler{
+		halalService: halalService,
+	}
+}
+
+// GetPreferences returns the user's strictness level, dietary school and
+// substitution setting
+// GET /api/v1/halal/preferences
+func (h *HalalHandler) GetPreferences(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	prefs, err := h.halalService.Preferences(c.Request().Context(), userID)
+	if err != nil {
+		return halalError(c, err, "Failed to fetch halal preferences")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   prefs,
+	})
+}
+
+// UpdatePreferences changes the user's halal preferences
+// PUT /api/v1/halal/preferences
+func (h *HalalHandler) UpdatePreferences(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.UpdateHalalPreferencesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	prefs, err := h.halalService.UpdatePreferences(c.Request().Context(), userID, req)
+	if err != nil {
+		return halalError(c, err, "Failed to update halal preferences")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Halal preferences updated successfully",
+		"data":    prefs,
+	})
+}
+
+// CheckCompliance checks ingredients and recipe instructions with the user's
+// halal preferences
+// POST /api/v1/halal/check
+func (h *HalalHandler) CheckCompliance(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.HalalCheckRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	result, err := h.halalService.Check(c.Request().Context(), userID, req.Ingredients, req.Recipe)
+	if err != nil {
+		return halalError(c, err, "Failed to check halal compliance")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   result,
+	})
+}
+
+// GetSuggestions returns halal substitutions for an ingredient
+// GET /api/v1/halal/suggestions/:ingredient
+func (h *HalalHandler) GetSuggestions(c echo.Context) error {
+	suggestions, err := h.halalService.Compliance().GetSuggestions(c.Param("ingredient"))
+	if err != nil {
+		return halalError(c, err, "Failed to fetch halal suggestions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"ingredient":  c.Param("ingredient"),
+			"suggestions": suggestions,
+		},
+	})
+}
+
+// GetAdditive returns the halal status of a food additive by its E or INS
+// number
+// GET /api/v1/halal/additives/:code
+func (h *HalalHandler) GetAdditive(c echo.Context) error {
+	additive, err := h.halalService.Compliance().LookupAdditive(c.Param("code"))
+	if err != nil {
+		return halalError(c, err, "Failed to fetch additive")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   additive,
+	})
+}
+
+// GetVersion returns the version of the loaded blacklist
+// GET /api/v1/halal/version
+func (h *HalalHandler) GetVersion(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]string{
+			"version": h.halalService.Compliance().GetBlacklistVersion(),
+		},
+	})
+}
+
+// ReloadBlacklist re-reads the blacklist file
+// POST /api/v1/auth/admin/halal/reload
+func (h *HalalHandler) ReloadBlacklist(c echo.Context) error {
+	compliance := h.halalService.Compliance()
+	if err := compliance.ReloadBlacklist(); err != nil {
+		return halalError(c, err, "Failed to reload halal blacklist")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Halal blacklist reloaded successfully",
+		"data": map[string]string{
+			"version": compliance.GetBlacklistVersion(),
+		},
+	})
+}
+
+func halalError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidHalalPreferences):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrNoHalalSuggestions), errors.Is(err, services.ErrUnknownAdditive):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
@@ -1,52 +1,34 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
+	"nutrition-platform/services"
 	"nutrition-platform/utils"
+
 	"github.com/labstack/echo/v4"
 )
 
 type InjuryHandler struct {
-	dataDir string
+	knowledgeBase *services.KnowledgeBase
 }
 
-func NewInjuryHandler(dataDir string) *InjuryHandler {
-	return &InjuryHandler{dataDir: dataDir}
+func NewInjuryHandler(knowledgeBase *services.KnowledgeBase) *InjuryHandler {
+	return &InjuryHandler{knowledgeBase: knowledgeBase}
 }
 
 // GetInjuries returns a list of all available injuries
 func (h *InjuryHandler) GetInjuries(c echo.Context) error {
-	injuriesDir := filepath.Join(h.dataDir, "../injury easy trae json")
-
-	files, err := os.ReadDir(injuriesDir)
-	if err != nil {
-		return utils.InternalServerErrorResponse(c, "Failed to read injuries directory")
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeInjuries) {
+		return knowledgeUnavailable(c, "Injury")
 	}
 
 	injuries := []map[string]interface{}{}
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".js" {
-			data, err := os.ReadFile(filepath.Join(injuriesDir, file.Name()))
-			if err != nil {
-				continue // Skip files that can't be read
-			}
-
-			// Parse the file to extract the title
-			content := string(data)
-			title := h.extractTitle(content, file.Name())
-
-			injuries = append(injuries, map[string]interface{}{
-				"id":    strings.TrimSuffix(file.Name(), ".js"),
-				"title": title,
-				"file":  file.Name(),
-			})
-		}
+	for _, injury := range snapshot.Injuries {
+		injuries = append(injuries, injurySummary(injury))
 	}
 
 	// Parse pagination parameters
@@ -66,19 +48,17 @@ func (h *InjuryHandler) GetInjuries(c echo.Context) error {
 
 // GetInjury returns a specific injury by ID
 func (h *InjuryHandler) GetInjury(c echo.Context) error {
-	injuryID := c.Param("id")
-	filePath := filepath.Join(h.dataDir, "../injury easy trae json", injuryID+".js")
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeInjuries) {
+		return knowledgeUnavailable(c, "Injury")
+	}
 
-	data, err := os.ReadFile(filePath)
-	if err != nil {
+	injury, ok := snapshot.Injury(c.Param("id"))
+	if !ok {
 		return utils.NotFoundResponse(c, "Injury not found")
 	}
 
-	// Parse the JSON content from the file
-	content := string(data)
-	injuryData := h.parseInjuryFile(content)
-
-	return utils.SuccessResponse(c, injuryData)
+	return utils.SuccessResponse(c, injury.Data)
 }
 
 // SearchInjuries allows searching across injury data
@@ -88,33 +68,16 @@ func (h *InjuryHandler) SearchInjuries(c echo.Context) error {
 		return h.GetInjuries(c)
 	}
 
-	injuriesDir := filepath.Join(h.dataDir, "../injury easy trae json")
-
-	files, err := os.ReadDir(injuriesDir)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to read injuries directory",
-		})
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeInjuries) {
+		return knowledgeUnavailable(c, "Injury")
 	}
 
 	results := []map[string]interface{}{}
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".js" {
-			data, err := os.ReadFile(filepath.Join(injuriesDir, file.Name()))
-			if err != nil {
-				continue
-			}
-
-			content := string(data)
-			if strings.Contains(strings.ToLower(content), query) {
-				title := h.extractTitle(content, file.Name())
-				results = append(results, map[string]interface{}{
-					"id":    strings.TrimSuffix(file.Name(), ".js"),
-					"title": title,
-					"file":  file.Name(),
-				})
-			}
-		}
+	for _, hit := range snapshot.SearchInjuries(query) {
+		result := injurySummary(snapshot.Injuries[hit.Index])
+		result["score"] = hit.Score
+		results = append(results, result)
 	}
 
 	// Handle pagination
@@ -157,159 +120,31 @@ func (h *InjuryHandler) SearchInjuries(c echo.Context) error {
 
 // GetInjuryCategories returns categorized injuries
 func (h *InjuryHandler) GetInjuryCategories(c echo.Context) error {
-	injuriesDir := filepath.Join(h.dataDir, "../injury easy trae json")
-
-	files, err := os.ReadDir(injuriesDir)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to read injuries directory",
-		})
+	snapshot := h.knowledgeBase.Snapshot()
+	if !snapshot.Available(services.KnowledgeInjuries) {
+		return knowledgeUnavailable(c, "Injury")
 	}
 
 	categories := map[string][]map[string]interface{}{}
-
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".js" {
-			data, err := os.ReadFile(filepath.Join(injuriesDir, file.Name()))
-			if err != nil {
-				continue
-			}
-
-			content := string(data)
-			title := h.extractTitle(content, file.Name())
-			category := h.categorizeInjury(file.Name(), content)
-
-			injury := map[string]interface{}{
-				"id":    strings.TrimSuffix(file.Name(), ".js"),
-				"title": title,
-				"file":  file.Name(),
-			}
-
-			categories[category] = append(categories[category], injury)
+	for category, injuries := range snapshot.InjuryCategories {
+		for _, injury := range injuries {
+			categories[category] = append(categories[category], injurySummary(injury))
 		}
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"status":     "success",
 		"categories": categories,
-		"total":      len(files),
+		"total":      len(snapshot.Injuries),
 	})
 }
 
 // Helper functions
 
-func (h *InjuryHandler) extractTitle(content, filename string) map[string]string {
-	// Try to extract title from JSON content
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, `"title": {`) {
-			// Extract the title object
-			startIndex := strings.Index(content, line)
-			if startIndex == -1 {
-				break
-			}
-
-			// Find the end of the title object
-			braceCount := 0
-			endIndex := startIndex
-			for i, char := range content[startIndex:] {
-				if char == '{' {
-					braceCount++
-				} else if char == '}' {
-					braceCount--
-					if braceCount == 0 {
-						endIndex = startIndex + i + 1
-						break
-					}
-				}
-			}
-
-			titleStr := content[startIndex:endIndex]
-			var title map[string]string
-			if err := json.Unmarshal([]byte(titleStr), &title); err == nil {
-				return title
-			}
-			break
-		}
-	}
-
-	// Fallback to filename-based title
-	baseName := strings.TrimSuffix(filename, ".js")
-	parts := strings.Split(baseName, " ")
-	if len(parts) > 1 {
-		baseName = strings.Join(parts[1:], " ")
-	}
-
-	return map[string]string{
-		"english": baseName,
-		"arabic":  baseName,
+func injurySummary(injury *services.KnowledgeInjury) map[string]interface{} {
+	return map[string]interface{}{
+		"id":    injury.ID,
+		"title": injury.Title,
+		"file":  injury.File,
 	}
 }
-
-func (h *InjuryHandler) categorizeInjury(filename, content string) string {
-	filename = strings.ToLower(filename)
-	content = strings.ToLower(content)
-
-	// Categorize based on filename patterns and content
-	if strings.Contains(filename, "neck") || strings.Contains(content, "neck") {
-		return "Neck Injuries"
-	}
-	if strings.Contains(filename, "back") || strings.Contains(content, "back") {
-		return "Back Injuries"
-	}
-	if strings.Contains(filename, "wrist") || strings.Contains(content, "wrist") {
-		return "Wrist Injuries"
-	}
-	if strings.Contains(filename, "shoulder") || strings.Contains(content, "shoulder") {
-		return "Shoulder Injuries"
-	}
-	if strings.Contains(filename, "knee") || strings.Contains(content, "knee") {
-		return "Knee Injuries"
-	}
-	if strings.Contains(filename, "ankle") || strings.Contains(content, "ankle") {
-		return "Ankle Injuries"
-	}
-	if strings.Contains(filename, "hip") || strings.Contains(content, "hip") {
-		return "Hip Injuries"
-	}
-
-	return "Other Injuries"
-}
-
-func (h *InjuryHandler) parseInjuryFile(content string) map[string]interface{} {
-	// Find JSON blocks in the file
-	lines := strings.Split(content, "\n")
-	inJsonBlock := false
-	jsonLines := []string{}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "```json") {
-			inJsonBlock = true
-			continue
-		}
-		if strings.HasPrefix(line, "```") && inJsonBlock {
-			inJsonBlock = false
-			break
-		}
-		if inJsonBlock && line != "" {
-			jsonLines = append(jsonLines, line)
-		}
-	}
-
-	jsonStr := strings.Join(jsonLines, "\n")
-	var injuryData map[string]interface{}
-
-	if err := json.Unmarshal([]byte(jsonStr), &injuryData); err != nil {
-		// If parsing fails, return a basic structure
-		title := h.extractTitle(content, "unknown")
-		return map[string]interface{}{
-			"title":       title,
-			"description": map[string]string{"english": "Failed to parse content", "arabic": "فشل في تحليل المحتوى"},
-			"error":       "JSON parsing error",
-		}
-	}
-
-	return injuryData
-}
@@ -9,6 +9,7 @@ import (
 	"nutrition-platform/database"
 	"nutrition-platform/models"
 	"nutrition-platform/repositories"
+	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
 )
@@ -16,6 +17,7 @@ import (
 // MeasurementsHandler handles body measurement operations
 type MeasurementsHandler struct {
 	measurementRepo *repositories.BodyMeasurementRepository
+	progressService *services.ProgressService
 }
 
 // NewMeasurementsHandler creates a new measurements handler
@@ -24,37 +26,19 @@ func NewMeasurementsHandler(db *sql.DB) *MeasurementsHandler {
 	dbWrapper := database.NewDatabase(db)
 	return &MeasurementsHandler{
 		measurementRepo: repositories.NewBodyMeasurementRepository(dbWrapper),
+		progressService: services.NewProgressService(db),
 	}
 }
 
 // LogMeasurement logs a body measurement entry
 func (h *MeasurementsHandler) LogMeasurement(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to uint
-	var userIDUint uint
-	switch v := userID.(type) {
-	case uint:
-		userIDUint = v
-	case string:
-		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
-			userIDUint = uint(id)
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	var req struct {
 		MeasurementDate   *time.Time `json:"measurement_date"`
 		Weight            *float64   `json:"weight,omitempty"`
@@ -89,7 +73,7 @@ func (h *MeasurementsHandler) LogMeasurement(c echo.Context) error {
 	}
 
 	measurement := &models.BodyMeasurement{
-		UserID:            userIDUint,
+		UserID:            userID,
 		MeasurementDate:   measurementDate,
 		Weight:            req.Weight,
 		Height:            req.Height,
@@ -119,7 +103,9 @@ func (h *MeasurementsHandler) LogMeasurement(c echo.Context) error {
 		})
 	}
 
-	err := h.measurementRepo.CreateBodyMeasurement(c.Request().Context(), measurement)
+	// Logging through the progress service also checks weight goals and
+	// milestones, as /actions/track-measurement does
+	measurement, err := h.progressService.LogMeasurement(c.Request().Context(), userID, measurement)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to log measurement: " + err.Error(),
@@ -135,32 +121,13 @@ func (h *MeasurementsHandler) LogMeasurement(c echo.Context) error {
 
 // GetMeasurements returns measurement history for current user
 func (h *MeasurementsHandler) GetMeasurements(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to uint
-	var userIDUint uint
-	switch v := userID.(type) {
-	case uint:
-		userIDUint = v
-	case string:
-		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
-			userIDUint = uint(id)
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	// Parse pagination
 	page := 1
 	limit := 20
@@ -178,7 +145,7 @@ func (h *MeasurementsHandler) GetMeasurements(c echo.Context) error {
 	offset := (page - 1) * limit
 
 	// Get measurements
-	measurements, err := h.measurementRepo.GetBodyMeasurementsByUserID(c.Request().Context(), int64(userIDUint), limit, offset)
+	measurements, err := h.measurementRepo.GetBodyMeasurementsByUserID(c.Request().Context(), userID, limit, offset)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch measurements: " + err.Error(),
@@ -186,7 +153,7 @@ func (h *MeasurementsHandler) GetMeasurements(c echo.Context) error {
 	}
 
 	// Get total count for pagination
-	total, err := h.measurementRepo.GetMeasurementCountByUserID(c.Request().Context(), int64(userIDUint))
+	total, err := h.measurementRepo.GetMeasurementCountByUserID(c.Request().Context(), userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch measurement count: " + err.Error(),
@@ -207,32 +174,13 @@ func (h *MeasurementsHandler) GetMeasurements(c echo.Context) error {
 
 // GetMeasurement returns a specific measurement by ID
 func (h *MeasurementsHandler) GetMeasurement(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to uint
-	var userIDUint uint
-	switch v := userID.(type) {
-	case uint:
-		userIDUint = v
-	case string:
-		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
-			userIDUint = uint(id)
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	measurementID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -253,7 +201,7 @@ func (h *MeasurementsHandler) GetMeasurement(c echo.Context) error {
 	}
 
 	// Check if the measurement belongs to the current user
-	if measurement.UserID != userIDUint {
+	if measurement.UserID != userID {
 		return c.JSON(http.StatusForbidden, map[string]string{
 			"error": "Access denied",
 		})
@@ -267,32 +215,13 @@ func (h *MeasurementsHandler) GetMeasurement(c echo.Context) error {
 
 // UpdateMeasurement updates a body measurement entry
 func (h *MeasurementsHandler) UpdateMeasurement(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to uint
-	var userIDUint uint
-	switch v := userID.(type) {
-	case uint:
-		userIDUint = v
-	case string:
-		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
-			userIDUint = uint(id)
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	measurementID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -341,7 +270,7 @@ func (h *MeasurementsHandler) UpdateMeasurement(c echo.Context) error {
 	}
 
 	// Check if the measurement belongs to the current user
-	if existingMeasurement.UserID != userIDUint {
+	if existingMeasurement.UserID != userID {
 		return c.JSON(http.StatusForbidden, map[string]string{
 			"error": "Access denied",
 		})
@@ -432,32 +361,13 @@ func (h *MeasurementsHandler) UpdateMeasurement(c echo.Context) error {
 
 // DeleteMeasurement deletes a body measurement entry
 func (h *MeasurementsHandler) DeleteMeasurement(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to uint
-	var userIDUint uint
-	switch v := userID.(type) {
-	case uint:
-		userIDUint = v
-	case string:
-		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
-			userIDUint = uint(id)
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	measurementID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -479,13 +389,13 @@ func (h *MeasurementsHandler) DeleteMeasurement(c echo.Context) error {
 	}
 
 	// Check if the measurement belongs to the current user
-	if existingMeasurement.UserID != userIDUint {
+	if existingMeasurement.UserID != userID {
 		return c.JSON(http.StatusForbidden, map[string]string{
 			"error": "Access denied",
 		})
 	}
 
-	err = h.measurementRepo.DeleteBodyMeasurement(c.Request().Context(), int64(measurementID), int64(userIDUint))
+	err = h.measurementRepo.DeleteBodyMeasurement(c.Request().Context(), int64(measurementID), userID)
 	if err != nil {
 		if err.Error() == "body measurement not found or access denied" {
 			return c.JSON(http.StatusNotFound, map[string]string{
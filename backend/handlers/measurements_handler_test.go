@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasurementsHandler_LogMeasurementRecordsWeightGoal(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, "001_initial_schema_sqlite.sql", "013_add_user_login_security.sql",
+		"014_create_user_sessions_table.sql", "015_create_password_reset_tokens_table.sql",
+		"016_add_two_factor_auth.sql", "017_create_rbac_tables.sql", "018_add_api_key_tiers.sql",
+		"019_create_food_diary.sql", "020_create_meal_plan_days.sql",
+		"021_add_generated_workout_programs.sql", "022_add_food_log_micronutrients.sql",
+		"023_create_water_intake.sql", "024_create_progress_photos.sql", "025_create_weight_goals.sql",
+		"026_create_personal_records.sql")
+	user, err := services.NewUserService(db).CreateUser(ctx,
+		services.CreateUserInput{Email: "weigh-in@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	goals := services.NewWeightGoalService(db)
+	goal, err := goals.CreateGoal(ctx, user.ID, models.CreateWeightGoalRequest{TargetWeight: 84, StartWeight: 92})
+	require.NoError(t, err)
+	// A week of weigh-ins below the target, stored before the goal was checked
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for day := 7; day >= 1; day-- {
+		_, err := db.Exec(`INSERT INTO body_measurements (user_id, measurement_date, weight) VALUES (?, ?, ?)`,
+			user.ID, today.AddDate(0, 0, -day).Add(7*time.Hour), 83)
+		require.NoError(t, err)
+	}
+	goal, err = goals.GetGoal(ctx, user.ID, goal.ID)
+	require.NoError(t, err)
+	require.Nil(t, goal.AchievedAt)
+
+	h := NewMeasurementsHandler(db)
+	e := echo.New()
+	progress := e.Group("/api/v1/progress", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", user.ID)
+			return next(c)
+		}
+	})
+	progress.POST("/measurements", h.LogMeasurement)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/progress/measurements", strings.NewReader(`{"weight":82.8}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	goal, err = goals.GetGoal(ctx, user.ID, goal.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, goal.AchievedAt, "logging a weigh-in marks the reached goal achieved")
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MedicationInteractionHandler handles medication interaction checks
+type MedicationInteractionHandler struct {
+	interactionService *services.MedicationInteractionService
+}
+
+func NewMedicationInteractionHandler(db *sql.DB, dataDir string) *MedicationInteractionHandler {
+	return &MedicationInteractionHandler{
+		interactionService: services.NewMedicationInteractionService(db, dataDir),
+	}
+}
+
+// CheckMyInteractions - Action: User opens the interaction check for their medications
+// GET /api/v1/actions/medication-interactions
+func (h *MedicationInteractionHandler) CheckMyInteractions(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	return h.check(c, userID)
+}
+
+// CheckUserInteractions returns the interaction check for another user's medications
+// GET /api/v1/auth/admin/users/:id/medication-interactions
+func (h *MedicationInteractionHandler) CheckUserInteractions(c echo.Context) error {
+	userID := c.Param("id")
+	if userID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "User ID is required",
+		})
+	}
+
+	return h.check(c, userID)
+}
+
+func (h *MedicationInteractionHandler) check(c echo.Context, userID string) error {
+	check, err := h.interactionService.CheckInteractions(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to check medication interactions",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   check,
+	})
+}
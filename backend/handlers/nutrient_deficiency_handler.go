@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NutrientDeficiencyHandler handles nutrient deficiency risk analysis
+type NutrientDeficiencyHandler struct {
+	deficiencyService *services.NutrientDeficiencyService
+}
+
+func NewNutrientDeficiencyHandler(db *sql.DB) *NutrientDeficiencyHandler {
+	return &NutrientDeficiencyHandler{
+		deficiencyService: services.NewNutrientDeficiencyService(db),
+	}
+}
+
+// AnalyzeDeficiencies - Action: User clicks "Check Nutrient Deficiencies" button
+// POST /api/v1/actions/analyze-deficiencies
+func (h *NutrientDeficiencyHandler) AnalyzeDeficiencies(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.NutrientDeficiencyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	analysis, err := h.deficiencyService.AnalyzeDeficiencies(c.Request().Context(), userID, req)
+	if err != nil {
+		return nutrientDeficiencyError(c, err, "Failed to analyze nutrient deficiencies")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   analysis,
+	})
+}
+
+func nutrientDeficiencyError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "User not found",
+		})
+	case errors.Is(err, services.ErrInvalidDeficiencyAnalysis):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
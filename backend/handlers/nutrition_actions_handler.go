@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"nutrition-platform/models"
 	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
@@ -14,69 +16,48 @@ import (
 // NutritionActionsHandler handles user-facing nutrition actions
 type NutritionActionsHandler struct {
 	nutritionPlanService *services.NutritionPlanService
+	foodLogService       *services.FoodLogService
+	mealPlanService      *services.MealPlanService
+	allergyService       *services.AllergyService
+	halalService         *services.HalalService
 }
 
-func NewNutritionActionsHandler(db *sql.DB) *NutritionActionsHandler {
+func NewNutritionActionsHandler(db *sql.DB, halalService *services.HalalService, allergyService *services.AllergyService) *NutritionActionsHandler {
 	return &NutritionActionsHandler{
 		nutritionPlanService: services.NewNutritionPlanService(db),
+		foodLogService:       services.NewFoodLogService(db),
+		mealPlanService:      services.NewMealPlanService(db, services.DefaultRecipeCatalogPath, halalService, allergyService),
+		allergyService:       allergyService,
+		halalService:         halalService,
 	}
 }
 
 // GenerateMealPlan - Action: User clicks "Generate Meal Plan" button
 // POST /api/v1/actions/generate-meal-plan
 func (h *NutritionActionsHandler) GenerateMealPlan(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to string (service expects string)
-	var userIDStr string
-	switch v := userID.(type) {
-	case uint:
-		userIDStr = strconv.FormatUint(uint64(v), 10)
-	case int:
-		userIDStr = strconv.Itoa(v)
-	case string:
-		userIDStr = v
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
-	var req struct {
-		Goal           string  `json:"goal"`
-		TargetCalories *int    `json:"target_calories"`
-		Duration       int     `json:"duration"` // days
-		Preferences    []string `json:"preferences"`
-		Restrictions   []string `json:"restrictions"`
-	}
-
+	var req models.GenerateMealPlanRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid request format: " + err.Error(),
 		})
 	}
 
-	// Default duration to 7 days if not provided
-	if req.Duration == 0 {
-		req.Duration = 7
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
 	}
 
-	// Call service to generate meal plan
-	// Note: This is a simplified version - you can expand based on your service implementation
-	mealPlan := map[string]interface{}{
-		"user_id":         userIDStr,
-		"goal":            req.Goal,
-		"target_calories": req.TargetCalories,
-		"duration_days":   req.Duration,
-		"preferences":     req.Preferences,
-		"restrictions":    req.Restrictions,
-		"generated_at":    time.Now().Format(time.RFC3339),
-		"meals":           []map[string]interface{}{}, // Will be populated by service
+	mealPlan, err := h.mealPlanService.GenerateMealPlan(c.Request().Context(), userID, req)
+	if err != nil {
+		return mealPlanError(c, err, "Failed to generate meal plan")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -86,94 +67,269 @@ func (h *NutritionActionsHandler) GenerateMealPlan(c echo.Context) error {
 	})
 }
 
-// LogMeal - Action: User clicks "Log Meal" button
-// POST /api/v1/actions/log-meal
-func (h *NutritionActionsHandler) LogMeal(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+// GetMealPlans - Action: User opens their generated meal plans
+// GET /api/v1/actions/meal-plans
+func (h *NutritionActionsHandler) GetMealPlans(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	var req struct {
-		FoodID   *uint   `json:"food_id"`
-		RecipeID *uint   `json:"recipe_id"`
-		MealType string  `json:"meal_type" validate:"required"`
-		Quantity float64 `json:"quantity" validate:"required,gt=0"`
-		Unit     string  `json:"unit" validate:"required"`
-		Date     string  `json:"date"` // YYYY-MM-DD format
-		Notes    *string `json:"notes"`
+	plans, err := h.mealPlanService.ListMealPlans(c.Request().Context(), userID)
+	if err != nil {
+		return mealPlanError(c, err, "Failed to fetch meal plans")
 	}
 
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   plans,
+	})
+}
+
+// GetMealPlan - Action: User opens one meal plan
+// GET /api/v1/actions/meal-plans/:id
+func (h *NutritionActionsHandler) GetMealPlan(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	plan, err := h.mealPlanService.GetMealPlan(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return mealPlanError(c, err, "Failed to fetch meal plan")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   plan,
+	})
+}
+
+// LogMeal - Action: User clicks "Log Meal" button. A food or recipe containing
+// one of the user's allergens is refused with 409 and the warnings until the
+// request sets acknowledge_allergens. A meal failing the halal check with the
+// user's preferences is logged with the compliance result.
+// POST /api/v1/actions/log-meal
+func (h *NutritionActionsHandler) LogMeal(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.LogMealRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid request format: " + err.Error(),
 		})
 	}
 
-	// Parse date or use current date
-	mealDate := time.Now()
-	if req.Date != "" {
-		if parsedDate, err := time.Parse("2006-01-02", req.Date); err == nil {
-			mealDate = parsedDate
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	var warnings []models.AllergenWarning
+	if h.allergyService != nil {
+		check, err := h.allergyService.CheckFoodLog(c.Request().Context(), userID, req.FoodID, req.RecipeID)
+		if err != nil {
+			return foodLogError(c, err, "Failed to check allergens")
 		}
+		if !check.Safe && !req.AcknowledgeAllergens {
+			return c.JSON(http.StatusConflict, map[string]interface{}{
+				"error":             "Meal contains allergens you are allergic to; set acknowledge_allergens to log it anyway",
+				"allergen_warnings": check.Warnings,
+			})
+		}
+		warnings = check.Warnings
+	}
+
+	halal, err := h.checkHalal(c, userID, req.FoodID, req.RecipeID)
+	if err != nil {
+		return foodLogError(c, err, "Failed to check halal compliance")
 	}
 
-	mealLog := map[string]interface{}{
-		"food_id":   req.FoodID,
-		"recipe_id": req.RecipeID,
-		"meal_type": req.MealType,
-		"quantity":  req.Quantity,
-		"unit":      req.Unit,
-		"date":      mealDate.Format("2006-01-02"),
-		"notes":     req.Notes,
-		"logged_at": time.Now().Format(time.RFC3339),
+	entry, err := h.foodLogService.LogMeal(c.Request().Context(), userID, req)
+	if err != nil {
+		return foodLogError(c, err, "Failed to log meal")
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": "Meal logged successfully",
-		"data":    mealLog,
+		"data":    entry,
+	}
+	if len(warnings) > 0 {
+		response["allergen_warnings"] = warnings
+	}
+	if halal != nil && !halal.IsCompliant {
+		response["halal_compliance"] = halal
+	}
+	return c.JSON(http.StatusCreated, response)
+}
+
+// UpdateMealLog - Action: User edits a logged meal. Its food or recipe is
+// checked again with the user's current halal preferences.
+// PUT /api/v1/actions/log-meal/:id
+func (h *NutritionActionsHandler) UpdateMealLog(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.UpdateFoodLogRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	entry, err := h.foodLogService.UpdateFoodLog(c.Request().Context(), userID, c.Param("id"), req)
+	if err != nil {
+		return foodLogError(c, err, "Failed to update meal")
+	}
+
+	halal, err := h.checkHalal(c, userID, entry.FoodID, entry.RecipeID)
+	if err != nil {
+		return foodLogError(c, err, "Failed to check halal compliance")
+	}
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"message": "Meal updated successfully",
+		"data":    entry,
+	}
+	if halal != nil && !halal.IsCompliant {
+		response["halal_compliance"] = halal
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// checkHalal runs the halal check on a diary entry's food or recipe. It
+// returns nil without a halal service.
+func (h *NutritionActionsHandler) checkHalal(c echo.Context, userID string, foodID, recipeID *string) (*services.ComplianceResult, error) {
+	if h.halalService == nil {
+		return nil, nil
+	}
+	return h.halalService.CheckFoodLog(c.Request().Context(), userID, foodID, recipeID)
+}
+
+// DeleteMealLog - Action: User removes a logged meal
+// DELETE /api/v1/actions/log-meal/:id
+func (h *NutritionActionsHandler) DeleteMealLog(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.foodLogService.DeleteFoodLog(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return foodLogError(c, err, "Failed to delete meal")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Meal deleted successfully",
+	})
+}
+
+// GetMealLogs - Action: User opens the food diary for a day
+// GET /api/v1/actions/meal-logs?date=2024-01-31
+func (h *NutritionActionsHandler) GetMealLogs(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	day := time.Now().UTC()
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "date must be formatted as YYYY-MM-DD",
+			})
+		}
+		day = parsed
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	entries, err := h.foodLogService.ListFoodLogs(c.Request().Context(), userID, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return foodLogError(c, err, "Failed to fetch meals")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   entries,
 	})
 }
 
 // GetNutritionSummary - Action: User clicks "View Nutrition Summary" button
+// GET /api/v1/actions/nutrition-summary?period=day|week&date=2024-01-31
 // GET /api/v1/actions/nutrition-summary?days=7
 func (h *NutritionActionsHandler) GetNutritionSummary(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Parse days parameter (default 7)
-	days := 7
-	if daysStr := c.QueryParam("days"); daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 {
-			days = d
+	day := time.Now().UTC()
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "date must be formatted as YYYY-MM-DD",
+			})
 		}
+		day = parsed
 	}
 
-	summary := map[string]interface{}{
-		"period_days": days,
-		"start_date":  time.Now().AddDate(0, 0, -days).Format("2006-01-02"),
-		"end_date":    time.Now().Format("2006-01-02"),
-		"totals": map[string]interface{}{
-			"calories": 0,
-			"protein":  0,
-			"carbs":    0,
-			"fat":      0,
-			"fiber":    0,
-		},
-		"daily_averages": map[string]interface{}{
-			"calories": 0,
-			"protein":  0,
-			"carbs":    0,
-			"fat":      0,
-		},
-		"meals_logged": 0,
+	ctx := c.Request().Context()
+	var (
+		summary *services.NutritionSummary
+		err     error
+	)
+	switch c.QueryParam("period") {
+	case "day":
+		summary, err = h.foodLogService.GetDailySummary(ctx, userID, day)
+	case "week":
+		summary, err = h.foodLogService.GetWeeklySummary(ctx, userID, day)
+	case "":
+		// Parse days parameter (default 7): the last N days up to and including date
+		days := 7
+		if daysStr := c.QueryParam("days"); daysStr != "" {
+			if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= 366 {
+				days = d
+			}
+		}
+		end := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+		summary, err = h.foodLogService.GetNutritionSummary(ctx, userID, end.AddDate(0, 0, -days), end)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "period must be day or week",
+		})
+	}
+	if err != nil {
+		return foodLogError(c, err, "Failed to build nutrition summary")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -222,3 +378,48 @@ func (h *NutritionActionsHandler) GetMealRecommendations(c echo.Context) error {
 	})
 }
 
+func foodLogError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrFoodLogNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Meal log entry not found",
+		})
+	case errors.Is(err, services.ErrFoodNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Food not found",
+		})
+	case errors.Is(err, services.ErrRecipeNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Recipe not found",
+		})
+	case errors.Is(err, services.ErrInvalidFoodLog):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
+
+func mealPlanError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrMealPlanNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Meal plan not found",
+		})
+	case errors.Is(err, services.ErrInvalidMealPlan), errors.Is(err, services.ErrUnknownRestriction):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrNoEligibleRecipes):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
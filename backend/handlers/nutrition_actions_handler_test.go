@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nutrition-platform/services"
+	"nutrition-platform/validation"
+
+	"github.com/labstack/echo/v4"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB opens an in-memory SQLite database with the given migration files applied
+func openTestDB(t *testing.T, migrations ...string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// A single connection keeps every query on the same in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	for _, name := range migrations {
+		content, err := os.ReadFile(filepath.Join("..", "migrations", name))
+		require.NoError(t, err)
+		_, err = db.Exec(string(content))
+		require.NoError(t, err, "applying %s", name)
+	}
+
+	return db
+}
+
+// newTestHalalService returns a halal service and a user with stored foods
+// to log
+func newTestHalalService(t *testing.T) (*services.HalalService, *sql.DB, string) {
+	t.Helper()
+	db := openTestDB(t, "001_initial_schema_sqlite.sql", "013_add_user_login_security.sql",
+		"014_create_user_sessions_table.sql", "015_create_password_reset_tokens_table.sql",
+		"016_add_two_factor_auth.sql", "017_create_rbac_tables.sql", "018_add_api_key_tiers.sql",
+		"019_create_food_diary.sql", "020_create_meal_plan_days.sql",
+		"021_add_generated_workout_programs.sql", "022_add_food_log_micronutrients.sql",
+		"027_create_halal_preferences.sql")
+	user, err := services.NewUserService(db).CreateUser(context.Background(),
+		services.CreateUserInput{Email: "halal@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO foods (id, name, ingredients, serving_size, serving_unit, calories_per_100g) VALUES
+		('oats', 'Rolled oats', '["oats"]', 40, 'g', 380),
+		('sausage', 'Breakfast sausage', '["pork", "salt"]', 50, 'g', 300)`)
+	require.NoError(t, err)
+
+	compliance, err := services.NewHalalCompliance("../" + services.DefaultHalalBlacklistPath)
+	require.NoError(t, err)
+	return services.NewHalalService(db, compliance), db, user.ID
+}
+
+func TestNutritionActionsHandler_LogMealChecksHalal(t *testing.T) {
+	halal, db, userID := newTestHalalService(t)
+	h := NewNutritionActionsHandler(db, halal, nil)
+	e := echo.New()
+	e.Validator = validation.NewInputValidator()
+	actions := e.Group("/api/v1/actions", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", userID)
+			return next(c)
+		}
+	})
+	actions.POST("/log-meal", h.LogMeal)
+	actions.PUT("/log-meal/:id", h.UpdateMealLog)
+	serve := func(method, target, body string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		e.ServeHTTP(rec, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return rec.Code, response
+	}
+
+	code, response := serve(http.MethodPost, "/api/v1/actions/log-meal",
+		`{"food_id":"oats","meal_type":"breakfast","quantity":1,"unit":"serving"}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.NotContains(t, response, "halal_compliance")
+
+	code, response = serve(http.MethodPost, "/api/v1/actions/log-meal",
+		`{"food_id":"sausage","meal_type":"breakfast","quantity":1,"unit":"serving"}`)
+	require.Equal(t, http.StatusCreated, code)
+	require.Contains(t, response, "halal_compliance")
+	compliance := response["halal_compliance"].(map[string]interface{})
+	assert.Equal(t, false, compliance["is_compliant"])
+	assert.NotEmpty(t, compliance["violations"])
+
+	// Editing the entry checks it again
+	entryID := response["data"].(map[string]interface{})["id"].(string)
+	code, response = serve(http.MethodPut, "/api/v1/actions/log-meal/"+entryID, `{"quantity":2}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, response, "halal_compliance")
+
+	code, _ = serve(http.MethodPost, "/api/v1/actions/log-meal",
+		`{"food_id":"missing","meal_type":"breakfast","quantity":1,"unit":"serving"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -29,34 +29,15 @@ func NewNutritionGoalHandler(db *sql.DB) *NutritionGoalHandler {
 // GetGoals returns all nutrition goals for the current user
 func (h *NutritionGoalHandler) GetGoals(c echo.Context) error {
 	// Get user ID from context
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to uint
-	var userIDUint uint
-	switch v := userID.(type) {
-	case uint:
-		userIDUint = v
-	case string:
-		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
-			userIDUint = uint(id)
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	// Get active goals
-	goals, err := h.userRepo.GetActiveNutritionGoals(userIDUint)
+	goals, err := h.userRepo.GetActiveNutritionGoals(userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch goals: " + err.Error(),
@@ -71,8 +52,8 @@ func (h *NutritionGoalHandler) GetGoals(c echo.Context) error {
 
 // GetGoal returns a specific nutrition goal by ID
 func (h *NutritionGoalHandler) GetGoal(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
@@ -87,7 +68,7 @@ func (h *NutritionGoalHandler) GetGoal(c echo.Context) error {
 	}
 
 	// Get all goals and find the one matching ID
-	goals, err := h.userRepo.GetActiveNutritionGoals(userID.(uint))
+	goals, err := h.userRepo.GetActiveNutritionGoals(userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch goals",
@@ -110,32 +91,13 @@ func (h *NutritionGoalHandler) GetGoal(c echo.Context) error {
 
 // CreateGoal creates a new nutrition goal
 func (h *NutritionGoalHandler) CreateGoal(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to uint
-	var userIDUint uint
-	switch v := userID.(type) {
-	case uint:
-		userIDUint = v
-	case string:
-		if id, err := strconv.ParseUint(v, 10, 32); err == nil {
-			userIDUint = uint(id)
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	var req struct {
 		DailyCalories *int       `json:"daily_calories"`
 		ProteinGrams  *float64   `json:"protein_grams"`
@@ -144,8 +106,9 @@ func (h *NutritionGoalHandler) CreateGoal(c echo.Context) error {
 		FiberGrams    *float64   `json:"fiber_grams"`
 		SugarGrams    *float64   `json:"sugar_grams"`
 		SodiumMg      *int       `json:"sodium_mg"`
+		PotassiumMg   *int       `json:"potassium_mg"`
 		WaterMl       *int       `json:"water_ml"`
-		IsActive      bool       `json:"is_active"`
+		IsActive      *bool      `json:"is_active"`
 		StartDate     *time.Time `json:"start_date"`
 		EndDate       *time.Time `json:"end_date"`
 	}
@@ -156,9 +119,12 @@ func (h *NutritionGoalHandler) CreateGoal(c echo.Context) error {
 		})
 	}
 
+	// Goals are active unless explicitly created inactive
+	isActive := req.IsActive == nil || *req.IsActive
+
 	// Create goal
 	goal := &models.NutritionGoal{
-		UserID:        userIDUint,
+		UserID:        userID,
 		DailyCalories: req.DailyCalories,
 		ProteinGrams:  req.ProteinGrams,
 		CarbsGrams:    req.CarbsGrams,
@@ -166,8 +132,9 @@ func (h *NutritionGoalHandler) CreateGoal(c echo.Context) error {
 		FiberGrams:    req.FiberGrams,
 		SugarGrams:    req.SugarGrams,
 		SodiumMg:      req.SodiumMg,
+		PotassiumMg:   req.PotassiumMg,
 		WaterMl:       req.WaterMl,
-		IsActive:      req.IsActive,
+		IsActive:      isActive,
 		StartDate:     req.StartDate,
 		EndDate:       req.EndDate,
 	}
@@ -188,8 +155,8 @@ func (h *NutritionGoalHandler) CreateGoal(c echo.Context) error {
 
 // UpdateGoal updates an existing nutrition goal
 func (h *NutritionGoalHandler) UpdateGoal(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
@@ -211,6 +178,7 @@ func (h *NutritionGoalHandler) UpdateGoal(c echo.Context) error {
 		FiberGrams    *float64   `json:"fiber_grams"`
 		SugarGrams    *float64   `json:"sugar_grams"`
 		SodiumMg      *int       `json:"sodium_mg"`
+		PotassiumMg   *int       `json:"potassium_mg"`
 		WaterMl       *int       `json:"water_ml"`
 		IsActive      *bool      `json:"is_active"`
 		StartDate     *time.Time `json:"start_date"`
@@ -224,7 +192,7 @@ func (h *NutritionGoalHandler) UpdateGoal(c echo.Context) error {
 	}
 
 	// Get existing goal
-	goals, err := h.userRepo.GetActiveNutritionGoals(userID.(uint))
+	goals, err := h.userRepo.GetActiveNutritionGoals(userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch goals",
@@ -267,6 +235,9 @@ func (h *NutritionGoalHandler) UpdateGoal(c echo.Context) error {
 	if req.SodiumMg != nil {
 		existingGoal.SodiumMg = req.SodiumMg
 	}
+	if req.PotassiumMg != nil {
+		existingGoal.PotassiumMg = req.PotassiumMg
+	}
 	if req.WaterMl != nil {
 		existingGoal.WaterMl = req.WaterMl
 	}
@@ -297,8 +268,8 @@ func (h *NutritionGoalHandler) UpdateGoal(c echo.Context) error {
 
 // DeleteGoal deletes a nutrition goal
 func (h *NutritionGoalHandler) DeleteGoal(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
@@ -313,7 +284,7 @@ func (h *NutritionGoalHandler) DeleteGoal(c echo.Context) error {
 	}
 
 	// Get existing goal and deactivate it
-	goals, err := h.userRepo.GetActiveNutritionGoals(userID.(uint))
+	goals, err := h.userRepo.GetActiveNutritionGoals(userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to fetch goals",
@@ -335,7 +306,7 @@ func (h *NutritionGoalHandler) DeleteGoal(c echo.Context) error {
 	}
 
 	// Delete goal (deactivate)
-	err = h.userRepo.DeleteNutritionGoal(int(goalIDUint), userID.(uint))
+	err = h.userRepo.DeleteNutritionGoal(int(goalIDUint), userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to delete goal: " + err.Error(),
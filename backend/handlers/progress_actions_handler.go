@@ -26,36 +26,13 @@ func NewProgressActionsHandler(db *sql.DB) *ProgressActionsHandler {
 // TrackMeasurement - Action: User clicks "Log Measurement" button
 // POST /api/v1/actions/track-measurement
 func (h *ProgressActionsHandler) TrackMeasurement(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to int64
-	var userIDInt int64
-	switch v := userID.(type) {
-	case uint:
-		userIDInt = int64(v)
-	case int:
-		userIDInt = int64(v)
-	case int64:
-		userIDInt = v
-	case string:
-		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-			userIDInt = id
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	var req struct {
 		MeasurementDate   *time.Time `json:"measurement_date"`
 		Weight            *float64   `json:"weight,omitempty"`
@@ -109,7 +86,7 @@ func (h *ProgressActionsHandler) TrackMeasurement(c echo.Context) error {
 		measurement.MeasurementDate = *req.MeasurementDate
 	}
 
-	result, err := h.progressService.LogMeasurement(c.Request().Context(), uint(userIDInt), measurement)
+	result, err := h.progressService.LogMeasurement(c.Request().Context(), userID, measurement)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
@@ -126,36 +103,13 @@ func (h *ProgressActionsHandler) TrackMeasurement(c echo.Context) error {
 // GetProgressSummary - Action: User clicks "View Progress" button
 // GET /api/v1/actions/progress-summary?days=30
 func (h *ProgressActionsHandler) GetProgressSummary(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to int64
-	var userIDInt int64
-	switch v := userID.(type) {
-	case uint:
-		userIDInt = int64(v)
-	case int:
-		userIDInt = int64(v)
-	case int64:
-		userIDInt = v
-	case string:
-		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-			userIDInt = id
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	// Parse days parameter (default 30)
 	days := 30
 	if daysStr := c.QueryParam("days"); daysStr != "" {
@@ -164,7 +118,7 @@ func (h *ProgressActionsHandler) GetProgressSummary(c echo.Context) error {
 		}
 	}
 
-	summary, err := h.progressService.GetProgressSummary(c.Request().Context(), uint(userIDInt), days)
+	summary, err := h.progressService.GetProgressSummary(c.Request().Context(), userID, days)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to get progress summary: " + err.Error(),
@@ -180,36 +134,13 @@ func (h *ProgressActionsHandler) GetProgressSummary(c echo.Context) error {
 // GetMeasurementHistory - Action: User views measurement history
 // GET /api/v1/actions/measurement-history?page=1&limit=20&start_date=2024-01-01&end_date=2024-12-31
 func (h *ProgressActionsHandler) GetMeasurementHistory(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to int64
-	var userIDInt int64
-	switch v := userID.(type) {
-	case uint:
-		userIDInt = int64(v)
-	case int:
-		userIDInt = int64(v)
-	case int64:
-		userIDInt = v
-	case string:
-		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-			userIDInt = id
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	// Parse pagination
 	page := 1
 	limit := 20
@@ -237,7 +168,7 @@ func (h *ProgressActionsHandler) GetMeasurementHistory(c echo.Context) error {
 		}
 	}
 
-	measurements, total, err := h.progressService.GetMeasurementHistory(c.Request().Context(), uint(userIDInt), page, limit, startDate, endDate)
+	measurements, total, err := h.progressService.GetMeasurementHistory(c.Request().Context(), userID, page, limit, startDate, endDate)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to get measurement history: " + err.Error(),
@@ -259,36 +190,13 @@ func (h *ProgressActionsHandler) GetMeasurementHistory(c echo.Context) error {
 // GetProgressCharts - Action: User views progress charts
 // GET /api/v1/actions/progress-charts?days=30
 func (h *ProgressActionsHandler) GetProgressCharts(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to int64
-	var userIDInt int64
-	switch v := userID.(type) {
-	case uint:
-		userIDInt = int64(v)
-	case int:
-		userIDInt = int64(v)
-	case int64:
-		userIDInt = v
-	case string:
-		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-			userIDInt = id
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	// Parse days parameter (default 30)
 	days := 30
 	if daysStr := c.QueryParam("days"); daysStr != "" {
@@ -297,7 +205,7 @@ func (h *ProgressActionsHandler) GetProgressCharts(c echo.Context) error {
 		}
 	}
 
-	charts, err := h.progressService.GetProgressCharts(c.Request().Context(), uint(userIDInt), days)
+	charts, err := h.progressService.GetProgressCharts(c.Request().Context(), userID, days)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to get progress charts: " + err.Error(),
@@ -313,36 +221,13 @@ func (h *ProgressActionsHandler) GetProgressCharts(c echo.Context) error {
 // CompareMeasurements - Action: User compares measurements between dates
 // POST /api/v1/actions/compare-measurements
 func (h *ProgressActionsHandler) CompareMeasurements(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Convert userID to int64
-	var userIDInt int64
-	switch v := userID.(type) {
-	case uint:
-		userIDInt = int64(v)
-	case int:
-		userIDInt = int64(v)
-	case int64:
-		userIDInt = v
-	case string:
-		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-			userIDInt = id
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
 	var req struct {
 		StartDate string `json:"start_date" validate:"required"`
 		EndDate   string `json:"end_date" validate:"required"`
@@ -368,7 +253,7 @@ func (h *ProgressActionsHandler) CompareMeasurements(c echo.Context) error {
 		})
 	}
 
-	comparison, err := h.progressService.CompareMeasurements(c.Request().Context(), uint(userIDInt), startDate, endDate)
+	comparison, err := h.progressService.CompareMeasurements(c.Request().Context(), userID, startDate, endDate)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to compare measurements: " + err.Error(),
@@ -380,139 +265,3 @@ func (h *ProgressActionsHandler) CompareMeasurements(c echo.Context) error {
 		"data":   comparison,
 	})
 }
-
-// UploadProgressPhoto - Action: User clicks "Upload Progress Photo" button
-// POST /api/v1/actions/upload-progress-photo
-func (h *ProgressActionsHandler) UploadProgressPhoto(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Convert userID to int64
-	var userIDInt int64
-	switch v := userID.(type) {
-	case uint:
-		userIDInt = int64(v)
-	case int:
-		userIDInt = int64(v)
-	case int64:
-		userIDInt = v
-	case string:
-		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-			userIDInt = id
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
-	var req struct {
-		PhotoURL     string    `json:"photo_url" validate:"required"`
-		ThumbnailURL string    `json:"thumbnail_url"`
-		Date         time.Time `json:"date"`
-		Weight       *float64  `json:"weight"`
-		Notes        *string   `json:"notes"`
-	}
-
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request format: " + err.Error(),
-		})
-	}
-
-	// Use current date if not provided
-	if req.Date.IsZero() {
-		req.Date = time.Now()
-	}
-
-	photo := map[string]interface{}{
-		"user_id":       userIDInt,
-		"photo_url":     req.PhotoURL,
-		"thumbnail_url": req.ThumbnailURL,
-		"date":          req.Date.Format("2006-01-02"),
-		"weight":        req.Weight,
-		"notes":         req.Notes,
-		"uploaded_at":   time.Now().Format(time.RFC3339),
-	}
-
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"status":  "success",
-		"message": "Progress photo uploaded successfully",
-		"data":    photo,
-	})
-}
-
-// GetPhotoHistory - Action: User views photo gallery
-// GET /api/v1/actions/photo-history?page=1&limit=20
-func (h *ProgressActionsHandler) GetPhotoHistory(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Convert userID to int64
-	var userIDInt int64
-	switch v := userID.(type) {
-	case uint:
-		userIDInt = int64(v)
-	case int:
-		userIDInt = int64(v)
-	case int64:
-		userIDInt = v
-	case string:
-		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
-			userIDInt = id
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid user ID",
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
-		})
-	}
-
-	// Parse pagination
-	page := 1
-	limit := 20
-	if pageStr := c.QueryParam("page"); pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
-	}
-	if limitStr := c.QueryParam("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	// Use the service to get photo history
-	photos, total, err := h.progressService.GetPhotoHistory(c.Request().Context(), uint(userIDInt), page, limit)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to fetch photo history: " + err.Error(),
-		})
-	}
-
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status": "success",
-		"data":   photos,
-		"pagination": map[string]interface{}{
-			"page":       page,
-			"limit":      limit,
-			"total":      total,
-			"totalPages": (total + int64(limit) - 1) / int64(limit),
-		},
-	})
-}
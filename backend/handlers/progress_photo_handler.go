@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProgressPhotoHandler handles private progress photo uploads
+type ProgressPhotoHandler struct {
+	photoService *services.ProgressPhotoService
+}
+
+func NewProgressPhotoHandler(photoService *services.ProgressPhotoService) *ProgressPhotoHandler {
+	return &ProgressPhotoHandler{
+		photoService: photoService,
+	}
+}
+
+// UploadPhoto - Action: User clicks "Upload Progress Photo" button
+// POST /api/v1/actions/upload-progress-photo (multipart: photo, photo_type, date, measurement_id, weight, notes)
+func (h *ProgressPhotoHandler) UploadPhoto(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.UploadProgressPhotoRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "A photo file is required in the 'photo' field",
+		})
+	}
+
+	photo, err := h.photoService.Upload(c.Request().Context(), userID, file, req)
+	if err != nil {
+		return progressPhotoError(c, err, "Failed to upload progress photo")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  "success",
+		"message": "Progress photo uploaded successfully",
+		"data":    photo,
+	})
+}
+
+// ListPhotos - Action: User views photo gallery
+// GET /api/v1/actions/photo-history?page=1&limit=20
+func (h *ProgressPhotoHandler) ListPhotos(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	page := 1
+	limit := 20
+	if pageStr := c.QueryParam("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	photos, total, err := h.photoService.List(c.Request().Context(), userID, page, limit)
+	if err != nil {
+		return progressPhotoError(c, err, "Failed to fetch photo history")
+	}
+
+	// Signed URLs expire, so the response must not be served from a cache
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   photos,
+		"pagination": map[string]interface{}{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetPhoto returns a photo with freshly signed URLs
+// GET /api/v1/progress/photos/:id
+func (h *ProgressPhotoHandler) GetPhoto(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	photo, err := h.photoService.Get(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return progressPhotoError(c, err, "Failed to fetch progress photo")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   photo,
+	})
+}
+
+// DeletePhoto removes a photo and its stored files
+// DELETE /api/v1/progress/photos/:id
+func (h *ProgressPhotoHandler) DeletePhoto(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.photoService.Delete(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return progressPhotoError(c, err, "Failed to delete progress photo")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Progress photo deleted successfully",
+	})
+}
+
+// ComparePhotos renders a before/after composite of two photos, chosen by ID
+// or as the photos taken closest to two dates
+// GET /api/v1/progress/photos/compare?before_id=&after_id= or ?before_date=&after_date=&photo_type=front
+func (h *ProgressPhotoHandler) ComparePhotos(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.PhotoComparisonRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	image, err := h.photoService.Compare(c.Request().Context(), userID, req)
+	if err != nil {
+		return progressPhotoError(c, err, "Failed to render photo comparison")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Content-Disposition", `inline; filename="progress-comparison.jpg"`)
+	return c.Blob(http.StatusOK, "image/jpeg", image)
+}
+
+// PhotoTimeline renders the photos of one type as an animated GIF
+// GET /api/v1/progress/photos/timeline?photo_type=front&from=&to=&frame_delay=800
+func (h *ProgressPhotoHandler) PhotoTimeline(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.PhotoTimelineRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	image, err := h.photoService.Timeline(c.Request().Context(), userID, req)
+	if err != nil {
+		return progressPhotoError(c, err, "Failed to render photo timeline")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Content-Disposition", `inline; filename="progress-timeline.gif"`)
+	return c.Blob(http.StatusOK, "image/gif", image)
+}
+
+func progressPhotoError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrProgressPhotoNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrFileTooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrFileTypeNotAllowed):
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidProgressPhoto), errors.Is(err, services.ErrInvalidImage):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RBACHandler manages roles and their permissions
+type RBACHandler struct {
+	rbacService *services.RBACService
+}
+
+// NewRBACHandler creates a new RBACHandler instance
+func NewRBACHandler(rbacService *services.RBACService) *RBACHandler {
+	return &RBACHandler{
+		rbacService: rbacService,
+	}
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// GetRoles returns every role with its permissions
+func (h *RBACHandler) GetRoles(c echo.Context) error {
+	roles, err := h.rbacService.ListRoles(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch roles",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"roles": roles,
+		"total": len(roles),
+	})
+}
+
+// GetPermissions returns every grantable permission
+func (h *RBACHandler) GetPermissions(c echo.Context) error {
+	permissions, err := h.rbacService.ListPermissions(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch permissions",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"permissions": permissions,
+		"total":       len(permissions),
+	})
+}
+
+// GrantPermission grants :permission to :role
+func (h *RBACHandler) GrantPermission(c echo.Context) error {
+	err := h.rbacService.GrantPermission(c.Request().Context(), c.Param("role"), c.Param("permission"))
+	if err != nil {
+		return rbacError(c, err, "Failed to grant permission")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Permission granted",
+	})
+}
+
+// RevokePermission revokes :permission from :role
+func (h *RBACHandler) RevokePermission(c echo.Context) error {
+	err := h.rbacService.RevokePermission(c.Request().Context(), c.Param("role"), c.Param("permission"))
+	if err != nil {
+		return rbacError(c, err, "Failed to revoke permission")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Permission revoked",
+	})
+}
+
+// AssignUserRole changes the role of user :id
+func (h *RBACHandler) AssignUserRole(c echo.Context) error {
+	var req AssignRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	if err := h.rbacService.AssignUserRole(c.Request().Context(), c.Param("id"), req.Role); err != nil {
+		return rbacError(c, err, "Failed to assign role")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Role assigned",
+	})
+}
+
+func rbacError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Role not found",
+		})
+	case errors.Is(err, services.ErrPermissionNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Permission not found",
+		})
+	case errors.Is(err, services.ErrUserNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "User not found",
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
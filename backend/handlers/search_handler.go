@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SearchHandler handles unified search across recipes, workouts, complaints and diseases
+type SearchHandler struct {
+	searchService  *services.SearchService
+	halalService   *services.HalalService
+	allergyService *services.AllergyService
+}
+
+func NewSearchHandler(searchService *services.SearchService, halalService *services.HalalService, allergyService *services.AllergyService) *SearchHandler {
+	return &SearchHandler{
+		searchService:  searchService,
+		halalService:   halalService,
+		allergyService: allergyService,
+	}
+}
+
+// Search returns ranked results with highlighted snippets, facet counts and suggestions.
+// With halal=true recipes are checked with the signed-in user's halal
+// preferences, or the defaults for anonymous requests. Recipes containing the
+// signed-in user's allergens are always left out.
+// GET /api/v1/search?q=...&types=recipe,disease&halal=true&page=1&limit=20
+func (h *SearchHandler) Search(c echo.Context) error {
+	var req models.SearchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+	for _, entityType := range strings.Split(c.QueryParam("types"), ",") {
+		if entityType = strings.TrimSpace(entityType); entityType != "" {
+			req.Types = append(req.Types, entityType)
+		}
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	userID, _ := c.Get("user_id").(string)
+	if halal, _ := strconv.ParseBool(c.QueryParam("halal")); halal {
+		prefs, err := h.halalService.Preferences(c.Request().Context(), userID)
+		if err != nil {
+			return searchError(c, err, "Failed to load halal preferences")
+		}
+		req.Halal = prefs
+	}
+	if userID != "" && h.allergyService != nil {
+		allergies, err := h.allergyService.Allergies(c.Request().Context(), userID)
+		if err != nil {
+			return searchError(c, err, "Failed to load allergies")
+		}
+		req.Allergies = allergies
+	}
+
+	results, err := h.searchService.Search(c.Request().Context(), req)
+	if err != nil {
+		return searchError(c, err, "Failed to search")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   results,
+	})
+}
+
+func searchError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidSearch):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
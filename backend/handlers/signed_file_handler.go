@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SignedFileHandler serves privately stored local files through signed URLs
+type SignedFileHandler struct {
+	storage *services.LocalStorageProvider
+}
+
+func NewSignedFileHandler(storage *services.LocalStorageProvider) *SignedFileHandler {
+	return &SignedFileHandler{
+		storage: storage,
+	}
+}
+
+// ServeSignedFile streams a file if its URL signature is valid and unexpired
+// GET /uploads/*?expires=1700000000&signature=...
+func (h *SignedFileHandler) ServeSignedFile(c echo.Context) error {
+	rel := c.Param("*")
+	file, err := h.storage.OpenSignedFile(c.Request().Context(), rel, c.QueryParam("expires"), c.QueryParam("signature"))
+	if errors.Is(err, services.ErrInvalidSignedURL) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "File not found",
+		})
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(rel))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Content-Type", contentType)
+	c.Response().WriteHeader(http.StatusOK)
+	_, err = io.Copy(c.Response(), file)
+	return err
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TwoFactorChallengeResponse is returned by Login when the account has 2FA enabled
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// issueTwoFactorChallenge answers a correct password with a challenge token
+// that must be exchanged at /auth/2fa/verify together with a TOTP code
+func (h *AuthHandler) issueTwoFactorChallenge(c echo.Context, user *models.UserAccount) error {
+	token, err := h.userService.CreateTwoFactorChallenge(c.Request().Context(), user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to start two-factor authentication",
+		})
+	}
+
+	return c.JSON(http.StatusOK, TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(services.TwoFactorChallengeTTL.Seconds()),
+	})
+}
+
+// VerifyTwoFactor completes a login with a TOTP or recovery code
+func (h *AuthHandler) VerifyTwoFactor(c echo.Context) error {
+	var req TwoFactorVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	user, err := h.userService.VerifyTwoFactorChallenge(c.Request().Context(), req.ChallengeToken, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid two-factor code",
+			})
+		case errors.Is(err, services.ErrInvalidTwoFactorChallenge), errors.Is(err, services.ErrTwoFactorNotEnabled):
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid or expired two-factor challenge, please log in again",
+			})
+		case errors.Is(err, services.ErrAccountLocked):
+			return c.JSON(http.StatusLocked, map[string]string{
+				"error": "Account temporarily locked due to too many failed login attempts",
+			})
+		case errors.Is(err, services.ErrAccountDisabled):
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Account is disabled",
+			})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify two-factor code",
+			})
+		}
+	}
+
+	return h.issueTokens(c, http.StatusOK, user, true)
+}
+
+// SetupTwoFactor starts TOTP enrollment and returns the secret and otpauth URI
+func (h *AuthHandler) SetupTwoFactor(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	enrollment, err := h.userService.BeginTwoFactorEnrollment(c.Request().Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Two-factor authentication is already enabled",
+			})
+		case errors.Is(err, services.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "User not found",
+			})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to start two-factor enrollment",
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+// EnableTwoFactor confirms enrollment with a code from the authenticator app
+// and returns the one-time recovery codes
+func (h *AuthHandler) EnableTwoFactor(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	codes, err := h.userService.ConfirmTwoFactorEnrollment(c.Request().Context(), userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Two-factor authentication is already enabled",
+			})
+		case errors.Is(err, services.ErrTwoFactorNotPending):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Start two-factor setup before enabling it",
+			})
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid two-factor code",
+			})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to enable two-factor authentication",
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns off 2FA after verifying a current code
+func (h *AuthHandler) DisableTwoFactor(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	if err := h.userService.DisableTwoFactor(c.Request().Context(), userID, req.Code); err != nil {
+		return twoFactorCodeError(c, err, "Failed to disable two-factor authentication")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after verifying a current code
+func (h *AuthHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	codes, err := h.userService.RegenerateRecoveryCodes(c.Request().Context(), userID, req.Code)
+	if err != nil {
+		return twoFactorCodeError(c, err, "Failed to regenerate recovery codes")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+func twoFactorCodeError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Two-factor authentication is not enabled",
+		})
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid two-factor code",
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
@@ -2,231 +2,171 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
 	"github.com/labstack/echo/v4"
 )
 
 // WaterIntakeHandler handles water intake tracking
 type WaterIntakeHandler struct {
-	db *sql.DB
+	hydrationService *services.HydrationService
 }
 
 // NewWaterIntakeHandler creates a new water intake handler
 func NewWaterIntakeHandler(db *sql.DB) *WaterIntakeHandler {
-	return &WaterIntakeHandler{db: db}
+	return &WaterIntakeHandler{
+		hydrationService: services.NewHydrationService(db),
+	}
 }
 
 // LogWater logs water intake for the current user
+// POST /api/v1/nutrition/water
 func (h *WaterIntakeHandler) LogWater(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	var req struct {
-		AmountMl int       `json:"amount_ml" validate:"required,min=1"`
-		Date     time.Time `json:"date"`
-		Notes    *string   `json:"notes"`
-	}
-
+	var req models.LogWaterRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request format",
+			"error": "Invalid request format: " + err.Error(),
 		})
 	}
 
-	// Use current date if not provided
-	if req.Date.IsZero() {
-		req.Date = time.Now()
-	}
-
-	// Convert userID to string
-	var userIDStr string
-	switch v := userID.(type) {
-	case uint:
-		userIDStr = strconv.FormatUint(uint64(v), 10)
-	case string:
-		userIDStr = v
-	default:
+	if err := c.Validate(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
+			"error": "Validation failed: " + err.Error(),
 		})
 	}
 
-	// Insert water intake record
-	query := `
-		INSERT INTO water_intake (user_id, amount_ml, date, notes, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		RETURNING id, created_at, updated_at
-	`
-
-	var id int
-	var createdAt, updatedAt time.Time
-	err := h.db.QueryRow(query, userIDStr, req.AmountMl, req.Date, req.Notes).Scan(&id, &createdAt, &updatedAt)
+	entry, err := h.hydrationService.LogWater(c.Request().Context(), userID, req)
 	if err != nil {
-		// If table doesn't exist, return stub response
-		return c.JSON(http.StatusCreated, map[string]interface{}{
-			"status":  "success",
-			"message": "Water intake logged successfully",
-			"data": map[string]interface{}{
-				"id":        id,
-				"user_id":   userIDStr,
-				"amount_ml": req.AmountMl,
-				"date":      req.Date,
-				"notes":     req.Notes,
-				"created_at": createdAt,
-				"updated_at": updatedAt,
-			},
-		})
+		return waterIntakeError(c, err, "Failed to log water intake")
 	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"status":  "success",
 		"message": "Water intake logged successfully",
-		"data": map[string]interface{}{
-			"id":         id,
-			"user_id":    userIDStr,
-			"amount_ml":  req.AmountMl,
-			"date":       req.Date,
-			"notes":      req.Notes,
-			"created_at": createdAt,
-			"updated_at": updatedAt,
-		},
+		"data":    entry,
 	})
 }
 
-// GetWaterIntake returns water intake history for the current user
+// GetWaterIntake returns a day or week of water intake against the daily
+// targets, with the user's streaks
+// GET /api/v1/nutrition/water?period=day|week&date=2024-01-31&climate=hot
 func (h *WaterIntakeHandler) GetWaterIntake(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Parse pagination parameters
-	page := 1
-	limit := 20
-	if pageStr := c.QueryParam("page"); pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	day, ok := waterDate(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "date must be formatted as YYYY-MM-DD",
+		})
 	}
-	if limitStr := c.QueryParam("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+
+	history, err := h.hydrationService.GetHistory(c.Request().Context(), userID, c.QueryParam("period"), day, c.QueryParam("climate"))
+	if err != nil {
+		return waterIntakeError(c, err, "Failed to fetch water intake")
 	}
 
-	// Parse date filters
-	startDate := c.QueryParam("start_date")
-	endDate := c.QueryParam("end_date")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   history,
+	})
+}
 
-	// Convert userID to string
-	var userIDStr string
-	switch v := userID.(type) {
-	case uint:
-		userIDStr = strconv.FormatUint(uint64(v), 10)
-	case string:
-		userIDStr = v
-	default:
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid user ID type",
+// GetWaterTarget explains the current user's hydration target for a day
+// GET /api/v1/nutrition/water/target?date=2024-01-31&climate=hot
+func (h *WaterIntakeHandler) GetWaterTarget(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
 		})
 	}
 
-	// Build query
-	query := `
-		SELECT id, user_id, amount_ml, date, notes, created_at, updated_at
-		FROM water_intake
-		WHERE user_id = $1
-	`
-	args := []interface{}{userIDStr}
-	argIndex := 2
-
-	if startDate != "" {
-		query += ` AND date >= $` + strconv.Itoa(argIndex)
-		args = append(args, startDate)
-		argIndex++
+	day, ok := waterDate(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "date must be formatted as YYYY-MM-DD",
+		})
 	}
 
-	if endDate != "" {
-		query += ` AND date <= $` + strconv.Itoa(argIndex)
-		args = append(args, endDate)
-		argIndex++
+	target, err := h.hydrationService.GetDailyTarget(c.Request().Context(), userID, day, c.QueryParam("climate"))
+	if err != nil {
+		return waterIntakeError(c, err, "Failed to compute water target")
 	}
 
-	query += ` ORDER BY date DESC LIMIT $` + strconv.Itoa(argIndex) + ` OFFSET $` + strconv.Itoa(argIndex+1)
-	offset := (page - 1) * limit
-	args = append(args, limit, offset)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   target,
+	})
+}
 
-	rows, err := h.db.Query(query, args...)
-	if err != nil {
-		// If table doesn't exist, return empty array
-		return c.JSON(http.StatusOK, map[string]interface{}{
-			"status": "success",
-			"data":   []interface{}{},
-			"pagination": map[string]interface{}{
-				"page":        page,
-				"limit":       limit,
-				"total":       0,
-				"total_pages": 0,
-				"has_next":    false,
-				"has_prev":    false,
-			},
+// DeleteWater removes one of the current user's water intake entries
+// DELETE /api/v1/nutrition/water/:id
+func (h *WaterIntakeHandler) DeleteWater(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
 		})
 	}
-	defer rows.Close()
-
-	var records []map[string]interface{}
-	for rows.Next() {
-		var id int
-		var userID string
-		var amountMl int
-		var date time.Time
-		var notes sql.NullString
-		var createdAt, updatedAt time.Time
 
-		err := rows.Scan(&id, &userID, &amountMl, &date, &notes, &createdAt, &updatedAt)
-		if err != nil {
-			continue
-		}
-
-		record := map[string]interface{}{
-			"id":         id,
-			"user_id":    userID,
-			"amount_ml":  amountMl,
-			"date":       date,
-			"created_at": createdAt,
-			"updated_at": updatedAt,
-		}
-
-		if notes.Valid {
-			record["notes"] = notes.String
-		}
-
-		records = append(records, record)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": services.ErrWaterIntakeNotFound.Error(),
+		})
 	}
 
-	// Get total count (simplified - in production, use separate count query)
-	total := len(records)
+	if err := h.hydrationService.DeleteWater(c.Request().Context(), userID, id); err != nil {
+		return waterIntakeError(c, err, "Failed to delete water intake")
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status": "success",
-		"data":   records,
-		"pagination": map[string]interface{}{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": (total + limit - 1) / limit,
-			"has_next":    page*limit < total,
-			"has_prev":    page > 1,
-		},
+		"status":  "success",
+		"message": "Water intake deleted successfully",
 	})
 }
 
+// waterDate reads the optional date query parameter, defaulting to today
+func waterDate(c echo.Context) (time.Time, bool) {
+	dateStr := c.QueryParam("date")
+	if dateStr == "" {
+		return time.Now().UTC(), true
+	}
+	day, err := time.Parse("2006-01-02", dateStr)
+	return day, err == nil
+}
+
+func waterIntakeError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrWaterIntakeNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidWaterIntake), errors.Is(err, services.ErrUnknownClimate):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WeightGoalHandler handles weight goals and the weight trend
+type WeightGoalHandler struct {
+	goalService *services.WeightGoalService
+}
+
+// NewWeightGoalHandler creates a new weight goal handler
+func NewWeightGoalHandler(db *sql.DB) *WeightGoalHandler {
+	return &WeightGoalHandler{
+		goalService: services.NewWeightGoalService(db),
+	}
+}
+
+// CreateGoal starts a new weight goal, replacing the active one
+// POST /api/v1/progress/weight-goals
+func (h *WeightGoalHandler) CreateGoal(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateWeightGoalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	goal, err := h.goalService.CreateGoal(c.Request().Context(), userID, req)
+	if err != nil {
+		return weightGoalError(c, err, "Failed to create weight goal")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status":  "success",
+		"message": "Weight goal created successfully",
+		"data":    goal,
+	})
+}
+
+// ListGoals returns the user's weight goals, newest first
+// GET /api/v1/progress/weight-goals
+func (h *WeightGoalHandler) ListGoals(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	goals, err := h.goalService.ListGoals(c.Request().Context(), userID)
+	if err != nil {
+		return weightGoalError(c, err, "Failed to fetch weight goals")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   goals,
+	})
+}
+
+// GetGoal returns one weight goal
+// GET /api/v1/progress/weight-goals/:id
+func (h *WeightGoalHandler) GetGoal(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid weight goal ID",
+		})
+	}
+
+	goal, err := h.goalService.GetGoal(c.Request().Context(), userID, uint(id))
+	if err != nil {
+		return weightGoalError(c, err, "Failed to fetch weight goal")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   goal,
+	})
+}
+
+// UpdateGoal changes a weight goal
+// PUT /api/v1/progress/weight-goals/:id
+func (h *WeightGoalHandler) UpdateGoal(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid weight goal ID",
+		})
+	}
+
+	var req models.UpdateWeightGoalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format: " + err.Error(),
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Validation failed: " + err.Error(),
+		})
+	}
+
+	goal, err := h.goalService.UpdateGoal(c.Request().Context(), userID, uint(id), req)
+	if err != nil {
+		return weightGoalError(c, err, "Failed to update weight goal")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Weight goal updated successfully",
+		"data":    goal,
+	})
+}
+
+// DeleteGoal removes a weight goal
+// DELETE /api/v1/progress/weight-goals/:id
+func (h *WeightGoalHandler) DeleteGoal(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid weight goal ID",
+		})
+	}
+
+	if err := h.goalService.DeleteGoal(c.Request().Context(), userID, uint(id)); err != nil {
+		return weightGoalError(c, err, "Failed to delete weight goal")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Weight goal deleted successfully",
+	})
+}
+
+// GetWeightTrend returns the smoothed trend weight, weekly rate, plateau and
+// the projection of the active goal
+// GET /api/v1/progress/weight-trend?days=90
+func (h *WeightGoalHandler) GetWeightTrend(c echo.Context) error {
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	days := 90
+	if daysStr := c.QueryParam("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= 730 {
+			days = d
+		}
+	}
+
+	analysis, err := h.goalService.Analyze(c.Request().Context(), userID, days)
+	if err != nil {
+		return weightGoalError(c, err, "Failed to analyze weight trend")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   analysis,
+	})
+}
+
+func weightGoalError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrWeightGoalNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidWeightGoal):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fallback,
+		})
+	}
+}
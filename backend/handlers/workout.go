@@ -2,26 +2,31 @@ package handlers
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"nutrition-platform/database"
 	"nutrition-platform/models"
 	"nutrition-platform/repositories"
+	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
 )
 
 // WorkoutHandler handles workout-related requests
 type WorkoutHandler struct {
-	workoutRepo *repositories.WorkoutRepository
+	workoutRepo   *repositories.WorkoutRepository
+	recordService *services.PersonalRecordService
 }
 
 // NewWorkoutHandler creates a new WorkoutHandler instance
 func NewWorkoutHandler(db *sql.DB) *WorkoutHandler {
 	dbWrapper := database.NewDatabase(db)
 	return &WorkoutHandler{
-		workoutRepo: repositories.NewWorkoutRepository(dbWrapper),
+		workoutRepo:   repositories.NewWorkoutRepository(dbWrapper),
+		recordService: services.NewPersonalRecordService(db),
 	}
 }
 
@@ -89,6 +94,19 @@ func (h *WorkoutHandler) LogWorkout(c echo.Context) error {
 		})
 	}
 
+	// The workout is already stored, so a failed record check is only logged
+	if session.Status == "completed" || session.Status == "partial" {
+		performedAt := time.Now()
+		if session.CompletedDate != nil {
+			performedAt = *session.CompletedDate
+		}
+		records, err := h.recordService.RecordWorkout(c.Request().Context(), userIDStr, session.ID, performedAt, session.ExercisesCompleted)
+		if err != nil {
+			log.Printf("Failed to detect personal records for workout %s: %v", session.ID, err)
+		}
+		session.PersonalRecords = records
+	}
+
 	return c.JSON(http.StatusCreated, session)
 }
 
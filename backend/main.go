@@ -6,14 +6,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"nutrition-platform/cache"
 	config "nutrition-platform/config"
-	"nutrition-platform/database"
 	"nutrition-platform/handlers"
 	backendmodels "nutrition-platform/models"
 	"nutrition-platform/security"
@@ -32,7 +33,6 @@ func main() {
 
 	// Initialize database
 	sqlDB := backendmodels.InitDB(cfg.GetDatabaseURL())
-	db := database.NewDatabase(sqlDB)
 	defer func() {
 		if err := backendmodels.Close(); err != nil {
 			log.Printf("Error closing database: %v", err)
@@ -87,15 +87,25 @@ func main() {
 		log.Println("✅ Enhanced rate limiting enabled (Memory-backed)")
 	}
 
+	// Consent-gated GETs are never cached, so withdrawn or expired consent
+	// takes effect on the next request
+	consentGatedPaths := []string{
+		"/api/v1/health/complaints",
+		"/api/v1/health/injuries",
+		"/api/v1/actions/meal-recommendations",
+		"/api/v1/actions/workout-recommendations",
+		"/api/v1/actions/medication-interactions",
+	}
+
 	// Cache middleware (only if Redis is available)
 	if redisCache != nil {
-		skipPaths := []string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register"}
+		skipPaths := append([]string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register"}, consentGatedPaths...)
 		e.Use(cache.CacheMiddleware(redisCache, 5*time.Minute, skipPaths))
 		log.Println("✅ Response caching enabled (Redis)")
 	} else {
 		// Use in-memory cache as fallback
 		cacheConfig := customMiddleware.NewCacheConfig()
-		cacheConfig.SkipPaths = []string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register"}
+		cacheConfig.SkipPaths = append([]string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register"}, consentGatedPaths...)
 		cacheConfig.DefaultTTL = 5 * time.Minute
 		responseCache := customMiddleware.NewResponseCache(cacheConfig)
 		e.Use(responseCache.Middleware())
@@ -107,17 +117,92 @@ func main() {
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(healthService)
 	nutritionPlanHandler := handlers.NewNutritionPlanHandler(nutritionPlanService, healthService)
-	nutritionDataHandler := handlers.NewNutritionDataHandler(sqlDB, "../../nutrition data json")
-	validationHandler := handlers.NewValidationHandler("../../nutrition data json")
+	nutritionDataHandler := handlers.NewNutritionDataHandler(sqlDB, cfg.DataDir)
+	validationHandler := handlers.NewValidationHandler(cfg.DataDir)
+
+	// Initialize disease, injury, and vitamins/minerals handlers from the shared knowledge base
+	knowledgeBase := services.NewKnowledgeBase(cfg.DataDir)
+	knowledgeBase.Watch(services.DefaultKnowledgeReloadInterval)
+	defer knowledgeBase.Close()
+	diseaseHandler := handlers.NewDiseaseHandler(knowledgeBase)
+	injuryHandler := handlers.NewInjuryHandler(knowledgeBase)
+	vitaminsMineralsHandler := handlers.NewVitaminsMineralsHandler(knowledgeBase)
+
+	// One halal compliance engine checks meals, recipe searches and meal plans
+	// with each user's stored preferences
+	halalCompliance, err := services.NewHalalCompliance(services.DefaultHalalBlacklistPath)
+	if err != nil {
+		log.Fatalf("Failed to load halal blacklist: %v", err)
+	}
+	halalService := services.NewHalalService(sqlDB, halalCompliance)
+	halalHandler := handlers.NewHalalHandler(halalService)
 
-	// Initialize disease, injury, and vitamins/minerals handlers
-	diseaseHandler := handlers.NewDiseaseHandler("../../nutrition data json")
-	injuryHandler := handlers.NewInjuryHandler("../../nutrition data json")
-	vitaminsMineralsHandler := handlers.NewVitaminsMineralsHandler("../../nutrition data json")
+	// One allergen registry warns on logged meals, drops recipes from meal
+	// plans and filters searches with each user's allergies
+	allergenRegistry, err := services.NewAllergenRegistry(services.DefaultAllergenRegistryPath)
+	if err != nil {
+		log.Fatalf("Failed to load allergen registry: %v", err)
+	}
+	allergyService := services.NewAllergyService(sqlDB, allergenRegistry)
+	allergyHandler := handlers.NewAllergyHandler(allergyService)
 
-	// Initialize JWT manager and auth handler
+	searchService := services.NewSearchService(sqlDB, knowledgeBase, halalCompliance, allergenRegistry)
+	go func() {
+		if err := searchService.Rebuild(context.Background()); err != nil {
+			log.Printf("Failed to build search index: %v", err)
+		}
+	}()
+	searchService.Watch(services.DefaultSearchRebuildInterval)
+	defer searchService.Close()
+	searchHandler := handlers.NewSearchHandler(searchService, halalService, allergyService)
+
+	// Initialize the question answering pipeline; without its intent model
+	// the endpoint reports 503 instead of keeping the server from starting
+	var intentDetector services.IntentDetector
+	if intentClassifier, err := services.LoadIntentClassifier(services.DefaultIntentTrainingPath); err != nil {
+		log.Printf("Failed to train intent classifier: %v", err)
+	} else {
+		intentDetector = intentClassifier
+	}
+	answerPipeline := services.NewAnswerPipeline(intentDetector,
+		services.NewEntityGazetteer(sqlDB, knowledgeBase),
+		services.NewRecordRetriever(sqlDB, searchService),
+		services.NewAnswerGenerationService())
+	answerHandler := handlers.NewAnswerHandler(answerPipeline)
+	medicationInteractionHandler := handlers.NewMedicationInteractionHandler(sqlDB, cfg.DataDir)
+
+	// Initialize JWT manager, user accounts and auth handler
+	securityConfig := config.LoadSecurityConfig()
 	jwtManager := security.NewJWTManager()
-	authHandler := handlers.NewAuthHandler(nil, jwtManager) // UserService is nil for stub implementation
+	userService := services.NewUserService(sqlDB)
+	userService.SetLockoutPolicy(securityConfig.Auth.MaxLoginAttempts, securityConfig.Auth.LockoutDuration)
+	userService.SetRefreshTokenTTL(securityConfig.Auth.RefreshTokenExpiration)
+	customMiddleware.SetSessionValidator(userService)
+	rbacService := services.NewRBACService(sqlDB)
+	customMiddleware.SetPermissionChecker(rbacService)
+	rbacHandler := handlers.NewRBACHandler(rbacService)
+	apiKeyService := services.NewAPIKeyService(sqlDB)
+	apiKeyService.SetRBAC(rbacService)
+	analyticsService := services.NewAnalyticsService(sqlDB)
+	defer analyticsService.Stop()
+	customMiddleware.SetAPIKeyAuthenticator(apiKeyService)
+	customMiddleware.SetAPIUsageRecorder(analyticsService)
+	if redisClient != nil {
+		customMiddleware.SetAPIKeyQuotaStore(customMiddleware.NewRedisStore(redisClient, "quota:"))
+	} else {
+		customMiddleware.SetAPIKeyQuotaStore(customMiddleware.NewMemoryStore())
+	}
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, analyticsService)
+	mailer, err := services.NewMailer(cfg.EmailConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+	userService.SetPasswordResetMailer(mailer, cfg.EmailConfig.ResetURL)
+	authHandler := handlers.NewAuthHandler(userService, jwtManager)
+	securityManager := security.NewSecurityManager(redisClient, security.SecurityConfig{
+		RequireAdminTwoFactor: securityConfig.Auth.AdminTwoFactorRequired,
+		AdminTwoFactorPaths:   security.DefaultAdminTwoFactorPaths,
+	})
 	userPreferencesHandler := handlers.NewUserPreferencesHandler()
 
 	// Routes
@@ -128,7 +213,7 @@ func main() {
 	auth.POST("/register", authHandler.Register)
 	auth.POST("/login", authHandler.Login)
 	auth.POST("/refresh", authHandler.RefreshToken)
-	auth.POST("/logout-all", handlers.LogoutAll)
+	auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)
 	auth.POST("/forgot-password", authHandler.ForgotPassword)
 	auth.POST("/reset-password", authHandler.ResetPassword)
 
@@ -136,6 +221,7 @@ func main() {
 	protectedAuth := api.Group("/auth")
 	protectedAuth.Use(customMiddleware.JWTAuth())
 	protectedAuth.POST("/logout", authHandler.Logout)
+	protectedAuth.POST("/logout-all", authHandler.LogoutAll)
 	protectedAuth.GET("/profile", authHandler.GetProfile)
 	protectedAuth.GET("/me", authHandler.GetMe) // Alias for /profile (frontend expects /auth/me)
 	protectedAuth.GET("/sessions", authHandler.GetSessions)
@@ -143,6 +229,10 @@ func main() {
 	protectedAuth.PUT("/profile", authHandler.UpdateProfile)
 	protectedAuth.DELETE("/profile", authHandler.DeleteProfile)
 	protectedAuth.POST("/change-password", authHandler.ChangePassword)
+	protectedAuth.POST("/2fa/setup", authHandler.SetupTwoFactor)
+	protectedAuth.POST("/2fa/enable", authHandler.EnableTwoFactor)
+	protectedAuth.POST("/2fa/disable", authHandler.DisableTwoFactor)
+	protectedAuth.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 
 	// User profile routes (aliases for frontend compatibility)
 	users := api.Group("/users")
@@ -163,6 +253,7 @@ func main() {
 	nutritionAPI.POST("/foods", foodHandler.CreateFood)
 	nutritionAPI.PUT("/foods/:id", foodHandler.UpdateFood)
 	nutritionAPI.DELETE("/foods/:id", foodHandler.DeleteFood)
+	nutritionAPI.PUT("/foods/:id/verify", foodHandler.VerifyFood, customMiddleware.RequirePermission(backendmodels.PermissionFoodsVerify))
 
 	// Nutrition Goals endpoints
 	nutritionGoalHandler := handlers.NewNutritionGoalHandler(sqlDB)
@@ -181,16 +272,19 @@ func main() {
 	nutritionAPI.DELETE("/weight/:id", weightHandler.DeleteWeightLog)
 
 	// Meal endpoints route aliases (frontend expects /nutrition/meals)
-	nutritionAPI.GET("/meals", handlers.GetMealsAPI)
-	nutritionAPI.POST("/meals", handlers.CreateMealAPI)
-	nutritionAPI.GET("/meals/:id", handlers.GetMealAPI)
-	nutritionAPI.PUT("/meals/:id", handlers.UpdateMealAPI)
-	nutritionAPI.DELETE("/meals/:id", handlers.DeleteMealAPI)
+	mealsAPIHandler := handlers.NewMealsAPIHandler(halalService)
+	nutritionAPI.GET("/meals", mealsAPIHandler.GetMealsAPI)
+	nutritionAPI.POST("/meals", mealsAPIHandler.CreateMealAPI)
+	nutritionAPI.GET("/meals/:id", mealsAPIHandler.GetMealAPI)
+	nutritionAPI.PUT("/meals/:id", mealsAPIHandler.UpdateMealAPI)
+	nutritionAPI.DELETE("/meals/:id", mealsAPIHandler.DeleteMealAPI)
 
 	// Water intake endpoints
 	waterIntakeHandler := handlers.NewWaterIntakeHandler(sqlDB)
 	nutritionAPI.POST("/water", waterIntakeHandler.LogWater)
 	nutritionAPI.GET("/water", waterIntakeHandler.GetWaterIntake)
+	nutritionAPI.GET("/water/target", waterIntakeHandler.GetWaterTarget)
+	nutritionAPI.DELETE("/water/:id", waterIntakeHandler.DeleteWater)
 
 	// Fitness endpoints (exercises and workouts)
 	exerciseHandler := handlers.NewExerciseHandler(sqlDB)
@@ -213,13 +307,44 @@ func main() {
 	fitness.PUT("/workouts/:id", workoutHandler.UpdateWorkout)
 	fitness.DELETE("/workouts/:id", workoutHandler.DeleteWorkout)
 
-	// Admin auth routes (require JWT authentication)
+	// Admin auth routes (require JWT authentication and a role granting each permission)
 	adminAuth := api.Group("/auth/admin")
 	adminAuth.Use(customMiddleware.JWTAuth())
-	adminAuth.Use(customMiddleware.AdminAuth())
-	adminAuth.GET("/users", authHandler.GetAllUsers)
-	adminAuth.DELETE("/users/:id", authHandler.DeleteUser)
-	adminAuth.GET("/audit-logs", authHandler.GetAuditLogs)
+	adminAuth.Use(securityManager.TwoFactorPolicyMiddleware())
+	adminAuth.GET("/users", authHandler.GetAllUsers, customMiddleware.RequirePermission(backendmodels.PermissionUsersManage))
+	adminAuth.DELETE("/users/:id", authHandler.DeleteUser, customMiddleware.RequirePermission(backendmodels.PermissionUsersManage))
+	adminAuth.GET("/audit-logs", authHandler.GetAuditLogs, customMiddleware.RequirePermission(backendmodels.PermissionAuditRead))
+	adminAuth.GET("/roles", rbacHandler.GetRoles, customMiddleware.RequirePermission(backendmodels.PermissionRolesManage))
+	adminAuth.GET("/permissions", rbacHandler.GetPermissions, customMiddleware.RequirePermission(backendmodels.PermissionRolesManage))
+	adminAuth.PUT("/roles/:role/permissions/:permission", rbacHandler.GrantPermission, customMiddleware.RequirePermission(backendmodels.PermissionRolesManage))
+	adminAuth.DELETE("/roles/:role/permissions/:permission", rbacHandler.RevokePermission, customMiddleware.RequirePermission(backendmodels.PermissionRolesManage))
+	adminAuth.PUT("/users/:id/role", rbacHandler.AssignUserRole, customMiddleware.RequirePermission(backendmodels.PermissionRolesManage))
+	adminAuth.PUT("/api-keys/:id/tier", apiKeyHandler.UpdateAPIKeyTier, customMiddleware.RequirePermission(backendmodels.PermissionAPIKeysManage))
+	adminAuth.GET("/api-keys/:id/statement", apiKeyHandler.AdminGetUsageStatement, customMiddleware.RequirePermission(backendmodels.PermissionAPIKeysManage))
+	adminAuth.POST("/halal/reload", halalHandler.ReloadBlacklist, customMiddleware.RequirePermission(backendmodels.PermissionFoodsVerify))
+	adminAuth.GET("/users/:id/medication-interactions", medicationInteractionHandler.CheckUserInteractions,
+		customMiddleware.RequirePermission(backendmodels.PermissionUsersReadHealth),
+		customMiddleware.RequireSubjectConsent(backendmodels.ConsentHealthData, "id"))
+
+	// API key management routes (keys belong to the authenticated user)
+	apiKeys := api.Group("/api-keys")
+	apiKeys.Use(customMiddleware.JWTAuth())
+	apiKeys.GET("", apiKeyHandler.GetAPIKeys)
+	apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+	apiKeys.GET("/:id", apiKeyHandler.GetAPIKey)
+	apiKeys.PUT("/:id", apiKeyHandler.UpdateAPIKey)
+	apiKeys.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
+	apiKeys.POST("/:id/rotate", apiKeyHandler.RegenerateAPIKey)
+	apiKeys.GET("/:id/statement", apiKeyHandler.GetUsageStatement)
+
+	// Product review routes
+	productHandler := handlers.NewProductHandler(services.NewProductService(sqlDB))
+	productReview := api.Group("/products")
+	productReview.Use(customMiddleware.JWTAuth())
+	productReview.Use(customMiddleware.RequirePermission(backendmodels.PermissionProductsReview))
+	productReview.GET("/pending", productHandler.GetPendingProducts)
+	productReview.POST("/:id/approve", productHandler.ApproveProduct)
+	productReview.POST("/:id/reject", productHandler.RejectProduct)
 
 	// Protected routes (require JWT authentication)
 	protected := api.Group("")
@@ -231,12 +356,17 @@ func main() {
 		})
 	})
 
+	// Special-category health data and AI personalization are only processed
+	// with the user's current consent to the purpose
+	healthDataConsent := customMiddleware.RequireConsent(backendmodels.ConsentHealthData)
+	aiConsent := customMiddleware.RequireConsent(backendmodels.ConsentAIRecommendations)
+
 	// Health routes
 	health := api.Group("/health")
-	health.POST("/complaints", healthHandler.CreateHealthComplaint)
-	health.GET("/complaints", healthHandler.GetUserHealthComplaints)
-	health.POST("/injuries", healthHandler.CreateUserInjury)
-	health.GET("/injuries", healthHandler.GetUserInjuries)
+	health.POST("/complaints", healthHandler.CreateHealthComplaint, customMiddleware.JWTAuth(), healthDataConsent)
+	health.GET("/complaints", healthHandler.GetUserHealthComplaints, customMiddleware.JWTAuth(), healthDataConsent)
+	health.POST("/injuries", healthHandler.CreateUserInjury, customMiddleware.JWTAuth(), healthDataConsent)
+	health.GET("/injuries", healthHandler.GetUserInjuries, customMiddleware.JWTAuth(), healthDataConsent)
 	health.GET("/conditions", healthHandler.GetHealthConditions)
 	health.POST("/assessment", healthHandler.PerformHealthAssessment)
 	health.POST("/risk-assessment", healthHandler.GetHealthRiskAssessment)
@@ -245,36 +375,40 @@ func main() {
 
 	// Nutrition plan routes
 	nutrition := api.Group("/nutrition-plans")
-	nutrition.POST("/recommendations", nutritionPlanHandler.GetNutritionPlanRecommendations)
+	nutrition.POST("/recommendations", nutritionPlanHandler.GetNutritionPlanRecommendations, customMiddleware.JWTAuth(), aiConsent)
 	nutrition.GET("/quick-assessment", nutritionPlanHandler.GetQuickNutritionAssessment)
 	nutrition.POST("/comparison", nutritionPlanHandler.GetNutritionPlanComparison)
 	nutrition.GET("/types", nutritionPlanHandler.GetNutritionPlanTypes)
 	nutrition.GET("/types/:plan_type", nutritionPlanHandler.GetNutritionPlanDetails)
-	nutrition.POST("/personalized", nutritionPlanHandler.CreatePersonalizedNutritionPlan)
+	nutrition.POST("/personalized", nutritionPlanHandler.CreatePersonalizedNutritionPlan, customMiddleware.JWTAuth(), aiConsent)
 
 	// Nutrition data routes
 	api.GET("/metabolism", nutritionDataHandler.GetMetabolism)
 	api.GET("/workout-techniques", nutritionDataHandler.GetWorkouts)
 	api.GET("/meal-plans", nutritionDataHandler.GetRecipes)
-	api.POST("/meal-plans/generate", nutritionDataHandler.GenerateAnswer)
+	api.POST("/meal-plans/generate", answerHandler.GenerateAnswer)
 	api.GET("/drugs-nutrition", nutritionDataHandler.GetDrugsNutrition)
 
-	// API routes
-	api.GET("/meals", handlers.GetMealsAPI)
-	api.POST("/meals", handlers.CreateMealAPI)
-	api.GET("/meals/:id", handlers.GetMealAPI)
-	api.PUT("/meals/:id", handlers.UpdateMealAPI)
-	api.DELETE("/meals/:id", handlers.DeleteMealAPI)
-
-	// Nutrition Data JSON API endpoints
+	// Partner API routes (require an X-API-Key with the meals scope)
+	mealsAPI := api.Group("/meals")
+	mealsAPI.Use(customMiddleware.APIKeyAuth())
+	mealsAPI.GET("", mealsAPIHandler.GetMealsAPI)
+	mealsAPI.POST("", mealsAPIHandler.CreateMealAPI)
+	mealsAPI.GET("/:id", mealsAPIHandler.GetMealAPI)
+	mealsAPI.PUT("/:id", mealsAPIHandler.UpdateMealAPI)
+	mealsAPI.DELETE("/:id", mealsAPIHandler.DeleteMealAPI)
+
+	// Nutrition Data JSON API endpoints (public; partners sending an X-API-Key
+	// are scoped to the nutrition scope and metered)
 	nutritionData := api.Group("/nutrition-data")
+	nutritionData.Use(customMiddleware.OptionalAPIKeyAuth())
 	nutritionData.GET("/recipes", nutritionDataHandler.GetRecipes)
 	nutritionData.GET("/workouts", nutritionDataHandler.GetWorkouts)
 	nutritionData.GET("/complaints", nutritionDataHandler.GetComplaints)
 	nutritionData.GET("/complaints/:id", nutritionDataHandler.GetComplaintByID)
 	nutritionData.GET("/metabolism", nutritionDataHandler.GetMetabolism)
 	nutritionData.GET("/drugs-nutrition", nutritionDataHandler.GetDrugsNutrition)
-	nutritionData.POST("/generate-answer", nutritionDataHandler.GenerateAnswer)
+	nutritionData.POST("/generate-answer", answerHandler.GenerateAnswer)
 
 	// Disease data routes
 	diseaseData := api.Group("/diseases")
@@ -286,7 +420,7 @@ func main() {
 	// Injury data routes
 	injuryData := api.Group("/injuries")
 	injuryData.GET("/", injuryHandler.GetInjuries)
-	injuryData.GET("/:name", injuryHandler.GetInjury)
+	injuryData.GET("/:id", injuryHandler.GetInjury)
 	injuryData.GET("/categories", injuryHandler.GetInjuryCategories)
 	injuryData.GET("/search", injuryHandler.SearchInjuries)
 
@@ -300,10 +434,82 @@ func main() {
 	vitaminsMineralsData.GET("/weight-loss-drugs", vitaminsMineralsHandler.GetWeightLossDrugs)
 	vitaminsMineralsData.GET("/drug-categories", vitaminsMineralsHandler.GetDrugCategories)
 
+	// Unified search across recipes, workouts, complaints and diseases; signed-in
+	// users get halal filtering with their own preferences and never see recipes
+	// containing their allergens
+	api.GET("/search", searchHandler.Search, customMiddleware.OptionalJWTAuth())
+
+	// Halal preferences and compliance checks
+	halal := api.Group("/halal")
+	halal.Use(customMiddleware.JWTAuth())
+	halal.GET("/preferences", halalHandler.GetPreferences)
+	halal.PUT("/preferences", halalHandler.UpdatePreferences)
+	halal.POST("/check", halalHandler.CheckCompliance)
+	halal.GET("/suggestions/:ingredient", halalHandler.GetSuggestions)
+	halal.GET("/additives/:code", halalHandler.GetAdditive)
+	halal.GET("/version", halalHandler.GetVersion)
+
+	// Allergies and allergen checks
+	allergies := api.Group("/allergies")
+	allergies.Use(customMiddleware.JWTAuth())
+	allergies.GET("", allergyHandler.GetAllergies)
+	allergies.PUT("", allergyHandler.UpdateAllergies)
+	allergies.GET("/allergens", allergyHandler.ListAllergens)
+	allergies.POST("/check", allergyHandler.CheckIngredients)
+
+	// Private file storage; local files are only served through signed URLs
+	storageProvider, err := services.NewStorageProvider(cfg.FileStorage)
+	if err != nil {
+		log.Fatalf("Failed to configure file storage: %v", err)
+	}
+	fileStorageService := services.NewFileStorageService(storageProvider, services.NewImageProcessorService())
+	if localStorage, ok := storageProvider.(*services.LocalStorageProvider); ok {
+		if storageURL, err := url.Parse(cfg.FileStorage.BaseURL); err == nil {
+			e.GET(strings.TrimSuffix(storageURL.Path, "/")+"/*", handlers.NewSignedFileHandler(localStorage).ServeSignedFile)
+		}
+	}
+	progressPhotoHandler := handlers.NewProgressPhotoHandler(services.NewProgressPhotoService(sqlDB, fileStorageService))
+
+	// GDPR export, erasure and consents of the signed-in user's data; verified
+	// erasures are hard deleted once their grace period ends
+	gdprCompliance := services.NewGDPRCompliance(sqlDB, fileStorageService)
+	customMiddleware.SetConsentChecker(gdprCompliance)
+	gdprCompliance.SetErasureMailer(mailer, cfg.EmailConfig.ErasureURL)
+	gdprCompliance.Watch(services.DefaultErasureSweepInterval)
+	defer gdprCompliance.Close()
+	gdprHandler := handlers.NewGDPRHandler(gdprCompliance)
+	users.GET("/me/export", gdprHandler.ExportData)
+	users.GET("/me/erasure", gdprHandler.GetErasure)
+	users.POST("/me/erasure", gdprHandler.RequestErasure)
+	users.POST("/me/erasure/verify", gdprHandler.VerifyErasure)
+	users.DELETE("/me/erasure", gdprHandler.CancelErasure)
+	users.GET("/me/consents", gdprHandler.GetConsents)
+	users.GET("/me/consents/history", gdprHandler.GetConsentHistory)
+	users.POST("/me/consents/:purpose", gdprHandler.GrantConsent)
+	users.DELETE("/me/consents/:purpose", gdprHandler.WithdrawConsent)
+	adminAuth.GET("/gdpr/audit", gdprHandler.GetAuditLog, customMiddleware.RequirePermission(backendmodels.PermissionAuditRead))
+
 	// Progress tracking endpoints
 	measurementsHandler := handlers.NewMeasurementsHandler(sqlDB)
+	weightGoalHandler := handlers.NewWeightGoalHandler(sqlDB)
+	achievementHandler := handlers.NewAchievementHandler(sqlDB)
 	progress := api.Group("/progress")
 	progress.Use(customMiddleware.JWTAuth())
+	progress.GET("/photos/compare", progressPhotoHandler.ComparePhotos)
+	progress.GET("/photos/timeline", progressPhotoHandler.PhotoTimeline)
+	progress.GET("/photos/:id", progressPhotoHandler.GetPhoto)
+	progress.DELETE("/photos/:id", progressPhotoHandler.DeletePhoto)
+	progress.GET("/weight-trend", weightGoalHandler.GetWeightTrend)
+	progress.GET("/weight-goals", weightGoalHandler.ListGoals)
+	progress.POST("/weight-goals", weightGoalHandler.CreateGoal)
+	progress.GET("/weight-goals/:id", weightGoalHandler.GetGoal)
+	progress.PUT("/weight-goals/:id", weightGoalHandler.UpdateGoal)
+	progress.DELETE("/weight-goals/:id", weightGoalHandler.DeleteGoal)
+	progress.GET("/personal-records", achievementHandler.ListPersonalRecords)
+	progress.GET("/personal-records/stats", achievementHandler.GetPersonalRecordStats)
+	progress.DELETE("/personal-records/:id", achievementHandler.DeletePersonalRecord)
+	progress.GET("/milestones", achievementHandler.GetMilestones)
+	progress.GET("/achievements", achievementHandler.GetAchievements)
 	progress.GET("/measurements", measurementsHandler.GetMeasurements)
 	progress.POST("/measurements", measurementsHandler.LogMeasurement)
 	progress.GET("/measurements/:id", measurementsHandler.GetMeasurement)
@@ -324,22 +530,34 @@ func main() {
 	actions.GET("/measurement-history", progressActionsHandler.GetMeasurementHistory)
 	actions.GET("/progress-charts", progressActionsHandler.GetProgressCharts)
 	actions.POST("/compare-measurements", progressActionsHandler.CompareMeasurements)
-	actions.POST("/upload-progress-photo", progressActionsHandler.UploadProgressPhoto)
-	actions.GET("/photo-history", progressActionsHandler.GetPhotoHistory)
+	actions.POST("/upload-progress-photo", progressPhotoHandler.UploadPhoto)
+	actions.GET("/photo-history", progressPhotoHandler.ListPhotos)
 
 	// Nutrition actions
-	nutritionActionsHandler := handlers.NewNutritionActionsHandler(sqlDB)
+	nutritionActionsHandler := handlers.NewNutritionActionsHandler(sqlDB, halalService, allergyService)
 	actions.POST("/generate-meal-plan", nutritionActionsHandler.GenerateMealPlan)
+	actions.GET("/meal-plans", nutritionActionsHandler.GetMealPlans)
+	actions.GET("/meal-plans/:id", nutritionActionsHandler.GetMealPlan)
 	actions.POST("/log-meal", nutritionActionsHandler.LogMeal)
+	actions.PUT("/log-meal/:id", nutritionActionsHandler.UpdateMealLog)
+	actions.DELETE("/log-meal/:id", nutritionActionsHandler.DeleteMealLog)
+	actions.GET("/meal-logs", nutritionActionsHandler.GetMealLogs)
 	actions.GET("/nutrition-summary", nutritionActionsHandler.GetNutritionSummary)
-	actions.GET("/meal-recommendations", nutritionActionsHandler.GetMealRecommendations)
+	actions.GET("/meal-recommendations", nutritionActionsHandler.GetMealRecommendations, aiConsent)
 
 	// Fitness actions
-	fitnessActionsHandler := handlers.NewFitnessActionsHandler(db)
+	fitnessActionsHandler := handlers.NewFitnessActionsHandler(sqlDB, gdprCompliance)
 	actions.POST("/generate-workout", fitnessActionsHandler.GenerateWorkout)
+	actions.GET("/workout-programs", fitnessActionsHandler.GetWorkoutPrograms)
+	actions.GET("/workout-programs/:id", fitnessActionsHandler.GetWorkoutProgram)
 	actions.POST("/log-workout", fitnessActionsHandler.LogWorkout)
 	actions.GET("/fitness-summary", fitnessActionsHandler.GetFitnessSummary)
-	actions.GET("/workout-recommendations", fitnessActionsHandler.GetWorkoutRecommendations)
+	actions.GET("/workout-recommendations", fitnessActionsHandler.GetWorkoutRecommendations, aiConsent)
+
+	// Health actions
+	actions.GET("/medication-interactions", medicationInteractionHandler.CheckMyInteractions, healthDataConsent)
+	nutrientDeficiencyHandler := handlers.NewNutrientDeficiencyHandler(sqlDB)
+	actions.POST("/analyze-deficiencies", nutrientDeficiencyHandler.AnalyzeDeficiencies, healthDataConsent)
 
 	// Validation endpoints
 	validation := api.Group("/validation")
@@ -348,11 +566,17 @@ func main() {
 
 	// Health check endpoint
 	e.GET("/health", func(c echo.Context) error {
+		status := "healthy"
+		knowledgeStatus := knowledgeBase.Status()
+		if !knowledgeStatus.Healthy {
+			status = "degraded"
+		}
 		return c.JSON(http.StatusOK, map[string]interface{}{
-			"status":    "healthy",
-			"timestamp": time.Now().UTC(),
-			"service":   "nutrition-platform-backend",
-			"version":   "1.0.0",
+			"status":         status,
+			"timestamp":      time.Now().UTC(),
+			"service":        "nutrition-platform-backend",
+			"version":        "1.0.0",
+			"knowledge_base": knowledgeStatus,
 		})
 	})
 
@@ -371,6 +595,7 @@ func main() {
 				"diseases":          "/api/v1/diseases/*",
 				"injuries":          "/api/v1/injuries/*",
 				"vitamins_minerals": "/api/v1/vitamins-minerals/*",
+				"search":            "/api/v1/search",
 			},
 		})
 	})
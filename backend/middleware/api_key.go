@@ -1,3 +1,244 @@
 package middleware
 
-// API Key middleware stub - to be implemented in Priority 2
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyHeader carries partner API keys
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator resolves a plaintext API key to an active key
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// APIUsageRecorder receives one record per request made with an API key
+type APIUsageRecorder interface {
+	RecordAPIUsage(apiKeyID, endpoint, method string, statusCode int, responseTime int64, ipAddress, userAgent string)
+}
+
+var (
+	apiKeyAuthenticator APIKeyAuthenticator
+	apiUsageRecorder    APIUsageRecorder
+	apiKeyQuotaStore    RateLimiterStore
+)
+
+// SetAPIKeyAuthenticator configures the authenticator used by APIKeyAuth
+func SetAPIKeyAuthenticator(authenticator APIKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// SetAPIUsageRecorder configures where APIKeyAuth reports API key usage
+func SetAPIUsageRecorder(recorder APIUsageRecorder) {
+	apiUsageRecorder = recorder
+}
+
+// SetAPIKeyQuotaStore configures the counters used to enforce the per-minute,
+// daily and monthly quotas of each key's tier. Without a store quotas are not
+// enforced.
+func SetAPIKeyQuotaStore(store RateLimiterStore) {
+	apiKeyQuotaStore = store
+}
+
+// APIKeyAuth authenticates requests with the X-API-Key header. The key must
+// be active, be allowed to call the endpoint with the request method
+// (models.APIKey.CanAccess), hold every scope in scopes and be within the
+// quotas of its tier. Each request is reported to the configured
+// APIUsageRecorder.
+func APIKeyAuth(scopes ...models.APIKeyScope) echo.MiddlewareFunc {
+	return apiKeyAuth(true, scopes)
+}
+
+// OptionalAPIKeyAuth behaves like APIKeyAuth when an X-API-Key header is sent
+// and lets anonymous requests through otherwise. It is used on public data
+// routes so partners are scoped and metered without locking out other clients.
+func OptionalAPIKeyAuth(scopes ...models.APIKeyScope) echo.MiddlewareFunc {
+	return apiKeyAuth(false, scopes)
+}
+
+func apiKeyAuth(required bool, scopes []models.APIKeyScope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(APIKeyHeader)
+			if key == "" {
+				if !required {
+					return next(c)
+				}
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "API key required",
+				})
+			}
+
+			if apiKeyAuthenticator == nil {
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{
+					"error": "API key authentication is not available",
+				})
+			}
+
+			apiKey, err := apiKeyAuthenticator.AuthenticateAPIKey(c.Request().Context(), key)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid or inactive API key",
+				})
+			}
+
+			start := time.Now()
+			err = authorizeAPIKey(c, apiKey, scopes, next)
+			recordAPIUsage(c, apiKey.ID, start, err)
+			return err
+		}
+	}
+}
+
+func authorizeAPIKey(c echo.Context, apiKey *models.APIKey, scopes []models.APIKeyScope, next echo.HandlerFunc) error {
+	for _, scope := range scopes {
+		if !apiKey.HasScope(scope) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "API key is missing scope " + string(scope),
+			})
+		}
+	}
+
+	if !apiKey.CanAccess(c.Request().URL.Path, c.Request().Method) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "API key is not allowed to access this endpoint",
+		})
+	}
+
+	if exceeded := enforceAPIKeyQuota(c, apiKey); exceeded != "" {
+		return c.JSON(http.StatusTooManyRequests, map[string]string{
+			"error": "API key " + exceeded + " quota exceeded",
+			"quota": exceeded,
+		})
+	}
+
+	c.Set("api_key", apiKey)
+	c.Set("api_key_id", apiKey.ID)
+
+	return next(c)
+}
+
+// quotaWindow is one of the counters a request is charged against
+type quotaWindow struct {
+	name       string
+	limit      int
+	identifier string
+	// ttl is the time left until the counter resets, policy the full window
+	ttl, policy time.Duration
+}
+
+func apiKeyQuotaWindows(apiKey *models.APIKey, now time.Time) []quotaWindow {
+	quota := apiKey.Quota()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	nextMonth := month.AddDate(0, 1, 0)
+	prefix := "apikey:" + apiKey.ID
+
+	return []quotaWindow{
+		{"minute", quota.RequestsPerMinute, prefix + ":minute", time.Minute, time.Minute},
+		{"daily", quota.RequestsPerDay, prefix + ":day:" + day.Format("2006-01-02"), day.AddDate(0, 0, 1).Sub(now), 24 * time.Hour},
+		{"monthly", quota.RequestsPerMonth, prefix + ":month:" + month.Format("2006-01"), nextMonth.Sub(now), nextMonth.Sub(month)},
+	}
+}
+
+// enforceAPIKeyQuota charges the request to the quota windows of the key,
+// shortest first, and sets the RateLimit-* headers for the window closest to
+// its limit. Charging stops at the first exceeded window so a rejected
+// request does not use up the longer windows. It returns the name of the
+// exceeded window, or "" if the request is allowed.
+func enforceAPIKeyQuota(c echo.Context, apiKey *models.APIKey) string {
+	if apiKeyQuotaStore == nil {
+		return ""
+	}
+
+	var (
+		policies  []string
+		exceeded  string
+		tightest  quotaWindow
+		remaining = -1
+		resetAt   time.Time
+	)
+
+	for _, window := range apiKeyQuotaWindows(apiKey, time.Now().UTC()) {
+		if window.limit <= 0 {
+			continue
+		}
+		policies = append(policies, fmt.Sprintf("%d;w=%d", window.limit, int64(window.policy.Seconds())))
+		if exceeded != "" {
+			continue
+		}
+
+		allowed, count, reset, err := apiKeyQuotaStore.Allow(c.Request().Context(), window.identifier, window.limit, window.ttl)
+		if err != nil {
+			// If the store fails, log the error but allow the request
+			c.Logger().Error("API key quota store error:", err)
+			continue
+		}
+
+		switch {
+		case !allowed:
+			exceeded = window.name
+			tightest, remaining, resetAt = window, 0, reset
+		case remaining == -1 || window.limit-count < remaining:
+			tightest, remaining, resetAt = window, window.limit-count, reset
+		}
+	}
+
+	if remaining == -1 {
+		return ""
+	}
+
+	resetIn := int64(time.Until(resetAt).Seconds() + 0.5)
+	if resetIn < 0 {
+		resetIn = 0
+	}
+
+	header := c.Response().Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(tightest.limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("RateLimit-Reset", strconv.FormatInt(resetIn, 10))
+	header.Set("RateLimit-Policy", strings.Join(policies, ", "))
+	if exceeded != "" {
+		header.Set("Retry-After", strconv.FormatInt(resetIn, 10))
+	}
+
+	c.Set("rate_limit", tightest.limit)
+	c.Set("rate_remaining", remaining)
+	c.Set("rate_reset", resetAt.Unix())
+
+	return exceeded
+}
+
+func recordAPIUsage(c echo.Context, apiKeyID string, start time.Time, err error) {
+	if apiUsageRecorder == nil {
+		return
+	}
+
+	status := c.Response().Status
+	if err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			status = httpErr.Code
+		} else {
+			status = http.StatusInternalServerError
+		}
+	}
+
+	endpoint := c.Path()
+	if endpoint == "" {
+		endpoint = c.Request().URL.Path
+	}
+
+	apiUsageRecorder.RecordAPIUsage(apiKeyID, endpoint, c.Request().Method, status,
+		time.Since(start).Milliseconds(), c.RealIP(), c.Request().UserAgent())
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPIKeyAuthenticator struct {
+	apiKey *models.APIKey
+}
+
+func (f fakeAPIKeyAuthenticator) AuthenticateAPIKey(context.Context, string) (*models.APIKey, error) {
+	return f.apiKey, nil
+}
+
+func TestAPIKeyAuth_RejectedRequestKeepsLongerQuotas(t *testing.T) {
+	apiKey := &models.APIKey{
+		ID:        "key-1",
+		Status:    models.APIKeyStatusActive,
+		Scopes:    []models.APIKeyScope{models.ScopeMeals, models.ScopeReadOnly},
+		Tier:      models.TierFree,
+		RateLimit: 1,
+	}
+	store := NewMemoryStore()
+	SetAPIKeyAuthenticator(fakeAPIKeyAuthenticator{apiKey: apiKey})
+	SetAPIKeyQuotaStore(store)
+	t.Cleanup(func() {
+		SetAPIKeyAuthenticator(nil)
+		SetAPIKeyQuotaStore(nil)
+	})
+
+	e := echo.New()
+	e.GET("/api/v1/meals", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, APIKeyAuth(models.ScopeMeals))
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/meals", nil)
+		req.Header.Set(APIKeyHeader, "nk_test")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, serve().Code)
+	rec := serve()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quota":"minute"`)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// Only the admitted request counts against the daily and monthly quotas
+	for _, window := range apiKeyQuotaWindows(apiKey, time.Now().UTC())[1:] {
+		entry, ok := store.entries[window.identifier]
+		require.True(t, ok, window.name)
+		assert.Equal(t, 1, entry.count, window.name)
+	}
+}
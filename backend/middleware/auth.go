@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"time"
@@ -9,27 +10,61 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// Claims are carried by access tokens. IsAdmin mirrors Role == "admin"; route
+// authorization should use RequirePermission rather than the flag.
 type Claims struct {
-	UserID  string `json:"user_id"`
-	Email   string `json:"email"`
-	Role    string `json:"role"`
-	IsAdmin bool   `json:"is_admin"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	IsAdmin   bool   `json:"is_admin"`
+	SessionID string `json:"sid,omitempty"`
+	TwoFactor bool   `json:"mfa,omitempty"`
 	jwt.RegisteredClaims
 }
 
 var jwtSecret = []byte("test_secret_key_for_development_32_chars_minimum_length")
 
+// AccessTokenTTL is the lifetime of access tokens bound to a session
+const AccessTokenTTL = 15 * time.Minute
+
+// SessionValidator checks whether a server-side session is still active
+type SessionValidator interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+var sessionValidator SessionValidator
+
+// SetSessionValidator makes JWTAuth reject access tokens whose session was
+// revoked. Once set, tokens without a session are rejected as well.
+func SetSessionValidator(validator SessionValidator) {
+	sessionValidator = validator
+}
+
 // JWTAuth middleware for JWT authentication
 func JWTAuth() echo.MiddlewareFunc {
+	return jwtAuth(true)
+}
+
+// OptionalJWTAuth behaves like JWTAuth when an Authorization header is sent
+// and lets anonymous requests through otherwise. It is used on public routes
+// that personalize their results for signed-in users.
+func OptionalJWTAuth() echo.MiddlewareFunc {
+	return jwtAuth(false)
+}
+
+func jwtAuth(required bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Skip authentication for public routes
-			if isPublicRoute(c.Request().URL.Path) {
+			if required && isPublicRoute(c.Request().URL.Path) {
 				return next(c)
 			}
 
 			auth := c.Request().Header.Get("Authorization")
 			if auth == "" {
+				if !required {
+					return next(c)
+				}
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": "Authorization header required",
 				})
@@ -59,9 +94,32 @@ func JWTAuth() echo.MiddlewareFunc {
 				})
 			}
 
+			if sessionValidator != nil {
+				if claims.SessionID == "" {
+					return c.JSON(http.StatusUnauthorized, map[string]string{
+						"error": "Session required",
+					})
+				}
+
+				active, err := sessionValidator.IsSessionActive(c.Request().Context(), claims.SessionID)
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{
+						"error": "Failed to validate session",
+					})
+				}
+				if !active {
+					return c.JSON(http.StatusUnauthorized, map[string]string{
+						"error": "Session has been revoked or expired",
+					})
+				}
+			}
+
 			// Set user context
 			c.Set("user_id", claims.UserID)
+			c.Set("user_role", claims.Role)
 			c.Set("is_admin", claims.IsAdmin)
+			c.Set("session_id", claims.SessionID)
+			c.Set("two_factor_verified", claims.TwoFactor)
 
 			return next(c)
 		}
@@ -69,6 +127,8 @@ func JWTAuth() echo.MiddlewareFunc {
 }
 
 // AdminAuth middleware for admin-only routes
+//
+// Deprecated: guard routes with RequirePermission so non-admin roles can be granted access.
 func AdminAuth() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -85,13 +145,25 @@ func AdminAuth() echo.MiddlewareFunc {
 
 // GenerateToken generates a JWT token for a user
 func GenerateToken(userID, email, role string, isAdmin bool) (string, error) {
+	return generateToken(userID, email, role, isAdmin, "", false, 24*time.Hour)
+}
+
+// GenerateSessionToken generates a short-lived JWT access token bound to a
+// server-side session. twoFactor records that the session passed a second factor.
+func GenerateSessionToken(userID, email, role string, isAdmin bool, sessionID string, twoFactor bool) (string, error) {
+	return generateToken(userID, email, role, isAdmin, sessionID, twoFactor, AccessTokenTTL)
+}
+
+func generateToken(userID, email, role string, isAdmin bool, sessionID string, twoFactor bool, ttl time.Duration) (string, error) {
 	claims := &Claims{
-		UserID:  userID,
-		Email:   email,
-		Role:    role,
-		IsAdmin: isAdmin,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		IsAdmin:   isAdmin,
+		SessionID: sessionID,
+		TwoFactor: twoFactor,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -101,7 +173,10 @@ func GenerateToken(userID, email, role string, isAdmin bool) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// GenerateRefreshToken generates a refresh token
+// GenerateRefreshToken generates a stateless refresh token.
+//
+// Deprecated: refresh tokens are issued and rotated server-side by
+// services.UserService.CreateSession and RotateRefreshToken.
 func GenerateRefreshToken(userID string) (string, error) {
 	claims := &jwt.RegisteredClaims{
 		Subject:   userID,
@@ -126,7 +201,7 @@ func isPublicRoute(path string) bool {
 		"/api/v1/auth/reset-password",
 		"/api/v1/auth/verify-email",
 		"/api/v1/auth/refresh",
-		"/api/v1/auth/logout-all",
+		"/api/v1/auth/2fa/verify",
 		// Disease routes (all public - serving static JSON data)
 		"/api/v1/diseases",
 		// Injury routes (all public - serving static JSON data)
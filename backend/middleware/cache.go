@@ -160,6 +160,17 @@ func (rc *ResponseCache) shouldSkipCache(c echo.Context) bool {
 		return true
 	}
 
+	// API key requests must reach APIKeyAuth to be authorized and metered
+	if c.Request().Header.Get(APIKeyHeader) != "" {
+		return true
+	}
+
+	// Signed-in requests must reach JWTAuth so revoked sessions and
+	// withdrawn consent take effect at once
+	if c.Request().Header.Get(echo.HeaderAuthorization) != "" {
+		return true
+	}
+
 	return false
 }
 
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ConsentChecker resolves whether a user currently consents to a purpose
+type ConsentChecker interface {
+	HasConsent(ctx context.Context, userID, purpose string) (bool, error)
+}
+
+var consentChecker ConsentChecker
+
+// SetConsentChecker configures the checker used by RequireConsent
+func SetConsentChecker(checker ConsentChecker) {
+	consentChecker = checker
+}
+
+// RequireConsent allows the request only if the authenticated user consents
+// to the current version of purpose and that consent has not expired. It must
+// run after JWTAuth. Without a configured ConsentChecker every request is
+// refused.
+func RequireConsent(purpose string) echo.MiddlewareFunc {
+	return requireConsent(purpose, func(c echo.Context) string {
+		userID, _ := c.Get("user_id").(string)
+		return userID
+	})
+}
+
+// RequireSubjectConsent allows the request only if the user whose ID is the
+// path parameter param currently consents to purpose. It guards routes where
+// staff process another user's data, after their permission is checked.
+func RequireSubjectConsent(purpose, param string) echo.MiddlewareFunc {
+	return requireConsent(purpose, func(c echo.Context) string {
+		return c.Param(param)
+	})
+}
+
+// requireConsent checks the consent to purpose of the user returned by
+// subject
+func requireConsent(purpose string, subject func(echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if userID, _ := c.Get("user_id").(string); userID == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized",
+				})
+			}
+
+			allowed := false
+			if subjectID := subject(c); subjectID != "" && consentChecker != nil {
+				var err error
+				allowed, err = consentChecker.HasConsent(c.Request().Context(), subjectID, purpose)
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{
+						"error": "Failed to check consent",
+					})
+				}
+			}
+
+			if !allowed {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error":   "Consent required",
+					"purpose": purpose,
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
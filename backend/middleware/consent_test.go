@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConsentChecker struct {
+	consents map[string]bool
+	err      error
+}
+
+func (f fakeConsentChecker) HasConsent(_ context.Context, userID, purpose string) (bool, error) {
+	return f.consents[userID+"/"+purpose], f.err
+}
+
+func TestRequireConsent(t *testing.T) {
+	t.Cleanup(func() { SetConsentChecker(nil) })
+	e := echo.New()
+	handler := RequireConsent("health_data")(func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	serve := func(userID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		if userID != "" {
+			c.Set("user_id", userID)
+		}
+		require.NoError(t, handler(c))
+		return rec
+	}
+
+	// Without a checker nothing is let through
+	assert.Equal(t, http.StatusForbidden, serve("user-1").Code)
+
+	SetConsentChecker(fakeConsentChecker{consents: map[string]bool{"user-1/health_data": true}})
+	assert.Equal(t, http.StatusUnauthorized, serve("").Code)
+	assert.Equal(t, http.StatusOK, serve("user-1").Code)
+
+	rec := serve("user-2")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"purpose":"health_data"`)
+
+	SetConsentChecker(fakeConsentChecker{err: errors.New("database is locked")})
+	assert.Equal(t, http.StatusInternalServerError, serve("user-1").Code)
+}
+
+func TestRequireSubjectConsent(t *testing.T) {
+	SetConsentChecker(fakeConsentChecker{consents: map[string]bool{"patient-1/health_data": true}})
+	t.Cleanup(func() { SetConsentChecker(nil) })
+	e := echo.New()
+	e.GET("/api/v1/admin/users/:id/medication-interactions", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", "doctor-1")
+			return next(c)
+		}
+	}, RequireSubjectConsent("health_data", "id"))
+	serve := func(patientID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/users/"+patientID+"/medication-interactions", nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve("patient-1").Code)
+
+	// The patient's consent counts, not the staff member's
+	rec := serve("patient-2")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"purpose":"health_data"`)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PermissionChecker resolves whether a role grants a permission
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+}
+
+var permissionChecker PermissionChecker
+
+// SetPermissionChecker configures the checker used by RequirePermission
+func SetPermissionChecker(checker PermissionChecker) {
+	permissionChecker = checker
+}
+
+// RequirePermission allows the request only if the authenticated user's role
+// grants permission. It must run after JWTAuth. Without a configured
+// PermissionChecker only admins are allowed through.
+func RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("user_role").(string)
+			if role == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized",
+				})
+			}
+
+			allowed := false
+			if permissionChecker != nil {
+				var err error
+				allowed, err = permissionChecker.HasPermission(c.Request().Context(), role, permission)
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{
+						"error": "Failed to check permissions",
+					})
+				}
+			} else {
+				allowed, _ = c.Get("is_admin").(bool)
+			}
+
+			if !allowed {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error":      "Insufficient permissions",
+					"permission": permission,
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+-- Migration: Add login security columns to users
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until DATETIME;
+ALTER TABLE users ADD COLUMN last_login_at DATETIME;
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
@@ -0,0 +1,52 @@
+-- Migration: Create role-based access control tables
+-- users.role names a row in roles; what a role may do is stored in role_permissions.
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT OR IGNORE INTO roles (name, description) VALUES
+    ('admin', 'Full platform administrator'),
+    ('clinic-admin', 'Manages a clinic''s staff, patients and content review'),
+    ('dietitian', 'Reviews nutrition data and assigns plans to patients'),
+    ('coach', 'Assigns workout and nutrition plans'),
+    ('patient', 'Receives care from clinic staff'),
+    ('user', 'Self-service account');
+
+INSERT OR IGNORE INTO permissions (name, description) VALUES
+    ('foods:verify', 'Mark foods as verified'),
+    ('products:review', 'Approve or reject submitted products'),
+    ('plans:assign', 'Assign meal and workout plans to users'),
+    ('users:read-health', 'Read users'' health records'),
+    ('users:manage', 'List, disable and delete user accounts'),
+    ('audit:read', 'Read admin audit logs'),
+    ('roles:manage', 'Change role permissions and user roles');
+
+INSERT OR IGNORE INTO role_permissions (role, permission) VALUES
+    ('clinic-admin', 'foods:verify'),
+    ('clinic-admin', 'products:review'),
+    ('clinic-admin', 'plans:assign'),
+    ('clinic-admin', 'users:read-health'),
+    ('clinic-admin', 'users:manage'),
+    ('clinic-admin', 'audit:read'),
+    ('dietitian', 'foods:verify'),
+    ('dietitian', 'plans:assign'),
+    ('dietitian', 'users:read-health'),
+    ('coach', 'plans:assign');
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_role_permissions_permission ON role_permissions(permission);
@@ -0,0 +1,9 @@
+-- Migration: Add plan tiers to API keys
+-- The tier selects the daily and monthly request quotas enforced by APIKeyAuth.
+ALTER TABLE api_keys ADD COLUMN tier TEXT NOT NULL DEFAULT 'free';
+
+INSERT OR IGNORE INTO permissions (name, description) VALUES
+    ('api-keys:manage', 'Change API key tiers and read usage statements for any key');
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_api_key_usage_key_timestamp ON api_key_usage(api_key_id, timestamp);
@@ -0,0 +1,38 @@
+-- Migration: Food diary entries and nutrition goals
+-- user_food_logs stores the nutrients of each entry as logged, scaled from the
+-- food's per-100g values (or a recipe's per-serving values), so later edits to
+-- the food do not rewrite the diary.
+ALTER TABLE user_food_logs ADD COLUMN recipe_id TEXT REFERENCES recipes(id);
+ALTER TABLE user_food_logs ADD COLUMN name TEXT;
+ALTER TABLE user_food_logs ADD COLUMN grams REAL;
+ALTER TABLE user_food_logs ADD COLUMN fiber REAL;
+ALTER TABLE user_food_logs ADD COLUMN sugar REAL;
+ALTER TABLE user_food_logs ADD COLUMN sodium REAL;
+ALTER TABLE user_food_logs ADD COLUMN potassium REAL;
+ALTER TABLE user_food_logs ADD COLUMN notes TEXT;
+ALTER TABLE user_food_logs ADD COLUMN updated_at DATETIME;
+
+ALTER TABLE foods ADD COLUMN potassium_per_100g REAL;
+
+CREATE TABLE IF NOT EXISTS nutrition_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    daily_calories INTEGER,
+    protein_grams REAL,
+    carbs_grams REAL,
+    fat_grams REAL,
+    fiber_grams REAL,
+    sugar_grams REAL,
+    sodium_mg INTEGER,
+    potassium_mg INTEGER,
+    water_ml INTEGER,
+    is_active INTEGER NOT NULL DEFAULT 1,
+    start_date DATETIME,
+    end_date DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_user_food_logs_user_consumed ON user_food_logs(user_id, consumed_at);
+CREATE INDEX IF NOT EXISTS idx_nutrition_goals_user_active ON nutrition_goals(user_id, is_active);
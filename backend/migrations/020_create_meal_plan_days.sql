@@ -0,0 +1,45 @@
+-- Migration: Generated meal plans
+-- meal_plans keeps one row per plan; the generator's inputs are stored with it
+-- so a plan can be explained and regenerated. Each day and its meals are
+-- stored separately along with the day's nutrient totals.
+ALTER TABLE meal_plans ADD COLUMN plan_type TEXT;
+ALTER TABLE meal_plans ADD COLUMN goal TEXT;
+ALTER TABLE meal_plans ADD COLUMN target_calories INTEGER;
+ALTER TABLE meal_plans ADD COLUMN meals_per_day INTEGER;
+ALTER TABLE meal_plans ADD COLUMN duration_days INTEGER;
+ALTER TABLE meal_plans ADD COLUMN tolerance_percent REAL;
+ALTER TABLE meal_plans ADD COLUMN restrictions TEXT DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS meal_plan_days (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    meal_plan_id TEXT NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+    day_number INTEGER NOT NULL,
+    date TEXT NOT NULL,
+    calories REAL NOT NULL DEFAULT 0,
+    protein REAL NOT NULL DEFAULT 0,
+    carbs REAL NOT NULL DEFAULT 0,
+    fat REAL NOT NULL DEFAULT 0,
+    within_tolerance INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (meal_plan_id, day_number)
+);
+
+-- recipe_id is not a foreign key: recipes come from the recipes table or the
+-- bundled data/recipes.json catalog (recipe_source)
+CREATE TABLE IF NOT EXISTS meal_plan_meals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    meal_plan_day_id INTEGER NOT NULL REFERENCES meal_plan_days(id) ON DELETE CASCADE,
+    meal_number INTEGER NOT NULL,
+    meal_type TEXT NOT NULL,
+    recipe_id TEXT NOT NULL,
+    recipe_source TEXT NOT NULL,
+    recipe_name TEXT NOT NULL,
+    servings REAL NOT NULL,
+    calories REAL NOT NULL DEFAULT 0,
+    protein REAL NOT NULL DEFAULT 0,
+    carbs REAL NOT NULL DEFAULT 0,
+    fat REAL NOT NULL DEFAULT 0
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_meal_plan_days_plan ON meal_plan_days(meal_plan_id);
+CREATE INDEX IF NOT EXISTS idx_meal_plan_meals_day ON meal_plan_meals(meal_plan_day_id);
@@ -0,0 +1,13 @@
+-- Migration: Generated workout programs
+-- workout_programs rows with a user_id are programs generated for that user;
+-- rows without one remain shared templates. Each generated session belongs to
+-- a week and a training day of the program's split.
+ALTER TABLE workout_programs ADD COLUMN user_id TEXT REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE workout_programs ADD COLUMN start_date TEXT;
+
+ALTER TABLE workout_sessions ADD COLUMN week_number INTEGER;
+ALTER TABLE workout_sessions ADD COLUMN day_number INTEGER;
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_workout_programs_user_id ON workout_programs(user_id);
+CREATE INDEX IF NOT EXISTS idx_workout_sessions_program ON workout_sessions(workout_program_id, session_number);
@@ -0,0 +1,6 @@
+-- Migration: Micronutrients in the food diary
+-- Foods carry their vitamins and minerals per 100g as a JSON object keyed by
+-- nutrient (see models.MicronutrientUnits for the units); diary entries store
+-- the amounts scaled to the logged quantity, like the other nutrients.
+ALTER TABLE foods ADD COLUMN micronutrients_per_100g TEXT DEFAULT '{}';
+ALTER TABLE user_food_logs ADD COLUMN micronutrients TEXT DEFAULT '{}';
@@ -0,0 +1,14 @@
+-- Migration: Water intake log
+-- One row per drink. Daily totals, targets and streaks are computed from these
+-- rows when requested, so editing or deleting an entry rewrites the history.
+CREATE TABLE IF NOT EXISTS water_intake (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount_ml INTEGER NOT NULL CHECK (amount_ml > 0),
+    consumed_at DATETIME NOT NULL,
+    notes TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_water_intake_user_consumed ON water_intake(user_id, consumed_at);
@@ -0,0 +1,51 @@
+-- Migration: Body measurements and progress photos
+-- body_measurements was only ever queried, never created, for SQLite. Its
+-- photos column lists the IDs of the progress photos taken with a measurement.
+CREATE TABLE IF NOT EXISTS body_measurements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    measurement_date DATETIME NOT NULL,
+    weight REAL,
+    height REAL,
+    body_fat_percentage REAL,
+    muscle_mass REAL,
+    neck REAL,
+    chest REAL,
+    waist REAL,
+    hips REAL,
+    left_bicep REAL,
+    right_bicep REAL,
+    left_forearm REAL,
+    right_forearm REAL,
+    left_thigh REAL,
+    right_thigh REAL,
+    left_calf REAL,
+    right_calf REAL,
+    notes TEXT,
+    photos TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Progress photos are private. file_url and thumb_url are storage handles
+-- that are only handed out as short-lived signed URLs.
+CREATE TABLE IF NOT EXISTS progress_photos (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    measurement_id INTEGER REFERENCES body_measurements(id) ON DELETE SET NULL,
+    photo_type TEXT NOT NULL DEFAULT 'front' CHECK (photo_type IN ('front', 'side', 'back', 'other')),
+    file_url TEXT NOT NULL,
+    thumb_url TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    notes TEXT,
+    taken_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_body_measurements_user_date ON body_measurements(user_id, measurement_date);
+CREATE INDEX IF NOT EXISTS idx_progress_photos_user_taken ON progress_photos(user_id, taken_at);
+CREATE INDEX IF NOT EXISTS idx_progress_photos_measurement ON progress_photos(measurement_id);
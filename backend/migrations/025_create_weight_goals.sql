@@ -0,0 +1,23 @@
+-- Migration: Weight goals
+-- A user has at most one active goal; starting a new goal closes the previous
+-- one. Progress towards the goal is measured against the smoothed trend of
+-- body_measurements.weight rather than single weigh-ins.
+CREATE TABLE IF NOT EXISTS weight_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    goal_type TEXT NOT NULL CHECK (goal_type IN ('lose', 'gain', 'maintain')),
+    start_weight REAL NOT NULL CHECK (start_weight > 0),
+    target_weight REAL NOT NULL CHECK (target_weight > 0),
+    weekly_goal REAL NOT NULL DEFAULT 0,
+    start_date DATETIME NOT NULL,
+    target_date DATETIME,
+    activity_level TEXT,
+    is_active INTEGER NOT NULL DEFAULT 1,
+    achieved_at DATETIME,
+    notes TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_weight_goals_user ON weight_goals(user_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_weight_goals_one_active ON weight_goals(user_id) WHERE is_active = 1;
@@ -0,0 +1,36 @@
+-- Migration: Personal records and milestones
+-- Every record a workout set is kept, so the newest row of an exercise and
+-- record type is the current best. Milestone rules live in MilestoneService;
+-- a row is stored the first time a user reaches one.
+CREATE TABLE IF NOT EXISTS personal_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    exercise_id TEXT,
+    exercise_name TEXT NOT NULL,
+    record_type TEXT NOT NULL CHECK (record_type IN ('estimated_1rm', 'weight', 'reps', 'volume')),
+    value REAL NOT NULL,
+    unit TEXT NOT NULL,
+    weight REAL,
+    reps INTEGER,
+    previous_value REAL,
+    workout_session_id TEXT REFERENCES user_workout_sessions(id) ON DELETE CASCADE,
+    notes TEXT,
+    achieved_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_records_user ON personal_records(user_id, achieved_at);
+CREATE INDEX IF NOT EXISTS idx_personal_records_exercise ON personal_records(user_id, exercise_id, record_type);
+
+CREATE TABLE IF NOT EXISTS milestones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    milestone_key TEXT NOT NULL,
+    type TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    target_value REAL,
+    achieved_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, milestone_key)
+);
@@ -0,0 +1,16 @@
+-- Migration: Halal preferences
+-- Each user's strictness level and dietary school used by every halal
+-- compliance check. Users without a row get the defaults (moderate, shafi).
+-- With auto_substitute set, violations come back with their suggested halal
+-- substitutions applied.
+CREATE TABLE IF NOT EXISTS halal_preferences (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    strictness_level TEXT NOT NULL DEFAULT 'moderate' CHECK (strictness_level IN ('strict', 'moderate', 'lenient')),
+    dietary_school TEXT NOT NULL DEFAULT 'shafi' CHECK (dietary_school IN ('hanafi', 'shafi', 'maliki', 'hanbali')),
+    auto_substitute INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Substitutions applied to a planned recipe for a user who opted in
+ALTER TABLE meal_plan_meals ADD COLUMN substitutions TEXT NOT NULL DEFAULT '{}';
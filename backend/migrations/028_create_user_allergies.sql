@@ -0,0 +1,13 @@
+-- Migration: User allergies
+-- The allergens each user must avoid, as codes of the allergen registry
+-- (data/allergens.json). Logging a meal containing one needs confirmation and
+-- meal plans and recipe searches leave such recipes out.
+CREATE TABLE IF NOT EXISTS user_allergies (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    allergen TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, allergen)
+);
+
+-- Cross-contact cautions of a planned recipe for the plan owner's allergies
+ALTER TABLE meal_plan_meals ADD COLUMN allergen_warnings TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,53 @@
+-- Migration: GDPR audit log, erasure requests and consent records
+-- The audit log and erasure requests outlive the account they describe, so
+-- their user_id does not reference users; they hold no other personal data.
+CREATE TABLE IF NOT EXISTS gdpr_audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    operation TEXT NOT NULL CHECK (operation IN ('export', 'erasure_requested', 'erasure_verified', 'erasure_cancelled', 'erasure_completed', 'consent_update')),
+    status TEXT NOT NULL CHECK (status IN ('completed', 'failed')),
+    request_id TEXT,
+    ip_address TEXT,
+    user_agent TEXT,
+    data_types TEXT NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    file_size INTEGER NOT NULL DEFAULT 0,
+    checksum TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- An erasure waits for the user to confirm the emailed token, then for the
+-- grace period during which it can be cancelled, before the account is hard
+-- deleted. Only a SHA-256 hash of the token is stored.
+CREATE TABLE IF NOT EXISTS data_deletion_requests (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending_verification', 'scheduled', 'cancelled', 'completed')),
+    reason TEXT,
+    token_hash TEXT UNIQUE NOT NULL,
+    token_expires_at DATETIME NOT NULL,
+    verified_at DATETIME,
+    scheduled_for DATETIME,
+    completed_at DATETIME,
+    cancelled_at DATETIME,
+    error_message TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS consent_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    consent_type TEXT NOT NULL,
+    granted INTEGER NOT NULL,
+    version TEXT NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT,
+    expires_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_gdpr_audit_log_user ON gdpr_audit_log(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_data_deletion_requests_user ON data_deletion_requests(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_data_deletion_requests_due ON data_deletion_requests(status, scheduled_for);
+CREATE INDEX IF NOT EXISTS idx_consent_records_user ON consent_records(user_id, consent_type, created_at);
@@ -0,0 +1,39 @@
+package models
+
+// Allergen warning severities
+const (
+	AllergenDanger  = "danger"  // the food contains the allergen
+	AllergenCaution = "caution" // the food may contain traces through cross-contact
+)
+
+// AllergenWarning flags an ingredient containing, or possibly containing, one
+// of the user's allergens
+type AllergenWarning struct {
+	Allergen     string `json:"allergen"`
+	Name         string `json:"name"`
+	NameAr       string `json:"name_ar,omitempty"`
+	Ingredient   string `json:"ingredient"`
+	Keyword      string `json:"keyword"`
+	Severity     string `json:"severity"`
+	CrossContact bool   `json:"cross_contact"`
+	Message      string `json:"message"`
+}
+
+// UserAllergies lists the allergens a user must avoid
+type UserAllergies struct {
+	UserID    string   `json:"user_id"`
+	Allergens []string `json:"allergens"`
+}
+
+// UpdateAllergiesRequest replaces a user's allergies. Allergens are registry
+// codes such as "milk" or "tree_nuts", or common names such as "dairy" that
+// map to them.
+type UpdateAllergiesRequest struct {
+	Allergens []string `json:"allergens" validate:"max=30,dive,required,max=100"`
+}
+
+// AllergenCheckRequest represents ingredients to check against the user's
+// allergies
+type AllergenCheckRequest struct {
+	Ingredients []string `json:"ingredients" validate:"required,min=1,max=100,dive,required,max=200"`
+}
@@ -0,0 +1,66 @@
+package models
+
+// Answer intents recognised by the intent classifier
+const (
+	AnswerIntentRecipe     = "recipe"
+	AnswerIntentWorkout    = "workout"
+	AnswerIntentHealth     = "health"
+	AnswerIntentDrug       = "drug"
+	AnswerIntentMetabolism = "metabolism"
+	AnswerIntentGeneric    = "generic"
+)
+
+// Entity types found in questions
+const (
+	AnswerEntityFood      = "food"
+	AnswerEntityDrug      = "drug"
+	AnswerEntityCondition = "condition"
+)
+
+// AnswerSourceMedication is the source type of medication records, which are
+// cited alongside the search entity types
+const AnswerSourceMedication = "medication"
+
+// AnswerRequest represents a free-text nutrition question. DataTypes optionally
+// limits the cited sources to recipes, workouts, complaints or drugs.
+type AnswerRequest struct {
+	Query     string   `json:"query" validate:"required,min=1,max=500"`
+	DataTypes []string `json:"data_types"`
+}
+
+// AnswerIntent is one intent with its calibrated probability
+type AnswerIntent struct {
+	Intent      string  `json:"intent"`
+	Probability float64 `json:"probability"`
+}
+
+// AnswerEntity is a known food, drug or condition mentioned in the question
+type AnswerEntity struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Text string `json:"text"` // the words of the question that matched
+}
+
+// AnswerSource is a record cited by an answer as [Ref]
+type AnswerSource struct {
+	Ref     int     `json:"ref"`
+	Type    string  `json:"type"`
+	ID      string  `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+}
+
+// AnswerResponse represents a templated answer with its cited sources. Confidence
+// is the calibrated probability of the detected intent.
+type AnswerResponse struct {
+	Query      string         `json:"query"`
+	Intent     string         `json:"intent"`
+	Intents    []AnswerIntent `json:"intents"`
+	Confidence float64        `json:"confidence"`
+	Entities   []AnswerEntity `json:"entities"`
+	Answer     string         `json:"answer"`
+	TemplateID string         `json:"template_id"`
+	Sources    []AnswerSource `json:"sources"`
+}
@@ -33,6 +33,36 @@ const (
 	ScopeSupplements APIKeyScope = "supplements"
 )
 
+// APIKeyTier is the billing plan of an API key
+type APIKeyTier string
+
+const (
+	TierFree       APIKeyTier = "free"
+	TierPro        APIKeyTier = "pro"
+	TierEnterprise APIKeyTier = "enterprise"
+)
+
+// APIKeyQuota holds the request allowances of a tier. Daily and monthly
+// windows follow UTC calendar days and months.
+type APIKeyQuota struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	RequestsPerDay    int `json:"requests_per_day"`
+	RequestsPerMonth  int `json:"requests_per_month"`
+}
+
+// APIKeyTierQuotas maps each tier to its quotas
+var APIKeyTierQuotas = map[APIKeyTier]APIKeyQuota{
+	TierFree:       {RequestsPerMinute: 60, RequestsPerDay: 1000, RequestsPerMonth: 10000},
+	TierPro:        {RequestsPerMinute: 600, RequestsPerDay: 50000, RequestsPerMonth: 1000000},
+	TierEnterprise: {RequestsPerMinute: 6000, RequestsPerDay: 1000000, RequestsPerMonth: 25000000},
+}
+
+// IsValid reports whether t is a known tier
+func (t APIKeyTier) IsValid() bool {
+	_, ok := APIKeyTierQuotas[t]
+	return ok
+}
+
 // APIKey represents an API key in the system
 type APIKey struct {
 	ID         string                 `json:"id" db:"id"`
@@ -42,6 +72,7 @@ type APIKey struct {
 	UserID     string                 `json:"user_id" db:"user_id"`
 	Status     APIKeyStatus           `json:"status" db:"status"`
 	Scopes     []APIKeyScope          `json:"scopes" db:"scopes"`
+	Tier       APIKeyTier             `json:"tier" db:"tier"`
 	RateLimit  int                    `json:"rate_limit" db:"rate_limit"` // requests per minute
 	ExpiresAt  *time.Time             `json:"expires_at" db:"expires_at"`
 	LastUsedAt *time.Time             `json:"last_used_at" db:"last_used_at"`
@@ -189,6 +220,19 @@ func (ak *APIKey) CanAccess(endpoint, method string) bool {
 	return false
 }
 
+// Quota returns the quotas of the key's tier. The per-minute allowance is
+// the lower of the tier limit and the key's own RateLimit.
+func (ak *APIKey) Quota() APIKeyQuota {
+	quota, ok := APIKeyTierQuotas[ak.Tier]
+	if !ok {
+		quota = APIKeyTierQuotas[TierFree]
+	}
+	if ak.RateLimit > 0 && ak.RateLimit < quota.RequestsPerMinute {
+		quota.RequestsPerMinute = ak.RateLimit
+	}
+	return quota
+}
+
 // UpdateLastUsed updates the last used timestamp
 func (ak *APIKey) UpdateLastUsed() {
 	now := time.Now()
@@ -205,6 +249,15 @@ type CreateAPIKeyRequest struct {
 	Metadata  map[string]interface{} `json:"metadata"`
 }
 
+// UpdateAPIKeyRequest represents a partial update of an API key. Nil fields
+// are left unchanged.
+type UpdateAPIKeyRequest struct {
+	Name      *string                `json:"name" validate:"omitempty,min=3,max=100"`
+	Scopes    []APIKeyScope          `json:"scopes" validate:"omitempty,min=1"`
+	RateLimit *int                   `json:"rate_limit" validate:"omitempty,min=1,max=10000"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
 // CreateAPIKeyResponse represents the response when creating an API key
 type CreateAPIKeyResponse struct {
 	APIKey  *APIKey `json:"api_key"`
@@ -212,6 +265,11 @@ type CreateAPIKeyResponse struct {
 	Warning string  `json:"warning,omitempty"`
 }
 
+// UpdateAPIKeyTierRequest changes the plan tier of an API key
+type UpdateAPIKeyTierRequest struct {
+	Tier APIKeyTier `json:"tier" validate:"required,oneof=free pro enterprise"`
+}
+
 // APIKeyListResponse represents a list of API keys
 type APIKeyListResponse struct {
 	APIKeys []APIKey `json:"api_keys"`
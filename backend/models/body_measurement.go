@@ -10,7 +10,7 @@ import (
 // BodyMeasurement represents a user's body measurement entry
 type BodyMeasurement struct {
 	ID                uint      `json:"id" db:"id"`
-	UserID            uint      `json:"user_id" db:"user_id"`
+	UserID            string    `json:"user_id" db:"user_id"`
 	MeasurementDate   time.Time `json:"measurement_date" db:"measurement_date"`
 	Weight            *float64  `json:"weight,omitempty" db:"weight"`
 	Height            *float64  `json:"height,omitempty" db:"height"`
@@ -51,7 +51,7 @@ func (m *BodyMeasurement) Validate() error {
 	return nil
 }
 
-// PhotoList is a custom type for handling JSON arrays of photo URLs
+// PhotoList is a custom type for handling JSON arrays of progress photo IDs
 type PhotoList []string
 
 // Value implements the driver.Valuer interface for PhotoList
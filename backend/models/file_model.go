@@ -89,21 +89,72 @@ type MealPhoto struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// ProgressPhoto represents before/after progress photos
+// Poses a progress photo can be taken in
+const (
+	PhotoTypeFront = "front"
+	PhotoTypeSide  = "side"
+	PhotoTypeBack  = "back"
+	PhotoTypeOther = "other"
+)
+
+// ProgressPhoto represents before/after progress photos. The stored files are
+// private: URL and ThumbnailURL are signed links that expire at URLExpiresAt.
 type ProgressPhoto struct {
-	ID          string    `json:"id" db:"id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	FileID      string    `json:"file_id" db:"file_id"`
-	FileURL     string    `json:"file_url" db:"file_url"`
-	ThumbURL    string    `json:"thumb_url" db:"thumb_url"`
-	PhotoType   string    `json:"photo_type" db:"photo_type"` // before, after, side, front, back
-	Weight      *float64  `json:"weight,omitempty" db:"weight"`
-	BodyFat     *float64  `json:"body_fat,omitempty" db:"body_fat"`
-	Measurements *string  `json:"measurements,omitempty" db:"measurements"` // JSON string
-	Notes       string    `json:"notes,omitempty" db:"notes"`
-	TakenAt     time.Time `json:"taken_at" db:"taken_at"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID            string     `json:"id" db:"id"`
+	UserID        string     `json:"user_id" db:"user_id"`
+	MeasurementID *uint      `json:"measurement_id,omitempty" db:"measurement_id"`
+	PhotoType     string     `json:"photo_type" db:"photo_type"` // front, side, back, other
+	FileURL       string     `json:"-" db:"file_url"`
+	ThumbURL      string     `json:"-" db:"thumb_url"`
+	ContentType   string     `json:"content_type" db:"content_type"`
+	Size          int64      `json:"size" db:"size"`
+	Width         int        `json:"width" db:"width"`
+	Height        int        `json:"height" db:"height"`
+	Notes         *string    `json:"notes,omitempty" db:"notes"`
+	TakenAt       time.Time  `json:"taken_at" db:"taken_at"`
+	URL           string     `json:"url,omitempty"`
+	ThumbnailURL  string     `json:"thumbnail_url,omitempty"`
+	URLExpiresAt  *time.Time `json:"url_expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// UploadProgressPhotoRequest holds the form fields sent with a progress photo.
+// The photo joins MeasurementID, or else the measurement taken on Date, which
+// is created with Weight when the user has not logged one that day.
+type UploadProgressPhotoRequest struct {
+	PhotoType     string  `form:"photo_type" json:"photo_type"`
+	Date          string  `form:"date" json:"date"` // YYYY-MM-DD, defaults to today
+	MeasurementID uint    `form:"measurement_id" json:"measurement_id,omitempty"`
+	Weight        float64 `form:"weight" json:"weight,omitempty" validate:"min=0,max=500"`
+	Notes         string  `form:"notes" json:"notes,omitempty" validate:"max=1000"`
+}
+
+// PhotoComparisonRequest selects the two photos of a before/after composite,
+// either by ID or as the photos taken closest to BeforeDate and AfterDate.
+// Width, Height and ResizeMode size each panel like ImageProcessingOptions.
+type PhotoComparisonRequest struct {
+	BeforeID   string `query:"before_id" json:"before_id,omitempty"`
+	AfterID    string `query:"after_id" json:"after_id,omitempty"`
+	BeforeDate string `query:"before_date" json:"before_date,omitempty"` // YYYY-MM-DD
+	AfterDate  string `query:"after_date" json:"after_date,omitempty"`   // YYYY-MM-DD
+	PhotoType  string `query:"photo_type" json:"photo_type,omitempty"`
+	Width      int    `query:"width" json:"width,omitempty" validate:"omitempty,min=100,max=1200"`
+	Height     int    `query:"height" json:"height,omitempty" validate:"omitempty,min=100,max=1600"`
+	ResizeMode string `query:"resize_mode" json:"resize_mode,omitempty" validate:"omitempty,oneof=fit fill"`
+}
+
+// PhotoTimelineRequest selects the photos of an animated timeline. Frames
+// shows each photo for FrameDelay milliseconds.
+type PhotoTimelineRequest struct {
+	From       string `query:"from" json:"from,omitempty"` // YYYY-MM-DD
+	To         string `query:"to" json:"to,omitempty"`     // YYYY-MM-DD
+	PhotoType  string `query:"photo_type" json:"photo_type,omitempty"`
+	Width      int    `query:"width" json:"width,omitempty" validate:"omitempty,min=100,max=800"`
+	Height     int    `query:"height" json:"height,omitempty" validate:"omitempty,min=100,max=1000"`
+	ResizeMode string `query:"resize_mode" json:"resize_mode,omitempty" validate:"omitempty,oneof=fit fill"`
+	FrameDelay int    `query:"frame_delay" json:"frame_delay,omitempty" validate:"omitempty,min=100,max=10000"`
+	MaxFrames  int    `query:"max_frames" json:"max_frames,omitempty" validate:"omitempty,min=2,max=60"`
 }
 
 // RecipeImage represents images for recipes
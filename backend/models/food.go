@@ -2,6 +2,8 @@ package models
 
 import (
 	"nutrition-platform/errors"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -33,6 +35,9 @@ type Food struct {
 	Verified     bool      `json:"verified" db:"verified"` // Repository uses Verified
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+
+	// Micronutrients per 100g, keyed as in MicronutrientUnits
+	Micronutrients map[string]float64 `json:"micronutrients,omitempty" db:"micronutrients_per_100g"`
 }
 
 // FoodSearchFilters represents filters for food search
@@ -121,8 +126,76 @@ func (f *Food) CalculateNutrition(quantity float64) *NutritionInfo {
 		Fiber:         f.Fiber * scale,
 		Sugar:         f.Sugar * scale,
 		Sodium:        float64(f.Sodium) * scale, // Convert int to float64
+		Potassium:     f.Potassium * scale,
 		// VitaminC, Calcium, Iron removed as they're not in the updated model
+
+		Micronutrients: scaleMicronutrients(f.Micronutrients, scale),
+	}
+}
+
+// scaleMicronutrients multiplies every amount by scale, returning nil when
+// there is nothing to scale
+func scaleMicronutrients(amounts map[string]float64, scale float64) map[string]float64 {
+	if len(amounts) == 0 {
+		return nil
+	}
+	scaled := make(map[string]float64, len(amounts))
+	for nutrient, amount := range amounts {
+		scaled[nutrient] = amount * scale
+	}
+	return scaled
+}
+
+// gramsPerUnit converts household and metric units to grams. Volumes assume
+// the density of water, which is close enough for diary entries.
+var gramsPerUnit = map[string]float64{
+	"g":      1,
+	"gram":   1,
+	"grams":  1,
+	"kg":     1000,
+	"mg":     0.001,
+	"oz":     28.3495,
+	"lb":     453.592,
+	"ml":     1,
+	"l":      1000,
+	"cup":    240,
+	"cups":   240,
+	"tbsp":   15,
+	"tsp":    5,
+	"fl_oz":  29.5735,
+	"fl oz":  29.5735,
+	"liter":  1000,
+	"litre":  1000,
+	"pound":  453.592,
+	"ounce":  28.3495,
+	"ounces": 28.3495,
+}
+
+// QuantityInGrams converts a quantity in unit to grams. "serving" and
+// "servings" use the food's serving size.
+func (f *Food) QuantityInGrams(quantity float64, unit string) (float64, error) {
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	if unit == "" {
+		unit = "g"
+	}
+
+	if unit == "serving" || unit == "servings" {
+		size, err := strconv.ParseFloat(strings.TrimSpace(f.ServingSize), 64)
+		if err != nil || size <= 0 {
+			return 0, errors.ErrInvalidInputError("food has no serving size")
+		}
+		perServing, ok := gramsPerUnit[strings.ToLower(strings.TrimSpace(f.ServingUnit))]
+		if !ok {
+			perServing = 1
+		}
+		return quantity * size * perServing, nil
+	}
+
+	factor, ok := gramsPerUnit[unit]
+	if !ok {
+		return 0, errors.ErrInvalidInputError("unsupported unit: " + unit)
 	}
+	return quantity * factor, nil
 }
 
 // Helper function
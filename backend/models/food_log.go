@@ -5,16 +5,58 @@ import (
 	"time"
 )
 
-// UserFoodLog represents a food logging entry by a user
+// UserFoodLog represents a food diary entry. Nutrients are stored as logged,
+// already scaled to the entry's quantity.
 type UserFoodLog struct {
-	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    int       `json:"user_id" gorm:"not null;index" validate:"required,min=1"`
-	FoodID    int       `json:"food_id" gorm:"not null;index" validate:"required,min=1"`
-	Quantity  float64   `json:"quantity" gorm:"not null" validate:"required,min=0"` // grams
-	MealType  string    `json:"meal_type" gorm:"not null" validate:"required,oneof=breakfast lunch dinner snack"`
-	LoggedAt  time.Time `json:"logged_at" gorm:"not null;index" validate:"required"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	FoodID     *string    `json:"food_id,omitempty" db:"food_id"`
+	RecipeID   *string    `json:"recipe_id,omitempty" db:"recipe_id"`
+	Name       string     `json:"name" db:"name"`
+	Quantity   float64    `json:"quantity" db:"quantity"`
+	Unit       string     `json:"unit" db:"unit"`
+	Grams      *float64   `json:"grams,omitempty" db:"grams"`
+	MealType   string     `json:"meal_type" db:"meal_type"`
+	ConsumedAt time.Time  `json:"consumed_at" db:"consumed_at"`
+	Calories   float64    `json:"calories" db:"calories"`
+	Protein    float64    `json:"protein" db:"protein"`
+	Carbs      float64    `json:"carbs" db:"carbs"`
+	Fat        float64    `json:"fat" db:"fat"`
+	Fiber      float64    `json:"fiber" db:"fiber"`
+	Sugar      float64    `json:"sugar" db:"sugar"`
+	Sodium     float64    `json:"sodium" db:"sodium"`
+	Potassium  float64    `json:"potassium" db:"potassium"`
+	Notes      *string    `json:"notes,omitempty" db:"notes"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+
+	// Micronutrients are keyed as in MicronutrientUnits
+	Micronutrients map[string]float64 `json:"micronutrients,omitempty" db:"micronutrients"`
+}
+
+// LogMealRequest represents a request to add a food or recipe to the diary
+type LogMealRequest struct {
+	FoodID   *string `json:"food_id,omitempty"`
+	RecipeID *string `json:"recipe_id,omitempty"`
+	MealType string  `json:"meal_type" validate:"required,oneof=breakfast lunch dinner snack"`
+	Quantity float64 `json:"quantity" validate:"required,gt=0"`
+	Unit     string  `json:"unit" validate:"required"`
+	Date     string  `json:"date,omitempty"` // YYYY-MM-DD format
+	Notes    *string `json:"notes,omitempty"`
+
+	// AcknowledgeAllergens logs a meal containing the user's allergens after
+	// they confirmed the warnings
+	AcknowledgeAllergens bool `json:"acknowledge_allergens,omitempty"`
+}
+
+// UpdateFoodLogRequest represents a request to edit a diary entry. Changing
+// the quantity or unit rescales the entry's nutrients.
+type UpdateFoodLogRequest struct {
+	MealType *string  `json:"meal_type,omitempty" validate:"omitempty,oneof=breakfast lunch dinner snack"`
+	Quantity *float64 `json:"quantity,omitempty" validate:"omitempty,gt=0"`
+	Unit     *string  `json:"unit,omitempty"`
+	Date     *string  `json:"date,omitempty"` // YYYY-MM-DD format
+	Notes    *string  `json:"notes,omitempty"`
 }
 
 // TableName returns the table name for the UserFoodLog model
@@ -24,16 +66,16 @@ func (UserFoodLog) TableName() string {
 
 // Validate validates the food log model
 func (log *UserFoodLog) Validate() error {
-	if log.UserID <= 0 {
-		return errors.ErrInvalidInputError("user_id must be greater than 0")
+	if log.UserID == "" {
+		return errors.ErrInvalidInputError("user_id is required")
 	}
-	if log.FoodID <= 0 {
-		return errors.ErrInvalidInputError("food_id must be greater than 0")
+	if (log.FoodID == nil) == (log.RecipeID == nil) {
+		return errors.ErrInvalidInputError("exactly one of food_id or recipe_id is required")
 	}
-	if log.Quantity < 0 {
-		return errors.ErrInvalidInputError("quantity cannot be negative")
+	if log.Quantity <= 0 {
+		return errors.ErrInvalidInputError("quantity must be greater than 0")
 	}
-	if log.Quantity > 10000 {
+	if log.Grams != nil && *log.Grams > 10000 {
 		return errors.ErrInvalidInputError("quantity cannot exceed 10000 grams")
 	}
 	validMealTypes := []string{"breakfast", "lunch", "dinner", "snack"}
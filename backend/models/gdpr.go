@@ -0,0 +1,122 @@
+package models
+
+import "time"
+
+// Erasure request statuses
+const (
+	ErasurePendingVerification = "pending_verification" // waiting for the emailed token
+	ErasureScheduled           = "scheduled"            // verified, hard delete after the grace period
+	ErasureCancelled           = "cancelled"
+	ErasureCompleted           = "completed"
+)
+
+// GDPR audit log operations
+const (
+	GDPROperationExport           = "export"
+	GDPROperationErasureRequested = "erasure_requested"
+	GDPROperationErasureVerified  = "erasure_verified"
+	GDPROperationErasureCancelled = "erasure_cancelled"
+	GDPROperationErasureCompleted = "erasure_completed"
+	GDPROperationConsentUpdate    = "consent_update"
+)
+
+// GDPR audit log statuses
+const (
+	GDPRStatusCompleted = "completed"
+	GDPRStatusFailed    = "failed"
+)
+
+// Consent purposes users grant or withdraw
+const (
+	ConsentHealthData        = "health_data"        // health complaints, injuries, medications and supplements
+	ConsentAIRecommendations = "ai_recommendations" // personalized meal, workout and nutrition plan recommendations
+	ConsentMarketing         = "marketing"
+	ConsentResearch          = "research"
+)
+
+// Consent states of a purpose; only ConsentGranted allows processing
+const (
+	ConsentGranted   = "granted"
+	ConsentWithdrawn = "withdrawn"
+	ConsentExpired   = "expired"
+	ConsentOutdated  = "outdated" // given to an earlier version of the purpose
+	ConsentMissing   = "missing"
+)
+
+// GDPRAuditEntry records one export, erasure or consent operation on a
+// user's data
+type GDPRAuditEntry struct {
+	ID           int64     `json:"id" db:"id"`
+	UserID       string    `json:"user_id" db:"user_id"`
+	Operation    string    `json:"operation" db:"operation"`
+	Status       string    `json:"status" db:"status"`
+	RequestID    string    `json:"request_id,omitempty" db:"request_id"`
+	IPAddress    string    `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent    string    `json:"user_agent,omitempty" db:"user_agent"`
+	DataTypes    []string  `json:"data_types" db:"data_types"`
+	ErrorMessage string    `json:"error_message,omitempty" db:"error_message"`
+	FileSize     int64     `json:"file_size,omitempty" db:"file_size"`
+	Checksum     string    `json:"checksum,omitempty" db:"checksum"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// DataDeletionRequest is a user's request to erase their account and data
+type DataDeletionRequest struct {
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	Status         string     `json:"status" db:"status"`
+	Reason         string     `json:"reason,omitempty" db:"reason"`
+	TokenExpiresAt time.Time  `json:"token_expires_at" db:"token_expires_at"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+	ScheduledFor   *time.Time `json:"scheduled_for,omitempty" db:"scheduled_for"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	ErrorMessage   string     `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ConsentRecord is one grant or withdrawal of consent
+type ConsentRecord struct {
+	ID          int64      `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	ConsentType string     `json:"consent_type" db:"consent_type"`
+	Granted     bool       `json:"granted" db:"granted"`
+	Version     string     `json:"version" db:"version"`
+	IPAddress   string     `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent   string     `json:"user_agent,omitempty" db:"user_agent"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ConsentPurpose is a reason for processing personal data that users consent
+// to. Version changes with its terms, and consent given to an earlier version
+// must be given again.
+type ConsentPurpose struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Version      string `json:"version"`
+	ValidDays    int    `json:"valid_days"`
+	OnWithdrawal string `json:"on_withdrawal"`
+}
+
+// ConsentStatus is a user's current consent to one purpose
+type ConsentStatus struct {
+	Purpose   ConsentPurpose `json:"purpose"`
+	State     string         `json:"state"`
+	Active    bool           `json:"active"`
+	Version   string         `json:"version,omitempty"`
+	UpdatedAt *time.Time     `json:"updated_at,omitempty"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+}
+
+// RequestErasureRequest starts the erasure of the signed-in user's account
+type RequestErasureRequest struct {
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// VerifyErasureRequest confirms an erasure with the token emailed to the user
+type VerifyErasureRequest struct {
+	Token string `json:"token" validate:"required,max=200"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Halal strictness levels
+const (
+	HalalStrict   = "strict"   // ingredients needing certification are violations
+	HalalModerate = "moderate" // ingredients needing certification are warnings
+	HalalLenient  = "lenient"  // only definite violations are reported
+)
+
+// Defaults for users who have not saved halal preferences
+const (
+	DefaultHalalStrictness    = HalalModerate
+	DefaultHalalDietarySchool = "shafi"
+)
+
+// HalalPreferences are a user's settings for halal compliance checks
+type HalalPreferences struct {
+	UserID          string    `json:"user_id" db:"user_id"`
+	StrictnessLevel string    `json:"strictness_level" db:"strictness_level"`
+	DietarySchool   string    `json:"dietary_school" db:"dietary_school"`
+	AutoSubstitute  bool      `json:"auto_substitute" db:"auto_substitute"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UpdateHalalPreferencesRequest represents a request to change halal
+// preferences. Omitted fields keep their current value.
+type UpdateHalalPreferencesRequest struct {
+	StrictnessLevel *string `json:"strictness_level,omitempty" validate:"omitempty,oneof=strict moderate lenient"`
+	DietarySchool   *string `json:"dietary_school,omitempty" validate:"omitempty,oneof=hanafi shafi maliki hanbali"`
+	AutoSubstitute  *bool   `json:"auto_substitute,omitempty"`
+}
+
+// HalalCheckRequest represents ingredients and optional recipe instructions
+// to check against the user's halal preferences
+type HalalCheckRequest struct {
+	Ingredients []string `json:"ingredients" validate:"required,min=1,max=100,dive,required,max=200"`
+	Recipe      string   `json:"recipe,omitempty" validate:"omitempty,max=10000"`
+}
+
+// Halal status of an ingredient or additive
+const (
+	HalalStatusHalal    = "halal"
+	HalalStatusHaram    = "haram"
+	HalalStatusMashbooh = "mashbooh" // doubtful until its source is verified
+)
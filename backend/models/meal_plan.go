@@ -2,10 +2,78 @@ package models
 
 import "time"
 
+// MealPlan is a generated meal plan. The generator inputs are kept with the
+// plan; Days holds the selected recipes and each day's totals.
 type MealPlan struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               string        `json:"id" db:"id"`
+	UserID           string        `json:"user_id" db:"user_id"`
+	Name             string        `json:"name" db:"name"`
+	Description      string        `json:"description,omitempty" db:"description"`
+	PlanType         string        `json:"plan_type" db:"plan_type"`
+	Goal             string        `json:"goal,omitempty" db:"goal"`
+	StartDate        string        `json:"start_date" db:"start_date"`
+	EndDate          string        `json:"end_date" db:"end_date"`
+	TargetCalories   int           `json:"target_calories" db:"target_calories"`
+	MealsPerDay      int           `json:"meals_per_day" db:"meals_per_day"`
+	DurationDays     int           `json:"duration_days" db:"duration_days"`
+	TolerancePercent float64       `json:"tolerance_percent" db:"tolerance_percent"`
+	Restrictions     []string      `json:"restrictions" db:"restrictions"`
+	Macros           MacroTargets  `json:"macro_targets"`
+	TotalCalories    int           `json:"total_calories" db:"total_calories"`
+	TotalProtein     float64       `json:"total_protein" db:"total_protein"`
+	TotalCarbs       float64       `json:"total_carbs" db:"total_carbs"`
+	TotalFat         float64       `json:"total_fat" db:"total_fat"`
+	IsActive         bool          `json:"is_active" db:"is_active"`
+	Days             []MealPlanDay `json:"days,omitempty"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// MealPlanDay is one day of a MealPlan
+type MealPlanDay struct {
+	DayNumber       int           `json:"day_number" db:"day_number"`
+	Date            string        `json:"date" db:"date"`
+	Meals           []PlannedMeal `json:"meals"`
+	Calories        float64       `json:"calories" db:"calories"`
+	Protein         float64       `json:"protein" db:"protein"`
+	Carbs           float64       `json:"carbs" db:"carbs"`
+	Fat             float64       `json:"fat" db:"fat"`
+	WithinTolerance bool          `json:"within_tolerance" db:"within_tolerance"`
+}
+
+// PlannedMeal is a recipe scheduled in a MealPlanDay. Nutrients are for the
+// planned number of servings.
+type PlannedMeal struct {
+	MealType     string  `json:"meal_type" db:"meal_type"`
+	RecipeID     string  `json:"recipe_id" db:"recipe_id"`
+	RecipeSource string  `json:"recipe_source" db:"recipe_source"`
+	Name         string  `json:"name" db:"recipe_name"`
+	Servings     float64 `json:"servings" db:"servings"`
+	Calories     float64 `json:"calories" db:"calories"`
+	Protein      float64 `json:"protein" db:"protein"`
+	Carbs        float64 `json:"carbs" db:"carbs"`
+	Fat          float64 `json:"fat" db:"fat"`
+
+	// Substitutions maps ingredients to the halal replacements applied for
+	// users who opted into substitutions
+	Substitutions map[string]string `json:"substitutions,omitempty" db:"substitutions"`
+
+	// AllergenWarnings are cross-contact cautions for the owner's allergies.
+	// Recipes containing one of them are never planned.
+	AllergenWarnings []AllergenWarning `json:"allergen_warnings,omitempty" db:"allergen_warnings"`
+}
+
+// GenerateMealPlanRequest holds the inputs of the meal plan generator. When
+// TargetCalories is not set, the calorie target is derived from the user's TDEE
+// and Goal.
+type GenerateMealPlanRequest struct {
+	Goal             string   `json:"goal,omitempty" validate:"omitempty,oneof=weight_loss maintenance weight_gain muscle_gain"`
+	PlanType         string   `json:"plan_type,omitempty"`
+	TargetCalories   *int     `json:"target_calories,omitempty" validate:"omitempty,min=1000,max=6000"`
+	MealsPerDay      int      `json:"meals_per_day,omitempty" validate:"omitempty,min=1,max=6"`
+	Duration         int      `json:"duration,omitempty" validate:"omitempty,min=1,max=28"` // days
+	StartDate        string   `json:"start_date,omitempty"`                                 // YYYY-MM-DD format
+	TolerancePercent float64  `json:"tolerance_percent,omitempty" validate:"omitempty,min=1,max=50"`
+	Preferences      []string `json:"preferences,omitempty"`
+	Restrictions     []string `json:"restrictions,omitempty"`
 }
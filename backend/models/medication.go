@@ -149,12 +149,17 @@ type CreateUserSupplementRequest struct {
 
 // MedicationInteractionCheck represents a medication interaction check
 type MedicationInteractionCheck struct {
+	UserID                 string                  `json:"user_id,omitempty"`
 	UserMedications        []string                `json:"user_medications"`
+	UserSupplements        []string                `json:"user_supplements"`
+	HighestSeverity        string                  `json:"highest_severity"` // major, moderate, minor, none
 	Interactions           []MedicationInteraction `json:"interactions"`
 	FoodInteractions       []FoodInteraction       `json:"food_interactions"`
 	SupplementInteractions []SupplementInteraction `json:"supplement_interactions"`
+	TimingAdvice           []TimingAdvice          `json:"timing_advice"`
 	Warnings               []string                `json:"warnings"`
 	Recommendations        []string                `json:"recommendations"`
+	CheckedAt              time.Time               `json:"checked_at"`
 }
 
 // MedicationInteraction represents an interaction between medications
@@ -172,6 +177,7 @@ type FoodInteraction struct {
 	Medication     string `json:"medication"`
 	Food           string `json:"food"`
 	Effect         string `json:"effect"`
+	Severity       string `json:"severity"`
 	Recommendation string `json:"recommendation"`
 }
 
@@ -180,9 +186,17 @@ type SupplementInteraction struct {
 	Medication     string `json:"medication"`
 	Supplement     string `json:"supplement"`
 	Effect         string `json:"effect"`
+	Severity       string `json:"severity"`
 	Recommendation string `json:"recommendation"`
 }
 
+// TimingAdvice represents when to take a medication or supplement relative to meals
+type TimingAdvice struct {
+	Item   string `json:"item"`
+	Type   string `json:"type"` // medication, supplement
+	Advice string `json:"advice"`
+}
+
 // NutrientDeficiencyAnalysis represents analysis of potential nutrient deficiencies
 type NutrientDeficiencyAnalysis struct {
 	UserID                    string                     `json:"user_id"`
@@ -195,6 +209,14 @@ type NutrientDeficiencyAnalysis struct {
 	GeneratedAt               time.Time                  `json:"generated_at"`
 }
 
+// NutrientDeficiencyRequest represents a request to analyze deficiency risk.
+// Pregnancy and conditions add to what the user's health complaints record.
+type NutrientDeficiencyRequest struct {
+	Days       int      `json:"days,omitempty" validate:"omitempty,min=3,max=90"`
+	Pregnant   *bool    `json:"pregnant,omitempty"`
+	Conditions []string `json:"conditions,omitempty"`
+}
+
 // PotentialDeficiency represents a potential nutrient deficiency
 type PotentialDeficiency struct {
 	Nutrient        string   `json:"nutrient"`
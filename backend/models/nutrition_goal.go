@@ -7,7 +7,7 @@ import (
 // NutritionGoal represents a nutrition goal for a user
 type NutritionGoal struct {
 	ID            int        `json:"id" db:"id"`
-	UserID        uint       `json:"user_id" db:"user_id"`
+	UserID        string     `json:"user_id" db:"user_id"`
 	DailyCalories *int       `json:"daily_calories,omitempty" db:"daily_calories"`
 	ProteinGrams  *float64   `json:"protein_grams,omitempty" db:"protein_grams"`
 	CarbsGrams    *float64   `json:"carbs_grams,omitempty" db:"carbs_grams"`
@@ -15,6 +15,7 @@ type NutritionGoal struct {
 	FiberGrams    *float64   `json:"fiber_grams,omitempty" db:"fiber_grams"`
 	SugarGrams    *float64   `json:"sugar_grams,omitempty" db:"sugar_grams"`
 	SodiumMg      *int       `json:"sodium_mg,omitempty" db:"sodium_mg"`
+	PotassiumMg   *int       `json:"potassium_mg,omitempty" db:"potassium_mg"`
 	WaterMl       *int       `json:"water_ml,omitempty" db:"water_ml"`
 	IsActive      bool       `json:"is_active" db:"is_active"`
 	StartDate     *time.Time `json:"start_date,omitempty" db:"start_date"`
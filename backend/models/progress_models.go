@@ -35,37 +35,46 @@ func (s *StringArray) Scan(value interface{}) error {
 	}
 }
 
-// Milestone represents a user's milestone achievements
+// Milestone represents a milestone a user has reached. Milestones are
+// defined by rules such as "first 5 kg lost" and stored once reached, keyed
+// by the rule.
 type Milestone struct {
 	ID          uint      `json:"id" db:"id"`
-	UserID      uint      `json:"user_id" db:"user_id"`
-	Type        string    `json:"type" db:"type"` // "weight", "measurement", "workout", "custom"
+	UserID      string    `json:"user_id" db:"user_id"`
+	Key         string    `json:"key" db:"milestone_key"`
+	Type        string    `json:"type" db:"type"` // "weight", "streak", "workout", "strength"
 	Title       string    `json:"title" db:"title"`
 	Description *string   `json:"description,omitempty" db:"description"`
 	TargetValue *float64  `json:"target_value,omitempty" db:"target_value"`
 	AchievedAt  time.Time `json:"achieved_at" db:"achieved_at"`
-	PhotoURL    *string   `json:"photo_url,omitempty" db:"photo_url"`
-	IsAchieved  bool      `json:"is_achieved" db:"is_achieved"`
+	IsAchieved  bool      `json:"is_achieved"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// WeightGoal represents a user's weight goal
+// Weight goal types
+const (
+	WeightGoalLose     = "lose"
+	WeightGoalGain     = "gain"
+	WeightGoalMaintain = "maintain"
+)
+
+// WeightGoal represents a user's weight goal. A user has at most one active
+// goal; CurrentWeight is the trend weight when the goal was read.
 type WeightGoal struct {
 	ID            uint       `json:"id" db:"id"`
-	UserID        uint       `json:"user_id" db:"user_id"`
+	UserID        string     `json:"user_id" db:"user_id"`
 	TargetWeight  float64    `json:"target_weight" db:"target_weight"`
-	CurrentWeight float64    `json:"current_weight" db:"current_weight"`
+	CurrentWeight float64    `json:"current_weight,omitempty"`
 	StartDate     time.Time  `json:"start_date" db:"start_date"`
 	TargetDate    *time.Time `json:"target_date,omitempty" db:"target_date"`
 	WeeklyGoal    float64    `json:"weekly_goal" db:"weekly_goal"` // kg per week, can be negative for weight loss
-	ActivityLevel string     `json:"activity_level" db:"activity_level"`
+	ActivityLevel string     `json:"activity_level,omitempty" db:"activity_level"`
 	IsActive      bool       `json:"is_active" db:"is_active"`
 	AchievedAt    *time.Time `json:"achieved_at,omitempty" db:"achieved_at"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 	StartWeight   float64    `json:"start_weight" db:"start_weight"`
-	GoalType      string     `json:"goal_type" db:"goal_type"`
+	GoalType      string     `json:"goal_type" db:"goal_type"` // lose, gain, maintain
 	Notes         *string    `json:"notes,omitempty" db:"notes"`
 }
 
@@ -74,12 +83,55 @@ func (g *WeightGoal) Validate() error {
 	if g.TargetWeight <= 0 {
 		return fmt.Errorf("target weight must be positive")
 	}
-	if g.WeeklyGoal == 0 {
+	if g.WeeklyGoal == 0 && g.GoalType != WeightGoalMaintain {
 		return fmt.Errorf("weekly goal cannot be zero")
 	}
 	return nil
 }
 
+// CreateWeightGoalRequest starts a new weight goal. StartWeight defaults to
+// the current trend weight and WeeklyGoal to a sustainable pace towards the
+// target.
+type CreateWeightGoalRequest struct {
+	TargetWeight  float64 `json:"target_weight" validate:"required,min=20,max=500"`
+	StartWeight   float64 `json:"start_weight,omitempty" validate:"omitempty,min=20,max=500"`
+	TargetDate    string  `json:"target_date,omitempty"` // YYYY-MM-DD
+	WeeklyGoal    float64 `json:"weekly_goal,omitempty" validate:"omitempty,min=-1.5,max=1"`
+	ActivityLevel string  `json:"activity_level,omitempty" validate:"omitempty,oneof=sedentary light moderate active very_active"`
+	Notes         string  `json:"notes,omitempty" validate:"max=1000"`
+}
+
+// UpdateWeightGoalRequest changes an existing weight goal
+type UpdateWeightGoalRequest struct {
+	TargetWeight  *float64 `json:"target_weight,omitempty" validate:"omitempty,min=20,max=500"`
+	TargetDate    *string  `json:"target_date,omitempty"` // YYYY-MM-DD, empty clears it
+	WeeklyGoal    *float64 `json:"weekly_goal,omitempty" validate:"omitempty,min=-1.5,max=1"`
+	ActivityLevel *string  `json:"activity_level,omitempty" validate:"omitempty,oneof=sedentary light moderate active very_active"`
+	Notes         *string  `json:"notes,omitempty" validate:"omitempty,max=1000"`
+}
+
+// WeightTrendAnalysis is the smoothed weight trend of a period. Each point
+// carries the day's average weigh-in as Weight and the trend weight as Value.
+type WeightTrendAnalysis struct {
+	Points        []WeightTrendPoint    `json:"points"`
+	LatestWeight  *float64              `json:"latest_weight,omitempty"`
+	TrendWeight   *float64              `json:"trend_weight,omitempty"`
+	WeeklyRate    *float64              `json:"weekly_rate,omitempty"` // kg per week, negative when losing
+	Plateau       *WeightPlateau        `json:"plateau,omitempty"`
+	Goal          *WeightGoal           `json:"goal,omitempty"`
+	Prediction    *WeightGoalPrediction `json:"prediction,omitempty"`
+	WeighInsCount int                   `json:"weigh_ins_count"`
+}
+
+// WeightPlateau reports a stretch where the trend weight stopped moving
+type WeightPlateau struct {
+	Detected   bool       `json:"detected"`
+	Since      *time.Time `json:"since,omitempty"`
+	Days       int        `json:"days"`
+	WeeklyRate float64    `json:"weekly_rate"`
+	Message    string     `json:"message,omitempty"`
+}
+
 // ProgressSummary represents a summary of user's overall progress
 type ProgressSummary struct {
 	UserID               uint             `json:"user_id"`
@@ -97,39 +149,44 @@ type ProgressSummary struct {
 	LastUpdated          time.Time        `json:"last_updated"`
 }
 
-// PersonalRecord represents a user's personal record for an exercise
+// PersonalRecord represents a user's personal record for an exercise. Every
+// record is kept, so the newest record of an exercise and type is the
+// current best and PreviousValue is the best it beat.
 type PersonalRecord struct {
-	ID           uint      `json:"id" db:"id"`
-	UserID       uint      `json:"user_id" db:"user_id"`
-	ExerciseID   uint      `json:"exercise_id" db:"exercise_id"`
-	RecordType   string    `json:"record_type" db:"record_type"` // "weight", "reps", "time", "distance"
-	Value        float64   `json:"value" db:"value"`
-	Unit         string    `json:"unit" db:"unit"`
-	Date         time.Time `json:"date" db:"date"`
-	Notes        *string   `json:"notes,omitempty" db:"notes"`
-	WorkoutLogID *uint     `json:"workout_log_id,omitempty" db:"workout_log_id"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID               uint      `json:"id" db:"id"`
+	UserID           string    `json:"user_id" db:"user_id"`
+	ExerciseID       *string   `json:"exercise_id,omitempty" db:"exercise_id"`
+	ExerciseName     string    `json:"exercise_name" db:"exercise_name"`
+	RecordType       string    `json:"record_type" db:"record_type"` // "estimated_1rm", "weight", "reps", "volume"
+	Value            float64   `json:"value" db:"value"`
+	Unit             string    `json:"unit" db:"unit"`
+	Weight           *float64  `json:"weight,omitempty" db:"weight"` // load of the set that set the record
+	Reps             *int      `json:"reps,omitempty" db:"reps"`
+	PreviousValue    *float64  `json:"previous_value,omitempty" db:"previous_value"`
+	Date             time.Time `json:"date" db:"achieved_at"`
+	Notes            *string   `json:"notes,omitempty" db:"notes"`
+	WorkoutSessionID *string   `json:"workout_session_id,omitempty" db:"workout_session_id"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
 }
 
 // PersonalRecordType represents the type of personal record
 type PersonalRecordType string
 
 const (
-	RecordWeight   PersonalRecordType = "weight"
-	RecordReps     PersonalRecordType = "reps"
-	RecordTime     PersonalRecordType = "time"
-	RecordDistance PersonalRecordType = "distance"
+	RecordEstimated1RM PersonalRecordType = "estimated_1rm"
+	RecordWeight       PersonalRecordType = "weight"
+	RecordReps         PersonalRecordType = "reps"
+	RecordVolume       PersonalRecordType = "volume"
+	RecordTime         PersonalRecordType = "time"
+	RecordDistance     PersonalRecordType = "distance"
 )
 
 // ListPersonalRecordsRequest represents a request to list personal records
 type ListPersonalRecordsRequest struct {
-	ExerciseID *uint      `json:"exercise_id,omitempty"`
-	RecordType *string    `json:"record_type,omitempty"`
-	StartDate  *time.Time `json:"start_date,omitempty"`
-	EndDate    *time.Time `json:"end_date,omitempty"`
-	Page       int        `json:"page,omitempty" validate:"omitempty,min=1"`
-	Limit      int        `json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
+	ExerciseID string `query:"exercise_id"`
+	RecordType string `query:"record_type" validate:"omitempty,oneof=estimated_1rm weight reps volume"`
+	Page       int    `query:"page" validate:"omitempty,min=1"`
+	Limit      int    `query:"limit" validate:"omitempty,min=1,max=100"`
 }
 
 // PersonalRecordListResponse represents a response for personal record listing
@@ -149,7 +206,8 @@ type PersonalRecordWithExercise struct {
 
 // MilestoneProgress represents progress towards a milestone
 type MilestoneProgress struct {
-	MilestoneID         uint       `json:"milestone_id"`
+	MilestoneKey        string     `json:"milestone_key"`
+	MilestoneType       string     `json:"milestone_type"`
 	MilestoneTitle      string     `json:"milestone_title"`
 	CurrentValue        float64    `json:"current_value"`
 	TargetValue         float64    `json:"target_value"`
@@ -294,12 +352,20 @@ type WeightGoalProgress struct {
 	TrendPoints         []WeightTrendPoint `json:"trend_points"`
 }
 
-// WeightGoalPrediction represents weight goal predictions
+// WeightGoalPrediction projects the trend weight to the goal. TargetDate is
+// the expected arrival at the current weekly rate, and EarliestDate and
+// LatestDate bound it at 95% confidence; LatestDate is nil when the slow end
+// of the band never reaches the target.
 type WeightGoalPrediction struct {
 	CurrentWeight   float64            `json:"current_weight"`
 	TargetWeight    float64            `json:"target_weight"`
+	WeeklyRate      float64            `json:"weekly_rate"`
 	PredictedWeight []WeightTrendPoint `json:"predicted_weight"`
 	TargetDate      *time.Time         `json:"target_date,omitempty"`
+	EarliestDate    *time.Time         `json:"earliest_date,omitempty"`
+	LatestDate      *time.Time         `json:"latest_date,omitempty"`
+	OnTrack         *bool              `json:"on_track,omitempty"`
+	Achieved        bool               `json:"achieved"`
 	Confidence      float64            `json:"confidence"`
 	Recommendations []string           `json:"recommendations"`
 }
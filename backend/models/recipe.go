@@ -63,10 +63,34 @@ type NutritionInfo struct {
 	Fiber         float64 `json:"fiber"`
 	Sugar         float64 `json:"sugar"`
 	Sodium        float64 `json:"sodium"`
+	Potassium     float64 `json:"potassium,omitempty"`
 	Cholesterol   float64 `json:"cholesterol"`
 	VitaminC      float64 `json:"vitamin_c,omitempty"`
 	Calcium       float64 `json:"calcium,omitempty"`
 	Iron          float64 `json:"iron,omitempty"`
+
+	// Micronutrients holds vitamins and minerals keyed as in MicronutrientUnits
+	Micronutrients map[string]float64 `json:"micronutrients,omitempty"`
+}
+
+// MicronutrientUnits lists the micronutrients tracked by the food diary and
+// the unit each amount is recorded in
+var MicronutrientUnits = map[string]string{
+	"vitamin_a":   "µg",
+	"vitamin_c":   "mg",
+	"vitamin_d":   "µg",
+	"vitamin_e":   "mg",
+	"vitamin_k":   "µg",
+	"vitamin_b6":  "mg",
+	"vitamin_b12": "µg",
+	"folate":      "µg",
+	"calcium":     "mg",
+	"iron":        "mg",
+	"magnesium":   "mg",
+	"zinc":        "mg",
+	"potassium":   "mg",
+	"iodine":      "µg",
+	"selenium":    "µg",
 }
 
 // RecipeSearchRequest represents recipe search parameters
@@ -0,0 +1,36 @@
+package models
+
+// Built-in roles. Their permissions live in the role_permissions table.
+const (
+	RoleAdmin       = "admin"
+	RoleClinicAdmin = "clinic-admin"
+	RoleDietitian   = "dietitian"
+	RoleCoach       = "coach"
+	RolePatient     = "patient"
+	RoleUser        = "user"
+)
+
+// Permissions checked by RequirePermission
+const (
+	PermissionFoodsVerify     = "foods:verify"
+	PermissionProductsReview  = "products:review"
+	PermissionPlansAssign     = "plans:assign"
+	PermissionUsersReadHealth = "users:read-health"
+	PermissionUsersManage     = "users:manage"
+	PermissionAuditRead       = "audit:read"
+	PermissionRolesManage     = "roles:manage"
+	PermissionAPIKeysManage   = "api-keys:manage"
+)
+
+// Role is a named set of permissions assigned to users
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// Permission is a single grantable capability such as "foods:verify"
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Search entity types
+const (
+	SearchTypeRecipe    = "recipe"
+	SearchTypeWorkout   = "workout"
+	SearchTypeComplaint = "complaint"
+	SearchTypeDisease   = "disease"
+)
+
+// SearchRequest represents a unified search across recipes, workouts, complaints and diseases
+type SearchRequest struct {
+	Query string   `query:"q" validate:"required,min=1,max=200"`
+	Types []string `query:"-"`
+	Page  int      `query:"page" validate:"omitempty,min=1"`
+	Limit int      `query:"limit" validate:"omitempty,min=1,max=100"`
+
+	// Halal restricts recipes to those compliant with these preferences
+	Halal *HalalPreferences `query:"-"`
+	// Allergies leaves out recipes containing any of these allergen codes
+	Allergies []string `query:"-"`
+}
+
+// SearchHit represents one ranked search result. Snippets and highlighted titles
+// are HTML-escaped with matches wrapped in <mark> tags.
+type SearchHit struct {
+	Type             string  `json:"type"`
+	ID               string  `json:"id"`
+	Title            string  `json:"title"`
+	TitleAr          string  `json:"title_ar,omitempty"`
+	HighlightedTitle string  `json:"highlighted_title"`
+	Snippet          string  `json:"snippet,omitempty"`
+	Score            float64 `json:"score"`
+}
+
+// SearchResponse represents a page of ranked results with per-type facet counts
+type SearchResponse struct {
+	Query       string         `json:"query"`
+	Results     []SearchHit    `json:"results"`
+	Total       int            `json:"total"`
+	Page        int            `json:"page"`
+	Limit       int            `json:"limit"`
+	Facets      map[string]int `json:"facets"`
+	Suggestions []string       `json:"suggestions,omitempty"`
+	Engine      string         `json:"engine"` // fts5, memory
+	IndexedAt   time.Time      `json:"indexed_at"`
+}
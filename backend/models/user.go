@@ -49,3 +49,34 @@ func (u *User) Validate() error {
 	}
 	return nil
 }
+
+// UserAccount represents the credentials and profile stored in the users table
+type UserAccount struct {
+	ID                  string     `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	PasswordHash        string     `json:"-"`
+	FirstName           string     `json:"first_name"`
+	LastName            string     `json:"last_name"`
+	DateOfBirth         string     `json:"date_of_birth,omitempty"`
+	Gender              string     `json:"gender,omitempty"`
+	PreferredLanguage   string     `json:"preferred_language"`
+	Role                string     `json:"role"`
+	IsActive            bool       `json:"is_active"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	FailedLoginAttempts int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+	LastLoginAt         *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// IsLocked reports whether the account is locked out at the given time
+func (a *UserAccount) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// IsAdmin reports whether the account has the admin role
+func (a *UserAccount) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Climates that adjust the computed hydration target
+const (
+	ClimateTemperate = "temperate"
+	ClimateHot       = "hot"
+	ClimateVeryHot   = "very_hot"
+)
+
+// Sources of a daily hydration target
+const (
+	WaterTargetFromGoal     = "goal"     // the active NutritionGoal's water_ml
+	WaterTargetComputed     = "computed" // body weight, workout minutes and climate
+	WaterTargetFromDefaults = "default"  // computed without a known body weight
+)
+
+// WaterIntake represents one logged drink
+type WaterIntake struct {
+	ID         int64     `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	AmountMl   int       `json:"amount_ml" db:"amount_ml"`
+	ConsumedAt time.Time `json:"consumed_at" db:"consumed_at"`
+	Notes      *string   `json:"notes,omitempty" db:"notes"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LogWaterRequest represents a request to log a drink. Date defaults to now.
+type LogWaterRequest struct {
+	AmountMl int        `json:"amount_ml" validate:"required,min=1,max=5000"`
+	Date     *time.Time `json:"date,omitempty"`
+	Notes    *string    `json:"notes,omitempty"`
+}
+
+// WaterTarget is a day's hydration target and how it was derived. Targets
+// computed without a goal add workout and climate allowances to a base of
+// 35 ml per kg of body weight.
+type WaterTarget struct {
+	Date           string   `json:"date"`
+	TargetMl       int      `json:"target_ml"`
+	Source         string   `json:"source"`
+	BodyWeightKg   *float64 `json:"body_weight_kg,omitempty"`
+	BaseMl         int      `json:"base_ml"`
+	WorkoutMinutes int      `json:"workout_minutes"`
+	WorkoutMl      int      `json:"workout_ml"`
+	Climate        string   `json:"climate"`
+	ClimateMl      int      `json:"climate_ml"`
+}
+
+// DailyHydration is one day of hydration history
+type DailyHydration struct {
+	Date            string  `json:"date"`
+	TotalMl         int     `json:"total_ml"`
+	TargetMl        int     `json:"target_ml"`
+	ProgressPercent float64 `json:"progress_percent"`
+	TargetMet       bool    `json:"target_met"`
+	Entries         int     `json:"entries"`
+}
+
+// HydrationStreak counts consecutive days on which the target was met. The
+// current streak still counts while today's target has not been reached yet.
+type HydrationStreak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+// HydrationHistory represents a day or week of water intake with targets and streaks
+type HydrationHistory struct {
+	Period    string           `json:"period"` // day, week
+	StartDate string           `json:"start_date"`
+	EndDate   string           `json:"end_date"`
+	Days      []DailyHydration `json:"days"`
+	TotalMl   int              `json:"total_ml"`
+	AverageMl int              `json:"average_ml"`
+	DaysMet   int              `json:"days_met"`
+	Target    WaterTarget      `json:"target"` // for the requested date
+	Streak    HydrationStreak  `json:"streak"`
+	Entries   []*WaterIntake   `json:"entries"`
+}
@@ -7,6 +7,7 @@ import (
 // WorkoutProgram represents a comprehensive workout program
 type WorkoutProgram struct {
 	ID                     string            `json:"id" db:"id"`
+	UserID                 *string           `json:"user_id,omitempty" db:"user_id"`
 	Name                   string            `json:"name" db:"name"`
 	NameAr                 *string           `json:"name_ar,omitempty" db:"name_ar"`
 	Description            *string           `json:"description,omitempty" db:"description"`
@@ -25,10 +26,28 @@ type WorkoutProgram struct {
 	CreatedBy              *string           `json:"created_by,omitempty" db:"created_by"`
 	DifficultyRating       *int              `json:"difficulty_rating,omitempty" db:"difficulty_rating"`
 	CalorieBurnEstimate    *int              `json:"calorie_burn_estimate,omitempty" db:"calorie_burn_estimate"`
+	StartDate              *string           `json:"start_date,omitempty" db:"start_date"`
+	Sessions               []WorkoutSession  `json:"sessions,omitempty"`
 	CreatedAt              time.Time         `json:"created_at" db:"created_at"`
 	UpdatedAt              time.Time         `json:"updated_at" db:"updated_at"`
 }
 
+// GenerateWorkoutProgramRequest holds the inputs of the workout program
+// generator. Duration is the length of one session in minutes; Equipment lists
+// what the user has available in addition to bodyweight. Restrictions are extra
+// movements or body parts to avoid on top of the user's recorded injuries.
+type GenerateWorkoutProgramRequest struct {
+	Goal         string   `json:"goal,omitempty" validate:"omitempty,oneof=strength muscle_gain weight_loss endurance flexibility general_fitness"`
+	Difficulty   string   `json:"difficulty,omitempty" validate:"omitempty,oneof=beginner intermediate advanced"`
+	DaysPerWeek  int      `json:"days_per_week,omitempty" validate:"omitempty,min=2,max=6"`
+	Weeks        int      `json:"weeks,omitempty" validate:"omitempty,min=1,max=16"`
+	Duration     int      `json:"duration,omitempty" validate:"omitempty,min=15,max=180"` // minutes
+	StartDate    string   `json:"start_date,omitempty"`                                   // YYYY-MM-DD format
+	Equipment    []string `json:"equipment,omitempty"`
+	MuscleGroups []string `json:"muscle_groups,omitempty"`
+	Restrictions []string `json:"restrictions,omitempty"`
+}
+
 // ProgressionStep represents a step in workout progression
 type ProgressionStep struct {
 	Week        int    `json:"week"`
@@ -41,6 +60,8 @@ type WorkoutSession struct {
 	ID                       string                 `json:"id" db:"id"`
 	WorkoutProgramID         string                 `json:"workout_program_id" db:"workout_program_id"`
 	SessionNumber            int                    `json:"session_number" db:"session_number"`
+	WeekNumber               int                    `json:"week_number" db:"week_number"`
+	DayNumber                int                    `json:"day_number" db:"day_number"`
 	Name                     string                 `json:"name" db:"name"`
 	Description              *string                `json:"description,omitempty" db:"description"`
 	WarmUpExercises          []SessionExercise      `json:"warm_up_exercises" db:"warm_up_exercises"`
@@ -101,6 +122,9 @@ type UserWorkoutSession struct {
 	Status             string              `json:"status" db:"status"`
 	CreatedAt          time.Time           `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time           `json:"updated_at" db:"updated_at"`
+
+	// PersonalRecords are the records set by the session when it was logged
+	PersonalRecords []*PersonalRecord `json:"personal_records,omitempty"`
 }
 
 // CompletedExercise represents a completed exercise
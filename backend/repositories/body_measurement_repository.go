@@ -107,7 +107,7 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementByID(ctx context.Context,
 }
 
 // GetBodyMeasurementsByUserID retrieves measurements for a user with pagination
-func (r *BodyMeasurementRepository) GetBodyMeasurementsByUserID(ctx context.Context, userID int64, limit, offset int) ([]*models.BodyMeasurement, error) {
+func (r *BodyMeasurementRepository) GetBodyMeasurementsByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.BodyMeasurement, error) {
 	query := `
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
@@ -208,7 +208,7 @@ func (r *BodyMeasurementRepository) UpdateBodyMeasurement(ctx context.Context, m
 }
 
 // DeleteBodyMeasurement deletes a body measurement
-func (r *BodyMeasurementRepository) DeleteBodyMeasurement(ctx context.Context, id, userID int64) error {
+func (r *BodyMeasurementRepository) DeleteBodyMeasurement(ctx context.Context, id int64, userID string) error {
 	query := `DELETE FROM body_measurements WHERE id = $1 AND user_id = $2`
 
 	result, err := r.db.DB.ExecContext(ctx, query, id, userID)
@@ -229,7 +229,7 @@ func (r *BodyMeasurementRepository) DeleteBodyMeasurement(ctx context.Context, i
 }
 
 // GetBodyMeasurementsByDateRange retrieves measurements within a date range
-func (r *BodyMeasurementRepository) GetBodyMeasurementsByDateRange(ctx context.Context, userID int64, startDate, endDate time.Time, limit, offset int) ([]*models.BodyMeasurement, error) {
+func (r *BodyMeasurementRepository) GetBodyMeasurementsByDateRange(ctx context.Context, userID string, startDate, endDate time.Time, limit, offset int) ([]*models.BodyMeasurement, error) {
 	query := `
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
@@ -284,7 +284,7 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementsByDateRange(ctx context.C
 }
 
 // GetLatestBodyMeasurement gets the most recent measurement for a user
-func (r *BodyMeasurementRepository) GetLatestBodyMeasurement(ctx context.Context, userID int64) (*models.BodyMeasurement, error) {
+func (r *BodyMeasurementRepository) GetLatestBodyMeasurement(ctx context.Context, userID string) (*models.BodyMeasurement, error) {
 	query := `
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
@@ -331,7 +331,7 @@ func (r *BodyMeasurementRepository) GetLatestBodyMeasurement(ctx context.Context
 }
 
 // GetMeasurementCountByUserID gets the total count of measurements for a user
-func (r *BodyMeasurementRepository) GetMeasurementCountByUserID(ctx context.Context, userID int64) (int64, error) {
+func (r *BodyMeasurementRepository) GetMeasurementCountByUserID(ctx context.Context, userID string) (int64, error) {
 	query := `SELECT COUNT(*) FROM body_measurements WHERE user_id = $1`
 
 	var count int64
@@ -344,18 +344,19 @@ func (r *BodyMeasurementRepository) GetMeasurementCountByUserID(ctx context.Cont
 }
 
 // GetWeightTrend retrieves weight trend data for analytics
-func (r *BodyMeasurementRepository) GetWeightTrend(ctx context.Context, userID int64, days int) ([]*models.WeightTrendPoint, error) {
+func (r *BodyMeasurementRepository) GetWeightTrend(ctx context.Context, userID string, days int) ([]*models.WeightTrendPoint, error) {
 	query := `
 		SELECT 
 			measurement_date as date,
 			weight as value
 		FROM body_measurements
 		WHERE user_id = $1 
-			AND measurement_date >= NOW() - INTERVAL '%d days'
+			AND measurement_date >= $2
+			AND weight IS NOT NULL
 		ORDER BY measurement_date ASC`
 
-	query = fmt.Sprintf(query, days)
-	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get weight trend: %w", err)
 	}
@@ -368,6 +369,7 @@ func (r *BodyMeasurementRepository) GetWeightTrend(ctx context.Context, userID i
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan weight trend point: %w", err)
 		}
+		point.Weight = point.Value
 		trendPoints = append(trendPoints, &point)
 	}
 
@@ -375,19 +377,19 @@ func (r *BodyMeasurementRepository) GetWeightTrend(ctx context.Context, userID i
 }
 
 // GetBodyFatTrend retrieves body fat percentage trend data
-func (r *BodyMeasurementRepository) GetBodyFatTrend(ctx context.Context, userID int64, days int) ([]*models.BodyFatTrendPoint, error) {
+func (r *BodyMeasurementRepository) GetBodyFatTrend(ctx context.Context, userID string, days int) ([]*models.BodyFatTrendPoint, error) {
 	query := `
 		SELECT 
 			measurement_date as date,
 			body_fat_percentage as value
 		FROM body_measurements
 		WHERE user_id = $1 
-			AND measurement_date >= NOW() - INTERVAL '%d days'
+			AND measurement_date >= $2
 			AND body_fat_percentage IS NOT NULL
 		ORDER BY measurement_date ASC`
 
-	query = fmt.Sprintf(query, days)
-	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get body fat trend: %w", err)
 	}
@@ -407,7 +409,7 @@ func (r *BodyMeasurementRepository) GetBodyFatTrend(ctx context.Context, userID
 }
 
 // GetMeasurementStats calculates statistics for a user's measurements
-func (r *BodyMeasurementRepository) GetMeasurementStats(ctx context.Context, userID int64, days int) (*models.MeasurementStats, error) {
+func (r *BodyMeasurementRepository) GetMeasurementStats(ctx context.Context, userID string, days int) (*models.MeasurementStats, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total_measurements,
@@ -420,17 +422,18 @@ func (r *BodyMeasurementRepository) GetMeasurementStats(ctx context.Context, use
 			COUNT(CASE WHEN body_fat_percentage IS NOT NULL THEN 1 END) as body_fat_count
 		FROM body_measurements
 		WHERE user_id = $1 
-			AND measurement_date >= NOW() - INTERVAL '%d days'`
+			AND measurement_date >= $2`
 
-	query = fmt.Sprintf(query, days)
+	since := time.Now().UTC().AddDate(0, 0, -days)
 	var stats models.MeasurementStats
+	var avgWeight, minWeight, maxWeight sql.NullFloat64
 	var minBodyFat, maxBodyFat, avgBodyFat sql.NullFloat64
 
-	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(
+	err := r.db.DB.QueryRowContext(ctx, query, userID, since).Scan(
 		&stats.TotalMeasurements,
-		&stats.AvgWeight,
-		&stats.MinWeight,
-		&stats.MaxWeight,
+		&avgWeight,
+		&minWeight,
+		&maxWeight,
 		&avgBodyFat,
 		&minBodyFat,
 		&maxBodyFat,
@@ -441,6 +444,15 @@ func (r *BodyMeasurementRepository) GetMeasurementStats(ctx context.Context, use
 		return nil, fmt.Errorf("failed to get measurement stats: %w", err)
 	}
 
+	if avgWeight.Valid {
+		stats.AvgWeight = avgWeight.Float64
+	}
+	if minWeight.Valid {
+		stats.MinWeight = minWeight.Float64
+	}
+	if maxWeight.Valid {
+		stats.MaxWeight = maxWeight.Float64
+	}
 	if avgBodyFat.Valid {
 		stats.AvgBodyFat = avgBodyFat.Float64
 	}
@@ -455,7 +467,7 @@ func (r *BodyMeasurementRepository) GetMeasurementStats(ctx context.Context, use
 }
 
 // CompareMeasurements compares measurements between two dates
-func (r *BodyMeasurementRepository) CompareMeasurements(ctx context.Context, userID int64, startDate, endDate time.Time) (*models.MeasurementComparison, error) {
+func (r *BodyMeasurementRepository) CompareMeasurements(ctx context.Context, userID string, startDate, endDate time.Time) (*models.MeasurementComparison, error) {
 	query := `
 		WITH first_measurement AS (
 			SELECT * FROM body_measurements
@@ -545,14 +557,14 @@ func (r *BodyMeasurementRepository) CompareMeasurements(ctx context.Context, use
 }
 
 // SearchBodyMeasurements searches measurements by notes
-func (r *BodyMeasurementRepository) SearchBodyMeasurements(ctx context.Context, userID int64, searchTerm string, limit, offset int) ([]*models.BodyMeasurement, error) {
+func (r *BodyMeasurementRepository) SearchBodyMeasurements(ctx context.Context, userID string, searchTerm string, limit, offset int) ([]*models.BodyMeasurement, error) {
 	query := `
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
 			   left_forearm, right_forearm, left_thigh, right_thigh,
 			   left_calf, right_calf, notes, created_at, updated_at
 		FROM body_measurements
-		WHERE user_id = $1 AND notes ILIKE $2
+		WHERE user_id = $1 AND LOWER(notes) LIKE LOWER($2)
 		ORDER BY measurement_date DESC, created_at DESC
 		LIMIT $3 OFFSET $4`
 
@@ -1,8 +1,12 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"nutrition-platform/database"
@@ -358,3 +362,115 @@ func (r *ExerciseRepository) GetExercisesByEquipment(equipment string, limit int
 
 	return exercises, nil
 }
+
+// bodyweightEquipment lists equipment values that need nothing beyond the
+// user's own bodyweight
+var bodyweightEquipment = map[string]bool{"": true, "none": true, "bodyweight": true, "body_weight": true, "mat": true}
+
+// GetExercisesByMuscleGroupsAndEquipment retrieves exercises that work any of
+// muscleGroups and need no equipment beyond available. An empty muscleGroups
+// matches every exercise; "gym" or "full_gym" in available matches all
+// equipment.
+func (r *ExerciseRepository) GetExercisesByMuscleGroupsAndEquipment(ctx context.Context, muscleGroups, available []string) ([]*models.EnhancedExercise, error) {
+	query := `
+		SELECT id, name, category, COALESCE(muscle_groups, '[]'), equipment, difficulty_level, instructions, met_value
+		FROM exercises`
+	args := []interface{}{}
+	if len(muscleGroups) > 0 {
+		conditions := make([]string, len(muscleGroups))
+		for i, group := range muscleGroups {
+			conditions[i] = fmt.Sprintf("LOWER(muscle_groups) LIKE $%d", i+1)
+			args = append(args, "%"+strings.ToLower(strings.TrimSpace(group))+"%")
+		}
+		query += "\n\t\tWHERE " + strings.Join(conditions, " OR ")
+	}
+	query += "\n\t\tORDER BY name ASC"
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exercises by muscle groups and equipment: %w", err)
+	}
+	defer rows.Close()
+
+	equipped := NormalizeEquipment(available)
+	allEquipment := equipped["gym"] || equipped["full_gym"]
+
+	var exercises []*models.EnhancedExercise
+	for rows.Next() {
+		var (
+			exercise                                      models.EnhancedExercise
+			muscles                                       string
+			category, equipment, difficulty, instructions sql.NullString
+			met                                           sql.NullFloat64
+		)
+		if err := rows.Scan(&exercise.ID, &exercise.Name, &category, &muscles, &equipment,
+			&difficulty, &instructions, &met); err != nil {
+			return nil, fmt.Errorf("failed to scan exercise: %w", err)
+		}
+		if !allEquipment && !hasEquipment(ParseEquipment(equipment.String), equipped) {
+			continue
+		}
+		if err := json.Unmarshal([]byte(muscles), &exercise.MuscleGroups); err != nil || exercise.MuscleGroups == nil {
+			exercise.MuscleGroups = []string{}
+		}
+		exercise.Category = nullString(category)
+		exercise.Equipment = nullString(equipment)
+		exercise.DifficultyLevel = nullString(difficulty)
+		exercise.Instructions = nullString(instructions)
+		if met.Valid {
+			exercise.METValue = &met.Float64
+		}
+		exercises = append(exercises, &exercise)
+	}
+
+	return exercises, rows.Err()
+}
+
+// ParseEquipment reads exercises.equipment, stored either as a JSON array or
+// a comma separated list, into sorted normalized names. Bodyweight entries
+// are dropped.
+func ParseEquipment(raw string) []string {
+	var items []string
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		items = strings.Split(raw, ",")
+	}
+	normalized := NormalizeEquipment(items)
+	names := make([]string, 0, len(normalized))
+	for name := range normalized {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NormalizeEquipment lowercases equipment names and writes them in singular
+// with underscores, so "Pull-up bars" and "pull_up_bar" compare equal.
+// Bodyweight entries are dropped.
+func NormalizeEquipment(items []string) map[string]bool {
+	normalized := map[string]bool{}
+	for _, item := range items {
+		item = strings.ToLower(strings.TrimSpace(item))
+		item = strings.NewReplacer(" ", "_", "-", "_").Replace(item)
+		item = strings.TrimSuffix(item, "s")
+		if !bodyweightEquipment[item] {
+			normalized[item] = true
+		}
+	}
+	return normalized
+}
+
+func hasEquipment(needed []string, available map[string]bool) bool {
+	for _, item := range needed {
+		if !available[item] {
+			return false
+		}
+	}
+	return true
+}
+
+func nullString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
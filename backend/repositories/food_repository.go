@@ -260,6 +260,26 @@ func (r *FoodRepository) DeleteFood(id, userID string) error {
 	return nil
 }
 
+// SetFoodVerified records a reviewer's verification decision for any user's food
+func (r *FoodRepository) SetFoodVerified(id string, verified bool) error {
+	query := `UPDATE foods SET verified = $1, updated_at = $2 WHERE id = $3`
+
+	result, err := r.db.Exec(query, verified, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to verify food: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify food: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("food not found")
+	}
+
+	return nil
+}
+
 // GetFoodByBarcode retrieves a food by its barcode
 func (r *FoodRepository) GetFoodByBarcode(barcode, userID string) (*models.Food, error) {
 	query := `
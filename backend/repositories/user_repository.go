@@ -235,13 +235,13 @@ func (r *UserRepository) DeleteUser(id int) error {
 }
 
 // GetActiveNutritionGoals retrieves active nutrition goals for a user
-func (r *UserRepository) GetActiveNutritionGoals(userID uint) ([]*models.NutritionGoal, error) {
+func (r *UserRepository) GetActiveNutritionGoals(userID string) ([]*models.NutritionGoal, error) {
 	query := `
 		SELECT id, user_id, daily_calories, protein_grams, carbs_grams, fat_grams,
-		       fiber_grams, sugar_grams, sodium_mg, water_ml, is_active,
+		       fiber_grams, sugar_grams, sodium_mg, potassium_mg, water_ml, is_active,
 		       start_date, end_date, created_at, updated_at
 		FROM nutrition_goals
-		WHERE user_id = $1 AND is_active = true
+		WHERE user_id = ? AND is_active = true
 		ORDER BY created_at DESC
 	`
 
@@ -264,6 +264,7 @@ func (r *UserRepository) GetActiveNutritionGoals(userID uint) ([]*models.Nutriti
 			&goal.FiberGrams,
 			&goal.SugarGrams,
 			&goal.SodiumMg,
+			&goal.PotassiumMg,
 			&goal.WaterMl,
 			&goal.IsActive,
 			&goal.StartDate,
@@ -284,12 +285,12 @@ func (r *UserRepository) GetActiveNutritionGoals(userID uint) ([]*models.Nutriti
 func (r *UserRepository) CreateNutritionGoal(goal *models.NutritionGoal) error {
 	query := `
 		INSERT INTO nutrition_goals (user_id, daily_calories, protein_grams, carbs_grams,
-		                    fat_grams, fiber_grams, sugar_grams, sodium_mg, water_ml,
-		                    is_active, start_date, end_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		                    fat_grams, fiber_grams, sugar_grams, sodium_mg, potassium_mg,
+		                    water_ml, is_active, start_date, end_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.Exec(query,
+	result, err := r.db.Exec(query,
 		goal.UserID,
 		goal.DailyCalories,
 		goal.ProteinGrams,
@@ -298,6 +299,7 @@ func (r *UserRepository) CreateNutritionGoal(goal *models.NutritionGoal) error {
 		goal.FiberGrams,
 		goal.SugarGrams,
 		goal.SodiumMg,
+		goal.PotassiumMg,
 		goal.WaterMl,
 		goal.IsActive,
 		goal.StartDate,
@@ -308,6 +310,10 @@ func (r *UserRepository) CreateNutritionGoal(goal *models.NutritionGoal) error {
 		return fmt.Errorf("failed to create nutrition goal: %w", err)
 	}
 
+	if id, err := result.LastInsertId(); err == nil {
+		goal.ID = int(id)
+	}
+
 	return nil
 }
 
@@ -315,15 +321,14 @@ func (r *UserRepository) CreateNutritionGoal(goal *models.NutritionGoal) error {
 func (r *UserRepository) UpdateNutritionGoal(goal *models.NutritionGoal) error {
 	query := `
 		UPDATE nutrition_goals
-		SET daily_calories = $2, protein_grams = $3, carbs_grams = $4,
-		    fat_grams = $5, fiber_grams = $6, sugar_grams = $7, sodium_mg = $8,
-		    water_ml = $9, is_active = $10, start_date = $11, end_date = $12,
+		SET daily_calories = ?, protein_grams = ?, carbs_grams = ?,
+		    fat_grams = ?, fiber_grams = ?, sugar_grams = ?, sodium_mg = ?,
+		    potassium_mg = ?, water_ml = ?, is_active = ?, start_date = ?, end_date = ?,
 		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1 AND user_id = $13
+		WHERE id = ? AND user_id = ?
 	`
 
 	_, err := r.db.Exec(query,
-		goal.ID,
 		goal.DailyCalories,
 		goal.ProteinGrams,
 		goal.CarbsGrams,
@@ -331,10 +336,12 @@ func (r *UserRepository) UpdateNutritionGoal(goal *models.NutritionGoal) error {
 		goal.FiberGrams,
 		goal.SugarGrams,
 		goal.SodiumMg,
+		goal.PotassiumMg,
 		goal.WaterMl,
 		goal.IsActive,
 		goal.StartDate,
 		goal.EndDate,
+		goal.ID,
 		goal.UserID,
 	)
 
@@ -346,11 +353,11 @@ func (r *UserRepository) UpdateNutritionGoal(goal *models.NutritionGoal) error {
 }
 
 // DeleteNutritionGoal deletes (deactivates) a nutrition goal
-func (r *UserRepository) DeleteNutritionGoal(goalID int, userID uint) error {
+func (r *UserRepository) DeleteNutritionGoal(goalID int, userID string) error {
 	query := `
 		UPDATE nutrition_goals
 		SET is_active = false, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1 AND user_id = $2
+		WHERE id = ? AND user_id = ?
 	`
 
 	_, err := r.db.Exec(query, goalID, userID)
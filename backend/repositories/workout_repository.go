@@ -8,6 +8,8 @@ import (
 
 	"nutrition-platform/database"
 	"nutrition-platform/models"
+
+	"github.com/google/uuid"
 )
 
 // WorkoutRepository handles workout-related database operations
@@ -20,13 +22,17 @@ func NewWorkoutRepository(db *database.Database) *WorkoutRepository {
 	return &WorkoutRepository{db: db}
 }
 
-// CreateUserWorkoutSession creates a new user workout session
+// CreateUserWorkoutSession creates a new user workout session, assigning its ID
 func (r *WorkoutRepository) CreateUserWorkoutSession(session *models.UserWorkoutSession) error {
 	query := `
-		INSERT INTO user_workout_sessions (user_id, workout_session_id, workout_program_id, scheduled_date, completed_date, duration_minutes, calories_burned, perceived_exertion, mood_before, mood_after, exercises_completed, exercises_skipped, modifications_used, notes, injuries_reported, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		INSERT INTO user_workout_sessions (user_id, workout_session_id, workout_program_id, scheduled_date, completed_date, duration_minutes, calories_burned, perceived_exertion, mood_before, mood_after, exercises_completed, exercises_skipped, modifications_used, notes, injuries_reported, status, created_at, updated_at, id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
 
+	if session.ID == "" {
+		session.ID = uuid.New().String()
+	}
+
 	exercisesCompletedJSON, _ := json.Marshal(session.ExercisesCompleted)
 	exercisesSkippedJSON, _ := json.Marshal(session.ExercisesSkipped)
 	modificationsUsedJSON, _ := json.Marshal(session.ModificationsUsed)
@@ -51,6 +57,7 @@ func (r *WorkoutRepository) CreateUserWorkoutSession(session *models.UserWorkout
 		session.Status,
 		time.Now(),
 		time.Now(),
+		session.ID,
 	)
 
 	if err != nil {
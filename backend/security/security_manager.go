@@ -76,6 +76,34 @@ type SecurityConfig struct {
 	WAFEnabled   bool
 	WAFRules     []WAFRule
 	WAFBlockMode bool // true for block, false for log only
+
+	// Two-factor authentication
+	RequireAdminTwoFactor bool     // admin routes need a session that passed 2FA
+	AdminTwoFactorPaths   []string // route prefixes covered by RequireAdminTwoFactor
+}
+
+// DefaultAdminTwoFactorPaths are the routes protected by RequireAdminTwoFactor
+// when AdminTwoFactorPaths is empty
+var DefaultAdminTwoFactorPaths = []string{"/api/v1/auth/admin/"}
+
+// RequiresTwoFactor reports whether the policy demands a completed second
+// factor for the request path. Admin routes are reachable by any role granted
+// the matching permission, so the policy applies to every caller.
+func (c SecurityConfig) RequiresTwoFactor(path string) bool {
+	if !c.RequireAdminTwoFactor {
+		return false
+	}
+
+	paths := c.AdminTwoFactorPaths
+	if len(paths) == 0 {
+		paths = DefaultAdminTwoFactorPaths
+	}
+	for _, prefix := range paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // SecurityManagerMetrics tracks security manager specific metrics
@@ -445,6 +473,27 @@ func (sm *SecurityManager) WAFMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// TwoFactorPolicyMiddleware enforces RequireAdminTwoFactor. It must run after
+// JWT authentication, which sets "two_factor_verified".
+func (sm *SecurityManager) TwoFactorPolicyMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !sm.config.RequiresTwoFactor(c.Request().URL.Path) {
+				return next(c)
+			}
+
+			if verified, _ := c.Get("two_factor_verified").(bool); !verified {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Two-factor authentication required",
+					"code":  "two_factor_required",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
 // InputSanitizationMiddleware sanitizes request inputs
 func (sm *SecurityManager) InputSanitizationMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
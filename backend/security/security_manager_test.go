@@ -0,0 +1,21 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityConfig_RequiresTwoFactor(t *testing.T) {
+	config := SecurityConfig{RequireAdminTwoFactor: true}
+
+	assert.True(t, config.RequiresTwoFactor("/api/v1/auth/admin/users"))
+	assert.False(t, config.RequiresTwoFactor("/api/v1/auth/profile"))
+
+	config.AdminTwoFactorPaths = []string{"/api/v1/products/"}
+	assert.True(t, config.RequiresTwoFactor("/api/v1/products/42/approve"))
+	assert.False(t, config.RequiresTwoFactor("/api/v1/auth/admin/users"))
+
+	config.RequireAdminTwoFactor = false
+	assert.False(t, config.RequiresTwoFactor("/api/v1/products/42/approve"))
+}
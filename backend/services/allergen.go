@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// DefaultAllergenRegistryPath is the bundled registry of EU and US allergens
+const DefaultAllergenRegistryPath = "data/allergens.json"
+
+// ErrInvalidAllergen is returned for allergies that name no registered
+// allergen
+var ErrInvalidAllergen = errors.New("invalid allergen")
+
+// AllergenRules is the registry entry of an allergen. Keywords are the
+// ingredients and derivatives containing it, e.g. whey for milk and tahini for
+// sesame. Implies lists allergens every food containing this one contains
+// too, so wheat implies gluten.
+type AllergenRules struct {
+	Name        string   `json:"name"`
+	NameAr      string   `json:"name_ar"`
+	Regulations []string `json:"regulations"` // "eu" for the EU 14, "us" for the US top 9
+	Implies     []string `json:"implies,omitempty"`
+	Keywords    []string `json:"keywords"`
+}
+
+// allergenData represents the structure of the allergen registry file.
+// FreeFrom maps labels such as "gluten free" to the allergens they rule out;
+// NotAllergens are phrases that look like an allergen but are not, such as
+// "coconut milk"; CrossContactMarkers introduce allergens that may only be
+// present as traces.
+type allergenData struct {
+	Version             string                   `json:"version"`
+	LastUpdated         string                   `json:"last_updated"`
+	Description         string                   `json:"description"`
+	Allergens           map[string]AllergenRules `json:"allergens"`
+	FreeFrom            map[string][]string      `json:"free_from"`
+	NotAllergens        []string                 `json:"not_allergens"`
+	CrossContactMarkers []string                 `json:"cross_contact_markers"`
+}
+
+// AllergenInfo describes a registered allergen
+type AllergenInfo struct {
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	NameAr      string   `json:"name_ar"`
+	Regulations []string `json:"regulations"`
+}
+
+// AllergenMatch is an allergen found in an ingredient
+type AllergenMatch struct {
+	Allergen     string `json:"allergen"`
+	Ingredient   string `json:"ingredient"`
+	Keyword      string `json:"keyword"`
+	CrossContact bool   `json:"cross_contact"`
+}
+
+// AllergenDetection lists the allergens found in a set of ingredients.
+// MayContain only holds allergens present through cross-contact.
+type AllergenDetection struct {
+	Contains   []string        `json:"contains"`
+	MayContain []string        `json:"may_contain"`
+	Matches    []AllergenMatch `json:"matches"`
+}
+
+// AllergenCheck is the result of checking ingredients against a user's
+// allergies. A check is safe unless a warning is a danger; cross-contact
+// cautions do not make it unsafe.
+type AllergenCheck struct {
+	Safe      bool                     `json:"safe"`
+	Allergies []string                 `json:"allergies"`
+	Warnings  []models.AllergenWarning `json:"warnings"`
+}
+
+// allergenKeyword is a registry phrase reduced to search terms. Phrases that
+// are not allergens have no allergens and only hide the shorter keywords they
+// contain.
+type allergenKeyword struct {
+	keyword   string
+	allergens []string
+	terms     []string
+}
+
+// allergenSpan is a keyword found in an ingredient
+type allergenSpan struct {
+	*allergenKeyword
+	start, end int
+}
+
+// AllergenRegistry detects allergens in ingredient lists. Matching works on
+// search terms like the halal checks, so it ignores case, Arabic spelling
+// variants and English inflections, and the longest phrase wins: "peanut
+// butter" is peanuts, not milk.
+type AllergenRegistry struct {
+	mu       sync.RWMutex
+	data     *allergenData
+	keywords []allergenKeyword
+	freeFrom []allergenKeyword
+	markers  [][]string
+	path     string
+}
+
+// NewAllergenRegistry creates a registry loaded from path
+func NewAllergenRegistry(path string) (*AllergenRegistry, error) {
+	r := &AllergenRegistry{path: path}
+	if err := r.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load allergen registry: %w", err)
+	}
+	return r, nil
+}
+
+// Load reads the registry file
+func (r *AllergenRegistry) Load() error {
+	raw, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read allergen registry: %w", err)
+	}
+	var data allergenData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to parse allergen registry: %w", err)
+	}
+	for code, rules := range data.Allergens {
+		for _, implied := range rules.Implies {
+			if _, ok := data.Allergens[implied]; !ok {
+				return fmt.Errorf("allergen %s implies unknown allergen %s", code, implied)
+			}
+		}
+	}
+
+	var keywords, freeFrom []allergenKeyword
+	add := func(list *[]allergenKeyword, phrase string, allergens []string) {
+		if terms := searchTerms(normalizeAdditiveCodes(phrase)); len(terms) > 0 {
+			*list = append(*list, allergenKeyword{keyword: phrase, allergens: allergens, terms: terms})
+		}
+	}
+	for _, code := range sortedRegistryKeys(data.Allergens) {
+		rules := data.Allergens[code]
+		allergens := append([]string{code}, rules.Implies...)
+		for _, keyword := range rules.Keywords {
+			add(&keywords, keyword, allergens)
+		}
+	}
+	for _, phrase := range data.NotAllergens {
+		add(&keywords, phrase, nil)
+	}
+	for _, label := range sortedRegistryKeys(data.FreeFrom) {
+		add(&freeFrom, label, data.FreeFrom[label])
+	}
+	var markers [][]string
+	for _, marker := range data.CrossContactMarkers {
+		if terms := searchTerms(marker); len(terms) > 0 {
+			markers = append(markers, terms)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data = &data
+	r.keywords = keywords
+	r.freeFrom = freeFrom
+	r.markers = markers
+	return nil
+}
+
+// Version returns the version of the loaded registry
+func (r *AllergenRegistry) Version() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.data.Version
+}
+
+// Allergens lists the registered allergens by code
+func (r *AllergenRegistry) Allergens() []AllergenInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	allergens := make([]AllergenInfo, 0, len(r.data.Allergens))
+	for _, code := range sortedRegistryKeys(r.data.Allergens) {
+		rules := r.data.Allergens[code]
+		allergens = append(allergens, AllergenInfo{Code: code, Name: rules.Name, NameAr: rules.NameAr, Regulations: rules.Regulations})
+	}
+	return allergens
+}
+
+// Normalize maps allergy names to registry codes. Codes are kept; other names
+// such as "dairy", "shellfish" or "حليب" become the allergens they name,
+// without the ones those imply.
+func (r *AllergenRegistry) Normalize(names []string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := map[string]bool{}
+	for _, name := range names {
+		code := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := r.data.Allergens[code]; ok {
+			set[code] = true
+			continue
+		}
+
+		found := map[string]bool{}
+		for _, match := range r.detectIngredient(name) {
+			if !match.CrossContact {
+				found[match.Allergen] = true
+			}
+		}
+		for allergen := range found {
+			for _, implied := range r.data.Allergens[allergen].Implies {
+				delete(found, implied)
+			}
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("%w: %q is not a known allergen", ErrInvalidAllergen, name)
+		}
+		for allergen := range found {
+			set[allergen] = true
+		}
+	}
+	return sortedKeys(set), nil
+}
+
+// Detect finds the allergens in ingredients
+func (r *AllergenRegistry) Detect(ingredients []string) *AllergenDetection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	detection := &AllergenDetection{Contains: []string{}, MayContain: []string{}, Matches: []AllergenMatch{}}
+	contains, mayContain := map[string]bool{}, map[string]bool{}
+	for _, ingredient := range ingredients {
+		for _, match := range r.detectIngredient(ingredient) {
+			detection.Matches = append(detection.Matches, match)
+			if match.CrossContact {
+				mayContain[match.Allergen] = true
+			} else {
+				contains[match.Allergen] = true
+			}
+		}
+	}
+	for allergen := range contains {
+		delete(mayContain, allergen)
+	}
+	detection.Contains = append(detection.Contains, sortedKeys(contains)...)
+	detection.MayContain = append(detection.MayContain, sortedKeys(mayContain)...)
+	return detection
+}
+
+// Check warns about the allergies found in ingredients: a danger for each
+// ingredient containing one, a caution for possible cross-contact
+func (r *AllergenRegistry) Check(allergies, ingredients []string) *AllergenCheck {
+	check := &AllergenCheck{Safe: true, Allergies: allergies, Warnings: []models.AllergenWarning{}}
+	if len(allergies) == 0 {
+		return check
+	}
+	allergic := map[string]bool{}
+	for _, allergen := range allergies {
+		allergic[allergen] = true
+	}
+
+	detection := r.Detect(ingredients)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, match := range detection.Matches {
+		if !allergic[match.Allergen] {
+			continue
+		}
+		rules := r.data.Allergens[match.Allergen]
+		warning := models.AllergenWarning{
+			Allergen:     match.Allergen,
+			Name:         rules.Name,
+			NameAr:       rules.NameAr,
+			Ingredient:   match.Ingredient,
+			Keyword:      match.Keyword,
+			Severity:     models.AllergenDanger,
+			CrossContact: match.CrossContact,
+			Message:      fmt.Sprintf("%s contains %s", match.Ingredient, strings.ToLower(rules.Name)),
+		}
+		if match.CrossContact {
+			warning.Severity = models.AllergenCaution
+			warning.Message = fmt.Sprintf("%s may contain traces of %s", match.Ingredient, strings.ToLower(rules.Name))
+		} else {
+			check.Safe = false
+		}
+		check.Warnings = append(check.Warnings, warning)
+	}
+	return check
+}
+
+// detectIngredient returns the allergens of one ingredient, once each.
+// Allergens named after a cross-contact marker such as "may contain" are
+// cross-contact. A free-from label drops the matches before the marker only,
+// so cross-contact warnings are always reported.
+func (r *AllergenRegistry) detectIngredient(ingredient string) []AllergenMatch {
+	terms := searchTerms(normalizeAdditiveCodes(ingredient))
+
+	markerStart := len(terms)
+	for _, marker := range r.markers {
+		for start := 0; start+len(marker) <= markerStart; start++ {
+			if termsEqual(terms[start:start+len(marker)], marker) {
+				markerStart = start
+				break
+			}
+		}
+	}
+	ruledOut := map[string]bool{}
+	for _, label := range r.freeFrom {
+		if containsAnyTerms(terms, [][]string{label.terms}) {
+			for _, allergen := range label.allergens {
+				ruledOut[allergen] = true
+			}
+		}
+	}
+
+	var found []allergenSpan
+	for i := range r.keywords {
+		keyword := &r.keywords[i]
+		for start := 0; start+len(keyword.terms) <= len(terms); start++ {
+			if termsEqual(terms[start:start+len(keyword.terms)], keyword.terms) {
+				found = append(found, allergenSpan{allergenKeyword: keyword, start: start, end: start + len(keyword.terms)})
+			}
+		}
+	}
+
+	var matches []AllergenMatch
+	index := map[string]int{}
+	for _, span := range found {
+		covered := false
+		for _, other := range found {
+			if other.end-other.start > span.end-span.start && other.start <= span.start && span.end <= other.end {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		for _, allergen := range span.allergens {
+			crossContact := span.start >= markerStart
+			if ruledOut[allergen] && !crossContact {
+				continue
+			}
+			match := AllergenMatch{Allergen: allergen, Ingredient: ingredient, Keyword: span.keyword, CrossContact: crossContact}
+			if i, ok := index[allergen]; !ok {
+				index[allergen] = len(matches)
+				matches = append(matches, match)
+			} else if matches[i].CrossContact && !match.CrossContact {
+				matches[i] = match
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Allergen < matches[j].Allergen })
+	return matches
+}
+
+// AllergyService stores each user's allergies and checks foods, recipes and
+// meals against them with the shared AllergenRegistry
+type AllergyService struct {
+	db       *sql.DB
+	registry *AllergenRegistry
+}
+
+// NewAllergyService creates a new allergy service checking with registry
+func NewAllergyService(db *sql.DB, registry *AllergenRegistry) *AllergyService {
+	return &AllergyService{
+		db:       db,
+		registry: registry,
+	}
+}
+
+// Registry returns the shared allergen registry
+func (s *AllergyService) Registry() *AllergenRegistry {
+	return s.registry
+}
+
+// Allergies returns the allergen codes userID must avoid
+func (s *AllergyService) Allergies(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT allergen FROM user_allergies WHERE user_id = ? ORDER BY allergen`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allergies: %w", err)
+	}
+	defer rows.Close()
+
+	allergies := []string{}
+	for rows.Next() {
+		var allergen string
+		if err := rows.Scan(&allergen); err != nil {
+			return nil, fmt.Errorf("failed to scan allergy: %w", err)
+		}
+		allergies = append(allergies, allergen)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get allergies: %w", err)
+	}
+	return allergies, nil
+}
+
+// UpdateAllergies replaces userID's allergies with the allergens names map to
+func (s *AllergyService) UpdateAllergies(ctx context.Context, userID string, names []string) (*models.UserAllergies, error) {
+	allergies, err := s.registry.Normalize(names)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_allergies WHERE user_id = ?`, userID); err != nil {
+		return nil, fmt.Errorf("failed to clear allergies: %w", err)
+	}
+	now := time.Now().UTC()
+	for _, allergen := range allergies {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_allergies (user_id, allergen, created_at) VALUES (?, ?, ?)`,
+			userID, allergen, now); err != nil {
+			return nil, fmt.Errorf("failed to save allergy: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit allergies: %w", err)
+	}
+
+	return &models.UserAllergies{UserID: userID, Allergens: allergies}, nil
+}
+
+// Check checks ingredients against userID's allergies
+func (s *AllergyService) Check(ctx context.Context, userID string, ingredients []string) (*AllergenCheck, error) {
+	allergies, err := s.Allergies(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.registry.Check(allergies, ingredients), nil
+}
+
+// CheckFoodLog checks the food or recipe of a diary entry against userID's
+// allergies. Its name, ingredients and declared allergens are all checked.
+func (s *AllergyService) CheckFoodLog(ctx context.Context, userID string, foodID, recipeID *string) (*AllergenCheck, error) {
+	allergies, err := s.Allergies(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(allergies) == 0 {
+		return s.registry.Check(allergies, nil), nil
+	}
+
+	item, err := loadFoodLogItem(ctx, s.db, foodID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	texts := append([]string{item.name}, item.ingredients...)
+	texts = append(texts, item.allergens...)
+	return s.registry.Check(allergies, texts), nil
+}
+
+// foodLogItem is the food or recipe a diary entry refers to
+type foodLogItem struct {
+	name        string
+	ingredients []string
+	allergens   []string
+}
+
+// loadFoodLogItem reads the name, ingredients and declared allergens of the
+// food or recipe a diary entry refers to
+func loadFoodLogItem(ctx context.Context, db *sql.DB, foodID, recipeID *string) (*foodLogItem, error) {
+	var (
+		name, ingredients, allergens string
+		notFound                     error
+		row                          *sql.Row
+	)
+	switch {
+	case foodID != nil:
+		notFound = ErrFoodNotFound
+		row = db.QueryRowContext(ctx, `SELECT name, COALESCE(ingredients, '[]'), COALESCE(allergens, '[]')
+			FROM foods WHERE id = ?`, *foodID)
+	case recipeID != nil:
+		notFound = ErrRecipeNotFound
+		row = db.QueryRowContext(ctx, `SELECT name, COALESCE(ingredients, '[]'), COALESCE(allergens, '[]')
+			FROM recipes WHERE id = ?`, *recipeID)
+	default:
+		return nil, fmt.Errorf("%w: food_id or recipe_id is required", ErrInvalidFoodLog)
+	}
+	err := row.Scan(&name, &ingredients, &allergens)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingredients: %w", err)
+	}
+
+	return &foodLogItem{
+		name:        name,
+		ingredients: recipeIngredientNames(ingredients),
+		allergens:   decodeStringList(allergens),
+	}, nil
+}
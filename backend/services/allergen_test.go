@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAllergenRegistry(t *testing.T) *AllergenRegistry {
+	t.Helper()
+	registry, err := NewAllergenRegistry("../" + DefaultAllergenRegistryPath)
+	require.NoError(t, err)
+	return registry
+}
+
+func newTestAllergyService(t *testing.T) (*AllergyService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "allergic@example.com", Password: "password123"})
+	require.NoError(t, err)
+	return NewAllergyService(users.db, newTestAllergenRegistry(t)), user.ID
+}
+
+func TestAllergenRegistry_Detect(t *testing.T) {
+	registry := newTestAllergenRegistry(t)
+
+	// Derivatives map to the allergen they come from
+	detection := registry.Detect([]string{"2 tbsp whey protein", "tahini"})
+	assert.Equal(t, []string{"milk", "sesame"}, detection.Contains)
+	assert.Empty(t, detection.MayContain)
+
+	// Longer phrases win over the allergens they mention
+	detection = registry.Detect([]string{"peanut butter", "coconut milk", "cocoa butter"})
+	assert.Equal(t, []string{"peanuts"}, detection.Contains)
+
+	// Wheat implies gluten, in Arabic too
+	assert.Equal(t, []string{"gluten", "wheat"}, registry.Detect([]string{"whole wheat flour"}).Contains)
+	assert.Equal(t, []string{"gluten", "wheat"}, registry.Detect([]string{"برغل ناعم"}).Contains)
+
+	// Free-from labels rule allergens out
+	assert.Empty(t, registry.Detect([]string{"gluten free pasta"}).Contains)
+
+	// Allergens after a marker are cross-contact only
+	detection = registry.Detect([]string{"dark chocolate (may contain traces of hazelnuts)"})
+	assert.Empty(t, detection.Contains)
+	assert.Equal(t, []string{"tree_nuts"}, detection.MayContain)
+	require.Len(t, detection.Matches, 1)
+	assert.True(t, detection.Matches[0].CrossContact)
+}
+
+func TestAllergenRegistry_Check(t *testing.T) {
+	registry := newTestAllergenRegistry(t)
+
+	check := registry.Check([]string{"milk", "tree_nuts"}, []string{"butter", "oats (may contain almonds)", "rice"})
+	assert.False(t, check.Safe)
+	require.Len(t, check.Warnings, 2)
+	assert.Equal(t, "milk", check.Warnings[0].Allergen)
+	assert.Equal(t, models.AllergenDanger, check.Warnings[0].Severity)
+	assert.Equal(t, "butter contains milk", check.Warnings[0].Message)
+	assert.Equal(t, "tree_nuts", check.Warnings[1].Allergen)
+	assert.Equal(t, models.AllergenCaution, check.Warnings[1].Severity)
+
+	// Cautions alone do not make the check unsafe
+	check = registry.Check([]string{"tree_nuts"}, []string{"oats (may contain almonds)"})
+	assert.True(t, check.Safe)
+	assert.Len(t, check.Warnings, 1)
+
+	check = registry.Check(nil, []string{"butter"})
+	assert.True(t, check.Safe)
+	assert.Empty(t, check.Warnings)
+}
+
+func TestAllergenRegistry_CheckFreeFromWithCrossContact(t *testing.T) {
+	registry := newTestAllergenRegistry(t)
+
+	// A free-from label does not hide a cross-contact warning for the allergen
+	tests := []struct {
+		ingredient string
+		allergy    string
+	}{
+		{"vegan dark chocolate (may contain milk)", "milk"},
+		{"gluten free oats, may contain wheat", "wheat"},
+		{"nut free granola may contain peanuts", "peanuts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ingredient, func(t *testing.T) {
+			check := registry.Check([]string{tt.allergy}, []string{tt.ingredient})
+			assert.True(t, check.Safe)
+			require.Len(t, check.Warnings, 1)
+			assert.Equal(t, tt.allergy, check.Warnings[0].Allergen)
+			assert.Equal(t, models.AllergenCaution, check.Warnings[0].Severity)
+		})
+	}
+}
+
+func TestAllergenRegistry_Normalize(t *testing.T) {
+	registry := newTestAllergenRegistry(t)
+
+	allergens, err := registry.Normalize([]string{"Dairy", "sesame", "shellfish"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"crustaceans", "milk", "molluscs", "sesame"}, allergens)
+
+	_, err = registry.Normalize([]string{"moonlight"})
+	assert.ErrorIs(t, err, ErrInvalidAllergen)
+}
+
+func TestAllergyService_UpdateAllergies(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAllergyService(t)
+
+	allergies, err := svc.Allergies(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, allergies)
+
+	updated, err := svc.UpdateAllergies(ctx, userID, []string{"peanuts", "dairy"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"milk", "peanuts"}, updated.Allergens)
+
+	// Updates replace the previous allergies
+	_, err = svc.UpdateAllergies(ctx, userID, []string{"eggs"})
+	require.NoError(t, err)
+	allergies, err = svc.Allergies(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eggs"}, allergies)
+
+	_, err = svc.UpdateAllergies(ctx, userID, []string{"eggs", "moonlight"})
+	assert.ErrorIs(t, err, ErrInvalidAllergen)
+	allergies, err = svc.Allergies(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eggs"}, allergies)
+}
+
+func TestAllergyService_CheckFoodLog(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAllergyService(t)
+
+	_, err := svc.db.Exec(`INSERT INTO foods (id, name, ingredients, allergens) VALUES
+		('hummus', 'Hummus', '["chickpeas", "tahini", "lemon"]', '[]'),
+		('bar', 'Protein bar', '["oats", "dates"]', '["milk"]')`)
+	require.NoError(t, err)
+	hummus, bar, missing := "hummus", "bar", "missing"
+
+	// Without allergies everything is safe
+	check, err := svc.CheckFoodLog(ctx, userID, &hummus, nil)
+	require.NoError(t, err)
+	assert.True(t, check.Safe)
+
+	_, err = svc.UpdateAllergies(ctx, userID, []string{"sesame", "milk"})
+	require.NoError(t, err)
+
+	check, err = svc.CheckFoodLog(ctx, userID, &hummus, nil)
+	require.NoError(t, err)
+	assert.False(t, check.Safe)
+	// Both the food's name and its tahini are sesame
+	require.Len(t, check.Warnings, 2)
+	assert.Equal(t, "sesame", check.Warnings[0].Allergen)
+	assert.Equal(t, "Hummus", check.Warnings[0].Ingredient)
+	assert.Equal(t, "tahini", check.Warnings[1].Ingredient)
+
+	// Declared allergens count as well as ingredients
+	check, err = svc.CheckFoodLog(ctx, userID, &bar, nil)
+	require.NoError(t, err)
+	assert.False(t, check.Safe)
+
+	_, err = svc.CheckFoodLog(ctx, userID, &missing, nil)
+	assert.ErrorIs(t, err, ErrFoodNotFound)
+	_, err = svc.CheckFoodLog(ctx, userID, nil, &missing)
+	assert.ErrorIs(t, err, ErrRecipeNotFound)
+	_, err = svc.CheckFoodLog(ctx, userID, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidFoodLog)
+}
+
+func TestMealPlanService_ExcludesAllergens(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestMealPlanService(t)
+	svc.allergies = NewAllergyService(svc.db, newTestAllergenRegistry(t))
+	_, err := svc.allergies.UpdateAllergies(ctx, userID, []string{"dairy", "gluten"})
+	require.NoError(t, err)
+
+	target := 1800
+	plan, err := svc.GenerateMealPlan(ctx, userID, models.GenerateMealPlanRequest{
+		TargetCalories: &target,
+		MealsPerDay:    2,
+		Duration:       2,
+	})
+	require.NoError(t, err)
+	for _, day := range plan.Days {
+		for _, meal := range day.Meals {
+			// Only the kabsa and salmon sushi have neither dairy nor gluten;
+			// the tabbouleh's bulgur is wheat
+			assert.Contains(t, []string{"1", "5"}, meal.RecipeID)
+		}
+	}
+}
+
+func TestSearchService_ExcludesAllergens(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestSearchService(t)
+	svc.allergens = newTestAllergenRegistry(t)
+
+	results, err := svc.Search(ctx, models.SearchRequest{Query: "protein", Allergies: []string{"milk"}})
+	require.NoError(t, err)
+	require.Equal(t, 1, results.Total)
+	assert.Equal(t, "salad", results.Results[0].ID)
+	assert.Equal(t, 1, results.Facets[models.SearchTypeRecipe])
+
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "protein"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, results.Total)
+}
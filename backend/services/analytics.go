@@ -58,8 +58,17 @@ func NewAnalyticsService(db *sql.DB) *AnalyticsService {
 	return service
 }
 
-// RecordAPIUsage records API usage metrics
+// RecordAPIUsage records API usage metrics. Each request is also stored in
+// api_key_usage, which GetUsageReport aggregates.
 func (s *AnalyticsService) RecordAPIUsage(apiKeyID, endpoint, method string, statusCode int, responseTime int64, ipAddress, userAgent string) {
+	query := `
+		INSERT INTO api_key_usage (api_key_id, endpoint, method, status_code, response_time, ip_address, user_agent, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, apiKeyID, endpoint, method, statusCode, responseTime, ipAddress, userAgent, time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to record usage for API key %s: %v", apiKeyID, err)
+	}
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -233,8 +242,16 @@ func (s *AnalyticsService) GetGlobalMetrics() (*APIMetrics, error) {
 
 // GetUsageReport generates a comprehensive usage report
 func (s *AnalyticsService) GetUsageReport(apiKeyID string, days int) (*UsageReport, error) {
-	endDate := time.Now()
+	endDate := time.Now().UTC()
 	startDate := endDate.AddDate(0, 0, -days)
+	return s.GetUsageReportForPeriod(apiKeyID, startDate, endDate)
+}
+
+// GetUsageReportForPeriod generates a usage report for requests made between
+// startDate and endDate inclusive
+func (s *AnalyticsService) GetUsageReportForPeriod(apiKeyID string, startDate, endDate time.Time) (*UsageReport, error) {
+	startDate = startDate.UTC()
+	endDate = endDate.UTC()
 
 	query := `
 		SELECT 
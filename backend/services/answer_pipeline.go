@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Answer errors returned by AnswerPipeline
+var (
+	ErrInvalidAnswerRequest = errors.New("invalid answer request")
+	ErrAnswerUnavailable    = errors.New("answer intent model is not available")
+)
+
+const (
+	maxAnswerSources = 5
+
+	// minAnswerIntentConfidence is the calibrated probability below which a
+	// question is answered generically from every source type
+	minAnswerIntentConfidence = 0.5
+
+	entityGazetteerTTL = 5 * time.Minute
+)
+
+// IntentDetector scores the intents a question may express, most likely first
+type IntentDetector interface {
+	Classify(terms []string) []models.AnswerIntent
+}
+
+// EntityExtractor finds known foods, drugs and conditions in a question
+type EntityExtractor interface {
+	Extract(ctx context.Context, query string) ([]models.AnswerEntity, error)
+}
+
+// SourceRetriever fetches the records an answer cites, restricted to the given
+// source types when there are any
+type SourceRetriever interface {
+	Retrieve(ctx context.Context, query string, entities []models.AnswerEntity, types []string, limit int) ([]models.AnswerSource, error)
+}
+
+// answerSourceTypes are the source types each intent cites. Intents without an
+// entry cite every type.
+var answerSourceTypes = map[string][]string{
+	models.AnswerIntentRecipe:  {models.SearchTypeRecipe},
+	models.AnswerIntentWorkout: {models.SearchTypeWorkout},
+	models.AnswerIntentHealth:  {models.SearchTypeComplaint, models.SearchTypeDisease},
+	models.AnswerIntentDrug:    {models.AnswerSourceMedication},
+}
+
+// answerDataTypes maps the data_types a request may name to source types
+var answerDataTypes = map[string][]string{
+	"recipes":    {models.SearchTypeRecipe},
+	"workouts":   {models.SearchTypeWorkout},
+	"complaints": {models.SearchTypeComplaint, models.SearchTypeDisease},
+	"drugs":      {models.AnswerSourceMedication},
+}
+
+// AnswerPipeline answers free-text questions in four pluggable steps: entity
+// extraction, intent classification, retrieval of the records to cite, and
+// rendering through an AnswerTemplate
+type AnswerPipeline struct {
+	intents   IntentDetector
+	entities  EntityExtractor
+	retriever SourceRetriever
+	templates *AnswerGenerationService
+}
+
+// NewAnswerPipeline creates an answer pipeline. A nil intent detector makes
+// every answer fail with ErrAnswerUnavailable.
+func NewAnswerPipeline(intents IntentDetector, entities EntityExtractor, retriever SourceRetriever, templates *AnswerGenerationService) *AnswerPipeline {
+	return &AnswerPipeline{
+		intents:   intents,
+		entities:  entities,
+		retriever: retriever,
+		templates: templates,
+	}
+}
+
+// Answer classifies the question, cites the best matching records and renders
+// the answer with the calibrated confidence of its intent
+func (p *AnswerPipeline) Answer(ctx context.Context, req models.AnswerRequest) (*models.AnswerResponse, error) {
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidAnswerRequest)
+	}
+	var types []string
+	for _, dataType := range req.DataTypes {
+		mapped, ok := answerDataTypes[strings.ToLower(strings.TrimSpace(dataType))]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown data type %q, expected one of %s",
+				ErrInvalidAnswerRequest, dataType, strings.Join(sortedKeys(answerDataTypeNames()), ", "))
+		}
+		types = append(types, mapped...)
+	}
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: query has no words", ErrInvalidAnswerRequest)
+	}
+	if p.intents == nil {
+		return nil, ErrAnswerUnavailable
+	}
+
+	entities, err := p.entities.Extract(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entities also count as their type, as the {food}, {drug} and {condition}
+	// slots do in the training questions
+	features := append([]string{}, terms...)
+	for _, entity := range entities {
+		features = append(features, searchTerm(entity.Type))
+	}
+	intents := p.intents.Classify(features)
+	intent, confidence := models.AnswerIntentGeneric, 0.0
+	if len(intents) > 0 {
+		confidence = intents[0].Probability
+		if confidence >= minAnswerIntentConfidence {
+			intent = intents[0].Intent
+		}
+	}
+
+	if len(types) == 0 {
+		types = answerSourceTypes[intent]
+	}
+	sources, err := p.retriever.Retrieve(ctx, query, entities, uniqueStrings(types), maxAnswerSources)
+	if err != nil {
+		return nil, err
+	}
+
+	answer, template := p.templates.Render(query, intent, confidence, entities, sources)
+	response := &models.AnswerResponse{
+		Query:      query,
+		Intent:     intent,
+		Intents:    intents,
+		Confidence: confidence,
+		Entities:   entities,
+		Answer:     answer,
+		Sources:    sources,
+	}
+	if template != nil {
+		response.Intent = template.Intent
+		response.TemplateID = template.ID
+	}
+	return response, nil
+}
+
+func answerDataTypeNames() map[string]bool {
+	names := map[string]bool{}
+	for name := range answerDataTypes {
+		names[name] = true
+	}
+	return names
+}
+
+// EntityGazetteer extracts entities by matching a question's words against the
+// names of foods, medications and health conditions, preferring the longest
+// name at each position. The names are reloaded every few minutes.
+type EntityGazetteer struct {
+	db            *sql.DB
+	knowledgeBase *KnowledgeBase
+
+	mu      sync.Mutex
+	names   map[string][]models.AnswerEntity // keyed by the name's search terms
+	longest int
+	builtAt time.Time
+}
+
+// NewEntityGazetteer creates an entity extractor. The knowledge base is
+// optional and contributes its diseases as conditions.
+func NewEntityGazetteer(db *sql.DB, knowledgeBase *KnowledgeBase) *EntityGazetteer {
+	return &EntityGazetteer{
+		db:            db,
+		knowledgeBase: knowledgeBase,
+	}
+}
+
+// Extract returns each known entity once, in the order it is mentioned
+func (g *EntityGazetteer) Extract(ctx context.Context, query string) ([]models.AnswerEntity, error) {
+	names, longest, err := g.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := tokenizeKnowledge(query)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = searchTerm(token)
+	}
+
+	entities := []models.AnswerEntity{}
+	seen := map[string]bool{}
+	for i := 0; i < len(terms); {
+		matched := 1
+		for n := min(longest, len(terms)-i); n > 0; n-- {
+			found := names[strings.Join(terms[i:i+n], " ")]
+			if len(found) == 0 {
+				continue
+			}
+			for _, entity := range found {
+				if key := entity.Type + ":" + entity.ID; !seen[key] {
+					seen[key] = true
+					entity.Text = strings.Join(tokens[i:i+n], " ")
+					entities = append(entities, entity)
+				}
+			}
+			matched = n
+			break
+		}
+		i += matched
+	}
+	return entities, nil
+}
+
+func (g *EntityGazetteer) load(ctx context.Context) (map[string][]models.AnswerEntity, int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.names != nil && time.Since(g.builtAt) < entityGazetteerTTL {
+		return g.names, g.longest, nil
+	}
+
+	names := map[string][]models.AnswerEntity{}
+	longest := 0
+	add := func(entityType, id, name string, aliases ...string) {
+		for _, alias := range append([]string{name}, aliases...) {
+			terms := searchTerms(alias)
+			if len(terms) == 0 {
+				continue
+			}
+			key := strings.Join(terms, " ")
+			duplicate := false
+			for _, existing := range names[key] {
+				duplicate = duplicate || (existing.Type == entityType && existing.ID == id)
+			}
+			if !duplicate {
+				names[key] = append(names[key], models.AnswerEntity{Type: entityType, ID: id, Name: name})
+				longest = max(longest, len(terms))
+			}
+		}
+	}
+
+	rows, err := g.db.QueryContext(ctx, `SELECT id, name, COALESCE(name_ar, '') FROM foods`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load food names: %w", err)
+	}
+	for rows.Next() {
+		var id, name, nameAr string
+		if err := rows.Scan(&id, &name, &nameAr); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan food name: %w", err)
+		}
+		add(models.AnswerEntityFood, id, name, nameAr)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to load food names: %w", err)
+	}
+
+	rows, err = g.db.QueryContext(ctx, `SELECT id, name, COALESCE(name_ar, ''), COALESCE(generic_name, ''),
+		COALESCE(brand_names, '[]') FROM medications`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load medication names: %w", err)
+	}
+	for rows.Next() {
+		var id, name, nameAr, genericName, brandNames string
+		if err := rows.Scan(&id, &name, &nameAr, &genericName, &brandNames); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan medication name: %w", err)
+		}
+		add(models.AnswerEntityDrug, id, name, append([]string{nameAr, genericName}, decodeStringList(brandNames)...)...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to load medication names: %w", err)
+	}
+
+	rows, err = g.db.QueryContext(ctx, `SELECT id, name, COALESCE(name_ar, '') FROM health_conditions`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load condition names: %w", err)
+	}
+	for rows.Next() {
+		var id, name, nameAr string
+		if err := rows.Scan(&id, &name, &nameAr); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan condition name: %w", err)
+		}
+		add(models.AnswerEntityCondition, id, name, nameAr)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to load condition names: %w", err)
+	}
+
+	if g.knowledgeBase != nil {
+		for _, disease := range g.knowledgeBase.Snapshot().Diseases {
+			add(models.AnswerEntityCondition, disease.Slug, disease.NameEn, disease.NameAr)
+		}
+	}
+
+	g.names, g.longest, g.builtAt = names, longest, time.Now()
+	return names, longest, nil
+}
+
+// RecordRetriever cites medications named in the question and the search
+// documents matching any of its words
+type RecordRetriever struct {
+	db     *sql.DB
+	search *SearchService
+}
+
+// NewRecordRetriever creates a source retriever over the search index
+func NewRecordRetriever(db *sql.DB, search *SearchService) *RecordRetriever {
+	return &RecordRetriever{
+		db:     db,
+		search: search,
+	}
+}
+
+// Retrieve returns up to limit sources numbered from 1. Medications come first
+// and carry no score, since they are matched by name rather than ranked.
+func (r *RecordRetriever) Retrieve(ctx context.Context, query string, entities []models.AnswerEntity, types []string, limit int) ([]models.AnswerSource, error) {
+	sources := []models.AnswerSource{}
+	var searchTypes []string
+	for _, sourceType := range types {
+		if sourceType != models.AnswerSourceMedication {
+			searchTypes = append(searchTypes, sourceType)
+		}
+	}
+
+	if len(types) == 0 || len(searchTypes) < len(types) {
+		for _, entity := range entities {
+			if entity.Type != models.AnswerEntityDrug || len(sources) == limit {
+				continue
+			}
+			source, err := r.medication(ctx, entity.ID)
+			if err != nil {
+				return nil, err
+			}
+			if source != nil {
+				sources = append(sources, *source)
+			}
+		}
+	}
+
+	if len(sources) < limit && (len(types) == 0 || len(searchTypes) > 0) {
+		hits, err := r.search.MatchAny(ctx, query, searchTypes, limit-len(sources))
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range hits {
+			sources = append(sources, models.AnswerSource{
+				Type:    hit.Type,
+				ID:      hit.ID,
+				Title:   hit.Title,
+				Snippet: plainSnippet(hit.Snippet),
+				Score:   hit.Score,
+			})
+		}
+	}
+
+	for i := range sources {
+		sources[i].Ref = i + 1
+	}
+	return sources, nil
+}
+
+func (r *RecordRetriever) medication(ctx context.Context, id string) (*models.AnswerSource, error) {
+	var name, foodInteractions, description string
+	err := r.db.QueryRowContext(ctx, `SELECT name, COALESCE(food_interactions, '[]'), COALESCE(description, '')
+		FROM medications WHERE id = ?`, id).Scan(&name, &foodInteractions, &description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load medication: %w", err)
+	}
+
+	snippet := strings.Join(nonBlank(jsonText(foodInteractions)), "; ")
+	if snippet == "" {
+		snippet = description
+	}
+	return &models.AnswerSource{
+		Type:    models.AnswerSourceMedication,
+		ID:      id,
+		Title:   name,
+		Snippet: snippet,
+	}, nil
+}
+
+// plainSnippet turns a highlighted, HTML-escaped search snippet into a single
+// line of plain text
+func plainSnippet(snippet string) string {
+	text := html.UnescapeString(strings.NewReplacer("<mark>", "", "</mark>", "").Replace(snippet))
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentClassifier(t *testing.T) {
+	classifier, err := LoadIntentClassifier("../" + DefaultIntentTrainingPath)
+	require.NoError(t, err)
+	stats := classifier.Stats()
+	assert.Equal(t, 5, stats.Intents)
+	assert.GreaterOrEqual(t, stats.LeaveOneOutAccuracy, 0.6)
+	assert.Greater(t, stats.Temperature, 0.0)
+
+	for query, intent := range map[string]string{
+		"what can I cook for dinner with chicken": models.AnswerIntentRecipe,
+		"exercises for my legs":                   models.AnswerIntentWorkout,
+		"is food good for condition":              models.AnswerIntentHealth,
+		"can I take drug with food":               models.AnswerIntentDrug,
+		"how many calories do I burn running":     models.AnswerIntentMetabolism,
+		"كيف أطبخ الأرز":                          models.AnswerIntentRecipe,
+	} {
+		intents := classifier.Classify(searchTerms(query))
+		require.Len(t, intents, 5)
+		assert.Equal(t, intent, intents[0].Intent, query)
+		assert.Greater(t, intents[0].Probability, minAnswerIntentConfidence, query)
+	}
+
+	// Unknown words leave only the priors, which are equal here
+	intents := classifier.Classify(searchTerms("hello there"))
+	assert.InDelta(t, 0.2, intents[0].Probability, 0.001)
+
+	_, err = TrainIntentClassifier([]IntentExample{{Intent: "recipe", Text: "cook rice"}, {Intent: "recipe", Text: "bake bread"}})
+	assert.ErrorIs(t, err, ErrInvalidIntentTraining)
+	_, err = TrainIntentClassifier([]IntentExample{
+		{Intent: "recipe", Text: "cook rice"}, {Intent: "recipe", Text: "bake bread"}, {Intent: "workout", Text: "squat"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidIntentTraining)
+}
+
+func TestIntentClassifier_Calibration(t *testing.T) {
+	// Held-out examples of two well separated intents are always right, so the
+	// fitted temperature sharpens rather than softens the posteriors
+	var examples []IntentExample
+	for _, text := range []string{"cook rice", "cook pasta", "bake bread", "cook soup bread"} {
+		examples = append(examples, IntentExample{Intent: "recipe", Text: text})
+	}
+	for _, text := range []string{"squat legs", "run miles", "squat run", "lift legs"} {
+		examples = append(examples, IntentExample{Intent: "workout", Text: text})
+	}
+	classifier, err := TrainIntentClassifier(examples)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, classifier.Stats().LeaveOneOutAccuracy)
+	assert.Less(t, classifier.Stats().Temperature, 1.0)
+	assert.Greater(t, classifier.Classify(searchTerms("cook bread"))[0].Probability, 0.9)
+}
+
+func newTestAnswerPipeline(t *testing.T) (*AnswerPipeline, *SearchService) {
+	t.Helper()
+	search := newTestSearchService(t)
+	_, err := search.db.Exec(`INSERT INTO foods (id, name, name_ar) VALUES
+		('rice', 'Basmati Rice', 'أرز بسمتي'), ('spinach', 'Spinach', 'سبانخ'), ('rice-cake', 'Rice', NULL)`)
+	require.NoError(t, err)
+	_, err = search.db.Exec(`INSERT INTO medications (id, name, generic_name, brand_names, food_interactions) VALUES
+		('warfarin', 'Warfarin', 'warfarin sodium', '["Coumadin"]',
+		 '["Keep vitamin K intake from leafy greens such as spinach consistent"]')`)
+	require.NoError(t, err)
+
+	classifier, err := LoadIntentClassifier("../" + DefaultIntentTrainingPath)
+	require.NoError(t, err)
+	return NewAnswerPipeline(classifier,
+		NewEntityGazetteer(search.db, search.knowledgeBase),
+		NewRecordRetriever(search.db, search),
+		NewAnswerGenerationService()), search
+}
+
+func TestEntityGazetteer_Extract(t *testing.T) {
+	_, search := newTestAnswerPipeline(t)
+	gazetteer := NewEntityGazetteer(search.db, search.knowledgeBase)
+
+	// The longest name wins, brand names and Arabic names are aliases, and
+	// knowledge base diseases count as conditions
+	entities, err := gazetteer.Extract(context.Background(), "Basmati rice and Coumadin with Type 2 diabetes, more rice")
+	require.NoError(t, err)
+	require.Len(t, entities, 4)
+	assert.Equal(t, models.AnswerEntity{Type: models.AnswerEntityFood, ID: "rice", Name: "Basmati Rice", Text: "basmati rice"}, entities[0])
+	assert.Equal(t, models.AnswerEntity{Type: models.AnswerEntityDrug, ID: "warfarin", Name: "Warfarin", Text: "coumadin"}, entities[1])
+	assert.Equal(t, models.AnswerEntity{Type: models.AnswerEntityCondition, ID: "type-2-diabetes", Name: "Type 2 Diabetes", Text: "type diabetes"}, entities[2])
+	assert.Equal(t, "rice-cake", entities[3].ID)
+
+	entities, err = gazetteer.Extract(context.Background(), "هل السبانخ مفيدة مع ارتفاع ضغط الدم")
+	require.NoError(t, err)
+	require.Len(t, entities, 2)
+	assert.Equal(t, "spinach", entities[0].ID)
+	assert.Equal(t, "hypertension", entities[1].ID)
+}
+
+func TestAnswerPipeline_Answer(t *testing.T) {
+	ctx := context.Background()
+	pipeline, _ := newTestAnswerPipeline(t)
+
+	answer, err := pipeline.Answer(ctx, models.AnswerRequest{Query: "What can I cook with basmati rice?"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AnswerIntentRecipe, answer.Intent)
+	assert.Equal(t, answer.Intents[0].Probability, answer.Confidence)
+	require.NotEmpty(t, answer.Sources)
+	assert.Equal(t, models.AnswerSource{Ref: 1, Type: models.SearchTypeRecipe, ID: "kabsa", Title: "Chicken Kabsa",
+		Snippet: "Spiced rice with roasted chicken. Saudi chicken, basmati rice halal", Score: answer.Sources[0].Score}, answer.Sources[0])
+	for _, source := range answer.Sources {
+		assert.Equal(t, models.SearchTypeRecipe, source.Type)
+	}
+	assert.Contains(t, answer.Answer, "Chicken Kabsa [1]")
+	assert.Contains(t, []string{"recipe_basic", "recipe_detailed"}, answer.TemplateID)
+
+	// Drug questions cite the medication's recorded food interactions
+	answer, err = pipeline.Answer(ctx, models.AnswerRequest{Query: "Can I take Coumadin with spinach?"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AnswerIntentDrug, answer.Intent)
+	assert.Equal(t, "drug_basic", answer.TemplateID)
+	require.Len(t, answer.Entities, 2)
+	require.Len(t, answer.Sources, 1)
+	assert.Equal(t, models.AnswerSourceMedication, answer.Sources[0].Type)
+	assert.Contains(t, answer.Answer, "You asked about Warfarin (drug), Spinach (food).")
+	assert.Contains(t, answer.Answer, "[1] **Warfarin** — Keep vitamin K intake from leafy greens such as spinach consistent")
+
+	answer, err = pipeline.Answer(ctx, models.AnswerRequest{Query: "What should I eat with hypertension?"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AnswerIntentHealth, answer.Intent)
+	require.NotEmpty(t, answer.Sources)
+	assert.Equal(t, "hypertension", answer.Sources[0].ID)
+	assert.Contains(t, answer.Answer, "Hypertension [1]")
+
+	// Unrecognised questions fall back to the generic template
+	answer, err = pipeline.Answer(ctx, models.AnswerRequest{Query: "hello there"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AnswerIntentGeneric, answer.Intent)
+	assert.Equal(t, "generic_basic", answer.TemplateID)
+	assert.Empty(t, answer.Sources)
+	assert.Contains(t, answer.Answer, "I didn't find specific information")
+
+	// Requested data types override the intent's sources
+	answer, err = pipeline.Answer(ctx, models.AnswerRequest{Query: "rice", DataTypes: []string{"complaints"}})
+	require.NoError(t, err)
+	for _, source := range answer.Sources {
+		assert.Contains(t, []string{models.SearchTypeComplaint, models.SearchTypeDisease}, source.Type)
+	}
+
+	_, err = pipeline.Answer(ctx, models.AnswerRequest{Query: "rice", DataTypes: []string{"injuries"}})
+	assert.ErrorIs(t, err, ErrInvalidAnswerRequest)
+	_, err = pipeline.Answer(ctx, models.AnswerRequest{Query: " ? "})
+	assert.ErrorIs(t, err, ErrInvalidAnswerRequest)
+	_, err = NewAnswerPipeline(nil, nil, nil, nil).Answer(ctx, models.AnswerRequest{Query: "rice"})
+	assert.ErrorIs(t, err, ErrAnswerUnavailable)
+}
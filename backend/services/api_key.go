@@ -1,12 +1,45 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/google/uuid"
+)
+
+// API key errors returned by APIKeyService
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyInactive = errors.New("api key is revoked or expired")
+	ErrInvalidScopes  = errors.New("invalid api key scopes")
+	ErrInvalidTier    = errors.New("invalid api key tier")
+	// ErrAdminScopeForbidden is returned when a user without permission to
+	// manage API keys asks for the admin scope
+	ErrAdminScopeForbidden = errors.New("admin scope requires permission to manage api keys")
 )
 
+const (
+	apiKeyPrefix            = "nk"
+	defaultAPIKeyRateLimit  = 100
+	apiKeyPlaintextWarning  = "Store this key securely. It will not be shown again."
+	apiKeyLastUsedPrecision = time.Minute
+)
+
+const apiKeyColumns = `id, name, key_hash, prefix, user_id, status, scopes, tier, rate_limit,
+	expires_at, last_used_at, created_at, updated_at, metadata`
+
 // APIKeyService handles API key-related operations
 type APIKeyService struct {
-	db *sql.DB
+	db   *sql.DB
+	rbac *RBACService
 }
 
 // NewAPIKeyService creates a new APIKeyService instance
@@ -15,3 +48,370 @@ func NewAPIKeyService(db *sql.DB) *APIKeyService {
 		db: db,
 	}
 }
+
+// SetRBAC lets every role granted models.PermissionAPIKeysManage give keys
+// the admin scope. Without it only admins can.
+func (s *APIKeyService) SetRBAC(rbac *RBACService) {
+	s.rbac = rbac
+}
+
+// CreateAPIKey issues a new key for userID. The plaintext key is only part of
+// the returned response; the database stores its SHA-256 hash.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID string, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	if err := s.checkScopes(ctx, userID, req.Scopes); err != nil {
+		return nil, err
+	}
+
+	key, keyHash, err := models.GenerateAPIKey(apiKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	apiKey := &models.APIKey{
+		ID:        uuid.New().String(),
+		Name:      strings.TrimSpace(req.Name),
+		KeyHash:   keyHash,
+		Prefix:    models.GetPrefix(key),
+		UserID:    userID,
+		Status:    models.APIKeyStatusActive,
+		Scopes:    req.Scopes,
+		Tier:      models.TierFree,
+		RateLimit: req.RateLimit,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Metadata:  req.Metadata,
+	}
+	if apiKey.RateLimit <= 0 {
+		apiKey.RateLimit = defaultAPIKeyRateLimit
+	}
+	if apiKey.Metadata == nil {
+		apiKey.Metadata = map[string]interface{}{}
+	}
+	if req.ExpiresIn != nil && *req.ExpiresIn > 0 {
+		expiresAt := now.AddDate(0, 0, *req.ExpiresIn)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	scopesJSON, metadataJSON, err := marshalAPIKeyFields(apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO api_keys (id, name, key_hash, prefix, user_id, status, scopes, tier, rate_limit,
+			expires_at, created_at, updated_at, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.db.ExecContext(ctx, query,
+		apiKey.ID, apiKey.Name, apiKey.KeyHash, apiKey.Prefix, apiKey.UserID, apiKey.Status,
+		scopesJSON, apiKey.Tier, apiKey.RateLimit, apiKey.ExpiresAt, apiKey.CreatedAt, apiKey.UpdatedAt, metadataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &models.CreateAPIKeyResponse{
+		APIKey:  apiKey,
+		Key:     key,
+		Warning: apiKeyPlaintextWarning,
+	}, nil
+}
+
+// ListAPIKeys returns a page of the keys owned by userID, newest first
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string, page, limit int) (*models.APIKeyListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM api_keys WHERE user_id = ?`, userID).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count api keys: %w", err)
+	}
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = ?
+		ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	rows, err := s.db.QueryContext(ctx, query, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *apiKey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyListResponse{
+		APIKeys: keys,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	}, nil
+}
+
+// GetAPIKey returns key id if it belongs to userID
+func (s *APIKeyService) GetAPIKey(ctx context.Context, userID, id string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ? AND user_id = ?`
+	return scanAPIKey(s.db.QueryRowContext(ctx, query, id, userID))
+}
+
+// GetAPIKeyByID returns key id regardless of its owner. It is meant for
+// administrators and billing.
+func (s *APIKeyService) GetAPIKeyByID(ctx context.Context, id string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ?`
+	return scanAPIKey(s.db.QueryRowContext(ctx, query, id))
+}
+
+// SetAPIKeyTier moves a key to another plan tier. The new quotas apply to the
+// next request made with the key.
+func (s *APIKeyService) SetAPIKeyTier(ctx context.Context, id string, tier models.APIKeyTier) (*models.APIKey, error) {
+	if !tier.IsValid() {
+		return nil, ErrInvalidTier
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE api_keys SET tier = ?, updated_at = ? WHERE id = ?`,
+		tier, time.Now().UTC(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update api key tier: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update api key tier: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return s.GetAPIKeyByID(ctx, id)
+}
+
+// UpdateAPIKey changes the name, scopes, rate limit or metadata of a key
+func (s *APIKeyService) UpdateAPIKey(ctx context.Context, userID, id string, req models.UpdateAPIKeyRequest) (*models.APIKey, error) {
+	apiKey, err := s.GetAPIKey(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.Status == models.APIKeyStatusRevoked {
+		return nil, ErrAPIKeyInactive
+	}
+
+	if req.Name != nil {
+		apiKey.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Scopes != nil {
+		if err := s.checkScopes(ctx, userID, req.Scopes); err != nil {
+			return nil, err
+		}
+		apiKey.Scopes = req.Scopes
+	}
+	if req.RateLimit != nil {
+		apiKey.RateLimit = *req.RateLimit
+	}
+	if req.Metadata != nil {
+		apiKey.Metadata = req.Metadata
+	}
+	apiKey.UpdatedAt = time.Now().UTC()
+
+	scopesJSON, metadataJSON, err := marshalAPIKeyFields(apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE api_keys SET name = ?, scopes = ?, rate_limit = ?, metadata = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`
+	_, err = s.db.ExecContext(ctx, query,
+		apiKey.Name, scopesJSON, apiKey.RateLimit, metadataJSON, apiKey.UpdatedAt, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update api key: %w", err)
+	}
+
+	return apiKey, nil
+}
+
+// RotateAPIKey replaces the secret of a key while keeping its id, scopes and
+// usage history. The previous secret stops working immediately.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, userID, id string) (*models.CreateAPIKeyResponse, error) {
+	apiKey, err := s.GetAPIKey(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.Status == models.APIKeyStatusRevoked {
+		return nil, ErrAPIKeyInactive
+	}
+
+	key, keyHash, err := models.GenerateAPIKey(apiKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey.KeyHash = keyHash
+	apiKey.Prefix = models.GetPrefix(key)
+	apiKey.UpdatedAt = time.Now().UTC()
+
+	query := `UPDATE api_keys SET key_hash = ?, prefix = ?, updated_at = ? WHERE id = ? AND user_id = ?`
+	if _, err := s.db.ExecContext(ctx, query, apiKey.KeyHash, apiKey.Prefix, apiKey.UpdatedAt, id, userID); err != nil {
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+
+	return &models.CreateAPIKeyResponse{
+		APIKey:  apiKey,
+		Key:     key,
+		Warning: apiKeyPlaintextWarning,
+	}, nil
+}
+
+// RevokeAPIKey permanently disables a key. Its usage history is kept.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	query := `UPDATE api_keys SET status = ?, updated_at = ? WHERE id = ? AND user_id = ?`
+	result, err := s.db.ExecContext(ctx, query, models.APIKeyStatusRevoked, time.Now().UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plaintext key sent by a client. It returns
+// ErrInvalidAPIKey for unknown keys and ErrAPIKeyInactive for revoked or
+// expired ones.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if !models.ValidateAPIKeyFormat(key) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = ?`
+	apiKey, err := scanAPIKey(s.db.QueryRowContext(ctx, query, models.HashAPIKey(key)))
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if !apiKey.IsActive() {
+		return nil, ErrAPIKeyInactive
+	}
+
+	// Only write last_used_at once per minute to keep hot keys off the write path
+	now := time.Now().UTC()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedPrecision {
+		if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, now, apiKey.ID); err != nil {
+			return nil, fmt.Errorf("failed to update api key usage: %w", err)
+		}
+		apiKey.LastUsedAt = &now
+	}
+
+	return apiKey, nil
+}
+
+func marshalAPIKeyFields(apiKey *models.APIKey) (string, string, error) {
+	scopesJSON, err := json.Marshal(apiKey.Scopes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal scopes: %w", err)
+	}
+	metadataJSON, err := json.Marshal(apiKey.Metadata)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return string(scopesJSON), string(metadataJSON), nil
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var (
+		apiKey                models.APIKey
+		userID, metadata      sql.NullString
+		scopes                string
+		expiresAt, lastUsedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&apiKey.ID,
+		&apiKey.Name,
+		&apiKey.KeyHash,
+		&apiKey.Prefix,
+		&userID,
+		&apiKey.Status,
+		&scopes,
+		&apiKey.Tier,
+		&apiKey.RateLimit,
+		&expiresAt,
+		&lastUsedAt,
+		&apiKey.CreatedAt,
+		&apiKey.UpdatedAt,
+		&metadata,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	apiKey.UserID = userID.String
+	if expiresAt.Valid {
+		apiKey.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		apiKey.LastUsedAt = &lastUsedAt.Time
+	}
+	if err := json.Unmarshal([]byte(scopes), &apiKey.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to parse api key scopes: %w", err)
+	}
+	apiKey.Metadata = map[string]interface{}{}
+	if metadata.Valid && metadata.String != "" {
+		if err := json.Unmarshal([]byte(metadata.String), &apiKey.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse api key metadata: %w", err)
+		}
+	}
+
+	return &apiKey, nil
+}
+
+// checkScopes validates scopes requested for a key of userID. The admin scope
+// needs a role allowed to manage API keys.
+func (s *APIKeyService) checkScopes(ctx context.Context, userID string, scopes []models.APIKeyScope) error {
+	if err := models.ValidateScopes(scopes); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidScopes, err)
+	}
+	admin := false
+	for _, scope := range scopes {
+		admin = admin || scope == models.ScopeAdmin
+	}
+	if !admin {
+		return nil
+	}
+
+	var role string
+	if err := s.db.QueryRowContext(ctx, `SELECT role FROM users WHERE id = ?`, userID).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAdminScopeForbidden
+		}
+		return fmt.Errorf("failed to get user role: %w", err)
+	}
+
+	allowed := role == models.RoleAdmin
+	if s.rbac != nil {
+		var err error
+		if allowed, err = s.rbac.HasPermission(ctx, role, models.PermissionAPIKeysManage); err != nil {
+			return err
+		}
+	}
+	if !allowed {
+		return ErrAdminScopeForbidden
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIKeyService(t *testing.T) (*APIKeyService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "partner@example.com", Password: "password123"})
+	require.NoError(t, err)
+	return NewAPIKeyService(users.db), user.ID
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAPIKeyService(t)
+
+	created, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+		Name:   "Partner app",
+		Scopes: []models.APIKeyScope{models.ScopeNutrition, models.ScopeReadOnly},
+	})
+	require.NoError(t, err)
+	assert.True(t, models.ValidateAPIKeyFormat(created.Key))
+	assert.NotContains(t, created.APIKey.KeyHash, created.Key)
+	assert.Equal(t, defaultAPIKeyRateLimit, created.APIKey.RateLimit)
+
+	apiKey, err := svc.AuthenticateAPIKey(ctx, created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, created.APIKey.ID, apiKey.ID)
+	assert.NotNil(t, apiKey.LastUsedAt)
+	assert.True(t, apiKey.CanAccess("/api/v1/nutrition-data/recipes", "GET"))
+	assert.False(t, apiKey.CanAccess("/api/v1/nutrition-data/generate-answer", "POST"))
+	assert.False(t, apiKey.CanAccess("/api/v1/meals", "GET"))
+
+	// The plaintext key is not retrievable afterwards
+	stored, err := svc.GetAPIKey(ctx, userID, created.APIKey.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.APIKeyScope{models.ScopeNutrition, models.ScopeReadOnly}, stored.Scopes)
+
+	_, err = svc.AuthenticateAPIKey(ctx, "nk_"+stored.KeyHash)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = svc.AuthenticateAPIKey(ctx, "not-a-key")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, err = svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+		Name:   "Bad scopes",
+		Scopes: []models.APIKeyScope{"everything"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidScopes)
+}
+
+func TestAPIKeyService_RotateAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAPIKeyService(t)
+
+	created, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+		Name:   "Partner app",
+		Scopes: []models.APIKeyScope{models.ScopeReadWrite},
+	})
+	require.NoError(t, err)
+
+	rotated, err := svc.RotateAPIKey(ctx, userID, created.APIKey.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.APIKey.ID, rotated.APIKey.ID)
+	assert.NotEqual(t, created.Key, rotated.Key)
+
+	_, err = svc.AuthenticateAPIKey(ctx, created.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = svc.AuthenticateAPIKey(ctx, rotated.Key)
+	require.NoError(t, err)
+
+	_, err = svc.RotateAPIKey(ctx, "someone-else", created.APIKey.ID)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.ErrorIs(t, svc.RevokeAPIKey(ctx, "someone-else", created.APIKey.ID), ErrAPIKeyNotFound)
+
+	require.NoError(t, svc.RevokeAPIKey(ctx, userID, created.APIKey.ID))
+	_, err = svc.AuthenticateAPIKey(ctx, rotated.Key)
+	assert.ErrorIs(t, err, ErrAPIKeyInactive)
+	_, err = svc.RotateAPIKey(ctx, userID, created.APIKey.ID)
+	assert.ErrorIs(t, err, ErrAPIKeyInactive)
+}
+
+func TestAPIKeyService_ListAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAPIKeyService(t)
+
+	for _, name := range []string{"First", "Second", "Third"} {
+		_, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+			Name:   name,
+			Scopes: []models.APIKeyScope{models.ScopeReadOnly},
+		})
+		require.NoError(t, err)
+	}
+
+	list, err := svc.ListAPIKeys(ctx, userID, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, list.Total)
+	assert.Len(t, list.APIKeys, 2)
+
+	name := "Renamed"
+	rateLimit := 500
+	updated, err := svc.UpdateAPIKey(ctx, userID, list.APIKeys[0].ID, models.UpdateAPIKeyRequest{
+		Name:      &name,
+		RateLimit: &rateLimit,
+		Scopes:    []models.APIKeyScope{models.ScopeMeals, models.ScopeReadOnly},
+	})
+	require.NoError(t, err)
+
+	stored, err := svc.GetAPIKey(ctx, userID, updated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", stored.Name)
+	assert.Equal(t, 500, stored.RateLimit)
+	assert.True(t, stored.HasScope(models.ScopeMeals))
+}
+
+func TestAnalyticsService_RecordAPIUsagePersistsUsage(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAPIKeyService(t)
+
+	created, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+		Name:   "Partner app",
+		Scopes: []models.APIKeyScope{models.ScopeReadOnly},
+	})
+	require.NoError(t, err)
+
+	analytics := NewAnalyticsService(svc.db)
+	defer analytics.Stop()
+
+	analytics.RecordAPIUsage(created.APIKey.ID, "/api/v1/nutrition-data/recipes", "GET", 200, 12, "127.0.0.1", "test")
+	analytics.RecordAPIUsage(created.APIKey.ID, "/api/v1/nutrition-data/recipes", "GET", 404, 8, "127.0.0.1", "test")
+
+	report, err := analytics.GetUsageReport(created.APIKey.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, report.DailyStats, 1)
+	assert.EqualValues(t, 2, report.DailyStats[0].TotalRequests)
+	assert.EqualValues(t, 1, report.DailyStats[0].ErrorRequests)
+}
+
+func TestAPIKeyService_SetAPIKeyTier(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAPIKeyService(t)
+
+	created, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+		Name:      "Partner app",
+		Scopes:    []models.APIKeyScope{models.ScopeReadOnly},
+		RateLimit: 300,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.TierFree, created.APIKey.Tier)
+	assert.Equal(t, models.APIKeyTierQuotas[models.TierFree], created.APIKey.Quota())
+
+	updated, err := svc.SetAPIKeyTier(ctx, created.APIKey.ID, models.TierPro)
+	require.NoError(t, err)
+	assert.Equal(t, models.TierPro, updated.Tier)
+	// The key's own per-minute limit is below the pro tier's
+	assert.Equal(t, 300, updated.Quota().RequestsPerMinute)
+	assert.Equal(t, models.APIKeyTierQuotas[models.TierPro].RequestsPerMonth, updated.Quota().RequestsPerMonth)
+
+	_, err = svc.SetAPIKeyTier(ctx, created.APIKey.ID, "platinum")
+	assert.ErrorIs(t, err, ErrInvalidTier)
+	_, err = svc.SetAPIKeyTier(ctx, "missing", models.TierPro)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
+
+func TestAnalyticsService_GetMonthlyStatement(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAPIKeyService(t)
+
+	created, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+		Name:   "Partner app",
+		Scopes: []models.APIKeyScope{models.ScopeReadOnly},
+	})
+	require.NoError(t, err)
+
+	analytics := NewAnalyticsService(svc.db)
+	defer analytics.Stop()
+
+	insert := `INSERT INTO api_key_usage (api_key_id, endpoint, method, status_code, response_time, timestamp)
+		VALUES (?, '/api/v1/nutrition-data/recipes', 'GET', ?, 10, ?)`
+	for _, row := range []struct {
+		status int
+		at     time.Time
+	}{
+		{200, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{200, time.Date(2026, 3, 1, 13, 30, 0, 0, time.UTC)},
+		{429, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{200, time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)},
+		{200, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{200, time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC)},
+	} {
+		_, err := svc.db.Exec(insert, created.APIKey.ID, row.status, row.at)
+		require.NoError(t, err)
+	}
+
+	statement, err := analytics.GetMonthlyStatement(created.APIKey, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03", statement.Period)
+	assert.EqualValues(t, 4, statement.TotalRequests)
+	assert.EqualValues(t, 3, statement.BillableRequests)
+	assert.EqualValues(t, 1, statement.ErrorRequests)
+	require.Len(t, statement.Days, 3)
+	assert.Equal(t, "2026-03-01", statement.Days[0].Date)
+	assert.InDelta(t, 0.03, statement.QuotaUsedPercent, 0.0001)
+
+	var buf bytes.Buffer
+	require.NoError(t, statement.WriteCSV(&buf))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, "date", records[0][3])
+	assert.Equal(t, []string{created.APIKey.ID, "free", "2026-03", "total", "4", "3", "1", ""}, records[4])
+}
+
+func TestAPIKeyService_AdminScopeNeedsPermission(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestAPIKeyService(t)
+	rbac := NewRBACService(svc.db)
+	svc.SetRBAC(rbac)
+	adminScopes := []models.APIKeyScope{models.ScopeAdmin}
+
+	_, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{Name: "Escalate", Scopes: adminScopes})
+	assert.ErrorIs(t, err, ErrAdminScopeForbidden)
+
+	created, err := svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{
+		Name:   "Partner app",
+		Scopes: []models.APIKeyScope{models.ScopeReadOnly},
+	})
+	require.NoError(t, err)
+	_, err = svc.UpdateAPIKey(ctx, userID, created.APIKey.ID, models.UpdateAPIKeyRequest{Scopes: adminScopes})
+	assert.ErrorIs(t, err, ErrAdminScopeForbidden)
+	stored, err := svc.GetAPIKey(ctx, userID, created.APIKey.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.APIKeyScope{models.ScopeReadOnly}, stored.Scopes)
+
+	// Roles granted the permission to manage API keys may issue admin keys
+	require.NoError(t, rbac.AssignUserRole(ctx, userID, models.RoleDietitian))
+	require.NoError(t, rbac.GrantPermission(ctx, models.RoleDietitian, models.PermissionAPIKeysManage))
+	_, err = svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{Name: "Clinic admin", Scopes: adminScopes})
+	assert.NoError(t, err)
+
+	// Without RBAC only the admin role can
+	svc.SetRBAC(nil)
+	_, err = svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{Name: "Escalate", Scopes: adminScopes})
+	assert.ErrorIs(t, err, ErrAdminScopeForbidden)
+	require.NoError(t, rbac.AssignUserRole(ctx, userID, models.RoleAdmin))
+	_, err = svc.CreateAPIKey(ctx, userID, models.CreateAPIKeyRequest{Name: "Admin", Scopes: adminScopes})
+	assert.NoError(t, err)
+}
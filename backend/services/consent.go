@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// ErrUnknownConsentPurpose is returned for a purpose missing from the consent
+// registry
+var ErrUnknownConsentPurpose = errors.New("unknown consent purpose")
+
+// consentPurpose is a registered purpose with the data erased when consent to
+// it is withdrawn
+type consentPurpose struct {
+	models.ConsentPurpose
+	withdrawal []consentWithdrawal
+}
+
+// consentWithdrawal erases one kind of data on withdrawal. statement takes
+// the user ID as its only argument.
+type consentWithdrawal struct {
+	dataType  string
+	statement string
+}
+
+// consentPurposes is the consent registry. Bump a purpose's Version when its
+// terms change so that users are asked again.
+var consentPurposes = []consentPurpose{
+	{
+		ConsentPurpose: models.ConsentPurpose{
+			ID:   models.ConsentHealthData,
+			Name: "Health data processing",
+			Description: "Store your health complaints, injuries, medications and supplements and use them to check " +
+				"interactions, find nutrient deficiencies and adapt your plans",
+			Version:      "1",
+			ValidDays:    365,
+			OnWithdrawal: "Your health complaints, injuries, medications and supplements are deleted",
+		},
+		withdrawal: []consentWithdrawal{
+			{"health_complaints", `DELETE FROM user_health_complaints WHERE user_id = ?`},
+			{"injuries", `DELETE FROM user_injuries WHERE user_id = ?`},
+			{"medications", `DELETE FROM user_medications WHERE user_id = ?`},
+			{"supplements", `DELETE FROM user_supplements WHERE user_id = ?`},
+		},
+	},
+	{
+		ConsentPurpose: models.ConsentPurpose{
+			ID:   models.ConsentAIRecommendations,
+			Name: "AI recommendations",
+			Description: "Use your logs, measurements and goals to generate personalized meal, workout and " +
+				"nutrition plan recommendations",
+			Version:      "1",
+			ValidDays:    365,
+			OnWithdrawal: "Personalized recommendations stop; the data you logged is kept",
+		},
+	},
+	{
+		ConsentPurpose: models.ConsentPurpose{
+			ID:           models.ConsentMarketing,
+			Name:         "Marketing",
+			Description:  "Email you news, offers and product updates",
+			Version:      "1",
+			ValidDays:    730,
+			OnWithdrawal: "You stop receiving marketing email",
+		},
+	},
+	{
+		ConsentPurpose: models.ConsentPurpose{
+			ID:           models.ConsentResearch,
+			Name:         "Research",
+			Description:  "Include your pseudonymized data in nutrition and fitness research",
+			Version:      "1",
+			ValidDays:    365,
+			OnWithdrawal: "Your data is left out of research datasets from now on",
+		},
+	},
+}
+
+func lookupConsentPurpose(id string) (*consentPurpose, error) {
+	for i := range consentPurposes {
+		if consentPurposes[i].ID == id {
+			return &consentPurposes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownConsentPurpose, id)
+}
+
+// ConsentPurposes returns the purposes users can consent to
+func ConsentPurposes() []models.ConsentPurpose {
+	purposes := make([]models.ConsentPurpose, 0, len(consentPurposes))
+	for _, purpose := range consentPurposes {
+		purposes = append(purposes, purpose.ConsentPurpose)
+	}
+	return purposes
+}
+
+// GrantConsent records the user's consent to the current version of purpose.
+// Granting again renews consent that is about to expire.
+func (g *GDPRCompliance) GrantConsent(ctx context.Context, userID, purposeID string, client SessionClient) (*models.ConsentRecord, error) {
+	purpose, err := lookupConsentPurpose(purposeID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+	defer tx.Rollback()
+
+	expiresAt := time.Now().UTC().AddDate(0, 0, purpose.ValidDays)
+	consent, err := recordConsent(ctx, tx, userID, purpose, true, &expiresAt, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+
+	if err := g.audit(ctx, models.GDPRAuditEntry{
+		UserID:    userID,
+		Operation: models.GDPROperationConsentUpdate,
+		Status:    models.GDPRStatusCompleted,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		DataTypes: []string{purpose.ID},
+	}); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// WithdrawConsent records the withdrawal of the user's consent to purpose and
+// erases the data that was only kept under it
+func (g *GDPRCompliance) WithdrawConsent(ctx context.Context, userID, purposeID string, client SessionClient) (*models.ConsentRecord, error) {
+	purpose, err := lookupConsentPurpose(purposeID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to withdraw consent: %w", err)
+	}
+	defer tx.Rollback()
+
+	consent, err := recordConsent(ctx, tx, userID, purpose, false, nil, client)
+	if err != nil {
+		return nil, err
+	}
+	dataTypes := []string{purpose.ID}
+	for _, withdrawal := range purpose.withdrawal {
+		if _, err := tx.ExecContext(ctx, withdrawal.statement, userID); err != nil {
+			return nil, fmt.Errorf("failed to erase %s: %w", withdrawal.dataType, err)
+		}
+		dataTypes = append(dataTypes, withdrawal.dataType)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to withdraw consent: %w", err)
+	}
+
+	if err := g.audit(ctx, models.GDPRAuditEntry{
+		UserID:    userID,
+		Operation: models.GDPROperationConsentUpdate,
+		Status:    models.GDPRStatusCompleted,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		DataTypes: dataTypes,
+	}); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// ConsentStatuses returns the user's current consent to every purpose
+func (g *GDPRCompliance) ConsentStatuses(ctx context.Context, userID string) ([]models.ConsentStatus, error) {
+	history, err := g.GetUserConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	latest := map[string]*models.ConsentRecord{}
+	for i := range history {
+		if _, ok := latest[history[i].ConsentType]; !ok {
+			latest[history[i].ConsentType] = &history[i]
+		}
+	}
+
+	now := time.Now().UTC()
+	statuses := make([]models.ConsentStatus, 0, len(consentPurposes))
+	for _, purpose := range consentPurposes {
+		statuses = append(statuses, consentStatus(purpose.ConsentPurpose, latest[purpose.ID], now))
+	}
+	return statuses, nil
+}
+
+// HasConsent reports whether the user currently consents to the current
+// version of purpose
+func (g *GDPRCompliance) HasConsent(ctx context.Context, userID, purposeID string) (bool, error) {
+	purpose, err := lookupConsentPurpose(purposeID)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		consent   models.ConsentRecord
+		expiresAt sql.NullTime
+	)
+	err = g.db.QueryRowContext(ctx, `SELECT granted, version, expires_at, created_at FROM consent_records
+		WHERE user_id = ? AND consent_type = ? ORDER BY created_at DESC, id DESC LIMIT 1`, userID, purpose.ID).
+		Scan(&consent.Granted, &consent.Version, &expiresAt, &consent.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check consent: %w", err)
+	}
+	consent.ExpiresAt = nullTimePtr(expiresAt)
+	return consentStatus(purpose.ConsentPurpose, &consent, time.Now().UTC()).Active, nil
+}
+
+// GetUserConsents returns the user's consent history, newest first
+func (g *GDPRCompliance) GetUserConsents(ctx context.Context, userID string) ([]models.ConsentRecord, error) {
+	rows, err := g.db.QueryContext(ctx, `SELECT id, user_id, consent_type, granted, version, ip_address, user_agent,
+		expires_at, created_at FROM consent_records WHERE user_id = ? ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := []models.ConsentRecord{}
+	for rows.Next() {
+		var (
+			consent              models.ConsentRecord
+			ipAddress, userAgent sql.NullString
+			expiresAt            sql.NullTime
+		)
+		if err := rows.Scan(&consent.ID, &consent.UserID, &consent.ConsentType, &consent.Granted, &consent.Version,
+			&ipAddress, &userAgent, &expiresAt, &consent.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan consent: %w", err)
+		}
+		consent.IPAddress, consent.UserAgent = ipAddress.String, userAgent.String
+		consent.ExpiresAt = nullTimePtr(expiresAt)
+		consents = append(consents, consent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get consents: %w", err)
+	}
+	return consents, nil
+}
+
+func recordConsent(ctx context.Context, tx *sql.Tx, userID string, purpose *consentPurpose, granted bool, expiresAt *time.Time, client SessionClient) (*models.ConsentRecord, error) {
+	consent := &models.ConsentRecord{
+		UserID:      userID,
+		ConsentType: purpose.ID,
+		Granted:     granted,
+		Version:     purpose.Version,
+		IPAddress:   client.IPAddress,
+		UserAgent:   client.UserAgent,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now().UTC(),
+	}
+	result, err := tx.ExecContext(ctx, `INSERT INTO consent_records
+		(user_id, consent_type, granted, version, ip_address, user_agent, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, purpose.ID, granted, purpose.Version, nullString(client.IPAddress), nullString(client.UserAgent),
+		expiresAt, consent.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+	if consent.ID, err = result.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+	return consent, nil
+}
+
+// consentStatus derives the state of purpose from the user's latest consent
+// record for it, which is nil when there is none
+func consentStatus(purpose models.ConsentPurpose, latest *models.ConsentRecord, now time.Time) models.ConsentStatus {
+	status := models.ConsentStatus{Purpose: purpose, State: models.ConsentMissing}
+	if latest == nil {
+		return status
+	}
+	status.Version = latest.Version
+	status.UpdatedAt = &latest.CreatedAt
+	status.ExpiresAt = latest.ExpiresAt
+
+	switch {
+	case !latest.Granted:
+		status.State = models.ConsentWithdrawn
+	case latest.Version != purpose.Version:
+		status.State = models.ConsentOutdated
+	case latest.ExpiresAt != nil && !now.Before(*latest.ExpiresAt):
+		status.State = models.ConsentExpired
+	default:
+		status.State = models.ConsentGranted
+		status.Active = true
+	}
+	return status
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func consentStatusOf(t *testing.T, gdpr *GDPRCompliance, userID, purpose string) models.ConsentStatus {
+	t.Helper()
+	statuses, err := gdpr.ConsentStatuses(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(consentPurposes))
+	for _, status := range statuses {
+		if status.Purpose.ID == purpose {
+			return status
+		}
+	}
+	t.Fatalf("no status for %s", purpose)
+	return models.ConsentStatus{}
+}
+
+func TestGDPRCompliance_ConsentLifecycle(t *testing.T) {
+	ctx := context.Background()
+	gdpr, _, userID, _ := newTestGDPRCompliance(t)
+
+	allowed, err := gdpr.HasConsent(ctx, userID, models.ConsentHealthData)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, models.ConsentMissing, consentStatusOf(t, gdpr, userID, models.ConsentHealthData).State)
+
+	_, err = gdpr.GrantConsent(ctx, userID, "profiling", SessionClient{})
+	assert.ErrorIs(t, err, ErrUnknownConsentPurpose)
+	_, err = gdpr.HasConsent(ctx, userID, "profiling")
+	assert.ErrorIs(t, err, ErrUnknownConsentPurpose)
+
+	consent, err := gdpr.GrantConsent(ctx, userID, models.ConsentHealthData, SessionClient{IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+	assert.True(t, consent.Granted)
+	assert.Equal(t, "1", consent.Version)
+	require.NotNil(t, consent.ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 365), *consent.ExpiresAt, time.Minute)
+
+	allowed, err = gdpr.HasConsent(ctx, userID, models.ConsentHealthData)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	status := consentStatusOf(t, gdpr, userID, models.ConsentHealthData)
+	assert.Equal(t, models.ConsentGranted, status.State)
+	assert.True(t, status.Active)
+
+	// Consent to one purpose does not cover another
+	allowed, err = gdpr.HasConsent(ctx, userID, models.ConsentAIRecommendations)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Expired consent and consent to an earlier version no longer count
+	_, err = gdpr.db.Exec(`UPDATE consent_records SET expires_at = ? WHERE id = ?`, time.Now().UTC().Add(-time.Hour), consent.ID)
+	require.NoError(t, err)
+	allowed, err = gdpr.HasConsent(ctx, userID, models.ConsentHealthData)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, models.ConsentExpired, consentStatusOf(t, gdpr, userID, models.ConsentHealthData).State)
+
+	_, err = gdpr.db.Exec(`UPDATE consent_records SET expires_at = NULL, version = '0' WHERE id = ?`, consent.ID)
+	require.NoError(t, err)
+	allowed, err = gdpr.HasConsent(ctx, userID, models.ConsentHealthData)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, models.ConsentOutdated, consentStatusOf(t, gdpr, userID, models.ConsentHealthData).State)
+
+	// Granting again renews it
+	_, err = gdpr.GrantConsent(ctx, userID, models.ConsentHealthData, SessionClient{})
+	require.NoError(t, err)
+	allowed, err = gdpr.HasConsent(ctx, userID, models.ConsentHealthData)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestGDPRCompliance_WithdrawConsentErasesHealthData(t *testing.T) {
+	ctx := context.Background()
+	gdpr, _, userID, _ := newTestGDPRCompliance(t)
+
+	_, err := gdpr.GrantConsent(ctx, userID, models.ConsentHealthData, SessionClient{})
+	require.NoError(t, err)
+	for _, statement := range []string{
+		`INSERT INTO user_health_complaints (user_id, complaint_type) VALUES (?, 'headache')`,
+		`INSERT INTO user_injuries (user_id, custom_injury_name) VALUES (?, 'sprained ankle')`,
+		`INSERT INTO user_medications (user_id, medication_id) VALUES (?, 'metformin')`,
+		`INSERT INTO user_supplements (user_id, supplement_name) VALUES (?, 'vitamin d')`,
+	} {
+		_, err := gdpr.db.Exec(statement, userID)
+		require.NoError(t, err)
+	}
+	_, err = gdpr.db.Exec(`INSERT INTO user_food_logs (user_id, quantity, consumed_at) VALUES (?, 1, ?)`, userID, time.Now())
+	require.NoError(t, err)
+
+	consent, err := gdpr.WithdrawConsent(ctx, userID, models.ConsentHealthData, SessionClient{})
+	require.NoError(t, err)
+	assert.False(t, consent.Granted)
+	assert.Nil(t, consent.ExpiresAt)
+
+	allowed, err := gdpr.HasConsent(ctx, userID, models.ConsentHealthData)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, models.ConsentWithdrawn, consentStatusOf(t, gdpr, userID, models.ConsentHealthData).State)
+
+	var count int
+	for _, table := range []string{"user_health_complaints", "user_injuries", "user_medications", "user_supplements"} {
+		require.NoError(t, gdpr.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE user_id = ?`, userID).Scan(&count))
+		assert.Zero(t, count, "rows left in %s", table)
+	}
+	// Data not kept under the purpose is untouched
+	require.NoError(t, gdpr.db.QueryRow(`SELECT COUNT(*) FROM user_food_logs WHERE user_id = ?`, userID).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	entries, err := gdpr.GetAuditLog(ctx, 1, userID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.GDPROperationConsentUpdate, entries[0].Operation)
+	assert.Equal(t, []string{models.ConsentHealthData, "health_complaints", "injuries", "medications", "supplements"},
+		entries[0].DataTypes)
+
+	// Withdrawing a purpose without stored data only records the withdrawal
+	_, err = gdpr.WithdrawConsent(ctx, userID, models.ConsentMarketing, SessionClient{})
+	require.NoError(t, err)
+
+	history, err := gdpr.GetUserConsents(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, models.ConsentMarketing, history[0].ConsentType)
+
+	// The whole history is part of the export
+	var archive bytes.Buffer
+	metadata, err := gdpr.ExportArchive(ctx, userID, SessionClient{}, &archive)
+	require.NoError(t, err)
+	assert.Equal(t, 3, metadata.Records["consents"])
+	assert.Zero(t, metadata.Records["medications"])
+	var data map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal(readZip(t, archive.Bytes())["data.json"], &data))
+	require.Len(t, data["consents"], 3)
+	assert.Equal(t, models.ConsentHealthData, data["consents"][0]["consent_type"])
+	assert.Equal(t, "1", data["consents"][0]["version"])
+}
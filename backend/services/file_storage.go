@@ -1,24 +1,60 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"nutrition-platform/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
 )
 
-// StorageProvider defines the interface for file storage operations
+// File storage errors returned by FileStorageService and the storage providers
+var (
+	ErrFileTooLarge       = errors.New("file too large")
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+	ErrInvalidSignedURL   = errors.New("invalid or expired signed URL")
+)
+
+// StorageProvider defines the interface for file storage operations. Files
+// are private; SignedURL grants temporary read access to one of them.
 type StorageProvider interface {
 	UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error)
 	DeleteFile(ctx context.Context, fileURL string) error
 	GetFile(ctx context.Context, fileURL string) (io.ReadCloser, error)
 	GetPublicURL(ctx context.Context, fileURL string) string
+	SignedURL(ctx context.Context, fileURL string, ttl time.Duration) (string, error)
+}
+
+// NewStorageProvider creates the provider selected by the storage configuration
+func NewStorageProvider(cfg config.FileStorageConfig) (StorageProvider, error) {
+	switch cfg.StorageType {
+	case "", "local":
+		provider := NewLocalStorageProvider(cfg.BasePath, cfg.BaseURL)
+		provider.SetSigningKey([]byte(cfg.SigningKey))
+		return provider, nil
+	case "s3":
+		return NewS3StorageProvider(cfg.S3Bucket, cfg.S3Region, cfg.S3URL, cfg.S3AccessKey, cfg.S3SecretKey)
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", cfg.StorageType)
+	}
 }
 
 // FileUploadRequest represents a file upload request
@@ -28,19 +64,22 @@ type FileUploadRequest struct {
 	Purpose      string // "profile", "meal", "progress", etc.
 	ValidateSize bool
 	ValidateType bool
+	ImagesOnly   bool
 }
 
 // FileUploadResponse represents the response after successful upload
 type FileUploadResponse struct {
-	FileID      string `json:"file_id"`
-	FileName    string `json:"file_name"`
-	FileURL     string `json:"file_url"`
+	FileID       string `json:"file_id"`
+	FileName     string `json:"file_name"`
+	FileURL      string `json:"file_url"`
 	ThumbnailURL string `json:"thumbnail_url,omitempty"`
-	Size        int64  `json:"size"`
-	ContentType string `json:"content_type"`
-	UploaderID  string `json:"uploader_id"`
-	Purpose     string `json:"purpose"`
-	UploadedAt  string `json:"uploaded_at"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	UploaderID   string `json:"uploader_id"`
+	Purpose      string `json:"purpose"`
+	UploadedAt   string `json:"uploaded_at"`
 }
 
 // FileInfo represents information about a stored file
@@ -60,83 +99,79 @@ type FileInfo struct {
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// LocalStorageProvider implements file storage for local development
+// LocalStorageProvider implements file storage for local development. Files
+// are only served through URLs signed with the provider's signing key.
 type LocalStorageProvider struct {
-	basePath string
-	baseURL  string
+	basePath   string
+	baseURL    string
+	signingKey []byte
 }
 
 // NewLocalStorageProvider creates a new local storage provider
 func NewLocalStorageProvider(basePath, baseURL string) *LocalStorageProvider {
 	return &LocalStorageProvider{
 		basePath: basePath,
-		baseURL:  baseURL,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
 	}
 }
 
+// SetSigningKey sets the HMAC key that signs and verifies file URLs
+func (ls *LocalStorageProvider) SetSigningKey(key []byte) {
+	ls.signingKey = key
+}
+
 // UploadFile uploads a file to local storage
 func (ls *LocalStorageProvider) UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error) {
 	// Create unique filename
 	ext := filepath.Ext(filename)
 	baseName := strings.TrimSuffix(filename, ext)
 	uniqueFilename := fmt.Sprintf("%s_%s%s", baseName, uuid.New().String()[:8], ext)
-	
-	// Create directory if it doesn't exist
-	fullPath := filepath.Join(ls.basePath, uniqueFilename)
-	dir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+
+	fullPath, err := ls.path(uniqueFilename)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
-	
+
 	// Create the file
 	dst, err := os.Create(fullPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to create file: %w", err)
 	}
 	defer dst.Close()
-	
+
 	// Copy the file content
 	if _, err := io.Copy(dst, file); err != nil {
 		return "", fmt.Errorf("failed to save file: %w", err)
 	}
-	
-	// Return the public URL
+
 	return fmt.Sprintf("%s/%s", ls.baseURL, uniqueFilename), nil
 }
 
 // DeleteFile deletes a file from local storage
 func (ls *LocalStorageProvider) DeleteFile(ctx context.Context, fileURL string) error {
-	// Extract filename from URL
-	parts := strings.Split(fileURL, "/")
-	if len(parts) == 0 {
-		return fmt.Errorf("invalid file URL")
-	}
-	
-	filename := parts[len(parts)-1]
-	fullPath := filepath.Join(ls.basePath, filename)
-	
+	fullPath, err := ls.path(fileURL)
+	if err != nil {
+		return err
+	}
 	if err := os.Remove(fullPath); err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
-	
 	return nil
 }
 
 // GetFile retrieves a file from local storage
 func (ls *LocalStorageProvider) GetFile(ctx context.Context, fileURL string) (io.ReadCloser, error) {
-	parts := strings.Split(fileURL, "/")
-	if len(parts) == 0 {
-		return nil, fmt.Errorf("invalid file URL")
-	}
-	
-	filename := parts[len(parts)-1]
-	fullPath := filepath.Join(ls.basePath, filename)
-	
+	fullPath, err := ls.path(fileURL)
+	if err != nil {
+		return nil, err
+	}
 	file, err := os.Open(fullPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
-	
 	return file, nil
 }
 
@@ -145,50 +180,170 @@ func (ls *LocalStorageProvider) GetPublicURL(ctx context.Context, fileURL string
 	return fileURL
 }
 
-// S3StorageProvider implements file storage for AWS S3 (placeholder for production)
+// SignedURL returns the file URL with an expiry and an HMAC signature that
+// OpenSignedFile checks
+func (ls *LocalStorageProvider) SignedURL(ctx context.Context, fileURL string, ttl time.Duration) (string, error) {
+	if len(ls.signingKey) == 0 {
+		return "", fmt.Errorf("local storage has no signing key")
+	}
+	fullPath, err := ls.path(fileURL)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(ls.basePath, fullPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL")
+	}
+	rel = filepath.ToSlash(rel)
+	expires := time.Now().Add(ttl).Unix()
+	return fmt.Sprintf("%s/%s?expires=%d&signature=%s", ls.baseURL, rel, expires, ls.sign(rel, expires)), nil
+}
+
+// OpenSignedFile opens the file at rel, relative to the base URL, if the
+// signature matches and has not expired
+func (ls *LocalStorageProvider) OpenSignedFile(ctx context.Context, rel, expires, signature string) (io.ReadCloser, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || len(ls.signingKey) == 0 || time.Now().Unix() > expiresAt {
+		return nil, ErrInvalidSignedURL
+	}
+	expected := ls.sign(path.Clean(rel), expiresAt)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, ErrInvalidSignedURL
+	}
+	return ls.GetFile(ctx, rel)
+}
+
+func (ls *LocalStorageProvider) sign(rel string, expires int64) string {
+	mac := hmac.New(sha256.New, ls.signingKey)
+	fmt.Fprintf(mac, "%s\n%d", rel, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path maps a file URL returned by UploadFile, or a path relative to the
+// base URL, to its location under basePath
+func (ls *LocalStorageProvider) path(fileURL string) (string, error) {
+	rel := strings.TrimPrefix(fileURL, ls.baseURL+"/")
+	if i := strings.IndexAny(rel, "?#"); i >= 0 {
+		rel = rel[:i]
+	}
+	rel = path.Clean("/" + rel)[1:]
+	if rel == "" {
+		return "", fmt.Errorf("invalid file URL")
+	}
+	return filepath.Join(ls.basePath, filepath.FromSlash(rel)), nil
+}
+
+// S3StorageProvider implements private file storage in an AWS S3 bucket
 type S3StorageProvider struct {
-	bucket    string
-	region    string
-	baseURL   string
-	accessKey string
-	secretKey string
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	region  string
+	baseURL string
 }
 
-// NewS3StorageProvider creates a new S3 storage provider
-func NewS3StorageProvider(bucket, region, baseURL, accessKey, secretKey string) *S3StorageProvider {
-	return &S3StorageProvider{
-		bucket:    bucket,
-		region:    region,
-		baseURL:   baseURL,
-		accessKey: accessKey,
-		secretKey: secretKey,
+// NewS3StorageProvider creates a new S3 storage provider. Without an access
+// key the default AWS credential chain is used.
+func NewS3StorageProvider(bucket, region, baseURL, accessKey, secretKey string) (*S3StorageProvider, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("S3 storage requires a bucket")
+	}
+	options := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(region)}
+	if accessKey != "" {
+		options = append(options, awsconfig.WithCredentialsProvider(aws.CredentialsProviderFunc(
+			func(ctx context.Context) (aws.Credentials, error) {
+				return aws.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey}, nil
+			})))
 	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	}
+
+	client := s3.NewFromConfig(cfg)
+	return &S3StorageProvider{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		bucket:  bucket,
+		region:  region,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
 }
 
-// UploadFile uploads a file to S3
-func (s3 *S3StorageProvider) UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error) {
-	// TODO: Implement S3 upload logic
-	// This is a placeholder implementation
-	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s3.bucket, s3.region, filename), nil
+// UploadFile uploads a file to S3 as a private object
+func (s3p *S3StorageProvider) UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error) {
+	ext := path.Ext(filename)
+	key := fmt.Sprintf("%s_%s%s", strings.TrimSuffix(filename, ext), uuid.New().String()[:8], ext)
+
+	// The request is signed over its payload, which needs a seekable body
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file content: %w", err)
+	}
+	_, err = s3p.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s3p.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return fmt.Sprintf("%s/%s", s3p.baseURL, key), nil
 }
 
 // DeleteFile deletes a file from S3
-func (s3 *S3StorageProvider) DeleteFile(ctx context.Context, fileURL string) error {
-	// TODO: Implement S3 delete logic
+func (s3p *S3StorageProvider) DeleteFile(ctx context.Context, fileURL string) error {
+	_, err := s3p.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s3p.bucket),
+		Key:    aws.String(s3p.key(fileURL)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from S3: %w", err)
+	}
 	return nil
 }
 
 // GetFile retrieves a file from S3
-func (s3 *S3StorageProvider) GetFile(ctx context.Context, fileURL string) (io.ReadCloser, error) {
-	// TODO: Implement S3 get logic
-	return nil, fmt.Errorf("S3 storage not implemented yet")
+func (s3p *S3StorageProvider) GetFile(ctx context.Context, fileURL string) (io.ReadCloser, error) {
+	resp, err := s3p.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s3p.bucket),
+		Key:    aws.String(s3p.key(fileURL)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object from S3: %w", err)
+	}
+	return resp.Body, nil
 }
 
 // GetPublicURL returns the public URL for a file
-func (s3 *S3StorageProvider) GetPublicURL(ctx context.Context, fileURL string) string {
+func (s3p *S3StorageProvider) GetPublicURL(ctx context.Context, fileURL string) string {
 	return fileURL
 }
 
+// SignedURL returns a presigned GET URL for the object
+func (s3p *S3StorageProvider) SignedURL(ctx context.Context, fileURL string, ttl time.Duration) (string, error) {
+	req, err := s3p.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s3p.bucket),
+		Key:    aws.String(s3p.key(fileURL)),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign S3 URL: %w", err)
+	}
+	return req.URL, nil
+}
+
+func (s3p *S3StorageProvider) key(fileURL string) string {
+	key := strings.TrimPrefix(fileURL, s3p.baseURL+"/")
+	if i := strings.IndexAny(key, "?#"); i >= 0 {
+		key = key[:i]
+	}
+	return key
+}
+
 // FileStorageService manages file uploads and storage
 type FileStorageService struct {
 	storageProvider StorageProvider
@@ -208,7 +363,7 @@ func NewFileStorageService(provider StorageProvider, processor *ImageProcessorSe
 		"video/mp4":  true,
 		"video/webm": true,
 	}
-	
+
 	return &FileStorageService{
 		storageProvider: provider,
 		processor:       processor,
@@ -217,58 +372,84 @@ func NewFileStorageService(provider StorageProvider, processor *ImageProcessorSe
 	}
 }
 
-// UploadFile handles the complete file upload process
+// processableImageTypes are re-encoded on upload; other files are stored as sent
+var processableImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// UploadFile handles the complete file upload process. The content type is
+// sniffed from the file instead of trusting the client. JPEG, PNG and WebP
+// images are re-encoded without their metadata and stored with a thumbnail.
 func (fss *FileStorageService) UploadFile(ctx context.Context, req *FileUploadRequest) (*FileUploadResponse, error) {
-	// Validate file
 	if req.ValidateSize && req.File.Size > fss.maxFileSize {
-		return nil, fmt.Errorf("file size exceeds maximum allowed size of %d bytes", fss.maxFileSize)
-	}
-	
-	if req.ValidateType {
-		if !fss.allowedTypes[req.File.Header.Get("Content-Type")] {
-			return nil, fmt.Errorf("file type not allowed")
-		}
+		return nil, fmt.Errorf("%w: the maximum is %d bytes", ErrFileTooLarge, fss.maxFileSize)
 	}
-	
-	// Open the uploaded file
+
 	src, err := req.File.Open()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
 	}
 	defer src.Close()
-	
-	// Upload original file
-	contentType := req.File.Header.Get("Content-Type")
-	fileURL, err := fss.storageProvider.UploadFile(ctx, src, req.File.Filename, contentType)
+	data, err := io.ReadAll(io.LimitReader(src, fss.maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if req.ValidateSize && int64(len(data)) > fss.maxFileSize {
+		return nil, fmt.Errorf("%w: the maximum is %d bytes", ErrFileTooLarge, fss.maxFileSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	if req.ValidateType && !fss.allowedTypes[contentType] {
+		return nil, fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, contentType)
+	}
+	if req.ImagesOnly && !processableImageTypes[contentType] {
+		return nil, fmt.Errorf("%w: expected a JPEG, PNG or WebP image", ErrFileTypeNotAllowed)
+	}
+
+	response := &FileUploadResponse{
+		FileID:     uuid.New().String(),
+		FileName:   req.File.Filename,
+		UploaderID: req.UploaderID,
+		Purpose:    req.Purpose,
+		UploadedAt: time.Now().Format(time.RFC3339),
+	}
+	var thumbnail []byte
+	if processableImageTypes[contentType] {
+		processed, err := fss.processor.PrepareUpload(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		data, thumbnail, contentType = processed.Data, processed.Thumbnail, processed.ContentType
+		response.Width, response.Height = processed.Width, processed.Height
+	}
+	response.Size = int64(len(data))
+	response.ContentType = contentType
+
+	// Stored names never include the client's filename
+	purpose := req.Purpose
+	if purpose == "" {
+		purpose = "files"
+	}
+	name := path.Join(purpose, req.UploaderID, response.FileID)
+	ext := strings.ToLower(filepath.Ext(req.File.Filename))
+	if contentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+
+	response.FileURL, err = fss.storageProvider.UploadFile(ctx, bytes.NewReader(data), name+ext, contentType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
-	
-	// Process image if it's an image
-	var thumbnailURL string
-	if strings.HasPrefix(contentType, "image/") {
-		// Reset file reader for processing
-		req.File.Open()
-		thumbnailURL, err = fss.processor.GenerateThumbnail(ctx, src, req.File.Filename)
+	if thumbnail != nil {
+		response.ThumbnailURL, err = fss.storageProvider.UploadFile(ctx, bytes.NewReader(thumbnail), name+"_thumb.jpg", "image/jpeg")
 		if err != nil {
-			// Log error but don't fail the upload
-			fmt.Printf("Warning: failed to generate thumbnail: %v\n", err)
+			fss.storageProvider.DeleteFile(ctx, response.FileURL)
+			return nil, fmt.Errorf("failed to upload thumbnail: %w", err)
 		}
 	}
-	
-	// Create response
-	response := &FileUploadResponse{
-		FileID:      uuid.New().String(),
-		FileName:    req.File.Filename,
-		FileURL:     fileURL,
-		ThumbnailURL: thumbnailURL,
-		Size:        req.File.Size,
-		ContentType: contentType,
-		UploaderID:  req.UploaderID,
-		Purpose:     req.Purpose,
-		UploadedAt:  time.Now().Format(time.RFC3339),
-	}
-	
+
 	return response, nil
 }
 
@@ -287,6 +468,11 @@ func (fss *FileStorageService) GetPublicURL(ctx context.Context, fileURL string)
 	return fss.storageProvider.GetPublicURL(ctx, fileURL)
 }
 
+// SignedURL returns a URL that grants read access to a file until ttl passes
+func (fss *FileStorageService) SignedURL(ctx context.Context, fileURL string, ttl time.Duration) (string, error) {
+	return fss.storageProvider.SignedURL(ctx, fileURL, ttl)
+}
+
 // ValidateFileType checks if a file type is allowed
 func (fss *FileStorageService) ValidateFileType(contentType string) bool {
 	return fss.allowedTypes[contentType]
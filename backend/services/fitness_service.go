@@ -1,79 +1,33 @@
 package services
 
 import (
+	"database/sql"
+
 	"nutrition-platform/database"
 	"nutrition-platform/repositories"
 )
 
 type FitnessService struct {
+	db           *sql.DB
 	exerciseRepo *repositories.ExerciseRepository
 	workoutRepo  *repositories.WorkoutRepository
+	gdpr         *GDPRCompliance
 }
 
-func NewFitnessService(db *database.Database) *FitnessService {
+func NewFitnessService(db *sql.DB) *FitnessService {
+	dbWrapper := database.NewDatabase(db)
 	return &FitnessService{
-		exerciseRepo: repositories.NewExerciseRepository(db),
-		workoutRepo:  repositories.NewWorkoutRepository(db),
+		db:           db,
+		exerciseRepo: repositories.NewExerciseRepository(dbWrapper),
+		workoutRepo:  repositories.NewWorkoutRepository(dbWrapper),
 	}
 }
 
-// GenerateWorkoutPlan creates a personalized workout plan
-func (s *FitnessService) GenerateWorkoutPlan(userID int64, goal string, duration int, difficulty string, equipment []string, muscleGroups []string) (map[string]interface{}, error) {
-	// For now, return a mock workout plan
-	// In a real implementation, this would query the exercise database and generate a personalized plan
-	exercises := []map[string]interface{}{
-		{
-			"id":           1,
-			"name":         "Warm-up Cardio",
-			"duration":     5,
-			"sets":         1,
-			"reps":         0,
-			"rest":         0,
-			"muscle_group": "cardio",
-			"equipment":    "none",
-		},
-		{
-			"id":           2,
-			"name":         "Push-ups",
-			"duration":     0,
-			"sets":         3,
-			"reps":         15,
-			"rest":         60,
-			"muscle_group": "chest",
-			"equipment":    "none",
-		},
-		{
-			"id":           3,
-			"name":         "Squats",
-			"duration":     0,
-			"sets":         3,
-			"reps":         20,
-			"rest":         90,
-			"muscle_group": "legs",
-			"equipment":    "none",
-		},
-		{
-			"id":           4,
-			"name":         "Plank",
-			"duration":     60,
-			"sets":         3,
-			"reps":         0,
-			"rest":         30,
-			"muscle_group": "core",
-			"equipment":    "none",
-		},
-	}
-
-	workoutPlan := map[string]interface{}{
-		"workout_plan":       exercises,
-		"duration":           duration,
-		"goal":               goal,
-		"difficulty":         difficulty,
-		"total_exercises":    len(exercises),
-		"estimated_calories": duration * 8, // Rough estimate
-	}
-
-	return workoutPlan, nil
+// SetConsent makes program generation work around a user's injuries only
+// while they consent to health data processing. Without it injuries are
+// never read.
+func (s *FitnessService) SetConsent(gdpr *GDPRCompliance) {
+	s.gdpr = gdpr
 }
 
 // LogWorkoutSession saves a workout session
@@ -0,0 +1,664 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/google/uuid"
+)
+
+// Food diary errors returned by FoodLogService
+var (
+	ErrFoodLogNotFound = errors.New("food log entry not found")
+	ErrFoodNotFound    = errors.New("food not found")
+	ErrRecipeNotFound  = errors.New("recipe not found")
+	ErrInvalidFoodLog  = errors.New("invalid food log entry")
+)
+
+const foodLogColumns = `id, user_id, food_id, recipe_id, COALESCE(name, ''), quantity, COALESCE(unit, ''),
+	grams, COALESCE(meal_type, ''), consumed_at, COALESCE(calories, 0), COALESCE(protein, 0),
+	COALESCE(carbs, 0), COALESCE(fat, 0), COALESCE(fiber, 0), COALESCE(sugar, 0),
+	COALESCE(sodium, 0), COALESCE(potassium, 0), COALESCE(micronutrients, '{}'), notes, created_at, updated_at`
+
+// NutrientTotals sums the nutrients tracked by the food diary. Sodium and
+// potassium are in milligrams, everything else in grams or kcal.
+type NutrientTotals struct {
+	Calories  float64 `json:"calories"`
+	Protein   float64 `json:"protein"`
+	Carbs     float64 `json:"carbs"`
+	Fat       float64 `json:"fat"`
+	Fiber     float64 `json:"fiber"`
+	Sugar     float64 `json:"sugar"`
+	Sodium    float64 `json:"sodium"`
+	Potassium float64 `json:"potassium"`
+}
+
+// DailyNutrition is one day of a NutritionSummary
+type DailyNutrition struct {
+	Date         string         `json:"date"`
+	Totals       NutrientTotals `json:"totals"`
+	EntriesCount int            `json:"entries_count"`
+}
+
+// NutritionSummary aggregates diary entries over a range of whole UTC days and
+// compares the daily averages with the user's active nutrition goal
+type NutritionSummary struct {
+	StartDate     string                    `json:"start_date"`
+	EndDate       string                    `json:"end_date"`
+	PeriodDays    int                       `json:"period_days"`
+	MealsLogged   int                       `json:"meals_logged"`
+	DaysLogged    int                       `json:"days_logged"`
+	Totals        NutrientTotals            `json:"totals"`
+	DailyAverages NutrientTotals            `json:"daily_averages"`
+	Goal          *models.NutritionGoal     `json:"goal,omitempty"`
+	DailyTargets  map[string]float64        `json:"daily_targets,omitempty"`
+	GoalProgress  map[string]float64        `json:"goal_progress,omitempty"`
+	Days          []DailyNutrition          `json:"days"`
+	ByMealType    map[string]NutrientTotals `json:"by_meal_type"`
+}
+
+// FoodLogService persists food diary entries and summarizes them
+type FoodLogService struct {
+	db *sql.DB
+}
+
+// NewFoodLogService creates a new FoodLogService instance
+func NewFoodLogService(db *sql.DB) *FoodLogService {
+	return &FoodLogService{
+		db: db,
+	}
+}
+
+// LogMeal adds a food or recipe to userID's diary. Nutrients are scaled to the
+// logged quantity: foods through Food.CalculateNutrition after converting the
+// quantity to grams, recipes per serving.
+func (s *FoodLogService) LogMeal(ctx context.Context, userID string, req models.LogMealRequest) (*models.UserFoodLog, error) {
+	now := time.Now().UTC()
+	consumedAt, err := consumedAtFor(req.Date, now)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.UserFoodLog{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		FoodID:     nonEmpty(req.FoodID),
+		RecipeID:   nonEmpty(req.RecipeID),
+		Quantity:   req.Quantity,
+		Unit:       strings.ToLower(strings.TrimSpace(req.Unit)),
+		MealType:   req.MealType,
+		ConsumedAt: consumedAt,
+		Notes:      req.Notes,
+		CreatedAt:  now,
+	}
+
+	if err := s.applyNutrition(ctx, entry); err != nil {
+		return nil, err
+	}
+	if err := entry.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFoodLog, err)
+	}
+
+	query := `
+		INSERT INTO user_food_logs (id, user_id, food_id, recipe_id, name, quantity, unit, grams,
+			meal_type, consumed_at, calories, protein, carbs, fat, fiber, sugar, sodium, potassium,
+			micronutrients, notes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.db.ExecContext(ctx, query,
+		entry.ID, entry.UserID, entry.FoodID, entry.RecipeID, entry.Name, entry.Quantity, entry.Unit, entry.Grams,
+		entry.MealType, entry.ConsumedAt, entry.Calories, entry.Protein, entry.Carbs, entry.Fat, entry.Fiber,
+		entry.Sugar, entry.Sodium, entry.Potassium, encodeMicronutrients(entry.Micronutrients), entry.Notes,
+		entry.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log meal: %w", err)
+	}
+
+	return entry, nil
+}
+
+// GetFoodLog returns one of userID's diary entries
+func (s *FoodLogService) GetFoodLog(ctx context.Context, userID, id string) (*models.UserFoodLog, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+foodLogColumns+` FROM user_food_logs WHERE id = ? AND user_id = ?`, id, userID)
+	entry, err := scanFoodLog(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFoodLogNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get food log: %w", err)
+	}
+	return entry, nil
+}
+
+// UpdateFoodLog edits a diary entry. Changing the quantity or unit rescales
+// its nutrients from the food or recipe it was logged from.
+func (s *FoodLogService) UpdateFoodLog(ctx context.Context, userID, id string, req models.UpdateFoodLogRequest) (*models.UserFoodLog, error) {
+	entry, err := s.GetFoodLog(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rescale := false
+	if req.Quantity != nil && *req.Quantity != entry.Quantity {
+		entry.Quantity = *req.Quantity
+		rescale = true
+	}
+	if req.Unit != nil {
+		unit := strings.ToLower(strings.TrimSpace(*req.Unit))
+		if unit != entry.Unit {
+			entry.Unit = unit
+			rescale = true
+		}
+	}
+	if req.MealType != nil {
+		entry.MealType = *req.MealType
+	}
+	if req.Notes != nil {
+		entry.Notes = req.Notes
+	}
+	if req.Date != nil {
+		consumedAt, err := consumedAtFor(*req.Date, entry.ConsumedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.ConsumedAt = consumedAt
+	}
+
+	if rescale {
+		if err := s.applyNutrition(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := entry.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFoodLog, err)
+	}
+
+	now := time.Now().UTC()
+	entry.UpdatedAt = &now
+
+	query := `
+		UPDATE user_food_logs
+		SET name = ?, quantity = ?, unit = ?, grams = ?, meal_type = ?, consumed_at = ?,
+			calories = ?, protein = ?, carbs = ?, fat = ?, fiber = ?, sugar = ?, sodium = ?,
+			potassium = ?, micronutrients = ?, notes = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`
+	_, err = s.db.ExecContext(ctx, query,
+		entry.Name, entry.Quantity, entry.Unit, entry.Grams, entry.MealType, entry.ConsumedAt,
+		entry.Calories, entry.Protein, entry.Carbs, entry.Fat, entry.Fiber, entry.Sugar, entry.Sodium,
+		entry.Potassium, encodeMicronutrients(entry.Micronutrients), entry.Notes, entry.UpdatedAt, entry.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update food log: %w", err)
+	}
+
+	return entry, nil
+}
+
+// DeleteFoodLog removes one of userID's diary entries
+func (s *FoodLogService) DeleteFoodLog(ctx context.Context, userID, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM user_food_logs WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete food log: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrFoodLogNotFound
+	}
+	return nil
+}
+
+// ListFoodLogs returns userID's entries consumed in [start, end), oldest first
+func (s *FoodLogService) ListFoodLogs(ctx context.Context, userID string, start, end time.Time) ([]*models.UserFoodLog, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+foodLogColumns+`
+		FROM user_food_logs
+		WHERE user_id = ? AND consumed_at >= ? AND consumed_at < ?
+		ORDER BY consumed_at ASC`, userID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list food logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.UserFoodLog{}
+	for rows.Next() {
+		entry, err := scanFoodLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan food log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetActiveNutritionGoal returns the newest active goal of userID that covers
+// on, or nil if the user has none
+func (s *FoodLogService) GetActiveNutritionGoal(ctx context.Context, userID string, on time.Time) (*models.NutritionGoal, error) {
+	goal := &models.NutritionGoal{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, daily_calories, protein_grams, carbs_grams, fat_grams,
+		       fiber_grams, sugar_grams, sodium_mg, potassium_mg, water_ml, is_active,
+		       start_date, end_date, created_at, updated_at
+		FROM nutrition_goals
+		WHERE user_id = ? AND is_active = 1
+		  AND (start_date IS NULL OR start_date <= ?)
+		  AND (end_date IS NULL OR end_date >= ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`, userID, on.UTC(), on.UTC()).Scan(
+		&goal.ID, &goal.UserID, &goal.DailyCalories, &goal.ProteinGrams, &goal.CarbsGrams, &goal.FatGrams,
+		&goal.FiberGrams, &goal.SugarGrams, &goal.SodiumMg, &goal.PotassiumMg, &goal.WaterMl, &goal.IsActive,
+		&goal.StartDate, &goal.EndDate, &goal.CreatedAt, &goal.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nutrition goal: %w", err)
+	}
+	return goal, nil
+}
+
+// GetDailySummary summarizes the UTC calendar day containing day
+func (s *FoodLogService) GetDailySummary(ctx context.Context, userID string, day time.Time) (*NutritionSummary, error) {
+	start := startOfDay(day)
+	return s.GetNutritionSummary(ctx, userID, start, start.AddDate(0, 0, 1))
+}
+
+// GetWeeklySummary summarizes the Monday-to-Sunday week containing day
+func (s *FoodLogService) GetWeeklySummary(ctx context.Context, userID string, day time.Time) (*NutritionSummary, error) {
+	start := startOfDay(day)
+	start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	return s.GetNutritionSummary(ctx, userID, start, start.AddDate(0, 0, 7))
+}
+
+// GetNutritionSummary aggregates userID's entries over the whole UTC days in
+// [start, end). Daily averages are taken over every day of the period, logged
+// or not, and compared with the goal active at the end of the period.
+func (s *FoodLogService) GetNutritionSummary(ctx context.Context, userID string, start, end time.Time) (*NutritionSummary, error) {
+	start, end = startOfDay(start), startOfDay(end)
+	if !end.After(start) {
+		end = start.AddDate(0, 0, 1)
+	}
+
+	entries, err := s.ListFoodLogs(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &NutritionSummary{
+		StartDate:  start.Format("2006-01-02"),
+		EndDate:    end.AddDate(0, 0, -1).Format("2006-01-02"),
+		PeriodDays: int(end.Sub(start).Hours()/24 + 0.5),
+		Days:       []DailyNutrition{},
+		ByMealType: map[string]NutrientTotals{},
+	}
+
+	byDay := map[string]*DailyNutrition{}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		summary.Days = append(summary.Days, DailyNutrition{Date: day.Format("2006-01-02")})
+	}
+	for i := range summary.Days {
+		byDay[summary.Days[i].Date] = &summary.Days[i]
+	}
+
+	for _, entry := range entries {
+		summary.Totals.add(entry)
+		summary.MealsLogged++
+
+		if day, ok := byDay[entry.ConsumedAt.UTC().Format("2006-01-02")]; ok {
+			day.Totals.add(entry)
+			day.EntriesCount++
+		}
+
+		meal := summary.ByMealType[entry.MealType]
+		meal.add(entry)
+		summary.ByMealType[entry.MealType] = meal
+	}
+
+	for i := range summary.Days {
+		if summary.Days[i].EntriesCount > 0 {
+			summary.DaysLogged++
+		}
+		summary.Days[i].Totals = summary.Days[i].Totals.rounded()
+	}
+	for mealType, totals := range summary.ByMealType {
+		summary.ByMealType[mealType] = totals.rounded()
+	}
+	summary.DailyAverages = summary.Totals.scaled(1 / float64(summary.PeriodDays)).rounded()
+	summary.Totals = summary.Totals.rounded()
+
+	goal, err := s.GetActiveNutritionGoal(ctx, userID, end.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	if goal != nil {
+		summary.Goal = goal
+		summary.DailyTargets = goalTargets(goal)
+		summary.GoalProgress = make(map[string]float64, len(summary.DailyTargets))
+		averages := summary.DailyAverages.byName()
+		for name, target := range summary.DailyTargets {
+			if target > 0 {
+				summary.GoalProgress[name] = round1(averages[name] / target * 100)
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// applyNutrition sets the name, grams and nutrients of entry from the food or
+// recipe it refers to, scaled to its quantity and unit
+func (s *FoodLogService) applyNutrition(ctx context.Context, entry *models.UserFoodLog) error {
+	var (
+		nutrition *models.NutritionInfo
+		err       error
+	)
+
+	switch {
+	case entry.FoodID != nil && entry.RecipeID != nil:
+		return fmt.Errorf("%w: log either a food or a recipe, not both", ErrInvalidFoodLog)
+	case entry.FoodID != nil:
+		nutrition, err = s.foodNutrition(ctx, entry)
+	case entry.RecipeID != nil:
+		nutrition, err = s.recipeNutrition(ctx, entry)
+	default:
+		return fmt.Errorf("%w: food_id or recipe_id is required", ErrInvalidFoodLog)
+	}
+	if err != nil {
+		return err
+	}
+
+	entry.Calories = round1(nutrition.Calories)
+	entry.Protein = round1(nutrition.Protein)
+	entry.Carbs = round1(nutrition.Carbohydrates)
+	entry.Fat = round1(nutrition.Fat)
+	entry.Fiber = round1(nutrition.Fiber)
+	entry.Sugar = round1(nutrition.Sugar)
+	entry.Sodium = round1(nutrition.Sodium)
+	entry.Potassium = round1(nutrition.Potassium)
+	entry.Micronutrients = nil
+	for nutrient, amount := range nutrition.Micronutrients {
+		if _, tracked := models.MicronutrientUnits[nutrient]; !tracked || amount <= 0 {
+			continue
+		}
+		if entry.Micronutrients == nil {
+			entry.Micronutrients = map[string]float64{}
+		}
+		// Two decimals keep microgram amounts such as vitamin B12 meaningful
+		entry.Micronutrients[nutrient] = math.Round(amount*100) / 100
+	}
+	return nil
+}
+
+func (s *FoodLogService) foodNutrition(ctx context.Context, entry *models.UserFoodLog) (*models.NutritionInfo, error) {
+	var (
+		food        models.Food
+		servingSize sql.NullFloat64
+		servingUnit sql.NullString
+		nutrients   [8]sql.NullFloat64
+		micros      sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT name, serving_size, serving_unit, calories_per_100g, protein_per_100g, carbs_per_100g,
+		       fat_per_100g, fiber_per_100g, sugar_per_100g, sodium_per_100g, potassium_per_100g,
+		       micronutrients_per_100g
+		FROM foods WHERE id = ?`, *entry.FoodID).Scan(
+		&food.Name, &servingSize, &servingUnit, &nutrients[0], &nutrients[1], &nutrients[2],
+		&nutrients[3], &nutrients[4], &nutrients[5], &nutrients[6], &nutrients[7], &micros)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFoodNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get food: %w", err)
+	}
+
+	if servingSize.Valid {
+		food.ServingSize = strconv.FormatFloat(servingSize.Float64, 'f', -1, 64)
+	}
+	food.ServingUnit = servingUnit.String
+	food.Calories = nutrients[0].Float64
+	food.Protein = nutrients[1].Float64
+	food.Carbs = nutrients[2].Float64
+	food.Fat = nutrients[3].Float64
+	food.Fiber = nutrients[4].Float64
+	food.Sugar = nutrients[5].Float64
+	food.Sodium = int(math.Round(nutrients[6].Float64))
+	food.Potassium = nutrients[7].Float64
+	food.Micronutrients = decodeMicronutrients(micros.String)
+
+	grams, err := food.QuantityInGrams(entry.Quantity, entry.Unit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported unit %q", ErrInvalidFoodLog, entry.Unit)
+	}
+
+	entry.Name = food.Name
+	grams = round1(grams)
+	entry.Grams = &grams
+	return food.CalculateNutrition(grams), nil
+}
+
+func (s *FoodLogService) recipeNutrition(ctx context.Context, entry *models.UserFoodLog) (*models.NutritionInfo, error) {
+	if entry.Unit != "serving" && entry.Unit != "servings" {
+		return nil, fmt.Errorf("%w: recipes are logged in servings", ErrInvalidFoodLog)
+	}
+
+	var (
+		name          string
+		nutritionJSON sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT name, nutrition_per_serving FROM recipes WHERE id = ?`, *entry.RecipeID).
+		Scan(&name, &nutritionJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecipeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipe: %w", err)
+	}
+
+	var perServing models.NutritionInfo
+	if nutritionJSON.Valid && nutritionJSON.String != "" {
+		if err := json.Unmarshal([]byte(nutritionJSON.String), &perServing); err != nil {
+			return nil, fmt.Errorf("failed to decode recipe nutrition: %w", err)
+		}
+	}
+
+	// Recipes may list vitamin C, calcium and iron as top-level fields
+	micronutrients := map[string]float64{}
+	for nutrient, amount := range map[string]float64{
+		"vitamin_c": perServing.VitaminC, "calcium": perServing.Calcium, "iron": perServing.Iron,
+	} {
+		if amount > 0 {
+			micronutrients[nutrient] = amount * entry.Quantity
+		}
+	}
+	for nutrient, amount := range perServing.Micronutrients {
+		micronutrients[nutrient] = amount * entry.Quantity
+	}
+
+	entry.Name = name
+	entry.Grams = nil
+	return &models.NutritionInfo{
+		Calories:      perServing.Calories * entry.Quantity,
+		Protein:       perServing.Protein * entry.Quantity,
+		Carbohydrates: perServing.Carbohydrates * entry.Quantity,
+		Fat:           perServing.Fat * entry.Quantity,
+		Fiber:         perServing.Fiber * entry.Quantity,
+		Sugar:         perServing.Sugar * entry.Quantity,
+		Sodium:        perServing.Sodium * entry.Quantity,
+		Potassium:     perServing.Potassium * entry.Quantity,
+
+		Micronutrients: micronutrients,
+	}, nil
+}
+
+func scanFoodLog(row rowScanner) (*models.UserFoodLog, error) {
+	var (
+		entry    models.UserFoodLog
+		foodID   sql.NullString
+		recipeID sql.NullString
+		grams    sql.NullFloat64
+		micros   string
+		notes    sql.NullString
+		updated  sql.NullTime
+	)
+	err := row.Scan(&entry.ID, &entry.UserID, &foodID, &recipeID, &entry.Name, &entry.Quantity, &entry.Unit,
+		&grams, &entry.MealType, &entry.ConsumedAt, &entry.Calories, &entry.Protein, &entry.Carbs, &entry.Fat,
+		&entry.Fiber, &entry.Sugar, &entry.Sodium, &entry.Potassium, &micros, &notes, &entry.CreatedAt, &updated)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Micronutrients = decodeMicronutrients(micros)
+
+	if foodID.Valid {
+		entry.FoodID = &foodID.String
+	}
+	if recipeID.Valid {
+		entry.RecipeID = &recipeID.String
+	}
+	if grams.Valid {
+		entry.Grams = &grams.Float64
+	}
+	if notes.Valid {
+		entry.Notes = &notes.String
+	}
+	if updated.Valid {
+		entry.UpdatedAt = &updated.Time
+	}
+	entry.ConsumedAt = entry.ConsumedAt.UTC()
+	return &entry, nil
+}
+
+func encodeMicronutrients(amounts map[string]float64) string {
+	if len(amounts) == 0 {
+		return "{}"
+	}
+	encoded, _ := json.Marshal(amounts)
+	return string(encoded)
+}
+
+// decodeMicronutrients returns nil for empty or malformed JSON so entries
+// without micronutrient data omit the field
+func decodeMicronutrients(raw string) map[string]float64 {
+	var amounts map[string]float64
+	if err := json.Unmarshal([]byte(raw), &amounts); err != nil || len(amounts) == 0 {
+		return nil
+	}
+	return amounts
+}
+
+// consumedAtFor places an entry on date (YYYY-MM-DD) at the time of day of
+// fallback, or returns fallback when date is empty
+func consumedAtFor(date string, fallback time.Time) (time.Time, error) {
+	fallback = fallback.UTC()
+	if date == "" {
+		return fallback, nil
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date must be formatted as YYYY-MM-DD", ErrInvalidFoodLog)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		fallback.Hour(), fallback.Minute(), fallback.Second(), 0, time.UTC), nil
+}
+
+func goalTargets(goal *models.NutritionGoal) map[string]float64 {
+	targets := map[string]float64{}
+	if goal.DailyCalories != nil {
+		targets["calories"] = float64(*goal.DailyCalories)
+	}
+	if goal.ProteinGrams != nil {
+		targets["protein"] = *goal.ProteinGrams
+	}
+	if goal.CarbsGrams != nil {
+		targets["carbs"] = *goal.CarbsGrams
+	}
+	if goal.FatGrams != nil {
+		targets["fat"] = *goal.FatGrams
+	}
+	if goal.FiberGrams != nil {
+		targets["fiber"] = *goal.FiberGrams
+	}
+	if goal.SugarGrams != nil {
+		targets["sugar"] = *goal.SugarGrams
+	}
+	if goal.SodiumMg != nil {
+		targets["sodium"] = float64(*goal.SodiumMg)
+	}
+	if goal.PotassiumMg != nil {
+		targets["potassium"] = float64(*goal.PotassiumMg)
+	}
+	return targets
+}
+
+func (t *NutrientTotals) add(entry *models.UserFoodLog) {
+	t.Calories += entry.Calories
+	t.Protein += entry.Protein
+	t.Carbs += entry.Carbs
+	t.Fat += entry.Fat
+	t.Fiber += entry.Fiber
+	t.Sugar += entry.Sugar
+	t.Sodium += entry.Sodium
+	t.Potassium += entry.Potassium
+}
+
+func (t NutrientTotals) scaled(factor float64) NutrientTotals {
+	return NutrientTotals{
+		Calories:  t.Calories * factor,
+		Protein:   t.Protein * factor,
+		Carbs:     t.Carbs * factor,
+		Fat:       t.Fat * factor,
+		Fiber:     t.Fiber * factor,
+		Sugar:     t.Sugar * factor,
+		Sodium:    t.Sodium * factor,
+		Potassium: t.Potassium * factor,
+	}
+}
+
+func (t NutrientTotals) rounded() NutrientTotals {
+	return NutrientTotals{
+		Calories:  round1(t.Calories),
+		Protein:   round1(t.Protein),
+		Carbs:     round1(t.Carbs),
+		Fat:       round1(t.Fat),
+		Fiber:     round1(t.Fiber),
+		Sugar:     round1(t.Sugar),
+		Sodium:    round1(t.Sodium),
+		Potassium: round1(t.Potassium),
+	}
+}
+
+func (t NutrientTotals) byName() map[string]float64 {
+	return map[string]float64{
+		"calories":  t.Calories,
+		"protein":   t.Protein,
+		"carbs":     t.Carbs,
+		"fat":       t.Fat,
+		"fiber":     t.Fiber,
+		"sugar":     t.Sugar,
+		"sodium":    t.Sodium,
+		"potassium": t.Potassium,
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+func nonEmpty(value *string) *string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	return &trimmed
+}
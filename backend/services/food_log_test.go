@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFoodLogService(t *testing.T) (*FoodLogService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "diary@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = users.db.Exec(`INSERT INTO foods (id, name, serving_size, serving_unit, calories_per_100g, protein_per_100g,
+		carbs_per_100g, fat_per_100g, fiber_per_100g, sugar_per_100g, sodium_per_100g, potassium_per_100g)
+		VALUES ('oats', 'Rolled oats', 40, 'g', 380, 13, 67, 7, 10, 1, 5, 360)`)
+	require.NoError(t, err)
+	_, err = users.db.Exec(`INSERT INTO recipes (id, name, servings, nutrition_per_serving)
+		VALUES ('lentil-soup', 'Lentil soup', 4, '{"calories": 250, "protein": 15, "carbohydrates": 35, "fat": 5, "fiber": 12, "sodium": 400, "potassium": 600}')`)
+	require.NoError(t, err)
+
+	return NewFoodLogService(users.db), user.ID
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func TestFoodLogService_LogMealScalesNutrients(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestFoodLogService(t)
+
+	entry, err := svc.LogMeal(ctx, userID, models.LogMealRequest{
+		FoodID:   stringPtr("oats"),
+		MealType: "breakfast",
+		Quantity: 2,
+		Unit:     "serving",
+		Date:     "2026-03-02",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Rolled oats", entry.Name)
+	require.NotNil(t, entry.Grams)
+	assert.Equal(t, 80.0, *entry.Grams)
+	assert.Equal(t, 304.0, entry.Calories)
+	assert.Equal(t, 8.0, entry.Fiber)
+	assert.Equal(t, 288.0, entry.Potassium)
+	assert.Equal(t, "2026-03-02", entry.ConsumedAt.Format("2006-01-02"))
+
+	recipe, err := svc.LogMeal(ctx, userID, models.LogMealRequest{
+		RecipeID: stringPtr("lentil-soup"),
+		MealType: "lunch",
+		Quantity: 1.5,
+		Unit:     "servings",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 375.0, recipe.Calories)
+	assert.Equal(t, 900.0, recipe.Potassium)
+
+	_, err = svc.LogMeal(ctx, userID, models.LogMealRequest{FoodID: stringPtr("oats"), MealType: "snack", Quantity: 1, Unit: "handful"})
+	assert.ErrorIs(t, err, ErrInvalidFoodLog)
+	_, err = svc.LogMeal(ctx, userID, models.LogMealRequest{FoodID: stringPtr("missing"), MealType: "snack", Quantity: 1, Unit: "g"})
+	assert.ErrorIs(t, err, ErrFoodNotFound)
+	_, err = svc.LogMeal(ctx, userID, models.LogMealRequest{MealType: "snack", Quantity: 1, Unit: "g"})
+	assert.ErrorIs(t, err, ErrInvalidFoodLog)
+}
+
+func TestFoodLogService_UpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestFoodLogService(t)
+
+	entry, err := svc.LogMeal(ctx, userID, models.LogMealRequest{
+		FoodID:   stringPtr("oats"),
+		MealType: "breakfast",
+		Quantity: 50,
+		Unit:     "g",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 190.0, entry.Calories)
+
+	quantity := 100.0
+	updated, err := svc.UpdateFoodLog(ctx, userID, entry.ID, models.UpdateFoodLogRequest{
+		Quantity: &quantity,
+		MealType: stringPtr("snack"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 380.0, updated.Calories)
+	assert.Equal(t, "snack", updated.MealType)
+
+	stored, err := svc.GetFoodLog(ctx, userID, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 380.0, stored.Calories)
+	assert.NotNil(t, stored.UpdatedAt)
+
+	_, err = svc.UpdateFoodLog(ctx, "someone-else", entry.ID, models.UpdateFoodLogRequest{Quantity: &quantity})
+	assert.ErrorIs(t, err, ErrFoodLogNotFound)
+	assert.ErrorIs(t, svc.DeleteFoodLog(ctx, "someone-else", entry.ID), ErrFoodLogNotFound)
+
+	require.NoError(t, svc.DeleteFoodLog(ctx, userID, entry.ID))
+	_, err = svc.GetFoodLog(ctx, userID, entry.ID)
+	assert.ErrorIs(t, err, ErrFoodLogNotFound)
+}
+
+func TestFoodLogService_SummariesAgainstGoal(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestFoodLogService(t)
+
+	_, err := svc.db.Exec(`INSERT INTO nutrition_goals (user_id, daily_calories, protein_grams, fiber_grams, potassium_mg)
+		VALUES (?, 2000, 100, 30, 3500)`, userID)
+	require.NoError(t, err)
+
+	// Monday and Wednesday of the same week, plus the following Monday
+	for _, date := range []string{"2026-03-02", "2026-03-04", "2026-03-09"} {
+		_, err := svc.LogMeal(ctx, userID, models.LogMealRequest{
+			FoodID:   stringPtr("oats"),
+			MealType: "breakfast",
+			Quantity: 100,
+			Unit:     "g",
+			Date:     date,
+		})
+		require.NoError(t, err)
+	}
+	_, err = svc.LogMeal(ctx, userID, models.LogMealRequest{
+		RecipeID: stringPtr("lentil-soup"),
+		MealType: "dinner",
+		Quantity: 1,
+		Unit:     "serving",
+		Date:     "2026-03-02",
+	})
+	require.NoError(t, err)
+
+	daily, err := svc.GetDailySummary(ctx, userID, time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 2, daily.MealsLogged)
+	assert.Equal(t, 630.0, daily.Totals.Calories)
+	assert.Equal(t, 960.0, daily.Totals.Potassium)
+	assert.Equal(t, 31.5, daily.GoalProgress["calories"])
+	assert.Equal(t, 73.3, daily.GoalProgress["fiber"])
+	assert.NotContains(t, daily.GoalProgress, "sodium")
+
+	weekly, err := svc.GetWeeklySummary(ctx, userID, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-02", weekly.StartDate)
+	assert.Equal(t, "2026-03-08", weekly.EndDate)
+	assert.Equal(t, 7, weekly.PeriodDays)
+	assert.Equal(t, 3, weekly.MealsLogged)
+	assert.Equal(t, 2, weekly.DaysLogged)
+	assert.Equal(t, 1010.0, weekly.Totals.Calories)
+	assert.Equal(t, 144.3, weekly.DailyAverages.Calories)
+	require.Len(t, weekly.Days, 7)
+	assert.Equal(t, 380.0, weekly.Days[2].Totals.Calories)
+	assert.Equal(t, 250.0, weekly.ByMealType["dinner"].Calories)
+	require.NotNil(t, weekly.Goal)
+	assert.Equal(t, 2000.0, weekly.DailyTargets["calories"])
+}
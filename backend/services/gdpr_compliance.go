@@ -2,813 +2,880 @@ package services
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
 	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/url"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
+	"nutrition-platform/models"
+
+	"github.com/google/uuid"
 )
 
-// GDPRCompliance manages GDPR compliance operations
-type GDPRCompliance struct {
-	mu                sync.RWMutex
-	db                *gorm.DB
-	auditLog          []GDPRAuditEntry
-	maxAuditEntries   int
-	exportPath        string
-	retentionPeriod   time.Duration
-	encryptionEnabled bool
-	notificationEmail string
-}
-
-// GDPRAuditEntry represents an audit log entry for GDPR operations
-type GDPRAuditEntry struct {
-	ID            uint       `json:"id" gorm:"primaryKey"`
-	Timestamp     time.Time  `json:"timestamp"`
-	UserID        string     `json:"user_id"`
-	Operation     string     `json:"operation"` // "export", "delete", "anonymize", "consent_update"
-	Status        string     `json:"status"`    // "initiated", "in_progress", "completed", "failed"
-	IPAddress     string     `json:"ip_address"`
-	UserAgent     string     `json:"user_agent"`
-	RequestID     string     `json:"request_id"`
-	DataTypes     []string   `json:"data_types" gorm:"serializer:json"`
-	Reason        string     `json:"reason,omitempty"`
-	CompletedAt   *time.Time `json:"completed_at,omitempty"`
-	ErrorMessage  string     `json:"error_message,omitempty"`
-	FileSize      int64      `json:"file_size,omitempty"`
-	Checksum      string     `json:"checksum,omitempty"`
-	RetentionDate *time.Time `json:"retention_date,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-}
-
-// UserDataExport represents exported user data
-type UserDataExport struct {
-	UserID        string                   `json:"user_id"`
-	ExportDate    time.Time                `json:"export_date"`
-	RequestID     string                   `json:"request_id"`
-	Profile       map[string]interface{}   `json:"profile"`
-	NutritionData map[string]interface{}   `json:"nutrition_data"`
-	Recipes       []map[string]interface{} `json:"recipes"`
-	MealPlans     []map[string]interface{} `json:"meal_plans"`
-	Preferences   map[string]interface{}   `json:"preferences"`
-	ActivityLog   []map[string]interface{} `json:"activity_log"`
-	Consents      []map[string]interface{} `json:"consents"`
-	Metadata      ExportMetadata           `json:"metadata"`
-}
-
-// ExportMetadata contains metadata about the export
-type ExportMetadata struct {
-	Version       string    `json:"version"`
-	Format        string    `json:"format"`
-	Compression   string    `json:"compression"`
-	Encryption    string    `json:"encryption"`
-	TotalRecords  int       `json:"total_records"`
-	DataSources   []string  `json:"data_sources"`
-	RetentionDate time.Time `json:"retention_date"`
-	Checksum      string    `json:"checksum"`
-}
-
-// DataDeletionRequest represents a data deletion request
-type DataDeletionRequest struct {
-	ID           uint       `json:"id" gorm:"primaryKey"`
-	UserID       string     `json:"user_id" gorm:"index"`
-	RequestID    string     `json:"request_id" gorm:"uniqueIndex"`
-	Status       string     `json:"status"` // "pending", "processing", "completed", "failed"
-	Reason       string     `json:"reason"`
-	DataTypes    []string   `json:"data_types" gorm:"serializer:json"`
-	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
-	CompletedAt  *time.Time `json:"completed_at,omitempty"`
-	Verification string     `json:"verification"`
-	IPAddress    string     `json:"ip_address"`
-	UserAgent    string     `json:"user_agent"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-}
-
-// ConsentRecord represents user consent records
-type ConsentRecord struct {
-	ID          uint       `json:"id" gorm:"primaryKey"`
-	UserID      string     `json:"user_id" gorm:"index"`
-	ConsentType string     `json:"consent_type"` // "data_processing", "marketing", "analytics", "cookies"
-	Granted     bool       `json:"granted"`
-	Version     string     `json:"version"`
-	IPAddress   string     `json:"ip_address"`
-	UserAgent   string     `json:"user_agent"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-}
-
-// NewGDPRCompliance creates a new GDPR compliance service
-func NewGDPRCompliance(db *gorm.DB, exportPath string) (*GDPRCompliance, error) {
-	gdpr := &GDPRCompliance{
-		db:                db,
-		auditLog:          make([]GDPRAuditEntry, 0),
-		maxAuditEntries:   50000,
-		exportPath:        exportPath,
-		retentionPeriod:   30 * 24 * time.Hour, // 30 days
-		encryptionEnabled: true,
-	}
-
-	// Auto-migrate GDPR tables
-	if err := db.AutoMigrate(&GDPRAuditEntry{}, &DataDeletionRequest{}, &ConsentRecord{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate GDPR tables: %w", err)
-	}
-
-	// Create export directory if it doesn't exist
-	if err := os.MkdirAll(exportPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create export directory: %w", err)
-	}
-
-	return gdpr, nil
-}
-
-// ExportUserData exports all user data in GDPR-compliant format
-func (g *GDPRCompliance) ExportUserData(userID, requestID, ipAddress, userAgent string) (*UserDataExport, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	// Create audit entry
-	auditEntry := GDPRAuditEntry{
-		Timestamp: time.Now(),
-		UserID:    userID,
-		Operation: "export",
-		Status:    "initiated",
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
-		RequestID: requestID,
-		DataTypes: []string{"profile", "nutrition", "recipes", "meal_plans", "preferences", "activity", "consents"},
-	}
+// GDPR errors returned by GDPRCompliance
+var (
+	ErrErasureNotFound      = errors.New("erasure request not found")
+	ErrErasureScheduled     = errors.New("account erasure already scheduled")
+	ErrInvalidErasureToken  = errors.New("invalid or expired erasure token")
+	ErrGDPRAccountNotFound  = errors.New("account not found")
+	ErrInvalidAuditLogQuery = errors.New("invalid audit log request")
+)
 
-	if err := g.db.Create(&auditEntry).Error; err != nil {
-		return nil, fmt.Errorf("failed to create audit entry: %w", err)
-	}
+const (
+	// DefaultErasureGracePeriod is how long a verified erasure can still be
+	// cancelled before the account is hard deleted
+	DefaultErasureGracePeriod = 30 * 24 * time.Hour
+	// DefaultErasureTokenTTL is how long the emailed erasure token stays valid
+	DefaultErasureTokenTTL = 24 * time.Hour
+	// DefaultErasureSweepInterval is how often Watch erases accounts whose
+	// grace period has ended
+	DefaultErasureSweepInterval = time.Hour
+)
 
-	// Update status to in_progress
-	auditEntry.Status = "in_progress"
-	g.db.Save(&auditEntry)
+// gdprExportVersion is the layout version of export archives
+const gdprExportVersion = "2.0"
+
+// gdprDataset is one kind of personal data in an export. query selects the
+// user's rows with the user ID as its only argument.
+type gdprDataset struct {
+	name  string
+	query string
+}
+
+// gdprDatasets lists the personal data included in exports
+var gdprDatasets = []gdprDataset{
+	{"profile", `SELECT * FROM users WHERE id = ?`},
+	{"food_logs", `SELECT * FROM user_food_logs WHERE user_id = ? ORDER BY consumed_at, id`},
+	{"nutrition_goals", `SELECT * FROM nutrition_goals WHERE user_id = ? ORDER BY created_at, id`},
+	{"water_intake", `SELECT * FROM water_intake WHERE user_id = ? ORDER BY rowid`},
+	{"meal_plans", `SELECT * FROM meal_plans WHERE user_id = ? ORDER BY created_at, id`},
+	{"measurements", `SELECT * FROM body_measurements WHERE user_id = ? ORDER BY measurement_date, id`},
+	{"weight_goals", `SELECT * FROM weight_goals WHERE user_id = ? ORDER BY rowid`},
+	{"photos", `SELECT * FROM progress_photos WHERE user_id = ? ORDER BY taken_at, id`},
+	{"medications", `SELECT * FROM user_medications WHERE user_id = ? ORDER BY rowid`},
+	{"supplements", `SELECT * FROM user_supplements WHERE user_id = ? ORDER BY rowid`},
+	{"health_complaints", `SELECT * FROM user_health_complaints WHERE user_id = ? ORDER BY rowid`},
+	{"injuries", `SELECT * FROM user_injuries WHERE user_id = ? ORDER BY rowid`},
+	{"allergies", `SELECT * FROM user_allergies WHERE user_id = ? ORDER BY allergen`},
+	{"halal_preferences", `SELECT * FROM halal_preferences WHERE user_id = ?`},
+	{"exercise_logs", `SELECT * FROM user_exercise_logs WHERE user_id = ? ORDER BY rowid`},
+	{"workout_sessions", `SELECT * FROM user_workout_sessions WHERE user_id = ? ORDER BY rowid`},
+	{"workout_programs", `SELECT * FROM workout_programs WHERE user_id = ? ORDER BY created_at, id`},
+	{"workout_program_sessions", `SELECT * FROM workout_sessions WHERE workout_program_id IN
+		(SELECT id FROM workout_programs WHERE user_id = ?) ORDER BY workout_program_id, week_number, day_number, rowid`},
+	{"personal_records", `SELECT * FROM personal_records WHERE user_id = ? ORDER BY rowid`},
+	{"milestones", `SELECT * FROM milestones WHERE user_id = ? ORDER BY rowid`},
+	{"sessions", `SELECT * FROM user_sessions WHERE user_id = ? ORDER BY created_at, id`},
+	{"api_keys", `SELECT * FROM api_keys WHERE user_id = ? ORDER BY created_at, id`},
+	{"consents", `SELECT * FROM consent_records WHERE user_id = ? ORDER BY created_at, id`},
+	{"gdpr_audit_log", `SELECT * FROM gdpr_audit_log WHERE user_id = ? ORDER BY created_at, id`},
+}
+
+// gdprSecretColumns are credentials left out of exports
+var gdprSecretColumns = map[string]bool{
+	"password_hash":  true,
+	"totp_secret":    true,
+	"totp_last_step": true,
+	"token_hash":     true,
+	"key_hash":       true,
+}
+
+// erasureDependents delete rows owned by the user through a parent row
+// rather than a user_id column, children before their parents
+var erasureDependents = []string{
+	`DELETE FROM session_refresh_tokens WHERE session_id IN (SELECT id FROM user_sessions WHERE user_id = ?)`,
+	`DELETE FROM meal_plan_meals WHERE meal_plan_day_id IN (SELECT id FROM meal_plan_days
+		WHERE meal_plan_id IN (SELECT id FROM meal_plans WHERE user_id = ?))`,
+	`DELETE FROM meal_plan_days WHERE meal_plan_id IN (SELECT id FROM meal_plans WHERE user_id = ?)`,
+	`DELETE FROM api_key_usage WHERE api_key_id IN (SELECT id FROM api_keys WHERE user_id = ?)`,
+	`DELETE FROM api_metrics_snapshots WHERE api_key_id IN (SELECT id FROM api_keys WHERE user_id = ?)`,
+	`DELETE FROM workout_sessions WHERE workout_program_id IN (SELECT id FROM workout_programs WHERE user_id = ?)`,
+	`UPDATE recipes SET created_by = NULL WHERE created_by = ?`,
+}
+
+// erasureRetainedTables keep their rows after an erasure as the record that
+// it happened
+var erasureRetainedTables = map[string]bool{
+	"gdpr_audit_log":         true,
+	"data_deletion_requests": true,
+}
+
+// gdprTable is one dataset of an export with the column order of its CSV
+// file
+type gdprTable struct {
+	columns []string
+	rows    []map[string]interface{}
+}
+
+// GDPRExportMetadata describes an export archive
+type GDPRExportMetadata struct {
+	Version      string         `json:"version"`
+	UserID       string         `json:"user_id"`
+	RequestID    string         `json:"request_id"`
+	ExportedAt   time.Time      `json:"exported_at"`
+	Records      map[string]int `json:"records"`
+	TotalRecords int            `json:"total_records"`
+	Files        int            `json:"files"`
+	MissingFiles []string       `json:"missing_files,omitempty"`
+	Checksum     string         `json:"checksum"`
+	Size         int64          `json:"size"`
+}
+
+// GDPRCompliance exports and erases users' personal data and keeps an audit
+// log of both. Erasure needs the token emailed to the account and then waits
+// for a grace period before Watch hard deletes the account from every table
+// and the file storage.
+type GDPRCompliance struct {
+	db          *sql.DB
+	files       *FileStorageService
+	mailer      Mailer
+	confirmURL  string
+	gracePeriod time.Duration
+	tokenTTL    time.Duration
 
-	export := &UserDataExport{
-		UserID:        userID,
-		ExportDate:    time.Now(),
-		RequestID:     requestID,
-		Profile:       make(map[string]interface{}),
-		NutritionData: make(map[string]interface{}),
-		Recipes:       make([]map[string]interface{}, 0),
-		MealPlans:     make([]map[string]interface{}, 0),
-		Preferences:   make(map[string]interface{}),
-		ActivityLog:   make([]map[string]interface{}, 0),
-		Consents:      make([]map[string]interface{}, 0),
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewGDPRCompliance creates a new GDPR compliance service. files holds
+// progress photos and may be nil when no storage is configured.
+func NewGDPRCompliance(db *sql.DB, files *FileStorageService) *GDPRCompliance {
+	return &GDPRCompliance{
+		db:          db,
+		files:       files,
+		gracePeriod: DefaultErasureGracePeriod,
+		tokenTTL:    DefaultErasureTokenTTL,
+		stop:        make(chan struct{}),
 	}
+}
 
-	totalRecords := 0
-	dataSources := make([]string, 0)
+// SetErasureMailer configures how erasure tokens are delivered. confirmURL is
+// the page that receives the token as its "token" query parameter.
+func (g *GDPRCompliance) SetErasureMailer(mailer Mailer, confirmURL string) {
+	g.mailer = mailer
+	g.confirmURL = confirmURL
+}
 
-	// Export user profile
-	if profileData, err := g.exportUserProfile(userID); err == nil {
-		export.Profile = profileData
-		totalRecords++
-		dataSources = append(dataSources, "user_profile")
+// SetGracePeriod configures how long verified erasures wait before the hard
+// delete
+func (g *GDPRCompliance) SetGracePeriod(gracePeriod time.Duration) {
+	if gracePeriod >= 0 {
+		g.gracePeriod = gracePeriod
 	}
+}
 
-	// Export nutrition data
-	if nutritionData, err := g.exportNutritionData(userID); err == nil {
-		export.NutritionData = nutritionData
-		totalRecords += len(nutritionData)
-		dataSources = append(dataSources, "nutrition_data")
+// ExportArchive writes a ZIP archive of the user's personal data to w: every
+// dataset as data.json and one CSV file each, their progress photo files and
+// a README. The export is recorded in the audit log.
+func (g *GDPRCompliance) ExportArchive(ctx context.Context, userID string, client SessionClient, w io.Writer) (*GDPRExportMetadata, error) {
+	metadata := &GDPRExportMetadata{
+		Version:    gdprExportVersion,
+		UserID:     userID,
+		RequestID:  uuid.New().String(),
+		ExportedAt: time.Now().UTC(),
+		Records:    map[string]int{},
 	}
 
-	// Export recipes
-	if recipes, err := g.exportUserRecipes(userID); err == nil {
-		export.Recipes = recipes
-		totalRecords += len(recipes)
-		dataSources = append(dataSources, "recipes")
+	datasets := map[string]*gdprTable{}
+	rows := map[string][]map[string]interface{}{}
+	for _, dataset := range gdprDatasets {
+		table, err := g.exportTable(ctx, dataset.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", dataset.name, err)
+		}
+		datasets[dataset.name], rows[dataset.name] = table, table.rows
+		metadata.Records[dataset.name] = len(table.rows)
+		metadata.TotalRecords += len(table.rows)
 	}
-
-	// Export meal plans
-	if mealPlans, err := g.exportUserMealPlans(userID); err == nil {
-		export.MealPlans = mealPlans
-		totalRecords += len(mealPlans)
-		dataSources = append(dataSources, "meal_plans")
+	if len(datasets["profile"].rows) == 0 {
+		return nil, ErrGDPRAccountNotFound
 	}
 
-	// Export preferences
-	if preferences, err := g.exportUserPreferences(userID); err == nil {
-		export.Preferences = preferences
-		totalRecords++
-		dataSources = append(dataSources, "preferences")
+	data, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
 	}
+	sum := sha256.Sum256(data)
+	metadata.Checksum = hex.EncodeToString(sum[:])
 
-	// Export activity log
-	if activityLog, err := g.exportUserActivity(userID); err == nil {
-		export.ActivityLog = activityLog
-		totalRecords += len(activityLog)
-		dataSources = append(dataSources, "activity_log")
+	counter := &countingWriter{w: w}
+	archive := zip.NewWriter(counter)
+	if err := writeZipFile(archive, "data.json", data); err != nil {
+		return nil, err
 	}
-
-	// Export consent records
-	if consents, err := g.exportUserConsents(userID); err == nil {
-		export.Consents = consents
-		totalRecords += len(consents)
-		dataSources = append(dataSources, "consents")
+	for _, dataset := range gdprDatasets {
+		content, err := gdprCSV(datasets[dataset.name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", dataset.name, err)
+		}
+		if err := writeZipFile(archive, "csv/"+dataset.name+".csv", content); err != nil {
+			return nil, err
+		}
 	}
-
-	// Generate checksum
-	exportJSON, _ := json.Marshal(export)
-	checksum := fmt.Sprintf("%x", sha256.Sum256(exportJSON))
-
-	// Set metadata
-	export.Metadata = ExportMetadata{
-		Version:       "1.0",
-		Format:        "JSON",
-		Compression:   "ZIP",
-		Encryption:    "AES-256",
-		TotalRecords:  totalRecords,
-		DataSources:   dataSources,
-		RetentionDate: time.Now().Add(g.retentionPeriod),
-		Checksum:      checksum,
+	if err := g.writePhotoFiles(ctx, archive, datasets["photos"], metadata); err != nil {
+		return nil, err
 	}
-
-	// Update audit entry as completed
-	now := time.Now()
-	auditEntry.Status = "completed"
-	auditEntry.CompletedAt = &now
-	auditEntry.FileSize = int64(len(exportJSON))
-	auditEntry.Checksum = checksum
-	g.db.Save(&auditEntry)
-
-	return export, nil
-}
-
-// DeleteUserData deletes user data according to GDPR requirements
-func (g *GDPRCompliance) DeleteUserData(userID, requestID, reason, verification, ipAddress, userAgent string, dataTypes []string) error {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	// Create deletion request
-	deletionRequest := DataDeletionRequest{
-		UserID:       userID,
-		RequestID:    requestID,
-		Status:       "pending",
-		Reason:       reason,
-		DataTypes:    dataTypes,
-		Verification: verification,
-		IPAddress:    ipAddress,
-		UserAgent:    userAgent,
+	if err := writeZipFile(archive, "README.txt", []byte(gdprReadme(metadata))); err != nil {
+		return nil, err
 	}
-
-	if err := g.db.Create(&deletionRequest).Error; err != nil {
-		return fmt.Errorf("failed to create deletion request: %w", err)
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write export archive: %w", err)
 	}
+	metadata.Size = counter.n
 
-	// Create audit entry
-	auditEntry := GDPRAuditEntry{
-		Timestamp: time.Now(),
+	err = g.audit(ctx, models.GDPRAuditEntry{
 		UserID:    userID,
-		Operation: "delete",
-		Status:    "initiated",
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
-		RequestID: requestID,
-		DataTypes: dataTypes,
-		Reason:    reason,
-	}
-
-	if err := g.db.Create(&auditEntry).Error; err != nil {
-		return fmt.Errorf("failed to create audit entry: %w", err)
+		Operation: models.GDPROperationExport,
+		Status:    models.GDPRStatusCompleted,
+		RequestID: metadata.RequestID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		DataTypes: sortedRegistryKeys(metadata.Records),
+		FileSize:  metadata.Size,
+		Checksum:  metadata.Checksum,
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	// Process deletion immediately or schedule for later
-	return g.processDeletion(deletionRequest.ID)
+	return metadata, nil
 }
 
-// processDeletion processes a data deletion request
-func (g *GDPRCompliance) processDeletion(requestID uint) error {
-	var request DataDeletionRequest
-	if err := g.db.First(&request, requestID).Error; err != nil {
-		return fmt.Errorf("deletion request not found: %w", err)
+// RequestErasure starts erasing the user's account by emailing them a
+// single-use token. A pending request is replaced; a scheduled one must be
+// cancelled first.
+func (g *GDPRCompliance) RequestErasure(ctx context.Context, userID, reason string, client SessionClient) (*models.DataDeletionRequest, error) {
+	if g.mailer == nil {
+		return nil, ErrMailerNotConfigured
 	}
 
-	// Update status to processing
-	request.Status = "processing"
-	g.db.Save(&request)
-
-	// Update audit entry
-	var auditEntry GDPRAuditEntry
-	g.db.Where("request_id = ? AND operation = ?", request.RequestID, "delete").First(&auditEntry)
-	auditEntry.Status = "in_progress"
-	g.db.Save(&auditEntry)
-
-	// Perform actual deletion based on data types
-	for _, dataType := range request.DataTypes {
-		switch dataType {
-		case "profile":
-			if err := g.deleteUserProfile(request.UserID); err != nil {
-				return g.handleDeletionError(request, auditEntry, err)
-			}
-		case "nutrition":
-			if err := g.deleteNutritionData(request.UserID); err != nil {
-				return g.handleDeletionError(request, auditEntry, err)
-			}
-		case "recipes":
-			if err := g.deleteUserRecipes(request.UserID); err != nil {
-				return g.handleDeletionError(request, auditEntry, err)
-			}
-		case "meal_plans":
-			if err := g.deleteUserMealPlans(request.UserID); err != nil {
-				return g.handleDeletionError(request, auditEntry, err)
-			}
-		case "preferences":
-			if err := g.deleteUserPreferences(request.UserID); err != nil {
-				return g.handleDeletionError(request, auditEntry, err)
-			}
-		case "activity":
-			if err := g.deleteUserActivity(request.UserID); err != nil {
-				return g.handleDeletionError(request, auditEntry, err)
-			}
-		case "all":
-			if err := g.deleteAllUserData(request.UserID); err != nil {
-				return g.handleDeletionError(request, auditEntry, err)
-			}
-		}
+	var email string
+	err := g.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = ?`, userID).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGDPRAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
 
-	// Mark as completed
-	now := time.Now()
-	request.Status = "completed"
-	request.CompletedAt = &now
-	g.db.Save(&request)
-
-	auditEntry.Status = "completed"
-	auditEntry.CompletedAt = &now
-	g.db.Save(&auditEntry)
-
-	return nil
-}
-
-// handleDeletionError handles errors during deletion process
-func (g *GDPRCompliance) handleDeletionError(request DataDeletionRequest, auditEntry GDPRAuditEntry, err error) error {
-	request.Status = "failed"
-	g.db.Save(&request)
-
-	auditEntry.Status = "failed"
-	auditEntry.ErrorMessage = err.Error()
-	g.db.Save(&auditEntry)
-
-	return err
-}
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	request := &models.DataDeletionRequest{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		Status:         models.ErasurePendingVerification,
+		Reason:         strings.TrimSpace(reason),
+		TokenExpiresAt: now.Add(g.tokenTTL),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
 
-// RecordConsent records user consent
-func (g *GDPRCompliance) RecordConsent(userID, consentType, version, ipAddress, userAgent string, granted bool, expiresAt *time.Time) error {
-	consent := ConsentRecord{
-		UserID:      userID,
-		ConsentType: consentType,
-		Granted:     granted,
-		Version:     version,
-		IPAddress:   ipAddress,
-		UserAgent:   userAgent,
-		ExpiresAt:   expiresAt,
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	if err := g.db.Create(&consent).Error; err != nil {
-		return fmt.Errorf("failed to record consent: %w", err)
+	var scheduled int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM data_deletion_requests WHERE user_id = ? AND status = ?`,
+		userID, models.ErasureScheduled).Scan(&scheduled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check erasure requests: %w", err)
+	}
+	if scheduled > 0 {
+		return nil, ErrErasureScheduled
+	}
+	// Only the most recently emailed token stays usable
+	_, err = tx.ExecContext(ctx, `UPDATE data_deletion_requests SET status = ?, cancelled_at = ?, updated_at = ?
+		WHERE user_id = ? AND status = ?`, models.ErasureCancelled, now, now, userID, models.ErasurePendingVerification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace erasure request: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO data_deletion_requests
+		(id, user_id, status, reason, token_hash, token_expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		request.ID, userID, request.Status, request.Reason, hashToken(token), request.TokenExpiresAt, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save erasure request: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit erasure request: %w", err)
 	}
 
-	// Create audit entry
-	auditEntry := GDPRAuditEntry{
-		Timestamp: time.Now(),
+	if err := g.audit(ctx, models.GDPRAuditEntry{
 		UserID:    userID,
-		Operation: "consent_update",
-		Status:    "completed",
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
-		DataTypes: []string{consentType},
+		Operation: models.GDPROperationErasureRequested,
+		Status:    models.GDPRStatusCompleted,
+		RequestID: request.ID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		DataTypes: []string{"all"},
+	}); err != nil {
+		return nil, err
 	}
 
-	return g.db.Create(&auditEntry).Error
-}
-
-// GetUserConsents retrieves user consent records
-func (g *GDPRCompliance) GetUserConsents(userID string) ([]ConsentRecord, error) {
-	var consents []ConsentRecord
-	err := g.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&consents).Error
-	return consents, err
+	err = g.mailer.Send(ctx, EmailMessage{
+		To:      email,
+		Subject: "Confirm deleting your account",
+		Body:    erasureBody(linkWithToken(g.confirmURL, token), g.tokenTTL, g.gracePeriod),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
 }
 
-// SaveExportToFile saves export data to a file
-func (g *GDPRCompliance) SaveExportToFile(export *UserDataExport) (string, error) {
-	// Create filename
-	filename := fmt.Sprintf("user_data_export_%s_%s.zip", export.UserID, export.RequestID)
-	filePath := filepath.Join(g.exportPath, filename)
+// VerifyErasure confirms the user's pending erasure with its emailed token
+// and schedules the hard delete for the end of the grace period
+func (g *GDPRCompliance) VerifyErasure(ctx context.Context, userID, token string, client SessionClient) (*models.DataDeletionRequest, error) {
+	now := time.Now().UTC()
+	scheduledFor := now.Add(g.gracePeriod)
 
-	// Create ZIP file
-	zipFile, err := os.Create(filePath)
+	result, err := g.db.ExecContext(ctx, `UPDATE data_deletion_requests
+		SET status = ?, verified_at = ?, scheduled_for = ?, updated_at = ?
+		WHERE user_id = ? AND token_hash = ? AND status = ? AND token_expires_at > ?`,
+		models.ErasureScheduled, now, scheduledFor, now,
+		userID, hashToken(token), models.ErasurePendingVerification, now)
 	if err != nil {
-		return "", fmt.Errorf("failed to create zip file: %w", err)
+		return nil, fmt.Errorf("failed to verify erasure: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, ErrInvalidErasureToken
 	}
-	defer zipFile.Close()
-
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
 
-	// Add JSON data to ZIP
-	jsonData, err := json.MarshalIndent(export, "", "  ")
+	request, err := g.ErasureStatus(ctx, userID)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal export data: %w", err)
+		return nil, err
 	}
+	if err := g.audit(ctx, models.GDPRAuditEntry{
+		UserID:    userID,
+		Operation: models.GDPROperationErasureVerified,
+		Status:    models.GDPRStatusCompleted,
+		RequestID: request.ID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		DataTypes: []string{"all"},
+	}); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
 
-	jsonFile, err := zipWriter.Create("user_data.json")
+// CancelErasure cancels the user's pending or scheduled erasure
+func (g *GDPRCompliance) CancelErasure(ctx context.Context, userID string, client SessionClient) (*models.DataDeletionRequest, error) {
+	request, err := g.ErasureStatus(ctx, userID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create JSON file in zip: %w", err)
+		return nil, err
 	}
-
-	if _, err := jsonFile.Write(jsonData); err != nil {
-		return "", fmt.Errorf("failed to write JSON data: %w", err)
+	if request.Status != models.ErasurePendingVerification && request.Status != models.ErasureScheduled {
+		return nil, ErrErasureNotFound
 	}
 
-	// Add README file
-	readmeContent := g.generateReadmeContent(export)
-	readmeFile, err := zipWriter.Create("README.txt")
+	now := time.Now().UTC()
+	result, err := g.db.ExecContext(ctx, `UPDATE data_deletion_requests SET status = ?, cancelled_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?`, models.ErasureCancelled, now, now, request.ID, request.Status)
 	if err != nil {
-		return "", fmt.Errorf("failed to create README file: %w", err)
+		return nil, fmt.Errorf("failed to cancel erasure: %w", err)
 	}
-
-	if _, err := readmeFile.Write([]byte(readmeContent)); err != nil {
-		return "", fmt.Errorf("failed to write README content: %w", err)
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, ErrErasureNotFound
 	}
+	request.Status, request.CancelledAt, request.UpdatedAt = models.ErasureCancelled, &now, now
 
-	return filePath, nil
+	if err := g.audit(ctx, models.GDPRAuditEntry{
+		UserID:    userID,
+		Operation: models.GDPROperationErasureCancelled,
+		Status:    models.GDPRStatusCompleted,
+		RequestID: request.ID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		DataTypes: []string{"all"},
+	}); err != nil {
+		return nil, err
+	}
+	return request, nil
 }
 
-// generateReadmeContent generates README content for export
-func (g *GDPRCompliance) generateReadmeContent(export *UserDataExport) string {
-	return fmt.Sprintf(`GDPR Data Export
-================
-
-User ID: %s
-Export Date: %s
-Request ID: %s
-
-This archive contains all your personal data stored in our nutrition platform.
-The data is provided in JSON format for easy processing.
-
-Data Included:
-- User Profile
-- Nutrition Data
-- Recipes (%d records)
-- Meal Plans (%d records)
-- Preferences
-- Activity Log (%d records)
-- Consent Records (%d records)
-
-Total Records: %d
-Checksum: %s
-
-Data Retention:
-This export will be automatically deleted on: %s
-
-For questions about this export, please contact our support team.
-`,
-		export.UserID,
-		export.ExportDate.Format("2006-01-02 15:04:05"),
-		export.RequestID,
-		len(export.Recipes),
-		len(export.MealPlans),
-		len(export.ActivityLog),
-		len(export.Consents),
-		export.Metadata.TotalRecords,
-		export.Metadata.Checksum,
-		export.Metadata.RetentionDate.Format("2006-01-02"),
+// ErasureStatus returns the user's most recent erasure request
+func (g *GDPRCompliance) ErasureStatus(ctx context.Context, userID string) (*models.DataDeletionRequest, error) {
+	var (
+		request                                            models.DataDeletionRequest
+		reason, errorMessage                               sql.NullString
+		verifiedAt, scheduledFor, completedAt, cancelledAt sql.NullTime
 	)
+	err := g.db.QueryRowContext(ctx, `SELECT id, user_id, status, reason, token_expires_at, verified_at, scheduled_for,
+		completed_at, cancelled_at, error_message, created_at, updated_at
+		FROM data_deletion_requests WHERE user_id = ? ORDER BY created_at DESC, rowid DESC LIMIT 1`, userID).Scan(
+		&request.ID, &request.UserID, &request.Status, &reason, &request.TokenExpiresAt, &verifiedAt, &scheduledFor,
+		&completedAt, &cancelledAt, &errorMessage, &request.CreatedAt, &request.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrErasureNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get erasure request: %w", err)
+	}
+	request.Reason, request.ErrorMessage = reason.String, errorMessage.String
+	request.VerifiedAt = nullTimePtr(verifiedAt)
+	request.ScheduledFor = nullTimePtr(scheduledFor)
+	request.CompletedAt = nullTimePtr(completedAt)
+	request.CancelledAt = nullTimePtr(cancelledAt)
+	return &request, nil
+}
+
+// ProcessDueErasures hard deletes every account whose erasure grace period
+// has ended and returns how many were erased. A failed erasure stays
+// scheduled with its error and is retried on the next run.
+func (g *GDPRCompliance) ProcessDueErasures(ctx context.Context) (int, error) {
+	rows, err := g.db.QueryContext(ctx, `SELECT id, user_id FROM data_deletion_requests
+		WHERE status = ? AND scheduled_for <= ? ORDER BY scheduled_for`, models.ErasureScheduled, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due erasures: %w", err)
+	}
+	type dueErasure struct{ id, userID string }
+	var due []dueErasure
+	for rows.Next() {
+		var erasure dueErasure
+		if err := rows.Scan(&erasure.id, &erasure.userID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan erasure request: %w", err)
+		}
+		due = append(due, erasure)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list due erasures: %w", err)
+	}
+
+	erased := 0
+	var failed []string
+	for _, erasure := range due {
+		entry := models.GDPRAuditEntry{
+			UserID:    erasure.userID,
+			Operation: models.GDPROperationErasureCompleted,
+			Status:    models.GDPRStatusCompleted,
+			RequestID: erasure.id,
+			DataTypes: []string{"all"},
+		}
+		now := time.Now().UTC()
+		if eraseErr := g.eraseUser(ctx, erasure.userID); eraseErr != nil {
+			failed = append(failed, eraseErr.Error())
+			entry.Status, entry.ErrorMessage = models.GDPRStatusFailed, eraseErr.Error()
+			_, err = g.db.ExecContext(ctx, `UPDATE data_deletion_requests SET error_message = ?, updated_at = ? WHERE id = ?`,
+				eraseErr.Error(), now, erasure.id)
+		} else {
+			erased++
+			_, err = g.db.ExecContext(ctx, `UPDATE data_deletion_requests
+				SET status = ?, completed_at = ?, error_message = NULL, updated_at = ? WHERE id = ?`,
+				models.ErasureCompleted, now, now, erasure.id)
+		}
+		if err != nil {
+			return erased, fmt.Errorf("failed to update erasure request: %w", err)
+		}
+		if err := g.audit(ctx, entry); err != nil {
+			return erased, err
+		}
+	}
+	if len(failed) > 0 {
+		return erased, fmt.Errorf("failed to erase %d accounts: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return erased, nil
 }
 
-// Data export helper methods (these would interact with your actual data models)
-
-func (g *GDPRCompliance) exportUserProfile(userID string) (map[string]interface{}, error) {
-	// This would query your user profile table
-	var profile map[string]interface{}
-	// Implementation depends on your user model
-	return profile, nil
-}
-
-func (g *GDPRCompliance) exportNutritionData(userID string) (map[string]interface{}, error) {
-	// This would query your nutrition data tables
-	var nutritionData map[string]interface{}
-	// Implementation depends on your nutrition models
-	return nutritionData, nil
-}
-
-func (g *GDPRCompliance) exportUserRecipes(userID string) ([]map[string]interface{}, error) {
-	// This would query your recipes table
-	var recipes []map[string]interface{}
-	// Implementation depends on your recipe model
-	return recipes, nil
-}
-
-func (g *GDPRCompliance) exportUserMealPlans(userID string) ([]map[string]interface{}, error) {
-	// This would query your meal plans table
-	var mealPlans []map[string]interface{}
-	// Implementation depends on your meal plan model
-	return mealPlans, nil
-}
+// Watch erases due accounts every interval. It returns immediately; call
+// Close to stop.
+func (g *GDPRCompliance) Watch(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultErasureSweepInterval
+	}
 
-func (g *GDPRCompliance) exportUserPreferences(userID string) (map[string]interface{}, error) {
-	// This would query your user preferences table
-	var preferences map[string]interface{}
-	// Implementation depends on your preferences model
-	return preferences, nil
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.stop:
+				return
+			case <-ticker.C:
+				if _, err := g.ProcessDueErasures(context.Background()); err != nil {
+					log.Printf("gdpr: %v", err)
+				}
+			}
+		}
+	}()
 }
 
-func (g *GDPRCompliance) exportUserActivity(userID string) ([]map[string]interface{}, error) {
-	// This would query your activity log table
-	var activity []map[string]interface{}
-	// Implementation depends on your activity model
-	return activity, nil
+// Close stops the erasures started by Watch
+func (g *GDPRCompliance) Close() {
+	g.stopOnce.Do(func() { close(g.stop) })
 }
 
-func (g *GDPRCompliance) exportUserConsents(userID string) ([]map[string]interface{}, error) {
-	var consents []ConsentRecord
-	if err := g.db.Where("user_id = ?", userID).Find(&consents).Error; err != nil {
-		return nil, err
+// GetAuditLog returns the newest audit log entries, limited to one user when
+// userID is set
+func (g *GDPRCompliance) GetAuditLog(ctx context.Context, limit int, userID string) ([]models.GDPRAuditEntry, error) {
+	if limit < 1 || limit > 1000 {
+		return nil, fmt.Errorf("%w: limit must be between 1 and 1000", ErrInvalidAuditLogQuery)
 	}
+	query := `SELECT id, user_id, operation, status, request_id, ip_address, user_agent, data_types, error_message,
+		file_size, checksum, created_at FROM gdpr_audit_log`
+	args := []interface{}{}
+	if userID != "" {
+		query += ` WHERE user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
 
-	result := make([]map[string]interface{}, len(consents))
-	for i, consent := range consents {
-		consentMap := map[string]interface{}{
-			"consent_type": consent.ConsentType,
-			"granted":      consent.Granted,
-			"version":      consent.Version,
-			"created_at":   consent.CreatedAt,
-			"expires_at":   consent.ExpiresAt,
+	rows, err := g.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.GDPRAuditEntry{}
+	for rows.Next() {
+		var (
+			entry                                                   models.GDPRAuditEntry
+			requestID, ipAddress, userAgent, errorMessage, checksum sql.NullString
+			dataTypes                                               string
+		)
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Operation, &entry.Status, &requestID, &ipAddress,
+			&userAgent, &dataTypes, &errorMessage, &entry.FileSize, &checksum, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
 		}
-		result[i] = consentMap
+		entry.RequestID, entry.IPAddress, entry.UserAgent = requestID.String, ipAddress.String, userAgent.String
+		entry.ErrorMessage, entry.Checksum = errorMessage.String, checksum.String
+		entry.DataTypes = decodeStringList(dataTypes)
+		entries = append(entries, entry)
 	}
-
-	return result, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	return entries, nil
 }
 
-// Data deletion helper methods
-
-func (g *GDPRCompliance) deleteUserProfile(userID string) error {
-	// Implementation depends on your user model
+func (g *GDPRCompliance) audit(ctx context.Context, entry models.GDPRAuditEntry) error {
+	dataTypes, err := json.Marshal(entry.DataTypes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	_, err = g.db.ExecContext(ctx, `INSERT INTO gdpr_audit_log
+		(user_id, operation, status, request_id, ip_address, user_agent, data_types, error_message, file_size, checksum, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.UserID, entry.Operation, entry.Status, nullString(entry.RequestID), nullString(entry.IPAddress),
+		nullString(entry.UserAgent), string(dataTypes), nullString(entry.ErrorMessage), entry.FileSize,
+		nullString(entry.Checksum), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
 	return nil
 }
 
-func (g *GDPRCompliance) deleteNutritionData(userID string) error {
-	// Implementation depends on your nutrition models
-	return nil
-}
+// exportTable reads the rows of query without credential columns
+func (g *GDPRCompliance) exportTable(ctx context.Context, query, userID string) (*gdprTable, error) {
+	rows, err := g.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-func (g *GDPRCompliance) deleteUserRecipes(userID string) error {
-	// Implementation depends on your recipe model
-	return nil
-}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &gdprTable{rows: []map[string]interface{}{}}
+	for _, column := range columns {
+		if !gdprSecretColumns[column] {
+			table.columns = append(table.columns, column)
+		}
+	}
 
-func (g *GDPRCompliance) deleteUserMealPlans(userID string) error {
-	// Implementation depends on your meal plan model
-	return nil
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := map[string]interface{}{}
+		for i, column := range columns {
+			if gdprSecretColumns[column] {
+				continue
+			}
+			if raw, ok := values[i].([]byte); ok {
+				values[i] = string(raw)
+			}
+			row[column] = values[i]
+		}
+		table.rows = append(table.rows, row)
+	}
+	return table, rows.Err()
 }
 
-func (g *GDPRCompliance) deleteUserPreferences(userID string) error {
-	// Implementation depends on your preferences model
+// writePhotoFiles adds the original of every progress photo to the archive.
+// Files missing from storage are listed in the metadata instead.
+func (g *GDPRCompliance) writePhotoFiles(ctx context.Context, archive *zip.Writer, photos *gdprTable, metadata *GDPRExportMetadata) error {
+	if g.files == nil {
+		return nil
+	}
+	for _, photo := range photos.rows {
+		fileURL, _ := photo["file_url"].(string)
+		id, _ := photo["id"].(string)
+		if fileURL == "" {
+			continue
+		}
+		file, err := g.files.GetFile(ctx, fileURL)
+		if err != nil {
+			metadata.MissingFiles = append(metadata.MissingFiles, id)
+			continue
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			metadata.MissingFiles = append(metadata.MissingFiles, id)
+			continue
+		}
+		if err := writeZipFile(archive, "photos/"+id+path.Ext(fileURL), content); err != nil {
+			return err
+		}
+		metadata.Files++
+	}
 	return nil
 }
 
-func (g *GDPRCompliance) deleteUserActivity(userID string) error {
-	// Implementation depends on your activity model
-	return nil
-}
+// eraseUser hard deletes the user's stored files and then their rows from
+// every table with a user_id column, the rows depending on those and the
+// account itself. Foreign keys are not relied on as SQLite only enforces
+// them when enabled per connection.
+func (g *GDPRCompliance) eraseUser(ctx context.Context, userID string) error {
+	if g.files != nil {
+		fileURLs, err := g.userFiles(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, fileURL := range fileURLs {
+			if err := g.files.DeleteFile(ctx, fileURL); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to delete %s: %w", fileURL, err)
+			}
+		}
+	}
 
-func (g *GDPRCompliance) deleteAllUserData(userID string) error {
-	// Delete all user data across all tables
-	if err := g.deleteUserProfile(userID); err != nil {
+	tables, err := g.userTables(ctx)
+	if err != nil {
 		return err
 	}
-	if err := g.deleteNutritionData(userID); err != nil {
-		return err
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	if err := g.deleteUserRecipes(userID); err != nil {
-		return err
+	defer tx.Rollback()
+
+	for _, statement := range erasureDependents {
+		if _, err := tx.ExecContext(ctx, statement, userID); err != nil {
+			return fmt.Errorf("failed to erase user data: %w", err)
+		}
 	}
-	if err := g.deleteUserMealPlans(userID); err != nil {
-		return err
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "`+table+`" WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("failed to erase %s: %w", table, err)
+		}
 	}
-	if err := g.deleteUserPreferences(userID); err != nil {
-		return err
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to erase account: %w", err)
 	}
-	if err := g.deleteUserActivity(userID); err != nil {
-		return err
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit erasure: %w", err)
 	}
-
-	// Delete consent records
-	return g.db.Where("user_id = ?", userID).Delete(&ConsentRecord{}).Error
+	return nil
 }
 
-// GetAuditLog retrieves GDPR audit log entries
-func (g *GDPRCompliance) GetAuditLog(limit int, userID string) ([]GDPRAuditEntry, error) {
-	var entries []GDPRAuditEntry
-	query := g.db.Order("created_at DESC")
-
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
+// userFiles returns the storage handles of the user's progress photos
+func (g *GDPRCompliance) userFiles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := g.db.QueryContext(ctx, `SELECT file_url, thumb_url FROM progress_photos WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user files: %w", err)
 	}
+	defer rows.Close()
 
-	if limit > 0 {
-		query = query.Limit(limit)
+	var fileURLs []string
+	for rows.Next() {
+		var fileURL, thumbURL string
+		if err := rows.Scan(&fileURL, &thumbURL); err != nil {
+			return nil, fmt.Errorf("failed to list user files: %w", err)
+		}
+		fileURLs = append(fileURLs, nonBlank([]string{fileURL, thumbURL})...)
 	}
-
-	err := query.Find(&entries).Error
-	return entries, err
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list user files: %w", err)
+	}
+	return fileURLs, nil
 }
 
-// CleanupExpiredExports removes expired export files
-func (g *GDPRCompliance) CleanupExpiredExports() error {
-	// This would be called by a scheduled job
-	files, err := filepath.Glob(filepath.Join(g.exportPath, "*.zip"))
+// userTables lists the tables with a user_id column whose rows are erased
+// with the account
+func (g *GDPRCompliance) userTables(ctx context.Context) ([]string, error) {
+	rows, err := g.db.QueryContext(ctx, `SELECT m.name FROM sqlite_master m
+		JOIN pragma_table_info(m.name) c ON c.name = 'user_id'
+		WHERE m.type = 'table' ORDER BY m.name`)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to list user tables: %w", err)
 	}
+	defer rows.Close()
 
-	for _, file := range files {
-		info, err := os.Stat(file)
-		if err != nil {
-			continue
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("failed to list user tables: %w", err)
 		}
-
-		if time.Since(info.ModTime()) > g.retentionPeriod {
-			os.Remove(file)
+		if !erasureRetainedTables[table] {
+			tables = append(tables, table)
 		}
 	}
-
-	return nil
-}
-
-// RegisterRoutes registers GDPR compliance API routes
-func (g *GDPRCompliance) RegisterRoutes(e *echo.Group) {
-	e.POST("/gdpr/export", g.handleExportData)
-	e.POST("/gdpr/delete", g.handleDeleteData)
-	e.POST("/gdpr/consent", g.handleRecordConsent)
-	e.GET("/gdpr/consents/:user_id", g.handleGetConsents)
-	e.GET("/gdpr/audit", g.handleGetAuditLog)
-	e.GET("/gdpr/download/:request_id", g.handleDownloadExport)
-	e.GET("/gdpr/status/:request_id", g.handleGetRequestStatus)
-}
-
-// API Handlers
-
-type ExportDataRequest struct {
-	UserID string `json:"user_id"`
-}
-
-type DeleteDataRequest struct {
-	UserID       string   `json:"user_id"`
-	Reason       string   `json:"reason"`
-	DataTypes    []string `json:"data_types"`
-	Verification string   `json:"verification"`
-}
-
-type RecordConsentRequest struct {
-	UserID      string     `json:"user_id"`
-	ConsentType string     `json:"consent_type"`
-	Granted     bool       `json:"granted"`
-	Version     string     `json:"version"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list user tables: %w", err)
+	}
+	return tables, nil
 }
 
-func (g *GDPRCompliance) handleExportData(c echo.Context) error {
-	var req ExportDataRequest
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, map[string]string{"error": "Invalid request format"})
+// gdprCSV encodes a dataset with a header row. Times are RFC 3339 and NULL
+// is an empty field.
+func gdprCSV(table *gdprTable) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(table.columns); err != nil {
+		return nil, err
 	}
-
-	requestID := fmt.Sprintf("exp_%d", time.Now().Unix())
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
-
-	export, err := g.ExportUserData(req.UserID, requestID, ipAddress, userAgent)
-	if err != nil {
-		return c.JSON(500, map[string]string{"error": err.Error()})
+	for _, row := range table.rows {
+		record := make([]string, len(table.columns))
+		for i, column := range table.columns {
+			switch value := row[column].(type) {
+			case nil:
+			case time.Time:
+				record[i] = value.UTC().Format(time.RFC3339)
+			case string:
+				record[i] = value
+			case int64:
+				record[i] = strconv.FormatInt(value, 10)
+			case float64:
+				record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+			default:
+				record[i] = fmt.Sprint(value)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
 	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
 
-	// Save to file
-	filePath, err := g.SaveExportToFile(export)
+func writeZipFile(archive *zip.Writer, name string, content []byte) error {
+	file, err := archive.Create(name)
 	if err != nil {
-		return c.JSON(500, map[string]string{"error": "Failed to save export file"})
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
 	}
-
-	return c.JSON(200, map[string]interface{}{
-		"request_id": requestID,
-		"status":     "completed",
-		"file_path":  filepath.Base(filePath),
-		"metadata":   export.Metadata,
-	})
-}
-
-func (g *GDPRCompliance) handleDeleteData(c echo.Context) error {
-	var req DeleteDataRequest
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, map[string]string{"error": "Invalid request format"})
+	if _, err := file.Write(content); err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
 	}
+	return nil
+}
 
-	requestID := fmt.Sprintf("del_%d", time.Now().Unix())
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
+func gdprReadme(metadata *GDPRExportMetadata) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `Personal Data Export
+====================
 
-	err := g.DeleteUserData(req.UserID, requestID, req.Reason, req.Verification, ipAddress, userAgent, req.DataTypes)
-	if err != nil {
-		return c.JSON(500, map[string]string{"error": err.Error()})
-	}
+User ID: %s
+Request ID: %s
+Exported: %s
+Format version: %s
 
-	return c.JSON(200, map[string]interface{}{
-		"request_id": requestID,
-		"status":     "processing",
-		"message":    "Data deletion request submitted successfully",
-	})
-}
+data.json holds every dataset below; csv/ has one CSV file per dataset and
+photos/ the original progress photos. consents is your consent history: every
+grant and withdrawal with the version of the purpose it applied to.
+Credentials such as password hashes are not included.
 
-func (g *GDPRCompliance) handleRecordConsent(c echo.Context) error {
-	var req RecordConsentRequest
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, map[string]string{"error": "Invalid request format"})
+`, metadata.UserID, metadata.RequestID, metadata.ExportedAt.Format(time.RFC3339), metadata.Version)
+	for _, dataset := range gdprDatasets {
+		fmt.Fprintf(&b, "- %s: %d records\n", dataset.name, metadata.Records[dataset.name])
+	}
+	fmt.Fprintf(&b, "\nTotal records: %d\nPhoto files: %d\n", metadata.TotalRecords, metadata.Files)
+	if len(metadata.MissingFiles) > 0 {
+		fmt.Fprintf(&b, "Photos missing from storage: %s\n", strings.Join(metadata.MissingFiles, ", "))
 	}
+	fmt.Fprintf(&b, "SHA-256 of data.json: %s\n", metadata.Checksum)
+	return b.String()
+}
 
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
+func erasureBody(link string, ttl, gracePeriod time.Duration) string {
+	return fmt.Sprintf(`We received a request to delete your account and all of its data.
 
-	err := g.RecordConsent(req.UserID, req.ConsentType, req.Version, ipAddress, userAgent, req.Granted, req.ExpiresAt)
-	if err != nil {
-		return c.JSON(500, map[string]string{"error": err.Error()})
-	}
+Use the token or link below to confirm. It expires in %d hours and can only be used once.
 
-	return c.JSON(200, map[string]string{"message": "Consent recorded successfully"})
-}
+%s
 
-func (g *GDPRCompliance) handleGetConsents(c echo.Context) error {
-	userID := c.Param("user_id")
-	consents, err := g.GetUserConsents(userID)
-	if err != nil {
-		return c.JSON(500, map[string]string{"error": err.Error()})
-	}
+Your account will be deleted %d days after you confirm; until then you can cancel the deletion from your account settings.
 
-	return c.JSON(200, consents)
+If you did not request this, you can ignore this email.
+`, int(ttl.Hours()), link, int(gracePeriod.Hours()/24))
 }
 
-func (g *GDPRCompliance) handleGetAuditLog(c echo.Context) error {
-	limit := 100
-	userID := c.QueryParam("user_id")
-
-	if limitStr := c.QueryParam("limit"); limitStr != "" {
-		if parsedLimit, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || parsedLimit != 1 {
-			limit = 100
-		}
+// linkWithToken adds token as the "token" query parameter of base, or returns
+// the bare token when base is not a URL
+func linkWithToken(base, token string) string {
+	link, err := url.Parse(base)
+	if err != nil || base == "" {
+		return token
 	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
 
-	entries, err := g.GetAuditLog(limit, userID)
-	if err != nil {
-		return c.JSON(500, map[string]string{"error": err.Error()})
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
 	}
-
-	return c.JSON(200, map[string]interface{}{
-		"audit_log": entries,
-		"count":     len(entries),
-	})
+	t := value.Time
+	return &t
 }
 
-func (g *GDPRCompliance) handleDownloadExport(c echo.Context) error {
-	requestID := c.Param("request_id")
-	filename := fmt.Sprintf("user_data_export_%s.zip", requestID)
-	filePath := filepath.Join(g.exportPath, filename)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return c.JSON(404, map[string]string{"error": "Export file not found"})
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
 	}
-
-	return c.File(filePath)
+	return value
 }
 
-func (g *GDPRCompliance) handleGetRequestStatus(c echo.Context) error {
-	requestID := c.Param("request_id")
-
-	// Check audit log for request status
-	var auditEntry GDPRAuditEntry
-	err := g.db.Where("request_id = ?", requestID).First(&auditEntry).Error
-	if err != nil {
-		return c.JSON(404, map[string]string{"error": "Request not found"})
-	}
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
 
-	return c.JSON(200, map[string]interface{}{
-		"request_id":   requestID,
-		"status":       auditEntry.Status,
-		"operation":    auditEntry.Operation,
-		"created_at":   auditEntry.CreatedAt,
-		"completed_at": auditEntry.CompletedAt,
-		"error":        auditEntry.ErrorMessage,
-	})
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
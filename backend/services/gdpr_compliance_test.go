@@ -0,0 +1,223 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGDPRCompliance returns a GDPR service sharing storage with a progress
+// photo service, an outbox for erasure tokens and a user with a stored photo
+func newTestGDPRCompliance(t *testing.T) (*GDPRCompliance, *OutboxMailer, string, *models.ProgressPhoto) {
+	t.Helper()
+	ctx := context.Background()
+	photos, _, userID := newTestProgressPhotoService(t)
+
+	photo, err := photos.Upload(ctx, userID, multipartPhoto(t, "front.jpg", exifJPEG(t, 30, 30, 1)),
+		models.UploadProgressPhotoRequest{Date: "2026-03-04", Weight: 80})
+	require.NoError(t, err)
+
+	outbox, err := NewOutboxMailer(t.TempDir())
+	require.NoError(t, err)
+	gdpr := NewGDPRCompliance(photos.db, photos.files)
+	gdpr.SetErasureMailer(outbox, "https://app.example.com/confirm-deletion")
+	return gdpr, outbox, userID, photo
+}
+
+func readZip(t *testing.T, archive []byte) map[string][]byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		content, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(content)
+		content.Close()
+		require.NoError(t, err)
+		files[file.Name] = data
+	}
+	return files
+}
+
+func TestGDPRCompliance_ExportArchive(t *testing.T) {
+	ctx := context.Background()
+	gdpr, _, userID, photo := newTestGDPRCompliance(t)
+
+	_, err := gdpr.db.Exec(`INSERT INTO user_food_logs (user_id, quantity, consumed_at, notes) VALUES (?, 2, ?, 'oats, "plain"')`,
+		userID, time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	_, err = gdpr.db.Exec(`INSERT INTO user_medications (user_id, medication_id) VALUES (?, 'metformin')`, userID)
+	require.NoError(t, err)
+	_, err = gdpr.db.Exec(`INSERT INTO workout_programs (id, user_id, name) VALUES ('program-1', ?, 'Knee-friendly strength')`, userID)
+	require.NoError(t, err)
+	_, err = gdpr.db.Exec(`INSERT INTO workout_sessions (workout_program_id, session_number, name, week_number, day_number)
+		VALUES ('program-1', 1, 'Upper body', 1, 1)`)
+	require.NoError(t, err)
+	_, err = gdpr.db.Exec(`INSERT INTO api_keys (id, name, key_hash, prefix, user_id, scopes)
+		VALUES ('key-1', 'Diary sync', 'secret-key-hash', 'nk_test', ?, '["meals"]')`, userID)
+	require.NoError(t, err)
+	users := &UserService{db: gdpr.db}
+	_, _, err = users.CreateSession(ctx, userID, SessionClient{IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+
+	var archive bytes.Buffer
+	metadata, err := gdpr.ExportArchive(ctx, userID, SessionClient{IPAddress: "203.0.113.7"}, &archive)
+	require.NoError(t, err)
+	assert.Equal(t, 1, metadata.Records["profile"])
+	assert.Equal(t, 1, metadata.Records["food_logs"])
+	assert.Equal(t, 1, metadata.Records["measurements"])
+	assert.Equal(t, 1, metadata.Records["photos"])
+	assert.Equal(t, 1, metadata.Records["medications"])
+	assert.Equal(t, 1, metadata.Records["sessions"])
+	assert.Equal(t, 1, metadata.Records["workout_programs"])
+	assert.Equal(t, 1, metadata.Records["workout_program_sessions"])
+	assert.Equal(t, 1, metadata.Records["api_keys"])
+	assert.Equal(t, 1, metadata.Files)
+	assert.Equal(t, int64(archive.Len()), metadata.Size)
+
+	files := readZip(t, archive.Bytes())
+	require.Contains(t, files, "data.json")
+	require.Contains(t, files, "README.txt")
+	require.Contains(t, files, "photos/"+photo.ID+".jpg")
+
+	var data map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal(files["data.json"], &data))
+	require.Len(t, data["profile"], 1)
+	assert.Equal(t, "photos@example.com", data["profile"][0]["email"])
+	// Credentials are never exported
+	assert.NotContains(t, data["profile"][0], "password_hash")
+	assert.NotContains(t, string(files["data.json"]), "token_hash")
+	assert.NotContains(t, string(files["data.json"]), "secret-key-hash")
+
+	records, err := csv.NewReader(bytes.NewReader(files["csv/food_logs.csv"])).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	header := map[string]int{}
+	for i, column := range records[0] {
+		header[column] = i
+	}
+	assert.Equal(t, `oats, "plain"`, records[1][header["notes"]])
+	assert.Equal(t, "2026-03-04T08:00:00Z", records[1][header["consumed_at"]])
+	assert.Contains(t, files, "csv/medications.csv")
+
+	entries, err := gdpr.GetAuditLog(ctx, 10, userID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.GDPROperationExport, entries[0].Operation)
+	assert.Equal(t, metadata.Checksum, entries[0].Checksum)
+	assert.Equal(t, "203.0.113.7", entries[0].IPAddress)
+
+	_, err = gdpr.ExportArchive(ctx, "someone-else", SessionClient{}, io.Discard)
+	assert.ErrorIs(t, err, ErrGDPRAccountNotFound)
+}
+
+func TestGDPRCompliance_Erasure(t *testing.T) {
+	ctx := context.Background()
+	gdpr, outbox, userID, photo := newTestGDPRCompliance(t)
+
+	_, err := gdpr.CancelErasure(ctx, userID, SessionClient{})
+	assert.ErrorIs(t, err, ErrErasureNotFound)
+
+	request, err := gdpr.RequestErasure(ctx, userID, "moving on", SessionClient{})
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasurePendingVerification, request.Status)
+	require.Len(t, outbox.Messages(), 1)
+	token := resetTokenFromEmail(t, outbox.Messages()[0])
+
+	_, err = gdpr.VerifyErasure(ctx, userID, "wrong-token", SessionClient{})
+	assert.ErrorIs(t, err, ErrInvalidErasureToken)
+	_, err = gdpr.VerifyErasure(ctx, "someone-else", token, SessionClient{})
+	assert.ErrorIs(t, err, ErrInvalidErasureToken)
+
+	request, err = gdpr.VerifyErasure(ctx, userID, token, SessionClient{})
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasureScheduled, request.Status)
+	require.NotNil(t, request.ScheduledFor)
+	assert.WithinDuration(t, time.Now().Add(DefaultErasureGracePeriod), *request.ScheduledFor, time.Minute)
+
+	// The token is single-use and a scheduled erasure is not requested twice
+	_, err = gdpr.VerifyErasure(ctx, userID, token, SessionClient{})
+	assert.ErrorIs(t, err, ErrInvalidErasureToken)
+	_, err = gdpr.RequestErasure(ctx, userID, "", SessionClient{})
+	assert.ErrorIs(t, err, ErrErasureScheduled)
+
+	// Nothing is erased during the grace period, and it can be cancelled
+	erased, err := gdpr.ProcessDueErasures(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, erased)
+	request, err = gdpr.CancelErasure(ctx, userID, SessionClient{})
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasureCancelled, request.Status)
+
+	// Requesting again replaces a pending request's token
+	gdpr.SetGracePeriod(0)
+	_, err = gdpr.RequestErasure(ctx, userID, "", SessionClient{})
+	require.NoError(t, err)
+	stale := resetTokenFromEmail(t, outbox.Messages()[1])
+	_, err = gdpr.RequestErasure(ctx, userID, "", SessionClient{})
+	require.NoError(t, err)
+	_, err = gdpr.VerifyErasure(ctx, userID, stale, SessionClient{})
+	assert.ErrorIs(t, err, ErrInvalidErasureToken)
+	_, err = gdpr.VerifyErasure(ctx, userID, resetTokenFromEmail(t, outbox.Messages()[2]), SessionClient{})
+	require.NoError(t, err)
+
+	_, err = gdpr.db.Exec(`INSERT INTO user_food_logs (user_id, quantity, consumed_at) VALUES (?, 1, ?)`, userID, time.Now())
+	require.NoError(t, err)
+	_, err = gdpr.GrantConsent(ctx, userID, models.ConsentMarketing, SessionClient{})
+	require.NoError(t, err)
+
+	erased, err = gdpr.ProcessDueErasures(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, erased)
+
+	var count int
+	require.NoError(t, gdpr.db.QueryRow(`SELECT COUNT(*) FROM users WHERE id = ?`, userID).Scan(&count))
+	assert.Zero(t, count)
+	tables, err := gdpr.userTables(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, tables, "progress_photos")
+	for _, table := range tables {
+		require.NoError(t, gdpr.db.QueryRow(`SELECT COUNT(*) FROM "`+table+`" WHERE user_id = ?`, userID).Scan(&count))
+		assert.Zero(t, count, "rows left in %s", table)
+	}
+
+	// Stored files are removed with the rows
+	for _, fileURL := range []string{photo.FileURL, photo.ThumbURL} {
+		_, err := gdpr.files.GetFile(ctx, fileURL)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+
+	// The request and audit log remain as the record of the erasure
+	request, err = gdpr.ErasureStatus(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasureCompleted, request.Status)
+	entries, err := gdpr.GetAuditLog(ctx, 1, userID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.GDPROperationErasureCompleted, entries[0].Operation)
+	assert.Equal(t, models.GDPRStatusCompleted, entries[0].Status)
+
+	_, err = gdpr.GetAuditLog(ctx, 0, "")
+	assert.ErrorIs(t, err, ErrInvalidAuditLogQuery)
+}
+
+func TestGDPRCompliance_RequestErasureNeedsMailer(t *testing.T) {
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "erase@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = NewGDPRCompliance(users.db, nil).RequestErasure(context.Background(), user.ID, "", SessionClient{})
+	assert.ErrorIs(t, err, ErrMailerNotConfigured)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// ErrInvalidHalalPreferences is returned by HalalService for unknown
+// strictness levels and dietary schools
+var ErrInvalidHalalPreferences = errors.New("invalid halal preferences")
+
+var (
+	halalStrictnessLevels = map[string]bool{models.HalalStrict: true, models.HalalModerate: true, models.HalalLenient: true}
+	halalDietarySchools   = map[string]bool{"hanafi": true, "shafi": true, "maliki": true, "hanbali": true}
+)
+
+// HalalService stores each user's halal preferences and runs the shared
+// HalalCompliance engine with them
+type HalalService struct {
+	db         *sql.DB
+	compliance *HalalCompliance
+}
+
+// NewHalalService creates a new halal service checking with compliance
+func NewHalalService(db *sql.DB, compliance *HalalCompliance) *HalalService {
+	return &HalalService{
+		db:         db,
+		compliance: compliance,
+	}
+}
+
+// Compliance returns the shared compliance engine
+func (s *HalalService) Compliance() *HalalCompliance {
+	return s.compliance
+}
+
+// Preferences returns userID's halal preferences, or the defaults when none
+// were saved
+func (s *HalalService) Preferences(ctx context.Context, userID string) (*models.HalalPreferences, error) {
+	prefs := &models.HalalPreferences{UserID: userID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT strictness_level, dietary_school, auto_substitute, updated_at
+		FROM halal_preferences WHERE user_id = ?`, userID).
+		Scan(&prefs.StrictnessLevel, &prefs.DietarySchool, &prefs.AutoSubstitute, &prefs.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		prefs.StrictnessLevel = models.DefaultHalalStrictness
+		prefs.DietarySchool = models.DefaultHalalDietarySchool
+		return prefs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get halal preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences changes the fields set in req and keeps the others
+func (s *HalalService) UpdatePreferences(ctx context.Context, userID string, req models.UpdateHalalPreferencesRequest) (*models.HalalPreferences, error) {
+	prefs, err := s.Preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.StrictnessLevel != nil {
+		if !halalStrictnessLevels[*req.StrictnessLevel] {
+			return nil, fmt.Errorf("%w: unknown strictness level %q", ErrInvalidHalalPreferences, *req.StrictnessLevel)
+		}
+		prefs.StrictnessLevel = *req.StrictnessLevel
+	}
+	if req.DietarySchool != nil {
+		if !halalDietarySchools[*req.DietarySchool] {
+			return nil, fmt.Errorf("%w: unknown dietary school %q", ErrInvalidHalalPreferences, *req.DietarySchool)
+		}
+		prefs.DietarySchool = *req.DietarySchool
+	}
+	if req.AutoSubstitute != nil {
+		prefs.AutoSubstitute = *req.AutoSubstitute
+	}
+
+	now := time.Now().UTC()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO halal_preferences (user_id, strictness_level, dietary_school, auto_substitute, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			strictness_level = excluded.strictness_level,
+			dietary_school = excluded.dietary_school,
+			auto_substitute = excluded.auto_substitute,
+			updated_at = excluded.updated_at`,
+		userID, prefs.StrictnessLevel, prefs.DietarySchool, prefs.AutoSubstitute, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save halal preferences: %w", err)
+	}
+	prefs.UpdatedAt = now
+	return prefs, nil
+}
+
+// Check runs a compliance check with userID's preferences
+func (s *HalalService) Check(ctx context.Context, userID string, ingredients []string, recipe string) (*ComplianceResult, error) {
+	prefs, err := s.Preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.compliance.CheckCompliance(ingredients, recipe, *prefs)
+}
+
+// CheckMeal sets meal.IsHalal from a check with its owner's preferences. When
+// the owner opted into substitutions they replace the meal's ingredients.
+func (s *HalalService) CheckMeal(ctx context.Context, meal *Meal) (*ComplianceResult, error) {
+	result, err := s.Check(ctx, meal.UserID, meal.Ingredients, "")
+	if err != nil {
+		return nil, err
+	}
+	if result.SubstitutionsApplied {
+		meal.Ingredients = result.ModifiedRecipe.ModifiedIngredients
+	}
+	meal.IsHalal = result.IsCompliant
+	return result, nil
+}
+
+// CheckFoodLog checks the food or recipe of userID's diary entry with their
+// preferences. The entry's name is checked with its ingredients.
+func (s *HalalService) CheckFoodLog(ctx context.Context, userID string, foodID, recipeID *string) (*ComplianceResult, error) {
+	item, err := loadFoodLogItem(ctx, s.db, foodID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	meal := &Meal{
+		UserID:      userID,
+		Name:        item.name,
+		Ingredients: append([]string{item.name}, item.ingredients...),
+	}
+	return s.CheckMeal(ctx, meal)
+}
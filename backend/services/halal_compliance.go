@@ -2,28 +2,47 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/labstack/echo/v4"
+	"nutrition-platform/models"
 )
 
-// HalalCompliance manages halal food compliance and substitutions
+// DefaultHalalBlacklistPath is the bundled blacklist of haram and doubtful
+// ingredients
+const DefaultHalalBlacklistPath = "data/halal_blacklist.json"
+
+// ErrNoHalalSuggestions is returned by HalalCompliance.GetSuggestions for
+// ingredients without known substitutions
+var ErrNoHalalSuggestions = errors.New("no halal suggestions found")
+
+// ErrUnknownAdditive is returned by HalalCompliance.LookupAdditive for codes
+// missing from the additive registry
+var ErrUnknownAdditive = errors.New("unknown additive")
+
+// additiveCodePattern matches E-numbers and INS numbers however they are
+// spaced or hyphenated, e.g. "E471", "E 471", "e-471", "INS 471" and "E472a"
+var additiveCodePattern = regexp.MustCompile(`(?i)\b(?:e|ins)[\s.\-‐–]*(\d{3,4}[a-z]?)\b`)
+
+// HalalCompliance checks ingredients against the halal blacklist. It holds no
+// per-user state: strictness level and dietary school are passed with each
+// check, so one instance is shared by every caller.
 type HalalCompliance struct {
-	mu              sync.RWMutex
-	blacklistData   *BlacklistData
-	lastUpdated     time.Time
-	blacklistPath   string
-	strictnessLevel string
-	dietarySchool   string
-	multilingual    bool
+	mu            sync.RWMutex
+	blacklistData *BlacklistData
+	keywords      []halalKeyword
+	sourceTerms   map[string][][]string
+	lastUpdated   time.Time
+	blacklistPath string
 }
 
-// BlacklistData represents the structure of blacklist.json
+// BlacklistData represents the structure of the blacklist file
 type BlacklistData struct {
 	HalalCompliance struct {
 		Version                string `json:"version"`
@@ -47,57 +66,81 @@ type BlacklistData struct {
 			CookingMethods    []string `json:"cooking_methods"`
 		} `json:"cultural_considerations"`
 		ValidationKeywords struct {
-			DefinitelyHaram         []string `json:"definitely_haram"`
-			RequiresVerification    []string `json:"requires_verification"`
-			HalalCertifiedPreferred []string `json:"halal_certified_preferred"`
+			DefinitelyHaram         []string            `json:"definitely_haram"`
+			RequiresVerification    []string            `json:"requires_verification"`
+			VerificationSuggestions map[string][]string `json:"verification_suggestions"`
+			HalalCertifiedPreferred []string            `json:"halal_certified_preferred"`
+			Permissible             []string            `json:"permissible"`
 		} `json:"validation_keywords"`
-		AutoDetectionPatterns struct {
-			IngredientScanning struct {
-				CaseInsensitive     bool     `json:"case_insensitive"`
-				PartialMatching     bool     `json:"partial_matching"`
-				SynonymDetection    bool     `json:"synonym_detection"`
-				MultilingualSupport []string `json:"multilingual_support"`
-			} `json:"ingredient_scanning"`
-			RecipeAnalysis struct {
-				ScanIngredients     bool `json:"scan_ingredients"`
-				ScanInstructions    bool `json:"scan_instructions"`
-				ScanNutritionalInfo bool `json:"scan_nutritional_info"`
-				FlagSuspiciousItems bool `json:"flag_suspicious_items"`
-			} `json:"recipe_analysis"`
-		} `json:"auto_detection_patterns"`
+		Additives       map[string]AdditiveRules       `json:"additives"`
+		AdditiveSources map[string][]string            `json:"additive_sources"`
+		Synonyms        map[string]map[string][]string `json:"synonyms"`
 		UserPreferences struct {
-			StrictnessLevels map[string]struct {
-				Description               string `json:"description"`
-				AutoReject                bool   `json:"auto_reject"`
-				RequireHalalCertification bool   `json:"require_halal_certification"`
-				ShowWarnings              bool   `json:"show_warnings"`
-				ShowSuggestions           bool   `json:"show_suggestions"`
-			} `json:"strictness_levels"`
-			DietarySchools map[string]struct {
-				AdditionalRestrictions []string `json:"additional_restrictions"`
-			} `json:"dietary_schools"`
+			StrictnessLevels map[string]StrictnessRules    `json:"strictness_levels"`
+			DietarySchools   map[string]DietarySchoolRules `json:"dietary_schools"`
 		} `json:"user_preferences"`
 	} `json:"halal_compliance"`
 }
 
-// ComplianceResult represents the result of halal compliance check
+// StrictnessRules configure how doubtful ingredients are reported
+type StrictnessRules struct {
+	Description               string `json:"description"`
+	RequireHalalCertification bool   `json:"require_halal_certification"`
+	ShowWarnings              bool   `json:"show_warnings"`
+}
+
+// DietarySchoolRules lists what a school avoids beyond the common blacklist
+type DietarySchoolRules struct {
+	AdditionalRestrictions []string            `json:"additional_restrictions"`
+	Suggestions            map[string][]string `json:"suggestions,omitempty"`
+}
+
+// AdditiveRules is the registry entry of a food additive. Sources maps the
+// origins it can be made from to their halal status; Status applies when an
+// ingredient does not state the origin.
+type AdditiveRules struct {
+	Name         string            `json:"name"`
+	Status       string            `json:"status"`
+	Sources      map[string]string `json:"sources,omitempty"`
+	Alternatives []string          `json:"alternatives,omitempty"`
+}
+
+// AdditiveInfo describes a registered additive with its names per language
+type AdditiveInfo struct {
+	Code string `json:"code"`
+	AdditiveRules
+	Synonyms map[string][]string `json:"synonyms,omitempty"`
+}
+
+// ComplianceResult represents the result of halal compliance check. When the
+// user opted into automatic substitution and some were applied,
+// SubstitutionsApplied is set and IsCompliant describes the modified
+// ingredients: it is true if every violation was substituted.
 type ComplianceResult struct {
-	IsCompliant       bool              `json:"is_compliant"`
-	Violations        []Violation       `json:"violations,omitempty"`
-	Suggestions       []Suggestion      `json:"suggestions,omitempty"`
-	Warnings          []Warning         `json:"warnings,omitempty"`
-	NutritionalImpact map[string]string `json:"nutritional_impact,omitempty"`
-	CulturalNotes     []string          `json:"cultural_notes,omitempty"`
-	ModifiedRecipe    *ModifiedRecipe   `json:"modified_recipe,omitempty"`
+	IsCompliant          bool              `json:"is_compliant"`
+	StrictnessLevel      string            `json:"strictness_level"`
+	DietarySchool        string            `json:"dietary_school"`
+	Violations           []Violation       `json:"violations,omitempty"`
+	Suggestions          []Suggestion      `json:"suggestions,omitempty"`
+	Warnings             []Warning         `json:"warnings,omitempty"`
+	NutritionalImpact    map[string]string `json:"nutritional_impact,omitempty"`
+	CulturalNotes        []string          `json:"cultural_notes,omitempty"`
+	ModifiedRecipe       *ModifiedRecipe   `json:"modified_recipe,omitempty"`
+	SubstitutionsApplied bool              `json:"substitutions_applied"`
 }
 
-// Violation represents a halal compliance violation
+// Violation represents a halal compliance violation. Status tells haram
+// ingredients from mashbooh ones that only fail strict checks. Substitution is
+// the replacement ingredient when substitutions were applied.
 type Violation struct {
 	Ingredient   string   `json:"ingredient"`
 	Reason       string   `json:"reason"`
 	Severity     string   `json:"severity"` // "critical", "warning", "info"
+	Status       string   `json:"status"`   // "haram", "mashbooh"
 	Category     string   `json:"category"`
+	Additive     string   `json:"additive,omitempty"`
 	Alternatives []string `json:"alternatives,omitempty"`
+	Substitution string   `json:"substitution,omitempty"`
 }
 
 // Suggestion represents a substitution suggestion
@@ -107,6 +150,9 @@ type Suggestion struct {
 	Reason            string            `json:"reason"`
 	NutritionalImpact map[string]string `json:"nutritional_impact,omitempty"`
 	CookingAdjustment string            `json:"cooking_adjustment,omitempty"`
+
+	// keyword is the blacklisted part of Original that is replaced
+	keyword string
 }
 
 // Warning represents a compliance warning
@@ -114,6 +160,8 @@ type Warning struct {
 	Message    string `json:"message"`
 	Ingredient string `json:"ingredient"`
 	Severity   string `json:"severity"`
+	Status     string `json:"status,omitempty"`
+	Additive   string `json:"additive,omitempty"`
 	Action     string `json:"action"`
 }
 
@@ -126,13 +174,68 @@ type ModifiedRecipe struct {
 	NutritionalChanges  map[string]string `json:"nutritional_changes"`
 }
 
+// Kinds of blacklist keywords, in the order they decide an ingredient that
+// matches several
+const (
+	keywordHaram = iota
+	keywordSchoolRestriction
+	keywordRequiresVerification
+	keywordPermissible
+	keywordCertificationPreferred
+)
+
+// halalKeyword is a blacklist entry reduced to search terms, so matching is
+// word-based, ignores case and Arabic spelling variants and the definite
+// article, and tolerates English inflections. A synonym keeps the kind and
+// suggestions of the entry it names, its canonical keyword.
+type halalKeyword struct {
+	kind      int
+	keyword   string
+	canonical string
+	category  string // blacklist category, or the dietary school of a restriction
+	additive  string // registry code of an additive
+	terms     []string
+}
+
+// halalMatch is a keyword found in a text. The kind of an additive match
+// follows the source stated next to it.
+type halalMatch struct {
+	halalKeyword
+	source     string
+	start, end int
+}
+
+// halalStatusRank orders statuses from the most to the least permissible
+var halalStatusRank = map[string]int{models.HalalStatusHalal: 0, models.HalalStatusMashbooh: 1, models.HalalStatusHaram: 2}
+
+// halalStatusKind is the keyword kind reporting status
+func halalStatusKind(status string) int {
+	switch status {
+	case models.HalalStatusHaram:
+		return keywordHaram
+	case models.HalalStatusMashbooh:
+		return keywordRequiresVerification
+	default:
+		return keywordPermissible
+	}
+}
+
+// halalKindStatus is the status reported for a keyword kind
+func halalKindStatus(kind int) string {
+	switch kind {
+	case keywordHaram, keywordSchoolRestriction:
+		return models.HalalStatusHaram
+	case keywordRequiresVerification:
+		return models.HalalStatusMashbooh
+	default:
+		return models.HalalStatusHalal
+	}
+}
+
 // NewHalalCompliance creates a new halal compliance service
 func NewHalalCompliance(blacklistPath string) (*HalalCompliance, error) {
 	hc := &HalalCompliance{
-		blacklistPath:   blacklistPath,
-		strictnessLevel: "moderate",
-		dietarySchool:   "shafi",
-		multilingual:    true,
+		blacklistPath: blacklistPath,
 	}
 
 	if err := hc.LoadBlacklist(); err != nil {
@@ -144,10 +247,7 @@ func NewHalalCompliance(blacklistPath string) (*HalalCompliance, error) {
 
 // LoadBlacklist loads the blacklist data from JSON file
 func (hc *HalalCompliance) LoadBlacklist() error {
-	hc.mu.Lock()
-	defer hc.mu.Unlock()
-
-	data, err := ioutil.ReadFile(hc.blacklistPath)
+	data, err := os.ReadFile(hc.blacklistPath)
 	if err != nil {
 		return fmt.Errorf("failed to read blacklist file: %w", err)
 	}
@@ -156,15 +256,98 @@ func (hc *HalalCompliance) LoadBlacklist() error {
 	if err := json.Unmarshal(data, &blacklistData); err != nil {
 		return fmt.Errorf("failed to parse blacklist JSON: %w", err)
 	}
+	keywords := buildHalalKeywords(&blacklistData)
+	sourceTerms := buildAdditiveSourceTerms(&blacklistData)
 
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
 	hc.blacklistData = &blacklistData
+	hc.keywords = keywords
+	hc.sourceTerms = sourceTerms
 	hc.lastUpdated = time.Now()
 
 	return nil
 }
 
-// CheckCompliance checks if ingredients/recipe comply with halal requirements
-func (hc *HalalCompliance) CheckCompliance(ingredients []string, recipe string) (*ComplianceResult, error) {
+func buildHalalKeywords(data *BlacklistData) []halalKeyword {
+	var keywords []halalKeyword
+	add := func(template halalKeyword, items []string) {
+		for _, item := range items {
+			if terms := searchTerms(normalizeAdditiveCodes(item)); len(terms) > 0 {
+				keyword := template
+				keyword.keyword, keyword.terms = item, terms
+				if keyword.canonical == "" {
+					keyword.canonical = item
+				}
+				keywords = append(keywords, keyword)
+			}
+		}
+	}
+
+	rules := &data.HalalCompliance
+	categories := make([]string, 0, len(rules.BlacklistedIngredients))
+	for category := range rules.BlacklistedIngredients {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		add(halalKeyword{kind: keywordHaram, category: category}, rules.BlacklistedIngredients[category].Items)
+	}
+	for school, schoolRules := range rules.UserPreferences.DietarySchools {
+		add(halalKeyword{kind: keywordSchoolRestriction, category: school}, schoolRules.AdditionalRestrictions)
+	}
+	add(halalKeyword{kind: keywordRequiresVerification, category: "requires_verification"}, rules.ValidationKeywords.RequiresVerification)
+	add(halalKeyword{kind: keywordPermissible, category: "permissible"}, rules.ValidationKeywords.Permissible)
+	add(halalKeyword{kind: keywordCertificationPreferred, category: "halal_certified_preferred"}, rules.ValidationKeywords.HalalCertifiedPreferred)
+	for _, code := range sortedRegistryKeys(rules.Additives) {
+		add(halalKeyword{kind: halalStatusKind(rules.Additives[code].Status), category: "additives", additive: code}, []string{code})
+	}
+
+	// Synonyms are matched like the entry they name, so "babi" is pork and
+	// "jelatin" is the E441 additive
+	canonical := make(map[string]halalKeyword, len(keywords))
+	for _, keyword := range keywords {
+		if _, ok := canonical[strings.ToLower(keyword.keyword)]; !ok {
+			canonical[strings.ToLower(keyword.keyword)] = keyword
+		}
+	}
+	for _, language := range sortedRegistryKeys(rules.Synonyms) {
+		synonyms := rules.Synonyms[language]
+		for _, name := range sortedRegistryKeys(synonyms) {
+			if template, ok := canonical[strings.ToLower(name)]; ok {
+				add(template, synonyms[name])
+			}
+		}
+	}
+	return keywords
+}
+
+// buildAdditiveSourceTerms reduces the words naming each additive source to
+// search terms
+func buildAdditiveSourceTerms(data *BlacklistData) map[string][][]string {
+	sourceTerms := make(map[string][][]string, len(data.HalalCompliance.AdditiveSources))
+	for source, words := range data.HalalCompliance.AdditiveSources {
+		for _, word := range words {
+			if terms := searchTerms(word); len(terms) > 0 {
+				sourceTerms[source] = append(sourceTerms[source], terms)
+			}
+		}
+	}
+	return sourceTerms
+}
+
+// normalizeAdditiveCodes rewrites the additive codes in text to single
+// tokens, so "E 471", "e-471" and "INS 471" all read "e471"
+func normalizeAdditiveCodes(text string) string {
+	return additiveCodePattern.ReplaceAllStringFunc(text, func(code string) string {
+		return "e" + strings.ToLower(additiveCodePattern.FindStringSubmatch(code)[1])
+	})
+}
+
+// CheckCompliance checks ingredients and optional recipe instructions against
+// the blacklist with prefs' strictness level and dietary school. Missing
+// preferences fall back to the defaults.
+func (hc *HalalCompliance) CheckCompliance(ingredients []string, recipe string, prefs models.HalalPreferences) (*ComplianceResult, error) {
 	hc.mu.RLock()
 	defer hc.mu.RUnlock()
 
@@ -172,18 +355,22 @@ func (hc *HalalCompliance) CheckCompliance(ingredients []string, recipe string)
 		return nil, fmt.Errorf("blacklist data not loaded")
 	}
 
+	prefs = normalizeHalalPreferences(prefs)
 	result := &ComplianceResult{
 		IsCompliant:       true,
+		StrictnessLevel:   prefs.StrictnessLevel,
+		DietarySchool:     prefs.DietarySchool,
 		Violations:        []Violation{},
 		Suggestions:       []Suggestion{},
 		Warnings:          []Warning{},
 		NutritionalImpact: make(map[string]string),
 		CulturalNotes:     []string{},
 	}
+	strictness := hc.strictnessRules(prefs.StrictnessLevel)
 
 	// Check ingredients
 	for _, ingredient := range ingredients {
-		hc.checkIngredient(ingredient, result)
+		hc.checkIngredient(ingredient, prefs, strictness, result)
 	}
 
 	// Check recipe text if provided
@@ -194,122 +381,282 @@ func (hc *HalalCompliance) CheckCompliance(ingredients []string, recipe string)
 	// Generate modified recipe if violations found
 	if len(result.Violations) > 0 {
 		result.ModifiedRecipe = hc.generateModifiedRecipe(ingredients, result.Suggestions)
+		if prefs.AutoSubstitute {
+			applySubstitutions(result)
+		}
 	}
 
 	// Add cultural considerations
-	hc.addCulturalNotes(result)
+	hc.addCulturalNotes(prefs.DietarySchool, result)
 
 	return result, nil
 }
 
-// checkIngredient checks a single ingredient for compliance
-func (hc *HalalCompliance) checkIngredient(ingredient string, result *ComplianceResult) {
-	ingredientLower := strings.ToLower(strings.TrimSpace(ingredient))
+func normalizeHalalPreferences(prefs models.HalalPreferences) models.HalalPreferences {
+	if prefs.StrictnessLevel == "" {
+		prefs.StrictnessLevel = models.DefaultHalalStrictness
+	}
+	if prefs.DietarySchool == "" {
+		prefs.DietarySchool = models.DefaultHalalDietarySchool
+	}
+	return prefs
+}
 
-	// Check against blacklisted ingredients
-	for category, categoryData := range hc.blacklistData.HalalCompliance.BlacklistedIngredients {
-		for _, blacklistedItem := range categoryData.Items {
-			if hc.matchesIngredient(ingredientLower, blacklistedItem) {
-				violation := Violation{
-					Ingredient: ingredient,
-					Reason:     fmt.Sprintf("Contains %s which is not halal", blacklistedItem),
-					Severity:   hc.getSeverity(blacklistedItem),
-					Category:   category,
-				}
+// strictnessRules returns the rules of level, or of the default level when the
+// blacklist does not define it
+func (hc *HalalCompliance) strictnessRules(level string) StrictnessRules {
+	levels := hc.blacklistData.HalalCompliance.UserPreferences.StrictnessLevels
+	if rules, ok := levels[level]; ok {
+		return rules
+	}
+	return levels[models.DefaultHalalStrictness]
+}
 
-				// Add alternatives if available
-				if alternatives, exists := categoryData.AutoSuggestions[blacklistedItem]; exists {
-					violation.Alternatives = alternatives
+// checkIngredient checks a single ingredient for compliance. An ingredient
+// yields at most one violation, decided by its most serious match.
+func (hc *HalalCompliance) checkIngredient(ingredient string, prefs models.HalalPreferences, strictness StrictnessRules, result *ComplianceResult) {
+	matches := hc.matchKeywords(ingredient, prefs.DietarySchool)
+	if len(matches) == 0 {
+		return
+	}
+	match := matches[0]
 
-					// Create suggestion
-					suggestion := Suggestion{
-						Original:    ingredient,
-						Recommended: alternatives,
-						Reason:      fmt.Sprintf("Halal alternative for %s", blacklistedItem),
-					}
+	switch match.kind {
+	case keywordHaram:
+		severity := "critical"
+		if match.additive == "" {
+			severity = hc.getSeverity(match.canonical)
+		}
+		hc.addViolation(result, Violation{
+			Ingredient: ingredient,
+			Reason:     fmt.Sprintf("Contains %s which is not halal", hc.describeMatch(match)),
+			Severity:   severity,
+			Category:   match.category,
+		}, match)
+
+	case keywordSchoolRestriction:
+		hc.addViolation(result, Violation{
+			Ingredient: ingredient,
+			Reason:     fmt.Sprintf("Contains %s which the %s school avoids", hc.describeMatch(match), match.category),
+			Severity:   "warning",
+			Category:   "dietary_school",
+		}, match)
+
+	case keywordRequiresVerification:
+		if strictness.RequireHalalCertification {
+			hc.addViolation(result, Violation{
+				Ingredient: ingredient,
+				Reason:     fmt.Sprintf("Contains %s which may come from a haram source", hc.describeMatch(match)),
+				Severity:   "warning",
+				Category:   match.category,
+			}, match)
+		} else if strictness.ShowWarnings {
+			result.Warnings = append(result.Warnings, Warning{
+				Message:    fmt.Sprintf("%s requires halal certification verification", ingredient),
+				Ingredient: ingredient,
+				Severity:   "warning",
+				Status:     models.HalalStatusMashbooh,
+				Additive:   match.additive,
+				Action:     "verify_halal_certification",
+			})
+		}
 
-					// Add nutritional impact if available
-					if impact := hc.getNutritionalImpact(blacklistedItem, alternatives[0]); impact != nil {
-						suggestion.NutritionalImpact = impact
-					}
+	case keywordCertificationPreferred:
+		if strictness.RequireHalalCertification {
+			result.Warnings = append(result.Warnings, Warning{
+				Message:    fmt.Sprintf("Use halal-certified %s", match.keyword),
+				Ingredient: ingredient,
+				Severity:   "info",
+				Action:     "use_halal_certified",
+			})
+		}
+	}
+}
 
-					// Add cooking adjustment if available
-					if adjustment := hc.getCookingAdjustment(blacklistedItem, alternatives[0]); adjustment != "" {
-						suggestion.CookingAdjustment = adjustment
-					}
+// describeMatch names a match in reasons: a synonym with the entry it names,
+// e.g. "babi (pork)", an additive code with the additive's name, and the
+// source when one was stated
+func (hc *HalalCompliance) describeMatch(match halalMatch) string {
+	description := match.keyword
+	switch {
+	case match.additive != "" && strings.EqualFold(match.keyword, match.additive):
+		description = fmt.Sprintf("%s (%s)", match.additive, hc.blacklistData.HalalCompliance.Additives[match.additive].Name)
+	case !strings.EqualFold(match.keyword, match.canonical):
+		description = fmt.Sprintf("%s (%s)", match.keyword, match.canonical)
+	}
+	if match.source != "" {
+		description += fmt.Sprintf(" of %s origin", match.source)
+	}
+	return description
+}
 
-					result.Suggestions = append(result.Suggestions, suggestion)
-				}
+// alternatives returns the halal substitutions known for a match
+func (hc *HalalCompliance) alternatives(match halalMatch) []string {
+	rules := &hc.blacklistData.HalalCompliance
+	if match.additive != "" {
+		return rules.Additives[match.additive].Alternatives
+	}
+	switch match.kind {
+	case keywordHaram:
+		return rules.BlacklistedIngredients[match.category].AutoSuggestions[match.canonical]
+	case keywordSchoolRestriction:
+		return rules.UserPreferences.DietarySchools[match.category].Suggestions[match.canonical]
+	case keywordRequiresVerification:
+		return rules.ValidationKeywords.VerificationSuggestions[match.canonical]
+	}
+	return nil
+}
 
-				result.Violations = append(result.Violations, violation)
-				result.IsCompliant = false
-				return
-			}
+// addViolation records violation with a substitution suggestion when
+// alternatives are known
+func (hc *HalalCompliance) addViolation(result *ComplianceResult, violation Violation, match halalMatch) {
+	violation.Status = halalKindStatus(match.kind)
+	violation.Additive = match.additive
+
+	if alternatives := hc.alternatives(match); len(alternatives) > 0 {
+		violation.Alternatives = alternatives
+
+		suggestion := Suggestion{
+			Original:    violation.Ingredient,
+			Recommended: alternatives,
+			Reason:      fmt.Sprintf("Halal alternative for %s", match.keyword),
+			keyword:     match.keyword,
 		}
-	}
 
-	// Check for ingredients that require verification
-	for _, keyword := range hc.blacklistData.HalalCompliance.ValidationKeywords.RequiresVerification {
-		if hc.matchesIngredient(ingredientLower, keyword) {
-			warning := Warning{
-				Message:    fmt.Sprintf("%s requires halal certification verification", ingredient),
-				Ingredient: ingredient,
-				Severity:   "warning",
-				Action:     "verify_halal_certification",
+		// Add nutritional impact if available
+		if impact := hc.getNutritionalImpact(match.canonical, alternatives[0]); impact != nil {
+			suggestion.NutritionalImpact = impact
+			for key, value := range impact {
+				result.NutritionalImpact[key] = value
 			}
-			result.Warnings = append(result.Warnings, warning)
 		}
+
+		// Add cooking adjustment if available
+		if adjustment := hc.getCookingAdjustment(match.canonical, alternatives[0]); adjustment != "" {
+			suggestion.CookingAdjustment = adjustment
+		}
+
+		result.Suggestions = append(result.Suggestions, suggestion)
 	}
+
+	result.Violations = append(result.Violations, violation)
+	result.IsCompliant = false
 }
 
-// checkRecipeText checks recipe instructions for non-halal content
+// checkRecipeText checks recipe instructions for haram ingredients such as
+// wine used for deglazing
 func (hc *HalalCompliance) checkRecipeText(recipe string, result *ComplianceResult) {
-	recipeLower := strings.ToLower(recipe)
-
-	// Check for alcohol in cooking instructions
-	for category, categoryData := range hc.blacklistData.HalalCompliance.BlacklistedIngredients {
-		if category == "alcohol_products" {
-			for _, item := range categoryData.Items {
-				if strings.Contains(recipeLower, item) {
-					violation := Violation{
-						Ingredient: item,
-						Reason:     fmt.Sprintf("Recipe contains %s in instructions", item),
-						Severity:   "critical",
-						Category:   category,
-					}
-
-					if alternatives, exists := categoryData.AutoSuggestions[item]; exists {
-						violation.Alternatives = alternatives
-					}
-
-					result.Violations = append(result.Violations, violation)
-					result.IsCompliant = false
-				}
+	seen := map[string]bool{}
+	for _, match := range hc.matchKeywords(recipe, "") {
+		if match.kind != keywordHaram || seen[match.keyword] {
+			continue
+		}
+		seen[match.keyword] = true
+
+		result.Violations = append(result.Violations, Violation{
+			Ingredient:   match.keyword,
+			Reason:       fmt.Sprintf("Recipe contains %s in instructions", hc.describeMatch(match)),
+			Severity:     "critical",
+			Status:       models.HalalStatusHaram,
+			Category:     match.category,
+			Additive:     match.additive,
+			Alternatives: hc.alternatives(match),
+		})
+		result.IsCompliant = false
+	}
+}
+
+// matchKeywords returns the keywords found in text, most serious first.
+// Matches inside a longer one are dropped, so "wine vinegar" is only doubtful
+// and "turkey bacon" is permissible. School restrictions only apply to school.
+func (hc *HalalCompliance) matchKeywords(text, school string) []halalMatch {
+	terms := searchTerms(normalizeAdditiveCodes(text))
+
+	var found []halalMatch
+	for i := range hc.keywords {
+		keyword := &hc.keywords[i]
+		if keyword.kind == keywordSchoolRestriction && keyword.category != school {
+			continue
+		}
+		for start := 0; start+len(keyword.terms) <= len(terms); start++ {
+			if termsEqual(terms[start:start+len(keyword.terms)], keyword.terms) {
+				found = append(found, halalMatch{halalKeyword: *keyword, start: start, end: start + len(keyword.terms)})
 			}
 		}
 	}
+
+	matches := []halalMatch{}
+	for _, match := range found {
+		covered := false
+		for _, other := range found {
+			if other.end-other.start > match.end-match.start && other.start <= match.start && match.end <= other.end {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			if match.additive != "" {
+				hc.resolveAdditiveSource(&match, terms)
+			}
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].kind != matches[j].kind {
+			return matches[i].kind < matches[j].kind
+		}
+		return matches[i].start < matches[j].start
+	})
+	return matches
 }
 
-// matchesIngredient checks if an ingredient matches a blacklisted item
-func (hc *HalalCompliance) matchesIngredient(ingredient, blacklistedItem string) bool {
-	blacklistedLower := strings.ToLower(blacklistedItem)
+// resolveAdditiveSource sets the kind of an additive match from the sources
+// named in terms, so "E471 (vegetable)" is halal and "gelatin (pork)" haram.
+// When several sources are named the least permissible one decides.
+func (hc *HalalCompliance) resolveAdditiveSource(match *halalMatch, terms []string) {
+	additive := hc.blacklistData.HalalCompliance.Additives[match.additive]
+	status := additive.Status
+	for _, source := range sortedRegistryKeys(additive.Sources) {
+		if !containsAnyTerms(terms, hc.sourceTerms[source]) {
+			continue
+		}
+		if match.source == "" || halalStatusRank[additive.Sources[source]] > halalStatusRank[status] {
+			status, match.source = additive.Sources[source], source
+		}
+	}
+	match.kind = halalStatusKind(status)
+}
 
-	// Exact match
-	if ingredient == blacklistedLower {
-		return true
+// containsAnyTerms reports whether terms contain one of phrases
+func containsAnyTerms(terms []string, phrases [][]string) bool {
+	for _, phrase := range phrases {
+		for start := 0; start+len(phrase) <= len(terms); start++ {
+			if termsEqual(terms[start:start+len(phrase)], phrase) {
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	// Partial match (if enabled)
-	if hc.blacklistData.HalalCompliance.AutoDetectionPatterns.IngredientScanning.PartialMatching {
-		if strings.Contains(ingredient, blacklistedLower) {
-			return true
+func termsEqual(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
+	return true
+}
 
-	// Word boundary match
-	wordPattern := fmt.Sprintf(`\b%s\b`, regexp.QuoteMeta(blacklistedLower))
-	matched, _ := regexp.MatchString(wordPattern, ingredient)
-	return matched
+// sortedRegistryKeys returns the keys of m in order so registry walks are
+// deterministic
+func sortedRegistryKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // getSeverity determines the severity of a violation
@@ -331,10 +678,9 @@ func (hc *HalalCompliance) getSeverity(ingredient string) string {
 
 // getNutritionalImpact gets nutritional impact of substitution
 func (hc *HalalCompliance) getNutritionalImpact(original, substitute string) map[string]string {
-	for substitutionKey, impact := range hc.blacklistData.HalalCompliance.NutritionalAdjustments {
-		if strings.Contains(strings.ToLower(substitutionKey), strings.ToLower(original)) {
-			return impact
-		}
+	key := strings.ToLower(original) + "_to_" + strings.ReplaceAll(strings.ToLower(substitute), " ", "_")
+	if impact, ok := hc.blacklistData.HalalCompliance.NutritionalAdjustments[key]; ok {
+		return impact
 	}
 	return nil
 }
@@ -342,9 +688,9 @@ func (hc *HalalCompliance) getNutritionalImpact(original, substitute string) map
 // getCookingAdjustment gets cooking adjustment for substitution
 func (hc *HalalCompliance) getCookingAdjustment(original, substitute string) string {
 	for protein, substitutes := range hc.blacklistData.HalalCompliance.SubstitutionRules.ProteinEquivalents {
-		if strings.Contains(strings.ToLower(original), strings.ToLower(protein)) {
+		if strings.EqualFold(original, protein) {
 			for sub, details := range substitutes {
-				if strings.Contains(strings.ToLower(substitute), strings.ToLower(sub)) {
+				if strings.EqualFold(substitute, sub) {
 					return details.CookingAdjustment
 				}
 			}
@@ -353,7 +699,10 @@ func (hc *HalalCompliance) getCookingAdjustment(original, substitute string) str
 	return ""
 }
 
-// generateModifiedRecipe creates a modified recipe with halal substitutions
+// generateModifiedRecipe creates a modified recipe with halal substitutions.
+// The blacklisted part of an ingredient is replaced by the first recommended
+// alternative so quantities are kept, e.g. "200g bacon" becomes
+// "200g turkey bacon".
 func (hc *HalalCompliance) generateModifiedRecipe(originalIngredients []string, suggestions []Suggestion) *ModifiedRecipe {
 	modified := &ModifiedRecipe{
 		OriginalIngredients: originalIngredients,
@@ -372,8 +721,8 @@ func (hc *HalalCompliance) generateModifiedRecipe(originalIngredients []string,
 			// Update ingredients list
 			for i, ingredient := range modified.ModifiedIngredients {
 				if strings.EqualFold(ingredient, suggestion.Original) {
-					modified.ModifiedIngredients[i] = recommended
-					modified.Substitutions[suggestion.Original] = recommended
+					modified.ModifiedIngredients[i] = replaceFold(ingredient, suggestion.keyword, recommended)
+					modified.Substitutions[suggestion.Original] = modified.ModifiedIngredients[i]
 					break
 				}
 			}
@@ -393,32 +742,47 @@ func (hc *HalalCompliance) generateModifiedRecipe(originalIngredients []string,
 	return modified
 }
 
+// replaceFold replaces the first case-insensitive occurrence of old in s, or
+// all of s when old does not occur verbatim
+func replaceFold(s, old, replacement string) string {
+	lower := strings.ToLower(s)
+	i := strings.Index(lower, strings.ToLower(old))
+	if old == "" || i < 0 || len(lower) != len(s) {
+		return replacement
+	}
+	return s[:i] + replacement + s[i+len(old):]
+}
+
+// applySubstitutions marks the violations whose ingredient was substituted
+// in the modified recipe
+func applySubstitutions(result *ComplianceResult) {
+	compliant := true
+	for i := range result.Violations {
+		substitution, ok := result.ModifiedRecipe.Substitutions[result.Violations[i].Ingredient]
+		if !ok {
+			compliant = false
+			continue
+		}
+		result.Violations[i].Substitution = substitution
+		result.SubstitutionsApplied = true
+	}
+	if result.SubstitutionsApplied {
+		result.IsCompliant = compliant
+	}
+}
+
 // addCulturalNotes adds cultural considerations to the result
-func (hc *HalalCompliance) addCulturalNotes(result *ComplianceResult) {
+func (hc *HalalCompliance) addCulturalNotes(dietarySchool string, result *ComplianceResult) {
 	// Add notes based on dietary school
-	if school, exists := hc.blacklistData.HalalCompliance.UserPreferences.DietarySchools[hc.dietarySchool]; exists {
+	if school, exists := hc.blacklistData.HalalCompliance.UserPreferences.DietarySchools[dietarySchool]; exists {
 		if len(school.AdditionalRestrictions) > 0 {
 			note := fmt.Sprintf("According to %s school, also avoid: %s",
-				hc.dietarySchool, strings.Join(school.AdditionalRestrictions, ", "))
+				dietarySchool, strings.Join(school.AdditionalRestrictions, ", "))
 			result.CulturalNotes = append(result.CulturalNotes, note)
 		}
 	}
 }
 
-// SetStrictnessLevel sets the compliance strictness level
-func (hc *HalalCompliance) SetStrictnessLevel(level string) {
-	hc.mu.Lock()
-	defer hc.mu.Unlock()
-	hc.strictnessLevel = level
-}
-
-// SetDietarySchool sets the Islamic dietary school
-func (hc *HalalCompliance) SetDietarySchool(school string) {
-	hc.mu.Lock()
-	defer hc.mu.Unlock()
-	hc.dietarySchool = school
-}
-
 // GetSuggestions gets substitution suggestions for a specific ingredient
 func (hc *HalalCompliance) GetSuggestions(ingredient string) ([]string, error) {
 	hc.mu.RLock()
@@ -428,17 +792,44 @@ func (hc *HalalCompliance) GetSuggestions(ingredient string) ([]string, error) {
 		return nil, fmt.Errorf("blacklist data not loaded")
 	}
 
-	ingredientLower := strings.ToLower(strings.TrimSpace(ingredient))
+	for _, match := range hc.matchKeywords(ingredient, "") {
+		if match.kind == keywordPermissible {
+			continue
+		}
+		if suggestions := hc.alternatives(match); len(suggestions) > 0 {
+			return suggestions, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w for ingredient: %s", ErrNoHalalSuggestions, ingredient)
+}
+
+// LookupAdditive returns the registry entry of an additive code written in
+// any of the forms CheckCompliance accepts, e.g. "e-471" or "INS 471"
+func (hc *HalalCompliance) LookupAdditive(code string) (*AdditiveInfo, error) {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	if hc.blacklistData == nil {
+		return nil, fmt.Errorf("blacklist data not loaded")
+	}
 
-	for _, categoryData := range hc.blacklistData.HalalCompliance.BlacklistedIngredients {
-		for blacklistedItem, suggestions := range categoryData.AutoSuggestions {
-			if hc.matchesIngredient(ingredientLower, blacklistedItem) {
-				return suggestions, nil
+	rules := &hc.blacklistData.HalalCompliance
+	normalized := normalizeAdditiveCodes(strings.TrimSpace(code))
+	for _, registered := range sortedRegistryKeys(rules.Additives) {
+		if !strings.EqualFold(normalizeAdditiveCodes(registered), normalized) {
+			continue
+		}
+		info := &AdditiveInfo{Code: registered, AdditiveRules: rules.Additives[registered], Synonyms: map[string][]string{}}
+		for language, synonyms := range rules.Synonyms {
+			if names := synonyms[registered]; len(names) > 0 {
+				info.Synonyms[language] = names
 			}
 		}
+		return info, nil
 	}
 
-	return nil, fmt.Errorf("no suggestions found for ingredient: %s", ingredient)
+	return nil, fmt.Errorf("%w: %s", ErrUnknownAdditive, code)
 }
 
 // ReloadBlacklist reloads the blacklist data
@@ -457,78 +848,3 @@ func (hc *HalalCompliance) GetBlacklistVersion() string {
 
 	return hc.blacklistData.HalalCompliance.Version
 }
-
-// RegisterRoutes registers halal compliance API routes
-func (hc *HalalCompliance) RegisterRoutes(e *echo.Group) {
-	e.POST("/halal/check", hc.handleCheckCompliance)
-	e.GET("/halal/suggestions/:ingredient", hc.handleGetSuggestions)
-	e.POST("/halal/reload", hc.handleReloadBlacklist)
-	e.GET("/halal/version", hc.handleGetVersion)
-	e.PUT("/halal/settings", hc.handleUpdateSettings)
-}
-
-// API Handlers
-
-type CheckComplianceRequest struct {
-	Ingredients []string `json:"ingredients"`
-	Recipe      string   `json:"recipe,omitempty"`
-}
-
-type UpdateSettingsRequest struct {
-	StrictnessLevel string `json:"strictness_level,omitempty"`
-	DietarySchool   string `json:"dietary_school,omitempty"`
-}
-
-func (hc *HalalCompliance) handleCheckCompliance(c echo.Context) error {
-	var req CheckComplianceRequest
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, map[string]string{"error": "Invalid request format"})
-	}
-
-	result, err := hc.CheckCompliance(req.Ingredients, req.Recipe)
-	if err != nil {
-		return c.JSON(500, map[string]string{"error": err.Error()})
-	}
-
-	return c.JSON(200, result)
-}
-
-func (hc *HalalCompliance) handleGetSuggestions(c echo.Context) error {
-	ingredient := c.Param("ingredient")
-	suggestions, err := hc.GetSuggestions(ingredient)
-	if err != nil {
-		return c.JSON(404, map[string]string{"error": err.Error()})
-	}
-
-	return c.JSON(200, map[string][]string{"suggestions": suggestions})
-}
-
-func (hc *HalalCompliance) handleReloadBlacklist(c echo.Context) error {
-	if err := hc.ReloadBlacklist(); err != nil {
-		return c.JSON(500, map[string]string{"error": err.Error()})
-	}
-
-	return c.JSON(200, map[string]string{"message": "Blacklist reloaded successfully"})
-}
-
-func (hc *HalalCompliance) handleGetVersion(c echo.Context) error {
-	version := hc.GetBlacklistVersion()
-	return c.JSON(200, map[string]string{"version": version})
-}
-
-func (hc *HalalCompliance) handleUpdateSettings(c echo.Context) error {
-	var req UpdateSettingsRequest
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(400, map[string]string{"error": "Invalid request format"})
-	}
-
-	if req.StrictnessLevel != "" {
-		hc.SetStrictnessLevel(req.StrictnessLevel)
-	}
-
-	if req.DietarySchool != "" {
-		hc.SetDietarySchool(req.DietarySchool)
-	}
-
-	return c.JSON(200, map[string]string{"message": "Settings updated successfully"})
-}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHalalCompliance(t *testing.T) *HalalCompliance {
+	t.Helper()
+	compliance, err := NewHalalCompliance("../" + DefaultHalalBlacklistPath)
+	require.NoError(t, err)
+	return compliance
+}
+
+func newTestHalalService(t *testing.T) (*HalalService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "halal@example.com", Password: "password123"})
+	require.NoError(t, err)
+	return NewHalalService(users.db, newTestHalalCompliance(t)), user.ID
+}
+
+func halalPrefs(level, school string, autoSubstitute bool) models.HalalPreferences {
+	return models.HalalPreferences{StrictnessLevel: level, DietarySchool: school, AutoSubstitute: autoSubstitute}
+}
+
+func TestHalalCompliance_CheckCompliance(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+
+	result, err := hc.CheckCompliance([]string{"200g bacon", "2 eggs"}, "", halalPrefs(models.HalalModerate, "shafi", false))
+	require.NoError(t, err)
+	assert.False(t, result.IsCompliant)
+	require.Len(t, result.Violations, 1)
+	assert.Equal(t, "200g bacon", result.Violations[0].Ingredient)
+	assert.Equal(t, "critical", result.Violations[0].Severity)
+	assert.Empty(t, result.Violations[0].Substitution)
+	assert.False(t, result.SubstitutionsApplied)
+	require.NotNil(t, result.ModifiedRecipe)
+	assert.Equal(t, []string{"200g turkey bacon", "2 eggs"}, result.ModifiedRecipe.ModifiedIngredients)
+
+	// A halal product containing a blacklisted word is not a violation
+	result, err = hc.CheckCompliance([]string{"turkey bacon", "fish gelatin"}, "", halalPrefs(models.HalalStrict, "shafi", false))
+	require.NoError(t, err)
+	assert.True(t, result.IsCompliant)
+	assert.Empty(t, result.Violations)
+
+	// Arabic ingredients match with or without the definite article
+	result, err = hc.CheckCompliance([]string{"الخنزير المقدد"}, "", halalPrefs(models.HalalModerate, "shafi", false))
+	require.NoError(t, err)
+	assert.False(t, result.IsCompliant)
+	assert.Len(t, result.Violations, 1)
+
+	// Haram keywords in the instructions are reported too
+	result, err = hc.CheckCompliance([]string{"chicken breast"}, "Deglaze the pan with white wine.", halalPrefs(models.HalalModerate, "shafi", false))
+	require.NoError(t, err)
+	assert.False(t, result.IsCompliant)
+}
+
+func TestHalalCompliance_Strictness(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+	ingredients := []string{"gelatin", "sugar"}
+
+	strict, err := hc.CheckCompliance(ingredients, "", halalPrefs(models.HalalStrict, "shafi", false))
+	require.NoError(t, err)
+	assert.False(t, strict.IsCompliant)
+	assert.Len(t, strict.Violations, 1)
+	assert.Equal(t, models.HalalStrict, strict.StrictnessLevel)
+
+	moderate, err := hc.CheckCompliance(ingredients, "", halalPrefs(models.HalalModerate, "shafi", false))
+	require.NoError(t, err)
+	assert.True(t, moderate.IsCompliant)
+	assert.Empty(t, moderate.Violations)
+	assert.NotEmpty(t, moderate.Warnings)
+
+	lenient, err := hc.CheckCompliance(ingredients, "", halalPrefs(models.HalalLenient, "shafi", false))
+	require.NoError(t, err)
+	assert.True(t, lenient.IsCompliant)
+	assert.Empty(t, lenient.Warnings)
+
+	// Unknown preferences fall back to the defaults
+	fallback, err := hc.CheckCompliance(ingredients, "", models.HalalPreferences{})
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultHalalStrictness, fallback.StrictnessLevel)
+	assert.Equal(t, models.DefaultHalalDietarySchool, fallback.DietarySchool)
+}
+
+func TestHalalCompliance_DietarySchool(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+	ingredients := []string{"shrimp", "rice"}
+
+	shafi, err := hc.CheckCompliance(ingredients, "", halalPrefs(models.HalalModerate, "shafi", false))
+	require.NoError(t, err)
+	assert.True(t, shafi.IsCompliant)
+
+	hanafi, err := hc.CheckCompliance(ingredients, "", halalPrefs(models.HalalModerate, "hanafi", true))
+	require.NoError(t, err)
+	assert.True(t, hanafi.SubstitutionsApplied)
+	assert.True(t, hanafi.IsCompliant)
+	require.Len(t, hanafi.Violations, 1)
+	assert.Equal(t, "white fish", hanafi.Violations[0].Substitution)
+}
+
+func TestHalalCompliance_AutoSubstitute(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+
+	result, err := hc.CheckCompliance([]string{"200g bacon", "1 tbsp lard"}, "", halalPrefs(models.HalalModerate, "shafi", true))
+	require.NoError(t, err)
+	assert.True(t, result.SubstitutionsApplied)
+	assert.True(t, result.IsCompliant)
+	require.Len(t, result.Violations, 2)
+	assert.Equal(t, "200g turkey bacon", result.Violations[0].Substitution)
+	assert.Equal(t, "1 tbsp ghee", result.Violations[1].Substitution)
+	assert.Equal(t, []string{"200g turkey bacon", "1 tbsp ghee"}, result.ModifiedRecipe.ModifiedIngredients)
+}
+
+func TestHalalCompliance_GetSuggestions(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+
+	suggestions, err := hc.GetSuggestions("Bacon")
+	require.NoError(t, err)
+	assert.Contains(t, suggestions, "turkey bacon")
+
+	_, err = hc.GetSuggestions("broccoli")
+	assert.ErrorIs(t, err, ErrNoHalalSuggestions)
+}
+
+func TestHalalService_Preferences(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestHalalService(t)
+
+	prefs, err := svc.Preferences(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultHalalStrictness, prefs.StrictnessLevel)
+	assert.Equal(t, models.DefaultHalalDietarySchool, prefs.DietarySchool)
+	assert.False(t, prefs.AutoSubstitute)
+
+	strict, hanafi, auto := models.HalalStrict, "hanafi", true
+	_, err = svc.UpdatePreferences(ctx, userID, models.UpdateHalalPreferencesRequest{StrictnessLevel: &strict, AutoSubstitute: &auto})
+	require.NoError(t, err)
+	_, err = svc.UpdatePreferences(ctx, userID, models.UpdateHalalPreferencesRequest{DietarySchool: &hanafi})
+	require.NoError(t, err)
+
+	prefs, err = svc.Preferences(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.HalalStrict, prefs.StrictnessLevel)
+	assert.Equal(t, "hanafi", prefs.DietarySchool)
+	assert.True(t, prefs.AutoSubstitute)
+
+	unknown := "Strict"
+	_, err = svc.UpdatePreferences(ctx, userID, models.UpdateHalalPreferencesRequest{StrictnessLevel: &unknown})
+	assert.ErrorIs(t, err, ErrInvalidHalalPreferences)
+}
+
+func TestHalalService_CheckMeal(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestHalalService(t)
+
+	meal := &Meal{UserID: userID, Ingredients: []string{"200g bacon", "2 eggs"}}
+	_, err := svc.CheckMeal(ctx, meal)
+	require.NoError(t, err)
+	assert.False(t, meal.IsHalal)
+	assert.Equal(t, []string{"200g bacon", "2 eggs"}, meal.Ingredients)
+
+	auto := true
+	_, err = svc.UpdatePreferences(ctx, userID, models.UpdateHalalPreferencesRequest{AutoSubstitute: &auto})
+	require.NoError(t, err)
+
+	_, err = svc.CheckMeal(ctx, meal)
+	require.NoError(t, err)
+	assert.True(t, meal.IsHalal)
+	assert.Equal(t, []string{"200g turkey bacon", "2 eggs"}, meal.Ingredients)
+}
+
+func TestHalalCompliance_AdditiveCodes(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+	prefs := halalPrefs(models.HalalStrict, "shafi", false)
+
+	for _, ingredient := range []string{"E471", "emulsifier (E 471)", "e-471", "INS 471", "mono- and diglycerides"} {
+		result, err := hc.CheckCompliance([]string{ingredient}, "", prefs)
+		require.NoError(t, err)
+		require.Len(t, result.Violations, 1, ingredient)
+		assert.Equal(t, "E471", result.Violations[0].Additive, ingredient)
+		assert.Equal(t, models.HalalStatusMashbooh, result.Violations[0].Status, ingredient)
+	}
+
+	result, err := hc.CheckCompliance([]string{"colour (E120)"}, "", halalPrefs(models.HalalLenient, "shafi", false))
+	require.NoError(t, err)
+	require.Len(t, result.Violations, 1)
+	assert.Equal(t, models.HalalStatusHaram, result.Violations[0].Status)
+	assert.Equal(t, "critical", result.Violations[0].Severity)
+	assert.Equal(t, "E120", result.Violations[0].Additive)
+
+	// Moderate checks only warn about mashbooh additives
+	result, err = hc.CheckCompliance([]string{"E471"}, "", halalPrefs(models.HalalModerate, "shafi", false))
+	require.NoError(t, err)
+	assert.True(t, result.IsCompliant)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, models.HalalStatusMashbooh, result.Warnings[0].Status)
+	assert.Equal(t, "E471", result.Warnings[0].Additive)
+}
+
+func TestHalalCompliance_AdditiveSources(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+	prefs := halalPrefs(models.HalalStrict, "shafi", false)
+
+	result, err := hc.CheckCompliance([]string{"E471 (vegetable origin)", "gelatin (fish)"}, "", prefs)
+	require.NoError(t, err)
+	assert.True(t, result.IsCompliant)
+	assert.Empty(t, result.Violations)
+
+	result, err = hc.CheckCompliance([]string{"E441 from porcine skin"}, "", prefs)
+	require.NoError(t, err)
+	require.Len(t, result.Violations, 1)
+	assert.Equal(t, models.HalalStatusHaram, result.Violations[0].Status)
+	assert.Contains(t, result.Violations[0].Reason, "pork origin")
+
+	// The least permissible of several stated sources decides
+	result, err = hc.CheckCompliance([]string{"E471 (plant or animal)"}, "", prefs)
+	require.NoError(t, err)
+	require.Len(t, result.Violations, 1)
+	assert.Equal(t, models.HalalStatusMashbooh, result.Violations[0].Status)
+}
+
+func TestHalalCompliance_Synonyms(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+	prefs := halalPrefs(models.HalalStrict, "shafi", true)
+
+	result, err := hc.CheckCompliance([]string{"daging babi", "domuz yağı", "jelatin", "جيلاتين", "şarap"}, "", prefs)
+	require.NoError(t, err)
+	require.Len(t, result.Violations, 5)
+
+	assert.Equal(t, models.HalalStatusHaram, result.Violations[0].Status)
+	assert.Contains(t, result.Violations[0].Reason, "babi (pork)")
+	assert.Equal(t, "beef", result.Violations[0].Substitution)
+	assert.Equal(t, "ghee", result.Violations[1].Substitution)
+	for _, violation := range result.Violations[2:4] {
+		assert.Equal(t, models.HalalStatusMashbooh, violation.Status)
+		assert.Equal(t, "E441", violation.Additive)
+	}
+	assert.Equal(t, models.HalalStatusHaram, result.Violations[4].Status)
+}
+
+func TestHalalCompliance_LookupAdditive(t *testing.T) {
+	hc := newTestHalalCompliance(t)
+
+	for _, code := range []string{"E441", "e-441", "E 441", "INS 441"} {
+		additive, err := hc.LookupAdditive(code)
+		require.NoError(t, err, code)
+		assert.Equal(t, "E441", additive.Code)
+		assert.Equal(t, models.HalalStatusMashbooh, additive.Status)
+		assert.Equal(t, models.HalalStatusHaram, additive.Sources["pork"])
+		assert.Contains(t, additive.Synonyms["tr"], "jelatin")
+	}
+
+	suggestions, err := hc.GetSuggestions("E120")
+	require.NoError(t, err)
+	assert.Contains(t, suggestions, "beetroot juice")
+
+	_, err = hc.LookupAdditive("E999")
+	assert.ErrorIs(t, err, ErrUnknownAdditive)
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Hydration errors returned by HydrationService
+var (
+	ErrWaterIntakeNotFound = errors.New("water intake entry not found")
+	ErrInvalidWaterIntake  = errors.New("invalid water intake entry")
+	ErrUnknownClimate      = errors.New("unknown climate")
+)
+
+const (
+	// Computed targets: a per-kg base, an allowance per minute of logged
+	// exercise (about 600 ml an hour) and a climate allowance, rounded to 50 ml
+	waterMlPerKg            = 35
+	waterMlPerWorkoutMinute = 10
+	defaultWaterBaseMl      = 2000
+	waterTargetStepMl       = 50
+
+	// hydrationStreakDays is how far back streaks are counted
+	hydrationStreakDays = 365
+)
+
+// climateWaterMl is the extra water a day in each climate calls for
+var climateWaterMl = map[string]int{
+	models.ClimateTemperate: 0,
+	models.ClimateHot:       500,
+	models.ClimateVeryHot:   1000,
+}
+
+const waterIntakeColumns = `id, user_id, amount_ml, consumed_at, notes, created_at, updated_at`
+
+// HydrationService logs water intake and compares it with adaptive daily targets
+type HydrationService struct {
+	db       *sql.DB
+	foodLogs *FoodLogService
+}
+
+// NewHydrationService creates a new hydration service
+func NewHydrationService(db *sql.DB) *HydrationService {
+	return &HydrationService{
+		db:       db,
+		foodLogs: NewFoodLogService(db),
+	}
+}
+
+// LogWater records a drink for userID
+func (s *HydrationService) LogWater(ctx context.Context, userID string, req models.LogWaterRequest) (*models.WaterIntake, error) {
+	if req.AmountMl <= 0 {
+		return nil, fmt.Errorf("%w: amount_ml must be greater than 0", ErrInvalidWaterIntake)
+	}
+	now := time.Now().UTC()
+	consumedAt := now
+	if req.Date != nil && !req.Date.IsZero() {
+		consumedAt = req.Date.UTC()
+	}
+	if consumedAt.After(now.Add(time.Minute)) {
+		return nil, fmt.Errorf("%w: date cannot be in the future", ErrInvalidWaterIntake)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO water_intake (user_id, amount_ml, consumed_at, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		userID, req.AmountMl, consumedAt, nonEmpty(req.Notes), now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log water intake: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to log water intake: %w", err)
+	}
+	return s.getWaterIntake(ctx, userID, id)
+}
+
+// DeleteWater removes one of userID's entries
+func (s *HydrationService) DeleteWater(ctx context.Context, userID string, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM water_intake WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete water intake: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrWaterIntakeNotFound
+	}
+	return nil
+}
+
+// ListWater returns userID's entries consumed in [start, end), oldest first
+func (s *HydrationService) ListWater(ctx context.Context, userID string, start, end time.Time) ([]*models.WaterIntake, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+waterIntakeColumns+` FROM water_intake
+		WHERE user_id = ? AND consumed_at >= ? AND consumed_at < ?
+		ORDER BY consumed_at, id`, userID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list water intake: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.WaterIntake{}
+	for rows.Next() {
+		entry, err := scanWaterIntake(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan water intake: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetDailyTarget returns the target for the UTC day containing day
+func (s *HydrationService) GetDailyTarget(ctx context.Context, userID string, day time.Time, climate string) (*models.WaterTarget, error) {
+	climate, err := normalizeClimate(climate)
+	if err != nil {
+		return nil, err
+	}
+	day = startOfDay(day)
+	minutes, err := s.workoutMinutes(ctx, userID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	weight, err := s.bodyWeight(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.dailyTarget(ctx, userID, day, weight, minutes[day.Format("2006-01-02")], climate)
+}
+
+// GetHistory returns the UTC day, or the Monday-to-Sunday week, containing
+// day with each day's total against its target, plus the user's streaks
+func (s *HydrationService) GetHistory(ctx context.Context, userID, period string, day time.Time, climate string) (*models.HydrationHistory, error) {
+	climate, err := normalizeClimate(climate)
+	if err != nil {
+		return nil, err
+	}
+	day = startOfDay(day)
+	start, days := day, 1
+	switch period {
+	case "", "day":
+		period = "day"
+	case "week":
+		start, days = day.AddDate(0, 0, -((int(day.Weekday())+6)%7)), 7
+	default:
+		return nil, fmt.Errorf("%w: period must be day or week", ErrInvalidWaterIntake)
+	}
+	end := start.AddDate(0, 0, days)
+
+	entries, err := s.ListWater(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Streaks look back from today regardless of the period shown
+	today := startOfDay(time.Now())
+	from := today.AddDate(0, 0, -hydrationStreakDays)
+	if start.Before(from) {
+		from = start
+	}
+	to := today.AddDate(0, 0, 1)
+	if end.After(to) {
+		to = end
+	}
+	totals, err := s.dailyTotals(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := s.workoutMinutes(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	weight, err := s.bodyWeight(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := map[string]*models.WaterTarget{}
+	targetOn := func(d time.Time) (*models.WaterTarget, error) {
+		key := d.Format("2006-01-02")
+		if target, ok := targets[key]; ok {
+			return target, nil
+		}
+		target, err := s.dailyTarget(ctx, userID, d, weight, minutes[key], climate)
+		if err != nil {
+			return nil, err
+		}
+		targets[key] = target
+		return target, nil
+	}
+	met := func(d time.Time) (bool, error) {
+		total := totals[d.Format("2006-01-02")].total
+		if total == 0 {
+			return false, nil
+		}
+		target, err := targetOn(d)
+		if err != nil {
+			return false, err
+		}
+		return total >= target.TargetMl, nil
+	}
+
+	history := &models.HydrationHistory{
+		Period:    period,
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Days:      make([]models.DailyHydration, 0, days),
+		Entries:   entries,
+	}
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		target, err := targetOn(d)
+		if err != nil {
+			return nil, err
+		}
+		intake := totals[d.Format("2006-01-02")]
+		daily := models.DailyHydration{
+			Date:            d.Format("2006-01-02"),
+			TotalMl:         intake.total,
+			TargetMl:        target.TargetMl,
+			ProgressPercent: round1(float64(intake.total) / float64(target.TargetMl) * 100),
+			TargetMet:       intake.total > 0 && intake.total >= target.TargetMl,
+			Entries:         intake.entries,
+		}
+		history.Days = append(history.Days, daily)
+		history.TotalMl += daily.TotalMl
+		if daily.TargetMet {
+			history.DaysMet++
+		}
+	}
+	history.AverageMl = int(math.Round(float64(history.TotalMl) / float64(days)))
+	target, err := targetOn(day)
+	if err != nil {
+		return nil, err
+	}
+	history.Target = *target
+
+	run := 0
+	for d := from; !d.After(today); d = d.AddDate(0, 0, 1) {
+		ok, err := met(d)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			run++
+			history.Streak.Longest = max(history.Streak.Longest, run)
+		} else {
+			run = 0
+		}
+	}
+	// Today is still in progress, so an unmet today does not break the streak
+	d := today
+	if ok, err := met(today); err != nil {
+		return nil, err
+	} else if !ok {
+		d = today.AddDate(0, 0, -1)
+	}
+	for ; !d.Before(from); d = d.AddDate(0, 0, -1) {
+		ok, err := met(d)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		history.Streak.Current++
+	}
+	return history, nil
+}
+
+// dailyTarget uses the water_ml of the goal active on day, or computes a
+// target from body weight, the day's workout minutes and the climate
+func (s *HydrationService) dailyTarget(ctx context.Context, userID string, day time.Time, weight *float64, workoutMinutes int, climate string) (*models.WaterTarget, error) {
+	target := &models.WaterTarget{
+		Date:           day.Format("2006-01-02"),
+		WorkoutMinutes: workoutMinutes,
+		Climate:        climate,
+	}
+
+	goal, err := s.foodLogs.GetActiveNutritionGoal(ctx, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	if goal != nil && goal.WaterMl != nil && *goal.WaterMl > 0 {
+		target.Source = models.WaterTargetFromGoal
+		target.TargetMl = *goal.WaterMl
+		return target, nil
+	}
+
+	target.Source = models.WaterTargetFromDefaults
+	target.BaseMl = defaultWaterBaseMl
+	if weight != nil {
+		target.Source = models.WaterTargetComputed
+		target.BodyWeightKg = weight
+		target.BaseMl = int(math.Round(*weight * waterMlPerKg))
+	}
+	target.WorkoutMl = workoutMinutes * waterMlPerWorkoutMinute
+	target.ClimateMl = climateWaterMl[climate]
+	total := float64(target.BaseMl + target.WorkoutMl + target.ClimateMl)
+	target.TargetMl = int(math.Round(total/waterTargetStepMl)) * waterTargetStepMl
+	return target, nil
+}
+
+// waterDay is the intake of one day
+type waterDay struct {
+	total   int
+	entries int
+}
+
+// dailyTotals sums userID's intake per UTC day in [start, end)
+func (s *HydrationService) dailyTotals(ctx context.Context, userID string, start, end time.Time) (map[string]waterDay, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT consumed_at, amount_ml FROM water_intake
+		WHERE user_id = ? AND consumed_at >= ? AND consumed_at < ?`, userID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum water intake: %w", err)
+	}
+	defer rows.Close()
+
+	days := map[string]waterDay{}
+	for rows.Next() {
+		var consumedAt time.Time
+		var amount int
+		if err := rows.Scan(&consumedAt, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan water intake: %w", err)
+		}
+		key := startOfDay(consumedAt).Format("2006-01-02")
+		day := days[key]
+		day.total += amount
+		day.entries++
+		days[key] = day
+	}
+	return days, rows.Err()
+}
+
+// workoutMinutes sums the minutes of completed workout sessions and logged
+// exercises per UTC day in [start, end)
+func (s *HydrationService) workoutMinutes(ctx context.Context, userID string, start, end time.Time) (map[string]int, error) {
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date(completed_date), COALESCE(SUM(duration_minutes), 0) FROM user_workout_sessions
+		WHERE user_id = ? AND status = 'completed' AND date(completed_date) >= ? AND date(completed_date) < ?
+		GROUP BY date(completed_date)
+		UNION ALL
+		SELECT date(performed_at), COALESCE(SUM(duration_minutes), 0) FROM user_exercise_logs
+		WHERE user_id = ? AND date(performed_at) >= ? AND date(performed_at) < ?
+		GROUP BY date(performed_at)`, userID, from, to, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum workout minutes: %w", err)
+	}
+	defer rows.Close()
+
+	minutes := map[string]int{}
+	for rows.Next() {
+		var day string
+		var total int
+		if err := rows.Scan(&day, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan workout minutes: %w", err)
+		}
+		minutes[day] += total
+	}
+	return minutes, rows.Err()
+}
+
+// bodyWeight returns the weight on userID's profile, or nil if it is unknown
+func (s *HydrationService) bodyWeight(ctx context.Context, userID string) (*float64, error) {
+	var weight sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `SELECT weight FROM users WHERE id = ?`, userID).Scan(&weight)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get body weight: %w", err)
+	}
+	if !weight.Valid || weight.Float64 <= 0 {
+		return nil, nil
+	}
+	return &weight.Float64, nil
+}
+
+func (s *HydrationService) getWaterIntake(ctx context.Context, userID string, id int64) (*models.WaterIntake, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+waterIntakeColumns+` FROM water_intake WHERE id = ? AND user_id = ?`, id, userID)
+	entry, err := scanWaterIntake(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWaterIntakeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get water intake: %w", err)
+	}
+	return entry, nil
+}
+
+func scanWaterIntake(row rowScanner) (*models.WaterIntake, error) {
+	entry := &models.WaterIntake{}
+	var notes sql.NullString
+	if err := row.Scan(&entry.ID, &entry.UserID, &entry.AmountMl, &entry.ConsumedAt, &notes,
+		&entry.CreatedAt, &entry.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if notes.Valid {
+		entry.Notes = &notes.String
+	}
+	return entry, nil
+}
+
+func normalizeClimate(climate string) (string, error) {
+	climate = strings.ToLower(strings.TrimSpace(climate))
+	if climate == "" {
+		return models.ClimateTemperate, nil
+	}
+	if _, ok := climateWaterMl[climate]; !ok {
+		return "", fmt.Errorf("%w %q, expected one of %s", ErrUnknownClimate, climate,
+			strings.Join([]string{models.ClimateTemperate, models.ClimateHot, models.ClimateVeryHot}, ", "))
+	}
+	return climate, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHydrationService(t *testing.T) (*HydrationService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "water@example.com", Password: "password123"})
+	require.NoError(t, err)
+	return NewHydrationService(users.db), user.ID
+}
+
+func logWaterOn(t *testing.T, svc *HydrationService, userID string, day time.Time, amount int) {
+	t.Helper()
+	at := day.Add(time.Hour)
+	if now := time.Now(); at.After(now) {
+		at = now
+	}
+	_, err := svc.LogWater(context.Background(), userID, models.LogWaterRequest{AmountMl: amount, Date: &at})
+	require.NoError(t, err)
+}
+
+func TestHydrationService_LogAndDelete(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestHydrationService(t)
+
+	entry, err := svc.LogWater(ctx, userID, models.LogWaterRequest{AmountMl: 250, Notes: stringPtr("after run")})
+	require.NoError(t, err)
+	assert.NotZero(t, entry.ID)
+	assert.Equal(t, 250, entry.AmountMl)
+	require.NotNil(t, entry.Notes)
+	assert.Equal(t, "after run", *entry.Notes)
+
+	future := time.Now().Add(48 * time.Hour)
+	_, err = svc.LogWater(ctx, userID, models.LogWaterRequest{AmountMl: 250, Date: &future})
+	assert.ErrorIs(t, err, ErrInvalidWaterIntake)
+	_, err = svc.LogWater(ctx, userID, models.LogWaterRequest{AmountMl: 0})
+	assert.ErrorIs(t, err, ErrInvalidWaterIntake)
+
+	assert.ErrorIs(t, svc.DeleteWater(ctx, "someone-else", entry.ID), ErrWaterIntakeNotFound)
+	require.NoError(t, svc.DeleteWater(ctx, userID, entry.ID))
+	assert.ErrorIs(t, svc.DeleteWater(ctx, userID, entry.ID), ErrWaterIntakeNotFound)
+}
+
+func TestHydrationService_DailyTarget(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestHydrationService(t)
+	day := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	target, err := svc.GetDailyTarget(ctx, userID, day, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.WaterTargetFromDefaults, target.Source)
+	assert.Equal(t, 2000, target.TargetMl)
+	assert.Equal(t, models.ClimateTemperate, target.Climate)
+
+	_, err = svc.db.Exec(`UPDATE users SET weight = 72 WHERE id = ?`, userID)
+	require.NoError(t, err)
+	_, err = svc.db.Exec(`INSERT INTO user_workout_sessions (user_id, completed_date, duration_minutes, status)
+		VALUES (?, '2026-03-04T18:00:00Z', 30, 'completed'), (?, '2026-03-04T19:00:00Z', 90, 'scheduled')`, userID, userID)
+	require.NoError(t, err)
+	_, err = svc.db.Exec(`INSERT INTO user_exercise_logs (user_id, duration_minutes, performed_at)
+		VALUES (?, 15, '2026-03-04 07:30:00'), (?, 40, '2026-03-05 07:30:00')`, userID, userID)
+	require.NoError(t, err)
+
+	target, err = svc.GetDailyTarget(ctx, userID, day.Add(15*time.Hour), "Hot")
+	require.NoError(t, err)
+	assert.Equal(t, models.WaterTargetComputed, target.Source)
+	assert.Equal(t, 2520, target.BaseMl)
+	assert.Equal(t, 45, target.WorkoutMinutes)
+	assert.Equal(t, 450, target.WorkoutMl)
+	assert.Equal(t, 500, target.ClimateMl)
+	assert.Equal(t, 3450, target.TargetMl) // 3470 rounded to 50 ml
+
+	_, err = svc.db.Exec(`INSERT INTO nutrition_goals (user_id, water_ml) VALUES (?, 2800)`, userID)
+	require.NoError(t, err)
+	target, err = svc.GetDailyTarget(ctx, userID, day, models.ClimateVeryHot)
+	require.NoError(t, err)
+	assert.Equal(t, models.WaterTargetFromGoal, target.Source)
+	assert.Equal(t, 2800, target.TargetMl)
+
+	_, err = svc.GetDailyTarget(ctx, userID, day, "arctic")
+	assert.ErrorIs(t, err, ErrUnknownClimate)
+}
+
+func TestHydrationService_HistoryAndStreaks(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestHydrationService(t)
+	today := startOfDay(time.Now())
+
+	// Met four days ago, missed three days ago, then met the last two days;
+	// today is under target but still counts towards the current streak
+	logWaterOn(t, svc, userID, today.AddDate(0, 0, -4), 2000)
+	logWaterOn(t, svc, userID, today.AddDate(0, 0, -3), 1500)
+	logWaterOn(t, svc, userID, today.AddDate(0, 0, -2), 1200)
+	logWaterOn(t, svc, userID, today.AddDate(0, 0, -2), 900)
+	logWaterOn(t, svc, userID, today.AddDate(0, 0, -1), 2500)
+	logWaterOn(t, svc, userID, today, 500)
+
+	history, err := svc.GetHistory(ctx, userID, "week", today, "")
+	require.NoError(t, err)
+	assert.Equal(t, "week", history.Period)
+	require.Len(t, history.Days, 7)
+	start, err := time.Parse("2006-01-02", history.StartDate)
+	require.NoError(t, err)
+	assert.Equal(t, time.Monday, start.Weekday())
+	assert.Equal(t, 2, history.Streak.Current)
+	assert.Equal(t, 2, history.Streak.Longest)
+	assert.Equal(t, 2000, history.Target.TargetMl)
+
+	for _, day := range history.Days {
+		if day.Date == today.AddDate(0, 0, -2).Format("2006-01-02") {
+			assert.Equal(t, 2100, day.TotalMl)
+			assert.Equal(t, 2, day.Entries)
+			assert.Equal(t, 105.0, day.ProgressPercent)
+			assert.True(t, day.TargetMet)
+		}
+	}
+
+	history, err = svc.GetHistory(ctx, userID, "day", today, "")
+	require.NoError(t, err)
+	require.Len(t, history.Days, 1)
+	assert.Equal(t, 500, history.TotalMl)
+	assert.Equal(t, 25.0, history.Days[0].ProgressPercent)
+	assert.False(t, history.Days[0].TargetMet)
+	assert.Len(t, history.Entries, 1)
+
+	// A higher goal breaks the streaks
+	_, err = svc.db.Exec(`INSERT INTO nutrition_goals (user_id, water_ml) VALUES (?, 2400)`, userID)
+	require.NoError(t, err)
+	history, err = svc.GetHistory(ctx, userID, "day", today, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, history.Streak.Current)
+	assert.Equal(t, 1, history.Streak.Longest)
+
+	_, err = svc.GetHistory(ctx, userID, "month", today, "")
+	assert.ErrorIs(t, err, ErrInvalidWaterIntake)
+	_, err = svc.GetHistory(ctx, userID, "day", today, "tropical")
+	assert.ErrorIs(t, err, ErrUnknownClimate)
+}
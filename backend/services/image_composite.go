@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// CaptionedImage is one panel of a composite together with the lines of text
+// printed underneath it
+type CaptionedImage struct {
+	Image   image.Image
+	Caption []string
+}
+
+const (
+	compositeGap   = 12
+	captionPadding = 8
+)
+
+// RenderComparison places the panels side by side, each resized to
+// opts.Width x opts.Height with opts.ResizeMode above its caption, and
+// encodes the result as JPEG
+func (ips *ImageProcessorService) RenderComparison(ctx context.Context, panels []CaptionedImage, opts ImageProcessingOptions) ([]byte, error) {
+	if len(panels) == 0 {
+		return nil, fmt.Errorf("%w: no panels to render", ErrInvalidImage)
+	}
+
+	rendered := make([]image.Image, len(panels))
+	for i, panel := range panels {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		rendered[i] = ips.captionedPanel(panel, opts)
+	}
+
+	panelWidth := rendered[0].Bounds().Dx()
+	panelHeight := rendered[0].Bounds().Dy()
+	canvas := imaging.New(len(rendered)*(panelWidth+compositeGap)+compositeGap, panelHeight+2*compositeGap, color.White)
+	for i, panel := range rendered {
+		canvas = imaging.Paste(canvas, panel, image.Pt(compositeGap+i*(panelWidth+compositeGap), compositeGap))
+	}
+
+	quality := opts.Quality
+	if quality <= 0 {
+		quality = ips.quality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode comparison: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderTimeline encodes the panels as a looping animated GIF that shows each
+// one for delay
+func (ips *ImageProcessorService) RenderTimeline(ctx context.Context, panels []CaptionedImage, opts ImageProcessingOptions, delay time.Duration) ([]byte, error) {
+	if len(panels) == 0 {
+		return nil, fmt.Errorf("%w: no frames to render", ErrInvalidImage)
+	}
+
+	// GIF delays are counted in hundredths of a second
+	frameDelay := max(1, int(delay/(10*time.Millisecond)))
+	animation := &gif.GIF{}
+	for _, panel := range panels {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		frame := ips.captionedPanel(panel, opts)
+		paletted := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, frame.Bounds(), frame, image.Point{})
+		animation.Image = append(animation.Image, paletted)
+		animation.Delay = append(animation.Delay, frameDelay)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		return nil, fmt.Errorf("failed to encode timeline: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// captionedPanel resizes the image into an opts.Width x opts.Height box on a
+// white background and prints the caption lines centred below it
+func (ips *ImageProcessorService) captionedPanel(panel CaptionedImage, opts ImageProcessingOptions) *image.NRGBA {
+	width, height := opts.Width, opts.Height
+	if width <= 0 || height <= 0 {
+		width, height = ips.thumbnailSize, ips.thumbnailSize
+	}
+	mode := opts.ResizeMode
+	if mode == "" {
+		mode = "fit"
+	}
+	resized := ips.resizeImage(panel.Image, width, height, mode)
+
+	// The bitmap font is 13px tall, so it is enlarged on wide panels
+	scale := max(1, width/240)
+	lineHeight := basicfont.Face7x13.Height * scale
+	captionHeight := 0
+	if len(panel.Caption) > 0 {
+		captionHeight = len(panel.Caption)*lineHeight + 2*captionPadding
+	}
+
+	canvas := imaging.New(width, height+captionHeight, color.White)
+	bounds := resized.Bounds()
+	canvas = imaging.Paste(canvas, resized, image.Pt((width-bounds.Dx())/2, (height-bounds.Dy())/2))
+	for i, line := range panel.Caption {
+		text := renderText(line, scale)
+		x := max(0, (width-text.Bounds().Dx())/2)
+		canvas = imaging.Paste(canvas, text, image.Pt(x, height+captionPadding+i*lineHeight))
+	}
+	return canvas
+}
+
+// renderText draws a line of black text on white in the basic bitmap font,
+// enlarged scale times
+func renderText(line string, scale int) image.Image {
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{Face: face}
+	width := max(1, drawer.MeasureString(line).Ceil())
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, face.Height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	drawer.Dst = img
+	drawer.Src = image.Black
+	drawer.Dot = fixed.P(0, face.Ascent)
+	drawer.DrawString(line)
+
+	if scale > 1 {
+		return imaging.Resize(img, width*scale, face.Height*scale, imaging.NearestNeighbor)
+	}
+	return img
+}
@@ -3,8 +3,10 @@ package services
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
@@ -14,10 +16,18 @@ import (
 	"strings"
 
 	"github.com/disintegration/imaging"
-	"github.com/google/uuid"
 	"golang.org/x/image/webp"
 )
 
+// Image errors returned by ImageProcessorService
+var (
+	ErrInvalidImage = errors.New("invalid image")
+)
+
+// maxImagePixels rejects images whose header claims more pixels than this
+// before they are decoded, so a small upload cannot expand into gigabytes
+const maxImagePixels = 50 * 1000 * 1000
+
 // ImageProcessingOptions defines options for image processing
 type ImageProcessingOptions struct {
 	Width       int    `json:"width"`
@@ -88,33 +98,71 @@ func (ips *ImageProcessorService) ProcessImage(ctx context.Context, reader io.Re
 	return buf.Bytes(), outputFormat, nil
 }
 
-// GenerateThumbnail creates a thumbnail of an image
-func (ips *ImageProcessorService) GenerateThumbnail(ctx context.Context, reader io.Reader, filename string) (string, error) {
-	// Decode the image
-	img, _, err := ips.decodeImage(reader)
+// GenerateThumbnail creates a square JPEG thumbnail of an image
+func (ips *ImageProcessorService) GenerateThumbnail(ctx context.Context, reader io.Reader) ([]byte, error) {
+	img, err := imaging.Decode(reader, imaging.AutoOrientation(true))
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
 	}
-	
-	// Create thumbnail
-	thumbnail := ips.resizeImage(img, ips.thumbnailSize, ips.thumbnailSize, "crop")
-	
-	// Encode thumbnail
+	return ips.thumbnail(img)
+}
+
+// ProcessedImage is an uploaded image re-encoded from its pixels alone
+type ProcessedImage struct {
+	Data        []byte
+	Thumbnail   []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// PrepareUpload validates an uploaded image and re-encodes it as a JPEG no
+// larger than the maximum dimensions, with a thumbnail. The EXIF orientation
+// is applied first and only the pixels are encoded, so EXIF (including GPS
+// coordinates), XMP and comment segments never reach storage.
+func (ips *ImageProcessorService) PrepareUpload(ctx context.Context, data []byte) (*ProcessedImage, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels is not supported", ErrInvalidImage, config.Width, config.Height)
+	}
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	// JPEG has no alpha channel, so transparent areas are flattened onto white
+	img = ips.resizeToMaxDimensions(img, ips.maxWidth, ips.maxHeight)
+	bounds := img.Bounds()
+	img = imaging.Overlay(imaging.New(bounds.Dx(), bounds.Dy(), color.White), img, image.Pt(0, 0), 1)
+
 	var buf bytes.Buffer
-	thumbnailFormat := "jpeg" // Always use JPEG for thumbnails for better compression
-	err = ips.encodeImage(&buf, thumbnail, thumbnailFormat, ips.quality)
+	if err := ips.encodeImage(&buf, img, "jpeg", ips.quality); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	thumbnail, err := ips.thumbnail(img)
 	if err != nil {
-		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+		return nil, err
 	}
-	
-	// Generate unique filename
-	ext := filepath.Ext(filename)
-	baseName := strings.TrimSuffix(filename, ext)
-	thumbnailFilename := fmt.Sprintf("%s_thumb_%s_%s.jpg", baseName, uuid.New().String()[:8], thumbnailFormat)
-	
-	// TODO: Save thumbnail using storage provider
-	// For now, return the filename
-	return thumbnailFilename, nil
+	return &ProcessedImage{
+		Data:        buf.Bytes(),
+		Thumbnail:   thumbnail,
+		ContentType: "image/jpeg",
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
+
+// thumbnail fills a thumbnailSize square from the center of img
+func (ips *ImageProcessorService) thumbnail(img image.Image) ([]byte, error) {
+	thumbnail := ips.resizeImage(img, ips.thumbnailSize, ips.thumbnailSize, "fill")
+	var buf bytes.Buffer
+	if err := ips.encodeImage(&buf, thumbnail, "jpeg", ips.quality); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
 }
 
 // ResizeImage resizes an image to specific dimensions
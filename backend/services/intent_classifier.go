@@ -0,0 +1,280 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"nutrition-platform/models"
+)
+
+// Intent classifier errors returned by TrainIntentClassifier
+var (
+	ErrInvalidIntentTraining = errors.New("invalid intent training data")
+)
+
+// DefaultIntentTrainingPath is the bundled set of labeled questions the answer
+// intent classifier is trained on
+const DefaultIntentTrainingPath = "data/intents.json"
+
+const (
+	// intentSmoothing is the additive (Lidstone) smoothing of term likelihoods
+	intentSmoothing = 0.1
+
+	// Temperatures tried when calibrating, on a geometric grid
+	minIntentTemperature  = 0.05
+	intentTemperatureStep = 1.1
+	intentTemperatureGrid = 60
+)
+
+// IntentExample is one labeled training question
+type IntentExample struct {
+	Intent string
+	Text   string
+}
+
+// IntentClassifierStats describes a trained classifier
+type IntentClassifierStats struct {
+	Examples            int     `json:"examples"`
+	Intents             int     `json:"intents"`
+	Vocabulary          int     `json:"vocabulary"`
+	Temperature         float64 `json:"temperature"`
+	LeaveOneOutAccuracy float64 `json:"leave_one_out_accuracy"`
+}
+
+// IntentClassifier is a multinomial naive Bayes classifier over TF-IDF weighted
+// search terms. Raw naive Bayes posteriors are badly overconfident, so scores
+// are softmax-scaled by a temperature fitted to leave-one-out predictions on
+// the training set, which makes the reported probabilities calibrated.
+type IntentClassifier struct {
+	intents     []string
+	idf         map[string]float64
+	examples    []int                // training examples per intent
+	weights     []map[string]float64 // summed feature weights per intent
+	totals      []float64
+	temperature float64
+	stats       IntentClassifierStats
+}
+
+// intentTrainingFile is the layout of DefaultIntentTrainingPath
+type intentTrainingFile struct {
+	Intents []struct {
+		Intent   string   `json:"intent"`
+		Examples []string `json:"examples"`
+	} `json:"intents"`
+}
+
+// LoadIntentClassifier trains a classifier on a labeled JSON file
+func LoadIntentClassifier(path string) (*IntentClassifier, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read intent training data: %w", err)
+	}
+	var file intentTrainingFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIntentTraining, err)
+	}
+
+	var examples []IntentExample
+	for _, intent := range file.Intents {
+		for _, text := range intent.Examples {
+			examples = append(examples, IntentExample{Intent: intent.Intent, Text: text})
+		}
+	}
+	return TrainIntentClassifier(examples)
+}
+
+// TrainIntentClassifier fits the classifier and calibrates its temperature.
+// Every intent needs at least two examples so it can be left out once.
+func TrainIntentClassifier(examples []IntentExample) (*IntentClassifier, error) {
+	c := &IntentClassifier{idf: map[string]float64{}}
+	index := map[string]int{}
+	labels := make([]int, 0, len(examples))
+	docs := make([][]string, 0, len(examples))
+	df := map[string]int{}
+	for _, example := range examples {
+		terms := searchTerms(example.Text)
+		if example.Intent == "" || len(terms) == 0 {
+			return nil, fmt.Errorf("%w: example %q needs an intent and words", ErrInvalidIntentTraining, example.Text)
+		}
+		label, ok := index[example.Intent]
+		if !ok {
+			label = len(c.intents)
+			index[example.Intent] = label
+			c.intents = append(c.intents, example.Intent)
+			c.examples = append(c.examples, 0)
+		}
+		c.examples[label]++
+		labels = append(labels, label)
+		docs = append(docs, terms)
+		for _, term := range uniqueStrings(terms) {
+			df[term]++
+		}
+	}
+	if len(c.intents) < 2 {
+		return nil, fmt.Errorf("%w: at least two intents are required", ErrInvalidIntentTraining)
+	}
+	for i, count := range c.examples {
+		if count < 2 {
+			return nil, fmt.Errorf("%w: intent %q has fewer than two examples", ErrInvalidIntentTraining, c.intents[i])
+		}
+	}
+
+	n := float64(len(docs))
+	for term, count := range df {
+		c.idf[term] = math.Log((n+1)/(float64(count)+1)) + 1
+	}
+
+	features := make([]map[string]float64, len(docs))
+	c.weights = make([]map[string]float64, len(c.intents))
+	c.totals = make([]float64, len(c.intents))
+	for i := range c.weights {
+		c.weights[i] = map[string]float64{}
+	}
+	for i, terms := range docs {
+		features[i] = c.features(terms)
+		for term, weight := range features[i] {
+			c.weights[labels[i]][term] += weight
+			c.totals[labels[i]] += weight
+		}
+	}
+
+	// Score every example with its own contribution removed, then pick the
+	// temperature that minimizes the negative log-likelihood of the labels
+	scores := make([][]float64, len(docs))
+	correct := 0
+	for i := range docs {
+		scores[i] = c.scores(features[i], labels[i], features[i])
+		if argmax(scores[i]) == labels[i] {
+			correct++
+		}
+	}
+	c.temperature = 1
+	best := math.Inf(1)
+	for step, temperature := 0, minIntentTemperature; step < intentTemperatureGrid; step, temperature = step+1, temperature*intentTemperatureStep {
+		loss := 0.0
+		for i := range scores {
+			loss -= math.Log(softmax(scores[i], temperature)[labels[i]])
+		}
+		if loss < best {
+			best = loss
+			c.temperature = temperature
+		}
+	}
+
+	c.stats = IntentClassifierStats{
+		Examples:            len(docs),
+		Intents:             len(c.intents),
+		Vocabulary:          len(c.idf),
+		Temperature:         math.Round(c.temperature*1000) / 1000,
+		LeaveOneOutAccuracy: math.Round(float64(correct)/n*1000) / 1000,
+	}
+	return c, nil
+}
+
+// Classify returns every intent with its calibrated probability, most likely first
+func (c *IntentClassifier) Classify(terms []string) []models.AnswerIntent {
+	probabilities := softmax(c.scores(c.features(terms), -1, nil), c.temperature)
+	intents := make([]models.AnswerIntent, len(c.intents))
+	for i, intent := range c.intents {
+		intents[i] = models.AnswerIntent{Intent: intent, Probability: math.Round(probabilities[i]*1000) / 1000}
+	}
+	sort.SliceStable(intents, func(i, j int) bool {
+		return intents[i].Probability > intents[j].Probability
+	})
+	return intents
+}
+
+// Stats describes the training set and calibration
+func (c *IntentClassifier) Stats() IntentClassifierStats {
+	return c.stats
+}
+
+// features weights each known term by (1 + log tf) * idf and normalizes the
+// vector to unit length so long questions do not dominate the class totals
+func (c *IntentClassifier) features(terms []string) map[string]float64 {
+	counts := map[string]int{}
+	for _, term := range terms {
+		if _, ok := c.idf[term]; ok {
+			counts[term]++
+		}
+	}
+	features := make(map[string]float64, len(counts))
+	norm := 0.0
+	for term, count := range counts {
+		weight := (1 + math.Log(float64(count))) * c.idf[term]
+		features[term] = weight
+		norm += weight * weight
+	}
+	norm = math.Sqrt(norm)
+	for term := range features {
+		features[term] /= norm
+	}
+	return features
+}
+
+// scores returns the joint log-likelihood of the features under each intent.
+// When exclude is a valid intent, the held-out features are subtracted from
+// its totals first, giving the leave-one-out score of a training example.
+func (c *IntentClassifier) scores(features map[string]float64, exclude int, heldOut map[string]float64) []float64 {
+	vocabulary := float64(len(c.idf))
+	total := 0
+	for _, count := range c.examples {
+		total += count
+	}
+	if exclude >= 0 {
+		total--
+	}
+
+	scores := make([]float64, len(c.intents))
+	for i := range c.intents {
+		examples := float64(c.examples[i])
+		classTotal := c.totals[i]
+		if i == exclude {
+			examples--
+			for _, weight := range heldOut {
+				classTotal -= weight
+			}
+		}
+		score := math.Log(examples / float64(total))
+		for term, weight := range features {
+			termWeight := c.weights[i][term]
+			if i == exclude {
+				termWeight -= heldOut[term]
+			}
+			score += weight * math.Log((max(termWeight, 0)+intentSmoothing)/(classTotal+intentSmoothing*vocabulary))
+		}
+		scores[i] = score
+	}
+	return scores
+}
+
+func softmax(scores []float64, temperature float64) []float64 {
+	top := math.Inf(-1)
+	for _, score := range scores {
+		top = max(top, score)
+	}
+	sum := 0.0
+	probabilities := make([]float64, len(scores))
+	for i, score := range scores {
+		probabilities[i] = math.Exp((score - top) / temperature)
+		sum += probabilities[i]
+	}
+	for i := range probabilities {
+		probabilities[i] /= sum
+	}
+	return probabilities
+}
+
+func argmax(values []float64) int {
+	best := 0
+	for i, value := range values {
+		if value > values[best] {
+			best = i
+		}
+	}
+	return best
+}
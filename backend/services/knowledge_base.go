@@ -0,0 +1,818 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"nutrition-platform/utils"
+)
+
+// Knowledge base datasets reported by Status
+const (
+	KnowledgeDiseases         = "diseases"
+	KnowledgeInjuries         = "injuries"
+	KnowledgeVitaminsMinerals = "vitamins_minerals"
+)
+
+// DefaultKnowledgeReloadInterval is how often Watch polls the data directory for changes
+const DefaultKnowledgeReloadInterval = 30 * time.Second
+
+const (
+	diseasesDirName = "../disease-nutrition-easy-json-files"
+	injuriesDirName = "../injury easy trae json"
+)
+
+// KnowledgeDisease is a disease nutrition guide from the diseases dataset
+type KnowledgeDisease struct {
+	Slug            string
+	NameEn          string
+	NameAr          string
+	DescriptionEn   string
+	BeneficialFoods []string
+	Category        string
+	Data            map[string]interface{}
+}
+
+// KnowledgeInjury is an injury guide from the injuries dataset
+type KnowledgeInjury struct {
+	ID       string
+	File     string
+	Title    map[string]string
+	Category string
+	Data     map[string]interface{}
+}
+
+// KnowledgeRecommendation is a vitamin, mineral or supplement recommendation
+type KnowledgeRecommendation struct {
+	Name    map[string]string `json:"name"`
+	Dose    map[string]string `json:"dose"`
+	Usage   map[string]string `json:"usage"`
+	Purpose map[string]string `json:"purpose"`
+}
+
+// KnowledgeHit is a search match: the position of the record in its dataset and its relevance
+type KnowledgeHit struct {
+	Index int
+	Score float64
+}
+
+// KnowledgeDatasetStatus describes the outcome of the last load of one dataset
+type KnowledgeDatasetStatus struct {
+	Source   string   `json:"source"`
+	Records  int      `json:"records"`
+	Error    string   `json:"error,omitempty"`
+	Stale    bool     `json:"stale,omitempty"`
+	Skipped  []string `json:"skipped,omitempty"`
+	LoadedAt string   `json:"loaded_at"`
+}
+
+// KnowledgeBaseStatus is the health of the knowledge base for health checks
+type KnowledgeBaseStatus struct {
+	Healthy  bool                              `json:"healthy"`
+	LoadedAt time.Time                         `json:"loaded_at"`
+	Datasets map[string]KnowledgeDatasetStatus `json:"datasets"`
+}
+
+// KnowledgeSnapshot is an immutable, fully indexed view of the reference datasets.
+// Readers must not modify the records it hands out.
+type KnowledgeSnapshot struct {
+	LoadedAt time.Time
+
+	Diseases          []*KnowledgeDisease
+	DiseaseCategories map[string][]string
+
+	Injuries         []*KnowledgeInjury
+	InjuryCategories map[string][]*KnowledgeInjury
+
+	Vitamins        []KnowledgeRecommendation
+	Supplements     []KnowledgeRecommendation
+	WeightLossDrugs []interface{}
+	DrugCategories  map[string][]interface{}
+
+	diseasesBySlug  map[string]*KnowledgeDisease
+	injuriesByID    map[string]*KnowledgeInjury
+	diseaseIndex    *knowledgeIndex
+	injuryIndex     *knowledgeIndex
+	vitaminIndex    *knowledgeIndex
+	supplementIndex *knowledgeIndex
+	status          map[string]KnowledgeDatasetStatus
+}
+
+// KnowledgeBase loads the disease, injury and vitamin datasets once and serves
+// them from memory. Reload and Watch build a new snapshot and swap it in
+// atomically, so readers always see a consistent set of indexes.
+type KnowledgeBase struct {
+	dataDir  string
+	snapshot atomic.Pointer[KnowledgeSnapshot]
+
+	reloadMu    sync.Mutex
+	fingerprint string
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewKnowledgeBase creates a knowledge base and performs the initial load.
+// Load failures are recorded in Status instead of being returned.
+func NewKnowledgeBase(dataDir string) *KnowledgeBase {
+	kb := &KnowledgeBase{
+		dataDir: dataDir,
+		stop:    make(chan struct{}),
+	}
+	kb.Reload()
+	return kb
+}
+
+// Snapshot returns the current snapshot
+func (kb *KnowledgeBase) Snapshot() *KnowledgeSnapshot {
+	return kb.snapshot.Load()
+}
+
+// Status reports per-dataset load results of the current snapshot
+func (kb *KnowledgeBase) Status() KnowledgeBaseStatus {
+	snapshot := kb.Snapshot()
+	status := KnowledgeBaseStatus{
+		Healthy:  true,
+		LoadedAt: snapshot.LoadedAt,
+		Datasets: make(map[string]KnowledgeDatasetStatus, len(snapshot.status)),
+	}
+	for name, dataset := range snapshot.status {
+		if dataset.Error != "" {
+			status.Healthy = false
+		}
+		status.Datasets[name] = dataset
+	}
+	return status
+}
+
+// Reload re-reads every dataset and swaps in the new snapshot. A dataset that
+// fails to load keeps its previous records, marked stale.
+func (kb *KnowledgeBase) Reload() *KnowledgeSnapshot {
+	kb.reloadMu.Lock()
+	defer kb.reloadMu.Unlock()
+	return kb.reload()
+}
+
+func (kb *KnowledgeBase) reload() *KnowledgeSnapshot {
+	kb.fingerprint = kb.sourceFingerprint()
+	snapshot := buildKnowledgeSnapshot(kb.dataDir, kb.snapshot.Load())
+	kb.snapshot.Store(snapshot)
+
+	for name, dataset := range snapshot.status {
+		if dataset.Error != "" {
+			log.Printf("knowledge base: failed to load %s: %s", name, dataset.Error)
+		}
+	}
+	return snapshot
+}
+
+// Watch polls the data directory every interval and reloads when any source
+// file is added, removed or modified. It returns immediately; call Close to stop.
+func (kb *KnowledgeBase) Watch(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultKnowledgeReloadInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-kb.stop:
+				return
+			case <-ticker.C:
+				kb.reloadIfChanged()
+			}
+		}
+	}()
+}
+
+// Close stops the watcher started by Watch
+func (kb *KnowledgeBase) Close() {
+	kb.stopOnce.Do(func() { close(kb.stop) })
+}
+
+func (kb *KnowledgeBase) reloadIfChanged() bool {
+	kb.reloadMu.Lock()
+	defer kb.reloadMu.Unlock()
+
+	if kb.sourceFingerprint() == kb.fingerprint {
+		return false
+	}
+	kb.reload()
+	return true
+}
+
+// sourceFingerprint summarizes the name, size and modification time of every source file
+func (kb *KnowledgeBase) sourceFingerprint() string {
+	var b strings.Builder
+	for _, path := range []string{
+		filepath.Join(kb.dataDir, diseasesDirName),
+		filepath.Join(kb.dataDir, injuriesDirName),
+		filepath.Join(kb.dataDir, DrugsNutritionFile),
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s:missing;", path)
+			continue
+		}
+		if !info.IsDir() {
+			fmt.Fprintf(&b, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s:unreadable;", path)
+			continue
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				fmt.Fprintf(&b, "%s/%s:%d:%d;", path, entry.Name(), info.Size(), info.ModTime().UnixNano())
+			}
+		}
+	}
+	return b.String()
+}
+
+// Available reports whether a dataset has records to serve, fresh or stale
+func (s *KnowledgeSnapshot) Available(dataset string) bool {
+	status, ok := s.status[dataset]
+	return ok && (status.Error == "" || status.Stale)
+}
+
+// Disease returns a disease by its file slug
+func (s *KnowledgeSnapshot) Disease(slug string) (*KnowledgeDisease, bool) {
+	disease, ok := s.diseasesBySlug[slug]
+	return disease, ok
+}
+
+// Injury returns an injury by its ID
+func (s *KnowledgeSnapshot) Injury(id string) (*KnowledgeInjury, bool) {
+	injury, ok := s.injuriesByID[id]
+	return injury, ok
+}
+
+// SearchDiseases ranks diseases by name (10), description (5) and beneficial foods (3)
+func (s *KnowledgeSnapshot) SearchDiseases(query string) []KnowledgeHit {
+	return s.diseaseIndex.search(query)
+}
+
+// SearchInjuries ranks injuries by title (10) and full text (1)
+func (s *KnowledgeSnapshot) SearchInjuries(query string) []KnowledgeHit {
+	return s.injuryIndex.search(query)
+}
+
+// SearchVitamins ranks vitamins and minerals by name (10) and purpose (5)
+func (s *KnowledgeSnapshot) SearchVitamins(query string) []KnowledgeHit {
+	return s.vitaminIndex.search(query)
+}
+
+// SearchSupplements ranks supplements by name (10) and purpose (5)
+func (s *KnowledgeSnapshot) SearchSupplements(query string) []KnowledgeHit {
+	return s.supplementIndex.search(query)
+}
+
+func buildKnowledgeSnapshot(dataDir string, previous *KnowledgeSnapshot) *KnowledgeSnapshot {
+	now := time.Now().UTC()
+	snapshot := &KnowledgeSnapshot{
+		LoadedAt:          now,
+		DiseaseCategories: map[string][]string{},
+		InjuryCategories:  map[string][]*KnowledgeInjury{},
+		DrugCategories:    map[string][]interface{}{},
+		diseasesBySlug:    map[string]*KnowledgeDisease{},
+		injuriesByID:      map[string]*KnowledgeInjury{},
+		diseaseIndex:      newKnowledgeIndex(),
+		injuryIndex:       newKnowledgeIndex(),
+		vitaminIndex:      newKnowledgeIndex(),
+		supplementIndex:   newKnowledgeIndex(),
+		status:            map[string]KnowledgeDatasetStatus{},
+	}
+
+	diseasesDir := filepath.Join(dataDir, diseasesDirName)
+	diseases, skipped, err := loadKnowledgeDiseases(diseasesDir)
+	status := newKnowledgeDatasetStatus(diseasesDir, skipped, now)
+	if err != nil && previous != nil && len(previous.Diseases) > 0 {
+		diseases = previous.Diseases
+		status.Stale = true
+		status.LoadedAt = previous.status[KnowledgeDiseases].LoadedAt
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.Records = len(diseases)
+	snapshot.status[KnowledgeDiseases] = status
+	snapshot.Diseases = diseases
+	for i, disease := range diseases {
+		snapshot.diseasesBySlug[disease.Slug] = disease
+		snapshot.DiseaseCategories[disease.Category] = append(snapshot.DiseaseCategories[disease.Category], disease.Slug)
+		snapshot.diseaseIndex.add(i, 10, disease.NameEn, disease.NameAr)
+		snapshot.diseaseIndex.add(i, 5, disease.DescriptionEn)
+		snapshot.diseaseIndex.add(i, 3, disease.BeneficialFoods...)
+	}
+
+	injuriesDir := filepath.Join(dataDir, injuriesDirName)
+	injuries, contents, skipped, err := loadKnowledgeInjuries(injuriesDir)
+	status = newKnowledgeDatasetStatus(injuriesDir, skipped, now)
+	if err != nil && previous != nil && len(previous.Injuries) > 0 {
+		// Stale injuries keep the full-text index they were loaded with
+		injuries = previous.Injuries
+		snapshot.injuryIndex = previous.injuryIndex
+		status.Stale = true
+		status.LoadedAt = previous.status[KnowledgeInjuries].LoadedAt
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.Records = len(injuries)
+	snapshot.status[KnowledgeInjuries] = status
+	snapshot.Injuries = injuries
+	for i, injury := range injuries {
+		snapshot.injuriesByID[injury.ID] = injury
+		snapshot.InjuryCategories[injury.Category] = append(snapshot.InjuryCategories[injury.Category], injury)
+		if !status.Stale {
+			snapshot.injuryIndex.add(i, 10, injury.Title["english"], injury.Title["arabic"])
+			snapshot.injuryIndex.add(i, 1, contents[i])
+		}
+	}
+
+	drugsFile := filepath.Join(dataDir, DrugsNutritionFile)
+	drugs, err := loadKnowledgeDrugs(drugsFile)
+	status = newKnowledgeDatasetStatus(drugsFile, nil, now)
+	if err != nil && previous != nil && previous.Available(KnowledgeVitaminsMinerals) {
+		drugs = &knowledgeDrugs{
+			vitamins:        previous.Vitamins,
+			supplements:     previous.Supplements,
+			weightLossDrugs: previous.WeightLossDrugs,
+		}
+		status.Stale = true
+		status.LoadedAt = previous.status[KnowledgeVitaminsMinerals].LoadedAt
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	if drugs != nil {
+		snapshot.Vitamins = drugs.vitamins
+		snapshot.Supplements = drugs.supplements
+		snapshot.WeightLossDrugs = drugs.weightLossDrugs
+	}
+	status.Records = len(snapshot.Vitamins) + len(snapshot.Supplements) + len(snapshot.WeightLossDrugs)
+	snapshot.status[KnowledgeVitaminsMinerals] = status
+	for i, vitamin := range snapshot.Vitamins {
+		snapshot.vitaminIndex.add(i, 10, vitamin.Name["en"], vitamin.Name["ar"])
+		snapshot.vitaminIndex.add(i, 5, vitamin.Purpose["en"], vitamin.Purpose["ar"])
+	}
+	for i, supplement := range snapshot.Supplements {
+		snapshot.supplementIndex.add(i, 10, supplement.Name["en"], supplement.Name["ar"])
+		snapshot.supplementIndex.add(i, 5, supplement.Purpose["en"], supplement.Purpose["ar"])
+	}
+	for _, drug := range snapshot.WeightLossDrugs {
+		if drugMap, ok := drug.(map[string]interface{}); ok {
+			if drugName, ok := drugMap["drug_name"].(map[string]interface{}); ok {
+				if generic, ok := drugName["generic"].(string); ok {
+					category := categorizeDrug(generic)
+					snapshot.DrugCategories[category] = append(snapshot.DrugCategories[category], drug)
+				}
+			}
+		}
+	}
+
+	snapshot.diseaseIndex.finish()
+	snapshot.injuryIndex.finish()
+	snapshot.vitaminIndex.finish()
+	snapshot.supplementIndex.finish()
+	return snapshot
+}
+
+func newKnowledgeDatasetStatus(source string, skipped []string, loadedAt time.Time) KnowledgeDatasetStatus {
+	return KnowledgeDatasetStatus{
+		Source:   source,
+		Skipped:  skipped,
+		LoadedAt: loadedAt.Format(time.RFC3339),
+	}
+}
+
+// loadKnowledgeDiseases parses every disease guide; unreadable files are skipped and listed
+func loadKnowledgeDiseases(dir string) ([]*KnowledgeDisease, []string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read diseases directory: %w", err)
+	}
+
+	var diseases []*KnowledgeDisease
+	var skipped []string
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		loaded, err := utils.LoadJSONFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			skipped = append(skipped, file.Name())
+			continue
+		}
+		data, ok := loaded.(map[string]interface{})
+		if arr, isArray := loaded.([]interface{}); isArray && len(arr) > 0 {
+			data, ok = arr[0].(map[string]interface{})
+		}
+		if !ok {
+			skipped = append(skipped, file.Name())
+			continue
+		}
+
+		slug := strings.TrimSuffix(file.Name(), ".json")
+		disease := &KnowledgeDisease{
+			Slug:     slug,
+			Category: categorizeDisease(slug),
+			Data:     data,
+		}
+		if name, ok := data["disease_name"].(map[string]interface{}); ok {
+			disease.NameEn, _ = name["en"].(string)
+			disease.NameAr, _ = name["ar"].(string)
+		}
+		if description, ok := data["description"].(map[string]interface{}); ok {
+			disease.DescriptionEn, _ = description["en"].(string)
+		}
+		if nutrition, ok := data["nutritional_recommendations"].(map[string]interface{}); ok {
+			if nutritionEn, ok := nutrition["en"].(map[string]interface{}); ok {
+				if foods, ok := nutritionEn["beneficial_foods"].([]interface{}); ok {
+					for _, food := range foods {
+						if foodStr, ok := food.(string); ok {
+							disease.BeneficialFoods = append(disease.BeneficialFoods, foodStr)
+						}
+					}
+				}
+			}
+		}
+		diseases = append(diseases, disease)
+	}
+	return diseases, skipped, nil
+}
+
+// loadKnowledgeInjuries parses every injury guide and returns the raw text of each for full-text indexing
+func loadKnowledgeInjuries(dir string) ([]*KnowledgeInjury, []string, []string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read injuries directory: %w", err)
+	}
+
+	var injuries []*KnowledgeInjury
+	var contents []string
+	var skipped []string
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".js" {
+			continue
+		}
+
+		raw, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			skipped = append(skipped, file.Name())
+			continue
+		}
+
+		content := string(raw)
+		injuries = append(injuries, &KnowledgeInjury{
+			ID:       strings.TrimSuffix(file.Name(), ".js"),
+			File:     file.Name(),
+			Title:    extractInjuryTitle(content, file.Name()),
+			Category: categorizeInjury(file.Name(), content),
+			Data:     parseInjuryFile(content),
+		})
+		contents = append(contents, content)
+	}
+	return injuries, contents, skipped, nil
+}
+
+type knowledgeDrugs struct {
+	vitamins        []KnowledgeRecommendation
+	supplements     []KnowledgeRecommendation
+	weightLossDrugs []interface{}
+}
+
+// loadKnowledgeDrugs reads the vitamin, supplement and weight-loss drug sections of the drugs file
+func loadKnowledgeDrugs(path string) (*knowledgeDrugs, error) {
+	loaded, err := utils.LoadJSONFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vitamins and minerals data: %w", err)
+	}
+
+	// Multiple concatenated objects: the first holds the recommendations
+	data, ok := loaded.(map[string]interface{})
+	if objects, isArray := loaded.([]interface{}); isArray && len(objects) > 0 {
+		data, ok = objects[0].(map[string]interface{})
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid vitamins and minerals data format")
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vitamins and minerals data: %w", err)
+	}
+	var parsed struct {
+		NutritionalRecommendations struct {
+			VitaminRecommendations    []KnowledgeRecommendation `json:"VitaminRecommendations"`
+			SupplementRecommendations []KnowledgeRecommendation `json:"SupplementRecommendations"`
+		} `json:"NutritionalRecommendations"`
+		WeightLossDrugs []interface{} `json:"weight_loss_drugs"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse vitamins and minerals data: %w", err)
+	}
+
+	return &knowledgeDrugs{
+		vitamins:        parsed.NutritionalRecommendations.VitaminRecommendations,
+		supplements:     parsed.NutritionalRecommendations.SupplementRecommendations,
+		weightLossDrugs: parsed.WeightLossDrugs,
+	}, nil
+}
+
+func categorizeDisease(name string) string {
+	switch {
+	case strings.Contains(name, "diabetes") || strings.Contains(name, "sugar"):
+		return "Metabolic"
+	case strings.Contains(name, "heart") || strings.Contains(name, "cardio"):
+		return "Cardiovascular"
+	case strings.Contains(name, "brain") || strings.Contains(name, "neuro") || strings.Contains(name, "migraine") ||
+		strings.Contains(name, "dementia") || strings.Contains(name, "alzheimer"):
+		return "Neurological"
+	case strings.Contains(name, "cancer"):
+		return "Oncology"
+	case strings.Contains(name, "nutrition") || strings.Contains(name, "diet"):
+		return "Nutritional"
+	case strings.Contains(name, "child") || strings.Contains(name, "pregnanc"):
+		return "Life Stage"
+	case strings.Contains(name, "pain") || strings.Contains(name, "ache"):
+		return "Pain Management"
+	default:
+		return "General"
+	}
+}
+
+func extractInjuryTitle(content, filename string) map[string]string {
+	// Try to extract title from JSON content
+	lines := strings.Split(content, "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, `"title": {`) {
+			// Extract the title object, starting at its opening brace
+			startIndex := strings.Index(content, line)
+			if startIndex == -1 {
+				break
+			}
+			startIndex += strings.Index(line, "{")
+
+			// Find the end of the title object
+			braceCount := 0
+			endIndex := startIndex
+			for i, char := range content[startIndex:] {
+				if char == '{' {
+					braceCount++
+				} else if char == '}' {
+					braceCount--
+					if braceCount == 0 {
+						endIndex = startIndex + i + 1
+						break
+					}
+				}
+			}
+
+			titleStr := content[startIndex:endIndex]
+			var title map[string]string
+			if err := json.Unmarshal([]byte(titleStr), &title); err == nil {
+				return title
+			}
+			break
+		}
+	}
+
+	// Fallback to filename-based title
+	baseName := strings.TrimSuffix(filename, ".js")
+	parts := strings.Split(baseName, " ")
+	if len(parts) > 1 {
+		baseName = strings.Join(parts[1:], " ")
+	}
+
+	return map[string]string{
+		"english": baseName,
+		"arabic":  baseName,
+	}
+}
+
+func categorizeInjury(filename, content string) string {
+	filename = strings.ToLower(filename)
+	content = strings.ToLower(content)
+
+	// Categorize based on filename patterns and content
+	for _, part := range []struct{ keyword, category string }{
+		{"neck", "Neck Injuries"},
+		{"back", "Back Injuries"},
+		{"wrist", "Wrist Injuries"},
+		{"shoulder", "Shoulder Injuries"},
+		{"knee", "Knee Injuries"},
+		{"ankle", "Ankle Injuries"},
+		{"hip", "Hip Injuries"},
+	} {
+		if strings.Contains(filename, part.keyword) || strings.Contains(content, part.keyword) {
+			return part.category
+		}
+	}
+
+	return "Other Injuries"
+}
+
+func parseInjuryFile(content string) map[string]interface{} {
+	// Find JSON blocks in the file
+	lines := strings.Split(content, "\n")
+	inJSONBlock := false
+	jsonLines := []string{}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```json") {
+			inJSONBlock = true
+			continue
+		}
+		if strings.HasPrefix(line, "```") && inJSONBlock {
+			break
+		}
+		if inJSONBlock && line != "" {
+			jsonLines = append(jsonLines, line)
+		}
+	}
+
+	var injuryData map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.Join(jsonLines, "\n")), &injuryData); err != nil {
+		// If parsing fails, return a basic structure
+		return map[string]interface{}{
+			"title":       extractInjuryTitle(content, "unknown"),
+			"description": map[string]string{"english": "Failed to parse content", "arabic": "فشل في تحليل المحتوى"},
+			"error":       "JSON parsing error",
+		}
+	}
+
+	return injuryData
+}
+
+func categorizeDrug(genericName string) string {
+	genericName = strings.ToLower(genericName)
+
+	switch {
+	case strings.Contains(genericName, "glp") || strings.Contains(genericName, "liraglutide") ||
+		strings.Contains(genericName, "semaglutide") || strings.Contains(genericName, "dulaglutide") ||
+		strings.Contains(genericName, "tirzepatide"):
+		return "GLP-1 Receptor Agonists"
+	case strings.Contains(genericName, "orlistat"):
+		return "Lipase Inhibitors"
+	case strings.Contains(genericName, "phentermine") || strings.Contains(genericName, "topiramate"):
+		return "Appetite Suppressants"
+	case strings.Contains(genericName, "bupropion") || strings.Contains(genericName, "naltrexone"):
+		return "Combination Therapy"
+	case strings.Contains(genericName, "metformin"):
+		return "Insulin Sensitizers"
+	case strings.Contains(genericName, "setmelanotide"):
+		return "Melanocortin Agonists"
+	case strings.Contains(genericName, "retatrutide") || strings.Contains(genericName, "survodutide") ||
+		strings.Contains(genericName, "pemvidutide"):
+		return "Multi-Agonists"
+	case strings.Contains(genericName, "orforglipron"):
+		return "Oral GLP-1 Agonists"
+	case strings.Contains(genericName, "cagrilintide"):
+		return "Amylin Analogues"
+	default:
+		return "Other"
+	}
+}
+
+// knowledgeIndex is a bilingual inverted index from normalized terms to
+// record positions and the weight of the best field the term appeared in
+type knowledgeIndex struct {
+	postings map[string]map[int]float64
+	terms    []string
+}
+
+func newKnowledgeIndex() *knowledgeIndex {
+	return &knowledgeIndex{postings: map[string]map[int]float64{}}
+}
+
+func (x *knowledgeIndex) add(doc int, weight float64, texts ...string) {
+	for _, text := range texts {
+		for _, token := range tokenizeKnowledge(text) {
+			for _, term := range []string{token, stripArabicArticle(token)} {
+				docs, ok := x.postings[term]
+				if !ok {
+					docs = map[int]float64{}
+					x.postings[term] = docs
+				}
+				if docs[doc] < weight {
+					docs[doc] = weight
+				}
+			}
+		}
+	}
+}
+
+// finish sorts the term list used for prefix lookups; call it once all records are added
+func (x *knowledgeIndex) finish() {
+	x.terms = make([]string, 0, len(x.postings))
+	for term := range x.postings {
+		x.terms = append(x.terms, term)
+	}
+	sort.Strings(x.terms)
+}
+
+// search matches every query token as a term prefix, so "diab" finds "diabetes",
+// and ranks records by the summed field weights of their matches
+func (x *knowledgeIndex) search(query string) []KnowledgeHit {
+	tokens := tokenizeKnowledge(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var scores map[int]float64
+	for _, token := range tokens {
+		token = stripArabicArticle(token)
+		matched := map[int]float64{}
+		for i := sort.SearchStrings(x.terms, token); i < len(x.terms) && strings.HasPrefix(x.terms[i], token); i++ {
+			for doc, weight := range x.postings[x.terms[i]] {
+				if matched[doc] < weight {
+					matched[doc] = weight
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = matched
+			continue
+		}
+		for doc := range scores {
+			if weight, ok := matched[doc]; ok {
+				scores[doc] += weight
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+
+	hits := make([]KnowledgeHit, 0, len(scores))
+	for doc, score := range scores {
+		hits = append(hits, KnowledgeHit{Index: doc, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Index < hits[j].Index
+	})
+	return hits
+}
+
+// tokenizeKnowledge lower-cases and normalizes text and splits it into terms of two or more characters
+func tokenizeKnowledge(text string) []string {
+	fields := strings.FieldsFunc(normalizeArabic(strings.ToLower(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) >= 2 {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// normalizeArabic removes diacritics and tatweel and folds letter variants
+// that are commonly typed interchangeably
+func normalizeArabic(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'ً' && r <= 'ْ', r == 'ـ', r == 'ٰ':
+			return -1
+		case r == 'أ', r == 'إ', r == 'آ', r == 'ٱ':
+			return 'ا'
+		case r == 'ة':
+			return 'ه'
+		case r == 'ى', r == 'ئ':
+			return 'ي'
+		case r == 'ؤ':
+			return 'و'
+		}
+		return r
+	}, text)
+}
+
+// stripArabicArticle drops the definite article "ال" from longer Arabic terms
+func stripArabicArticle(token string) string {
+	if strings.HasPrefix(token, "ال") && len([]rune(token)) > 4 {
+		return strings.TrimPrefix(token, "ال")
+	}
+	return token
+}
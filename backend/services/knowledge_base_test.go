@@ -0,0 +1,127 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKnowledgeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func newTestKnowledgeDir(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	dataDir := filepath.Join(root, "nutrition data json")
+
+	writeKnowledgeFile(t, filepath.Join(root, "disease-nutrition-easy-json-files", "type-2-diabetes.json"), `{
+		"disease_name": {"en": "Type 2 Diabetes", "ar": "السكري من النوع الثاني"},
+		"description": {"en": "A chronic condition affecting blood sugar regulation."},
+		"nutritional_recommendations": {"en": {"beneficial_foods": ["Oats", "Lentils"]}}
+	}`)
+	writeKnowledgeFile(t, filepath.Join(root, "disease-nutrition-easy-json-files", "heart-failure.json"), `[{
+		"disease_name": {"en": "Heart Failure", "ar": "قصور القلب"},
+		"description": {"en": "Eat oats and limit sodium."}
+	}]`)
+	writeKnowledgeFile(t, filepath.Join(root, "disease-nutrition-easy-json-files", "broken.json"), `{not json`)
+	writeKnowledgeFile(t, filepath.Join(root, "injury easy trae json", "01 knee sprain.js"),
+		"# Knee\n```json\n{\n\"title\": {\"english\": \"Knee Sprain\", \"arabic\": \"التواء الركبة\"},\n\"rest\": \"ice the joint\"\n}\n```\n")
+	writeKnowledgeFile(t, filepath.Join(dataDir, DrugsNutritionFile), `{
+		"NutritionalRecommendations": {
+			"VitaminRecommendations": [{"name": {"en": "Vitamin D", "ar": "فيتامين د"}, "purpose": {"en": "Bone health"}}],
+			"SupplementRecommendations": [{"name": {"en": "Creatine", "ar": "كرياتين"}, "purpose": {"en": "Muscle strength"}}]
+		},
+		"weight_loss_drugs": [{"drug_name": {"generic": "Semaglutide"}}, {"drug_name": {"generic": "Orlistat"}}]
+	}`)
+	return dataDir
+}
+
+func TestKnowledgeBase_LoadAndSearch(t *testing.T) {
+	kb := NewKnowledgeBase(newTestKnowledgeDir(t))
+	snapshot := kb.Snapshot()
+
+	status := kb.Status()
+	assert.True(t, status.Healthy)
+	assert.Equal(t, 2, status.Datasets[KnowledgeDiseases].Records)
+	assert.Equal(t, []string{"broken.json"}, status.Datasets[KnowledgeDiseases].Skipped)
+	assert.Equal(t, 1, status.Datasets[KnowledgeInjuries].Records)
+	assert.Equal(t, 4, status.Datasets[KnowledgeVitaminsMinerals].Records)
+
+	diabetes, ok := snapshot.Disease("type-2-diabetes")
+	require.True(t, ok)
+	assert.Equal(t, "Metabolic", diabetes.Category)
+	assert.Equal(t, []string{"Oats", "Lentils"}, diabetes.BeneficialFoods)
+	assert.Equal(t, map[string][]string{"Metabolic": {"type-2-diabetes"}, "Cardiovascular": {"heart-failure"}},
+		snapshot.DiseaseCategories)
+
+	// Prefix match on the name outranks a description match; Arabic matches without the article
+	hits := snapshot.SearchDiseases("diab")
+	require.Len(t, hits, 1)
+	assert.Equal(t, 10.0, hits[0].Score)
+	hits = snapshot.SearchDiseases("oats")
+	require.Len(t, hits, 2)
+	assert.Equal(t, "heart-failure", snapshot.Diseases[hits[0].Index].Slug)
+	assert.Equal(t, 5.0, hits[0].Score)
+	assert.Equal(t, 3.0, hits[1].Score)
+	hits = snapshot.SearchDiseases("سكري")
+	require.Len(t, hits, 1)
+	assert.Equal(t, "type-2-diabetes", snapshot.Diseases[hits[0].Index].Slug)
+	assert.Empty(t, snapshot.SearchDiseases("oats kidney"))
+
+	injury, ok := snapshot.Injury("01 knee sprain")
+	require.True(t, ok)
+	assert.Equal(t, "Knee Injuries", injury.Category)
+	assert.Equal(t, "Knee Sprain", injury.Title["english"])
+	assert.Equal(t, "ice the joint", injury.Data["rest"])
+	assert.Len(t, snapshot.SearchInjuries("ركبه"), 1)
+	assert.Len(t, snapshot.SearchInjuries("ice"), 1)
+
+	require.Len(t, snapshot.SearchVitamins("bone"), 1)
+	require.Len(t, snapshot.SearchSupplements("كرياتين"), 1)
+	assert.Len(t, snapshot.DrugCategories["GLP-1 Receptor Agonists"], 1)
+	assert.Len(t, snapshot.DrugCategories["Lipase Inhibitors"], 1)
+}
+
+func TestKnowledgeBase_ReloadAndFailures(t *testing.T) {
+	dataDir := newTestKnowledgeDir(t)
+	kb := NewKnowledgeBase(dataDir)
+	first := kb.Snapshot()
+	assert.False(t, kb.reloadIfChanged())
+
+	// A new disease file is picked up and swapped in without touching the old snapshot
+	diseasesDir := filepath.Join(dataDir, diseasesDirName)
+	writeKnowledgeFile(t, filepath.Join(diseasesDir, "migraine.json"), `{"disease_name": {"en": "Migraine"}}`)
+	assert.True(t, kb.reloadIfChanged())
+	assert.Len(t, kb.Snapshot().Diseases, 3)
+	assert.Len(t, first.Diseases, 2)
+
+	// A broken drugs file keeps the previous vitamins, marked stale and unhealthy
+	drugsFile := filepath.Join(dataDir, DrugsNutritionFile)
+	writeKnowledgeFile(t, drugsFile, `[1, 2]`)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(drugsFile, future, future))
+	assert.True(t, kb.reloadIfChanged())
+	status := kb.Status()
+	assert.False(t, status.Healthy)
+	assert.True(t, status.Datasets[KnowledgeVitaminsMinerals].Stale)
+	assert.Equal(t, "invalid vitamins and minerals data format", status.Datasets[KnowledgeVitaminsMinerals].Error)
+	assert.True(t, kb.Snapshot().Available(KnowledgeVitaminsMinerals))
+	assert.Len(t, kb.Snapshot().SearchVitamins("vitamin"), 1)
+
+	// Without anything to fall back on, the dataset is unavailable
+	empty := NewKnowledgeBase(t.TempDir())
+	assert.False(t, empty.Status().Healthy)
+	assert.False(t, empty.Snapshot().Available(KnowledgeDiseases))
+	assert.Contains(t, empty.Status().Datasets[KnowledgeInjuries].Error, "failed to read injuries directory")
+
+	kb.Watch(10 * time.Millisecond)
+	kb.Close()
+	kb.Close()
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nutrition-platform/config"
+
+	"github.com/google/uuid"
+)
+
+// EmailMessage is a plain-text email sent by the platform
+type EmailMessage struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Mailer delivers transactional email
+type Mailer interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
+
+// NewMailer returns the mailer selected by cfg.Provider
+func NewMailer(cfg config.EmailConfig) (Mailer, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "outbox", "file", "log":
+		return NewOutboxMailer(cfg.OutboxPath)
+	default:
+		return nil, fmt.Errorf("unsupported email provider: %s", cfg.Provider)
+	}
+}
+
+// SMTPMailer sends email through an SMTP relay
+type SMTPMailer struct {
+	cfg config.EmailConfig
+}
+
+// NewSMTPMailer creates a new SMTPMailer instance
+func NewSMTPMailer(cfg config.EmailConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg}
+}
+
+// Send delivers msg using the configured SMTP server
+func (m *SMTPMailer) Send(ctx context.Context, msg EmailMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	from := mail.Address{Name: m.cfg.FromName, Address: m.cfg.FromEmail}
+	addr := m.cfg.SMTPHost + ":" + strconv.Itoa(m.cfg.SMTPPort)
+
+	var auth smtp.Auth
+	if m.cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", m.cfg.SMTPUser, m.cfg.SMTPPass, m.cfg.SMTPHost)
+	}
+
+	if err := smtp.SendMail(addr, auth, m.cfg.FromEmail, []string{msg.To}, buildMIMEMessage(from.String(), msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func buildMIMEMessage(from string, msg EmailMessage) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// OutboxMailer records messages instead of sending them. Each message is
+// written as a JSON file under dir (when set) and kept in memory so tests
+// can assert on what would have been delivered.
+type OutboxMailer struct {
+	mu       sync.Mutex
+	dir      string
+	messages []EmailMessage
+}
+
+// NewOutboxMailer creates an OutboxMailer writing to dir; an empty dir only logs
+func NewOutboxMailer(dir string) (*OutboxMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+	}
+	return &OutboxMailer{dir: dir}, nil
+}
+
+// Send records msg in the outbox
+func (m *OutboxMailer) Send(ctx context.Context, msg EmailMessage) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dir == "" {
+		log.Printf("outbox: email to %s: %s", msg.To, msg.Subject)
+	} else {
+		data, err := json.MarshalIndent(msg, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode email: %w", err)
+		}
+		name := msg.SentAt.Format("20060102T150405") + "-" + uuid.New().String() + ".json"
+		if err := os.WriteFile(filepath.Join(m.dir, name), data, 0600); err != nil {
+			return fmt.Errorf("failed to write email to outbox: %w", err)
+		}
+	}
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the messages recorded so far, oldest first
+func (m *OutboxMailer) Messages() []EmailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]EmailMessage, len(m.messages))
+	copy(messages, m.messages)
+	return messages
+}
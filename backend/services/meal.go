@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"strings"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 // Meal represents a meal entry
@@ -38,30 +36,6 @@ type MealData struct {
 
 const mealsFile = "backend/data/meals.json"
 
-// CreateMeal creates a new meal
-func CreateMeal(meal *Meal) error {
-	// Generate ID and timestamps
-	meal.ID = uuid.New().String()
-	meal.CreatedAt = time.Now()
-	meal.UpdatedAt = time.Now()
-
-	// Validate meal type
-	validMealTypes := map[string]bool{
-		"breakfast": true,
-		"lunch":     true,
-		"dinner":    true,
-		"snack":     true,
-	}
-	if !validMealTypes[meal.MealType] {
-		meal.MealType = "snack" // default
-	}
-
-	// Auto-detect halal status based on ingredients
-	meal.IsHalal = isHalalMeal(meal.Ingredients)
-
-	return AppendJSON(mealsFile, meal)
-}
-
 // GetMealsByUserID retrieves all meals for a specific user
 func GetMealsByUserID(userID string) ([]Meal, error) {
 	var data MealData
@@ -97,34 +71,6 @@ func GetMealByID(mealID string) (*Meal, error) {
 	return nil, fmt.Errorf("meal not found")
 }
 
-// UpdateMeal updates an existing meal
-func UpdateMeal(mealID string, updatedMeal *Meal) error {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
-	if err != nil {
-		return err
-	}
-
-	for i, meal := range data.Meals {
-		if meal.ID == mealID {
-			// Preserve original ID and created time
-			updatedMeal.ID = meal.ID
-			updatedMeal.CreatedAt = meal.CreatedAt
-			updatedMeal.UpdatedAt = time.Now()
-
-			// Auto-detect halal status
-			updatedMeal.IsHalal = isHalalMeal(updatedMeal.Ingredients)
-
-			data.Meals[i] = *updatedMeal
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(mealsFile, data)
-		}
-	}
-
-	return fmt.Errorf("meal not found")
-}
-
 // DeleteMeal deletes a meal
 func DeleteMeal(mealID string, userID string) error {
 	var data MealData
@@ -269,27 +215,6 @@ func SearchMeals(userID, query string) ([]Meal, error) {
 	return results, nil
 }
 
-// isHalalMeal checks if a meal is halal based on ingredients
-func isHalalMeal(ingredients []string) bool {
-	// List of non-halal ingredients
-	nonHalalIngredients := []string{
-		"pork", "ham", "bacon", "sausage", "pepperoni", "prosciutto",
-		"alcohol", "wine", "beer", "rum", "vodka", "whiskey",
-		"gelatin", "lard", "pancetta", "chorizo",
-	}
-
-	for _, ingredient := range ingredients {
-		ingredientLower := strings.ToLower(ingredient)
-		for _, nonHalal := range nonHalalIngredients {
-			if strings.Contains(ingredientLower, nonHalal) {
-				return false
-			}
-		}
-	}
-
-	return true
-}
-
 // GetMealStats returns statistics about user's meals
 func GetMealStats(userID string) (map[string]interface{}, error) {
 	meals, err := GetMealsByUserID(userID)
@@ -0,0 +1,862 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/google/uuid"
+)
+
+// Meal plan errors returned by MealPlanService
+var (
+	ErrMealPlanNotFound   = errors.New("meal plan not found")
+	ErrInvalidMealPlan    = errors.New("invalid meal plan request")
+	ErrNoEligibleRecipes  = errors.New("not enough recipes match the plan restrictions")
+	ErrUnknownRestriction = errors.New("unknown dietary restriction")
+)
+
+// DefaultRecipeCatalogPath is the bundled recipe catalog used alongside the
+// recipes table
+const DefaultRecipeCatalogPath = "data/recipes.json"
+
+const (
+	defaultMealPlanDays      = 7
+	defaultMealsPerDay       = 3
+	defaultMealPlanTolerance = 10.0
+	minPlanCalories          = 1200
+	minServings              = 0.5
+	maxServings              = 2.0
+)
+
+// balancedMacroRatio is used when neither the request nor its preferences
+// name one of NutritionPlanTypes
+var balancedMacroRatio = MacroRatio{Protein: 20, Carbs: 50, Fat: 30}
+
+// mealSlot is one meal of a planned day and its share of the daily calories
+type mealSlot struct {
+	mealType string
+	share    float64
+}
+
+var mealSlotsPerDay = map[int][]mealSlot{
+	1: {{"dinner", 1}},
+	2: {{"lunch", 0.5}, {"dinner", 0.5}},
+	3: {{"breakfast", 0.3}, {"lunch", 0.4}, {"dinner", 0.3}},
+	4: {{"breakfast", 0.25}, {"lunch", 0.35}, {"snack", 0.1}, {"dinner", 0.3}},
+	5: {{"breakfast", 0.25}, {"snack", 0.1}, {"lunch", 0.3}, {"snack", 0.1}, {"dinner", 0.25}},
+	6: {{"breakfast", 0.2}, {"snack", 0.1}, {"lunch", 0.3}, {"snack", 0.1}, {"dinner", 0.2}, {"snack", 0.1}},
+}
+
+// recipeCategoryMealTypes maps catalog categories (Arabic and English) to the
+// meal slots a recipe suits. Recipes without a known category suit any slot.
+var recipeCategoryMealTypes = map[string][]string{
+	"فطور":       {"breakfast"},
+	"إفطار":      {"breakfast"},
+	"breakfast":  {"breakfast"},
+	"غداء":       {"lunch", "dinner"},
+	"lunch":      {"lunch", "dinner"},
+	"عشاء":       {"dinner", "lunch"},
+	"dinner":     {"dinner", "lunch"},
+	"مقبلات":     {"snack", "lunch", "dinner"},
+	"appetizer":  {"snack", "lunch", "dinner"},
+	"وجبة خفيفة": {"snack"},
+	"snack":      {"snack"},
+}
+
+// planRecipe is a recipe candidate with its per-serving nutrients
+type planRecipe struct {
+	ID          string
+	Source      string
+	Name        string
+	MealTypes   []string
+	Tags        []string
+	Allergens   []string
+	Ingredients []string
+	IsHalal     bool
+	Calories    float64
+	Protein     float64
+	Carbs       float64
+	Fat         float64
+
+	// Substitutions that make the recipe halal for a user who opted in
+	Substitutions map[string]string
+
+	// AllergenWarnings are the cross-contact cautions for the user's allergies
+	AllergenWarnings []models.AllergenWarning
+}
+
+// restrictionRules decide whether a recipe satisfies a dietary restriction.
+// Tags and allergens are matched in English and Arabic.
+var restrictionRules = map[string]func(r *planRecipe) bool{
+	"vegetarian": func(r *planRecipe) bool {
+		return r.hasTag("vegetarian", "vegan", "نباتي", "نباتي صرف")
+	},
+	"vegan": func(r *planRecipe) bool {
+		return r.hasTag("vegan", "نباتي صرف")
+	},
+	"gluten_free": func(r *planRecipe) bool {
+		return r.hasTag("gluten_free", "gluten-free", "خالي من الجلوتين") && !r.hasAllergen("gluten", "جلوتين")
+	},
+	"dairy_free": func(r *planRecipe) bool {
+		return !r.hasAllergen("dairy", "milk", "منتجات الألبان", "حليب")
+	},
+	"nut_free": func(r *planRecipe) bool {
+		return !r.hasAllergen("nuts", "tree_nuts", "peanuts", "مكسرات", "فول سوداني")
+	},
+	"fish_free": func(r *planRecipe) bool {
+		return !r.hasAllergen("fish", "shellfish", "seafood", "أسماك", "مأكولات بحرية")
+	},
+	"egg_free": func(r *planRecipe) bool {
+		return !r.hasAllergen("eggs", "egg", "بيض")
+	},
+	"halal": func(r *planRecipe) bool {
+		return r.IsHalal && !r.hasAllergen("alcohol", "pork", "كحول", "لحم خنزير")
+	},
+	"alcohol_free": func(r *planRecipe) bool {
+		return !r.hasAllergen("alcohol", "كحول")
+	},
+}
+
+// MealPlanService generates meal plans from the recipe catalog and persists them
+type MealPlanService struct {
+	db          *sql.DB
+	plans       *NutritionPlanService
+	halal       *HalalService
+	allergies   *AllergyService
+	catalogPath string
+}
+
+// NewMealPlanService creates a new MealPlanService reading the bundled catalog
+// from catalogPath in addition to the recipes table. Plans restricted to halal
+// recipes are checked by halal with the user's preferences; with allergies
+// set, recipes containing one of the user's allergens are left out.
+func NewMealPlanService(db *sql.DB, catalogPath string, halal *HalalService, allergies *AllergyService) *MealPlanService {
+	return &MealPlanService{
+		db:          db,
+		plans:       NewNutritionPlanService(db),
+		halal:       halal,
+		allergies:   allergies,
+		catalogPath: catalogPath,
+	}
+}
+
+// GenerateMealPlan builds and stores a plan for userID. Each meal slot gets
+// the remaining calories of the day in proportion to its share, so the last
+// meal closes the gap; portions are scaled in quarter servings. A recipe is
+// never used twice in a day and recently used recipes are avoided.
+func (s *MealPlanService) GenerateMealPlan(ctx context.Context, userID string, req models.GenerateMealPlanRequest) (*models.MealPlan, error) {
+	planType, ratio, planName, err := resolvePlanType(req)
+	if err != nil {
+		return nil, err
+	}
+
+	restrictions, err := normalizeRestrictions(req.Restrictions)
+	if err != nil {
+		return nil, err
+	}
+
+	targetCalories, err := s.targetCalories(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	mealsPerDay := req.MealsPerDay
+	if mealsPerDay == 0 {
+		mealsPerDay = defaultMealsPerDay
+	}
+	slots, ok := mealSlotsPerDay[mealsPerDay]
+	if !ok {
+		return nil, fmt.Errorf("%w: meals_per_day must be between 1 and 6", ErrInvalidMealPlan)
+	}
+
+	duration := req.Duration
+	if duration == 0 {
+		duration = defaultMealPlanDays
+	}
+	tolerance := req.TolerancePercent
+	if tolerance == 0 {
+		tolerance = defaultMealPlanTolerance
+	}
+
+	startDate := time.Now().UTC()
+	if req.StartDate != "" {
+		startDate, err = time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: start_date must be formatted as YYYY-MM-DD", ErrInvalidMealPlan)
+		}
+	}
+	startDate = startOfDay(startDate)
+
+	recipes, err := s.loadRecipes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.halal != nil && containsFold(restrictions, []string{"halal"}) {
+		if err := s.checkHalal(ctx, userID, recipes); err != nil {
+			return nil, err
+		}
+	}
+	if s.allergies != nil {
+		if recipes, err = s.excludeAllergens(ctx, userID, recipes); err != nil {
+			return nil, err
+		}
+	}
+	eligible := filterRecipes(recipes, restrictions)
+	if len(eligible) < mealsPerDay {
+		return nil, fmt.Errorf("%w: %d recipes available for %d meals per day", ErrNoEligibleRecipes, len(eligible), mealsPerDay)
+	}
+
+	macros := s.plans.calculateMacroTargets(ratio, float64(targetCalories))
+	now := time.Now().UTC()
+	plan := &models.MealPlan{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		Name:             fmt.Sprintf("%d-day %s meal plan", duration, planName),
+		Description:      fmt.Sprintf("%d kcal per day, %d meals per day", targetCalories, mealsPerDay),
+		PlanType:         planType,
+		Goal:             req.Goal,
+		StartDate:        startDate.Format("2006-01-02"),
+		EndDate:          startDate.AddDate(0, 0, duration-1).Format("2006-01-02"),
+		TargetCalories:   targetCalories,
+		MealsPerDay:      mealsPerDay,
+		DurationDays:     duration,
+		TolerancePercent: tolerance,
+		Restrictions:     restrictions,
+		Macros:           macros,
+		IsActive:         true,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	usage := map[string]int{}
+	lastUsed := map[string]int{}
+	var totalCalories float64
+	for day := 1; day <= duration; day++ {
+		planDay := planMealDay(eligible, slots, float64(targetCalories), tolerance, macros, day, usage, lastUsed)
+		planDay.Date = startDate.AddDate(0, 0, day-1).Format("2006-01-02")
+		planDay.WithinTolerance = withinTolerance(planDay.Calories, float64(targetCalories), tolerance)
+
+		totalCalories += planDay.Calories
+		plan.TotalProtein += planDay.Protein
+		plan.TotalCarbs += planDay.Carbs
+		plan.TotalFat += planDay.Fat
+		plan.Days = append(plan.Days, planDay)
+	}
+	plan.TotalCalories = int(math.Round(totalCalories))
+	plan.TotalProtein = round1(plan.TotalProtein)
+	plan.TotalCarbs = round1(plan.TotalCarbs)
+	plan.TotalFat = round1(plan.TotalFat)
+
+	if err := s.saveMealPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetMealPlan returns one of userID's plans with its days and meals
+func (s *MealPlanService) GetMealPlan(ctx context.Context, userID, id string) (*models.MealPlan, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+mealPlanColumns+` FROM meal_plans WHERE id = ? AND user_id = ?`, id, userID)
+	plan, err := scanMealPlan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMealPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meal plan: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.id, d.day_number, d.date, d.calories, d.protein, d.carbs, d.fat, d.within_tolerance
+		FROM meal_plan_days d
+		WHERE d.meal_plan_id = ?
+		ORDER BY d.day_number`, plan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meal plan days: %w", err)
+	}
+	dayIndex := map[int64]int{}
+	for rows.Next() {
+		var (
+			dayID int64
+			day   models.MealPlanDay
+		)
+		if err := rows.Scan(&dayID, &day.DayNumber, &day.Date, &day.Calories, &day.Protein, &day.Carbs, &day.Fat, &day.WithinTolerance); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan meal plan day: %w", err)
+		}
+		dayIndex[dayID] = len(plan.Days)
+		plan.Days = append(plan.Days, day)
+	}
+	rows.Close()
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT m.meal_plan_day_id, m.meal_type, m.recipe_id, m.recipe_source, m.recipe_name,
+		       m.servings, m.calories, m.protein, m.carbs, m.fat, COALESCE(m.substitutions, '{}'),
+		       COALESCE(m.allergen_warnings, '[]')
+		FROM meal_plan_meals m
+		JOIN meal_plan_days d ON d.id = m.meal_plan_day_id
+		WHERE d.meal_plan_id = ?
+		ORDER BY d.day_number, m.meal_number`, plan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meal plan meals: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			dayID                           int64
+			meal                            models.PlannedMeal
+			substitutions, allergenWarnings string
+		)
+		if err := rows.Scan(&dayID, &meal.MealType, &meal.RecipeID, &meal.RecipeSource, &meal.Name,
+			&meal.Servings, &meal.Calories, &meal.Protein, &meal.Carbs, &meal.Fat, &substitutions,
+			&allergenWarnings); err != nil {
+			return nil, fmt.Errorf("failed to scan planned meal: %w", err)
+		}
+		if substitutions != "{}" {
+			_ = json.Unmarshal([]byte(substitutions), &meal.Substitutions)
+		}
+		if allergenWarnings != "[]" {
+			_ = json.Unmarshal([]byte(allergenWarnings), &meal.AllergenWarnings)
+		}
+		if i, ok := dayIndex[dayID]; ok {
+			plan.Days[i].Meals = append(plan.Days[i].Meals, meal)
+		}
+	}
+
+	return plan, rows.Err()
+}
+
+// ListMealPlans returns userID's plans, newest first, without their days
+func (s *MealPlanService) ListMealPlans(ctx context.Context, userID string) ([]*models.MealPlan, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+mealPlanColumns+` FROM meal_plans WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list meal plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []*models.MealPlan{}
+	for rows.Next() {
+		plan, err := scanMealPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan meal plan: %w", err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+const mealPlanColumns = `id, user_id, name, COALESCE(description, ''), COALESCE(plan_type, ''), COALESCE(goal, ''),
+	COALESCE(start_date, ''), COALESCE(end_date, ''), COALESCE(target_calories, 0), COALESCE(meals_per_day, 0),
+	COALESCE(duration_days, 0), COALESCE(tolerance_percent, 0), COALESCE(restrictions, '[]'),
+	COALESCE(total_calories, 0), COALESCE(total_protein, 0), COALESCE(total_carbs, 0), COALESCE(total_fat, 0),
+	is_active, created_at, updated_at`
+
+func scanMealPlan(row rowScanner) (*models.MealPlan, error) {
+	var (
+		plan         models.MealPlan
+		restrictions string
+	)
+	err := row.Scan(&plan.ID, &plan.UserID, &plan.Name, &plan.Description, &plan.PlanType, &plan.Goal,
+		&plan.StartDate, &plan.EndDate, &plan.TargetCalories, &plan.MealsPerDay, &plan.DurationDays,
+		&plan.TolerancePercent, &restrictions, &plan.TotalCalories, &plan.TotalProtein, &plan.TotalCarbs,
+		&plan.TotalFat, &plan.IsActive, &plan.CreatedAt, &plan.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(restrictions), &plan.Restrictions); err != nil || plan.Restrictions == nil {
+		plan.Restrictions = []string{}
+	}
+	ratio := balancedMacroRatio
+	if info, ok := NutritionPlanTypes[plan.PlanType]; ok {
+		ratio = info.MacroRatio
+	}
+	plan.Macros = (&NutritionPlanService{}).calculateMacroTargets(ratio, float64(plan.TargetCalories))
+	return &plan, nil
+}
+
+func (s *MealPlanService) saveMealPlan(ctx context.Context, plan *models.MealPlan) error {
+	restrictionsJSON, err := json.Marshal(plan.Restrictions)
+	if err != nil {
+		return fmt.Errorf("failed to encode restrictions: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO meal_plans (id, user_id, name, description, start_date, end_date, total_calories,
+			total_protein, total_carbs, total_fat, is_active, plan_type, goal, target_calories,
+			meals_per_day, duration_days, tolerance_percent, restrictions, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		plan.ID, plan.UserID, plan.Name, plan.Description, plan.StartDate, plan.EndDate, plan.TotalCalories,
+		plan.TotalProtein, plan.TotalCarbs, plan.TotalFat, plan.IsActive, plan.PlanType, plan.Goal,
+		plan.TargetCalories, plan.MealsPerDay, plan.DurationDays, plan.TolerancePercent, string(restrictionsJSON),
+		plan.CreatedAt, plan.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create meal plan: %w", err)
+	}
+
+	for _, day := range plan.Days {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO meal_plan_days (meal_plan_id, day_number, date, calories, protein, carbs, fat, within_tolerance)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			plan.ID, day.DayNumber, day.Date, day.Calories, day.Protein, day.Carbs, day.Fat, day.WithinTolerance)
+		if err != nil {
+			return fmt.Errorf("failed to create meal plan day: %w", err)
+		}
+		dayID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to create meal plan day: %w", err)
+		}
+
+		for i, meal := range day.Meals {
+			substitutions := []byte("{}")
+			if len(meal.Substitutions) > 0 {
+				if substitutions, err = json.Marshal(meal.Substitutions); err != nil {
+					return fmt.Errorf("failed to encode substitutions: %w", err)
+				}
+			}
+			allergenWarnings := []byte("[]")
+			if len(meal.AllergenWarnings) > 0 {
+				if allergenWarnings, err = json.Marshal(meal.AllergenWarnings); err != nil {
+					return fmt.Errorf("failed to encode allergen warnings: %w", err)
+				}
+			}
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO meal_plan_meals (meal_plan_day_id, meal_number, meal_type, recipe_id, recipe_source,
+					recipe_name, servings, calories, protein, carbs, fat, substitutions, allergen_warnings)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				dayID, i+1, meal.MealType, meal.RecipeID, meal.RecipeSource, meal.Name, meal.Servings,
+				meal.Calories, meal.Protein, meal.Carbs, meal.Fat, string(substitutions), string(allergenWarnings))
+			if err != nil {
+				return fmt.Errorf("failed to create planned meal: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// targetCalories returns the requested daily calories, or the user's TDEE
+// adjusted for the goal when none was requested
+func (s *MealPlanService) targetCalories(ctx context.Context, userID string, req models.GenerateMealPlanRequest) (int, error) {
+	if req.TargetCalories != nil {
+		return *req.TargetCalories, nil
+	}
+
+	var (
+		dateOfBirth, gender, activityLevel sql.NullString
+		height, weight                     sql.NullFloat64
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT date_of_birth, gender, height, weight, activity_level FROM users WHERE id = ?`, userID).
+		Scan(&dateOfBirth, &gender, &height, &weight, &activityLevel)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get user profile: %w", err)
+	}
+
+	age := ageOn(dateOfBirth.String, time.Now().UTC())
+	if age <= 0 || !height.Valid || !weight.Valid || height.Float64 <= 0 || weight.Float64 <= 0 {
+		return 0, fmt.Errorf("%w: target_calories is required when the profile has no age, height and weight", ErrInvalidMealPlan)
+	}
+
+	level := activityLevel.String
+	if level == "" {
+		level = "sedentary"
+	}
+	metrics := s.plans.calculateUserMetrics(&models.HealthAssessmentRequest{
+		Age:           age,
+		Gender:        gender.String,
+		Height:        height.Float64,
+		Weight:        weight.Float64,
+		ActivityLevel: level,
+	})
+	if metrics.TDEE <= 0 {
+		return 0, fmt.Errorf("%w: unknown activity level %q", ErrInvalidMealPlan, level)
+	}
+
+	target := metrics.TDEE
+	switch req.Goal {
+	case "weight_loss":
+		target -= 500
+	case "weight_gain", "muscle_gain":
+		target += 300
+	}
+	return int(math.Max(minPlanCalories, math.Round(target/10)*10)), nil
+}
+
+// planMealDay picks one recipe per slot. usage and lastUsed carry recipe use
+// across days. The last slot strongly prefers recipes that bring the day
+// within tolerance percent of dailyCalories.
+func planMealDay(recipes []*planRecipe, slots []mealSlot, dailyCalories, tolerance float64, macros models.MacroTargets, day int, usage, lastUsed map[string]int) models.MealPlanDay {
+	planDay := models.MealPlanDay{DayNumber: day}
+	usedToday := map[string]bool{}
+
+	for i, slot := range slots {
+		remainingShare := 0.0
+		for _, next := range slots[i:] {
+			remainingShare += next.share
+		}
+		fraction := slot.share / remainingShare
+		target := planRecipe{
+			Calories: math.Max(dailyCalories-planDay.Calories, 0) * fraction,
+			Protein:  math.Max(macros.ProteinGrams-planDay.Protein, 0) * fraction,
+			Carbs:    math.Max(macros.CarbsGrams-planDay.Carbs, 0) * fraction,
+			Fat:      math.Max(macros.FatGrams-planDay.Fat, 0) * fraction,
+		}
+
+		var (
+			best         *planRecipe
+			bestServings float64
+			bestScore    = math.Inf(1)
+		)
+		for _, recipe := range recipes {
+			if usedToday[recipe.key()] {
+				continue
+			}
+			servings := portionFor(recipe.Calories, target.Calories)
+			score := macroError(recipe, servings, &target)
+			if !recipe.suits(slot.mealType) {
+				score += 0.3
+			}
+			score += 0.25 * float64(usage[recipe.key()])
+			if last, ok := lastUsed[recipe.key()]; ok && day-last == 1 {
+				score++
+			}
+			if i == len(slots)-1 && !withinTolerance(planDay.Calories+recipe.Calories*servings, dailyCalories, tolerance) {
+				score += 10
+			}
+			if score < bestScore {
+				best, bestServings, bestScore = recipe, servings, score
+			}
+		}
+
+		meal := models.PlannedMeal{
+			MealType:      slot.mealType,
+			RecipeID:      best.ID,
+			RecipeSource:  best.Source,
+			Name:          best.Name,
+			Servings:      bestServings,
+			Calories:      round1(best.Calories * bestServings),
+			Protein:       round1(best.Protein * bestServings),
+			Carbs:         round1(best.Carbs * bestServings),
+			Fat:           round1(best.Fat * bestServings),
+			Substitutions: best.Substitutions,
+
+			AllergenWarnings: best.AllergenWarnings,
+		}
+		planDay.Meals = append(planDay.Meals, meal)
+		planDay.Calories += meal.Calories
+		planDay.Protein += meal.Protein
+		planDay.Carbs += meal.Carbs
+		planDay.Fat += meal.Fat
+
+		usedToday[best.key()] = true
+		usage[best.key()]++
+		lastUsed[best.key()] = day
+	}
+
+	planDay.Calories = round1(planDay.Calories)
+	planDay.Protein = round1(planDay.Protein)
+	planDay.Carbs = round1(planDay.Carbs)
+	planDay.Fat = round1(planDay.Fat)
+	return planDay
+}
+
+func withinTolerance(actual, target, tolerance float64) bool {
+	return math.Abs(actual-target)/target*100 <= tolerance
+}
+
+// portionFor returns the number of servings, in quarters, closest to target
+func portionFor(caloriesPerServing, target float64) float64 {
+	if caloriesPerServing <= 0 {
+		return 1
+	}
+	servings := math.Round(target/caloriesPerServing*4) / 4
+	return math.Min(math.Max(servings, minServings), maxServings)
+}
+
+// macroError scores how far servings of recipe are from target: the relative
+// calorie error plus half the mean relative macro error
+func macroError(recipe *planRecipe, servings float64, target *planRecipe) float64 {
+	relative := func(actual, want float64) float64 {
+		if want <= 0 {
+			return actual / 100
+		}
+		return math.Abs(actual-want) / want
+	}
+
+	calories := relative(recipe.Calories*servings, target.Calories)
+	macros := (relative(recipe.Protein*servings, target.Protein) +
+		relative(recipe.Carbs*servings, target.Carbs) +
+		relative(recipe.Fat*servings, target.Fat)) / 3
+	return calories + macros/2
+}
+
+func resolvePlanType(req models.GenerateMealPlanRequest) (string, MacroRatio, string, error) {
+	if req.PlanType != "" {
+		info, ok := NutritionPlanTypes[req.PlanType]
+		if !ok {
+			return "", MacroRatio{}, "", fmt.Errorf("%w: unknown plan_type %q", ErrInvalidMealPlan, req.PlanType)
+		}
+		return req.PlanType, info.MacroRatio, info.Name, nil
+	}
+	for _, preference := range req.Preferences {
+		if info, ok := NutritionPlanTypes[preference]; ok {
+			return preference, info.MacroRatio, info.Name, nil
+		}
+	}
+	return "balanced", balancedMacroRatio, "Balanced", nil
+}
+
+func normalizeRestrictions(restrictions []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, restriction := range restrictions {
+		key := strings.ToLower(strings.TrimSpace(restriction))
+		key = strings.NewReplacer("-", "_", " ", "_").Replace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		if _, ok := restrictionRules[key]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRestriction, restriction)
+		}
+		seen[key] = true
+		normalized = append(normalized, key)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// checkHalal runs the compliance check on every recipe with userID's halal
+// preferences. Failing recipes lose IsHalal; with substitutions opted in a
+// recipe that is halal once substituted keeps it and carries them.
+func (s *MealPlanService) checkHalal(ctx context.Context, userID string, recipes []*planRecipe) error {
+	prefs, err := s.halal.Preferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, recipe := range recipes {
+		result, err := s.halal.Compliance().CheckCompliance(recipe.Ingredients, "", *prefs)
+		if err != nil {
+			return fmt.Errorf("failed to check recipe %s: %w", recipe.key(), err)
+		}
+		recipe.IsHalal = recipe.IsHalal && result.IsCompliant
+		if recipe.IsHalal && result.SubstitutionsApplied {
+			recipe.Substitutions = result.ModifiedRecipe.Substitutions
+		}
+	}
+	return nil
+}
+
+// excludeAllergens drops the recipes containing one of userID's allergens,
+// judged by their ingredients and declared allergens. The others keep their
+// cross-contact cautions.
+func (s *MealPlanService) excludeAllergens(ctx context.Context, userID string, recipes []*planRecipe) ([]*planRecipe, error) {
+	allergies, err := s.allergies.Allergies(ctx, userID)
+	if err != nil || len(allergies) == 0 {
+		return recipes, err
+	}
+
+	safe := make([]*planRecipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		check := s.allergies.Registry().Check(allergies, append(append([]string{recipe.Name}, recipe.Ingredients...), recipe.Allergens...))
+		if !check.Safe {
+			continue
+		}
+		if len(check.Warnings) > 0 {
+			recipe.AllergenWarnings = check.Warnings
+		}
+		safe = append(safe, recipe)
+	}
+	return safe, nil
+}
+
+func filterRecipes(recipes []*planRecipe, restrictions []string) []*planRecipe {
+	eligible := []*planRecipe{}
+	for _, recipe := range recipes {
+		if recipe.Calories <= 0 {
+			continue
+		}
+		ok := true
+		for _, restriction := range restrictions {
+			if !restrictionRules[restriction](recipe) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			eligible = append(eligible, recipe)
+		}
+	}
+	return eligible
+}
+
+// loadRecipes reads the recipes table followed by the bundled catalog. A
+// missing catalog file is not an error.
+func (s *MealPlanService) loadRecipes(ctx context.Context) ([]*planRecipe, error) {
+	recipes := []*planRecipe{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, COALESCE(nutrition_per_serving, '{}'), COALESCE(dietary_tags, '[]'),
+		       COALESCE(allergens, '[]'), COALESCE(ingredients, '[]'), COALESCE(is_halal, 1)
+		FROM recipes
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recipes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			recipe                                    = &planRecipe{Source: "database"}
+			nutritionJSON, tags, allergy, ingredients string
+			nutrition                                 models.NutritionInfo
+		)
+		if err := rows.Scan(&recipe.ID, &recipe.Name, &nutritionJSON, &tags, &allergy, &ingredients, &recipe.IsHalal); err != nil {
+			return nil, fmt.Errorf("failed to scan recipe: %w", err)
+		}
+		// Malformed JSON leaves the recipe without nutrients, which excludes it
+		_ = json.Unmarshal([]byte(nutritionJSON), &nutrition)
+		_ = json.Unmarshal([]byte(tags), &recipe.Tags)
+		_ = json.Unmarshal([]byte(allergy), &recipe.Allergens)
+		recipe.Ingredients = recipeIngredientNames(ingredients)
+		recipe.Calories = nutrition.Calories
+		recipe.Protein = nutrition.Protein
+		recipe.Carbs = nutrition.Carbohydrates
+		recipe.Fat = nutrition.Fat
+		for _, tag := range recipe.Tags {
+			recipe.MealTypes = append(recipe.MealTypes, recipeCategoryMealTypes[strings.ToLower(tag)]...)
+		}
+		recipes = append(recipes, recipe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load recipes: %w", err)
+	}
+
+	catalog, err := loadRecipeCatalog(s.catalogPath)
+	if err != nil {
+		return nil, err
+	}
+	return append(recipes, catalog...), nil
+}
+
+func loadRecipeCatalog(path string) ([]*planRecipe, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipe catalog: %w", err)
+	}
+
+	var catalog struct {
+		Recipes []struct {
+			ID                  string   `json:"id"`
+			Name                string   `json:"name"`
+			Category            string   `json:"category"`
+			CaloriesPerServing  float64  `json:"calories_per_serving"`
+			ProteinPerServing   float64  `json:"protein_per_serving"`
+			CarbsPerServing     float64  `json:"carbs_per_serving"`
+			FatPerServing       float64  `json:"fat_per_serving"`
+			DietaryRestrictions []string `json:"dietary_restrictions"`
+			Allergens           []string `json:"allergens"`
+			Ingredients         []struct {
+				Name string `json:"name"`
+			} `json:"ingredients"`
+		} `json:"recipes"`
+	}
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse recipe catalog: %w", err)
+	}
+
+	recipes := make([]*planRecipe, 0, len(catalog.Recipes))
+	for _, r := range catalog.Recipes {
+		ingredients := make([]string, 0, len(r.Ingredients))
+		for _, ingredient := range r.Ingredients {
+			ingredients = append(ingredients, ingredient.Name)
+		}
+		recipes = append(recipes, &planRecipe{
+			ID:          r.ID,
+			Source:      "catalog",
+			Name:        r.Name,
+			MealTypes:   recipeCategoryMealTypes[strings.ToLower(r.Category)],
+			Tags:        r.DietaryRestrictions,
+			Allergens:   r.Allergens,
+			Ingredients: ingredients,
+			IsHalal:     true,
+			Calories:    r.CaloriesPerServing,
+			Protein:     r.ProteinPerServing,
+			Carbs:       r.CarbsPerServing,
+			Fat:         r.FatPerServing,
+		})
+	}
+	return recipes, nil
+}
+
+// key identifies a recipe across the recipes table and the catalog
+func (r *planRecipe) key() string {
+	return r.Source + ":" + r.ID
+}
+
+func (r *planRecipe) suits(mealType string) bool {
+	if len(r.MealTypes) == 0 {
+		return true
+	}
+	for _, t := range r.MealTypes {
+		if t == mealType {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *planRecipe) hasTag(tags ...string) bool {
+	return containsFold(r.Tags, tags)
+}
+
+func (r *planRecipe) hasAllergen(allergens ...string) bool {
+	return containsFold(r.Allergens, allergens)
+}
+
+func containsFold(values, candidates []string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if strings.EqualFold(strings.TrimSpace(value), candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ageOn returns the age in whole years on day of someone born on dateOfBirth
+// (YYYY-MM-DD, optionally followed by a time), or 0 if it cannot be parsed
+func ageOn(dateOfBirth string, day time.Time) int {
+	if len(dateOfBirth) < 10 {
+		return 0
+	}
+	born, err := time.Parse("2006-01-02", dateOfBirth[:10])
+	if err != nil {
+		return 0
+	}
+	age := day.Year() - born.Year()
+	if day.Month() < born.Month() || (day.Month() == born.Month() && day.Day() < born.Day()) {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMealPlanService(t *testing.T) (*MealPlanService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "planner@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = users.db.Exec(`INSERT INTO recipes (id, name, nutrition_per_serving, dietary_tags, allergens)
+		VALUES ('oat-bowl', 'Oat bowl', '{"calories": 350, "protein": 14, "carbohydrates": 55, "fat": 8}',
+		        '["breakfast", "vegetarian"]', '["gluten", "dairy"]')`)
+	require.NoError(t, err)
+
+	return NewMealPlanService(users.db, "../"+DefaultRecipeCatalogPath, nil, nil), user.ID
+}
+
+func TestMealPlanService_GenerateMealPlan(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestMealPlanService(t)
+
+	target := 1800
+	plan, err := svc.GenerateMealPlan(ctx, userID, models.GenerateMealPlanRequest{
+		TargetCalories: &target,
+		PlanType:       "mediterranean",
+		MealsPerDay:    3,
+		Duration:       5,
+		StartDate:      "2026-03-02",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-06", plan.EndDate)
+	assert.Equal(t, 15.0, plan.Macros.ProteinPercent)
+	require.Len(t, plan.Days, 5)
+
+	var total float64
+	for _, day := range plan.Days {
+		require.Len(t, day.Meals, 3)
+		assert.Equal(t, "breakfast", day.Meals[0].MealType)
+
+		seen := map[string]bool{}
+		var calories float64
+		for _, meal := range day.Meals {
+			key := meal.RecipeSource + ":" + meal.RecipeID
+			assert.False(t, seen[key], "recipe repeated on day %d", day.DayNumber)
+			seen[key] = true
+			calories += meal.Calories
+		}
+		assert.InDelta(t, calories, day.Calories, 0.1)
+		assert.True(t, day.WithinTolerance, "day %d has %.0f kcal", day.DayNumber, day.Calories)
+		total += day.Calories
+	}
+	assert.InDelta(t, total, float64(plan.TotalCalories), 1)
+
+	// Consecutive days do not start with the same recipe when alternatives exist
+	assert.NotEqual(t, plan.Days[0].Meals[1].RecipeID, plan.Days[1].Meals[1].RecipeID)
+
+	stored, err := svc.GetMealPlan(ctx, userID, plan.ID)
+	require.NoError(t, err)
+	assert.Equal(t, plan.Days, stored.Days)
+	assert.Equal(t, plan.TargetCalories, stored.TargetCalories)
+
+	plans, err := svc.ListMealPlans(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	assert.Empty(t, plans[0].Days)
+
+	_, err = svc.GetMealPlan(ctx, "someone-else", plan.ID)
+	assert.ErrorIs(t, err, ErrMealPlanNotFound)
+}
+
+func TestMealPlanService_Restrictions(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestMealPlanService(t)
+	target := 2000
+
+	plan, err := svc.GenerateMealPlan(ctx, userID, models.GenerateMealPlanRequest{
+		TargetCalories: &target,
+		MealsPerDay:    2,
+		Duration:       3,
+		Restrictions:   []string{"Dairy-Free", "halal"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dairy_free", "halal"}, plan.Restrictions)
+	for _, day := range plan.Days {
+		for _, meal := range day.Meals {
+			// Only the kabsa, tabbouleh and salmon sushi are free of dairy and alcohol
+			assert.Contains(t, []string{"1", "2", "5"}, meal.RecipeID)
+		}
+	}
+
+	_, err = svc.GenerateMealPlan(ctx, userID, models.GenerateMealPlanRequest{
+		TargetCalories: &target,
+		MealsPerDay:    4,
+		Restrictions:   []string{"vegetarian"},
+	})
+	assert.ErrorIs(t, err, ErrNoEligibleRecipes)
+
+	_, err = svc.GenerateMealPlan(ctx, userID, models.GenerateMealPlanRequest{
+		TargetCalories: &target,
+		Restrictions:   []string{"carnivore"},
+	})
+	assert.ErrorIs(t, err, ErrUnknownRestriction)
+}
+
+func TestMealPlanService_TargetFromProfile(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestMealPlanService(t)
+
+	_, err := svc.GenerateMealPlan(ctx, userID, models.GenerateMealPlanRequest{Duration: 1})
+	assert.ErrorIs(t, err, ErrInvalidMealPlan)
+
+	_, err = svc.db.Exec(`UPDATE users SET date_of_birth = '1990-06-15', gender = 'male', height = 180,
+		weight = 80, activity_level = 'moderate' WHERE id = ?`, userID)
+	require.NoError(t, err)
+
+	plan, err := svc.GenerateMealPlan(ctx, userID, models.GenerateMealPlanRequest{
+		Goal:        "weight_loss",
+		Preferences: []string{"spicy", "low_carb"},
+		Duration:    1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "low_carb", plan.PlanType)
+	// Mifflin-St Jeor BMR x1.55 for moderate activity, minus 500 kcal
+	age := ageOn("1990-06-15", time.Now().UTC())
+	bmr := 10*80 + 6.25*180 - 5*float64(age) + 5
+	assert.InDelta(t, bmr*1.55-500, plan.TargetCalories, 5)
+}
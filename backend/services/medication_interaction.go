@@ -0,0 +1,802 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/utils"
+)
+
+// DrugsNutritionFile is the drug reference file read from the nutrition data
+// directory
+const DrugsNutritionFile = "drugs-and-nutrition.json"
+
+// Interaction severities, most serious first
+const (
+	SeverityMajor    = "major"
+	SeverityModerate = "moderate"
+	SeverityMinor    = "minor"
+	SeverityNone     = "none"
+)
+
+var severityRank = map[string]int{
+	SeverityMajor:    3,
+	SeverityModerate: 2,
+	SeverityMinor:    1,
+	SeverityNone:     0,
+}
+
+// severityKeywords classify interaction text; the first matching severity wins
+var severityKeywords = []struct {
+	severity string
+	keywords []string
+}{
+	{SeverityMajor, []string{"avoid", "contraindicated", "do not", "serotonin syndrome", "bleeding", "toxicity",
+		"hypertensive", "fatal", "life threatening", "severe", "arrhythmia", "seizure"}},
+	{SeverityModerate, []string{"reduce", "decrease", "increase", "absorption", "levels", "efficacy", "monitor",
+		"apart", "deficiency", "hypokalemia", "hyperkalemia", "induce", "inhibit", "bind"}},
+}
+
+var defaultManagement = map[string]string{
+	SeverityMajor:    "Avoid this combination unless a clinician confirms it is safe",
+	SeverityModerate: "Monitor and separate doses as advised by a pharmacist",
+	SeverityMinor:    "No action usually needed; mention it at the next medication review",
+}
+
+// foodTerms mark the subject of an interaction note as a food or drink
+var foodTerms = []string{"meal", "food", "diet", "alcohol", "grapefruit", "caffeine", "coffee", "tea", "juice",
+	"dairy", "milk", "fiber", "fibre", "protein", "fat", "sugar", "salt", "tyramine", "leafy"}
+
+// supplementAliases map ingredient names to the nutrient they supply
+var supplementAliases = map[string]string{
+	"ferrous":         "iron",
+	"cholecalciferol": "vitamin d",
+	"ergocalciferol":  "vitamin d",
+	"cyanocobalamin":  "vitamin b12",
+	"methylcobalamin": "vitamin b12",
+	"cobalamin":       "vitamin b12",
+	"folic acid":      "folate",
+	"ascorbic acid":   "vitamin c",
+	"retinol":         "vitamin a",
+	"tocopherol":      "vitamin e",
+	"phylloquinone":   "vitamin k",
+	"menaquinone":     "vitamin k",
+	"fish oil":        "omega 3",
+}
+
+var minerals = []string{"calcium", "iron", "magnesium", "zinc", "potassium", "selenium", "iodine", "copper", "chromium"}
+
+var (
+	vitaminListPattern  = regexp.MustCompile(`\bvitamins?((?:\s+(?:and\s+)?[a-k]\d{0,2}\b)+)`)
+	vitaminNamePattern  = regexp.MustCompile(`\bvitamin ([a-k])\d{1,2}\b`)
+	managementPattern   = regexp.MustCompile(`(?i)(take \d+\s*hours? apart|\d+\s*hours? (?:before|after|apart)[^.;)]*|avoid[^.;)]*)`)
+	interactionNameTidy = strings.NewReplacer("®", "", "™", "", "’", "", "'", "", ".", "")
+	interactionNameGaps = strings.NewReplacer("-", " ", "_", " ", "/", " ", "(", " ", ")", " ", ",", " ", ":", " ", ";", " ", "→", " ")
+)
+
+// drugEntry is one drug or drug class from the reference data, or a row of the
+// medications table, with the interaction notes that apply to it
+type drugEntry struct {
+	display      string
+	names        []string
+	class        string
+	interactions []string // "<other drug, food or supplement> (<effect>)"
+	dietNotes    []string // "<food or supplement>: <effect>"
+	vitaminNotes []string // "<drug or nutrient>: <effect>"
+	foodNotes    []string // "<food>: <effect>" from the medications table
+	regimen      string
+}
+
+// supplementRule is a drug class/supplement pair from the reference summary
+type supplementRule struct {
+	drugClass   string
+	supplements []string
+	interaction string
+	management  string
+}
+
+// drugKnowledge is the parsed drug reference data
+type drugKnowledge struct {
+	entries         []*drugEntry
+	rules           []supplementRule
+	supplementUsage map[string]string
+}
+
+type userDrug struct {
+	display string
+	names   []string
+	class   string
+	entries []*drugEntry
+}
+
+type userSupplement struct {
+	display string
+	keys    []string
+}
+
+// MedicationInteractionService checks a user's medications and supplements
+// against each other and against foods
+type MedicationInteractionService struct {
+	db      *sql.DB
+	dataDir string
+}
+
+// NewMedicationInteractionService creates a new MedicationInteractionService
+// reading DrugsNutritionFile from dataDir
+func NewMedicationInteractionService(db *sql.DB, dataDir string) *MedicationInteractionService {
+	return &MedicationInteractionService{db: db, dataDir: dataDir}
+}
+
+// CheckInteractions returns the severity-ranked drug–drug, drug–food and
+// drug–supplement interactions of userID's active user_medications and
+// user_supplements, with food-timing advice. Interactions come from the drug
+// reference file, the medications table and the supplement usage notes stored
+// in drug_nutrition_interactions.
+func (s *MedicationInteractionService) CheckInteractions(ctx context.Context, userID string) (*models.MedicationInteractionCheck, error) {
+	check := &models.MedicationInteractionCheck{
+		UserID:                 userID,
+		UserMedications:        []string{},
+		UserSupplements:        []string{},
+		Interactions:           []models.MedicationInteraction{},
+		FoodInteractions:       []models.FoodInteraction{},
+		SupplementInteractions: []models.SupplementInteraction{},
+		TimingAdvice:           []models.TimingAdvice{},
+		Warnings:               []string{},
+		Recommendations:        []string{},
+		CheckedAt:              time.Now().UTC(),
+	}
+
+	knowledge, err := s.loadDrugKnowledge(ctx)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err != nil {
+		check.Warnings = append(check.Warnings, "Drug reference data is unavailable; only the medications table was checked")
+	}
+
+	drugs, err := s.loadUserDrugs(ctx, userID, knowledge)
+	if err != nil {
+		return nil, err
+	}
+	supplements, err := loadActiveSupplements(ctx, s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, drug := range drugs {
+		check.UserMedications = append(check.UserMedications, drug.display)
+		if len(drug.entries) == 0 {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("No interaction data found for %s", drug.display))
+		}
+	}
+	for _, supplement := range supplements {
+		check.UserSupplements = append(check.UserSupplements, supplement.display)
+	}
+
+	seen := map[string]bool{}
+	once := func(parts ...string) bool {
+		key := strings.Join(parts, "|")
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		return true
+	}
+	addDrugPair := func(a, b *userDrug, note, management string) {
+		first, second := a.display, b.display
+		if second < first {
+			first, second = second, first
+		}
+		_, effect := splitInteraction(note)
+		if !once("drug", first, second, normalizeDrugName(effect)) {
+			return
+		}
+		severity := classifySeverity(note + " " + management)
+		check.Interactions = append(check.Interactions, models.MedicationInteraction{
+			Medication1:     first,
+			Medication2:     second,
+			InteractionType: severity,
+			Description:     effect,
+			Severity:        severity,
+			Management:      interactionManagement(note, management, severity),
+		})
+	}
+	addSupplement := func(drug *userDrug, supplement *userSupplement, note, management string) {
+		_, effect := splitInteraction(note)
+		if !once("supplement", drug.display, supplement.display, normalizeDrugName(effect)) {
+			return
+		}
+		severity := classifySeverity(note + " " + management)
+		check.SupplementInteractions = append(check.SupplementInteractions, models.SupplementInteraction{
+			Medication:     drug.display,
+			Supplement:     supplement.display,
+			Effect:         effect,
+			Severity:       severity,
+			Recommendation: interactionManagement(note, management, severity),
+		})
+	}
+	addFood := func(drug *userDrug, food, effect string) {
+		if !once("food", drug.display, normalizeDrugName(food), normalizeDrugName(effect)) {
+			return
+		}
+		severity := classifySeverity(food + " " + effect)
+		check.FoodInteractions = append(check.FoodInteractions, models.FoodInteraction{
+			Medication:     drug.display,
+			Food:           strings.TrimSpace(food),
+			Effect:         strings.TrimSpace(effect),
+			Severity:       severity,
+			Recommendation: interactionManagement(effect, "", severity),
+		})
+	}
+
+	// otherDrugNamed returns another of the user's drugs that subject names
+	otherDrugNamed := func(self *userDrug, subject string) *userDrug {
+		for _, other := range drugs {
+			if other != self && namesMatch(other.names, []string{subject}) {
+				return other
+			}
+		}
+		return nil
+	}
+	supplementNamed := func(text string) []*userSupplement {
+		expanded := expandVitaminLists(normalizeDrugName(text))
+		var matched []*userSupplement
+		for _, supplement := range supplements {
+			for _, key := range supplement.keys {
+				if mentionsName(expanded, key) {
+					matched = append(matched, supplement)
+					break
+				}
+			}
+		}
+		return matched
+	}
+	// aboutOtherDrug reports whether a class-level note names a drug that is
+	// not one of the user's
+	aboutOtherDrug := func(drug *userDrug, text string) bool {
+		normalized := normalizeDrugName(text)
+		for _, entry := range knowledge.entries {
+			for _, name := range entry.names {
+				if mentionsName(normalized, name) && !namesMatch(drug.names, []string{name}) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, drug := range drugs {
+		for _, entry := range drug.entries {
+			for _, note := range entry.interactions {
+				subject, effect := splitInteraction(note)
+				if other := otherDrugNamed(drug, subject); other != nil {
+					addDrugPair(drug, other, note, "")
+					continue
+				}
+				if matched := supplementNamed(subject); len(matched) > 0 {
+					for _, supplement := range matched {
+						addSupplement(drug, supplement, note, "")
+					}
+					continue
+				}
+				if isFoodSubject(subject) {
+					addFood(drug, subject, effect)
+				}
+			}
+
+			for _, note := range entry.foodNotes {
+				subject, effect := splitInteraction(note)
+				addFood(drug, subject, effect)
+			}
+
+			for _, note := range entry.dietNotes {
+				subject, effect := splitInteraction(note)
+				if aboutOtherDrug(drug, effect) {
+					continue
+				}
+				if other := otherDrugNamed(drug, subject); other != nil {
+					addDrugPair(drug, other, note, "")
+				} else if matched := supplementNamed(subject); len(matched) > 0 {
+					for _, supplement := range matched {
+						addSupplement(drug, supplement, note, "")
+					}
+				} else if isFoodSubject(subject) {
+					addFood(drug, subject, effect)
+				}
+			}
+
+			for _, note := range entry.vitaminNotes {
+				subject, effect := splitInteraction(note)
+				// The subject is either a drug of the class or the affected nutrients
+				if mentionsAnyName(subject, entry.names) && !namesMatch(drug.names, []string{subject}) {
+					continue
+				}
+				if matched := supplementNamed(note); len(matched) > 0 {
+					for _, supplement := range matched {
+						addSupplement(drug, supplement, note, "")
+					}
+				} else if strings.Contains(normalizeDrugName(note), "deficien") || strings.Contains(normalizeDrugName(effect), "reduce") {
+					check.Recommendations = append(check.Recommendations, fmt.Sprintf("%s: %s", drug.display, strings.TrimSpace(note)))
+				}
+			}
+
+			if entry.regimen != "" && once("timing", drug.display, entry.regimen) {
+				check.TimingAdvice = append(check.TimingAdvice, models.TimingAdvice{
+					Item:   drug.display,
+					Type:   "medication",
+					Advice: entry.regimen,
+				})
+			}
+		}
+
+		for _, rule := range knowledge.rules {
+			if !drug.matchesClass(rule.drugClass) {
+				continue
+			}
+			var matched []*userSupplement
+			for _, name := range rule.supplements {
+				matched = append(matched, supplementNamed(name)...)
+			}
+			for _, supplement := range matched {
+				addSupplement(drug, supplement, rule.interaction, rule.management)
+			}
+			if len(matched) == 0 && strings.Contains(normalizeDrugName(rule.interaction), "deficien") &&
+				once("recommendation", drug.display, rule.interaction) {
+				check.Recommendations = append(check.Recommendations,
+					fmt.Sprintf("%s may cause %s deficiency: %s", drug.display, strings.Join(rule.supplements, "/"), rule.management))
+			}
+		}
+	}
+
+	for _, supplement := range supplements {
+		for _, key := range supplement.keys {
+			if usage, ok := knowledge.supplementUsage[key]; ok {
+				check.TimingAdvice = append(check.TimingAdvice, models.TimingAdvice{
+					Item:   supplement.display,
+					Type:   "supplement",
+					Advice: usage,
+				})
+				break
+			}
+		}
+	}
+
+	rankInteractions(check)
+	return check, nil
+}
+
+// rankInteractions sorts every list most severe first and fills in the
+// highest severity and the warnings for major interactions
+func rankInteractions(check *models.MedicationInteractionCheck) {
+	sort.SliceStable(check.Interactions, func(i, j int) bool {
+		a, b := check.Interactions[i], check.Interactions[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		return a.Medication1+a.Medication2 < b.Medication1+b.Medication2
+	})
+	sort.SliceStable(check.SupplementInteractions, func(i, j int) bool {
+		a, b := check.SupplementInteractions[i], check.SupplementInteractions[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		return a.Medication+a.Supplement < b.Medication+b.Supplement
+	})
+	sort.SliceStable(check.FoodInteractions, func(i, j int) bool {
+		a, b := check.FoodInteractions[i], check.FoodInteractions[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		return a.Medication+a.Food < b.Medication+b.Food
+	})
+
+	check.HighestSeverity = SeverityNone
+	raise := func(severity string) {
+		if severityRank[severity] > severityRank[check.HighestSeverity] {
+			check.HighestSeverity = severity
+		}
+	}
+	for _, interaction := range check.Interactions {
+		raise(interaction.Severity)
+		if interaction.Severity == SeverityMajor {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("Major interaction between %s and %s: %s",
+				interaction.Medication1, interaction.Medication2, interaction.Description))
+		}
+	}
+	for _, interaction := range check.SupplementInteractions {
+		raise(interaction.Severity)
+		if interaction.Severity == SeverityMajor {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("Major interaction between %s and %s: %s",
+				interaction.Medication, interaction.Supplement, interaction.Effect))
+		}
+	}
+	for _, interaction := range check.FoodInteractions {
+		raise(interaction.Severity)
+	}
+}
+
+func (s *MedicationInteractionService) loadUserDrugs(ctx context.Context, userID string, knowledge *drugKnowledge) ([]*userDrug, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(um.custom_medication_name, ''), COALESCE(m.name, ''), COALESCE(m.generic_name, ''),
+		       COALESCE(m.brand_names, '[]'), COALESCE(m.drug_class, ''), COALESCE(m.drug_interactions, '[]'),
+		       COALESCE(m.food_interactions, '[]')
+		FROM user_medications um
+		LEFT JOIN medications m ON m.id = um.medication_id
+		WHERE um.user_id = ? AND COALESCE(um.is_active, 1) = 1
+		  AND (um.end_date IS NULL OR um.end_date = '' OR date(um.end_date) >= date('now'))
+		ORDER BY um.created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user medications: %w", err)
+	}
+	defer rows.Close()
+
+	var drugs []*userDrug
+	for rows.Next() {
+		var customName, name, genericName, brands, class, drugInteractions, foodInteractions string
+		if err := rows.Scan(&customName, &name, &genericName, &brands, &class, &drugInteractions, &foodInteractions); err != nil {
+			return nil, fmt.Errorf("failed to scan user medication: %w", err)
+		}
+
+		drug := &userDrug{display: strings.TrimSpace(customName), class: normalizeDrugName(class)}
+		if drug.display == "" {
+			drug.display = name
+		}
+		for _, value := range append([]string{customName, name, genericName}, decodeStringList(brands)...) {
+			if normalized := normalizeDrugName(value); normalized != "" {
+				drug.names = append(drug.names, normalized)
+			}
+		}
+		if drug.display == "" || len(drug.names) == 0 {
+			continue
+		}
+
+		if name != "" {
+			drug.entries = append(drug.entries, &drugEntry{
+				display:      name,
+				names:        drug.names,
+				class:        drug.class,
+				interactions: decodeStringList(drugInteractions),
+				foodNotes:    decodeStringList(foodInteractions),
+			})
+		}
+		for _, entry := range knowledge.entries {
+			if namesMatch(drug.names, entry.names) {
+				drug.entries = append(drug.entries, entry)
+			}
+		}
+		if len(drug.entries) == 1 && drug.entries[0].display == name &&
+			len(drug.entries[0].interactions)+len(drug.entries[0].foodNotes) == 0 {
+			drug.entries = nil
+		}
+		drugs = append(drugs, drug)
+	}
+	return drugs, rows.Err()
+}
+
+// loadActiveSupplements returns userID's current user_supplements with the
+// nutrient names each one supplies
+func loadActiveSupplements(ctx context.Context, db *sql.DB, userID string) ([]*userSupplement, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT COALESCE(us.supplement_name, ''), COALESCE(vm.name, '')
+		FROM user_supplements us
+		LEFT JOIN vitamins_minerals vm ON vm.id = us.vitamin_mineral_id
+		WHERE us.user_id = ? AND COALESCE(us.is_active, 1) = 1
+		  AND (us.end_date IS NULL OR us.end_date = '' OR date(us.end_date) >= date('now'))
+		ORDER BY us.created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user supplements: %w", err)
+	}
+	defer rows.Close()
+
+	var supplements []*userSupplement
+	for rows.Next() {
+		var name, nutrient string
+		if err := rows.Scan(&name, &nutrient); err != nil {
+			return nil, fmt.Errorf("failed to scan user supplement: %w", err)
+		}
+		supplement := &userSupplement{display: strings.TrimSpace(name), keys: supplementKeys(name, nutrient)}
+		if supplement.display == "" {
+			supplement.display = nutrient
+		}
+		if supplement.display != "" && len(supplement.keys) > 0 {
+			supplements = append(supplements, supplement)
+		}
+	}
+	return supplements, rows.Err()
+}
+
+// loadDrugKnowledge reads the drug reference file and the supplement usage
+// notes of drug_nutrition_interactions. A missing reference file is reported
+// with os.ErrNotExist alongside whatever the table provided.
+func (s *MedicationInteractionService) loadDrugKnowledge(ctx context.Context) (*drugKnowledge, error) {
+	knowledge := &drugKnowledge{supplementUsage: map[string]string{}}
+
+	// drug_nutrition_interactions only exists once the JSON data has been imported
+	var tables int
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'drug_nutrition_interactions'`).Scan(&tables); err != nil {
+		return nil, fmt.Errorf("failed to load drug nutrition interactions: %w", err)
+	}
+	if tables > 0 {
+		if err := s.loadSupplementUsage(ctx, knowledge); err != nil {
+			return nil, err
+		}
+	}
+
+	path := filepath.Join(s.dataDir, DrugsNutritionFile)
+	if _, err := os.Stat(path); err != nil {
+		return knowledge, fmt.Errorf("failed to read %s: %w", DrugsNutritionFile, err)
+	}
+	data, err := utils.LoadJSONFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DrugsNutritionFile, err)
+	}
+	knowledge.walk(data)
+	return knowledge, nil
+}
+
+func (s *MedicationInteractionService) loadSupplementUsage(ctx context.Context, knowledge *drugKnowledge) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT COALESCE(nutritional_recommendations, '{}') FROM drug_nutrition_interactions`)
+	if err != nil {
+		return fmt.Errorf("failed to load drug nutrition interactions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return fmt.Errorf("failed to scan drug nutrition interactions: %w", err)
+		}
+		var recommendations models.NutritionalRecommendations
+		if err := json.Unmarshal([]byte(raw), &recommendations); err != nil {
+			continue
+		}
+		for _, vitamin := range recommendations.VitaminRecommendations {
+			knowledge.addUsage(vitamin.Name, vitamin.Usage)
+		}
+		for _, supplement := range recommendations.SupplementRecommendations {
+			knowledge.addUsage(supplement.Name, supplement.Usage)
+		}
+	}
+	return rows.Err()
+}
+
+func (k *drugKnowledge) addUsage(name, usage models.BilingualText) {
+	if usage.En == "" {
+		return
+	}
+	for _, value := range []string{name.En, name.Ar} {
+		for _, key := range supplementKeys(value, "") {
+			if _, ok := k.supplementUsage[key]; !ok {
+				k.supplementUsage[key] = usage.En
+			}
+		}
+	}
+}
+
+// walk collects drug entries ("drug_name"), drug classes ("class" with
+// "examples") and class/supplement summaries wherever they appear in the
+// reference data
+func (k *drugKnowledge) walk(value interface{}) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			k.walk(item)
+		}
+	case map[string]interface{}:
+		if drugName, ok := v["drug_name"].(map[string]interface{}); ok {
+			entry := &drugEntry{regimen: jsonString(v["dosage_regimen"])}
+			entry.display = jsonString(drugName["generic"])
+			entry.names = drugNames(append([]string{entry.display}, jsonStrings(drugName["brand"])...))
+			entry.interactions = jsonStrings(v["interactions"])
+			if len(entry.names) > 0 {
+				k.entries = append(k.entries, entry)
+			}
+		}
+		if class := jsonString(v["class"]); class != "" && v["examples"] != nil {
+			entry := &drugEntry{display: class, class: normalizeDrugName(class)}
+			entry.names = drugNames(jsonStrings(v["examples"]))
+			entry.dietNotes = jsonStrings(v["diet_supplement_interactions"])
+			entry.vitaminNotes = jsonStrings(v["vitamin_supplement_interactions"])
+			if len(entry.names) > 0 {
+				k.entries = append(k.entries, entry)
+			}
+		}
+		if drugClass := jsonString(v["drug_class"]); drugClass != "" && v["supplement"] != nil {
+			k.rules = append(k.rules, supplementRule{
+				drugClass:   drugClass,
+				supplements: strings.FieldsFunc(jsonString(v["supplement"]), func(r rune) bool { return r == '/' || r == ',' }),
+				interaction: jsonString(v["interaction"]),
+				management:  jsonString(v["management"]),
+			})
+		}
+		for _, item := range v {
+			k.walk(item)
+		}
+	}
+}
+
+// matchesClass reports whether a summary's drug class names the drug, its
+// class or one of the reference classes it belongs to
+func (d *userDrug) matchesClass(drugClass string) bool {
+	class := normalizeDrugName(drugClass)
+	if class == "" {
+		return false
+	}
+	if namesMatch(d.names, []string{class}) || (d.class != "" && (class == d.class || mentionsName(d.class, class))) {
+		return true
+	}
+	for _, entry := range d.entries {
+		if entry.class != "" && (entry.class == class || mentionsName(entry.class, class) || mentionsName(class, entry.class)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitInteraction splits "<subject>: <effect>" and "<subject> (<effect>)"
+// notes. Notes in neither form are their own subject and effect.
+func splitInteraction(note string) (string, string) {
+	if i := strings.Index(note, ":"); i > 0 {
+		return strings.TrimSpace(note[:i]), strings.TrimSpace(note[i+1:])
+	}
+	if i := strings.Index(note, "("); i > 0 {
+		effect := strings.TrimSuffix(strings.TrimSpace(note[i+1:]), ")")
+		return strings.TrimSpace(note[:i]), strings.TrimSpace(effect)
+	}
+	return strings.TrimSpace(note), strings.TrimSpace(note)
+}
+
+func classifySeverity(text string) string {
+	normalized := normalizeDrugName(text)
+	for _, level := range severityKeywords {
+		for _, keyword := range level.keywords {
+			if strings.Contains(normalized, keyword) {
+				return level.severity
+			}
+		}
+	}
+	return SeverityMinor
+}
+
+// interactionManagement prefers the given management, then timing or
+// avoidance advice found in the note, then the default for the severity
+func interactionManagement(note, management, severity string) string {
+	if management = strings.TrimSpace(management); management != "" {
+		return management
+	}
+	if match := managementPattern.FindString(note); match != "" {
+		return strings.ToUpper(match[:1]) + strings.TrimSpace(match[1:])
+	}
+	return defaultManagement[severity]
+}
+
+func isFoodSubject(subject string) bool {
+	normalized := normalizeDrugName(subject)
+	for _, term := range foodTerms {
+		if strings.Contains(normalized, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDrugName lowercases a name and drops punctuation, trademarks and
+// apostrophes so "St. John’s Wort" matches "st johns wort"
+func normalizeDrugName(value string) string {
+	value = interactionNameGaps.Replace(interactionNameTidy.Replace(strings.ToLower(value)))
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// drugNames splits "Liraglutide (Saxenda®)" style names into their parts
+func drugNames(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == '(' || r == ')' || r == ',' }) {
+			if normalized := normalizeDrugName(part); len(normalized) >= 4 {
+				names = append(names, normalized)
+			}
+		}
+	}
+	return names
+}
+
+// supplementKeys returns the normalized names a supplement can be referred
+// to by: its own name, the nutrient it supplies and "vitamin d" for "vitamin d3"
+func supplementKeys(name, nutrient string) []string {
+	keys := map[string]bool{}
+	for _, value := range []string{name, nutrient} {
+		normalized := normalizeDrugName(value)
+		if normalized == "" {
+			continue
+		}
+		keys[normalized] = true
+		for alias, target := range supplementAliases {
+			if mentionsName(normalized, alias) {
+				keys[target] = true
+			}
+		}
+		for _, mineral := range minerals {
+			if mentionsName(normalized, mineral) {
+				keys[mineral] = true
+			}
+		}
+		for _, match := range vitaminNamePattern.FindAllStringSubmatch(normalized, -1) {
+			keys["vitamin "+match[1]] = true
+		}
+	}
+	return sortedKeys(keys)
+}
+
+// expandVitaminLists rewrites "vitamins a d e k" as separate vitamin names
+func expandVitaminLists(text string) string {
+	for _, match := range vitaminListPattern.FindAllStringSubmatch(text, -1) {
+		for _, letter := range strings.Fields(match[1]) {
+			if letter != "and" {
+				text += " vitamin " + letter
+			}
+		}
+	}
+	return text
+}
+
+// mentionsName reports whether name appears in text starting at a word boundary
+func mentionsName(text, name string) bool {
+	if name == "" {
+		return false
+	}
+	return strings.Contains(" "+text, " "+name)
+}
+
+func mentionsAnyName(text string, names []string) bool {
+	normalized := normalizeDrugName(text)
+	for _, name := range names {
+		if mentionsName(normalized, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// namesMatch reports whether any name in a equals or starts a name in b, or
+// the other way round
+func namesMatch(a, b []string) bool {
+	for _, x := range a {
+		x = normalizeDrugName(x)
+		for _, y := range b {
+			y = normalizeDrugName(y)
+			if len(x) < 4 || len(y) < 4 {
+				continue
+			}
+			if x == y || mentionsName(x, y) || mentionsName(y, x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonString(value interface{}) string {
+	text, _ := value.(string)
+	return strings.TrimSpace(text)
+}
+
+func jsonStrings(value interface{}) []string {
+	items, _ := value.([]interface{})
+	var values []string
+	for _, item := range items {
+		if text := jsonString(item); text != "" {
+			values = append(values, text)
+		}
+	}
+	return values
+}
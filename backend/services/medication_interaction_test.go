@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDrugsNutrition = `{
+  "weight_loss_drugs": [
+    {
+      "drug_name": {"generic": "Orlistat", "brand": ["Xenical", "Alli"]},
+      "dosage_regimen": "Taken with each fat-containing meal or up to 1 hour after the meal.",
+      "interactions": [
+        "Warfarin (reduced absorption)",
+        "Cyclosporine (avoid concurrent use)",
+        "Levothyroxine (take 4 hours apart)"
+      ]
+    }
+  ],
+  "drug_classes": [
+    {
+      "class": "Lipase Inhibitors",
+      "examples": ["Orlistat"],
+      "diet_supplement_interactions": ["High-fat meals: increase gastrointestinal side effects"],
+      "vitamin_supplement_interactions": ["Vitamins A, D, E, K: reduced absorption, take 2 hours apart"]
+    }
+  ],
+  "key_interactions_summary": [
+    {
+      "drug_class": "Thyroid Hormones",
+      "supplement": "Iron/Calcium",
+      "interaction": "Bind levothyroxine and reduce its absorption",
+      "management": "Take 4 hours apart"
+    }
+  ]
+}`
+
+func newTestMedicationInteractionService(t *testing.T, withReference bool) (*MedicationInteractionService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "patient@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	dataDir := t.TempDir()
+	if withReference {
+		require.NoError(t, os.WriteFile(filepath.Join(dataDir, DrugsNutritionFile), []byte(testDrugsNutrition), 0o644))
+	}
+
+	_, err = users.db.Exec(`INSERT INTO medications (id, name, generic_name, drug_class, drug_interactions, food_interactions) VALUES
+		('warfarin', 'Warfarin', 'warfarin', 'Anticoagulants', '["St. John''s Wort (avoid, reduces anticoagulant effect)"]',
+		 '["Vitamin K-rich foods: reduce warfarin effect, keep intake consistent"]'),
+		('levothyroxine', 'Levothyroxine', 'levothyroxine', 'Thyroid Hormones', '[]', '[]'),
+		('orlistat', 'Orlistat', 'orlistat', 'Lipase Inhibitors', '[]', '[]'),
+		('metformin', 'Metformin', 'metformin', 'Biguanides', '[]', '[]')`)
+	require.NoError(t, err)
+	_, err = users.db.Exec(`INSERT INTO user_medications (user_id, medication_id, is_active, end_date) VALUES
+		(?, 'warfarin', 1, NULL), (?, 'levothyroxine', 1, NULL), (?, 'orlistat', 1, NULL),
+		(?, 'metformin', 0, NULL)`, user.ID, user.ID, user.ID, user.ID)
+	require.NoError(t, err)
+	_, err = users.db.Exec(`INSERT INTO user_supplements (user_id, supplement_name, is_active, end_date) VALUES
+		(?, 'Calcium carbonate', 1, NULL), (?, 'Vitamin D3', 1, NULL), (?, 'St John''s Wort', 1, NULL),
+		(?, 'Zinc', 1, '2000-01-01')`, user.ID, user.ID, user.ID, user.ID)
+	require.NoError(t, err)
+
+	_, err = users.db.Exec(`CREATE TABLE drug_nutrition_interactions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, supported_languages TEXT, nutritional_recommendations TEXT)`)
+	require.NoError(t, err)
+	_, err = users.db.Exec(`INSERT INTO drug_nutrition_interactions (supported_languages, nutritional_recommendations)
+		VALUES ('["en", "ar"]', '{"VitaminRecommendations": [{"name": {"en": "Vitamin D", "ar": "فيتامين د"},
+		"usage": {"en": "Take with the largest meal of the day", "ar": "مع أكبر وجبة"}}]}')`)
+	require.NoError(t, err)
+
+	return NewMedicationInteractionService(users.db, dataDir), user.ID
+}
+
+func TestMedicationInteractionService_CheckInteractions(t *testing.T) {
+	svc, userID := newTestMedicationInteractionService(t, true)
+
+	check, err := svc.CheckInteractions(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Warfarin", "Levothyroxine", "Orlistat"}, check.UserMedications)
+	assert.Equal(t, []string{"Calcium carbonate", "Vitamin D3", "St John's Wort"}, check.UserSupplements)
+	assert.Equal(t, SeverityMajor, check.HighestSeverity)
+
+	// Drug pairs come from the Orlistat entry; Cyclosporine is not taken
+	require.Len(t, check.Interactions, 2)
+	assert.Equal(t, models.MedicationInteraction{
+		Medication1:     "Levothyroxine",
+		Medication2:     "Orlistat",
+		InteractionType: SeverityModerate,
+		Description:     "take 4 hours apart",
+		Severity:        SeverityModerate,
+		Management:      "Take 4 hours apart",
+	}, check.Interactions[0])
+	assert.Equal(t, "Orlistat", check.Interactions[1].Medication1)
+	assert.Equal(t, "Warfarin", check.Interactions[1].Medication2)
+
+	supplements := map[string]models.SupplementInteraction{}
+	for _, interaction := range check.SupplementInteractions {
+		supplements[interaction.Medication+"+"+interaction.Supplement] = interaction
+	}
+	require.Len(t, supplements, 3)
+	assert.Equal(t, SeverityMajor, check.SupplementInteractions[0].Severity)
+	assert.Equal(t, SeverityMajor, supplements["Warfarin+St John's Wort"].Severity)
+	assert.Equal(t, "Take 4 hours apart", supplements["Levothyroxine+Calcium carbonate"].Recommendation)
+	assert.Equal(t, "Take 2 hours apart", supplements["Orlistat+Vitamin D3"].Recommendation)
+
+	foods := map[string]models.FoodInteraction{}
+	for _, interaction := range check.FoodInteractions {
+		foods[interaction.Medication+"+"+interaction.Food] = interaction
+	}
+	assert.Contains(t, foods, "Warfarin+Vitamin K-rich foods")
+	assert.Contains(t, foods, "Orlistat+High-fat meals")
+
+	assert.Contains(t, check.TimingAdvice, models.TimingAdvice{
+		Item:   "Orlistat",
+		Type:   "medication",
+		Advice: "Taken with each fat-containing meal or up to 1 hour after the meal.",
+	})
+	assert.Contains(t, check.TimingAdvice, models.TimingAdvice{
+		Item:   "Vitamin D3",
+		Type:   "supplement",
+		Advice: "Take with the largest meal of the day",
+	})
+	assert.Contains(t, check.Warnings, "Major interaction between Warfarin and St John's Wort: avoid, reduces anticoagulant effect")
+	assert.Contains(t, check.Warnings, "No interaction data found for Levothyroxine")
+}
+
+func TestMedicationInteractionService_MissingReference(t *testing.T) {
+	svc, userID := newTestMedicationInteractionService(t, false)
+
+	check, err := svc.CheckInteractions(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Contains(t, check.Warnings, "Drug reference data is unavailable; only the medications table was checked")
+	assert.Empty(t, check.Interactions)
+	assert.Len(t, check.FoodInteractions, 1)
+	assert.Len(t, check.SupplementInteractions, 1)
+
+	check, err = svc.CheckInteractions(context.Background(), "nobody")
+	require.NoError(t, err)
+	assert.Equal(t, SeverityNone, check.HighestSeverity)
+	assert.Empty(t, check.UserMedications)
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Metrics milestone rules are measured on
+const (
+	metricWeightLost    = "weight_lost"
+	metricLoggingStreak = "logging_streak"
+	metricWorkouts      = "workouts"
+	metricRecords       = "personal_records"
+	metricGoalsReached  = "weight_goals_reached"
+)
+
+// recentMilestoneDays is the window of AchievementAnalytics.AchievementRate
+const recentMilestoneDays = 30
+
+// milestoneRule is reached once its metric gets to target
+type milestoneRule struct {
+	key           string
+	metric        string
+	milestoneType string
+	title         string
+	description   string
+	target        float64
+}
+
+var milestoneRules = []milestoneRule{
+	{"weight_lost_5", metricWeightLost, "weight", "First 5 kg lost", "Your trend weight is 5 kg below your first weigh-in", 5},
+	{"weight_lost_10", metricWeightLost, "weight", "10 kg lost", "Your trend weight is 10 kg below your first weigh-in", 10},
+	{"weight_lost_20", metricWeightLost, "weight", "20 kg lost", "Your trend weight is 20 kg below your first weigh-in", 20},
+	{"weight_goal_reached", metricGoalsReached, "weight", "Weight goal reached", "You reached the target of a weight goal", 1},
+	{"logging_streak_7", metricLoggingStreak, "streak", "7-day logging streak", "You logged your meals 7 days in a row", 7},
+	{"logging_streak_30", metricLoggingStreak, "streak", "30-day logging streak", "You logged your meals 30 days in a row", 30},
+	{"logging_streak_100", metricLoggingStreak, "streak", "100-day logging streak", "You logged your meals 100 days in a row", 100},
+	{"first_workout", metricWorkouts, "workout", "First workout", "You completed your first workout", 1},
+	{"workouts_10", metricWorkouts, "workout", "10 workouts", "You completed 10 workouts", 10},
+	{"workouts_50", metricWorkouts, "workout", "50 workouts", "You completed 50 workouts", 50},
+	{"workouts_100", metricWorkouts, "workout", "100 workouts", "You completed 100 workouts", 100},
+	{"first_personal_record", metricRecords, "strength", "First personal record", "You set your first personal record", 1},
+	{"personal_records_25", metricRecords, "strength", "25 personal records", "You set 25 personal records", 25},
+}
+
+// milestoneMetric is the daily history of a metric and its value today
+type milestoneMetric struct {
+	days    []time.Time
+	values  []float64
+	current float64
+}
+
+// reached returns the first day the metric got to target
+func (m milestoneMetric) reached(target float64) (time.Time, bool) {
+	for i, value := range m.values {
+		if value >= target {
+			return m.days[i], true
+		}
+	}
+	return time.Time{}, false
+}
+
+// MilestoneService evaluates milestone rules against a user's weigh-ins, food
+// diary, workouts and personal records
+type MilestoneService struct {
+	db    *sql.DB
+	goals *WeightGoalService
+}
+
+// NewMilestoneService creates a new milestone service
+func NewMilestoneService(db *sql.DB) *MilestoneService {
+	return &MilestoneService{
+		db:    db,
+		goals: NewWeightGoalService(db),
+	}
+}
+
+// Evaluate stores the milestones userID has reached since the last evaluation
+// and returns them. A milestone is dated by the day its metric got there, so
+// evaluating late does not move the date.
+func (s *MilestoneService) Evaluate(ctx context.Context, userID string) ([]*models.Milestone, error) {
+	metrics, err := s.metrics(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(ctx, userID, metrics)
+}
+
+// Stats evaluates userID's milestones and returns the reached ones, newest
+// first, with the progress towards the others, closest first
+func (s *MilestoneService) Stats(ctx context.Context, userID string) (*models.MilestoneStats, error) {
+	metrics, err := s.metrics(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.evaluate(ctx, userID, metrics); err != nil {
+		return nil, err
+	}
+	achieved, err := s.ListAchieved(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[string]bool{}
+	for _, milestone := range achieved {
+		done[milestone.Key] = true
+	}
+
+	stats := &models.MilestoneStats{
+		TotalMilestones:    len(milestoneRules),
+		RecentAchievements: achieved,
+		UpcomingMilestones: []models.MilestoneProgress{},
+		ProgressByType:     map[string]models.MilestoneStats{},
+	}
+	for _, rule := range milestoneRules {
+		byType := stats.ProgressByType[rule.milestoneType]
+		byType.TotalMilestones++
+		if done[rule.key] {
+			stats.CompletedMilestones++
+			byType.CompletedMilestones++
+		} else {
+			progress := rule.progress(metrics[rule.metric].current)
+			stats.UpcomingMilestones = append(stats.UpcomingMilestones, progress)
+			if progress.CurrentValue > 0 {
+				stats.InProgressMilestones++
+				byType.InProgressMilestones++
+			}
+		}
+		byType.CompletionRate = round1(float64(byType.CompletedMilestones) / float64(byType.TotalMilestones) * 100)
+		stats.ProgressByType[rule.milestoneType] = byType
+	}
+	stats.CompletionRate = round1(float64(stats.CompletedMilestones) / float64(stats.TotalMilestones) * 100)
+	sort.SliceStable(stats.UpcomingMilestones, func(i, j int) bool {
+		return stats.UpcomingMilestones[i].ProgressPercent > stats.UpcomingMilestones[j].ProgressPercent
+	})
+
+	return stats, nil
+}
+
+// Analytics summarizes userID's milestones. AchievementRate is the number
+// reached in the last 30 days.
+func (s *MilestoneService) Analytics(ctx context.Context, userID string) (*models.AchievementAnalytics, error) {
+	stats, err := s.Stats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &models.AchievementAnalytics{
+		TotalAchievements:  stats.CompletedMilestones,
+		RecentAchievements: stats.RecentAchievements[:min(len(stats.RecentAchievements), 5)],
+		AchievementsByType: map[string]int{},
+		NextMilestones:     stats.UpcomingMilestones[:min(len(stats.UpcomingMilestones), 3)],
+		CompletionRate:     stats.CompletionRate,
+	}
+	since := time.Now().UTC().AddDate(0, 0, -recentMilestoneDays)
+	for _, milestone := range stats.RecentAchievements {
+		analytics.AchievementsByType[milestone.Type]++
+		if !milestone.AchievedAt.Before(since) {
+			analytics.AchievementRate++
+		}
+	}
+
+	return analytics, nil
+}
+
+// ListAchieved returns the milestones userID has reached, newest first
+func (s *MilestoneService) ListAchieved(ctx context.Context, userID string) ([]models.Milestone, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_id, milestone_key, type, title, description, target_value,
+		achieved_at, created_at FROM milestones WHERE user_id = ? ORDER BY achieved_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestones: %w", err)
+	}
+	defer rows.Close()
+
+	milestones := []models.Milestone{}
+	for rows.Next() {
+		var milestone models.Milestone
+		var description sql.NullString
+		var target sql.NullFloat64
+		if err := rows.Scan(&milestone.ID, &milestone.UserID, &milestone.Key, &milestone.Type, &milestone.Title,
+			&description, &target, &milestone.AchievedAt, &milestone.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan milestone: %w", err)
+		}
+		milestone.Description = nullStringPtr(description)
+		if target.Valid {
+			milestone.TargetValue = &target.Float64
+		}
+		milestone.IsAchieved = true
+		milestones = append(milestones, milestone)
+	}
+	return milestones, rows.Err()
+}
+
+func (s *MilestoneService) evaluate(ctx context.Context, userID string, metrics map[string]milestoneMetric) ([]*models.Milestone, error) {
+	achieved, err := s.ListAchieved(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	done := map[string]bool{}
+	for _, milestone := range achieved {
+		done[milestone.Key] = true
+	}
+
+	var candidates []*models.Milestone
+	for _, rule := range milestoneRules {
+		if done[rule.key] {
+			continue
+		}
+		if day, ok := metrics[rule.metric].reached(rule.target); ok {
+			candidates = append(candidates, rule.milestone(userID, day))
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A concurrent evaluation may have stored a milestone since it was listed
+	var reached []*models.Milestone
+	for _, milestone := range candidates {
+		result, err := tx.ExecContext(ctx, `INSERT INTO milestones (user_id, milestone_key, type, title, description,
+			target_value, achieved_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, milestone_key) DO NOTHING`,
+			milestone.UserID, milestone.Key, milestone.Type, milestone.Title, milestone.Description,
+			milestone.TargetValue, milestone.AchievedAt, milestone.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save milestone: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to save milestone: %w", err)
+		}
+		milestone.ID = uint(id)
+		reached = append(reached, milestone)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit milestones: %w", err)
+	}
+	return reached, nil
+}
+
+// metrics builds the daily history of every milestone metric for userID
+func (s *MilestoneService) metrics(ctx context.Context, userID string, now time.Time) (map[string]milestoneMetric, error) {
+	metrics := map[string]milestoneMetric{}
+
+	// Weight lost is measured on the trend so a single light weigh-in does
+	// not count
+	days, err := s.goals.weighIns(ctx, userID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	var lost milestoneMetric
+	trend := smoothTrend(days)
+	for i, day := range days {
+		lost.days = append(lost.days, day.day)
+		lost.values = append(lost.values, round2(trend[0]-trend[i]))
+	}
+	if len(lost.values) > 0 {
+		lost.current = max(0, lost.values[len(lost.values)-1])
+	}
+	metrics[metricWeightLost] = lost
+
+	logged, err := s.dailyCounts(ctx, `SELECT date(consumed_at), COUNT(*) FROM user_food_logs
+		WHERE user_id = ? GROUP BY date(consumed_at) ORDER BY date(consumed_at)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	metrics[metricLoggingStreak] = streakMetric(logged.days, now)
+
+	queries := map[string]string{
+		metricWorkouts: `SELECT date(completed_date), COUNT(*) FROM user_workout_sessions
+			WHERE user_id = ? AND status = 'completed' AND completed_date IS NOT NULL
+			GROUP BY date(completed_date) ORDER BY date(completed_date)`,
+		metricRecords: `SELECT date(achieved_at), COUNT(*) FROM personal_records
+			WHERE user_id = ? GROUP BY date(achieved_at) ORDER BY date(achieved_at)`,
+		metricGoalsReached: `SELECT date(achieved_at), COUNT(*) FROM weight_goals
+			WHERE user_id = ? AND achieved_at IS NOT NULL GROUP BY date(achieved_at) ORDER BY date(achieved_at)`,
+	}
+	for metric, query := range queries {
+		if metrics[metric], err = s.dailyCounts(ctx, query, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return metrics, nil
+}
+
+// dailyCounts runs a query returning (date, count) per day and accumulates
+// the counts into a metric
+func (s *MilestoneService) dailyCounts(ctx context.Context, query, userID string) (milestoneMetric, error) {
+	var metric milestoneMetric
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return metric, fmt.Errorf("failed to load milestone metric: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date sql.NullString
+		var count int
+		if err := rows.Scan(&date, &count); err != nil {
+			return metric, fmt.Errorf("failed to scan milestone metric: %w", err)
+		}
+		day, err := time.Parse("2006-01-02", date.String)
+		if err != nil {
+			continue
+		}
+		metric.current += float64(count)
+		metric.days = append(metric.days, day)
+		metric.values = append(metric.values, metric.current)
+	}
+	return metric, rows.Err()
+}
+
+// streakMetric turns logged days into the length of the run each day ends.
+// Today is still in progress, so a streak ending yesterday is still current.
+func streakMetric(days []time.Time, now time.Time) milestoneMetric {
+	metric := milestoneMetric{days: days}
+	run := 0
+	for i, day := range days {
+		if i > 0 && day.Equal(days[i-1].AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		metric.values = append(metric.values, float64(run))
+	}
+	if n := len(days); n > 0 && !days[n-1].Before(startOfDay(now).AddDate(0, 0, -1)) {
+		metric.current = float64(run)
+	}
+	return metric
+}
+
+func (r milestoneRule) milestone(userID string, achievedAt time.Time) *models.Milestone {
+	description, target := r.description, r.target
+	return &models.Milestone{
+		UserID:      userID,
+		Key:         r.key,
+		Type:        r.milestoneType,
+		Title:       r.title,
+		Description: &description,
+		TargetValue: &target,
+		AchievedAt:  achievedAt,
+		IsAchieved:  true,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+func (r milestoneRule) progress(current float64) models.MilestoneProgress {
+	return models.MilestoneProgress{
+		MilestoneKey:    r.key,
+		MilestoneType:   r.milestoneType,
+		MilestoneTitle:  r.title,
+		CurrentValue:    current,
+		TargetValue:     r.target,
+		ProgressPercent: round1(min(current/r.target, 1) * 100),
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreakMetric(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	day := func(offset int) time.Time { return startOfDay(now).AddDate(0, 0, offset) }
+
+	metric := streakMetric([]time.Time{day(-6), day(-5), day(-3), day(-2), day(-1)}, now)
+	assert.Equal(t, []float64{1, 2, 1, 2, 3}, metric.values)
+	assert.Equal(t, 3.0, metric.current, "an unlogged today does not break the streak")
+	reached, ok := metric.reached(3)
+	require.True(t, ok)
+	assert.Equal(t, day(-1), reached)
+
+	metric = streakMetric([]time.Time{day(-4), day(-3), day(-2)}, now)
+	assert.Zero(t, metric.current)
+	_, ok = metric.reached(4)
+	assert.False(t, ok)
+}
+
+func TestMilestoneService_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	records, userID := newTestPersonalRecordService(t)
+	svc := records.milestones
+	today := startOfDay(time.Now())
+
+	weights := make([]float64, 40)
+	for i := range weights {
+		weights[i] = 100 - 0.2*float64(i)
+	}
+	logWeights(t, svc.db, userID, weights...)
+	for i := 7; i >= 0; i-- {
+		_, err := svc.db.Exec(`INSERT INTO user_food_logs (user_id, quantity, consumed_at) VALUES (?, 1, ?)`,
+			userID, today.AddDate(0, 0, -i).Add(12*time.Hour))
+		require.NoError(t, err)
+	}
+	_, err := svc.db.Exec(`INSERT INTO user_workout_sessions (user_id, completed_date, status)
+		VALUES (?, ?, 'completed'), (?, ?, 'scheduled')`, userID, today.AddDate(0, 0, -3), userID, today)
+	require.NoError(t, err)
+
+	reached, err := svc.Evaluate(ctx, userID)
+	require.NoError(t, err)
+	keys := make([]string, len(reached))
+	for i, milestone := range reached {
+		keys[i] = milestone.Key
+		assert.NotZero(t, milestone.ID)
+	}
+	assert.Equal(t, []string{"weight_lost_5", "logging_streak_7", "first_workout"}, keys)
+	assert.True(t, reached[0].AchievedAt.Before(today), "dated by the weigh-in that reached it")
+	assert.Equal(t, today.AddDate(0, 0, -1), reached[1].AchievedAt)
+	assert.Equal(t, today.AddDate(0, 0, -3), reached[2].AchievedAt)
+
+	reached, err = svc.Evaluate(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, reached)
+
+	stats, err := svc.Stats(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, len(milestoneRules), stats.TotalMilestones)
+	assert.Equal(t, 3, stats.CompletedMilestones)
+	require.Len(t, stats.UpcomingMilestones, len(milestoneRules)-3)
+	next := stats.UpcomingMilestones[0]
+	assert.Equal(t, "weight_lost_10", next.MilestoneKey)
+	assert.Greater(t, next.ProgressPercent, 50.0)
+	assert.Equal(t, 3, stats.ProgressByType["workout"].InProgressMilestones)
+	for _, progress := range stats.UpcomingMilestones {
+		if progress.MilestoneKey == "logging_streak_30" {
+			assert.Equal(t, 8.0, progress.CurrentValue)
+			assert.Equal(t, 26.7, progress.ProgressPercent)
+		}
+	}
+
+	analytics, err := svc.Analytics(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, analytics.TotalAchievements)
+	assert.Equal(t, 3.0, analytics.AchievementRate)
+	assert.Equal(t, map[string]int{"weight": 1, "streak": 1, "workout": 1}, analytics.AchievementsByType)
+	assert.Len(t, analytics.NextMilestones, 3)
+}
@@ -0,0 +1,770 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Nutrient deficiency errors returned by NutrientDeficiencyService
+var (
+	ErrInvalidDeficiencyAnalysis = errors.New("invalid deficiency analysis request")
+)
+
+const (
+	defaultDeficiencyDays = 14
+	// minDiaryDays is the fewest logged days for intake to count as evidence
+	minDiaryDays = 3
+)
+
+// intakeBand holds the reference intakes that apply from minAge upward
+type intakeBand struct {
+	minAge int
+	male   float64
+	female float64
+}
+
+// referenceIntake describes one micronutrient: reference intakes by age, sex
+// and pregnancy (RDA, or AI where no RDA exists), how it is tested and how it
+// is supplemented
+type referenceIntake struct {
+	nutrient    string // key in models.MicronutrientUnits
+	name        string
+	aliases     []string
+	bands       []intakeBand // ascending by minAge
+	pregnancy   float64
+	labTest     string
+	foodSources []string
+	symptoms    []string
+	form        string
+	timing      string
+	precautions []string
+	monitor     bool
+}
+
+// referenceIntakes follow the US National Academies dietary reference intakes
+var referenceIntakes = []referenceIntake{
+	{
+		nutrient: "vitamin_d", name: "Vitamin D", aliases: []string{"vitamin d", "cholecalciferol", "calciferol"},
+		bands:     []intakeBand{{9, 15, 15}, {71, 20, 20}},
+		pregnancy: 15,
+		labTest:   "25-hydroxyvitamin D [25(OH)D]",
+		foodSources: []string{"Fatty fish (salmon, mackerel)", "Egg yolks", "Fortified milk and yogurt",
+			"Mushrooms exposed to UV light"},
+		symptoms:    []string{"Bone pain", "Muscle weakness", "Fatigue"},
+		form:        "Vitamin D3 (cholecalciferol)",
+		timing:      "With the largest meal of the day",
+		precautions: []string{"Do not exceed 100 µg (4000 IU) a day without medical supervision"},
+		monitor:     true,
+	},
+	{
+		nutrient: "vitamin_b12", name: "Vitamin B12", aliases: []string{"vitamin b12", "b12", "cobalamin"},
+		bands:       []intakeBand{{9, 1.8, 1.8}, {14, 2.4, 2.4}},
+		pregnancy:   2.6,
+		labTest:     "Serum vitamin B12 and methylmalonic acid",
+		foodSources: []string{"Meat and poultry", "Fish and shellfish", "Eggs and dairy", "Fortified cereals and plant milks"},
+		symptoms:    []string{"Fatigue", "Numbness or tingling in hands and feet", "Memory problems"},
+		form:        "Methylcobalamin or cyanocobalamin",
+		timing:      "Any time of day; sublingual or injected if absorption is impaired",
+		monitor:     true,
+	},
+	{
+		nutrient: "iron", name: "Iron", aliases: []string{"iron", "ferritin", "ferrous"},
+		bands:       []intakeBand{{9, 8, 8}, {14, 11, 15}, {19, 8, 18}, {51, 8, 8}},
+		pregnancy:   27,
+		labTest:     "Serum ferritin and complete blood count",
+		foodSources: []string{"Red meat (beef, lamb)", "Lentils and beans", "Spinach", "Fortified cereals"},
+		symptoms:    []string{"Fatigue", "Pale skin", "Shortness of breath"},
+		form:        "Ferrous bisglycinate or ferrous sulfate",
+		timing:      "On an empty stomach with vitamin C, or with food if it upsets the stomach",
+		precautions: []string{"Excess iron is toxic; keep supplements away from children",
+			"Take 2 hours apart from calcium and antacids"},
+		monitor: true,
+	},
+	{
+		nutrient: "folate", name: "Folate", aliases: []string{"folate", "folic acid", "vitamin b9"},
+		bands:       []intakeBand{{9, 300, 300}, {14, 400, 400}},
+		pregnancy:   600,
+		labTest:     "Serum or red blood cell folate",
+		foodSources: []string{"Leafy green vegetables", "Legumes", "Asparagus", "Fortified grains"},
+		symptoms:    []string{"Fatigue", "Mouth sores", "Irritability"},
+		form:        "Folic acid or methylfolate",
+		timing:      "With food",
+		precautions: []string{"Check vitamin B12 status before supplementing folate"},
+	},
+	{
+		nutrient: "calcium", name: "Calcium", aliases: []string{"calcium"},
+		bands:       []intakeBand{{9, 1300, 1300}, {19, 1000, 1000}, {51, 1000, 1200}, {71, 1200, 1200}},
+		pregnancy:   1000,
+		labTest:     "Serum calcium",
+		foodSources: []string{"Milk, yogurt and cheese", "Fortified plant milks", "Sardines with bones", "Tofu set with calcium"},
+		symptoms:    []string{"Muscle cramps", "Brittle nails", "Numbness around the mouth"},
+		form:        "Calcium citrate or calcium carbonate",
+		timing:      "Carbonate with food; split doses above 500 mg",
+		precautions: []string{"Take 4 hours apart from thyroid medication and iron"},
+	},
+	{
+		nutrient: "magnesium", name: "Magnesium", aliases: []string{"magnesium"},
+		bands:       []intakeBand{{9, 240, 240}, {14, 410, 360}, {19, 400, 310}, {31, 420, 320}},
+		pregnancy:   350,
+		labTest:     "Serum magnesium",
+		foodSources: []string{"Nuts and seeds", "Whole grains", "Legumes", "Dark leafy greens"},
+		symptoms:    []string{"Muscle cramps", "Fatigue", "Irregular heartbeat"},
+		form:        "Magnesium glycinate or magnesium citrate",
+		timing:      "In the evening with food",
+		precautions: []string{"Lower the dose if it causes diarrhea; avoid with kidney disease unless prescribed"},
+	},
+	{
+		nutrient: "zinc", name: "Zinc", aliases: []string{"zinc"},
+		bands:       []intakeBand{{9, 8, 8}, {14, 11, 9}, {19, 11, 8}},
+		pregnancy:   11,
+		labTest:     "Plasma zinc",
+		foodSources: []string{"Shellfish", "Red meat", "Pumpkin seeds", "Chickpeas"},
+		symptoms:    []string{"Frequent infections", "Slow wound healing", "Loss of taste"},
+		form:        "Zinc gluconate or zinc picolinate",
+		timing:      "With food",
+		precautions: []string{"Long-term intake above 40 mg a day depletes copper"},
+	},
+	{
+		nutrient: "iodine", name: "Iodine", aliases: []string{"iodine", "iodide"},
+		bands:       []intakeBand{{9, 120, 120}, {14, 150, 150}},
+		pregnancy:   220,
+		labTest:     "Urinary iodine and thyroid function (TSH)",
+		foodSources: []string{"Iodized salt", "Seafood and seaweed", "Dairy products", "Eggs"},
+		symptoms:    []string{"Swelling in the neck (goiter)", "Fatigue", "Weight gain"},
+		form:        "Potassium iodide",
+		timing:      "With food",
+		precautions: []string{"Excess iodine can disturb thyroid function"},
+		monitor:     true,
+	},
+	{
+		nutrient: "vitamin_a", name: "Vitamin A", aliases: []string{"vitamin a", "retinol", "beta carotene"},
+		bands:       []intakeBand{{9, 600, 600}, {14, 900, 700}},
+		pregnancy:   770,
+		labTest:     "Serum retinol",
+		foodSources: []string{"Liver", "Sweet potatoes and carrots", "Dark leafy greens", "Eggs"},
+		symptoms:    []string{"Night blindness", "Dry eyes", "Dry skin"},
+		form:        "Beta-carotene or retinyl palmitate",
+		timing:      "With a meal containing fat",
+		precautions: []string{"Avoid high-dose preformed vitamin A during pregnancy"},
+		monitor:     true,
+	},
+	{
+		nutrient: "vitamin_c", name: "Vitamin C", aliases: []string{"vitamin c", "ascorbic acid"},
+		bands:       []intakeBand{{9, 45, 45}, {14, 75, 65}, {19, 90, 75}},
+		pregnancy:   85,
+		labTest:     "Plasma ascorbic acid",
+		foodSources: []string{"Citrus fruits (oranges, lemons)", "Bell peppers", "Broccoli", "Strawberries"},
+		symptoms:    []string{"Bleeding gums", "Poor wound healing", "Fatigue"},
+		form:        "Ascorbic acid",
+		timing:      "With meals; split doses above 500 mg",
+	},
+	{
+		nutrient: "vitamin_e", name: "Vitamin E", aliases: []string{"vitamin e", "tocopherol"},
+		bands:       []intakeBand{{9, 11, 11}, {14, 15, 15}},
+		pregnancy:   15,
+		labTest:     "Serum alpha-tocopherol",
+		foodSources: []string{"Nuts (almonds, hazelnuts)", "Sunflower seeds", "Vegetable oils", "Spinach"},
+		symptoms:    []string{"Muscle weakness", "Loss of coordination", "Vision problems"},
+		form:        "Natural d-alpha-tocopherol",
+		timing:      "With a meal containing fat",
+		precautions: []string{"High doses may increase bleeding risk with anticoagulants"},
+		monitor:     true,
+	},
+	{
+		nutrient: "vitamin_k", name: "Vitamin K", aliases: []string{"vitamin k", "phylloquinone", "menaquinone"},
+		bands:       []intakeBand{{9, 60, 60}, {14, 75, 75}, {19, 120, 90}},
+		pregnancy:   90,
+		labTest:     "Prothrombin time (INR)",
+		foodSources: []string{"Kale and spinach", "Broccoli", "Brussels sprouts", "Fermented foods"},
+		symptoms:    []string{"Easy bruising", "Bleeding gums", "Heavy menstrual bleeding"},
+		form:        "Vitamin K1 (phylloquinone)",
+		timing:      "With a meal containing fat",
+		precautions: []string{"Changes the effect of warfarin; consult the prescriber first"},
+		monitor:     true,
+	},
+	{
+		nutrient: "vitamin_b6", name: "Vitamin B6", aliases: []string{"vitamin b6", "pyridoxine"},
+		bands:       []intakeBand{{9, 1.0, 1.0}, {14, 1.3, 1.2}, {19, 1.3, 1.3}, {51, 1.7, 1.5}},
+		pregnancy:   1.9,
+		labTest:     "Plasma pyridoxal 5'-phosphate (PLP)",
+		foodSources: []string{"Chickpeas", "Fish and poultry", "Potatoes", "Bananas"},
+		symptoms:    []string{"Cracked lips", "Sore tongue", "Low mood"},
+		form:        "Pyridoxine hydrochloride",
+		timing:      "With food",
+		precautions: []string{"Long-term intake above 100 mg a day can damage nerves"},
+	},
+	{
+		nutrient: "potassium", name: "Potassium", aliases: []string{"potassium"},
+		bands:       []intakeBand{{9, 2500, 2300}, {14, 3000, 2300}, {19, 3400, 2600}},
+		pregnancy:   2900,
+		labTest:     "Serum potassium",
+		foodSources: []string{"Bananas", "Potatoes", "Beans and lentils", "Dried apricots"},
+		symptoms:    []string{"Muscle weakness", "Cramps", "Constipation"},
+		form:        "Dietary sources preferred",
+		timing:      "Spread across meals",
+		precautions: []string{"Do not take potassium supplements without medical advice, especially with kidney disease or ACE inhibitors"},
+		monitor:     true,
+	},
+	{
+		nutrient: "selenium", name: "Selenium", aliases: []string{"selenium"},
+		bands:       []intakeBand{{9, 40, 40}, {14, 55, 55}},
+		pregnancy:   60,
+		labTest:     "Plasma selenium",
+		foodSources: []string{"Brazil nuts", "Fish and seafood", "Eggs", "Whole grains"},
+		symptoms:    []string{"Fatigue", "Hair loss", "Weakened immunity"},
+		form:        "Selenomethionine",
+		timing:      "With food",
+		precautions: []string{"Do not exceed 400 µg a day"},
+	},
+}
+
+// Risk modifiers and the extra risk points they add per nutrient
+var (
+	veganRisk = map[string]int{"vitamin_b12": 3, "iron": 1, "zinc": 1, "calcium": 1, "vitamin_d": 1, "iodine": 1}
+
+	vegetarianRisk = map[string]int{"vitamin_b12": 2, "iron": 1, "zinc": 1}
+
+	pregnancyRisk = map[string]int{"folate": 2, "iron": 2, "iodine": 1, "vitamin_d": 1}
+
+	malabsorptionRisk = map[string]int{"vitamin_a": 2, "vitamin_d": 2, "vitamin_e": 2, "vitamin_k": 2,
+		"vitamin_b12": 2, "iron": 1, "folate": 1, "calcium": 1, "zinc": 1, "magnesium": 1}
+
+	// medicationRiskPoints is added for each nutrient a medication depletes
+	medicationRiskPoints = 2
+	// supplementRiskCredit is removed when the user already supplements a nutrient
+	supplementRiskCredit = 2
+)
+
+var malabsorptionConditions = []string{"celiac", "coeliac", "crohn", "ulcerative colitis", "inflammatory bowel",
+	"ibd", "bariatric", "gastric bypass", "gastric sleeve", "short bowel", "pancreatic insufficiency",
+	"chronic pancreatitis", "cystic fibrosis", "atrophic gastritis", "malabsorption"}
+
+// depletionTerms mark a medication's nutritional effect as lowering a nutrient
+var depletionTerms = []string{"deplet", "deficien", "decrease", "reduc", "lower", "impair", "malabsor",
+	"loss", "block", "inhibit", "interfere"}
+
+// NutrientDeficiencyService estimates micronutrient deficiency risk from the
+// food diary and the user's profile
+type NutrientDeficiencyService struct {
+	db *sql.DB
+}
+
+// NewNutrientDeficiencyService creates a new NutrientDeficiencyService
+func NewNutrientDeficiencyService(db *sql.DB) *NutrientDeficiencyService {
+	return &NutrientDeficiencyService{db: db}
+}
+
+// deficiencyProfile gathers what affects a user's deficiency risk
+type deficiencyProfile struct {
+	age           int
+	sex           string
+	vegan         bool
+	vegetarian    bool
+	pregnant      bool
+	malabsorption []string
+	medications   map[string][]string // nutrient -> medications depleting it
+	supplements   map[string][]string // nutrient -> supplements supplying it
+}
+
+// nutrientIntake is the average daily intake of one nutrient, known only when
+// most diary entries record it
+type nutrientIntake struct {
+	average float64
+	known   bool
+}
+
+// AnalyzeDeficiencies compares userID's average daily micronutrient intake over
+// the last req.Days days with the reference intake for their age, sex and
+// pregnancy, and raises each nutrient's risk for a plant-based diet,
+// pregnancy, malabsorption conditions and medications that affect nutrition.
+// Nutrients already supplemented carry less risk.
+func (s *NutrientDeficiencyService) AnalyzeDeficiencies(ctx context.Context, userID string, req models.NutrientDeficiencyRequest) (*models.NutrientDeficiencyAnalysis, error) {
+	days := req.Days
+	if days == 0 {
+		days = defaultDeficiencyDays
+	}
+	if days < minDiaryDays || days > 90 {
+		return nil, fmt.Errorf("%w: days must be between %d and 90", ErrInvalidDeficiencyAnalysis, minDiaryDays)
+	}
+
+	now := time.Now().UTC()
+	profile, err := s.loadProfile(ctx, userID, req, now)
+	if err != nil {
+		return nil, err
+	}
+
+	end := startOfDay(now).AddDate(0, 0, 1)
+	entries, err := NewFoodLogService(s.db).ListFoodLogs(ctx, userID, end.AddDate(0, 0, -days), end)
+	if err != nil {
+		return nil, err
+	}
+	intakes, daysLogged := averageIntakes(entries)
+
+	analysis := &models.NutrientDeficiencyAnalysis{
+		UserID:                    userID,
+		PotentialDeficiencies:     []models.PotentialDeficiency{},
+		RecommendedTests:          []string{},
+		DietaryRecommendations:    []string{},
+		SupplementRecommendations: []models.SupplementRecommendation{},
+		LifestyleFactors:          profile.lifestyleFactors(),
+		GeneratedAt:               now,
+	}
+	if daysLogged < minDiaryDays {
+		analysis.LifestyleFactors = append(analysis.LifestyleFactors, fmt.Sprintf(
+			"Only %d of the last %d days are logged in the food diary; intake was not assessed", daysLogged, days))
+	} else if daysLogged < days {
+		analysis.LifestyleFactors = append(analysis.LifestyleFactors, fmt.Sprintf(
+			"Intake averaged over the %d of the last %d days logged in the food diary", daysLogged, days))
+	}
+
+	sources, err := s.loadNutrientReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type scoredDeficiency struct {
+		deficiency models.PotentialDeficiency
+		reference  referenceIntake
+		score      int
+	}
+	var scored []scoredDeficiency
+	for _, ref := range referenceIntakes {
+		target := ref.intakeFor(profile)
+		unit := models.MicronutrientUnits[ref.nutrient]
+		score := 0
+		var factors []string
+
+		intake := intakes[ref.nutrient]
+		if intake.known && daysLogged >= minDiaryDays {
+			percent := intake.average / target * 100
+			switch {
+			case percent < 50:
+				score += 3
+			case percent < 75:
+				score += 2
+			case percent < 100:
+				score++
+			}
+			if percent < 100 {
+				factors = append(factors, fmt.Sprintf("Average intake %s %s/day is %.0f%% of the %s %s reference",
+					formatAmount(intake.average), unit, percent, formatAmount(target), unit))
+			}
+		}
+
+		if profile.vegan {
+			if points := veganRisk[ref.nutrient]; points > 0 {
+				score += points
+				factors = append(factors, "Vegan diet")
+			}
+		} else if profile.vegetarian {
+			if points := vegetarianRisk[ref.nutrient]; points > 0 {
+				score += points
+				factors = append(factors, "Vegetarian diet")
+			}
+		}
+		if profile.pregnant && pregnancyRisk[ref.nutrient] > 0 {
+			score += pregnancyRisk[ref.nutrient]
+			factors = append(factors, "Pregnancy increases requirements")
+		}
+		if len(profile.malabsorption) > 0 && malabsorptionRisk[ref.nutrient] > 0 {
+			score += malabsorptionRisk[ref.nutrient]
+			factors = append(factors, "Malabsorption: "+strings.Join(profile.malabsorption, ", "))
+		}
+		for _, medication := range profile.medications[ref.nutrient] {
+			score += medicationRiskPoints
+			factors = append(factors, fmt.Sprintf("%s affects %s status", medication, ref.name))
+		}
+		if score == 0 {
+			continue
+		}
+		if !intake.known && len(factors) > 0 {
+			factors = append(factors, "Intake not tracked in the food diary")
+		}
+		if supplements := profile.supplements[ref.nutrient]; len(supplements) > 0 {
+			score -= supplementRiskCredit
+			factors = append(factors, "Already supplemented: "+strings.Join(supplements, ", "))
+		}
+		if score <= 0 {
+			continue
+		}
+
+		reference := sources[ref.nutrient]
+		scored = append(scored, scoredDeficiency{
+			deficiency: models.PotentialDeficiency{
+				Nutrient:        ref.name,
+				RiskLevel:       deficiencyRiskLevel(score),
+				RiskFactors:     factors,
+				Symptoms:        nonEmptyList(reference.symptoms, ref.symptoms),
+				FoodSources:     nonEmptyList(reference.foodSources, ref.foodSources),
+				RecommendedDose: fmt.Sprintf("%s %s/day", formatAmount(target), unit),
+			},
+			reference: ref,
+			score:     score,
+		})
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+
+	highest := "none"
+	for _, item := range scored {
+		analysis.PotentialDeficiencies = append(analysis.PotentialDeficiencies, item.deficiency)
+		level := item.deficiency.RiskLevel
+		if level == "low" {
+			continue
+		}
+		if highest != "high" {
+			highest = level
+		}
+
+		analysis.RecommendedTests = append(analysis.RecommendedTests, item.reference.labTest)
+		sources := item.deficiency.FoodSources
+		if len(sources) > 3 {
+			sources = sources[:3]
+		}
+		analysis.DietaryRecommendations = append(analysis.DietaryRecommendations,
+			fmt.Sprintf("Eat more foods rich in %s, such as %s", strings.ToLower(item.reference.name), strings.Join(sources, "; ")))
+
+		if level == "high" {
+			analysis.SupplementRecommendations = append(analysis.SupplementRecommendations, models.SupplementRecommendation{
+				Nutrient:         item.reference.name,
+				RecommendedDose:  item.deficiency.RecommendedDose,
+				Form:             item.reference.form,
+				Timing:           item.reference.timing,
+				Duration:         "8-12 weeks, then retest",
+				Precautions:      append([]string{"Confirm the deficiency with a blood test before high-dose supplementation"}, item.reference.precautions...),
+				MonitoringNeeded: item.reference.monitor,
+			})
+		}
+	}
+	analysis.RecommendedTests = uniqueStrings(analysis.RecommendedTests)
+
+	switch highest {
+	case "high":
+		analysis.FollowUpTimeline = "Discuss the recommended tests with a clinician and reassess in 4 weeks"
+	case "moderate":
+		analysis.FollowUpTimeline = "Improve intake from food sources and reassess in 8 weeks"
+	default:
+		analysis.FollowUpTimeline = "Reassess in 3 months"
+	}
+	return analysis, nil
+}
+
+func (s *NutrientDeficiencyService) loadProfile(ctx context.Context, userID string, req models.NutrientDeficiencyRequest, now time.Time) (*deficiencyProfile, error) {
+	var dateOfBirth, gender, restrictions sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT date_of_birth, gender, dietary_restrictions FROM users WHERE id = ?`, userID).
+		Scan(&dateOfBirth, &gender, &restrictions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	profile := &deficiencyProfile{
+		age:         ageOn(dateOfBirth.String, now),
+		sex:         strings.ToLower(strings.TrimSpace(gender.String)),
+		medications: map[string][]string{},
+		supplements: map[string][]string{},
+	}
+
+	// A plant-based diet shows in the profile's restrictions or the type of the latest meal plan
+	diets := decodeStringList(restrictions.String)
+	var planType sql.NullString
+	err = s.db.QueryRowContext(ctx,
+		`SELECT plan_type FROM meal_plans WHERE user_id = ? ORDER BY created_at DESC LIMIT 1`, userID).Scan(&planType)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get meal plan: %w", err)
+	}
+	diets = append(diets, planType.String)
+	for _, diet := range diets {
+		switch matchText(diet) {
+		case "vegan", "plant based":
+			profile.vegan = true
+		case "vegetarian", "lacto vegetarian", "ovo vegetarian", "lacto ovo vegetarian":
+			profile.vegetarian = true
+		}
+	}
+
+	// Pregnancy and conditions come from the request and active health complaints
+	conditions := append([]string{}, req.Conditions...)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT complaint_type FROM user_health_complaints WHERE user_id = ? AND COALESCE(status, 'active') = 'active'`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load health complaints: %w", err)
+	}
+	for rows.Next() {
+		var complaint string
+		if err := rows.Scan(&complaint); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan health complaint: %w", err)
+		}
+		conditions = append(conditions, complaint)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, condition := range conditions {
+		normalized := matchText(condition)
+		if strings.Contains(normalized, "pregnan") {
+			profile.pregnant = true
+		}
+		for _, term := range malabsorptionConditions {
+			if mentionsName(normalized, term) {
+				profile.malabsorption = append(profile.malabsorption, strings.TrimSpace(condition))
+				break
+			}
+		}
+	}
+	if req.Pregnant != nil {
+		profile.pregnant = *req.Pregnant
+	}
+	if profile.sex == "male" {
+		profile.pregnant = false
+	}
+	profile.malabsorption = uniqueStrings(profile.malabsorption)
+
+	if err := s.loadMedicationEffects(ctx, userID, profile); err != nil {
+		return nil, err
+	}
+
+	supplements, err := loadActiveSupplements(ctx, s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range referenceIntakes {
+		for _, supplement := range supplements {
+			if namesMatch(supplement.keys, ref.aliases) {
+				profile.supplements[ref.nutrient] = append(profile.supplements[ref.nutrient], supplement.display)
+			}
+		}
+	}
+	return profile, nil
+}
+
+// loadMedicationEffects records which nutrients the user's active medications
+// deplete, read from the medications' nutritional_effects
+func (s *NutrientDeficiencyService) loadMedicationEffects(ctx context.Context, userID string, profile *deficiencyProfile) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.name, COALESCE(m.nutritional_effects, '{}')
+		FROM user_medications um
+		JOIN medications m ON m.id = um.medication_id
+		WHERE um.user_id = ? AND COALESCE(um.is_active, 1) = 1 AND m.affects_nutrition = 1
+		  AND (um.end_date IS NULL OR um.end_date = '' OR date(um.end_date) >= date('now'))
+		ORDER BY m.name`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user medications: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, raw string
+		if err := rows.Scan(&name, &raw); err != nil {
+			return fmt.Errorf("failed to scan user medication: %w", err)
+		}
+		var effects interface{}
+		if err := json.Unmarshal([]byte(raw), &effects); err != nil {
+			continue
+		}
+		statements := flattenEffects("", effects)
+		for _, ref := range referenceIntakes {
+			for _, statement := range statements {
+				if mentionsAnyName(statement, ref.aliases) && containsAny(statement, depletionTerms) {
+					profile.medications[ref.nutrient] = append(profile.medications[ref.nutrient], name)
+					break
+				}
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// intakeFor returns the reference intake for the profile. An unknown sex uses
+// the higher of the male and female values, an unknown age the adult values.
+func (r referenceIntake) intakeFor(profile *deficiencyProfile) float64 {
+	if profile.pregnant && r.pregnancy > 0 {
+		return r.pregnancy
+	}
+	age := profile.age
+	if age == 0 {
+		age = 19
+	}
+	band := r.bands[0]
+	for _, candidate := range r.bands {
+		if age >= candidate.minAge {
+			band = candidate
+		}
+	}
+	switch profile.sex {
+	case "male":
+		return band.male
+	case "female":
+		return band.female
+	}
+	if band.male > band.female {
+		return band.male
+	}
+	return band.female
+}
+
+func (p *deficiencyProfile) lifestyleFactors() []string {
+	factors := []string{}
+	if p.vegan {
+		factors = append(factors, "Vegan diet excludes the main sources of vitamin B12")
+	} else if p.vegetarian {
+		factors = append(factors, "Vegetarian diet lowers the intake of well-absorbed iron and zinc")
+	}
+	if p.pregnant {
+		factors = append(factors, "Pregnancy raises the reference intakes for folate, iron and iodine")
+	}
+	if len(p.malabsorption) > 0 {
+		factors = append(factors, "Malabsorption condition: "+strings.Join(p.malabsorption, ", "))
+	}
+	medications := map[string]bool{}
+	for _, names := range p.medications {
+		for _, name := range names {
+			medications[name] = true
+		}
+	}
+	for _, name := range sortedKeys(medications) {
+		factors = append(factors, "Takes "+name+", which affects nutrient status")
+	}
+	if p.age == 0 || (p.sex != "male" && p.sex != "female") {
+		factors = append(factors, "Age or sex missing from the profile; adult reference intakes were used")
+	}
+	return factors
+}
+
+// nutrientSources holds the food sources and symptoms recorded in the
+// vitamins_minerals table for one nutrient
+type nutrientSources struct {
+	foodSources []string
+	symptoms    []string
+}
+
+func (s *NutrientDeficiencyService) loadNutrientReferences(ctx context.Context) (map[string]nutrientSources, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, COALESCE(food_sources, '[]'), COALESCE(deficiency_symptoms, '[]') FROM vitamins_minerals`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vitamins and minerals: %w", err)
+	}
+	defer rows.Close()
+
+	sources := map[string]nutrientSources{}
+	for rows.Next() {
+		var name, foods, symptoms string
+		if err := rows.Scan(&name, &foods, &symptoms); err != nil {
+			return nil, fmt.Errorf("failed to scan vitamins and minerals: %w", err)
+		}
+		for _, ref := range referenceIntakes {
+			if _, seen := sources[ref.nutrient]; seen || !mentionsAnyName(name, ref.aliases) {
+				continue
+			}
+			sources[ref.nutrient] = nutrientSources{
+				foodSources: decodeStringList(foods),
+				symptoms:    decodeStringList(symptoms),
+			}
+			break
+		}
+	}
+	return sources, rows.Err()
+}
+
+// averageIntakes averages each micronutrient over the days with diary entries
+func averageIntakes(entries []*models.UserFoodLog) (map[string]nutrientIntake, int) {
+	loggedDays := map[string]bool{}
+	totals := map[string]float64{}
+	recorded := map[string]int{}
+	for _, entry := range entries {
+		loggedDays[entry.ConsumedAt.Format("2006-01-02")] = true
+		amounts := map[string]float64{}
+		for nutrient, amount := range entry.Micronutrients {
+			amounts[nutrient] = amount
+		}
+		if entry.Potassium > 0 {
+			amounts["potassium"] = entry.Potassium
+		}
+		for nutrient, amount := range amounts {
+			totals[nutrient] += amount
+			recorded[nutrient]++
+		}
+	}
+
+	intakes := map[string]nutrientIntake{}
+	if len(loggedDays) == 0 {
+		return intakes, 0
+	}
+	for nutrient, total := range totals {
+		intakes[nutrient] = nutrientIntake{
+			average: total / float64(len(loggedDays)),
+			// Sparse data would understate intake, so it is not used as evidence
+			known: recorded[nutrient]*2 >= len(entries),
+		}
+	}
+	return intakes, len(loggedDays)
+}
+
+func deficiencyRiskLevel(score int) string {
+	switch {
+	case score >= 4:
+		return "high"
+	case score >= 2:
+		return "moderate"
+	default:
+		return "low"
+	}
+}
+
+// flattenEffects turns nested nutritional effects into "key: value" statements
+func flattenEffects(prefix string, value interface{}) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var statements []string
+		for key, item := range v {
+			statements = append(statements, flattenEffects(strings.TrimSpace(prefix+" "+key), item)...)
+		}
+		return statements
+	case []interface{}:
+		var statements []string
+		for _, item := range v {
+			statements = append(statements, flattenEffects(prefix, item)...)
+		}
+		return statements
+	case string:
+		return []string{prefix + ": " + v}
+	case bool:
+		if v {
+			return []string{prefix}
+		}
+	}
+	return nil
+}
+
+func containsAny(text string, terms []string) bool {
+	normalized := normalizeDrugName(text)
+	for _, term := range terms {
+		if strings.Contains(normalized, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmptyList(values, fallback []string) []string {
+	if len(values) > 0 {
+		return values
+	}
+	return fallback
+}
+
+func formatAmount(value float64) string {
+	return strconv.FormatFloat(round1(value), 'f', -1, 64)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findDeficiency(analysis *models.NutrientDeficiencyAnalysis, nutrient string) *models.PotentialDeficiency {
+	for i := range analysis.PotentialDeficiencies {
+		if analysis.PotentialDeficiencies[i].Nutrient == nutrient {
+			return &analysis.PotentialDeficiencies[i]
+		}
+	}
+	return nil
+}
+
+func TestNutrientDeficiencyService_AnalyzeDeficiencies(t *testing.T) {
+	ctx := context.Background()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(ctx, CreateUserInput{Email: "vegan@example.com", Password: "password123"})
+	require.NoError(t, err)
+	db := users.db
+
+	_, err = db.Exec(`UPDATE users SET date_of_birth = '1994-05-01', gender = 'female', dietary_restrictions = '["vegan"]'
+		WHERE id = ?`, user.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO foods (id, name, calories_per_100g, protein_per_100g, carbs_per_100g, fat_per_100g,
+		micronutrients_per_100g) VALUES ('lentils', 'Lentils', 116, 9, 20, 0.4,
+		'{"iron": 3.3, "folate": 181, "zinc": 1.3, "magnesium": 36, "calcium": 19}')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO medications (id, name, affects_nutrition, nutritional_effects)
+		VALUES ('metformin', 'Metformin', 1, '{"vitamin_b12": "reduced absorption with long-term use"}')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_medications (user_id, medication_id) VALUES (?, 'metformin')`, user.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_supplements (user_id, supplement_name) VALUES (?, 'Cyanocobalamin')`, user.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_health_complaints (user_id, complaint_type) VALUES (?, 'Celiac disease')`, user.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO vitamins_minerals (name, food_sources, deficiency_symptoms)
+		VALUES ('Iron', '["Lentils", "Red meat"]', '["Fatigue"]')`)
+	require.NoError(t, err)
+
+	diary := NewFoodLogService(db)
+	for day := 1; day <= 4; day++ {
+		date := time.Now().UTC().AddDate(0, 0, -day).Format("2006-01-02")
+		entry, err := diary.LogMeal(ctx, user.ID, models.LogMealRequest{
+			FoodID: stringPtr("lentils"), MealType: "lunch", Quantity: 200, Unit: "g", Date: date,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 6.6, entry.Micronutrients["iron"])
+	}
+
+	svc := NewNutrientDeficiencyService(db)
+	analysis, err := svc.AnalyzeDeficiencies(ctx, user.ID, models.NutrientDeficiencyRequest{})
+	require.NoError(t, err)
+
+	// 6.6 of 18 mg iron is under half the reference, on top of a vegan diet and celiac disease
+	iron := findDeficiency(analysis, "Iron")
+	require.NotNil(t, iron)
+	assert.Equal(t, "high", iron.RiskLevel)
+	assert.Equal(t, "18 mg/day", iron.RecommendedDose)
+	assert.Equal(t, []string{"Lentils", "Red meat"}, iron.FoodSources)
+	assert.Equal(t, []string{"Fatigue"}, iron.Symptoms)
+	assert.Contains(t, iron.RiskFactors, "Average intake 6.6 mg/day is 37% of the 18 mg reference")
+	assert.Contains(t, iron.RiskFactors, "Vegan diet")
+	assert.Contains(t, iron.RiskFactors, "Malabsorption: Celiac disease")
+
+	// Folate intake is 90% of the reference and celiac disease adds to the risk
+	folate := findDeficiency(analysis, "Folate")
+	require.NotNil(t, folate)
+	assert.Equal(t, "moderate", folate.RiskLevel)
+
+	// Vitamin B12 is untracked: vegan diet, malabsorption and metformin, less the supplement
+	b12 := findDeficiency(analysis, "Vitamin B12")
+	require.NotNil(t, b12)
+	assert.Equal(t, "high", b12.RiskLevel)
+	assert.Contains(t, b12.RiskFactors, "Metformin affects Vitamin B12 status")
+	assert.Contains(t, b12.RiskFactors, "Intake not tracked in the food diary")
+	assert.Contains(t, b12.RiskFactors, "Already supplemented: Cyanocobalamin")
+
+	assert.Nil(t, findDeficiency(analysis, "Vitamin C"))
+	assert.Contains(t, analysis.RecommendedTests, "Serum ferritin and complete blood count")
+	assert.Contains(t, analysis.LifestyleFactors, "Takes Metformin, which affects nutrient status")
+	assert.Contains(t, analysis.LifestyleFactors, "Intake averaged over the 4 of the last 14 days logged in the food diary")
+	assert.Equal(t, "Discuss the recommended tests with a clinician and reassess in 4 weeks", analysis.FollowUpTimeline)
+
+	var supplemented []string
+	for _, recommendation := range analysis.SupplementRecommendations {
+		supplemented = append(supplemented, recommendation.Nutrient)
+	}
+	assert.Contains(t, supplemented, "Iron")
+	assert.NotContains(t, supplemented, "Folate")
+}
+
+func TestNutrientDeficiencyService_Pregnancy(t *testing.T) {
+	ctx := context.Background()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(ctx, CreateUserInput{Email: "expecting@example.com", Password: "password123"})
+	require.NoError(t, err)
+	svc := NewNutrientDeficiencyService(users.db)
+
+	pregnant := true
+	analysis, err := svc.AnalyzeDeficiencies(ctx, user.ID, models.NutrientDeficiencyRequest{Days: 7, Pregnant: &pregnant})
+	require.NoError(t, err)
+
+	folate := findDeficiency(analysis, "Folate")
+	require.NotNil(t, folate)
+	assert.Equal(t, "moderate", folate.RiskLevel)
+	assert.Equal(t, "600 µg/day", folate.RecommendedDose)
+	assert.Equal(t, []string{"Pregnancy increases requirements", "Intake not tracked in the food diary"}, folate.RiskFactors)
+	assert.Equal(t, "low", findDeficiency(analysis, "Vitamin D").RiskLevel)
+	assert.Nil(t, findDeficiency(analysis, "Zinc"))
+	assert.Empty(t, analysis.SupplementRecommendations)
+	assert.Contains(t, analysis.LifestyleFactors, "Only 0 of the last 7 days are logged in the food diary; intake was not assessed")
+	assert.Contains(t, analysis.LifestyleFactors, "Age or sex missing from the profile; adult reference intakes were used")
+	assert.Equal(t, "Improve intake from food sources and reassess in 8 weeks", analysis.FollowUpTimeline)
+
+	_, err = svc.AnalyzeDeficiencies(ctx, user.ID, models.NutrientDeficiencyRequest{Days: 365})
+	assert.ErrorIs(t, err, ErrInvalidDeficiencyAnalysis)
+
+	_, err = svc.AnalyzeDeficiencies(ctx, "nobody", models.NutrientDeficiencyRequest{})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
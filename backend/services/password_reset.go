@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password reset errors returned by UserService
+var (
+	ErrInvalidResetToken   = errors.New("invalid or expired reset token")
+	ErrMailerNotConfigured = errors.New("mailer not configured")
+)
+
+const defaultResetTokenTTL = time.Hour
+
+// SetPasswordResetMailer configures how reset links are delivered. resetURL is
+// the page that receives the token as its "token" query parameter.
+func (s *UserService) SetPasswordResetMailer(mailer Mailer, resetURL string) {
+	s.mailer = mailer
+	s.resetURL = resetURL
+}
+
+// SetResetTokenTTL configures how long password reset tokens stay valid
+func (s *UserService) SetResetTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.resetTokenTTL = ttl
+	}
+}
+
+// RequestPasswordReset emails a single-use reset link to the account. Unknown
+// and disabled accounts are ignored and delivery failures are only logged, so
+// callers cannot probe which emails exist.
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.resetTokenTTL)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Only the most recently requested link stays usable
+	_, err = tx.ExecContext(ctx,
+		`UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate previous reset tokens: %w", err)
+	}
+
+	query := `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	if _, err := tx.ExecContext(ctx, query, uuid.New().String(), user.ID, hashToken(token), expiresAt, now); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reset token: %w", err)
+	}
+
+	if err := s.mailer.Send(ctx, EmailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    passwordResetBody(s.resetLink(token), s.resetTokenTTL),
+	}); err != nil {
+		log.Printf("password reset: failed to email user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token, consumes the token
+// and revokes every session of the account
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		tokenID, userID string
+		expiresAt       time.Time
+		usedAt          sql.NullTime
+	)
+	query := `SELECT id, user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash = ?`
+	err = tx.QueryRowContext(ctx, query, hashToken(token)).Scan(&tokenID, &userID, &expiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("failed to look up reset token: %w", err)
+	}
+	if usedAt.Valid || !now.Before(expiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	// The conditional update guarantees the token is consumed exactly once
+	result, err := tx.ExecContext(ctx,
+		`UPDATE password_reset_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to consume reset token: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrInvalidResetToken
+	}
+
+	query = `
+		UPDATE users
+		SET password_hash = ?, failed_login_attempts = 0, locked_until = NULL, updated_at = ?
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, string(hash), now, userID); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := revokeSessions(ctx, tx, `user_id = ?`, []interface{}{userID}, SessionRevokedPasswordReset, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit password reset: %w", err)
+	}
+
+	return nil
+}
+
+func (s *UserService) resetLink(token string) string {
+	return linkWithToken(s.resetURL, token)
+}
+
+func passwordResetBody(link string, ttl time.Duration) string {
+	return fmt.Sprintf(`We received a request to reset your password.
+
+Use the link below to choose a new password. It expires in %d minutes and can only be used once.
+
+%s
+
+If you did not request a password reset, you can ignore this email.
+`, int(ttl.Minutes()), link)
+}
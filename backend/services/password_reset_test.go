@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var resetLinkPattern = regexp.MustCompile(`https?://\S+`)
+
+func resetTokenFromEmail(t *testing.T, msg EmailMessage) string {
+	t.Helper()
+
+	link, err := url.Parse(resetLinkPattern.FindString(msg.Body))
+	require.NoError(t, err)
+	token := link.Query().Get("token")
+	require.NotEmpty(t, token)
+	return token
+}
+
+func TestUserService_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	outboxDir := t.TempDir()
+	outbox, err := NewOutboxMailer(outboxDir)
+	require.NoError(t, err)
+	svc.SetPasswordResetMailer(outbox, "https://app.example.com/reset-password")
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Email: "reset@example.com", Password: "password123"})
+	require.NoError(t, err)
+	session, _, err := svc.CreateSession(ctx, user.ID, SessionClient{})
+	require.NoError(t, err)
+
+	// Unknown emails are accepted silently and send nothing
+	require.NoError(t, svc.RequestPasswordReset(ctx, "nobody@example.com"))
+	assert.Empty(t, outbox.Messages())
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, "Reset@Example.com"))
+	messages := outbox.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "reset@example.com", messages[0].To)
+
+	files, err := os.ReadDir(outboxDir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	token := resetTokenFromEmail(t, messages[0])
+
+	// Only the hash is stored
+	var stored int
+	require.NoError(t, svc.db.QueryRow("SELECT COUNT(*) FROM password_reset_tokens WHERE token_hash = ?", token).Scan(&stored))
+	assert.Zero(t, stored)
+
+	assert.ErrorIs(t, svc.ResetPassword(ctx, "bogus-token", "newpassword1"), ErrInvalidResetToken)
+	require.NoError(t, svc.ResetPassword(ctx, token, "newpassword1"))
+
+	// Tokens are single-use
+	assert.ErrorIs(t, svc.ResetPassword(ctx, token, "newpassword2"), ErrInvalidResetToken)
+
+	_, err = svc.Authenticate(ctx, "reset@example.com", "password123")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = svc.Authenticate(ctx, "reset@example.com", "newpassword1")
+	assert.NoError(t, err)
+
+	active, err := svc.IsSessionActive(ctx, session.ID)
+	require.NoError(t, err)
+	assert.False(t, active)
+}
+
+func TestUserService_PasswordResetSupersededAndExpired(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	outbox, err := NewOutboxMailer("")
+	require.NoError(t, err)
+	svc.SetPasswordResetMailer(outbox, "https://app.example.com/reset-password")
+
+	_, err = svc.CreateUser(ctx, CreateUserInput{Email: "expire@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, "expire@example.com"))
+	require.NoError(t, svc.RequestPasswordReset(ctx, "expire@example.com"))
+	messages := outbox.Messages()
+	require.Len(t, messages, 2)
+
+	// Requesting a new link invalidates the previous one
+	first := resetTokenFromEmail(t, messages[0])
+	assert.ErrorIs(t, svc.ResetPassword(ctx, first, "newpassword1"), ErrInvalidResetToken)
+
+	_, err = svc.db.Exec("UPDATE password_reset_tokens SET expires_at = datetime('now', '-1 minute')")
+	require.NoError(t, err)
+
+	second := resetTokenFromEmail(t, messages[1])
+	assert.ErrorIs(t, svc.ResetPassword(ctx, second, "newpassword1"), ErrInvalidResetToken)
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, EmailMessage) error {
+	return errors.New("smtp: connection refused")
+}
+
+func TestUserService_PasswordResetHidesDeliveryFailures(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+	svc.SetPasswordResetMailer(failingMailer{}, "https://app.example.com/reset-password")
+
+	_, err := svc.CreateUser(ctx, CreateUserInput{Email: "smtp@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	// A registered email answers like an unknown one even when sending fails
+	assert.NoError(t, svc.RequestPasswordReset(ctx, "smtp@example.com"))
+	assert.NoError(t, svc.RequestPasswordReset(ctx, "nobody@example.com"))
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Personal record errors returned by PersonalRecordService
+var (
+	ErrPersonalRecordNotFound = errors.New("personal record not found")
+)
+
+const (
+	// maxEstimateReps is the longest set a 1RM is estimated from; beyond it
+	// a set says more about endurance than about strength
+	maxEstimateReps = 12
+
+	// recordEpsilon keeps rounding noise from counting as a new record
+	recordEpsilon = 0.01
+
+	// recentRecordDays is the window of PersonalRecordStats.RecentAchievements
+	recentRecordDays = 30
+
+	poundsToKg = 0.45359237
+)
+
+const personalRecordColumns = `id, user_id, exercise_id, exercise_name, record_type, value, unit, weight, reps,
+	previous_value, achieved_at, notes, workout_session_id, created_at`
+
+// PersonalRecordService detects personal records in logged workouts and
+// stores them
+type PersonalRecordService struct {
+	db         *sql.DB
+	milestones *MilestoneService
+}
+
+// NewPersonalRecordService creates a new personal record service
+func NewPersonalRecordService(db *sql.DB) *PersonalRecordService {
+	return &PersonalRecordService{
+		db:         db,
+		milestones: NewMilestoneService(db),
+	}
+}
+
+// liftSet is one set of a logged exercise, with the load in kg
+type liftSet struct {
+	weight float64
+	reps   int
+}
+
+// exerciseBests are the current bests of one exercise. Rep records are kept
+// with their loads: the rep best at a load is the most reps done at that load
+// or heavier.
+type exerciseBests struct {
+	oneRepMax float64
+	heaviest  float64
+	volume    float64
+	reps      []liftSet
+}
+
+// RecordWorkout compares the sets of a logged workout with userID's bests and
+// stores the records it sets: estimated 1RM, heaviest load, most reps at a
+// load and session volume per exercise. Exercises are matched by ID, or by
+// name when the log has no ID.
+func (s *PersonalRecordService) RecordWorkout(ctx context.Context, userID, sessionID string, performedAt time.Time, exercises []models.CompletedExercise) ([]*models.PersonalRecord, error) {
+	bests, err := s.loadBests(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*models.PersonalRecord{}
+	for _, exercise := range exercises {
+		exerciseID := strings.TrimSpace(exercise.ExerciseID)
+		name := strings.TrimSpace(exercise.ExerciseName)
+		key := exerciseKey(exerciseID, name)
+		if key == "" {
+			continue
+		}
+		if name == "" {
+			name = exerciseID
+		}
+		best := bests[key]
+		if best == nil {
+			best = &exerciseBests{}
+			bests[key] = best
+		}
+		for _, record := range best.detect(parseSets(exercise)) {
+			record.UserID = userID
+			record.ExerciseID = nonEmpty(&exerciseID)
+			record.ExerciseName = name
+			record.Date = performedAt.UTC()
+			record.WorkoutSessionID = nonEmpty(&sessionID)
+			records = append(records, record)
+		}
+	}
+
+	if len(records) > 0 {
+		if err := s.saveRecords(ctx, records); err != nil {
+			return nil, err
+		}
+	}
+
+	// The records are already stored, so a failed milestone check is only logged
+	if _, err := s.milestones.Evaluate(ctx, userID); err != nil {
+		log.Printf("Failed to evaluate milestones for user %s: %v", userID, err)
+	}
+
+	return records, nil
+}
+
+// ListRecords returns userID's records, newest first
+func (s *PersonalRecordService) ListRecords(ctx context.Context, userID string, req models.ListPersonalRecordsRequest) (*models.PersonalRecordListResponse, error) {
+	page := max(req.Page, 1)
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	where := `user_id = ?`
+	args := []interface{}{userID}
+	if req.ExerciseID != "" {
+		where += ` AND exercise_id = ?`
+		args = append(args, req.ExerciseID)
+	}
+	if req.RecordType != "" {
+		where += ` AND record_type = ?`
+		args = append(args, req.RecordType)
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM personal_records WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count personal records: %w", err)
+	}
+
+	records, err := s.queryRecords(ctx, `SELECT `+personalRecordColumns+` FROM personal_records WHERE `+where+`
+		ORDER BY achieved_at DESC, id DESC LIMIT ? OFFSET ?`, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PersonalRecordListResponse{
+		Records: records,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+		HasNext: page*limit < total,
+	}, nil
+}
+
+// Stats summarizes userID's records. RecordsByExercise holds the current best
+// of each record type per exercise name.
+func (s *PersonalRecordService) Stats(ctx context.Context, userID string) (*models.PersonalRecordStats, error) {
+	records, err := s.queryRecords(ctx, `SELECT `+personalRecordColumns+` FROM personal_records
+		WHERE user_id = ? ORDER BY achieved_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.PersonalRecordStats{
+		TotalRecords:       len(records),
+		RecentRecords:      records[:min(len(records), 10)],
+		RecordsByType:      map[string]int{},
+		RecordsByExercise:  map[string][]models.PersonalRecord{},
+		RecentAchievements: []models.PersonalRecord{},
+	}
+
+	now := time.Now().UTC()
+	recentSince := now.AddDate(0, 0, -recentRecordDays)
+	var recent, previous int
+	bestIndex := map[string]int{}
+	for _, record := range records {
+		stats.RecordsByType[record.RecordType]++
+		if !record.Date.Before(recentSince) {
+			stats.RecentAchievements = append(stats.RecentAchievements, record)
+			recent++
+		} else if !record.Date.Before(recentSince.AddDate(0, 0, -recentRecordDays)) {
+			previous++
+		}
+
+		key := record.ExerciseName + "\x00" + record.RecordType
+		if i, ok := bestIndex[key]; ok {
+			if bests := stats.RecordsByExercise[record.ExerciseName]; record.Value > bests[i].Value {
+				bests[i] = record
+			}
+			continue
+		}
+		bestIndex[key] = len(stats.RecordsByExercise[record.ExerciseName])
+		stats.RecordsByExercise[record.ExerciseName] = append(stats.RecordsByExercise[record.ExerciseName], record)
+	}
+
+	switch {
+	case len(records) == 0:
+		stats.ProgressTrend = "no_records"
+	case recent > previous:
+		stats.ProgressTrend = "improving"
+	case recent > 0:
+		stats.ProgressTrend = "steady"
+	default:
+		stats.ProgressTrend = "stalled"
+	}
+
+	return stats, nil
+}
+
+// DeleteRecord removes one of userID's records, for example one set by a
+// mistyped load. The previous best becomes current again.
+func (s *PersonalRecordService) DeleteRecord(ctx context.Context, userID string, id uint) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM personal_records WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete personal record: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrPersonalRecordNotFound
+	}
+	return nil
+}
+
+// loadBests returns userID's bests keyed by exerciseKey
+func (s *PersonalRecordService) loadBests(ctx context.Context, userID string) (map[string]*exerciseBests, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT COALESCE(exercise_id, ''), exercise_name, record_type, value, weight
+		FROM personal_records WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load personal records: %w", err)
+	}
+	defer rows.Close()
+
+	bests := map[string]*exerciseBests{}
+	for rows.Next() {
+		var exerciseID, name, recordType string
+		var value float64
+		var weight sql.NullFloat64
+		if err := rows.Scan(&exerciseID, &name, &recordType, &value, &weight); err != nil {
+			return nil, fmt.Errorf("failed to scan personal record: %w", err)
+		}
+		key := exerciseKey(exerciseID, name)
+		best := bests[key]
+		if best == nil {
+			best = &exerciseBests{}
+			bests[key] = best
+		}
+		switch models.PersonalRecordType(recordType) {
+		case models.RecordEstimated1RM:
+			best.oneRepMax = math.Max(best.oneRepMax, value)
+		case models.RecordWeight:
+			best.heaviest = math.Max(best.heaviest, value)
+		case models.RecordVolume:
+			best.volume = math.Max(best.volume, value)
+		case models.RecordReps:
+			best.reps = append(best.reps, liftSet{weight: weight.Float64, reps: int(value)})
+		}
+	}
+	return bests, rows.Err()
+}
+
+func (s *PersonalRecordService) saveRecords(ctx context.Context, records []*models.PersonalRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, record := range records {
+		record.CreatedAt = now
+		result, err := tx.ExecContext(ctx, `INSERT INTO personal_records (user_id, exercise_id, exercise_name,
+			record_type, value, unit, weight, reps, previous_value, achieved_at, notes, workout_session_id, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			record.UserID, record.ExerciseID, record.ExerciseName, record.RecordType, record.Value, record.Unit,
+			record.Weight, record.Reps, record.PreviousValue, record.Date, record.Notes, record.WorkoutSessionID,
+			record.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save personal record: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to save personal record: %w", err)
+		}
+		record.ID = uint(id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit personal records: %w", err)
+	}
+	return nil
+}
+
+func (s *PersonalRecordService) queryRecords(ctx context.Context, query string, args ...interface{}) ([]models.PersonalRecord, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query personal records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []models.PersonalRecord{}
+	for rows.Next() {
+		record, err := scanPersonalRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+// detect returns the records sets beat and raises the bests to them. Rep
+// records are checked heaviest set first, so a lighter set only counts when
+// it beats the heavier sets of the same workout too.
+func (b *exerciseBests) detect(sets []liftSet) []*models.PersonalRecord {
+	var records []*models.PersonalRecord
+	var oneRepMax, volume float64
+	var topSet, heaviest liftSet
+	for _, set := range sets {
+		if set.weight <= 0 {
+			continue
+		}
+		volume += set.weight * float64(set.reps)
+		if estimate, ok := estimateOneRepMax(set.weight, set.reps); ok && estimate > oneRepMax {
+			oneRepMax, topSet = estimate, set
+		}
+		if set.weight > heaviest.weight || set.weight == heaviest.weight && set.reps > heaviest.reps {
+			heaviest = set
+		}
+	}
+
+	if oneRepMax = round1(oneRepMax); oneRepMax > b.oneRepMax+recordEpsilon {
+		records = append(records, newPersonalRecord(models.RecordEstimated1RM, oneRepMax, "kg", &topSet, b.oneRepMax))
+		b.oneRepMax = oneRepMax
+	}
+	if heaviest.weight > b.heaviest+recordEpsilon {
+		records = append(records, newPersonalRecord(models.RecordWeight, heaviest.weight, "kg", &heaviest, b.heaviest))
+		b.heaviest = heaviest.weight
+	}
+	if volume = round1(volume); volume > b.volume+recordEpsilon {
+		records = append(records, newPersonalRecord(models.RecordVolume, volume, "kg", nil, b.volume))
+		b.volume = volume
+	}
+
+	ordered := append([]liftSet(nil), sets...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].weight != ordered[j].weight {
+			return ordered[i].weight > ordered[j].weight
+		}
+		return ordered[i].reps > ordered[j].reps
+	})
+	for _, set := range ordered {
+		if previous := b.repsAt(set.weight); set.reps > previous {
+			records = append(records, newPersonalRecord(models.RecordReps, float64(set.reps), "reps", &set, float64(previous)))
+			b.reps = append(b.reps, set)
+		}
+	}
+
+	return records
+}
+
+// repsAt returns the most reps done at weight or heavier
+func (b *exerciseBests) repsAt(weight float64) int {
+	best := 0
+	for _, set := range b.reps {
+		if set.weight+recordEpsilon >= weight {
+			best = max(best, set.reps)
+		}
+	}
+	return best
+}
+
+func newPersonalRecord(recordType models.PersonalRecordType, value float64, unit string, set *liftSet, previous float64) *models.PersonalRecord {
+	record := &models.PersonalRecord{
+		RecordType: string(recordType),
+		Value:      value,
+		Unit:       unit,
+	}
+	if set != nil {
+		weight, reps := set.weight, set.reps
+		record.Weight = &weight
+		record.Reps = &reps
+	}
+	if previous > 0 {
+		record.PreviousValue = &previous
+	}
+	return record
+}
+
+// estimateOneRepMax averages the Epley and Brzycki estimates of the 1RM
+// behind a set. A single is its own 1RM, and sets longer than
+// maxEstimateReps are not estimated.
+func estimateOneRepMax(weight float64, reps int) (float64, bool) {
+	if weight <= 0 || reps < 1 || reps > maxEstimateReps {
+		return 0, false
+	}
+	if reps == 1 {
+		return weight, true
+	}
+	epley := weight * (1 + float64(reps)/30)
+	brzycki := weight * 36 / (37 - float64(reps))
+	return (epley + brzycki) / 2, true
+}
+
+// parseSets reads the per-set reps and loads of a logged exercise. A set
+// without its own load repeats the previous one; bodyweight sets have no load.
+func parseSets(exercise models.CompletedExercise) []liftSet {
+	sets := make([]liftSet, 0, len(exercise.RepsCompleted))
+	weight := 0.0
+	for i, value := range exercise.RepsCompleted {
+		if i < len(exercise.WeightUsed) {
+			weight = parseLoad(exercise.WeightUsed[i])
+		}
+		reps, _, ok := leadingNumber(value)
+		if !ok || reps < 1 {
+			continue
+		}
+		sets = append(sets, liftSet{weight: weight, reps: int(reps)})
+	}
+	return sets
+}
+
+// parseLoad reads a load such as "60", "60 kg" or "135lb" in kg. Loads it
+// cannot read, such as "bodyweight", are zero.
+func parseLoad(value string) float64 {
+	weight, unit, ok := leadingNumber(value)
+	if !ok || weight < 0 {
+		return 0
+	}
+	if strings.HasPrefix(unit, "lb") {
+		weight *= poundsToKg
+	}
+	return round2(weight)
+}
+
+// leadingNumber splits a logged value such as "8-10" or "62,5kg" into its
+// leading number and the lower-cased rest
+func leadingNumber(value string) (float64, string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	end := 0
+	for end < len(value) && (value[end] >= '0' && value[end] <= '9' || value[end] == '.' || value[end] == ',') {
+		end++
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value[:end], ",", "."), 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return number, strings.TrimSpace(value[end:]), true
+}
+
+func exerciseKey(exerciseID, name string) string {
+	if exerciseID != "" {
+		return "id:" + exerciseID
+	}
+	if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+		return "name:" + name
+	}
+	return ""
+}
+
+func scanPersonalRecord(row rowScanner) (*models.PersonalRecord, error) {
+	var record models.PersonalRecord
+	var exerciseID, notes, sessionID sql.NullString
+	var weight, previous sql.NullFloat64
+	var reps sql.NullInt64
+	err := row.Scan(&record.ID, &record.UserID, &exerciseID, &record.ExerciseName, &record.RecordType, &record.Value,
+		&record.Unit, &weight, &reps, &previous, &record.Date, &notes, &sessionID, &record.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan personal record: %w", err)
+	}
+	record.ExerciseID = nullStringPtr(exerciseID)
+	record.Notes = nullStringPtr(notes)
+	record.WorkoutSessionID = nullStringPtr(sessionID)
+	record.Reps = nullIntPtr(reps)
+	if weight.Valid {
+		record.Weight = &weight.Float64
+	}
+	if previous.Valid {
+		record.PreviousValue = &previous.Float64
+	}
+	return &record, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPersonalRecordService(t *testing.T) (*PersonalRecordService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "lifter@example.com", Password: "password123"})
+	require.NoError(t, err)
+	return NewPersonalRecordService(users.db), user.ID
+}
+
+func TestEstimateOneRepMax(t *testing.T) {
+	estimate, ok := estimateOneRepMax(100, 1)
+	require.True(t, ok)
+	assert.Equal(t, 100.0, estimate)
+
+	// Epley gives 116.7 and Brzycki 112.5
+	estimate, ok = estimateOneRepMax(100, 5)
+	require.True(t, ok)
+	assert.InDelta(t, 114.58, estimate, 0.01)
+
+	_, ok = estimateOneRepMax(100, maxEstimateReps+1)
+	assert.False(t, ok)
+	_, ok = estimateOneRepMax(0, 5)
+	assert.False(t, ok)
+}
+
+func TestParseSets(t *testing.T) {
+	sets := parseSets(models.CompletedExercise{
+		RepsCompleted: []string{"5", "5 reps", "8-10", "", "12"},
+		WeightUsed:    []string{"100 kg", "225lb", "", "", "bodyweight"},
+	})
+	assert.Equal(t, []liftSet{
+		{weight: 100, reps: 5},
+		{weight: 102.06, reps: 5},
+		{weight: 0, reps: 8},
+		{weight: 0, reps: 12},
+	}, sets)
+
+	sets = parseSets(models.CompletedExercise{RepsCompleted: []string{"6", "6"}, WeightUsed: []string{"62,5"}})
+	assert.Equal(t, []liftSet{{weight: 62.5, reps: 6}, {weight: 62.5, reps: 6}}, sets, "a set without a load repeats the previous one")
+}
+
+func recordValues(records []*models.PersonalRecord) map[string][]float64 {
+	values := map[string][]float64{}
+	for _, record := range records {
+		values[record.RecordType] = append(values[record.RecordType], record.Value)
+	}
+	return values
+}
+
+func TestPersonalRecordService_RecordWorkout(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestPersonalRecordService(t)
+	firstDay := time.Now().UTC().AddDate(0, 0, -7)
+
+	records, err := svc.RecordWorkout(ctx, userID, "", firstDay, []models.CompletedExercise{{
+		ExerciseName:  "Back Squat",
+		RepsCompleted: []string{"5", "5", "8"},
+		WeightUsed:    []string{"100", "100", "90"},
+	}, {
+		ExerciseName:  "Push-ups",
+		RepsCompleted: []string{"20"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]float64{
+		"estimated_1rm": {114.6},
+		"weight":        {100},
+		"volume":        {1720},
+		"reps":          {5, 8, 20},
+	}, recordValues(records))
+	for _, record := range records {
+		assert.NotZero(t, record.ID)
+		if record.RecordType == "reps" && *record.Weight == 90 {
+			require.NotNil(t, record.PreviousValue)
+			assert.Equal(t, 5.0, *record.PreviousValue, "beats the five reps at 100 kg")
+			continue
+		}
+		assert.Nil(t, record.PreviousValue, "the first workout sets baselines")
+	}
+
+	records, err = svc.RecordWorkout(ctx, userID, "", firstDay.AddDate(0, 0, 3), []models.CompletedExercise{{
+		ExerciseName:  "back squat",
+		RepsCompleted: []string{"3", "5"},
+		WeightUsed:    []string{"105 kg", "80 kg"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "weight", records[0].RecordType)
+	assert.Equal(t, 105.0, records[0].Value)
+	require.NotNil(t, records[0].PreviousValue)
+	assert.Equal(t, 100.0, *records[0].PreviousValue)
+	assert.Equal(t, "reps", records[1].RecordType)
+	assert.Equal(t, 3.0, records[1].Value, "the first set at 105 kg")
+	assert.Equal(t, "back squat", records[1].ExerciseName)
+
+	// Five reps at 80 kg do not beat eight at 90 kg
+	records, err = svc.RecordWorkout(ctx, userID, "", firstDay.AddDate(0, 0, 5), []models.CompletedExercise{{
+		ExerciseName:  "Back Squat",
+		RepsCompleted: []string{"5"},
+		WeightUsed:    []string{"80"},
+	}})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	milestones, err := svc.milestones.ListAchieved(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, milestones, 1)
+	assert.Equal(t, "first_personal_record", milestones[0].Key)
+	assert.Equal(t, startOfDay(firstDay), milestones[0].AchievedAt)
+}
+
+func TestPersonalRecordService_ListStatsDelete(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestPersonalRecordService(t)
+
+	exercise := func(reps, weight string) []models.CompletedExercise {
+		return []models.CompletedExercise{{
+			ExerciseID:    "bench-press",
+			ExerciseName:  "Bench Press",
+			RepsCompleted: []string{reps},
+			WeightUsed:    []string{weight},
+		}}
+	}
+	_, err := svc.RecordWorkout(ctx, userID, "", time.Now().AddDate(0, 0, -2), exercise("5", "80"))
+	require.NoError(t, err)
+	_, err = svc.RecordWorkout(ctx, userID, "", time.Now(), exercise("5", "85"))
+	require.NoError(t, err)
+
+	list, err := svc.ListRecords(ctx, userID, models.ListPersonalRecordsRequest{RecordType: "weight"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+	require.Len(t, list.Records, 2)
+	assert.Equal(t, 85.0, list.Records[0].Value)
+	assert.Equal(t, "bench-press", *list.Records[0].ExerciseID)
+
+	list, err = svc.ListRecords(ctx, userID, models.ListPersonalRecordsRequest{ExerciseID: "bench-press", Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 8, list.Total)
+	assert.Len(t, list.Records, 3)
+	assert.True(t, list.HasNext)
+
+	stats, err := svc.Stats(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 8, stats.TotalRecords)
+	assert.Equal(t, 2, stats.RecordsByType["volume"])
+	assert.Len(t, stats.RecordsByExercise["Bench Press"], 4, "one best per record type")
+	assert.Equal(t, "improving", stats.ProgressTrend)
+
+	heaviest := list.Records[0]
+	for _, record := range list.Records {
+		if record.RecordType == "weight" {
+			heaviest = record
+		}
+	}
+	require.NoError(t, svc.DeleteRecord(ctx, userID, heaviest.ID))
+	assert.ErrorIs(t, svc.DeleteRecord(ctx, userID, heaviest.ID), ErrPersonalRecordNotFound)
+	assert.ErrorIs(t, svc.DeleteRecord(ctx, "someone-else", list.Records[1].ID), ErrPersonalRecordNotFound)
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Progress photo errors returned by ProgressPhotoService
+var (
+	ErrProgressPhotoNotFound = errors.New("progress photo not found")
+	ErrInvalidProgressPhoto  = errors.New("invalid progress photo")
+)
+
+// DefaultPhotoURLTTL is how long a signed progress photo URL stays valid
+const DefaultPhotoURLTTL = 15 * time.Minute
+
+var progressPhotoTypes = map[string]bool{
+	models.PhotoTypeFront: true,
+	models.PhotoTypeSide:  true,
+	models.PhotoTypeBack:  true,
+	models.PhotoTypeOther: true,
+}
+
+const progressPhotoColumns = `id, user_id, measurement_id, photo_type, file_url, thumb_url, content_type,
+	size, width, height, notes, taken_at, created_at, updated_at`
+
+// ProgressPhotoService stores private progress photos and links them to the
+// body measurement taken the same day
+type ProgressPhotoService struct {
+	db     *sql.DB
+	files  *FileStorageService
+	urlTTL time.Duration
+}
+
+// NewProgressPhotoService creates a new progress photo service
+func NewProgressPhotoService(db *sql.DB, files *FileStorageService) *ProgressPhotoService {
+	return &ProgressPhotoService{
+		db:     db,
+		files:  files,
+		urlTTL: DefaultPhotoURLTTL,
+	}
+}
+
+// SetURLTTL sets how long signed photo URLs stay valid
+func (s *ProgressPhotoService) SetURLTTL(ttl time.Duration) {
+	s.urlTTL = ttl
+}
+
+// Upload validates and stores a photo without its metadata, together with a
+// thumbnail, and adds it to the photos of the matching body measurement
+func (s *ProgressPhotoService) Upload(ctx context.Context, userID string, file *multipart.FileHeader, req models.UploadProgressPhotoRequest) (*models.ProgressPhoto, error) {
+	if file == nil {
+		return nil, fmt.Errorf("%w: photo file is required", ErrInvalidProgressPhoto)
+	}
+	photoType, err := parsePhotoType(req.PhotoType, models.PhotoTypeFront)
+	if err != nil {
+		return nil, err
+	}
+	takenAt := time.Now().UTC()
+	if req.Date != "" {
+		day, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: date must be formatted as YYYY-MM-DD", ErrInvalidProgressPhoto)
+		}
+		if day.After(takenAt) {
+			return nil, fmt.Errorf("%w: date cannot be in the future", ErrInvalidProgressPhoto)
+		}
+		takenAt = day
+	}
+	if req.MeasurementID != 0 {
+		if _, err := s.measurementPhotos(ctx, s.db, userID, req.MeasurementID); err != nil {
+			return nil, err
+		}
+	}
+
+	upload, err := s.files.UploadFile(ctx, &FileUploadRequest{
+		File:         file,
+		UploaderID:   userID,
+		Purpose:      "progress",
+		ValidateSize: true,
+		ValidateType: true,
+		ImagesOnly:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	photo := &models.ProgressPhoto{
+		ID:          upload.FileID,
+		UserID:      userID,
+		PhotoType:   photoType,
+		FileURL:     upload.FileURL,
+		ThumbURL:    upload.ThumbnailURL,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		Width:       upload.Width,
+		Height:      upload.Height,
+		Notes:       nonEmpty(&req.Notes),
+		TakenAt:     takenAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.insert(ctx, photo, req); err != nil {
+		s.deleteFiles(ctx, photo)
+		return nil, err
+	}
+	return s.sign(ctx, photo)
+}
+
+// List returns userID's photos, most recently taken first, with signed URLs
+func (s *ProgressPhotoService) List(ctx context.Context, userID string, page, limit int) ([]*models.ProgressPhoto, int64, error) {
+	var total int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM progress_photos WHERE user_id = ?`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count progress photos: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+progressPhotoColumns+` FROM progress_photos
+		WHERE user_id = ? ORDER BY taken_at DESC, created_at DESC LIMIT ? OFFSET ?`,
+		userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list progress photos: %w", err)
+	}
+	defer rows.Close()
+
+	photos := []*models.ProgressPhoto{}
+	for rows.Next() {
+		photo, err := scanProgressPhoto(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan progress photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	for _, photo := range photos {
+		if _, err := s.sign(ctx, photo); err != nil {
+			return nil, 0, err
+		}
+	}
+	return photos, total, nil
+}
+
+// Get returns one of userID's photos with freshly signed URLs
+func (s *ProgressPhotoService) Get(ctx context.Context, userID, id string) (*models.ProgressPhoto, error) {
+	photo, err := s.getProgressPhoto(ctx, s.db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(ctx, photo)
+}
+
+// Delete removes a photo, its link from the body measurement and its files
+func (s *ProgressPhotoService) Delete(ctx context.Context, userID, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	photo, err := s.getProgressPhoto(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM progress_photos WHERE id = ? AND user_id = ?`, id, userID); err != nil {
+		return fmt.Errorf("failed to delete progress photo: %w", err)
+	}
+	if photo.MeasurementID != nil {
+		photos, err := s.measurementPhotos(ctx, tx, userID, *photo.MeasurementID)
+		if err != nil && !errors.Is(err, ErrInvalidProgressPhoto) {
+			return err
+		}
+		if err == nil {
+			kept := models.PhotoList{}
+			for _, photoID := range photos {
+				if photoID != id {
+					kept = append(kept, photoID)
+				}
+			}
+			if err := s.setMeasurementPhotos(ctx, tx, *photo.MeasurementID, kept); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.deleteFiles(ctx, photo)
+}
+
+// parsePhotoType normalizes a requested photo type, using fallback when it
+// is empty
+func parsePhotoType(photoType, fallback string) (string, error) {
+	photoType = strings.ToLower(strings.TrimSpace(photoType))
+	if photoType == "" {
+		return fallback, nil
+	}
+	if !progressPhotoTypes[photoType] {
+		return "", fmt.Errorf("%w: photo_type must be front, side, back or other", ErrInvalidProgressPhoto)
+	}
+	return photoType, nil
+}
+
+// insert stores the photo row and appends it to the measurement it was
+// taken with, creating that day's measurement when there is none
+func (s *ProgressPhotoService) insert(ctx context.Context, photo *models.ProgressPhoto, req models.UploadProgressPhotoRequest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	measurementID := req.MeasurementID
+	if measurementID == 0 {
+		day := startOfDay(photo.TakenAt)
+		err := tx.QueryRowContext(ctx, `SELECT id FROM body_measurements
+			WHERE user_id = ? AND measurement_date >= ? AND measurement_date < ?
+			ORDER BY measurement_date DESC, id DESC LIMIT 1`,
+			photo.UserID, day, day.AddDate(0, 0, 1)).Scan(&measurementID)
+		if errors.Is(err, sql.ErrNoRows) {
+			var weight *float64
+			if req.Weight > 0 {
+				weight = &req.Weight
+			}
+			result, err := tx.ExecContext(ctx, `INSERT INTO body_measurements (user_id, measurement_date, weight, photos, created_at, updated_at)
+				VALUES (?, ?, ?, '[]', ?, ?)`, photo.UserID, day, weight, photo.CreatedAt, photo.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to create body measurement: %w", err)
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to create body measurement: %w", err)
+			}
+			measurementID = uint(id)
+		} else if err != nil {
+			return fmt.Errorf("failed to find body measurement: %w", err)
+		}
+	}
+	photos, err := s.measurementPhotos(ctx, tx, photo.UserID, measurementID)
+	if err != nil {
+		return err
+	}
+	photo.MeasurementID = &measurementID
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO progress_photos (`+progressPhotoColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		photo.ID, photo.UserID, measurementID, photo.PhotoType, photo.FileURL, photo.ThumbURL, photo.ContentType,
+		photo.Size, photo.Width, photo.Height, photo.Notes, photo.TakenAt, photo.CreatedAt, photo.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save progress photo: %w", err)
+	}
+	if err := s.setMeasurementPhotos(ctx, tx, measurementID, append(photos, photo.ID)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (s *ProgressPhotoService) measurementPhotos(ctx context.Context, q queryer, userID string, measurementID uint) (models.PhotoList, error) {
+	var photos models.PhotoList
+	err := q.QueryRowContext(ctx, `SELECT photos FROM body_measurements WHERE id = ? AND user_id = ?`,
+		measurementID, userID).Scan(&photos)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: measurement %d not found", ErrInvalidProgressPhoto, measurementID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get body measurement: %w", err)
+	}
+	return photos, nil
+}
+
+func (s *ProgressPhotoService) setMeasurementPhotos(ctx context.Context, tx *sql.Tx, measurementID uint, photos models.PhotoList) error {
+	_, err := tx.ExecContext(ctx, `UPDATE body_measurements SET photos = ?, updated_at = ? WHERE id = ?`,
+		photos, time.Now().UTC(), measurementID)
+	if err != nil {
+		return fmt.Errorf("failed to link progress photo: %w", err)
+	}
+	return nil
+}
+
+// sign replaces the photo's URLs with signed ones that expire after urlTTL
+func (s *ProgressPhotoService) sign(ctx context.Context, photo *models.ProgressPhoto) (*models.ProgressPhoto, error) {
+	expiresAt := time.Now().UTC().Add(s.urlTTL)
+	url, err := s.files.SignedURL(ctx, photo.FileURL, s.urlTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign photo URL: %w", err)
+	}
+	thumbnailURL, err := s.files.SignedURL(ctx, photo.ThumbURL, s.urlTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign photo URL: %w", err)
+	}
+	photo.URL, photo.ThumbnailURL, photo.URLExpiresAt = url, thumbnailURL, &expiresAt
+	return photo, nil
+}
+
+func (s *ProgressPhotoService) deleteFiles(ctx context.Context, photo *models.ProgressPhoto) error {
+	var failed []string
+	for _, fileURL := range []string{photo.FileURL, photo.ThumbURL} {
+		if err := s.files.DeleteFile(ctx, fileURL); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete photo files: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func (s *ProgressPhotoService) getProgressPhoto(ctx context.Context, q queryer, userID, id string) (*models.ProgressPhoto, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+progressPhotoColumns+` FROM progress_photos WHERE id = ? AND user_id = ?`, id, userID)
+	photo, err := scanProgressPhoto(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProgressPhotoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get progress photo: %w", err)
+	}
+	return photo, nil
+}
+
+func scanProgressPhoto(row rowScanner) (*models.ProgressPhoto, error) {
+	photo := &models.ProgressPhoto{}
+	var measurementID sql.NullInt64
+	var notes sql.NullString
+	if err := row.Scan(&photo.ID, &photo.UserID, &measurementID, &photo.PhotoType, &photo.FileURL, &photo.ThumbURL,
+		&photo.ContentType, &photo.Size, &photo.Width, &photo.Height, &notes, &photo.TakenAt,
+		&photo.CreatedAt, &photo.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if measurementID.Valid {
+		id := uint(measurementID.Int64)
+		photo.MeasurementID = &id
+	}
+	if notes.Valid {
+		photo.Notes = &notes.String
+	}
+	return photo, nil
+}
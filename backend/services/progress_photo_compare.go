@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/disintegration/imaging"
+)
+
+// Panel sizes and limits for rendered comparisons and timelines
+const (
+	defaultComparisonWidth    = 480
+	defaultComparisonHeight   = 640
+	defaultTimelineWidth      = 320
+	defaultTimelineHeight     = 420
+	defaultTimelineFrameDelay = 800 * time.Millisecond
+	defaultTimelineMaxFrames  = 24
+
+	// captionMeasurementWindow is how far a body measurement may be from a
+	// photo for its readings to be printed in the caption
+	captionMeasurementWindow = 14 * 24 * time.Hour
+)
+
+// Compare renders a JPEG with the before and after photos side by side,
+// captioned with their dates and the nearest weight and body fat readings
+func (s *ProgressPhotoService) Compare(ctx context.Context, userID string, req models.PhotoComparisonRequest) ([]byte, error) {
+	photoType, err := parsePhotoType(req.PhotoType, "")
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.selectPhoto(ctx, userID, req.BeforeID, req.BeforeDate, photoType, "before")
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.selectPhoto(ctx, userID, req.AfterID, req.AfterDate, photoType, "after")
+	if err != nil {
+		return nil, err
+	}
+	if before.ID == after.ID {
+		return nil, fmt.Errorf("%w: before and after must be different photos", ErrInvalidProgressPhoto)
+	}
+
+	panels, err := s.captionedPanels(ctx, userID, []*models.ProgressPhoto{before, after})
+	if err != nil {
+		return nil, err
+	}
+	opts := ImageProcessingOptions{Width: req.Width, Height: req.Height, ResizeMode: req.ResizeMode}
+	if opts.Width == 0 {
+		opts.Width = defaultComparisonWidth
+	}
+	if opts.Height == 0 {
+		opts.Height = defaultComparisonHeight
+	}
+	return s.files.processor.RenderComparison(ctx, panels, opts)
+}
+
+// Timeline renders userID's photos of one type, oldest first, as an animated
+// GIF. Long series are sampled evenly down to MaxFrames, always keeping the
+// first and last photo.
+func (s *ProgressPhotoService) Timeline(ctx context.Context, userID string, req models.PhotoTimelineRequest) ([]byte, error) {
+	photoType, err := parsePhotoType(req.PhotoType, models.PhotoTypeFront)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + progressPhotoColumns + ` FROM progress_photos WHERE user_id = ? AND photo_type = ?`
+	args := []interface{}{userID, photoType}
+	if req.From != "" {
+		from, err := time.Parse("2006-01-02", req.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from must be formatted as YYYY-MM-DD", ErrInvalidProgressPhoto)
+		}
+		query += ` AND taken_at >= ?`
+		args = append(args, from)
+	}
+	if req.To != "" {
+		to, err := time.Parse("2006-01-02", req.To)
+		if err != nil {
+			return nil, fmt.Errorf("%w: to must be formatted as YYYY-MM-DD", ErrInvalidProgressPhoto)
+		}
+		query += ` AND taken_at < ?`
+		args = append(args, to.AddDate(0, 0, 1))
+	}
+	rows, err := s.db.QueryContext(ctx, query+` ORDER BY taken_at ASC, created_at ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list progress photos: %w", err)
+	}
+	defer rows.Close()
+
+	var photos []*models.ProgressPhoto
+	for rows.Next() {
+		photo, err := scanProgressPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan progress photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(photos) < 2 {
+		return nil, fmt.Errorf("%w: a timeline needs at least two %s photos", ErrInvalidProgressPhoto, photoType)
+	}
+
+	maxFrames := req.MaxFrames
+	if maxFrames == 0 {
+		maxFrames = defaultTimelineMaxFrames
+	}
+	panels, err := s.captionedPanels(ctx, userID, sampleEvenly(photos, maxFrames))
+	if err != nil {
+		return nil, err
+	}
+	opts := ImageProcessingOptions{Width: req.Width, Height: req.Height, ResizeMode: req.ResizeMode}
+	if opts.Width == 0 {
+		opts.Width = defaultTimelineWidth
+	}
+	if opts.Height == 0 {
+		opts.Height = defaultTimelineHeight
+	}
+	delay := defaultTimelineFrameDelay
+	if req.FrameDelay > 0 {
+		delay = time.Duration(req.FrameDelay) * time.Millisecond
+	}
+	return s.files.processor.RenderTimeline(ctx, panels, opts, delay)
+}
+
+// selectPhoto returns the photo with the given ID, or else the photo of
+// photoType (any type when empty) taken closest to date
+func (s *ProgressPhotoService) selectPhoto(ctx context.Context, userID, id, date, photoType, label string) (*models.ProgressPhoto, error) {
+	if id != "" {
+		return s.getProgressPhoto(ctx, s.db, userID, id)
+	}
+	if date == "" {
+		return nil, fmt.Errorf("%w: %s_id or %s_date is required", ErrInvalidProgressPhoto, label, label)
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s_date must be formatted as YYYY-MM-DD", ErrInvalidProgressPhoto, label)
+	}
+
+	filter := ``
+	args := []interface{}{userID}
+	if photoType != "" {
+		filter = ` AND photo_type = ?`
+		args = append(args, photoType)
+	}
+	var closest *models.ProgressPhoto
+	for _, query := range []string{
+		`SELECT ` + progressPhotoColumns + ` FROM progress_photos WHERE user_id = ?` + filter + ` AND taken_at <= ? ORDER BY taken_at DESC LIMIT 1`,
+		`SELECT ` + progressPhotoColumns + ` FROM progress_photos WHERE user_id = ?` + filter + ` AND taken_at > ? ORDER BY taken_at ASC LIMIT 1`,
+	} {
+		photo, err := scanProgressPhoto(s.db.QueryRowContext(ctx, query, append(args, day)...))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find progress photo: %w", err)
+		}
+		if closest == nil || absDuration(photo.TakenAt.Sub(day)) < absDuration(closest.TakenAt.Sub(day)) {
+			closest = photo
+		}
+	}
+	if closest == nil {
+		return nil, fmt.Errorf("%w: no photo near %s", ErrProgressPhotoNotFound, date)
+	}
+	return closest, nil
+}
+
+// captionedPanels loads the stored photos and captions each with its date
+// and the readings of the nearest body measurement
+func (s *ProgressPhotoService) captionedPanels(ctx context.Context, userID string, photos []*models.ProgressPhoto) ([]CaptionedImage, error) {
+	panels := make([]CaptionedImage, 0, len(photos))
+	for _, photo := range photos {
+		file, err := s.files.GetFile(ctx, photo.FileURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load progress photo %s: %w", photo.ID, err)
+		}
+		img, err := imaging.Decode(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode progress photo %s: %w", photo.ID, err)
+		}
+
+		caption := []string{photo.TakenAt.Format("2 Jan 2006")}
+		readings, err := s.nearestReadings(ctx, userID, photo.TakenAt)
+		if err != nil {
+			return nil, err
+		}
+		if readings != "" {
+			caption = append(caption, readings)
+		}
+		panels = append(panels, CaptionedImage{Image: img, Caption: caption})
+	}
+	return panels, nil
+}
+
+// nearestReadings formats the weight and body fat of the body measurement
+// closest to at, or returns "" when none lies within captionMeasurementWindow
+func (s *ProgressPhotoService) nearestReadings(ctx context.Context, userID string, at time.Time) (string, error) {
+	var (
+		closest         time.Time
+		weight, bodyFat sql.NullFloat64
+		found           bool
+	)
+	for _, query := range []string{
+		`SELECT measurement_date, weight, body_fat_percentage FROM body_measurements
+			WHERE user_id = ? AND (weight IS NOT NULL OR body_fat_percentage IS NOT NULL) AND measurement_date <= ?
+			ORDER BY measurement_date DESC LIMIT 1`,
+		`SELECT measurement_date, weight, body_fat_percentage FROM body_measurements
+			WHERE user_id = ? AND (weight IS NOT NULL OR body_fat_percentage IS NOT NULL) AND measurement_date > ?
+			ORDER BY measurement_date ASC LIMIT 1`,
+	} {
+		var date time.Time
+		var w, bf sql.NullFloat64
+		err := s.db.QueryRowContext(ctx, query, userID, at).Scan(&date, &w, &bf)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to find body measurement: %w", err)
+		}
+		if !found || absDuration(date.Sub(at)) < absDuration(closest.Sub(at)) {
+			closest, weight, bodyFat, found = date, w, bf, true
+		}
+	}
+	if !found || absDuration(closest.Sub(at)) > captionMeasurementWindow {
+		return "", nil
+	}
+
+	var parts []string
+	if weight.Valid {
+		parts = append(parts, fmt.Sprintf("%.1f kg", weight.Float64))
+	}
+	if bodyFat.Valid {
+		parts = append(parts, fmt.Sprintf("%.1f%% body fat", bodyFat.Float64))
+	}
+	return strings.Join(parts, " | "), nil
+}
+
+// sampleEvenly picks at most n photos spread evenly from first to last
+func sampleEvenly(photos []*models.ProgressPhoto, n int) []*models.ProgressPhoto {
+	if len(photos) <= n {
+		return photos
+	}
+	sampled := make([]*models.ProgressPhoto, n)
+	for i := range sampled {
+		sampled[i] = photos[i*(len(photos)-1)/(n-1)]
+	}
+	return sampled
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uploadPhotoOn(t *testing.T, svc *ProgressPhotoService, userID, photoType, date string, weight float64) *models.ProgressPhoto {
+	t.Helper()
+	photo, err := svc.Upload(context.Background(), userID, multipartPhoto(t, "photo.jpg", exifJPEG(t, 40, 60, 1)), models.UploadProgressPhotoRequest{
+		PhotoType: photoType,
+		Date:      date,
+		Weight:    weight,
+	})
+	require.NoError(t, err)
+	return photo
+}
+
+func TestProgressPhotoService_Compare(t *testing.T) {
+	ctx := context.Background()
+	svc, _, userID := newTestProgressPhotoService(t)
+
+	before := uploadPhotoOn(t, svc, userID, models.PhotoTypeFront, "2026-01-05", 88)
+	after := uploadPhotoOn(t, svc, userID, models.PhotoTypeFront, "2026-03-02", 0)
+	uploadPhotoOn(t, svc, userID, models.PhotoTypeSide, "2026-03-02", 0)
+	_, err := svc.db.Exec(`INSERT INTO body_measurements (user_id, measurement_date, weight, body_fat_percentage) VALUES (?, ?, ?, ?)`,
+		userID, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), 81.5, 18.0)
+	require.NoError(t, err)
+
+	readings, err := svc.nearestReadings(ctx, userID, before.TakenAt)
+	require.NoError(t, err)
+	assert.Equal(t, "88.0 kg", readings)
+	readings, err = svc.nearestReadings(ctx, userID, after.TakenAt)
+	require.NoError(t, err)
+	assert.Equal(t, "81.5 kg | 18.0% body fat", readings)
+	readings, err = svc.nearestReadings(ctx, userID, after.TakenAt.AddDate(0, 2, 0))
+	require.NoError(t, err)
+	assert.Empty(t, readings)
+
+	composite, err := svc.Compare(ctx, userID, models.PhotoComparisonRequest{BeforeID: before.ID, AfterID: after.ID, Width: 200, Height: 300})
+	require.NoError(t, err)
+	config, err := jpeg.DecodeConfig(bytes.NewReader(composite))
+	require.NoError(t, err)
+	assert.Equal(t, 2*200+3*compositeGap, config.Width)
+	assert.Greater(t, config.Height, 300+2*compositeGap)
+
+	// Dates resolve to the closest photo of the requested type
+	selected, err := svc.selectPhoto(ctx, userID, "", "2026-02-25", models.PhotoTypeFront, "after")
+	require.NoError(t, err)
+	assert.Equal(t, after.ID, selected.ID)
+	selected, err = svc.selectPhoto(ctx, userID, "", "2025-06-01", models.PhotoTypeFront, "before")
+	require.NoError(t, err)
+	assert.Equal(t, before.ID, selected.ID)
+	_, err = svc.Compare(ctx, userID, models.PhotoComparisonRequest{BeforeDate: "2026-01-01", AfterDate: "2026-04-01", PhotoType: "front"})
+	require.NoError(t, err)
+
+	_, err = svc.Compare(ctx, userID, models.PhotoComparisonRequest{BeforeID: before.ID, AfterID: before.ID})
+	assert.ErrorIs(t, err, ErrInvalidProgressPhoto)
+	_, err = svc.Compare(ctx, userID, models.PhotoComparisonRequest{BeforeID: before.ID})
+	assert.ErrorIs(t, err, ErrInvalidProgressPhoto)
+	_, err = svc.Compare(ctx, userID, models.PhotoComparisonRequest{BeforeID: before.ID, AfterID: "missing"})
+	assert.ErrorIs(t, err, ErrProgressPhotoNotFound)
+	_, err = svc.Compare(ctx, "someone-else", models.PhotoComparisonRequest{BeforeDate: "2026-01-01", AfterDate: "2026-04-01"})
+	assert.ErrorIs(t, err, ErrProgressPhotoNotFound)
+}
+
+func TestProgressPhotoService_Timeline(t *testing.T) {
+	ctx := context.Background()
+	svc, _, userID := newTestProgressPhotoService(t)
+
+	for _, date := range []string{"2026-01-05", "2026-02-02", "2026-03-02"} {
+		uploadPhotoOn(t, svc, userID, models.PhotoTypeFront, date, 85)
+	}
+	uploadPhotoOn(t, svc, userID, models.PhotoTypeBack, "2026-03-02", 0)
+
+	timeline, err := svc.Timeline(ctx, userID, models.PhotoTimelineRequest{FrameDelay: 500})
+	require.NoError(t, err)
+	animation, err := gif.DecodeAll(bytes.NewReader(timeline))
+	require.NoError(t, err)
+	require.Len(t, animation.Image, 3)
+	assert.Equal(t, []int{50, 50, 50}, animation.Delay)
+	assert.Equal(t, defaultTimelineWidth, animation.Config.Width)
+
+	timeline, err = svc.Timeline(ctx, userID, models.PhotoTimelineRequest{From: "2026-02-01", To: "2026-03-02"})
+	require.NoError(t, err)
+	animation, err = gif.DecodeAll(bytes.NewReader(timeline))
+	require.NoError(t, err)
+	assert.Len(t, animation.Image, 2)
+
+	_, err = svc.Timeline(ctx, userID, models.PhotoTimelineRequest{PhotoType: models.PhotoTypeBack})
+	assert.ErrorIs(t, err, ErrInvalidProgressPhoto)
+	_, err = svc.Timeline(ctx, userID, models.PhotoTimelineRequest{From: "02/01/2026"})
+	assert.ErrorIs(t, err, ErrInvalidProgressPhoto)
+}
+
+func TestSampleEvenly(t *testing.T) {
+	photos := make([]*models.ProgressPhoto, 10)
+	for i := range photos {
+		photos[i] = &models.ProgressPhoto{Width: i}
+	}
+
+	sampled := sampleEvenly(photos, 4)
+	require.Len(t, sampled, 4)
+	var picked []int
+	for _, photo := range sampled {
+		picked = append(picked, photo.Width)
+	}
+	assert.Equal(t, []int{0, 3, 6, 9}, picked)
+	assert.Len(t, sampleEvenly(photos, 24), 10)
+}
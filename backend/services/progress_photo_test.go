@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProgressPhotoService(t *testing.T) (*ProgressPhotoService, *LocalStorageProvider, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "photos@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	local := NewLocalStorageProvider(t.TempDir(), "http://localhost:8080/uploads")
+	local.SetSigningKey([]byte("test-signing-key"))
+	files := NewFileStorageService(local, NewImageProcessorService())
+	return NewProgressPhotoService(users.db, files), local, user.ID
+}
+
+// exifJPEG encodes a width x height JPEG carrying an EXIF segment with the
+// given orientation and a GPS latitude reference
+func exifJPEG(t *testing.T, width, height int, orientation byte) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, img, nil))
+
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08,
+		0x00, 0x02, // IFD0: orientation and a pointer to the GPS IFD
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00,
+		0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x26,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x01, // GPS IFD: GPSLatitudeRef "N"
+		0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x02, 'N', 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(segment) + 2
+	app1 := append([]byte{0xff, 0xe1, byte(length >> 8), byte(length)}, segment...)
+
+	data := encoded.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func multipartPhoto(t *testing.T, filename string, data []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photo", filename)
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(32 << 20)
+	require.NoError(t, err)
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["photo"][0]
+}
+
+// openSigned follows a signed local storage URL
+func openSigned(t *testing.T, local *LocalStorageProvider, signedURL string) ([]byte, error) {
+	t.Helper()
+	parsed, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	file, err := local.OpenSignedFile(context.Background(), strings.TrimPrefix(parsed.Path, "/uploads/"),
+		parsed.Query().Get("expires"), parsed.Query().Get("signature"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func TestProgressPhotoService_UploadStripsMetadata(t *testing.T) {
+	ctx := context.Background()
+	svc, local, userID := newTestProgressPhotoService(t)
+
+	original := exifJPEG(t, 40, 20, 6)
+	require.True(t, bytes.Contains(original, []byte("Exif")))
+
+	photo, err := svc.Upload(ctx, userID, multipartPhoto(t, "IMG_0001 at home.jpg", original), models.UploadProgressPhotoRequest{
+		PhotoType: "Side",
+		Date:      "2026-03-04",
+		Weight:    81.5,
+		Notes:     "  week 1 ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.PhotoTypeSide, photo.PhotoType)
+	assert.Equal(t, "image/jpeg", photo.ContentType)
+	assert.Equal(t, "2026-03-04", photo.TakenAt.Format("2006-01-02"))
+	require.NotNil(t, photo.Notes)
+	assert.Equal(t, "week 1", *photo.Notes)
+	assert.NotContains(t, photo.FileURL, "IMG_0001")
+
+	// Orientation 6 is applied before the metadata is dropped
+	assert.Equal(t, 20, photo.Width)
+	assert.Equal(t, 40, photo.Height)
+
+	stored, err := openSigned(t, local, photo.URL)
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(stored, []byte("Exif")))
+	config, err := jpeg.DecodeConfig(bytes.NewReader(stored))
+	require.NoError(t, err)
+	assert.Equal(t, 20, config.Width)
+
+	thumbnail, err := openSigned(t, local, photo.ThumbnailURL)
+	require.NoError(t, err)
+	config, err = jpeg.DecodeConfig(bytes.NewReader(thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, 300, config.Width)
+	assert.Equal(t, 300, config.Height)
+
+	// The photo joins a measurement created for that day
+	require.NotNil(t, photo.MeasurementID)
+	var photos models.PhotoList
+	var weight float64
+	require.NoError(t, svc.db.QueryRow(`SELECT photos, weight FROM body_measurements WHERE id = ? AND user_id = ?`,
+		*photo.MeasurementID, userID).Scan(&photos, &weight))
+	assert.Equal(t, models.PhotoList{photo.ID}, photos)
+	assert.Equal(t, 81.5, weight)
+}
+
+func TestProgressPhotoService_SignedURLs(t *testing.T) {
+	ctx := context.Background()
+	svc, local, userID := newTestProgressPhotoService(t)
+
+	photo, err := svc.Upload(ctx, userID, multipartPhoto(t, "front.jpg", exifJPEG(t, 30, 30, 1)), models.UploadProgressPhotoRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, photo.URLExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(DefaultPhotoURLTTL), *photo.URLExpiresAt, time.Minute)
+
+	_, err = openSigned(t, local, strings.Replace(photo.URL, "signature=", "signature=0", 1))
+	assert.ErrorIs(t, err, ErrInvalidSignedURL)
+	_, err = openSigned(t, local, strings.Replace(photo.URL, "expires=", "expires=9", 1))
+	assert.ErrorIs(t, err, ErrInvalidSignedURL)
+
+	svc.SetURLTTL(-time.Minute)
+	expired, err := svc.Get(ctx, userID, photo.ID)
+	require.NoError(t, err)
+	_, err = openSigned(t, local, expired.URL)
+	assert.ErrorIs(t, err, ErrInvalidSignedURL)
+
+	_, err = svc.Get(ctx, "someone-else", photo.ID)
+	assert.ErrorIs(t, err, ErrProgressPhotoNotFound)
+}
+
+func TestProgressPhotoService_ListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	svc, local, userID := newTestProgressPhotoService(t)
+
+	first, err := svc.Upload(ctx, userID, multipartPhoto(t, "front.jpg", exifJPEG(t, 30, 30, 1)), models.UploadProgressPhotoRequest{Date: "2026-03-04"})
+	require.NoError(t, err)
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 24, 24))
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, transparent))
+	second, err := svc.Upload(ctx, userID, multipartPhoto(t, "back.png", encoded.Bytes()), models.UploadProgressPhotoRequest{
+		PhotoType:     models.PhotoTypeBack,
+		MeasurementID: *first.MeasurementID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", second.ContentType)
+	assert.Equal(t, *first.MeasurementID, *second.MeasurementID)
+
+	photos, total, err := svc.List(ctx, userID, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, photos, 2)
+	assert.Equal(t, second.ID, photos[0].ID)
+	assert.NotEmpty(t, photos[1].URL)
+
+	require.NoError(t, svc.Delete(ctx, userID, first.ID))
+	assert.ErrorIs(t, svc.Delete(ctx, userID, first.ID), ErrProgressPhotoNotFound)
+	_, err = local.GetFile(ctx, first.FileURL)
+	assert.Error(t, err)
+	_, err = local.GetFile(ctx, first.ThumbURL)
+	assert.Error(t, err)
+
+	var linked models.PhotoList
+	require.NoError(t, svc.db.QueryRow(`SELECT photos FROM body_measurements WHERE id = ?`, *first.MeasurementID).Scan(&linked))
+	assert.Equal(t, models.PhotoList{second.ID}, linked)
+}
+
+func TestProgressPhotoService_RejectsInvalidUploads(t *testing.T) {
+	ctx := context.Background()
+	svc, _, userID := newTestProgressPhotoService(t)
+
+	_, err := svc.Upload(ctx, userID, multipartPhoto(t, "notes.jpg", []byte("not an image at all")), models.UploadProgressPhotoRequest{})
+	assert.ErrorIs(t, err, ErrFileTypeNotAllowed)
+
+	_, err = svc.Upload(ctx, userID, multipartPhoto(t, "broken.jpg", []byte("\xff\xd8\xff\xe0 truncated")), models.UploadProgressPhotoRequest{})
+	assert.ErrorIs(t, err, ErrInvalidImage)
+
+	photo := multipartPhoto(t, "front.jpg", exifJPEG(t, 30, 30, 1))
+	_, err = svc.Upload(ctx, userID, photo, models.UploadProgressPhotoRequest{PhotoType: "selfie"})
+	assert.ErrorIs(t, err, ErrInvalidProgressPhoto)
+	_, err = svc.Upload(ctx, userID, photo, models.UploadProgressPhotoRequest{Date: "2999-01-01"})
+	assert.ErrorIs(t, err, ErrInvalidProgressPhoto)
+	_, err = svc.Upload(ctx, userID, photo, models.UploadProgressPhotoRequest{MeasurementID: 999})
+	assert.ErrorIs(t, err, ErrInvalidProgressPhoto)
+
+	svc.files.SetMaxFileSize(100)
+	_, err = svc.Upload(ctx, userID, photo, models.UploadProgressPhotoRequest{})
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	var count int
+	require.NoError(t, svc.db.QueryRow(`SELECT COUNT(*) FROM progress_photos`).Scan(&count))
+	assert.Zero(t, count)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"time"
 
 	"nutrition-platform/database"
@@ -15,6 +16,8 @@ import (
 type ProgressService struct {
 	measurementRepo *repositories.BodyMeasurementRepository
 	weightRepo      *repositories.WeightRepository
+	goals           *WeightGoalService
+	milestones      *MilestoneService
 }
 
 func NewProgressService(db *sql.DB) *ProgressService {
@@ -22,18 +25,20 @@ func NewProgressService(db *sql.DB) *ProgressService {
 	return &ProgressService{
 		measurementRepo: repositories.NewBodyMeasurementRepository(dbWrapper),
 		weightRepo:      repositories.NewWeightRepository(dbWrapper),
+		goals:           NewWeightGoalService(db),
+		milestones:      NewMilestoneService(db),
 	}
 }
 
 // LogMeasurement logs a body measurement with validation
-func (s *ProgressService) LogMeasurement(ctx context.Context, userID uint, measurement *models.BodyMeasurement) (*models.BodyMeasurement, error) {
+func (s *ProgressService) LogMeasurement(ctx context.Context, userID string, measurement *models.BodyMeasurement) (*models.BodyMeasurement, error) {
 	// Validate measurement
 	if err := measurement.Validate(); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
 	// Set user ID and current date if not provided
-	measurement.UserID = uint(userID)
+	measurement.UserID = userID
 	if measurement.MeasurementDate.IsZero() {
 		measurement.MeasurementDate = time.Now().Truncate(24 * time.Hour)
 	}
@@ -44,11 +49,22 @@ func (s *ProgressService) LogMeasurement(ctx context.Context, userID uint, measu
 		return nil, fmt.Errorf("failed to log measurement: %w", err)
 	}
 
+	// The measurement is already stored, so failed goal and milestone checks
+	// are only logged
+	if measurement.Weight != nil {
+		if err := s.goals.RecordAchievement(ctx, userID); err != nil {
+			log.Printf("Failed to check weight goal for user %s: %v", userID, err)
+		}
+		if _, err := s.milestones.Evaluate(ctx, userID); err != nil {
+			log.Printf("Failed to evaluate milestones for user %s: %v", userID, err)
+		}
+	}
+
 	return measurement, nil
 }
 
 // GetMeasurementHistory retrieves measurement history with filters
-func (s *ProgressService) GetMeasurementHistory(ctx context.Context, userID uint, page, perPage int, startDate, endDate *time.Time) ([]*models.BodyMeasurement, int64, error) {
+func (s *ProgressService) GetMeasurementHistory(ctx context.Context, userID string, page, perPage int, startDate, endDate *time.Time) ([]*models.BodyMeasurement, int64, error) {
 	// Convert page/limit to offset
 	offset := (page - 1) * perPage
 
@@ -57,20 +73,20 @@ func (s *ProgressService) GetMeasurementHistory(ctx context.Context, userID uint
 
 	if startDate != nil && endDate != nil {
 		// Get measurements by date range
-		measurements, err = s.measurementRepo.GetBodyMeasurementsByDateRange(ctx, int64(userID), *startDate, *endDate, perPage, offset)
+		measurements, err = s.measurementRepo.GetBodyMeasurementsByDateRange(ctx, userID, *startDate, *endDate, perPage, offset)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to get measurements by date range: %w", err)
 		}
 	} else {
 		// Get all measurements with pagination
-		measurements, err = s.measurementRepo.GetBodyMeasurementsByUserID(ctx, int64(userID), perPage, offset)
+		measurements, err = s.measurementRepo.GetBodyMeasurementsByUserID(ctx, userID, perPage, offset)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to get measurements: %w", err)
 		}
 	}
 
 	// Get total count
-	total, err := s.measurementRepo.GetMeasurementCountByUserID(ctx, int64(userID))
+	total, err := s.measurementRepo.GetMeasurementCountByUserID(ctx, userID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get measurement count: %w", err)
 	}
@@ -78,75 +94,45 @@ func (s *ProgressService) GetMeasurementHistory(ctx context.Context, userID uint
 	return measurements, total, nil
 }
 
-// GetPhotoHistory retrieves progress photos for a user with pagination
-func (s *ProgressService) GetPhotoHistory(ctx context.Context, userID uint, page, limit int) ([]map[string]interface{}, int64, error) {
-	// For now, return empty array as photo storage is not implemented
-	// In a real implementation, this would query a progress_photos table
-	photos := []map[string]interface{}{}
-
-	// Mock data for demonstration
-	if page == 1 && limit >= 3 {
-		photos = []map[string]interface{}{
-			{
-				"id":            1,
-				"user_id":       userID,
-				"photo_url":     "https://example.com/photo1.jpg",
-				"thumbnail_url": "https://example.com/thumb1.jpg",
-				"date":          "2025-01-15",
-				"weight":        75.5,
-				"notes":         "Front pose - progress update",
-				"uploaded_at":   "2025-01-15T10:30:00Z",
-			},
-			{
-				"id":            2,
-				"user_id":       userID,
-				"photo_url":     "https://example.com/photo2.jpg",
-				"thumbnail_url": "https://example.com/thumb2.jpg",
-				"date":          "2025-01-22",
-				"weight":        74.8,
-				"notes":         "Side pose - starting to see definition",
-				"uploaded_at":   "2025-01-22T14:15:00Z",
-			},
-		}
-	}
-
-	// For now, return mock total count
-	total := int64(len(photos))
-
-	return photos, total, nil
-}
-
 // GetProgressSummary returns a summary of all progress metrics
-func (s *ProgressService) GetProgressSummary(ctx context.Context, userID uint, days int) (map[string]interface{}, error) {
+func (s *ProgressService) GetProgressSummary(ctx context.Context, userID string, days int) (map[string]interface{}, error) {
 	endDate := time.Now()
 	startDate := endDate.AddDate(0, 0, -days)
 
 	// Get body measurement stats
-	measurements, err := s.measurementRepo.GetBodyMeasurementsByDateRange(ctx, int64(userID), startDate, endDate, 1000, 0)
+	measurements, err := s.measurementRepo.GetBodyMeasurementsByDateRange(ctx, userID, startDate, endDate, 1000, 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get measurements: %w", err)
 	}
 
 	// Get measurement stats
-	stats, err := s.measurementRepo.GetMeasurementStats(ctx, int64(userID), days)
+	stats, err := s.measurementRepo.GetMeasurementStats(ctx, userID, days)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get measurement stats: %w", err)
 	}
 
 	// Get latest measurement
-	latest, err := s.measurementRepo.GetLatestBodyMeasurement(ctx, int64(userID))
+	latest, err := s.measurementRepo.GetLatestBodyMeasurement(ctx, userID)
 	if err != nil {
 		// It's okay if no measurements exist yet
 		latest = nil
 	}
 
+	// Smoothed trend weight, weekly rate, plateau and goal projection
+	weightTrend, err := s.goals.Analyze(ctx, userID, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze weight trend: %w", err)
+	}
+
 	summary := map[string]interface{}{
-		"period_days": days,
-		"start_date":  startDate.Format("2006-01-02"),
-		"end_date":    endDate.Format("2006-01-02"),
-		"stats":       stats,
-		"latest":      latest,
-		"trends":      make(map[string]interface{}),
+		"period_days":  days,
+		"start_date":   startDate.Format("2006-01-02"),
+		"end_date":     endDate.Format("2006-01-02"),
+		"stats":        stats,
+		"latest":       latest,
+		"trends":       make(map[string]interface{}),
+		"weight_trend": weightTrend,
+		"plateau":      weightTrend.Plateau,
 	}
 
 	// Calculate trends if we have enough data
@@ -205,21 +191,23 @@ func (s *ProgressService) GetProgressSummary(ctx context.Context, userID uint, d
 }
 
 // GetProgressCharts returns chart data for visualization
-func (s *ProgressService) GetProgressCharts(ctx context.Context, userID uint, days int) (map[string]interface{}, error) {
-	// Get weight trend
-	weightTrend, err := s.measurementRepo.GetWeightTrend(ctx, int64(userID), days)
+func (s *ProgressService) GetProgressCharts(ctx context.Context, userID string, days int) (map[string]interface{}, error) {
+	// Get weight trend; each point carries the weigh-in and the smoothed trend
+	weightTrend, err := s.goals.Analyze(ctx, userID, days)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get weight trend: %w", err)
 	}
 
 	// Get body fat trend
-	bodyFatTrend, err := s.measurementRepo.GetBodyFatTrend(ctx, int64(userID), days)
+	bodyFatTrend, err := s.measurementRepo.GetBodyFatTrend(ctx, userID, days)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get body fat trend: %w", err)
 	}
 
 	charts := map[string]interface{}{
-		"weight_trend":   weightTrend,
+		"weight_trend":   weightTrend.Points,
+		"trend_weight":   weightTrend.TrendWeight,
+		"weekly_rate":    weightTrend.WeeklyRate,
 		"body_fat_trend": bodyFatTrend,
 		"period_days":    days,
 	}
@@ -228,8 +216,8 @@ func (s *ProgressService) GetProgressCharts(ctx context.Context, userID uint, da
 }
 
 // CompareMeasurements compares measurements between two dates
-func (s *ProgressService) CompareMeasurements(ctx context.Context, userID uint, startDate, endDate time.Time) (*models.MeasurementComparison, error) {
-	comparison, err := s.measurementRepo.CompareMeasurements(ctx, int64(userID), startDate, endDate)
+func (s *ProgressService) CompareMeasurements(ctx context.Context, userID string, startDate, endDate time.Time) (*models.MeasurementComparison, error) {
+	comparison, err := s.measurementRepo.CompareMeasurements(ctx, userID, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compare measurements: %w", err)
 	}
@@ -238,14 +226,14 @@ func (s *ProgressService) CompareMeasurements(ctx context.Context, userID uint,
 }
 
 // UpdateMeasurement updates an existing measurement
-func (s *ProgressService) UpdateMeasurement(ctx context.Context, userID uint, measurement *models.BodyMeasurement) (*models.BodyMeasurement, error) {
+func (s *ProgressService) UpdateMeasurement(ctx context.Context, userID string, measurement *models.BodyMeasurement) (*models.BodyMeasurement, error) {
 	// Validate measurement
 	if err := measurement.Validate(); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
 	// Ensure user ID matches
-	measurement.UserID = uint(userID)
+	measurement.UserID = userID
 
 	// Update measurement
 	err := s.measurementRepo.UpdateBodyMeasurement(ctx, measurement)
@@ -257,8 +245,8 @@ func (s *ProgressService) UpdateMeasurement(ctx context.Context, userID uint, me
 }
 
 // DeleteMeasurement deletes a measurement
-func (s *ProgressService) DeleteMeasurement(ctx context.Context, userID uint, measurementID int64) error {
-	err := s.measurementRepo.DeleteBodyMeasurement(ctx, measurementID, int64(userID))
+func (s *ProgressService) DeleteMeasurement(ctx context.Context, userID string, measurementID int64) error {
+	err := s.measurementRepo.DeleteBodyMeasurement(ctx, measurementID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete measurement: %w", err)
 	}
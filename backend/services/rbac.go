@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// RBAC errors returned by RBACService
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionNotFound = errors.New("permission not found")
+)
+
+const defaultPermissionCacheTTL = time.Minute
+
+// RBACService resolves role permissions stored in the database. Lookups are
+// served from an in-memory snapshot refreshed every cacheTTL and after changes.
+type RBACService struct {
+	db       *sql.DB
+	cacheTTL time.Duration
+
+	mu       sync.RWMutex
+	grants   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+// NewRBACService creates a new RBACService instance
+func NewRBACService(db *sql.DB) *RBACService {
+	return &RBACService{
+		db:       db,
+		cacheTTL: defaultPermissionCacheTTL,
+	}
+}
+
+// HasPermission reports whether role grants permission. The admin role is
+// granted every permission.
+func (s *RBACService) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	if role == models.RoleAdmin {
+		return true, nil
+	}
+
+	grants, err := s.snapshot(ctx)
+	if err != nil {
+		return false, err
+	}
+	return grants[role][permission], nil
+}
+
+// GetRolePermissions returns the permissions granted to role, sorted by name
+func (s *RBACService) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	grants, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]string, 0, len(grants[role]))
+	for permission := range grants[role] {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+// ListRoles returns every role with its permissions
+func (s *RBACService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, description FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var (
+			role        models.Role
+			description sql.NullString
+		)
+		if err := rows.Scan(&role.Name, &description); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		role.Description = description.String
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range roles {
+		roles[i].Permissions, err = s.GetRolePermissions(ctx, roles[i].Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
+
+// ListPermissions returns every known permission
+func (s *RBACService) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var (
+			permission  models.Permission
+			description sql.NullString
+		)
+		if err := rows.Scan(&permission.Name, &description); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permission.Description = description.String
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// GrantPermission adds permission to role
+func (s *RBACService) GrantPermission(ctx context.Context, role, permission string) error {
+	if err := s.checkRoleAndPermission(ctx, role, permission); err != nil {
+		return err
+	}
+
+	query := `INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`
+	if _, err := s.db.ExecContext(ctx, query, role, permission); err != nil {
+		return fmt.Errorf("failed to grant permission: %w", err)
+	}
+
+	s.invalidate()
+	return nil
+}
+
+// RevokePermission removes permission from role
+func (s *RBACService) RevokePermission(ctx context.Context, role, permission string) error {
+	if err := s.checkRoleAndPermission(ctx, role, permission); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM role_permissions WHERE role = ? AND permission = ?`
+	if _, err := s.db.ExecContext(ctx, query, role, permission); err != nil {
+		return fmt.Errorf("failed to revoke permission: %w", err)
+	}
+
+	s.invalidate()
+	return nil
+}
+
+// AssignUserRole changes a user's role. It applies to access tokens issued
+// from the next login or token refresh.
+func (s *RBACService) AssignUserRole(ctx context.Context, userID, role string) error {
+	if exists, err := s.exists(ctx, `SELECT COUNT(*) FROM roles WHERE name = ?`, role); err != nil {
+		return err
+	} else if !exists {
+		return ErrRoleNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET role = ?, updated_at = ? WHERE id = ?`,
+		role, time.Now().UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *RBACService) checkRoleAndPermission(ctx context.Context, role, permission string) error {
+	if exists, err := s.exists(ctx, `SELECT COUNT(*) FROM roles WHERE name = ?`, role); err != nil {
+		return err
+	} else if !exists {
+		return ErrRoleNotFound
+	}
+
+	if exists, err := s.exists(ctx, `SELECT COUNT(*) FROM permissions WHERE name = ?`, permission); err != nil {
+		return err
+	} else if !exists {
+		return ErrPermissionNotFound
+	}
+
+	return nil
+}
+
+func (s *RBACService) exists(ctx context.Context, query string, arg interface{}) (bool, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, arg).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+	return count > 0, nil
+}
+
+// snapshot returns the cached role -> permission grants, reloading them once stale
+func (s *RBACService) snapshot(ctx context.Context) (map[string]map[string]bool, error) {
+	s.mu.RLock()
+	if s.grants != nil && time.Since(s.loadedAt) < s.cacheTTL {
+		grants := s.grants
+		s.mu.RUnlock()
+		return grants, nil
+	}
+	s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT role, permission FROM role_permissions`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	grants := make(map[string]map[string]bool)
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role permission: %w", err)
+		}
+		if grants[role] == nil {
+			grants[role] = make(map[string]bool)
+		}
+		grants[role][permission] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.grants = grants
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return grants, nil
+}
+
+func (s *RBACService) invalidate() {
+	s.mu.Lock()
+	s.grants = nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRBACService(t *testing.T) *RBACService {
+	return NewRBACService(newTestUserService(t).db)
+}
+
+func TestRBACService_HasPermission(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestRBACService(t)
+
+	cases := []struct {
+		role, permission string
+		want             bool
+	}{
+		{models.RoleAdmin, models.PermissionRolesManage, true},
+		{models.RoleDietitian, models.PermissionFoodsVerify, true},
+		{models.RoleDietitian, models.PermissionAuditRead, false},
+		{models.RoleClinicAdmin, models.PermissionProductsReview, true},
+		{models.RoleCoach, models.PermissionPlansAssign, true},
+		{models.RoleCoach, models.PermissionUsersReadHealth, false},
+		{models.RolePatient, models.PermissionFoodsVerify, false},
+		{"unknown", models.PermissionFoodsVerify, false},
+	}
+
+	for _, tc := range cases {
+		got, err := svc.HasPermission(ctx, tc.role, tc.permission)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "%s %s", tc.role, tc.permission)
+	}
+}
+
+func TestRBACService_GrantAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestRBACService(t)
+
+	// Prime the cache so the grant has to invalidate it
+	allowed, err := svc.HasPermission(ctx, models.RoleCoach, models.PermissionFoodsVerify)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	require.NoError(t, svc.GrantPermission(ctx, models.RoleCoach, models.PermissionFoodsVerify))
+	allowed, err = svc.HasPermission(ctx, models.RoleCoach, models.PermissionFoodsVerify)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, svc.RevokePermission(ctx, models.RoleCoach, models.PermissionFoodsVerify))
+	permissions, err := svc.GetRolePermissions(ctx, models.RoleCoach)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.PermissionPlansAssign}, permissions)
+
+	assert.ErrorIs(t, svc.GrantPermission(ctx, "wizard", models.PermissionFoodsVerify), ErrRoleNotFound)
+	assert.ErrorIs(t, svc.GrantPermission(ctx, models.RoleCoach, "foods:eat"), ErrPermissionNotFound)
+
+	roles, err := svc.ListRoles(ctx)
+	require.NoError(t, err)
+	assert.Len(t, roles, 6)
+}
+
+func TestRBACService_AssignUserRole(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestRBACService(t)
+	users := NewUserService(svc.db)
+
+	user, err := users.CreateUser(ctx, CreateUserInput{Email: "staff@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.AssignUserRole(ctx, user.ID, models.RoleDietitian))
+	assert.ErrorIs(t, svc.AssignUserRole(ctx, user.ID, "wizard"), ErrRoleNotFound)
+	assert.ErrorIs(t, svc.AssignUserRole(ctx, "missing", models.RoleCoach), ErrUserNotFound)
+
+	var role string
+	require.NoError(t, svc.db.QueryRow("SELECT role FROM users WHERE id = ?", user.ID).Scan(&role))
+	assert.Equal(t, models.RoleDietitian, role)
+}
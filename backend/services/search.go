@@ -0,0 +1,886 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Search errors returned by SearchService
+var (
+	ErrInvalidSearch = errors.New("invalid search request")
+)
+
+// DefaultSearchRebuildInterval is how often Watch rebuilds the search index
+const DefaultSearchRebuildInterval = 10 * time.Minute
+
+const (
+	searchEngineFTS5   = "fts5"
+	searchEngineMemory = "memory"
+
+	// BM25 parameters and column weights, identical for FTS5 and the in-memory ranker
+	bm25K1            = 1.2
+	bm25B             = 0.75
+	searchTitleWeight = 4.0
+	searchBodyWeight  = 1.0
+
+	maxSearchSuggestions = 3
+)
+
+var searchTypes = []string{
+	models.SearchTypeRecipe,
+	models.SearchTypeWorkout,
+	models.SearchTypeComplaint,
+	models.SearchTypeDisease,
+}
+
+// searchDocument is one searchable record with its original text for display
+// and its normalized terms for matching
+type searchDocument struct {
+	entityType string
+	id         string
+	title      string
+	titleAr    string
+	body       string
+
+	titleTerms []string
+	bodyTerms  []string
+	weighted   map[string]float64 // term frequency weighted by column
+	length     int
+
+	// Recipes keep their ingredients for halal filtering and the allergens
+	// they contain for allergy filtering
+	ingredients []string
+	halal       bool
+	allergens   []string
+}
+
+// searchIndex is an immutable build of every searchable document
+type searchIndex struct {
+	docs       []*searchDocument
+	postings   map[string][]int
+	terms      []string
+	vocabulary map[string]int
+	avgLength  float64
+	engine     string
+	builtAt    time.Time
+}
+
+type searchMatch struct {
+	doc   int
+	score float64
+}
+
+// SearchService provides ranked bilingual full-text search across recipes,
+// workouts, complaints and diseases. Matching and BM25 ranking run in an FTS5
+// table when the SQLite build includes it, and in memory otherwise.
+type SearchService struct {
+	db            *sql.DB
+	knowledgeBase *KnowledgeBase
+	compliance    *HalalCompliance
+	allergens     *AllergenRegistry
+
+	mu    sync.RWMutex
+	index *searchIndex
+	fts5  *bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSearchService creates a new search service. The knowledge base is
+// optional and contributes its disease guides to the index; compliance checks
+// recipes for searches restricted to halal results and allergens finds the
+// allergens in recipes for searches leaving out a user's allergies.
+func NewSearchService(db *sql.DB, knowledgeBase *KnowledgeBase, compliance *HalalCompliance, allergens *AllergenRegistry) *SearchService {
+	return &SearchService{
+		db:            db,
+		knowledgeBase: knowledgeBase,
+		compliance:    compliance,
+		allergens:     allergens,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Rebuild re-reads every source and replaces the index
+func (s *SearchService) Rebuild(ctx context.Context) error {
+	docs, err := s.collectDocuments(ctx)
+	if err != nil {
+		return err
+	}
+	index := buildSearchIndex(docs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fts5 == nil {
+		available, err := s.createFTSTable(ctx)
+		if err != nil {
+			return err
+		}
+		s.fts5 = &available
+	}
+	if *s.fts5 {
+		if err := s.writeFTSTable(ctx, docs); err != nil {
+			return err
+		}
+		index.engine = searchEngineFTS5
+	}
+
+	s.index = index
+	return nil
+}
+
+// Watch rebuilds the index every interval so new recipes, exercises and data
+// imports become searchable. It returns immediately; call Close to stop.
+func (s *SearchService) Watch(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSearchRebuildInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.Rebuild(context.Background()); err != nil {
+					log.Printf("search: failed to rebuild index: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the rebuilds started by Watch
+func (s *SearchService) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Search ranks every document against the query and returns one page of
+// results with facet counts per entity type, ignoring the type filter. With
+// req.Halal set, recipes failing a compliance check with those preferences
+// are left out of results and facets, as are recipes containing any of
+// req.Allergies.
+func (s *SearchService) Search(ctx context.Context, req models.SearchRequest) (*models.SearchResponse, error) {
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidSearch)
+	}
+	types := map[string]bool{}
+	for _, entityType := range req.Types {
+		if !containsFold(searchTypes, []string{entityType}) {
+			return nil, fmt.Errorf("%w: unknown type %q, expected one of %s",
+				ErrInvalidSearch, entityType, strings.Join(searchTypes, ", "))
+		}
+		types[strings.ToLower(entityType)] = true
+	}
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := req.Limit
+	if limit < 1 {
+		limit = 20
+	}
+
+	tokens := tokenizeKnowledge(query)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: query has no searchable words", ErrInvalidSearch)
+	}
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = searchTerm(token)
+	}
+
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	index := s.index
+
+	var matches []searchMatch
+	var err error
+	if index.engine == searchEngineFTS5 {
+		matches, err = s.matchFTS(ctx, terms)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		matches = index.match(terms)
+	}
+
+	response := &models.SearchResponse{
+		Query:     query,
+		Results:   []models.SearchHit{},
+		Page:      page,
+		Limit:     limit,
+		Facets:    map[string]int{},
+		Engine:    index.engine,
+		IndexedAt: index.builtAt,
+	}
+	for _, entityType := range searchTypes {
+		response.Facets[entityType] = 0
+	}
+
+	allergic := map[string]bool{}
+	for _, allergen := range req.Allergies {
+		allergic[allergen] = true
+	}
+
+	highlighter := newSearchHighlighter(terms)
+	var filtered []searchMatch
+	for _, match := range matches {
+		doc := index.docs[match.doc]
+		if doc.hasAllergen(allergic) {
+			continue
+		}
+		if req.Halal != nil && doc.entityType == models.SearchTypeRecipe {
+			halal, err := s.isHalalRecipe(doc, *req.Halal)
+			if err != nil {
+				return nil, err
+			}
+			if !halal {
+				continue
+			}
+		}
+		response.Facets[doc.entityType]++
+		if len(types) == 0 || types[doc.entityType] {
+			filtered = append(filtered, match)
+		}
+	}
+	response.Total = len(filtered)
+
+	start := (page - 1) * limit
+	for i := start; i < len(filtered) && i < start+limit; i++ {
+		response.Results = append(response.Results, index.hit(filtered[i], highlighter))
+	}
+
+	response.Suggestions = index.suggest(tokens, terms)
+	return response, nil
+}
+
+// isHalalRecipe checks a recipe document with prefs. Recipes marked as not
+// halal fail without a check; substitutions count when prefs opt into them.
+func (s *SearchService) isHalalRecipe(doc *searchDocument, prefs models.HalalPreferences) (bool, error) {
+	if !doc.halal {
+		return false, nil
+	}
+	if s.compliance == nil {
+		return true, nil
+	}
+	result, err := s.compliance.CheckCompliance(doc.ingredients, "", prefs)
+	if err != nil {
+		return false, err
+	}
+	return result.IsCompliant, nil
+}
+
+// hasAllergen reports whether the document contains one of allergic
+func (d *searchDocument) hasAllergen(allergic map[string]bool) bool {
+	for _, allergen := range d.allergens {
+		if allergic[allergen] {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAny ranks the documents of the given types that contain any of the
+// query's words, ignoring stop words. Unlike Search it does not require every
+// word to match, which suits free-text questions looking for evidence. It
+// always ranks with the in-memory index.
+func (s *SearchService) MatchAny(ctx context.Context, query string, types []string, limit int) ([]models.SearchHit, error) {
+	var terms []string
+	for _, token := range tokenizeKnowledge(query) {
+		if !searchStopWords[token] {
+			terms = append(terms, searchTerm(token))
+		}
+	}
+	hits := []models.SearchHit{}
+	if len(terms) == 0 {
+		return hits, nil
+	}
+
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	index := s.index
+
+	highlighter := newSearchHighlighter(terms)
+	for _, match := range index.matchAny(uniqueStrings(terms)) {
+		if len(hits) == limit {
+			break
+		}
+		if len(types) == 0 || containsFold(types, []string{index.docs[match.doc].entityType}) {
+			hits = append(hits, index.hit(match, highlighter))
+		}
+	}
+	return hits, nil
+}
+
+func (s *SearchService) ensureIndex(ctx context.Context) error {
+	s.mu.RLock()
+	built := s.index != nil
+	s.mu.RUnlock()
+	if built {
+		return nil
+	}
+	return s.Rebuild(ctx)
+}
+
+// createFTSTable creates the FTS5 table, reporting false when this SQLite build has no FTS5
+func (s *SearchService) createFTSTable(ctx context.Context) (bool, error) {
+	_, err := s.db.ExecContext(ctx, `CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts5(
+		title_terms, body_terms, tokenize = 'unicode61 remove_diacritics 0')`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			log.Printf("search: FTS5 is not available in this SQLite build, ranking in memory")
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create search index: %w", err)
+	}
+	return true, nil
+}
+
+// writeFTSTable replaces the FTS5 rows; rowid n holds document n-1
+func (s *SearchService) writeFTSTable(ctx context.Context, docs []*searchDocument) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM search_fts`); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO search_fts (rowid, title_terms, body_terms) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	defer stmt.Close()
+	for i, doc := range docs {
+		if _, err := stmt.ExecContext(ctx, i+1, strings.Join(doc.titleTerms, " "), strings.Join(doc.bodyTerms, " ")); err != nil {
+			return fmt.Errorf("failed to write search index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	return nil
+}
+
+// matchFTS runs the query through FTS5: every term must match and the last is a prefix
+func (s *SearchService) matchFTS(ctx context.Context, terms []string) ([]searchMatch, error) {
+	phrases := make([]string, len(terms))
+	for i, term := range terms {
+		phrases[i] = `"` + term + `"`
+	}
+	phrases[len(phrases)-1] += "*"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT rowid, bm25(search_fts, ?, ?) AS rank
+		FROM search_fts WHERE search_fts MATCH ? ORDER BY rank, rowid`,
+		searchTitleWeight, searchBodyWeight, strings.Join(phrases, " AND "))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []searchMatch
+	for rows.Next() {
+		var rowID int
+		var rank float64
+		if err := rows.Scan(&rowID, &rank); err != nil {
+			return nil, fmt.Errorf("failed to search: %w", err)
+		}
+		// FTS5 reports BM25 negated so that ascending order is best first
+		matches = append(matches, searchMatch{doc: rowID - 1, score: -rank})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	return matches, nil
+}
+
+func buildSearchIndex(docs []*searchDocument) *searchIndex {
+	index := &searchIndex{
+		docs:       docs,
+		postings:   map[string][]int{},
+		vocabulary: map[string]int{},
+		engine:     searchEngineMemory,
+		builtAt:    time.Now().UTC(),
+	}
+
+	total := 0
+	for i, doc := range docs {
+		doc.weighted = map[string]float64{}
+		for _, term := range doc.titleTerms {
+			doc.weighted[term] += searchTitleWeight
+		}
+		for _, term := range doc.bodyTerms {
+			doc.weighted[term] += searchBodyWeight
+		}
+		doc.length = len(doc.titleTerms) + len(doc.bodyTerms)
+		total += doc.length
+
+		for term := range doc.weighted {
+			index.postings[term] = append(index.postings[term], i)
+		}
+		words := map[string]bool{}
+		for _, text := range []string{doc.title, doc.titleAr, doc.body} {
+			for _, word := range tokenizeKnowledge(text) {
+				words[word] = true
+			}
+		}
+		for word := range words {
+			index.vocabulary[word]++
+		}
+	}
+	if len(docs) > 0 {
+		index.avgLength = float64(total) / float64(len(docs))
+	}
+
+	index.terms = make([]string, 0, len(index.postings))
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+	sort.Strings(index.terms)
+	return index
+}
+
+// expand returns the indexed terms a query term stands for
+func (x *searchIndex) expand(term string, prefix bool) []string {
+	if !prefix {
+		if _, ok := x.postings[term]; ok {
+			return []string{term}
+		}
+		return nil
+	}
+	var terms []string
+	for i := sort.SearchStrings(x.terms, term); i < len(x.terms) && strings.HasPrefix(x.terms[i], term); i++ {
+		terms = append(terms, x.terms[i])
+	}
+	return terms
+}
+
+// match ranks documents with the same BM25 formula FTS5 uses: each phrase
+// contributes idf * f * (k1 + 1) / (f + k1 * (1 - b + b * len / avglen)),
+// where f is the column-weighted frequency of the phrase in the document
+func (x *searchIndex) match(terms []string) []searchMatch {
+	return x.rank(terms, true)
+}
+
+// matchAny ranks documents containing any of the terms, all matched exactly
+func (x *searchIndex) matchAny(terms []string) []searchMatch {
+	return x.rank(terms, false)
+}
+
+// rank scores documents with BM25. With all set, a document must contain
+// every term and the last term also matches as a prefix.
+func (x *searchIndex) rank(terms []string, all bool) []searchMatch {
+	scores := map[int]float64{}
+	for i, term := range terms {
+		expanded := x.expand(term, all && i == len(terms)-1)
+		docs := map[int]bool{}
+		for _, indexed := range expanded {
+			for _, doc := range x.postings[indexed] {
+				docs[doc] = true
+			}
+		}
+
+		n := float64(len(docs))
+		idf := math.Log((float64(len(x.docs)) - n + 0.5) / (n + 0.5))
+		if idf <= 0 {
+			idf = 1e-6
+		}
+
+		// Documents missing the term drop out when every term is required
+		next := scores
+		if all {
+			next = map[int]float64{}
+		}
+		for doc := range docs {
+			if _, ok := scores[doc]; all && i > 0 && !ok {
+				continue
+			}
+			frequency := 0.0
+			for _, indexed := range expanded {
+				frequency += x.docs[doc].weighted[indexed]
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(x.docs[doc].length)/x.avgLength)
+			next[doc] = scores[doc] + idf*frequency*(bm25K1+1)/(frequency+norm)
+		}
+		scores = next
+	}
+
+	matches := make([]searchMatch, 0, len(scores))
+	for doc, score := range scores {
+		matches = append(matches, searchMatch{doc: doc, score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].doc < matches[j].doc
+	})
+	return matches
+}
+
+// hit renders a ranked document as a search result
+func (x *searchIndex) hit(match searchMatch, highlighter *searchHighlighter) models.SearchHit {
+	doc := x.docs[match.doc]
+	return models.SearchHit{
+		Type:             doc.entityType,
+		ID:               doc.id,
+		Title:            doc.title,
+		TitleAr:          doc.titleAr,
+		HighlightedTitle: highlighter.highlight(strings.TrimSpace(doc.title + " " + doc.titleAr)),
+		Snippet:          highlighter.snippet(doc.body),
+		Score:            math.Round(match.score*1000) / 1000,
+	}
+}
+
+// suggest proposes corrected queries when a word is not in the index.
+// Candidates come from the vocabulary within one edit for short words and two
+// for longer ones, preferring the closest and then the most common.
+func (x *searchIndex) suggest(tokens, terms []string) []string {
+	candidates := make([][]string, len(tokens))
+	corrected := false
+	for i, token := range tokens {
+		candidates[i] = []string{token}
+		if len(x.expand(terms[i], i == len(tokens)-1)) > 0 {
+			continue
+		}
+		if corrections := x.corrections(token); len(corrections) > 0 {
+			candidates[i] = corrections
+			corrected = true
+		}
+	}
+	if !corrected {
+		return nil
+	}
+
+	var suggestions []string
+	seen := map[string]bool{}
+	for k := 0; k < maxSearchSuggestions; k++ {
+		words := make([]string, len(tokens))
+		for i, options := range candidates {
+			words[i] = options[min(k, len(options)-1)]
+		}
+		suggestion := strings.Join(words, " ")
+		if !seen[suggestion] {
+			seen[suggestion] = true
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
+
+func (x *searchIndex) corrections(word string) []string {
+	maxEdits := 2
+	switch length := len([]rune(word)); {
+	case length < 3:
+		return nil
+	case length <= 5:
+		maxEdits = 1
+	}
+
+	type candidate struct {
+		word      string
+		distance  int
+		frequency int
+	}
+	var found []candidate
+	for known, frequency := range x.vocabulary {
+		if distance := editDistance(word, known, maxEdits); distance <= maxEdits && distance > 0 {
+			found = append(found, candidate{word: known, distance: distance, frequency: frequency})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		if found[i].frequency != found[j].frequency {
+			return found[i].frequency > found[j].frequency
+		}
+		return found[i].word < found[j].word
+	})
+
+	var words []string
+	for i := 0; i < len(found) && i < maxSearchSuggestions; i++ {
+		words = append(words, found[i].word)
+	}
+	return words
+}
+
+func newSearchDocument(entityType, id, title, titleAr string, body ...string) *searchDocument {
+	text := strings.Join(nonBlank(body), "\n")
+	return &searchDocument{
+		entityType: entityType,
+		id:         id,
+		title:      title,
+		titleAr:    titleAr,
+		body:       text,
+		titleTerms: searchTerms(title + " " + titleAr),
+		bodyTerms:  searchTerms(text),
+	}
+}
+
+// collectDocuments reads every searchable source. Tables that only exist once
+// the JSON data has been imported are skipped when missing.
+func (s *SearchService) collectDocuments(ctx context.Context) ([]*searchDocument, error) {
+	var docs []*searchDocument
+	for _, collect := range []func(context.Context) ([]*searchDocument, error){
+		s.collectRecipes,
+		s.collectExercises,
+		s.collectWorkoutPlans,
+		s.collectComplaints,
+		s.collectConditions,
+	} {
+		collected, err := collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, collected...)
+	}
+
+	if s.knowledgeBase != nil {
+		for _, disease := range s.knowledgeBase.Snapshot().Diseases {
+			title := disease.NameEn
+			if title == "" {
+				title = disease.Slug
+			}
+			docs = append(docs, newSearchDocument(models.SearchTypeDisease, disease.Slug, title, disease.NameAr,
+				disease.DescriptionEn, strings.Join(disease.BeneficialFoods, ", ")))
+		}
+	}
+	return docs, nil
+}
+
+func (s *SearchService) collectRecipes(ctx context.Context) ([]*searchDocument, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, COALESCE(name_ar, ''), COALESCE(description, ''),
+		COALESCE(description_ar, ''), COALESCE(cuisine, ''), COALESCE(ingredients, '[]'), COALESCE(dietary_tags, '[]'),
+		COALESCE(is_halal, 1), COALESCE(allergens, '[]')
+		FROM recipes ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index recipes: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*searchDocument
+	for rows.Next() {
+		var id, name, nameAr, description, descriptionAr, cuisine, ingredientsJSON, tagsJSON, allergensJSON string
+		var isHalal bool
+		if err := rows.Scan(&id, &name, &nameAr, &description, &descriptionAr, &cuisine, &ingredientsJSON, &tagsJSON,
+			&isHalal, &allergensJSON); err != nil {
+			return nil, fmt.Errorf("failed to index recipes: %w", err)
+		}
+
+		names := recipeIngredientNames(ingredientsJSON)
+		doc := newSearchDocument(models.SearchTypeRecipe, id, name, nameAr, description, descriptionAr,
+			cuisine, strings.Join(names, ", "), strings.Join(decodeStringList(tagsJSON), ", "))
+		doc.ingredients = names
+		doc.halal = isHalal
+		if s.allergens != nil {
+			texts := append([]string{name}, names...)
+			texts = append(texts, decodeStringList(allergensJSON)...)
+			doc.allergens = s.allergens.Detect(texts).Contains
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to index recipes: %w", err)
+	}
+	return docs, nil
+}
+
+// recipeIngredientNames decodes a recipes.ingredients column holding either
+// RecipeIngredient objects or plain names
+func recipeIngredientNames(raw string) []string {
+	var ingredients []models.RecipeIngredient
+	if json.Unmarshal([]byte(raw), &ingredients) != nil {
+		return nonBlank(decodeStringList(raw))
+	}
+	names := make([]string, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		names = append(names, ingredient.Name)
+	}
+	return nonBlank(names)
+}
+
+func (s *SearchService) collectExercises(ctx context.Context) ([]*searchDocument, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, COALESCE(name_ar, ''), COALESCE(description, ''),
+		COALESCE(description_ar, ''), COALESCE(category, ''), COALESCE(muscle_groups, '[]'), COALESCE(equipment, '')
+		FROM exercises ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index exercises: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*searchDocument
+	for rows.Next() {
+		var id, name, nameAr, description, descriptionAr, category, musclesJSON, equipment string
+		if err := rows.Scan(&id, &name, &nameAr, &description, &descriptionAr, &category, &musclesJSON, &equipment); err != nil {
+			return nil, fmt.Errorf("failed to index exercises: %w", err)
+		}
+		docs = append(docs, newSearchDocument(models.SearchTypeWorkout, id, name, nameAr, description, descriptionAr,
+			category, strings.Join(decodeStringList(musclesJSON), ", "), equipment))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to index exercises: %w", err)
+	}
+	return docs, nil
+}
+
+func (s *SearchService) collectWorkoutPlans(ctx context.Context) ([]*searchDocument, error) {
+	if exists, err := tableExists(ctx, s.db, "workout_plans_json"); err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, COALESCE(goal, ''), COALESCE(purpose, ''),
+		COALESCE(training_split, ''), COALESCE(experience_level, '') FROM workout_plans_json ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index workout plans: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*searchDocument
+	for rows.Next() {
+		var id int64
+		var goal, purpose, split, level string
+		if err := rows.Scan(&id, &goal, &purpose, &split, &level); err != nil {
+			return nil, fmt.Errorf("failed to index workout plans: %w", err)
+		}
+		docs = append(docs, newSearchDocument(models.SearchTypeWorkout, "plan-"+strconv.FormatInt(id, 10),
+			goal, "", purpose, split, level))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to index workout plans: %w", err)
+	}
+	return docs, nil
+}
+
+func (s *SearchService) collectComplaints(ctx context.Context) ([]*searchDocument, error) {
+	if exists, err := tableExists(ctx, s.db, "health_complaint_cases"); err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, condition_en, condition_ar, COALESCE(recommendations, '')
+		FROM health_complaint_cases ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index complaints: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*searchDocument
+	for rows.Next() {
+		var id int64
+		var conditionEn, conditionAr, recommendations string
+		if err := rows.Scan(&id, &conditionEn, &conditionAr, &recommendations); err != nil {
+			return nil, fmt.Errorf("failed to index complaints: %w", err)
+		}
+		docs = append(docs, newSearchDocument(models.SearchTypeComplaint, strconv.FormatInt(id, 10),
+			conditionEn, conditionAr, jsonText(recommendations)...))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to index complaints: %w", err)
+	}
+	return docs, nil
+}
+
+func (s *SearchService) collectConditions(ctx context.Context) ([]*searchDocument, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, COALESCE(name_ar, ''), COALESCE(description, ''),
+		COALESCE(description_ar, ''), COALESCE(symptoms, '[]'), COALESCE(dietary_recommendations, '[]')
+		FROM health_conditions ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index health conditions: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*searchDocument
+	for rows.Next() {
+		var id, name, nameAr, description, descriptionAr, symptoms, recommendations string
+		if err := rows.Scan(&id, &name, &nameAr, &description, &descriptionAr, &symptoms, &recommendations); err != nil {
+			return nil, fmt.Errorf("failed to index health conditions: %w", err)
+		}
+		docs = append(docs, newSearchDocument(models.SearchTypeDisease, id, name, nameAr, description, descriptionAr,
+			strings.Join(decodeStringList(symptoms), ", "), strings.Join(decodeStringList(recommendations), ", ")))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to index health conditions: %w", err)
+	}
+	return docs, nil
+}
+
+func nonBlank(values []string) []string {
+	var kept []string
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			kept = append(kept, strings.TrimSpace(value))
+		}
+	}
+	return kept
+}
+
+func tableExists(ctx context.Context, db *sql.DB, name string) (bool, error) {
+	var tables int
+	if err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&tables); err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", name, err)
+	}
+	return tables > 0, nil
+}
+
+// jsonText collects the string values of a JSON document in order, or returns
+// the raw text when it is not JSON
+func jsonText(raw string) []string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return []string{raw}
+	}
+
+	var texts []string
+	var walk func(interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			texts = append(texts, v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, key := range sortedMapKeys(v) {
+				walk(v[key])
+			}
+		}
+	}
+	walk(value)
+	return texts
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSearchService(t *testing.T) *SearchService {
+	t.Helper()
+	users := newTestUserService(t)
+	db := users.db
+
+	_, err := db.Exec(`INSERT INTO recipes (id, name, name_ar, description, cuisine, ingredients, dietary_tags) VALUES
+		('kabsa', 'Chicken Kabsa', 'كبسة الدجاج', 'Spiced rice with roasted chicken.', 'Saudi',
+		 '[{"name": "chicken", "amount": 500, "unit": "g"}, {"name": "basmati rice", "amount": 2, "unit": "cup"}]', '["halal"]'),
+		('oats', 'Overnight Oats', NULL, 'High-protein breakfast, good for blood sugar.', NULL, '["oats", "yogurt"]', '[]'),
+		('salad', 'Lentil Salad', 'سلطة العدس', 'Proteins from lentils & greens <fresh>.', NULL, '[]', '[]')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO exercises (id, name, name_ar, description, category, muscle_groups, equipment) VALUES
+		('squat', 'Barbell Squat', 'القرفصاء', 'Squatting builds leg strength.', 'strength', '["quadriceps", "glutes"]', 'barbell'),
+		('stretch', 'Hamstring Stretch', NULL, 'Gentle stretching after running.', 'flexibility', '["hamstrings"]', NULL)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO health_conditions (id, name, name_ar, description, symptoms) VALUES
+		('hypertension', 'Hypertension', 'ارتفاع ضغط الدم', 'High blood pressure; limit sodium.', '["headache"]')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE health_complaint_cases (id INTEGER PRIMARY KEY, condition_en TEXT NOT NULL,
+		condition_ar TEXT NOT NULL, recommendations TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO health_complaint_cases (id, condition_en, condition_ar, recommendations) VALUES
+		(7, 'Bloating', 'الانتفاخ', '{"nutrition": ["Eat slowly", "Limit fizzy drinks"], "supplements": ["Peppermint oil"]}')`)
+	require.NoError(t, err)
+
+	return NewSearchService(db, NewKnowledgeBase(newTestKnowledgeDir(t)), nil, nil)
+}
+
+func TestSearchService_Search(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestSearchService(t)
+
+	// Stemming matches "proteins" and "protein"; BM25 favours the shorter recipe
+	results, err := svc.Search(ctx, models.SearchRequest{Query: "protein"})
+	require.NoError(t, err)
+	require.Equal(t, 2, results.Total)
+	assert.Equal(t, "salad", results.Results[0].ID)
+	assert.Equal(t, "<mark>Proteins</mark> from lentils &amp; greens &lt;fresh&gt;.", results.Results[0].Snippet)
+	assert.Equal(t, "oats", results.Results[1].ID)
+	assert.Greater(t, results.Results[0].Score, results.Results[1].Score)
+
+	// Facets count every type while the filter narrows the results
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "blood", Types: []string{"disease"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"recipe": 1, "workout": 0, "complaint": 0, "disease": 2}, results.Facets)
+	assert.Equal(t, 2, results.Total)
+	for _, hit := range results.Results {
+		assert.Equal(t, models.SearchTypeDisease, hit.Type)
+	}
+
+	// Arabic folding: no article, ta marbuta typed as ha, alef variants
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "كبسه"})
+	require.NoError(t, err)
+	require.Equal(t, 1, results.Total)
+	assert.Equal(t, "Chicken Kabsa <mark>كبسة</mark> الدجاج", results.Results[0].HighlightedTitle)
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "ارتفاع الضغط"})
+	require.NoError(t, err)
+	require.Equal(t, 1, results.Total)
+	assert.Equal(t, "hypertension", results.Results[0].ID)
+
+	// The last word matches as a prefix; inflections meet on the same stem
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "stretched hamst"})
+	require.NoError(t, err)
+	require.Equal(t, 1, results.Total)
+	assert.Equal(t, models.SearchTypeWorkout, results.Results[0].Type)
+	assert.Contains(t, results.Results[0].Snippet, "<mark>stretching</mark>")
+
+	// Complaints and knowledge base diseases are indexed
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "peppermint"})
+	require.NoError(t, err)
+	require.Equal(t, 1, results.Total)
+	assert.Equal(t, "7", results.Results[0].ID)
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "diabetes", Types: []string{"disease"}})
+	require.NoError(t, err)
+	require.Equal(t, 1, results.Total)
+	assert.Equal(t, "type-2-diabetes", results.Results[0].ID)
+
+	// Typos get suggestions from the vocabulary
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "chiken rcie"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, results.Total)
+	require.NotEmpty(t, results.Suggestions)
+	assert.Equal(t, "chicken rice", results.Suggestions[0])
+
+	// Pagination
+	results, err = svc.Search(ctx, models.SearchRequest{Query: "blood", Page: 2, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, results.Total)
+	assert.Len(t, results.Results, 1)
+
+	_, err = svc.Search(ctx, models.SearchRequest{Query: "rice", Types: []string{"injury"}})
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+	_, err = svc.Search(ctx, models.SearchRequest{Query: " - "})
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+}
+
+func TestSearchService_RankingMatchesFTS5(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestSearchService(t)
+	require.NoError(t, svc.Rebuild(ctx))
+	if svc.index.engine != searchEngineFTS5 {
+		t.Skip("SQLite was built without FTS5; build with -tags sqlite_fts5")
+	}
+
+	for _, query := range []string{"protein", "blood", "chicken rice", "stretch ham"} {
+		terms := searchTerms(query)
+		fts, err := svc.matchFTS(ctx, terms)
+		require.NoError(t, err)
+		memory := svc.index.match(terms)
+		require.Len(t, memory, len(fts), query)
+		for i := range fts {
+			assert.Equal(t, fts[i].doc, memory[i].doc, query)
+			assert.InDelta(t, fts[i].score, memory[i].score, 1e-9, query)
+		}
+	}
+}
+
+func TestStemEnglish(t *testing.T) {
+	for word, stem := range map[string]string{
+		"proteins":   "protein",
+		"injuries":   "injury",
+		"running":    "run",
+		"stretching": "stretch",
+		"exercises":  "exercis",
+		"exercising": "exercis",
+		"hopped":     "hop",
+		"rice":       "rice",
+		"hummus":     "hummus",
+	} {
+		assert.Equal(t, stem, stemEnglish(word), word)
+	}
+}
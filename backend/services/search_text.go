@@ -0,0 +1,257 @@
+package services
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// searchTerm reduces a normalized token to its index term: Arabic words lose
+// the definite article and English words are stemmed
+func searchTerm(token string) string {
+	for _, r := range token {
+		if unicode.Is(unicode.Arabic, r) {
+			return stripArabicArticle(token)
+		}
+	}
+	return stemEnglish(token)
+}
+
+// searchTerms tokenizes text the same way for documents and queries
+func searchTerms(text string) []string {
+	tokens := tokenizeKnowledge(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = searchTerm(token)
+	}
+	return terms
+}
+
+// searchStopWords are normalized question words that carry no evidence when
+// any word of a query may match
+var searchStopWords = map[string]bool{
+	"about": true, "an": true, "and": true, "any": true, "are": true, "be": true,
+	"best": true, "can": true, "do": true, "does": true, "for": true, "give": true, "good": true,
+	"how": true, "in": true, "is": true, "it": true, "me": true, "my": true, "of": true, "on": true,
+	"or": true, "should": true, "some": true, "take": true, "that": true, "the": true, "to": true,
+	"what": true, "when": true, "which": true, "with": true, "you": true, "your": true,
+	"في": true, "من": true, "على": true, "عن": true, "الي": true, "الى": true, "مع": true,
+	"ما": true, "ماذا": true, "هل": true, "كيف": true, "متي": true,
+}
+
+// stemEnglish is a light suffix-stripping stemmer modelled on the first steps
+// of the Porter algorithm. It only needs to map inflections of a word to the
+// same term, not to produce dictionary words.
+func stemEnglish(word string) string {
+	if len(word) <= 3 || !isASCIIWord(word) {
+		return word
+	}
+
+	// Plurals
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+	case strings.HasSuffix(word, "s"):
+		word = word[:len(word)-1]
+	}
+
+	// Past tense and participles
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		if !strings.HasSuffix(word, suffix) {
+			continue
+		}
+		stem := word[:len(word)-len(suffix)]
+		if len(stem) < 3 || !strings.ContainsAny(stem, "aeiouy") {
+			break
+		}
+		word = stem
+		switch {
+		case strings.HasSuffix(word, "at"), strings.HasSuffix(word, "bl"), strings.HasSuffix(word, "iz"):
+			word += "e"
+		case len(word) > 3 && word[len(word)-1] == word[len(word)-2] && !strings.ContainsRune("aeiouylsz", rune(word[len(word)-1])):
+			word = word[:len(word)-1]
+		}
+		break
+	}
+
+	// Derivational suffixes
+	for _, rule := range []struct{ suffix, replacement string }{
+		{"ational", "ate"}, {"ization", "ize"}, {"fulness", "ful"}, {"iveness", "ive"},
+		{"ousness", "ous"}, {"ation", "ate"}, {"ness", ""},
+	} {
+		if strings.HasSuffix(word, rule.suffix) && len(word)-len(rule.suffix) >= 3 {
+			word = word[:len(word)-len(rule.suffix)] + rule.replacement
+			break
+		}
+	}
+
+	// A final e is dropped so "exercise" and "exercising" meet
+	if strings.HasSuffix(word, "e") && len(word) > 4 {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+func isASCIIWord(word string) bool {
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// searchHighlighter marks the words of a text that match the query terms
+type searchHighlighter struct {
+	terms  map[string]bool
+	prefix string
+}
+
+func newSearchHighlighter(terms []string) *searchHighlighter {
+	h := &searchHighlighter{terms: map[string]bool{}}
+	for _, term := range terms {
+		h.terms[term] = true
+	}
+	// The last query term also matches as a prefix, as in the search itself
+	if len(terms) > 0 {
+		h.prefix = terms[len(terms)-1]
+	}
+	return h
+}
+
+func (h *searchHighlighter) matches(word string) bool {
+	for _, term := range searchTerms(word) {
+		if h.terms[term] || (h.prefix != "" && strings.HasPrefix(term, h.prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// textRun is a maximal run of word or non-word characters of a text
+type textRun struct {
+	text string
+	word bool
+}
+
+func splitTextRuns(text string) []textRun {
+	var runs []textRun
+	start := 0
+	inWord := false
+	for i, r := range text {
+		// Diacritics are part of the word they decorate
+		word := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if i > start && word != inWord {
+			runs = append(runs, textRun{text: text[start:i], word: inWord})
+			start = i
+		}
+		inWord = word
+	}
+	if start < len(text) {
+		runs = append(runs, textRun{text: text[start:], word: inWord})
+	}
+	return runs
+}
+
+func (h *searchHighlighter) render(runs []textRun) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.word && h.matches(run.text) {
+			b.WriteString("<mark>")
+			b.WriteString(html.EscapeString(run.text))
+			b.WriteString("</mark>")
+			continue
+		}
+		b.WriteString(html.EscapeString(run.text))
+	}
+	return b.String()
+}
+
+// highlight escapes the whole text and marks matching words
+func (h *searchHighlighter) highlight(text string) string {
+	return h.render(splitTextRuns(text))
+}
+
+// snippet returns a window of about 30 words around the first match, or the
+// opening words when the text has no match
+func (h *searchHighlighter) snippet(text string) string {
+	const before, size = 8, 30
+
+	runs := splitTextRuns(text)
+	var words []int
+	first := -1
+	for i, run := range runs {
+		if !run.word {
+			continue
+		}
+		if first < 0 && h.matches(run.text) {
+			first = len(words)
+		}
+		words = append(words, i)
+	}
+	if len(words) == 0 {
+		return ""
+	}
+
+	from := 0
+	if first > before {
+		from = first - before
+	}
+	to := from + size - 1
+	if to > len(words)-1 {
+		to = len(words) - 1
+	}
+
+	end := len(runs)
+	if to < len(words)-1 {
+		end = words[to] + 1
+	}
+	snippet := strings.TrimSpace(h.render(runs[words[from]:end]))
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runs) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// editDistance is the optimal string alignment distance between two words,
+// counting a transposition of adjacent letters as one edit. It gives up and
+// returns max+1 as soon as the distance must exceed max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
@@ -1,315 +1,347 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Session represents a user session
+// Session errors returned by UserService
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+const defaultRefreshTokenTTL = 7 * 24 * time.Hour
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout        = "logout"
+	SessionRevokedLogoutAll     = "logout_all"
+	SessionRevokedByUser        = "revoked_by_user"
+	SessionRevokedTokenReuse    = "refresh_token_reuse"
+	SessionRevokedPasswordReset = "password_reset"
+)
+
+// Session represents a device login. All refresh tokens issued for a session
+// belong to the same rotation family.
 type Session struct {
-	ID           string    `json:"id"`
-	UserID       string    `json:"user_id"`
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
-	DeviceInfo   string    `json:"device_info,omitempty"`
-	IPAddress    string    `json:"ip_address,omitempty"`
-	UserAgent    string    `json:"user_agent,omitempty"`
-	IsActive     bool      `json:"is_active"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	CreatedAt    time.Time `json:"created_at"`
-	LastUsedAt   time.Time `json:"last_used_at"`
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	DeviceInfo    string     `json:"device_info,omitempty"`
+	IPAddress     string     `json:"ip_address,omitempty"`
+	UserAgent     string     `json:"user_agent,omitempty"`
+	IsActive      bool       `json:"is_active"`
+	TwoFactor     bool       `json:"two_factor_verified"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
 }
 
-// SessionData represents the structure of sessions.json
-type SessionData struct {
-	Sessions []Session `json:"sessions"`
-	Metadata Metadata  `json:"metadata"`
+// SessionClient describes the device a session is created for
+type SessionClient struct {
+	DeviceInfo string
+	IPAddress  string
+	UserAgent  string
+	// TwoFactor records that the login completed a second factor
+	TwoFactor bool
 }
 
-const sessionsFile = "backend/data/sessions.json"
+const sessionColumns = `id, user_id, device_info, ip_address, user_agent, is_active, two_factor_verified,
+	revoked_at, revoked_reason, expires_at, created_at, last_used_at`
+
+// SetRefreshTokenTTL configures how long refresh tokens and idle sessions stay valid
+func (s *UserService) SetRefreshTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.refreshTokenTTL = ttl
+	}
+}
 
-// CreateSession creates a new session
-func CreateSession(userID, accessToken, refreshToken, deviceInfo, ipAddress, userAgent string) (*Session, error) {
+// CreateSession starts a new device session and returns its first refresh token
+func (s *UserService) CreateSession(ctx context.Context, userID string, client SessionClient) (*Session, string, error) {
+	now := time.Now().UTC()
 	session := &Session{
-		ID:           uuid.New().String(),
-		UserID:       userID,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		DeviceInfo:   deviceInfo,
-		IPAddress:    ipAddress,
-		UserAgent:    userAgent,
-		IsActive:     true,
-		ExpiresAt:    time.Now().Add(24 * time.Hour), // 24 hours for refresh token
-		CreatedAt:    time.Now(),
-		LastUsedAt:   time.Now(),
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		DeviceInfo: client.DeviceInfo,
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+		IsActive:   true,
+		TwoFactor:  client.TwoFactor,
+		ExpiresAt:  now.Add(s.refreshTokenTTL),
+		CreatedAt:  now,
+		LastUsedAt: now,
 	}
 
-	err := AppendJSON(sessionsFile, session)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_sessions (id, user_id, device_info, ip_address, user_agent, two_factor_verified,
+			expires_at, created_at, last_used_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.ExecContext(ctx, query, session.ID, session.UserID, session.DeviceInfo, session.IPAddress,
+		session.UserAgent, session.TwoFactor, session.ExpiresAt, session.CreatedAt, session.LastUsedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create session: %w", err)
 	}
 
-	return session, nil
-}
-
-// GetSessionByRefreshToken retrieves a session by refresh token
-func GetSessionByRefreshToken(refreshToken string) (*Session, error) {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+	refreshToken, err := s.insertRefreshToken(ctx, tx, session.ID, session.ExpiresAt)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	for _, session := range data.Sessions {
-		if session.RefreshToken == refreshToken && session.IsActive {
-			// Check if session is not expired
-			if time.Now().Before(session.ExpiresAt) {
-				return &session, nil
-			}
-		}
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("failed to commit session: %w", err)
 	}
 
-	return nil, fmt.Errorf("session not found or expired")
+	return session, refreshToken, nil
 }
 
-// GetSessionByAccessToken retrieves a session by access token
-func GetSessionByAccessToken(accessToken string) (*Session, error) {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+// RotateRefreshToken exchanges a refresh token for a new one. Presenting a
+// token that was already rotated revokes the whole session.
+func (s *UserService) RotateRefreshToken(ctx context.Context, refreshToken string) (*Session, string, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", fmt.Errorf("failed to begin transaction: %w", err)
 	}
-
-	for _, session := range data.Sessions {
-		if session.AccessToken == accessToken && session.IsActive {
-			// Check if session is not expired
-			if time.Now().Before(session.ExpiresAt) {
-				return &session, nil
-			}
+	defer tx.Rollback()
+
+	var (
+		tokenID, sessionID string
+		tokenExpiresAt     time.Time
+		rotatedAt          sql.NullTime
+	)
+	query := `SELECT id, session_id, expires_at, rotated_at FROM session_refresh_tokens WHERE token_hash = ?`
+	err = tx.QueryRowContext(ctx, query, hashToken(refreshToken)).Scan(&tokenID, &sessionID, &tokenExpiresAt, &rotatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", ErrInvalidRefreshToken
 		}
+		return nil, "", fmt.Errorf("failed to look up refresh token: %w", err)
 	}
 
-	return nil, fmt.Errorf("session not found or expired")
-}
-
-// GetUserSessions retrieves all active sessions for a user
-func GetUserSessions(userID string) ([]Session, error) {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+	session, err := scanSession(tx.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM user_sessions WHERE id = ?`, sessionID))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-
-	var userSessions []Session
-	for _, session := range data.Sessions {
-		if session.UserID == userID && session.IsActive {
-			// Only return non-expired sessions
-			if time.Now().Before(session.ExpiresAt) {
-				userSessions = append(userSessions, session)
-			}
-		}
+	if !session.IsActive || !now.Before(session.ExpiresAt) || !now.Before(tokenExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
 	}
 
-	return userSessions, nil
-}
-
-// UpdateSessionTokens updates the access and refresh tokens for a session
-func UpdateSessionTokens(sessionID, newAccessToken, newRefreshToken string) error {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+	// Marking the token as rotated only succeeds once, so concurrent or replayed
+	// use of the same token is treated as reuse
+	result, err := tx.ExecContext(ctx,
+		`UPDATE session_refresh_tokens SET rotated_at = ? WHERE id = ? AND rotated_at IS NULL`, now, tokenID)
 	if err != nil {
-		return err
+		return nil, "", fmt.Errorf("failed to rotate refresh token: %w", err)
 	}
-
-	for i, session := range data.Sessions {
-		if session.ID == sessionID {
-			data.Sessions[i].AccessToken = newAccessToken
-			data.Sessions[i].RefreshToken = newRefreshToken
-			data.Sessions[i].LastUsedAt = time.Now()
-			data.Sessions[i].ExpiresAt = time.Now().Add(24 * time.Hour) // Extend expiry
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(sessionsFile, data)
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if rotatedAt.Valid || rows == 0 {
+		if err := revokeSessions(ctx, tx, `id = ?`, []interface{}{sessionID}, SessionRevokedTokenReuse, now); err != nil {
+			return nil, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, "", fmt.Errorf("failed to revoke session: %w", err)
 		}
+		return nil, "", ErrRefreshTokenReused
 	}
 
-	return fmt.Errorf("session not found")
-}
-
-// UpdateSessionLastUsed updates the last used timestamp for a session
-func UpdateSessionLastUsed(accessToken string) error {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+	session.ExpiresAt = now.Add(s.refreshTokenTTL)
+	session.LastUsedAt = now
+	_, err = tx.ExecContext(ctx, `UPDATE user_sessions SET expires_at = ?, last_used_at = ? WHERE id = ?`,
+		session.ExpiresAt, session.LastUsedAt, session.ID)
 	if err != nil {
-		return err
+		return nil, "", fmt.Errorf("failed to update session: %w", err)
 	}
 
-	for i, session := range data.Sessions {
-		if session.AccessToken == accessToken && session.IsActive {
-			data.Sessions[i].LastUsedAt = time.Now()
-			data.Metadata.UpdatedAt = time.Now()
+	newToken, err := s.insertRefreshToken(ctx, tx, session.ID, session.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
 
-			return WriteJSON(sessionsFile, data)
-		}
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("failed to commit refresh token rotation: %w", err)
 	}
 
-	return fmt.Errorf("session not found")
+	return session, newToken, nil
 }
 
-// InvalidateSession invalidates a specific session
-func InvalidateSession(sessionID string) error {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
-	if err != nil {
-		return err
+// IsSessionActive reports whether the session exists, is not revoked and has not expired
+func (s *UserService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM user_sessions WHERE id = ? AND is_active = 1 AND expires_at > ?`
+	if err := s.db.QueryRowContext(ctx, query, sessionID, time.Now().UTC()).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
 	}
+	return count > 0, nil
+}
 
-	for i, session := range data.Sessions {
-		if session.ID == sessionID {
-			data.Sessions[i].IsActive = false
-			data.Sessions[i].LastUsedAt = time.Now()
-			data.Metadata.UpdatedAt = time.Now()
+// GetUserSessions retrieves all active sessions for a user
+func (s *UserService) GetUserSessions(ctx context.Context, userID string) ([]Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM user_sessions
+		WHERE user_id = ? AND is_active = 1 AND expires_at > ?
+		ORDER BY last_used_at DESC
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
 
-			return WriteJSON(sessionsFile, data)
+	sessions := []Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
 		}
+		sessions = append(sessions, *session)
 	}
 
-	return fmt.Errorf("session not found")
+	return sessions, rows.Err()
 }
 
-// InvalidateSessionByToken invalidates a session by access token
-func InvalidateSessionByToken(accessToken string) error {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+// RevokeSession revokes one of the user's sessions
+func (s *UserService) RevokeSession(ctx context.Context, userID, sessionID, reason string) error {
+	query := `
+		UPDATE user_sessions SET is_active = 0, revoked_at = ?, revoked_reason = ?
+		WHERE id = ? AND user_id = ? AND is_active = 1
+	`
+	result, err := s.db.ExecContext(ctx, query, time.Now().UTC(), reason, sessionID, userID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to revoke session: %w", err)
 	}
 
-	for i, session := range data.Sessions {
-		if session.AccessToken == accessToken {
-			data.Sessions[i].IsActive = false
-			data.Sessions[i].LastUsedAt = time.Now()
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(sessionsFile, data)
-		}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
 	}
 
-	return fmt.Errorf("session not found")
+	return nil
 }
 
-// InvalidateAllUserSessions invalidates all sessions for a user
-func InvalidateAllUserSessions(userID string) error {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
-	if err != nil {
-		return err
-	}
+// RevokeAllUserSessions revokes every active session of a user
+func (s *UserService) RevokeAllUserSessions(ctx context.Context, userID, reason string) error {
+	return revokeSessions(ctx, s.db, `user_id = ?`, []interface{}{userID}, reason, time.Now().UTC())
+}
 
-	updated := false
-	for i, session := range data.Sessions {
-		if session.UserID == userID && session.IsActive {
-			data.Sessions[i].IsActive = false
-			data.Sessions[i].LastUsedAt = time.Now()
-			updated = true
-		}
+// CleanupExpiredSessions deletes sessions that expired or were revoked more than a week ago
+func (s *UserService) CleanupExpiredSessions(ctx context.Context) error {
+	cutoff := time.Now().UTC().Add(-7 * 24 * time.Hour)
+	query := `DELETE FROM user_sessions WHERE expires_at < ? OR (is_active = 0 AND revoked_at < ?)`
+	if _, err := s.db.ExecContext(ctx, query, time.Now().UTC(), cutoff); err != nil {
+		return fmt.Errorf("failed to clean up sessions: %w", err)
 	}
+	return nil
+}
 
-	if updated {
-		data.Metadata.UpdatedAt = time.Now()
-		return WriteJSON(sessionsFile, data)
-	}
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
 
+func revokeSessions(ctx context.Context, db sqlExecer, where string, args []interface{}, reason string, now time.Time) error {
+	query := `UPDATE user_sessions SET is_active = 0, revoked_at = ?, revoked_reason = ? WHERE is_active = 1 AND ` + where
+	if _, err := db.ExecContext(ctx, query, append([]interface{}{now, reason}, args...)...); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
 	return nil
 }
 
-// CleanupExpiredSessions removes expired sessions from storage
-func CleanupExpiredSessions() error {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+func (s *UserService) insertRefreshToken(ctx context.Context, tx *sql.Tx, sessionID string, expiresAt time.Time) (string, error) {
+	token, err := generateOpaqueToken()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	now := time.Now()
-	var activeSessions []Session
-
-	for _, session := range data.Sessions {
-		// Keep sessions that are not expired or are inactive but recent
-		if session.IsActive && now.Before(session.ExpiresAt) {
-			activeSessions = append(activeSessions, session)
-		} else if !session.IsActive && now.Sub(session.LastUsedAt) < 7*24*time.Hour {
-			// Keep inactive sessions for 7 days for audit purposes
-			activeSessions = append(activeSessions, session)
-		}
+	query := `INSERT INTO session_refresh_tokens (id, session_id, token_hash, expires_at) VALUES (?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, query, uuid.New().String(), sessionID, hashToken(token), expiresAt); err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
-	// Only update if there were changes
-	if len(activeSessions) != len(data.Sessions) {
-		data.Sessions = activeSessions
-		data.Metadata.UpdatedAt = time.Now()
-		return WriteJSON(sessionsFile, data)
-	}
+	return token, nil
+}
 
-	return nil
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-// GetSessionStats returns session statistics
-func GetSessionStats() (map[string]interface{}, error) {
-	var data SessionData
-	err := ReadJSON(sessionsFile, &data)
+func scanSession(row rowScanner) (*Session, error) {
+	var (
+		session                                     Session
+		deviceInfo, ipAddress, userAgent, revReason sql.NullString
+		revokedAt                                   sql.NullTime
+	)
+
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&deviceInfo,
+		&ipAddress,
+		&userAgent,
+		&session.IsActive,
+		&session.TwoFactor,
+		&revokedAt,
+		&revReason,
+		&session.ExpiresAt,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+	)
 	if err != nil {
-		return nil, err
-	}
-
-	now := time.Now()
-	activeCount := 0
-	expiredCount := 0
-	inactiveCount := 0
-	uniqueUsers := make(map[string]bool)
-
-	for _, session := range data.Sessions {
-		uniqueUsers[session.UserID] = true
-
-		if session.IsActive {
-			if now.Before(session.ExpiresAt) {
-				activeCount++
-			} else {
-				expiredCount++
-			}
-		} else {
-			inactiveCount++
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
 		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	stats := map[string]interface{}{
-		"total_sessions":    len(data.Sessions),
-		"active_sessions":   activeCount,
-		"expired_sessions":  expiredCount,
-		"inactive_sessions": inactiveCount,
-		"unique_users":      len(uniqueUsers),
-		"last_cleanup":      data.Metadata.UpdatedAt,
+	session.DeviceInfo = deviceInfo.String
+	session.IPAddress = ipAddress.String
+	session.UserAgent = userAgent.String
+	session.RevokedReason = revReason.String
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
 	}
 
-	return stats, nil
+	return &session, nil
 }
 
-// StartSessionCleanup starts a background routine to clean up expired sessions
-func StartSessionCleanup() {
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour) // Run cleanup every hour
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				if err := CleanupExpiredSessions(); err != nil {
-					// Log error but don't stop the cleanup routine
-					fmt.Printf("Session cleanup error: %v\n", err)
-				}
-			}
-		}
-	}()
+// generateOpaqueToken returns a random URL-safe token with 256 bits of entropy
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the SHA-256 hex digest stored in place of a bearer token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
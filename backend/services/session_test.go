@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserService_RotateRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Email: "rotate@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	session, token, err := svc.CreateSession(ctx, user.ID, SessionClient{UserAgent: "test"})
+	require.NoError(t, err)
+	assert.True(t, session.IsActive)
+
+	rotated, nextToken, err := svc.RotateRefreshToken(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, rotated.ID)
+	assert.NotEqual(t, token, nextToken)
+
+	_, _, err = svc.RotateRefreshToken(ctx, "not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Replaying the old token revokes the whole family, including the newest token
+	_, _, err = svc.RotateRefreshToken(ctx, token)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	active, err := svc.IsSessionActive(ctx, session.ID)
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	_, _, err = svc.RotateRefreshToken(ctx, nextToken)
+	assert.Error(t, err)
+}
+
+func TestUserService_RevokeSessions(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Email: "revoke@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	first, _, err := svc.CreateSession(ctx, user.ID, SessionClient{DeviceInfo: "phone"})
+	require.NoError(t, err)
+	second, _, err := svc.CreateSession(ctx, user.ID, SessionClient{DeviceInfo: "laptop"})
+	require.NoError(t, err)
+
+	sessions, err := svc.GetUserSessions(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	// Users cannot revoke sessions they do not own
+	err = svc.RevokeSession(ctx, "someone-else", first.ID, SessionRevokedByUser)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	require.NoError(t, svc.RevokeSession(ctx, user.ID, first.ID, SessionRevokedByUser))
+	active, err := svc.IsSessionActive(ctx, first.ID)
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	require.NoError(t, svc.RevokeAllUserSessions(ctx, user.ID, SessionRevokedLogoutAll))
+	active, err = svc.IsSessionActive(ctx, second.ID)
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	sessions, err = svc.GetUserSessions(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/security"
+
+	"github.com/google/uuid"
+)
+
+// Two-factor authentication errors returned by UserService
+var (
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorNotPending       = errors.New("two-factor enrollment not started")
+	ErrInvalidTwoFactorCode      = errors.New("invalid two-factor code")
+	ErrInvalidTwoFactorChallenge = errors.New("invalid or expired two-factor challenge")
+)
+
+// TwoFactorChallengeTTL is how long a login may wait between password and second factor
+const TwoFactorChallengeTTL = 5 * time.Minute
+
+const (
+	totpIssuer           = "Nutrition Platform"
+	totpSkew             = 1
+	maxTwoFactorAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorEnrollment is returned when a user starts TOTP enrollment
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// BeginTwoFactorEnrollment generates a new TOTP secret for the user. The secret
+// only takes effect once ConfirmTwoFactorEnrollment verifies a code from it.
+func (s *UserService) BeginTwoFactorEnrollment(ctx context.Context, userID string) (*TwoFactorEnrollment, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE users SET totp_secret = ?, totp_last_step = 0, updated_at = ? WHERE id = ? AND totp_enabled = 0`
+	if _, err := s.db.ExecContext(ctx, query, secret, time.Now().UTC(), userID); err != nil {
+		return nil, fmt.Errorf("failed to store TOTP secret: %w", err)
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: secret,
+		URI:    security.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactorEnrollment enables 2FA after verifying a code from the
+// pending secret and returns a fresh set of recovery codes
+func (s *UserService) ConfirmTwoFactorEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	secret, enabled, _, err := loadTOTPState(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if secret == "" {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	step, ok := security.ValidateTOTP(secret, code, now, totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	query := `UPDATE users SET totp_enabled = 1, totp_last_step = ?, updated_at = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, step, now, userID); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit two-factor enrollment: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns off 2FA after verifying a TOTP or recovery code
+func (s *UserService) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := verifySecondFactor(ctx, tx, userID, code, now); err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET totp_secret = NULL, totp_enabled = 0, totp_last_step = 0, updated_at = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, now, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor change: %w", err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after verifying a
+// TOTP or recovery code
+func (s *UserService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := verifySecondFactor(ctx, tx, userID, code, now); err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// CreateTwoFactorChallenge issues the short-lived token that stands in for
+// the password during the second login step
+func (s *UserService) CreateTwoFactorChallenge(ctx context.Context, userID string) (string, error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO two_factor_challenges (id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err = s.db.ExecContext(ctx, query, uuid.New().String(), userID, hashToken(token), now.Add(TwoFactorChallengeTTL), now)
+	if err != nil {
+		return "", fmt.Errorf("failed to create two-factor challenge: %w", err)
+	}
+
+	return token, nil
+}
+
+// VerifyTwoFactorChallenge completes a login by checking a TOTP or recovery
+// code against a challenge. A challenge is burned after too many wrong codes,
+// and every wrong code counts toward the account lockout like a wrong
+// password, so opening new challenges does not allow more guesses.
+func (s *UserService) VerifyTwoFactorChallenge(ctx context.Context, challengeToken, code string) (*models.UserAccount, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		challengeID, userID string
+		attempts            int
+		expiresAt           time.Time
+		usedAt              sql.NullTime
+	)
+	query := `SELECT id, user_id, attempts, expires_at, used_at FROM two_factor_challenges WHERE token_hash = ?`
+	err = tx.QueryRowContext(ctx, query, hashToken(challengeToken)).Scan(&challengeID, &userID, &attempts, &expiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidTwoFactorChallenge
+		}
+		return nil, fmt.Errorf("failed to look up two-factor challenge: %w", err)
+	}
+	if usedAt.Valid || !now.Before(expiresAt) || attempts >= maxTwoFactorAttempts {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+
+	var lockedUntil sql.NullTime
+	if err := tx.QueryRowContext(ctx, `SELECT locked_until FROM users WHERE id = ?`, userID).Scan(&lockedUntil); err != nil {
+		return nil, fmt.Errorf("failed to check account lock: %w", err)
+	}
+	if lockedUntil.Valid && now.Before(lockedUntil.Time) {
+		return nil, ErrAccountLocked
+	}
+
+	if err := verifySecondFactor(ctx, tx, userID, code, now); err != nil {
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			return nil, err
+		}
+
+		query = `
+			UPDATE two_factor_challenges
+			SET attempts = attempts + 1, used_at = CASE WHEN attempts + 1 >= ? THEN ? ELSE used_at END
+			WHERE id = ?
+		`
+		if _, execErr := tx.ExecContext(ctx, query, maxTwoFactorAttempts, now, challengeID); execErr != nil {
+			return nil, fmt.Errorf("failed to record two-factor attempt: %w", execErr)
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			return nil, fmt.Errorf("failed to record two-factor attempt: %w", commitErr)
+		}
+
+		locked, recordErr := s.recordFailedLogin(ctx, userID, now)
+		if recordErr != nil {
+			return nil, recordErr
+		}
+		if locked {
+			return nil, ErrAccountLocked
+		}
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE two_factor_challenges SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, challengeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume two-factor challenge: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+
+	query = `
+		UPDATE users
+		SET failed_login_attempts = 0, locked_until = NULL, last_login_at = ?
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, now, userID); err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit two-factor challenge: %w", err)
+	}
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
+	}
+
+	return user, nil
+}
+
+func loadTOTPState(ctx context.Context, tx *sql.Tx, userID string) (string, bool, int64, error) {
+	var (
+		secret   sql.NullString
+		enabled  bool
+		lastStep int64
+	)
+	query := `SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE id = ?`
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&secret, &enabled, &lastStep); err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, 0, ErrUserNotFound
+		}
+		return "", false, 0, fmt.Errorf("failed to load two-factor settings: %w", err)
+	}
+	return secret.String, enabled, lastStep, nil
+}
+
+// verifySecondFactor accepts either a TOTP code newer than the last accepted
+// one or an unused recovery code, which is consumed
+func verifySecondFactor(ctx context.Context, tx *sql.Tx, userID, code string, now time.Time) error {
+	secret, enabled, lastStep, err := loadTOTPState(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := security.ValidateTOTP(secret, code, now, totpSkew); ok {
+		if step <= lastStep {
+			return ErrInvalidTwoFactorCode
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE users SET totp_last_step = ? WHERE id = ?`, step, userID)
+		if err != nil {
+			return fmt.Errorf("failed to record TOTP use: %w", err)
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	query := `UPDATE user_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, now, userID, hashToken(normalized))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if rows == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, now time.Time) ([]string, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	query := `INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at) VALUES (?, ?, ?, ?)`
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, query, uuid.New().String(), userID, hashToken(normalizeRecoveryCode(code)), now); err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code such as "k3m9q-x7p2d" (50 bits of entropy)
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
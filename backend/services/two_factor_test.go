@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/security"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func totpCodeAt(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := security.TOTPCode(secret, security.TOTPStep(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+func TestUserService_TwoFactorEnrollmentAndLogin(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Email: "mfa@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = svc.ConfirmTwoFactorEnrollment(ctx, user.ID, "123456")
+	assert.ErrorIs(t, err, ErrTwoFactorNotPending)
+
+	enrollment, err := svc.BeginTwoFactorEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	_, err = svc.ConfirmTwoFactorEnrollment(ctx, user.ID, "000000x")
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	codes, err := svc.ConfirmTwoFactorEnrollment(ctx, user.ID, totpCodeAt(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+
+	user, err = svc.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, user.TwoFactorEnabled)
+
+	// The code used for enrollment cannot be replayed to log in
+	challenge, err := svc.CreateTwoFactorChallenge(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, totpCodeAt(t, enrollment.Secret, 0))
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	verified, err := svc.VerifyTwoFactorChallenge(ctx, challenge, totpCodeAt(t, enrollment.Secret, 1))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, verified.ID)
+
+	// A challenge completes only one login
+	_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, codes[0])
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorChallenge)
+
+	// Recovery codes work once, with or without formatting
+	challenge, err = svc.CreateTwoFactorChallenge(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, " "+codes[0][:5]+codes[0][6:]+" ")
+	require.NoError(t, err)
+
+	challenge, err = svc.CreateTwoFactorChallenge(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, codes[0])
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	require.NoError(t, svc.DisableTwoFactor(ctx, user.ID, codes[1]))
+	user, err = svc.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, user.TwoFactorEnabled)
+}
+
+func TestUserService_TwoFactorChallengeAttempts(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	// Stay below the account lockout to see the challenge burned
+	svc.SetLockoutPolicy(maxTwoFactorAttempts+1, time.Hour)
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Email: "attempts@example.com", Password: "password123"})
+	require.NoError(t, err)
+	enrollment, err := svc.BeginTwoFactorEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.ConfirmTwoFactorEnrollment(ctx, user.ID, totpCodeAt(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+
+	challenge, err := svc.CreateTwoFactorChallenge(ctx, user.ID)
+	require.NoError(t, err)
+
+	for i := 0; i < maxTwoFactorAttempts; i++ {
+		_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, "wrong-code")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	}
+
+	// Once burned, even a valid code is refused
+	_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, totpCodeAt(t, enrollment.Secret, 1))
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorChallenge)
+}
+
+func TestUserService_TwoFactorFailuresLockAccount(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+	svc.SetLockoutPolicy(3, time.Hour)
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Email: "guess@example.com", Password: "password123"})
+	require.NoError(t, err)
+	enrollment, err := svc.BeginTwoFactorEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.ConfirmTwoFactorEnrollment(ctx, user.ID, totpCodeAt(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+	login := func() string {
+		t.Helper()
+		_, err := svc.Authenticate(ctx, "guess@example.com", "password123")
+		require.NoError(t, err)
+		challenge, err := svc.CreateTwoFactorChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		return challenge
+	}
+	failedAttempts := func() int {
+		t.Helper()
+		user, err := svc.GetUserByID(ctx, user.ID)
+		require.NoError(t, err)
+		return user.FailedLoginAttempts
+	}
+
+	// A verified second factor clears earlier failures
+	_, err = svc.VerifyTwoFactorChallenge(ctx, login(), "wrong-code")
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	assert.Equal(t, 1, failedAttempts())
+	_, err = svc.VerifyTwoFactorChallenge(ctx, login(), totpCodeAt(t, enrollment.Secret, 1))
+	require.NoError(t, err)
+	assert.Zero(t, failedAttempts())
+
+	// A new challenge per guess neither resets the counter nor avoids the lock
+	for i := 0; i < 2; i++ {
+		_, err = svc.VerifyTwoFactorChallenge(ctx, login(), "wrong-code")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	}
+	challenge := login()
+	_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, "wrong-code")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	// While locked neither the password nor an open challenge gets through
+	_, err = svc.Authenticate(ctx, "guess@example.com", "password123")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	_, err = svc.VerifyTwoFactorChallenge(ctx, challenge, totpCodeAt(t, enrollment.Secret, 2))
+	assert.ErrorIs(t, err, ErrAccountLocked)
+}
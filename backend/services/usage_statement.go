@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// UsageStatement summarizes one calendar month (UTC) of requests made with an
+// API key. Only successful requests are billable; rejected and failed
+// requests are listed but not charged.
+type UsageStatement struct {
+	APIKeyID           string             `json:"api_key_id"`
+	APIKeyName         string             `json:"api_key_name"`
+	UserID             string             `json:"user_id"`
+	Tier               models.APIKeyTier  `json:"tier"`
+	Quota              models.APIKeyQuota `json:"quota"`
+	Period             string             `json:"period"`
+	PeriodStart        time.Time          `json:"period_start"`
+	PeriodEnd          time.Time          `json:"period_end"`
+	TotalRequests      int64              `json:"total_requests"`
+	SuccessfulRequests int64              `json:"successful_requests"`
+	ErrorRequests      int64              `json:"error_requests"`
+	BillableRequests   int64              `json:"billable_requests"`
+	QuotaUsedPercent   float64            `json:"quota_used_percent"`
+	Days               []DailyStats       `json:"days"`
+	GeneratedAt        time.Time          `json:"generated_at"`
+}
+
+// GetMonthlyStatement builds the usage statement of apiKey for the month
+// containing month
+func (s *AnalyticsService) GetMonthlyStatement(apiKey *models.APIKey, month time.Time) (*UsageStatement, error) {
+	month = month.UTC()
+	periodStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0).Add(-time.Nanosecond)
+
+	report, err := s.GetUsageReportForPeriod(apiKey.ID, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &UsageStatement{
+		APIKeyID:    apiKey.ID,
+		APIKeyName:  apiKey.Name,
+		UserID:      apiKey.UserID,
+		Tier:        apiKey.Tier,
+		Quota:       apiKey.Quota(),
+		Period:      periodStart.Format("2006-01"),
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Days:        report.DailyStats,
+		GeneratedAt: time.Now().UTC(),
+	}
+	if statement.Days == nil {
+		statement.Days = []DailyStats{}
+	}
+
+	for _, day := range statement.Days {
+		statement.TotalRequests += day.TotalRequests
+		statement.SuccessfulRequests += day.SuccessRequests
+		statement.ErrorRequests += day.ErrorRequests
+	}
+	statement.BillableRequests = statement.SuccessfulRequests
+	if statement.Quota.RequestsPerMonth > 0 {
+		statement.QuotaUsedPercent = float64(statement.BillableRequests) / float64(statement.Quota.RequestsPerMonth) * 100
+	}
+
+	return statement, nil
+}
+
+// WriteCSV writes one row per day followed by a total row
+func (st *UsageStatement) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	records := [][]string{
+		{"api_key_id", "tier", "period", "date", "total_requests", "successful_requests", "error_requests", "avg_response_time_ms"},
+	}
+	for _, day := range st.Days {
+		records = append(records, []string{
+			st.APIKeyID,
+			string(st.Tier),
+			st.Period,
+			day.Date,
+			strconv.FormatInt(day.TotalRequests, 10),
+			strconv.FormatInt(day.SuccessRequests, 10),
+			strconv.FormatInt(day.ErrorRequests, 10),
+			strconv.FormatFloat(day.AvgResponseTime, 'f', 1, 64),
+		})
+	}
+	records = append(records, []string{
+		st.APIKeyID,
+		string(st.Tier),
+		st.Period,
+		"total",
+		strconv.FormatInt(st.TotalRequests, 10),
+		strconv.FormatInt(st.SuccessfulRequests, 10),
+		strconv.FormatInt(st.ErrorRequests, 10),
+		"",
+	})
+
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write usage statement: %w", err)
+	}
+	return nil
+}
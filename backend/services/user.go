@@ -1,55 +1,274 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Account errors returned by UserService
+var (
+	ErrEmailAlreadyRegistered = errors.New("email already registered")
+	ErrInvalidCredentials     = errors.New("invalid email or password")
+	ErrAccountLocked          = errors.New("account temporarily locked")
+	ErrAccountDisabled        = errors.New("account disabled")
+	ErrUserNotFound           = errors.New("user not found")
+)
+
+const (
+	defaultMaxLoginAttempts = 5
+	defaultLockoutDuration  = 15 * time.Minute
+)
+
+// dummyPasswordHash is compared against when the email is unknown so that
+// login timing does not reveal which accounts exist
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
 )
 
 // UserService handles user-related operations
 type UserService struct {
-	db *sql.DB
+	db               *sql.DB
+	maxLoginAttempts int
+	lockoutDuration  time.Duration
+	refreshTokenTTL  time.Duration
+	resetTokenTTL    time.Duration
+	mailer           Mailer
+	resetURL         string
 }
 
 // NewUserService creates a new UserService instance
 func NewUserService(db *sql.DB) *UserService {
 	return &UserService{
-		db: db,
+		db:               db,
+		maxLoginAttempts: defaultMaxLoginAttempts,
+		lockoutDuration:  defaultLockoutDuration,
+		refreshTokenTTL:  defaultRefreshTokenTTL,
+		resetTokenTTL:    defaultResetTokenTTL,
 	}
 }
 
-// Stub methods - to be implemented in Priority 2
-func (s *UserService) GetUserByID(userID string) (interface{}, error) {
-	return nil, nil
+// SetLockoutPolicy configures how many failed logins lock an account and for how long
+func (s *UserService) SetLockoutPolicy(maxAttempts int, duration time.Duration) {
+	if maxAttempts > 0 {
+		s.maxLoginAttempts = maxAttempts
+	}
+	if duration > 0 {
+		s.lockoutDuration = duration
+	}
 }
 
-func (s *UserService) GetUserByEmail(email string) (interface{}, error) {
-	return nil, nil
+// CreateUserInput holds the fields required to register a new account
+type CreateUserInput struct {
+	Email       string
+	Password    string
+	FirstName   string
+	LastName    string
+	DateOfBirth string
+	Gender      string
+	Language    string
 }
 
-func (s *UserService) CreateUser(email, passwordHash, firstName, lastName string) (interface{}, error) {
-	return nil, nil
+const userAccountColumns = `id, username, email, password_hash, first_name, last_name, date_of_birth,
+	gender, preferred_language, role, is_active, totp_enabled, failed_login_attempts, locked_until,
+	last_login_at, created_at, updated_at`
+
+// CreateUser registers a new account with a bcrypt-hashed password
+func (s *UserService) CreateUser(ctx context.Context, input CreateUserInput) (*models.UserAccount, error) {
+	email := normalizeEmail(input.Email)
+
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE email = ?", email).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing user: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrEmailAlreadyRegistered
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	language := input.Language
+	if language == "" {
+		language = "en"
+	}
+
+	id := uuid.New().String()
+	query := `
+		INSERT INTO users (id, username, email, password_hash, first_name, last_name, date_of_birth, gender, preferred_language)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = s.db.ExecContext(ctx, query, id, email, email, string(hash),
+		input.FirstName, input.LastName, input.DateOfBirth, input.Gender, language)
+	if err != nil {
+		// Another request may have registered the same email between the check and the insert
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrEmailAlreadyRegistered
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return s.GetUserByID(ctx, id)
 }
 
-// Session management stubs
-func (s *UserService) CreateSession(userID, accessToken, refreshToken, deviceInfo, ipAddress, userAgent string) (interface{}, error) {
-	return nil, nil
+// GetUserByID retrieves an account by ID
+func (s *UserService) GetUserByID(ctx context.Context, userID string) (*models.UserAccount, error) {
+	query := `SELECT ` + userAccountColumns + ` FROM users WHERE id = ?`
+	return s.scanUser(s.db.QueryRowContext(ctx, query, userID))
 }
 
-func (s *UserService) GetSessionByRefreshToken(refreshToken string) (interface{}, error) {
-	return nil, nil
+// GetUserByEmail retrieves an account by email
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.UserAccount, error) {
+	query := `SELECT ` + userAccountColumns + ` FROM users WHERE email = ?`
+	return s.scanUser(s.db.QueryRowContext(ctx, query, normalizeEmail(email)))
 }
 
-func (s *UserService) UpdateSessionTokens(sessionID string, accessToken, refreshToken string) error {
-	return nil
+// Authenticate verifies credentials and applies the lockout policy
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (*models.UserAccount, error) {
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			compareDummyPassword(password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
+	}
+	if user.IsLocked(now) {
+		return nil, ErrAccountLocked
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		locked, recordErr := s.recordFailedLogin(ctx, user.ID, now)
+		if recordErr != nil {
+			return nil, recordErr
+		}
+		if locked {
+			return nil, ErrAccountLocked
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// With two-factor authentication the login completes, and the failure
+	// counter is cleared, only once the second factor is verified
+	if user.TwoFactorEnabled {
+		return user, nil
+	}
+
+	query := `
+		UPDATE users
+		SET failed_login_attempts = 0, locked_until = NULL, last_login_at = ?
+		WHERE id = ?
+	`
+	if _, err := s.db.ExecContext(ctx, query, now, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	user.LastLoginAt = &now
+
+	return user, nil
 }
 
-func (s *UserService) InvalidateSessionByToken(accessToken string) error {
-	return nil
+// recordFailedLogin increments the failure counter and locks the account once
+// the configured threshold is reached
+func (s *UserService) recordFailedLogin(ctx context.Context, userID string, now time.Time) (bool, error) {
+	var attempts int
+	query := `
+		UPDATE users SET failed_login_attempts = failed_login_attempts + 1
+		WHERE id = ?
+		RETURNING failed_login_attempts
+	`
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&attempts); err != nil {
+		return false, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	if attempts < s.maxLoginAttempts {
+		return false, nil
+	}
+
+	lockedUntil := now.Add(s.lockoutDuration)
+	query = `UPDATE users SET failed_login_attempts = 0, locked_until = ? WHERE id = ?`
+	if _, err := s.db.ExecContext(ctx, query, lockedUntil, userID); err != nil {
+		return false, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	return true, nil
+}
+
+func (s *UserService) scanUser(row *sql.Row) (*models.UserAccount, error) {
+	var (
+		user                                             models.UserAccount
+		firstName, lastName, dateOfBirth, gender, locale sql.NullString
+		lockedUntil, lastLoginAt                         sql.NullTime
+	)
+
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&firstName,
+		&lastName,
+		&dateOfBirth,
+		&gender,
+		&locale,
+		&user.Role,
+		&user.IsActive,
+		&user.TwoFactorEnabled,
+		&user.FailedLoginAttempts,
+		&lockedUntil,
+		&lastLoginAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.FirstName = firstName.String
+	user.LastName = lastName.String
+	user.DateOfBirth = dateOfBirth.String
+	user.Gender = gender.String
+	user.PreferredLanguage = locale.String
+	if lockedUntil.Valid {
+		user.LockedUntil = &lockedUntil.Time
+	}
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+
+	return &user, nil
 }
 
-func (s *UserService) InvalidateAllUserSessions(userID string) error {
-	return nil
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
-func (s *UserService) GetUserSessions(userID string) (interface{}, error) {
-	return nil, nil
+func compareDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("nutrition-platform-unknown-user"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
 }
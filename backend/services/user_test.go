@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB opens an in-memory SQLite database with the given migration files applied
+func openTestDB(t *testing.T, migrations ...string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// A single connection keeps every query on the same in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	for _, name := range migrations {
+		content, err := os.ReadFile(filepath.Join("..", "migrations", name))
+		require.NoError(t, err)
+		_, err = db.Exec(string(content))
+		require.NoError(t, err, "applying %s", name)
+	}
+
+	return db
+}
+
+func newTestUserService(t *testing.T) *UserService {
+	db := openTestDB(t, "001_initial_schema_sqlite.sql", "013_add_user_login_security.sql",
+		"014_create_user_sessions_table.sql", "015_create_password_reset_tokens_table.sql",
+		"016_add_two_factor_auth.sql", "017_create_rbac_tables.sql", "018_add_api_key_tiers.sql",
+		"019_create_food_diary.sql", "020_create_meal_plan_days.sql",
+		"021_add_generated_workout_programs.sql", "022_add_food_log_micronutrients.sql",
+		"023_create_water_intake.sql", "024_create_progress_photos.sql", "025_create_weight_goals.sql",
+		"026_create_personal_records.sql", "027_create_halal_preferences.sql", "028_create_user_allergies.sql",
+		"029_create_gdpr_tables.sql")
+	return NewUserService(db)
+}
+
+func TestUserService_CreateUser(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{
+		Email:     "  Jane@Example.com ",
+		Password:  "password123",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Gender:    "female",
+	})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, user.ID)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.Equal(t, "user", user.Role)
+	assert.Equal(t, "en", user.PreferredLanguage)
+	assert.True(t, user.IsActive)
+	assert.NotEqual(t, "password123", user.PasswordHash)
+
+	_, err = svc.CreateUser(ctx, CreateUserInput{Email: "jane@example.com", Password: "another123"})
+	assert.ErrorIs(t, err, ErrEmailAlreadyRegistered)
+}
+
+func TestUserService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+
+	_, err := svc.CreateUser(ctx, CreateUserInput{Email: "john@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	user, err := svc.Authenticate(ctx, "JOHN@example.com", "password123")
+	require.NoError(t, err)
+	assert.NotNil(t, user.LastLoginAt)
+
+	_, err = svc.Authenticate(ctx, "john@example.com", "wrong-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = svc.Authenticate(ctx, "nobody@example.com", "password123")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestUserService_AuthenticateLockout(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService(t)
+	svc.SetLockoutPolicy(3, time.Hour)
+
+	_, err := svc.CreateUser(ctx, CreateUserInput{Email: "locked@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = svc.Authenticate(ctx, "locked@example.com", "wrong-password")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	_, err = svc.Authenticate(ctx, "locked@example.com", "wrong-password")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	// The correct password is rejected while the lock is active
+	_, err = svc.Authenticate(ctx, "locked@example.com", "password123")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+
+	// Once the lock expires the account can log in again
+	_, err = svc.db.Exec("UPDATE users SET locked_until = ?", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	user, err := svc.Authenticate(ctx, "locked@example.com", "password123")
+	require.NoError(t, err)
+	assert.Nil(t, user.LockedUntil)
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Weight goal errors returned by WeightGoalService
+var (
+	ErrWeightGoalNotFound = errors.New("weight goal not found")
+	ErrInvalidWeightGoal  = errors.New("invalid weight goal")
+)
+
+// Default weekly paces for new goals, in kg per week
+const (
+	defaultWeeklyLoss = -0.5
+	defaultWeeklyGain = 0.25
+
+	// maintainTolerance is how close to the start weight a target counts as
+	// maintaining
+	maintainTolerance = 1.0
+
+	// trendWarmupDays of weigh-ins before a period are loaded so the trend
+	// has settled by the period's first day
+	trendWarmupDays = 60
+)
+
+const weightGoalColumns = `id, user_id, goal_type, start_weight, target_weight, weekly_goal, start_date,
+	target_date, activity_level, is_active, achieved_at, notes, created_at, updated_at`
+
+// WeightGoalService stores weight goals and analyses the weight trend
+// against them
+type WeightGoalService struct {
+	db *sql.DB
+}
+
+// NewWeightGoalService creates a new weight goal service
+func NewWeightGoalService(db *sql.DB) *WeightGoalService {
+	return &WeightGoalService{db: db}
+}
+
+// CreateGoal starts a new goal for userID, replacing the active one
+func (s *WeightGoalService) CreateGoal(ctx context.Context, userID string, req models.CreateWeightGoalRequest) (*models.WeightGoal, error) {
+	now := time.Now().UTC()
+	startWeight := req.StartWeight
+	if startWeight == 0 {
+		days, err := s.weighIns(ctx, userID, now.AddDate(0, 0, -trendWarmupDays))
+		if err != nil {
+			return nil, err
+		}
+		if len(days) == 0 {
+			return nil, fmt.Errorf("%w: start_weight is required until a weight has been logged", ErrInvalidWeightGoal)
+		}
+		trend := smoothTrend(days)
+		startWeight = round1(trend[len(trend)-1])
+	}
+
+	goal := &models.WeightGoal{
+		UserID:        userID,
+		StartWeight:   startWeight,
+		TargetWeight:  req.TargetWeight,
+		StartDate:     startOfDay(now),
+		WeeklyGoal:    req.WeeklyGoal,
+		ActivityLevel: req.ActivityLevel,
+		IsActive:      true,
+		Notes:         nonEmpty(&req.Notes),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	goal.GoalType = weightGoalType(startWeight, req.TargetWeight)
+	if goal.WeeklyGoal == 0 {
+		switch goal.GoalType {
+		case models.WeightGoalLose:
+			goal.WeeklyGoal = defaultWeeklyLoss
+		case models.WeightGoalGain:
+			goal.WeeklyGoal = defaultWeeklyGain
+		}
+	}
+	if req.TargetDate != "" {
+		targetDate, err := parseGoalDate(req.TargetDate, now)
+		if err != nil {
+			return nil, err
+		}
+		goal.TargetDate = &targetDate
+	}
+	if err := validateWeightGoal(goal); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE weight_goals SET is_active = 0, updated_at = ? WHERE user_id = ? AND is_active = 1`,
+		now, userID); err != nil {
+		return nil, fmt.Errorf("failed to close active weight goal: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `INSERT INTO weight_goals (user_id, goal_type, start_weight, target_weight, weekly_goal,
+		start_date, target_date, activity_level, is_active, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?)`,
+		userID, goal.GoalType, goal.StartWeight, goal.TargetWeight, goal.WeeklyGoal, goal.StartDate, goal.TargetDate,
+		goal.ActivityLevel, goal.Notes, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create weight goal: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create weight goal: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	goal.ID = uint(id)
+	goal.CurrentWeight = startWeight
+	return goal, nil
+}
+
+// ListGoals returns all of userID's goals, newest first
+func (s *WeightGoalService) ListGoals(ctx context.Context, userID string) ([]*models.WeightGoal, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+weightGoalColumns+` FROM weight_goals
+		WHERE user_id = ? ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list weight goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []*models.WeightGoal{}
+	for rows.Next() {
+		goal, err := scanWeightGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan weight goal: %w", err)
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+// GetGoal returns one of userID's goals
+func (s *WeightGoalService) GetGoal(ctx context.Context, userID string, id uint) (*models.WeightGoal, error) {
+	return s.getWeightGoal(ctx, `id = ? AND user_id = ?`, id, userID)
+}
+
+// GetActiveGoal returns userID's active goal
+func (s *WeightGoalService) GetActiveGoal(ctx context.Context, userID string) (*models.WeightGoal, error) {
+	return s.getWeightGoal(ctx, `user_id = ? AND is_active = 1`, userID)
+}
+
+// UpdateGoal changes the target, pace, date or notes of a goal. Changing the
+// target re-derives the goal type from the start weight.
+func (s *WeightGoalService) UpdateGoal(ctx context.Context, userID string, id uint, req models.UpdateWeightGoalRequest) (*models.WeightGoal, error) {
+	goal, err := s.GetGoal(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if req.TargetWeight != nil {
+		goal.TargetWeight = *req.TargetWeight
+		goal.GoalType = weightGoalType(goal.StartWeight, goal.TargetWeight)
+		goal.AchievedAt = nil
+	}
+	if req.WeeklyGoal != nil {
+		goal.WeeklyGoal = *req.WeeklyGoal
+	}
+	if req.TargetDate != nil {
+		goal.TargetDate = nil
+		if *req.TargetDate != "" {
+			targetDate, err := parseGoalDate(*req.TargetDate, now)
+			if err != nil {
+				return nil, err
+			}
+			goal.TargetDate = &targetDate
+		}
+	}
+	if req.ActivityLevel != nil {
+		goal.ActivityLevel = *req.ActivityLevel
+	}
+	if req.Notes != nil {
+		goal.Notes = nonEmpty(req.Notes)
+	}
+	if err := validateWeightGoal(goal); err != nil {
+		return nil, err
+	}
+
+	goal.UpdatedAt = now
+	_, err = s.db.ExecContext(ctx, `UPDATE weight_goals SET goal_type = ?, target_weight = ?, weekly_goal = ?, target_date = ?,
+		activity_level = ?, achieved_at = ?, notes = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+		goal.GoalType, goal.TargetWeight, goal.WeeklyGoal, goal.TargetDate, goal.ActivityLevel, goal.AchievedAt,
+		goal.Notes, goal.UpdatedAt, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update weight goal: %w", err)
+	}
+	return goal, nil
+}
+
+// DeleteGoal removes one of userID's goals
+func (s *WeightGoalService) DeleteGoal(ctx context.Context, userID string, id uint) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM weight_goals WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete weight goal: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrWeightGoalNotFound
+	}
+	return nil
+}
+
+// RecordAchievement stamps the active goal as achieved the first time the
+// trend weight reaches its target
+func (s *WeightGoalService) RecordAchievement(ctx context.Context, userID string) error {
+	analysis, err := s.Analyze(ctx, userID, rateWindowDays)
+	if err != nil {
+		return err
+	}
+	if analysis.Goal == nil || analysis.Goal.AchievedAt != nil || analysis.Prediction == nil || !analysis.Prediction.Achieved {
+		return nil
+	}
+	now := time.Now().UTC()
+	_, err = s.db.ExecContext(ctx, `UPDATE weight_goals SET achieved_at = ?, updated_at = ? WHERE id = ? AND achieved_at IS NULL`,
+		now, now, analysis.Goal.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record weight goal achievement: %w", err)
+	}
+	return nil
+}
+
+// Analyze smooths userID's weigh-ins of the last days into a trend, fits the
+// weekly rate, checks for a plateau and projects the active goal
+func (s *WeightGoalService) Analyze(ctx context.Context, userID string, days int) (*models.WeightTrendAnalysis, error) {
+	now := time.Now().UTC()
+	periodStart := startOfDay(now).AddDate(0, 0, -days)
+	loadFrom := periodStart
+	if days < rateWindowDays {
+		loadFrom = startOfDay(now).AddDate(0, 0, -rateWindowDays)
+	}
+	weighIns, err := s.weighIns(ctx, userID, loadFrom.AddDate(0, 0, -trendWarmupDays))
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &models.WeightTrendAnalysis{Points: []models.WeightTrendPoint{}}
+	goal, err := s.GetActiveGoal(ctx, userID)
+	if err != nil && !errors.Is(err, ErrWeightGoalNotFound) {
+		return nil, err
+	}
+	analysis.Goal = goal
+	if len(weighIns) == 0 {
+		return analysis, nil
+	}
+
+	trend := smoothTrend(weighIns)
+	for i, d := range weighIns {
+		if d.day.Before(periodStart) {
+			continue
+		}
+		analysis.WeighInsCount++
+		analysis.Points = append(analysis.Points, models.WeightTrendPoint{
+			Date:   d.day,
+			Weight: round1(d.weight),
+			Value:  round1(trend[i]),
+		})
+	}
+	latest := round1(weighIns[len(weighIns)-1].weight)
+	trendWeight := round1(trend[len(trend)-1])
+	analysis.LatestWeight, analysis.TrendWeight = &latest, &trendWeight
+
+	rate, stderr, ok := weeklyRate(weighIns, rateWindowDays, now)
+	if ok {
+		weekly := round2(rate)
+		analysis.WeeklyRate = &weekly
+	}
+	if goal == nil || goal.GoalType != models.WeightGoalMaintain {
+		analysis.Plateau = detectPlateau(weighIns, trend, now)
+	}
+	if goal != nil {
+		goal.CurrentWeight = trendWeight
+		analysis.Prediction = predictGoal(goal, trend[len(trend)-1], rate, stderr, now)
+		if !ok && !analysis.Prediction.Achieved && goal.GoalType != models.WeightGoalMaintain {
+			analysis.Prediction.Recommendations = []string{
+				fmt.Sprintf("Weigh in over at least %d days to project a goal date", minRateSpanDays),
+			}
+		}
+	}
+	return analysis, nil
+}
+
+// weighIns returns userID's daily average weights since from, oldest first
+func (s *WeightGoalService) weighIns(ctx context.Context, userID string, from time.Time) ([]weighIn, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT measurement_date, weight FROM body_measurements
+		WHERE user_id = ? AND weight IS NOT NULL AND measurement_date >= ?
+		ORDER BY measurement_date ASC`, userID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load weigh-ins: %w", err)
+	}
+	defer rows.Close()
+
+	var days []weighIn
+	var count int
+	for rows.Next() {
+		var date time.Time
+		var weight float64
+		if err := rows.Scan(&date, &weight); err != nil {
+			return nil, fmt.Errorf("failed to scan weigh-in: %w", err)
+		}
+		day := startOfDay(date)
+		if len(days) > 0 && days[len(days)-1].day.Equal(day) {
+			last := &days[len(days)-1]
+			count++
+			last.weight += (weight - last.weight) / float64(count)
+			continue
+		}
+		days = append(days, weighIn{day: day, weight: weight})
+		count = 1
+	}
+	return days, rows.Err()
+}
+
+func (s *WeightGoalService) getWeightGoal(ctx context.Context, where string, args ...interface{}) (*models.WeightGoal, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+weightGoalColumns+` FROM weight_goals WHERE `+where, args...)
+	goal, err := scanWeightGoal(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWeightGoalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weight goal: %w", err)
+	}
+	return goal, nil
+}
+
+func weightGoalType(startWeight, targetWeight float64) string {
+	switch diff := targetWeight - startWeight; {
+	case math.Abs(diff) < maintainTolerance:
+		return models.WeightGoalMaintain
+	case diff < 0:
+		return models.WeightGoalLose
+	default:
+		return models.WeightGoalGain
+	}
+}
+
+func parseGoalDate(value string, now time.Time) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: target_date must be formatted as YYYY-MM-DD", ErrInvalidWeightGoal)
+	}
+	if !date.After(startOfDay(now)) {
+		return time.Time{}, fmt.Errorf("%w: target_date must be in the future", ErrInvalidWeightGoal)
+	}
+	return date, nil
+}
+
+func validateWeightGoal(goal *models.WeightGoal) error {
+	if err := goal.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWeightGoal, err)
+	}
+	switch {
+	case goal.GoalType == models.WeightGoalLose && goal.WeeklyGoal > 0:
+		return fmt.Errorf("%w: weekly_goal must be negative when losing weight", ErrInvalidWeightGoal)
+	case goal.GoalType == models.WeightGoalGain && goal.WeeklyGoal < 0:
+		return fmt.Errorf("%w: weekly_goal must be positive when gaining weight", ErrInvalidWeightGoal)
+	}
+	return nil
+}
+
+func scanWeightGoal(row rowScanner) (*models.WeightGoal, error) {
+	goal := &models.WeightGoal{}
+	var targetDate, achievedAt sql.NullTime
+	var activityLevel, notes sql.NullString
+	if err := row.Scan(&goal.ID, &goal.UserID, &goal.GoalType, &goal.StartWeight, &goal.TargetWeight, &goal.WeeklyGoal,
+		&goal.StartDate, &targetDate, &activityLevel, &goal.IsActive, &achievedAt, &notes,
+		&goal.CreatedAt, &goal.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if targetDate.Valid {
+		goal.TargetDate = &targetDate.Time
+	}
+	if achievedAt.Valid {
+		goal.AchievedAt = &achievedAt.Time
+	}
+	goal.ActivityLevel = activityLevel.String
+	if notes.Valid {
+		goal.Notes = &notes.String
+	}
+	return goal, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWeightGoalService(t *testing.T) (*WeightGoalService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "goals@example.com", Password: "password123"})
+	require.NoError(t, err)
+	return NewWeightGoalService(users.db), user.ID
+}
+
+// logWeights stores one weigh-in per day ending today
+func logWeights(t *testing.T, db *sql.DB, userID string, weights ...float64) {
+	t.Helper()
+	today := startOfDay(time.Now())
+	for i, weight := range weights {
+		_, err := db.Exec(`INSERT INTO body_measurements (user_id, measurement_date, weight) VALUES (?, ?, ?)`,
+			userID, today.AddDate(0, 0, i-len(weights)+1).Add(7*time.Hour), weight)
+		require.NoError(t, err)
+	}
+}
+
+func TestWeightGoalService_Goals(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestWeightGoalService(t)
+
+	_, err := svc.CreateGoal(ctx, userID, models.CreateWeightGoalRequest{TargetWeight: 75})
+	assert.ErrorIs(t, err, ErrInvalidWeightGoal, "start weight is needed before any weigh-in")
+
+	first, err := svc.CreateGoal(ctx, userID, models.CreateWeightGoalRequest{TargetWeight: 75, StartWeight: 85, Notes: " summer "})
+	require.NoError(t, err)
+	assert.Equal(t, models.WeightGoalLose, first.GoalType)
+	assert.Equal(t, defaultWeeklyLoss, first.WeeklyGoal)
+	require.NotNil(t, first.Notes)
+	assert.Equal(t, "summer", *first.Notes)
+
+	second, err := svc.CreateGoal(ctx, userID, models.CreateWeightGoalRequest{
+		TargetWeight: 90,
+		StartWeight:  85,
+		TargetDate:   time.Now().AddDate(0, 6, 0).Format("2006-01-02"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.WeightGoalGain, second.GoalType)
+	assert.Equal(t, defaultWeeklyGain, second.WeeklyGoal)
+	require.NotNil(t, second.TargetDate)
+
+	goals, err := svc.ListGoals(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, goals, 2)
+	assert.Equal(t, second.ID, goals[0].ID)
+	assert.True(t, goals[0].IsActive)
+	assert.False(t, goals[1].IsActive, "a new goal closes the active one")
+
+	active, err := svc.GetActiveGoal(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, active.ID)
+
+	target := 85.5
+	empty := ""
+	updated, err := svc.UpdateGoal(ctx, userID, second.ID, models.UpdateWeightGoalRequest{TargetWeight: &target, TargetDate: &empty})
+	require.NoError(t, err)
+	assert.Equal(t, models.WeightGoalMaintain, updated.GoalType)
+	assert.Nil(t, updated.TargetDate)
+
+	target = 80
+	wrongWay := 0.5
+	_, err = svc.UpdateGoal(ctx, userID, second.ID, models.UpdateWeightGoalRequest{TargetWeight: &target, WeeklyGoal: &wrongWay})
+	assert.ErrorIs(t, err, ErrInvalidWeightGoal)
+	past := "2020-01-01"
+	_, err = svc.UpdateGoal(ctx, userID, second.ID, models.UpdateWeightGoalRequest{TargetDate: &past})
+	assert.ErrorIs(t, err, ErrInvalidWeightGoal)
+
+	_, err = svc.GetGoal(ctx, "someone-else", second.ID)
+	assert.ErrorIs(t, err, ErrWeightGoalNotFound)
+	require.NoError(t, svc.DeleteGoal(ctx, userID, first.ID))
+	assert.ErrorIs(t, svc.DeleteGoal(ctx, userID, first.ID), ErrWeightGoalNotFound)
+}
+
+func TestWeightGoalService_Analyze(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestWeightGoalService(t)
+
+	analysis, err := svc.Analyze(ctx, userID, 30)
+	require.NoError(t, err)
+	assert.Empty(t, analysis.Points)
+	assert.Nil(t, analysis.TrendWeight)
+	assert.Nil(t, analysis.Goal)
+
+	weights := make([]float64, 35)
+	for i := range weights {
+		weights[i] = 90 - 0.1*float64(i)
+	}
+	logWeights(t, svc.db, userID, weights...)
+	// A second weigh-in today is averaged with the first
+	_, err = svc.db.Exec(`INSERT INTO body_measurements (user_id, measurement_date, weight) VALUES (?, ?, ?)`,
+		userID, startOfDay(time.Now()).Add(8*time.Hour), weights[34]-0.2)
+	require.NoError(t, err)
+
+	goal, err := svc.CreateGoal(ctx, userID, models.CreateWeightGoalRequest{TargetWeight: 80})
+	require.NoError(t, err)
+	assert.Equal(t, models.WeightGoalLose, goal.GoalType)
+	assert.Greater(t, goal.StartWeight, weights[34], "the trend lags behind a falling weight")
+
+	analysis, err = svc.Analyze(ctx, userID, 14)
+	require.NoError(t, err)
+	assert.Len(t, analysis.Points, 15)
+	assert.Equal(t, 15, analysis.WeighInsCount)
+	last := analysis.Points[len(analysis.Points)-1]
+	assert.Equal(t, round1(weights[34]-0.1), last.Weight)
+	require.NotNil(t, analysis.WeeklyRate)
+	assert.InDelta(t, -0.7, *analysis.WeeklyRate, 0.05)
+	require.NotNil(t, analysis.Plateau)
+	assert.False(t, analysis.Plateau.Detected)
+
+	require.NotNil(t, analysis.Goal)
+	assert.Equal(t, *analysis.TrendWeight, analysis.Goal.CurrentWeight)
+	require.NotNil(t, analysis.Prediction)
+	require.NotNil(t, analysis.Prediction.TargetDate)
+	assert.True(t, analysis.Prediction.TargetDate.After(time.Now()))
+	assert.Greater(t, analysis.Prediction.Confidence, 0.5)
+}
+
+func TestWeightGoalService_RecordAchievement(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestWeightGoalService(t)
+	logWeights(t, svc.db, userID, 86, 85.8, 85.5, 85.4, 85.1, 85, 84.8, 84.6)
+
+	goal, err := svc.CreateGoal(ctx, userID, models.CreateWeightGoalRequest{TargetWeight: 84, StartWeight: 92})
+	require.NoError(t, err)
+	require.NoError(t, svc.RecordAchievement(ctx, userID))
+	goal, err = svc.GetGoal(ctx, userID, goal.ID)
+	require.NoError(t, err)
+	assert.Nil(t, goal.AchievedAt, "the trend has not reached the target yet")
+
+	logWeights(t, svc.db, userID, 70)
+	_, err = svc.db.Exec(`UPDATE body_measurements SET weight = 80 WHERE user_id = ?`, userID)
+	require.NoError(t, err)
+	require.NoError(t, svc.RecordAchievement(ctx, userID))
+	goal, err = svc.GetGoal(ctx, userID, goal.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, goal.AchievedAt)
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Weight trend tuning. The trend weight is an exponentially weighted moving
+// average that moves trendSmoothing of the way towards each day's weigh-in,
+// the smoothing "true weight" apps use to hide day-to-day water swings.
+const (
+	trendSmoothing     = 0.1
+	rateWindowDays     = 28   // weigh-ins used for the weekly rate
+	minRateSpanDays    = 7    // shortest history a weekly rate is fitted to
+	plateauWindowDays  = 21   // history checked for a plateau
+	minPlateauDays     = 14   // shortest flat stretch reported as a plateau
+	minPlateauWeighIns = 4    // weigh-ins needed inside the plateau window
+	plateauMaxRate     = 0.1  // kg per week
+	plateauBand        = 0.5  // kg the trend may wander while on a plateau
+	stalledRate        = 0.05 // kg per week below which no goal date is projected
+	maxProjectionWeeks = 104
+	confidenceZ        = 1.96 // 95% confidence band
+)
+
+// weighIn is one day's average weight
+type weighIn struct {
+	day    time.Time
+	weight float64
+}
+
+// smoothTrend returns the trend weight after each day. A gap between
+// weigh-ins counts as one smoothing step per day, so a weigh-in after a week
+// away moves the trend further than one taken the next morning.
+func smoothTrend(days []weighIn) []float64 {
+	trend := make([]float64, len(days))
+	for i, d := range days {
+		if i == 0 {
+			trend[i] = d.weight
+			continue
+		}
+		gap := math.Max(1, d.day.Sub(days[i-1].day).Hours()/24)
+		alpha := 1 - math.Pow(1-trendSmoothing, gap)
+		trend[i] = trend[i-1] + alpha*(d.weight-trend[i-1])
+	}
+	return trend
+}
+
+// weeklyRate fits a least-squares line through the weigh-ins of the window
+// days up to now and returns its slope in kg per week together with the
+// slope's standard error. ok is false when those weigh-ins span less than
+// minRateSpanDays.
+func weeklyRate(days []weighIn, window int, now time.Time) (rate, stderr float64, ok bool) {
+	since := startOfDay(now).AddDate(0, 0, -window)
+	var xs, ys []float64
+	for _, d := range days {
+		if d.day.Before(since) {
+			continue
+		}
+		xs = append(xs, d.day.Sub(since).Hours()/24)
+		ys = append(ys, d.weight)
+	}
+	if len(xs) < 2 || xs[len(xs)-1]-xs[0] < minRateSpanDays {
+		return 0, 0, false
+	}
+
+	n := float64(len(xs))
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i] / n
+		meanY += ys[i] / n
+	}
+	var sxx, sxy float64
+	for i := range xs {
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+		sxy += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	slope := sxy / sxx
+
+	// Two points fit any line exactly, so their slope is no more certain
+	// than its own size
+	stderr = math.Abs(slope)
+	if len(xs) > 2 {
+		var residuals float64
+		for i := range xs {
+			r := ys[i] - (meanY + slope*(xs[i]-meanX))
+			residuals += r * r
+		}
+		stderr = math.Sqrt(residuals / (n - 2) / sxx)
+	}
+	return slope * 7, stderr * 7, true
+}
+
+// detectPlateau reports a plateau when the weekly rate over the last
+// plateauWindowDays is below plateauMaxRate. The plateau starts at the oldest
+// day from which the trend stayed within plateauBand of today's trend.
+func detectPlateau(days []weighIn, trend []float64, now time.Time) *models.WeightPlateau {
+	plateau := &models.WeightPlateau{}
+	since := startOfDay(now).AddDate(0, 0, -plateauWindowDays)
+	recent := 0
+	for _, d := range days {
+		if !d.day.Before(since) {
+			recent++
+		}
+	}
+	rate, _, ok := weeklyRate(days, plateauWindowDays, now)
+	if !ok || recent < minPlateauWeighIns {
+		return plateau
+	}
+	plateau.WeeklyRate = round2(rate)
+	if math.Abs(rate) >= plateauMaxRate {
+		return plateau
+	}
+
+	last := len(trend) - 1
+	start := last
+	for start > 0 && math.Abs(trend[start-1]-trend[last]) <= plateauBand {
+		start--
+	}
+	flatDays := int(startOfDay(now).Sub(days[start].day).Hours() / 24)
+	if flatDays < minPlateauDays {
+		return plateau
+	}
+	plateau.Detected = true
+	plateau.Since = &days[start].day
+	plateau.Days = flatDays
+	plateau.Message = fmt.Sprintf("Your trend weight has stayed within %.1f kg for %d days", plateauBand, flatDays)
+	return plateau
+}
+
+// predictGoal projects the trend weight to the goal at the current weekly
+// rate. The arrival window uses the rate's 95% confidence band, and
+// Confidence falls from 1 towards 0 as that band widens relative to the rate.
+func predictGoal(goal *models.WeightGoal, trendWeight, rate, stderr float64, now time.Time) *models.WeightGoalPrediction {
+	prediction := &models.WeightGoalPrediction{
+		CurrentWeight:   round1(trendWeight),
+		TargetWeight:    goal.TargetWeight,
+		WeeklyRate:      round2(rate),
+		PredictedWeight: []models.WeightTrendPoint{},
+		Recommendations: []string{},
+	}
+	remaining := goal.TargetWeight - trendWeight
+
+	switch {
+	case goal.GoalType == models.WeightGoalMaintain:
+		prediction.Achieved = math.Abs(remaining) <= 1
+		if !prediction.Achieved {
+			prediction.Recommendations = append(prediction.Recommendations,
+				fmt.Sprintf("Your trend weight is %.1f kg away from the weight you want to maintain", math.Abs(remaining)))
+		}
+		return prediction
+	case goal.GoalType == models.WeightGoalLose && remaining >= 0,
+		goal.GoalType == models.WeightGoalGain && remaining <= 0:
+		prediction.Achieved = true
+		prediction.Confidence = 1
+		return prediction
+	}
+
+	if math.Abs(rate) < stalledRate {
+		prediction.Recommendations = append(prediction.Recommendations,
+			"Your trend weight is not moving, so no goal date can be projected yet")
+		return prediction
+	}
+	if math.Signbit(rate) != math.Signbit(remaining) {
+		prediction.Recommendations = append(prediction.Recommendations,
+			"Your trend weight is moving away from your goal; review your calorie target")
+		return prediction
+	}
+
+	weeks := math.Min(remaining/rate, maxProjectionWeeks)
+	targetDate := now.Add(time.Duration(weeks * 7 * 24 * float64(time.Hour)))
+	prediction.TargetDate = &targetDate
+
+	fast := math.Abs(rate) + confidenceZ*stderr
+	earliest := now.Add(time.Duration(math.Abs(remaining) / fast * 7 * 24 * float64(time.Hour)))
+	prediction.EarliestDate = &earliest
+	if slow := math.Abs(rate) - confidenceZ*stderr; slow >= stalledRate {
+		latest := now.Add(time.Duration(math.Min(math.Abs(remaining)/slow, maxProjectionWeeks) * 7 * 24 * float64(time.Hour)))
+		prediction.LatestDate = &latest
+	}
+	prediction.Confidence = round2(math.Max(0, math.Min(1, 1-confidenceZ*stderr/math.Abs(rate))))
+
+	for week := 1; week <= int(math.Ceil(weeks)); week++ {
+		weight := trendWeight + rate*float64(week)
+		if (rate < 0 && weight < goal.TargetWeight) || (rate > 0 && weight > goal.TargetWeight) {
+			weight = goal.TargetWeight
+		}
+		predicted := round1(weight)
+		prediction.PredictedWeight = append(prediction.PredictedWeight, models.WeightTrendPoint{
+			Date:   now.AddDate(0, 0, 7*week),
+			Weight: predicted,
+			Value:  predicted,
+		})
+	}
+
+	if goal.TargetDate != nil {
+		onTrack := !targetDate.After(*goal.TargetDate)
+		prediction.OnTrack = &onTrack
+		if !onTrack {
+			prediction.Recommendations = append(prediction.Recommendations,
+				fmt.Sprintf("At %.2f kg per week you will reach your goal after your target date of %s",
+					math.Abs(rate), goal.TargetDate.Format("2 Jan 2006")))
+		}
+	}
+	if goal.GoalType == models.WeightGoalLose && -rate > 0.01*trendWeight {
+		prediction.Recommendations = append(prediction.Recommendations,
+			"You are losing more than 1% of your body weight a week; a smaller deficit protects muscle")
+	}
+	if goal.WeeklyGoal != 0 && math.Abs(rate) < math.Abs(goal.WeeklyGoal)/2 {
+		prediction.Recommendations = append(prediction.Recommendations,
+			fmt.Sprintf("You are progressing at less than half of your planned %.2f kg per week", math.Abs(goal.WeeklyGoal)))
+	}
+	return prediction
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dailySeries returns one weigh-in per day for the last len(weights) days,
+// ending today
+func dailySeries(now time.Time, weights ...float64) []weighIn {
+	today := startOfDay(now)
+	days := make([]weighIn, len(weights))
+	for i, weight := range weights {
+		days[i] = weighIn{day: today.AddDate(0, 0, i-len(weights)+1), weight: weight}
+	}
+	return days
+}
+
+func TestSmoothTrend(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	trend := smoothTrend([]weighIn{
+		{day: day, weight: 100},
+		{day: day.AddDate(0, 0, 1), weight: 90},
+		{day: day.AddDate(0, 0, 4), weight: 90},
+	})
+	require.Len(t, trend, 3)
+	assert.Equal(t, 100.0, trend[0])
+	assert.InDelta(t, 99, trend[1], 1e-9)
+	// A three day gap counts as three smoothing steps
+	assert.InDelta(t, 99-0.271*9, trend[2], 1e-9)
+}
+
+func TestWeeklyRate(t *testing.T) {
+	now := time.Now().UTC()
+	weights := make([]float64, 28)
+	for i := range weights {
+		weights[i] = 100 - 0.1*float64(i)
+	}
+	rate, stderr, ok := weeklyRate(dailySeries(now, weights...), rateWindowDays, now)
+	require.True(t, ok)
+	assert.InDelta(t, -0.7, rate, 1e-9)
+	assert.InDelta(t, 0, stderr, 1e-9)
+
+	_, _, ok = weeklyRate(dailySeries(now, 80, 79.5, 79), rateWindowDays, now)
+	assert.False(t, ok, "three days of weigh-ins are too short to fit a rate")
+
+	// Noise widens the standard error without moving the slope much
+	noisy := make([]float64, 28)
+	for i := range noisy {
+		noisy[i] = 100 - 0.1*float64(i)
+		if i%2 == 0 {
+			noisy[i] += 0.8
+		}
+	}
+	rate, stderr, ok = weeklyRate(dailySeries(now, noisy...), rateWindowDays, now)
+	require.True(t, ok)
+	assert.InDelta(t, -0.7, rate, 0.1)
+	assert.Greater(t, stderr, 0.05)
+}
+
+func TestDetectPlateau(t *testing.T) {
+	now := time.Now().UTC()
+
+	flat := make([]float64, 40)
+	for i := range flat {
+		flat[i] = 90 - 0.25*float64(min(i, 15))
+		if i%2 == 1 {
+			flat[i] += 0.3
+		}
+	}
+	days := dailySeries(now, flat...)
+	plateau := detectPlateau(days, smoothTrend(days), now)
+	assert.True(t, plateau.Detected)
+	require.NotNil(t, plateau.Since)
+	assert.GreaterOrEqual(t, plateau.Days, minPlateauDays)
+	assert.Less(t, plateau.Days, 39, "the plateau starts after the early loss")
+	assert.NotEmpty(t, plateau.Message)
+
+	falling := make([]float64, 40)
+	for i := range falling {
+		falling[i] = 90 - 0.1*float64(i)
+	}
+	days = dailySeries(now, falling...)
+	plateau = detectPlateau(days, smoothTrend(days), now)
+	assert.False(t, plateau.Detected)
+	assert.InDelta(t, -0.7, plateau.WeeklyRate, 0.01)
+
+	days = dailySeries(now, 80, 80, 80)
+	assert.False(t, detectPlateau(days, smoothTrend(days), now).Detected)
+}
+
+func TestPredictGoal(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	goal := &models.WeightGoal{GoalType: models.WeightGoalLose, StartWeight: 85, TargetWeight: 70, WeeklyGoal: -0.5}
+
+	prediction := predictGoal(goal, 80, -0.5, 0.05, now)
+	require.NotNil(t, prediction.TargetDate)
+	assert.Equal(t, now.AddDate(0, 0, 140), *prediction.TargetDate)
+	require.NotNil(t, prediction.EarliestDate)
+	require.NotNil(t, prediction.LatestDate)
+	assert.True(t, prediction.EarliestDate.Before(*prediction.TargetDate))
+	assert.True(t, prediction.LatestDate.After(*prediction.TargetDate))
+	assert.InDelta(t, 0.8, prediction.Confidence, 0.01)
+	require.Len(t, prediction.PredictedWeight, 20)
+	assert.Equal(t, 79.5, prediction.PredictedWeight[0].Weight)
+	assert.Equal(t, 70.0, prediction.PredictedWeight[19].Weight)
+	assert.Nil(t, prediction.OnTrack)
+	assert.False(t, prediction.Achieved)
+
+	// A band reaching zero leaves the latest date open
+	prediction = predictGoal(goal, 80, -0.5, 0.3, now)
+	assert.NotNil(t, prediction.TargetDate)
+	assert.Nil(t, prediction.LatestDate)
+	assert.Zero(t, prediction.Confidence)
+
+	deadline := now.AddDate(0, 0, 100)
+	goal.TargetDate = &deadline
+	prediction = predictGoal(goal, 80, -0.5, 0.05, now)
+	require.NotNil(t, prediction.OnTrack)
+	assert.False(t, *prediction.OnTrack)
+	assert.NotEmpty(t, prediction.Recommendations)
+
+	prediction = predictGoal(goal, 80, 0.3, 0.05, now)
+	assert.Nil(t, prediction.TargetDate)
+	assert.Contains(t, prediction.Recommendations[0], "moving away")
+
+	prediction = predictGoal(goal, 80, -0.01, 0.05, now)
+	assert.Nil(t, prediction.TargetDate)
+
+	prediction = predictGoal(goal, 69.8, -0.5, 0.05, now)
+	assert.True(t, prediction.Achieved)
+	assert.Equal(t, 1.0, prediction.Confidence)
+
+	maintain := &models.WeightGoal{GoalType: models.WeightGoalMaintain, TargetWeight: 75}
+	assert.True(t, predictGoal(maintain, 75.6, 0.1, 0.05, now).Achieved)
+	assert.False(t, predictGoal(maintain, 77, 0.1, 0.05, now).Achieved)
+}
@@ -0,0 +1,988 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Workout program errors returned by FitnessService
+var (
+	ErrWorkoutProgramNotFound = errors.New("workout program not found")
+	ErrInvalidWorkoutProgram  = errors.New("invalid workout program request")
+	ErrNoEligibleExercises    = errors.New("not enough exercises match the available equipment and injuries")
+)
+
+const (
+	defaultProgramWeeks   = 4
+	defaultDaysPerWeek    = 3
+	defaultSessionMinutes = 45
+	defaultBodyWeightKg   = 70.0
+	defaultMETValue       = 5.0
+	warmUpMinutes         = 5
+	coolDownMinutes       = 5
+	workSecondsPerSet     = 40
+	deloadEvery           = 4
+	deloadLoadPercent     = 10.0
+)
+
+// prescription is the set/rep/rest scheme of a training goal and how it
+// progresses from week to week
+type prescription struct {
+	sets            int
+	maxSets         int
+	repsMin         int
+	repsMax         int
+	holdSeconds     int // timed holds instead of reps when set
+	restSeconds     int
+	minRestSeconds  int
+	loadStepPercent float64 // load added each week, relative to week 1
+	setEvery        int     // weeks between added sets
+	repStep         int     // reps (or hold seconds) added each week
+	restStep        int     // rest seconds removed each week
+}
+
+var goalPrescriptions = map[string]prescription{
+	"strength":        {sets: 4, maxSets: 6, repsMin: 4, repsMax: 6, restSeconds: 180, minRestSeconds: 180, loadStepPercent: 2.5, setEvery: 3},
+	"muscle_gain":     {sets: 3, maxSets: 5, repsMin: 8, repsMax: 12, restSeconds: 90, minRestSeconds: 90, loadStepPercent: 2.5, setEvery: 2},
+	"weight_loss":     {sets: 3, maxSets: 4, repsMin: 12, repsMax: 15, restSeconds: 60, minRestSeconds: 30, setEvery: 3, restStep: 10},
+	"endurance":       {sets: 2, maxSets: 4, repsMin: 15, repsMax: 20, restSeconds: 45, minRestSeconds: 30, setEvery: 2, repStep: 2, restStep: 5},
+	"flexibility":     {sets: 2, maxSets: 3, holdSeconds: 30, restSeconds: 15, minRestSeconds: 15, setEvery: 3, repStep: 5},
+	"general_fitness": {sets: 3, maxSets: 4, repsMin: 10, repsMax: 12, restSeconds: 75, minRestSeconds: 60, loadStepPercent: 2.5, setEvery: 3, restStep: 5},
+}
+
+var fitnessLevelRatings = map[string]int{
+	"beginner":     2,
+	"intermediate": 3,
+	"advanced":     4,
+}
+
+// splitDay is one training day of a split and the muscle groups it covers in
+// order of priority
+type splitDay struct {
+	name  string
+	focus []string
+}
+
+var (
+	fullBodyFocus = []string{"legs", "chest", "back", "shoulders", "core", "glutes", "biceps", "triceps"}
+	upperFocus    = []string{"chest", "back", "shoulders", "biceps", "triceps", "core"}
+	lowerFocus    = []string{"quadriceps", "hamstrings", "glutes", "calves", "core"}
+	pushFocus     = []string{"chest", "shoulders", "triceps", "chest"}
+	pullFocus     = []string{"back", "biceps", "back", "core"}
+)
+
+// splitsPerWeek maps training days per week to a split. Days sharing a focus
+// rotate through alternative exercises (A/B/C variants).
+var splitsPerWeek = map[int]struct {
+	programType string
+	label       string
+	days        []splitDay
+}{
+	2: {"full_body", "full body", []splitDay{{"Full Body A", fullBodyFocus}, {"Full Body B", fullBodyFocus}}},
+	3: {"full_body", "full body", []splitDay{{"Full Body A", fullBodyFocus}, {"Full Body B", fullBodyFocus}, {"Full Body C", fullBodyFocus}}},
+	4: {"upper_lower", "upper/lower", []splitDay{{"Upper A", upperFocus}, {"Lower A", lowerFocus}, {"Upper B", upperFocus}, {"Lower B", lowerFocus}}},
+	5: {"push_pull_legs_upper_lower", "push/pull/legs + upper/lower", []splitDay{{"Push", pushFocus}, {"Pull", pullFocus}, {"Legs", lowerFocus}, {"Upper", upperFocus}, {"Lower", lowerFocus}}},
+	6: {"push_pull_legs", "push/pull/legs", []splitDay{{"Push A", pushFocus}, {"Pull A", pullFocus}, {"Legs A", lowerFocus}, {"Push B", pushFocus}, {"Pull B", pullFocus}, {"Legs B", lowerFocus}}},
+}
+
+// muscleGroupAliases maps the muscle groups used by splits and requests to the
+// names found in exercises.muscle_groups (English and Arabic)
+var muscleGroupAliases = map[string][]string{
+	"chest":      {"chest", "pectorals", "pecs", "صدر"},
+	"back":       {"back", "upper back", "lower back", "lats", "latissimus", "rhomboids", "traps", "ظهر"},
+	"shoulders":  {"shoulders", "shoulder", "deltoids", "delts", "أكتاف"},
+	"biceps":     {"biceps", "arms", "ذراعين"},
+	"triceps":    {"triceps", "arms", "ذراعين"},
+	"forearms":   {"forearms", "grip"},
+	"quadriceps": {"quadriceps", "quads", "legs", "أرجل"},
+	"hamstrings": {"hamstrings", "legs", "أرجل"},
+	"glutes":     {"glutes", "gluteus", "hips"},
+	"calves":     {"calves", "legs"},
+	"core":       {"core", "abs", "abdominals", "obliques", "بطن"},
+	"legs":       {"legs", "quadriceps", "quads", "hamstrings", "glutes", "calves", "أرجل"},
+	"full_body":  {"full body", "full_body"},
+}
+
+// bodyPartMuscles maps injured body parts to the muscle groups whose
+// exercises load them
+var bodyPartMuscles = map[string][]string{
+	"knee":       {"quadriceps", "calves"},
+	"ankle":      {"calves"},
+	"hip":        {"glutes"},
+	"lower back": {"back", "hamstrings"},
+	"back":       {"back"},
+	"shoulder":   {"shoulders", "chest"},
+	"elbow":      {"biceps", "triceps"},
+	"wrist":      {"forearms"},
+	"neck":       {"traps"},
+}
+
+var (
+	warmUpCategories   = []string{"cardio", "warm_up", "warm-up", "warmup"}
+	coolDownCategories = []string{"flexibility", "stretching", "mobility", "yoga"}
+)
+
+// programExercise is an exercise candidate with its muscle groups and
+// equipment normalized for matching
+type programExercise struct {
+	ID           string
+	Name         string
+	Category     string
+	Difficulty   string
+	Instructions string
+	MuscleGroups []string
+	Equipment    []string
+	MET          float64
+}
+
+// contraindications collects what a user's injuries and restrictions rule out
+type contraindications struct {
+	muscles map[string]bool
+	terms   []string
+	labels  []string
+}
+
+// GenerateWorkoutPlan builds and stores a multi-week program for userID. The
+// split follows the days per week, exercises come from the exercises table
+// filtered by equipment and by contraindications from the user's active
+// injuries when they consent to health data processing, and sets, reps and rest follow the goal with weekly progressive
+// overload. Every fourth week that is not the last is a deload week.
+func (s *FitnessService) GenerateWorkoutPlan(ctx context.Context, userID string, req models.GenerateWorkoutProgramRequest) (*models.WorkoutProgram, error) {
+	goal := req.Goal
+	if goal == "" {
+		goal = "general_fitness"
+	}
+	scheme, ok := goalPrescriptions[goal]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown goal %q", ErrInvalidWorkoutProgram, goal)
+	}
+	level := req.Difficulty
+	if level == "" {
+		level = "beginner"
+	}
+	rating, ok := fitnessLevelRatings[level]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown difficulty %q", ErrInvalidWorkoutProgram, level)
+	}
+
+	daysPerWeek := req.DaysPerWeek
+	if daysPerWeek == 0 {
+		daysPerWeek = defaultDaysPerWeek
+	}
+	split, ok := splitsPerWeek[daysPerWeek]
+	if !ok {
+		return nil, fmt.Errorf("%w: days_per_week must be between 2 and 6", ErrInvalidWorkoutProgram)
+	}
+	weeks := req.Weeks
+	if weeks == 0 {
+		weeks = defaultProgramWeeks
+	}
+	minutes := req.Duration
+	if minutes == 0 {
+		minutes = defaultSessionMinutes
+	}
+
+	var startDate *string
+	if req.StartDate != "" {
+		if _, err := time.Parse("2006-01-02", req.StartDate); err != nil {
+			return nil, fmt.Errorf("%w: start_date must be formatted as YYYY-MM-DD", ErrInvalidWorkoutProgram)
+		}
+		startDate = &req.StartDate
+	}
+
+	focusGroups := make([]string, 0, len(req.MuscleGroups))
+	for _, group := range req.MuscleGroups {
+		group = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(group)), " ", "_")
+		if _, ok := muscleGroupAliases[group]; !ok {
+			return nil, fmt.Errorf("%w: unknown muscle group %q", ErrInvalidWorkoutProgram, group)
+		}
+		focusGroups = append(focusGroups, group)
+	}
+
+	exercises, err := s.loadProgramExercises(ctx, req.Equipment)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := s.loadContraindications(ctx, userID, req.Restrictions)
+	if err != nil {
+		return nil, err
+	}
+
+	var eligible []*programExercise
+	for _, exercise := range exercises {
+		if !blocked.excludes(exercise) && !(level == "beginner" && exercise.Difficulty == "advanced") {
+			eligible = append(eligible, exercise)
+		}
+	}
+
+	// Size each session for the heaviest week so later weeks still fit
+	peak := scheme.forWeek(weeks, weeks, level)
+	perExercise := float64(peak.sets*(workSecondsPerSet+peak.rest))/60 + 1
+	slots := int(float64(minutes-warmUpMinutes-coolDownMinutes) / perExercise)
+	if slots < 1 {
+		slots = 1
+	}
+
+	templates := make([][]*programExercise, len(split.days))
+	variants := map[string]int{}
+	targeted := map[string]bool{}
+	for i, day := range split.days {
+		key := strings.Join(day.focus, ",")
+		templates[i] = pickSessionExercises(eligible, prioritizeFocus(day.focus, focusGroups, split.programType == "full_body"), goal, level, slots, variants[key], targeted)
+		variants[key]++
+		if len(templates[i]) == 0 {
+			return nil, fmt.Errorf("%w: nothing available for %s", ErrNoEligibleExercises, day.name)
+		}
+	}
+	warmUp := pickAccessoryExercises(eligible, warmUpCategories, 1, nil)
+	coolDown := pickAccessoryExercises(eligible, coolDownCategories, 2, templates)
+
+	bodyWeight := s.userBodyWeight(ctx, userID)
+	now := time.Now().UTC()
+	program := &models.WorkoutProgram{
+		ID:                     uuid.New().String(),
+		UserID:                 &userID,
+		Name:                   fmt.Sprintf("%d-week %s %s program", weeks, split.label, strings.ReplaceAll(goal, "_", " ")),
+		ProgramType:            &split.programType,
+		FitnessLevel:           &level,
+		DurationWeeks:          &weeks,
+		DaysPerWeek:            &daysPerWeek,
+		SessionDurationMinutes: &minutes,
+		TargetGoals:            []string{goal},
+		Contraindications:      blocked.labels,
+		ModificationsAvailable: []string{},
+		CreatedBy:              &userID,
+		DifficultyRating:       &rating,
+		StartDate:              startDate,
+		CreatedAt:              now,
+		UpdatedAt:              now,
+	}
+	description := fmt.Sprintf("%d sessions per week of about %d minutes", daysPerWeek, minutes)
+	program.Description = &description
+
+	equipment := map[string]bool{}
+	var weekCalories int
+	for week := 1; week <= weeks; week++ {
+		plan := scheme.forWeek(week, weeks, level)
+		program.ProgressionPlan = append(program.ProgressionPlan, plan.step(week))
+
+		for day, template := range templates {
+			session := buildWorkoutSession(program.ID, split.days[day], template, warmUp, coolDown, plan, rating, bodyWeight, blocked)
+			session.SessionNumber = len(program.Sessions) + 1
+			session.WeekNumber = week
+			session.DayNumber = day + 1
+			session.Name = fmt.Sprintf("Week %d - %s", week, split.days[day].name)
+			session.CreatedAt = now
+			session.UpdatedAt = now
+			for _, item := range session.EquipmentNeeded {
+				equipment[item] = true
+			}
+			if week == 1 {
+				weekCalories += *session.EstimatedCaloriesBurned
+			}
+			program.Sessions = append(program.Sessions, session)
+		}
+	}
+	calories := int(math.Round(float64(weekCalories) / float64(daysPerWeek)))
+	program.CalorieBurnEstimate = &calories
+	program.EquipmentRequired = sortedKeys(equipment)
+	program.MuscleGroupsTargeted = sortedKeys(targeted)
+
+	if err := s.saveWorkoutProgram(ctx, program); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// GetWorkoutProgram returns one of userID's generated programs with its sessions
+func (s *FitnessService) GetWorkoutProgram(ctx context.Context, userID, id string) (*models.WorkoutProgram, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+workoutProgramColumns+` FROM workout_programs WHERE id = ? AND user_id = ?`, id, userID)
+	program, err := scanWorkoutProgram(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkoutProgramNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workout program: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, workout_program_id, COALESCE(session_number, 0), COALESCE(week_number, 0), COALESCE(day_number, 0),
+		       name, description, COALESCE(warm_up_exercises, '[]'), COALESCE(main_exercises, '[]'),
+		       COALESCE(cool_down_exercises, '[]'), estimated_duration_minutes, estimated_calories_burned,
+		       difficulty_level, COALESCE(equipment_needed, '[]'), instructions, safety_notes, created_at, updated_at
+		FROM workout_sessions
+		WHERE workout_program_id = ?
+		ORDER BY session_number`, program.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workout sessions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			session                   models.WorkoutSession
+			warmUp, main, coolDown    string
+			equipment                 string
+			duration, calories, level sql.NullInt64
+			description, instructions sql.NullString
+			safetyNotes               sql.NullString
+		)
+		err := rows.Scan(&session.ID, &session.WorkoutProgramID, &session.SessionNumber, &session.WeekNumber,
+			&session.DayNumber, &session.Name, &description, &warmUp, &main, &coolDown, &duration, &calories,
+			&level, &equipment, &instructions, &safetyNotes, &session.CreatedAt, &session.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workout session: %w", err)
+		}
+
+		session.Description = nullStringPtr(description)
+		session.Instructions = nullStringPtr(instructions)
+		session.SafetyNotes = nullStringPtr(safetyNotes)
+		session.EstimatedDurationMinutes = nullIntPtr(duration)
+		session.EstimatedCaloriesBurned = nullIntPtr(calories)
+		session.DifficultyLevel = nullIntPtr(level)
+		_ = json.Unmarshal([]byte(warmUp), &session.WarmUpExercises)
+		_ = json.Unmarshal([]byte(main), &session.MainExercises)
+		_ = json.Unmarshal([]byte(coolDown), &session.CoolDownExercises)
+		session.EquipmentNeeded = decodeStringList(equipment)
+		session.Modifications = []models.ExerciseModification{}
+		program.Sessions = append(program.Sessions, session)
+	}
+
+	return program, rows.Err()
+}
+
+// ListWorkoutPrograms returns userID's generated programs, newest first,
+// without their sessions
+func (s *FitnessService) ListWorkoutPrograms(ctx context.Context, userID string) ([]*models.WorkoutProgram, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+workoutProgramColumns+` FROM workout_programs WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workout programs: %w", err)
+	}
+	defer rows.Close()
+
+	programs := []*models.WorkoutProgram{}
+	for rows.Next() {
+		program, err := scanWorkoutProgram(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workout program: %w", err)
+		}
+		programs = append(programs, program)
+	}
+	return programs, rows.Err()
+}
+
+const workoutProgramColumns = `id, user_id, name, description, program_type, fitness_level, duration_weeks, days_per_week,
+	session_duration_minutes, COALESCE(equipment_required, '[]'), COALESCE(target_goals, '[]'),
+	COALESCE(muscle_groups_targeted, '[]'), COALESCE(contraindications, '[]'), COALESCE(modifications_available, '[]'),
+	COALESCE(progression_plan, '[]'), created_by, difficulty_rating, calorie_burn_estimate, start_date,
+	created_at, updated_at`
+
+func scanWorkoutProgram(row rowScanner) (*models.WorkoutProgram, error) {
+	var (
+		program                                 models.WorkoutProgram
+		userID, description, programType        sql.NullString
+		fitnessLevel, createdBy, startDate      sql.NullString
+		weeks, daysPerWeek, minutes             sql.NullInt64
+		rating, calories                        sql.NullInt64
+		equipment, goals, muscles, contra, mods string
+		progression                             string
+	)
+	err := row.Scan(&program.ID, &userID, &program.Name, &description, &programType, &fitnessLevel, &weeks,
+		&daysPerWeek, &minutes, &equipment, &goals, &muscles, &contra, &mods, &progression, &createdBy, &rating,
+		&calories, &startDate, &program.CreatedAt, &program.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	program.UserID = nullStringPtr(userID)
+	program.Description = nullStringPtr(description)
+	program.ProgramType = nullStringPtr(programType)
+	program.FitnessLevel = nullStringPtr(fitnessLevel)
+	program.CreatedBy = nullStringPtr(createdBy)
+	program.StartDate = nullStringPtr(startDate)
+	program.DurationWeeks = nullIntPtr(weeks)
+	program.DaysPerWeek = nullIntPtr(daysPerWeek)
+	program.SessionDurationMinutes = nullIntPtr(minutes)
+	program.DifficultyRating = nullIntPtr(rating)
+	program.CalorieBurnEstimate = nullIntPtr(calories)
+	program.EquipmentRequired = decodeStringList(equipment)
+	program.TargetGoals = decodeStringList(goals)
+	program.MuscleGroupsTargeted = decodeStringList(muscles)
+	program.Contraindications = decodeStringList(contra)
+	program.ModificationsAvailable = decodeStringList(mods)
+	if err := json.Unmarshal([]byte(progression), &program.ProgressionPlan); err != nil || program.ProgressionPlan == nil {
+		program.ProgressionPlan = []models.ProgressionStep{}
+	}
+	return &program, nil
+}
+
+func (s *FitnessService) saveWorkoutProgram(ctx context.Context, program *models.WorkoutProgram) error {
+	encode := func(value interface{}) string {
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO workout_programs (id, user_id, name, description, program_type, fitness_level, duration_weeks,
+			days_per_week, session_duration_minutes, equipment_required, target_goals, muscle_groups_targeted,
+			contraindications, modifications_available, progression_plan, created_by, difficulty_rating,
+			calorie_burn_estimate, start_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		program.ID, program.UserID, program.Name, program.Description, program.ProgramType, program.FitnessLevel,
+		program.DurationWeeks, program.DaysPerWeek, program.SessionDurationMinutes, encode(program.EquipmentRequired),
+		encode(program.TargetGoals), encode(program.MuscleGroupsTargeted), encode(program.Contraindications),
+		encode(program.ModificationsAvailable), encode(program.ProgressionPlan), program.CreatedBy,
+		program.DifficultyRating, program.CalorieBurnEstimate, program.StartDate, program.CreatedAt, program.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create workout program: %w", err)
+	}
+
+	for _, session := range program.Sessions {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO workout_sessions (id, workout_program_id, session_number, week_number, day_number, name,
+				description, warm_up_exercises, main_exercises, cool_down_exercises, estimated_duration_minutes,
+				estimated_calories_burned, difficulty_level, equipment_needed, instructions, safety_notes,
+				modifications, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			session.ID, program.ID, session.SessionNumber, session.WeekNumber, session.DayNumber, session.Name,
+			session.Description, encode(session.WarmUpExercises), encode(session.MainExercises),
+			encode(session.CoolDownExercises), session.EstimatedDurationMinutes, session.EstimatedCaloriesBurned,
+			session.DifficultyLevel, encode(session.EquipmentNeeded), session.Instructions, session.SafetyNotes,
+			encode(session.Modifications), session.CreatedAt, session.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create workout session: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// weekPrescription is a goal's prescription resolved for one week
+type weekPrescription struct {
+	sets        int
+	repsMin     int
+	repsMax     int
+	holdSeconds int
+	rest        int
+	loadPercent float64 // relative to week 1, negative on deload weeks
+	extraReps   int     // replaces added load on bodyweight exercises
+	deload      bool
+}
+
+// forWeek resolves the prescription for week of a program lasting weeks.
+// Progress pauses on deload weeks, which drop a set and reduce the load.
+func (p prescription) forWeek(week, weeks int, level string) weekPrescription {
+	sets, maxSets := p.sets, p.maxSets
+	switch level {
+	case "beginner":
+		sets, maxSets = sets-1, maxSets-1
+	case "advanced":
+		sets++
+	}
+	if sets < 2 {
+		sets = 2
+	}
+
+	if week%deloadEvery == 0 && week < weeks {
+		plan := weekPrescription{
+			sets:        sets - 1,
+			repsMin:     p.repsMin,
+			repsMax:     p.repsMax,
+			holdSeconds: p.holdSeconds,
+			rest:        p.restSeconds,
+			deload:      true,
+		}
+		if p.loadStepPercent > 0 {
+			plan.loadPercent = -deloadLoadPercent
+		}
+		return plan
+	}
+
+	step := week - 1 - (week-1)/deloadEvery
+	if p.setEvery > 0 {
+		sets += step / p.setEvery
+	}
+	if sets > maxSets {
+		sets = maxSets
+	}
+	rest := p.restSeconds - step*p.restStep
+	if rest < p.minRestSeconds {
+		rest = p.minRestSeconds
+	}
+	plan := weekPrescription{
+		sets:        sets,
+		repsMin:     p.repsMin + step*p.repStep,
+		repsMax:     p.repsMax + step*p.repStep,
+		rest:        rest,
+		loadPercent: float64(step) * p.loadStepPercent,
+	}
+	if p.holdSeconds > 0 {
+		plan.holdSeconds = p.holdSeconds + step*p.repStep
+	}
+	if p.loadStepPercent > 0 {
+		plan.extraReps = step
+	}
+	return plan
+}
+
+// step describes the week for the program's progression plan
+func (p weekPrescription) step(week int) models.ProgressionStep {
+	description := "Build week"
+	switch {
+	case p.deload:
+		description = "Deload week: reduced volume and load to recover"
+	case week == 1:
+		description = "Baseline week: learn the movements and find working weights"
+	}
+
+	changes := fmt.Sprintf("%d sets x %s, %ds rest", p.sets, p.reps(false), p.rest)
+	if p.loadPercent != 0 {
+		changes += fmt.Sprintf(", %+g%% load vs week 1", p.loadPercent)
+	}
+	return models.ProgressionStep{Week: week, Description: description, Changes: changes}
+}
+
+func (p weekPrescription) reps(bodyweight bool) string {
+	if p.holdSeconds > 0 {
+		return fmt.Sprintf("%d seconds", p.holdSeconds)
+	}
+	if bodyweight {
+		return fmt.Sprintf("%d-%d", p.repsMin+p.extraReps, p.repsMax+p.extraReps)
+	}
+	return fmt.Sprintf("%d-%d", p.repsMin, p.repsMax)
+}
+
+func (p weekPrescription) exercise(exercise *programExercise) models.SessionExercise {
+	bodyweight := len(exercise.Equipment) == 0
+	item := models.SessionExercise{
+		ExerciseID:      exercise.ID,
+		ExerciseName:    exercise.Name,
+		Sets:            p.sets,
+		Reps:            p.reps(bodyweight),
+		RestSeconds:     p.rest,
+		Instructions:    exercise.Instructions,
+		TargetMuscles:   exercise.MuscleGroups,
+		Equipment:       exercise.Equipment,
+		DifficultyLevel: difficultyRating(exercise.Difficulty),
+	}
+	if !bodyweight && p.loadPercent != 0 {
+		weight := fmt.Sprintf("%+g%% load vs week 1", p.loadPercent)
+		item.Weight = &weight
+	}
+	return item
+}
+
+func buildWorkoutSession(programID string, day splitDay, template, warmUp, coolDown []*programExercise, plan weekPrescription, rating int, bodyWeight float64, blocked contraindications) models.WorkoutSession {
+	session := models.WorkoutSession{
+		ID:                uuid.New().String(),
+		WorkoutProgramID:  programID,
+		WarmUpExercises:   []models.SessionExercise{},
+		MainExercises:     []models.SessionExercise{},
+		CoolDownExercises: []models.SessionExercise{},
+		Modifications:     []models.ExerciseModification{},
+		DifficultyLevel:   &rating,
+	}
+
+	var met float64
+	equipment := map[string]bool{}
+	seconds := (warmUpMinutes + coolDownMinutes) * 60
+	for _, exercise := range warmUp {
+		session.WarmUpExercises = append(session.WarmUpExercises, models.SessionExercise{
+			ExerciseID:    exercise.ID,
+			ExerciseName:  exercise.Name,
+			Sets:          1,
+			Reps:          fmt.Sprintf("%d minutes", warmUpMinutes),
+			Instructions:  exercise.Instructions,
+			TargetMuscles: exercise.MuscleGroups,
+			Equipment:     exercise.Equipment,
+		})
+	}
+	for _, exercise := range template {
+		item := plan.exercise(exercise)
+		session.MainExercises = append(session.MainExercises, item)
+		seconds += item.Sets*(workSecondsPerSet+item.RestSeconds) + 60
+		met += exercise.met()
+		for _, name := range exercise.Equipment {
+			equipment[name] = true
+		}
+	}
+	for _, exercise := range coolDown {
+		session.CoolDownExercises = append(session.CoolDownExercises, models.SessionExercise{
+			ExerciseID:    exercise.ID,
+			ExerciseName:  exercise.Name,
+			Sets:          1,
+			Reps:          "30 seconds",
+			Instructions:  exercise.Instructions,
+			TargetMuscles: exercise.MuscleGroups,
+			Equipment:     exercise.Equipment,
+		})
+	}
+
+	minutes := int(math.Ceil(float64(seconds) / 60))
+	calories := int(math.Round(met / float64(len(template)) * bodyWeight * float64(minutes) / 60))
+	session.EstimatedDurationMinutes = &minutes
+	session.EstimatedCaloriesBurned = &calories
+	session.EquipmentNeeded = sortedKeys(equipment)
+
+	description := fmt.Sprintf("%s session targeting %s", day.name, strings.Join(uniqueStrings(day.focus), ", "))
+	if plan.deload {
+		description += " (deload)"
+	}
+	instructions := fmt.Sprintf("Warm up for %d minutes, then perform each exercise for the listed sets with %d seconds rest between sets.", warmUpMinutes, plan.rest)
+	safetyNotes := "Stop any exercise that causes sharp pain."
+	if len(blocked.labels) > 0 {
+		safetyNotes = "Exercises affecting " + strings.Join(blocked.labels, ", ") + " were left out. " + safetyNotes
+	}
+	session.Description = &description
+	session.Instructions = &instructions
+	session.SafetyNotes = &safetyNotes
+	return session
+}
+
+// pickSessionExercises fills up to slots focus groups with one exercise each.
+// Exercises that train the group as their primary muscle come first, compound
+// movements are preferred for resistance goals and variant rotates through the
+// ranked alternatives so A/B days differ.
+func pickSessionExercises(exercises []*programExercise, focus []string, goal, level string, slots, variant int, targeted map[string]bool) []*programExercise {
+	var picked []*programExercise
+	used := map[string]bool{}
+	for _, group := range focus {
+		if len(picked) == slots {
+			break
+		}
+
+		var candidates []*programExercise
+		for _, exercise := range exercises {
+			if used[exercise.ID] || !exercise.trains(group) || !exercise.suitsGoal(goal) {
+				continue
+			}
+			candidates = append(candidates, exercise)
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if pa, pb := a.primarily(group), b.primarily(group); pa != pb {
+				return pa
+			}
+			if goal != "flexibility" && len(a.MuscleGroups) != len(b.MuscleGroups) {
+				return len(a.MuscleGroups) > len(b.MuscleGroups)
+			}
+			if da, db := a.levelDistance(level), b.levelDistance(level); da != db {
+				return da < db
+			}
+			return a.Name < b.Name
+		})
+
+		exercise := candidates[variant%len(candidates)]
+		used[exercise.ID] = true
+		targeted[group] = true
+		picked = append(picked, exercise)
+	}
+	return picked
+}
+
+// pickAccessoryExercises returns up to limit exercises from categories that
+// are not already part of a session template
+func pickAccessoryExercises(exercises []*programExercise, categories []string, limit int, templates [][]*programExercise) []*programExercise {
+	inUse := map[string]bool{}
+	for _, template := range templates {
+		for _, exercise := range template {
+			inUse[exercise.ID] = true
+		}
+	}
+
+	picked := []*programExercise{}
+	for _, exercise := range exercises {
+		if len(picked) == limit {
+			break
+		}
+		if !inUse[exercise.ID] && containsFold(categories, []string{exercise.Category}) {
+			picked = append(picked, exercise)
+		}
+	}
+	return picked
+}
+
+// prioritizeFocus moves the requested muscle groups to the front of a split
+// day. Full-body days also gain requested groups they do not cover.
+func prioritizeFocus(focus, requested []string, fullBody bool) []string {
+	if len(requested) == 0 {
+		return focus
+	}
+
+	ordered := make([]string, 0, len(focus)+len(requested))
+	for _, group := range focus {
+		if containsFold(requested, []string{group}) {
+			ordered = append(ordered, group)
+		}
+	}
+	for _, group := range requested {
+		if fullBody && !containsFold(ordered, []string{group}) {
+			ordered = append(ordered, group)
+		}
+	}
+	for _, group := range focus {
+		if !containsFold(requested, []string{group}) {
+			ordered = append(ordered, group)
+		}
+	}
+	return ordered
+}
+
+// loadProgramExercises returns the exercises that need no equipment beyond
+// what the user has, normalized for matching
+func (s *FitnessService) loadProgramExercises(ctx context.Context, equipment []string) ([]*programExercise, error) {
+	found, err := s.exerciseRepo.GetExercisesByMuscleGroupsAndEquipment(ctx, nil, equipment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exercises: %w", err)
+	}
+
+	exercises := make([]*programExercise, 0, len(found))
+	for _, e := range found {
+		exercise := &programExercise{
+			ID:           e.ID,
+			Name:         e.Name,
+			Category:     strings.ToLower(strings.TrimSpace(stringValue(e.Category))),
+			Difficulty:   strings.ToLower(strings.TrimSpace(stringValue(e.DifficultyLevel))),
+			Instructions: stringValue(e.Instructions),
+			Equipment:    repositories.ParseEquipment(stringValue(e.Equipment)),
+		}
+		for _, group := range e.MuscleGroups {
+			exercise.MuscleGroups = append(exercise.MuscleGroups, strings.ToLower(strings.TrimSpace(group)))
+		}
+		if e.METValue != nil {
+			exercise.MET = *e.METValue
+		}
+		exercises = append(exercises, exercise)
+	}
+	return exercises, nil
+}
+
+// loadContraindications gathers the body parts, exercise limitations and
+// injury restrictions of userID's injuries that still affect exercise, plus the
+// restrictions given with the request. Injuries are health data and are only
+// read while the user consents to its processing.
+func (s *FitnessService) loadContraindications(ctx context.Context, userID string, restrictions []string) (contraindications, error) {
+	blocked := contraindications{muscles: map[string]bool{}}
+	labels := map[string]bool{}
+	addTerm := func(term string) {
+		term = matchText(term)
+		if term == "" {
+			return
+		}
+		if muscles, ok := bodyPartMuscles[term]; ok {
+			blocked.blockMuscles(muscles)
+		}
+		blocked.terms = append(blocked.terms, term)
+		labels[term] = true
+	}
+
+	consented := false
+	if s.gdpr != nil {
+		var err error
+		consented, err = s.gdpr.HasConsent(ctx, userID, models.ConsentHealthData)
+		if err != nil {
+			return blocked, err
+		}
+	}
+	if consented {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT COALESCE(i.body_part, ''), COALESCE(ui.exercise_limitations, '[]'), COALESCE(i.exercise_restrictions, '[]')
+			FROM user_injuries ui
+			LEFT JOIN injuries i ON i.id = ui.injury_id
+			WHERE ui.user_id = ? AND COALESCE(ui.affects_exercise, 1) = 1
+			  AND COALESCE(ui.current_status, 'healing') NOT IN ('recovered', 'healed', 'resolved')`, userID)
+		if err != nil {
+			return blocked, fmt.Errorf("failed to load injuries: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var bodyPart, limitations, injuryRestrictions string
+			if err := rows.Scan(&bodyPart, &limitations, &injuryRestrictions); err != nil {
+				return blocked, fmt.Errorf("failed to scan injury: %w", err)
+			}
+
+			bodyPart = strings.ToLower(strings.TrimSpace(bodyPart))
+			for part, muscles := range bodyPartMuscles {
+				if bodyPart != "" && strings.Contains(bodyPart, part) {
+					blocked.blockMuscles(muscles)
+					labels[bodyPart] = true
+				}
+			}
+			for _, term := range append(decodeStringList(limitations), decodeStringList(injuryRestrictions)...) {
+				addTerm(term)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return blocked, err
+		}
+	}
+
+	for _, term := range restrictions {
+		addTerm(term)
+	}
+	blocked.labels = sortedKeys(labels)
+	return blocked, nil
+}
+
+func (c contraindications) blockMuscles(groups []string) {
+	for _, group := range groups {
+		c.muscles[group] = true
+		for _, alias := range muscleGroupAliases[group] {
+			c.muscles[alias] = true
+		}
+	}
+}
+
+// excludes reports whether an exercise loads an injured muscle group or its
+// name, category or muscle groups match a restriction
+func (c contraindications) excludes(exercise *programExercise) bool {
+	for _, group := range exercise.MuscleGroups {
+		if c.muscles[group] {
+			return true
+		}
+	}
+
+	name := matchText(exercise.Name)
+	for _, term := range c.terms {
+		if strings.Contains(name, term) || strings.Contains(term, name) || term == exercise.Category {
+			return true
+		}
+		for _, group := range exercise.MuscleGroups {
+			if term == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *programExercise) trains(group string) bool {
+	return containsFold(muscleGroupAliases[group], e.MuscleGroups)
+}
+
+func (e *programExercise) primarily(group string) bool {
+	return len(e.MuscleGroups) > 0 && containsFold(muscleGroupAliases[group], e.MuscleGroups[:1])
+}
+
+func (e *programExercise) suitsGoal(goal string) bool {
+	stretch := containsFold(coolDownCategories, []string{e.Category})
+	if goal == "flexibility" {
+		return stretch
+	}
+	if containsFold(warmUpCategories, []string{e.Category}) {
+		return goal == "weight_loss" || goal == "endurance"
+	}
+	return !stretch
+}
+
+func (e *programExercise) levelDistance(level string) int {
+	distance := difficultyRating(e.Difficulty) - fitnessLevelRatings[level]
+	if distance < 0 {
+		return -distance
+	}
+	return distance
+}
+
+func (e *programExercise) met() float64 {
+	if e.MET > 0 {
+		return e.MET
+	}
+	return defaultMETValue
+}
+
+func (s *FitnessService) userBodyWeight(ctx context.Context, userID string) float64 {
+	var weight sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `SELECT weight FROM users WHERE id = ?`, userID).Scan(&weight)
+	if err != nil || !weight.Valid || weight.Float64 <= 0 {
+		return defaultBodyWeightKg
+	}
+	return weight.Float64
+}
+
+// matchText lowercases s and treats underscores and hyphens as spaces so
+// "push_up" matches "Push-up"
+func matchText(s string) string {
+	return strings.ToLower(strings.TrimSpace(strings.NewReplacer("_", " ", "-", " ").Replace(s)))
+}
+
+func difficultyRating(difficulty string) int {
+	if rating, ok := fitnessLevelRatings[difficulty]; ok {
+		return rating
+	}
+	return fitnessLevelRatings["intermediate"]
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func decodeStringList(raw string) []string {
+	var values []string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil || values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"nutrition-platform/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFitnessService(t *testing.T) (*FitnessService, string) {
+	t.Helper()
+	users := newTestUserService(t)
+	user, err := users.CreateUser(context.Background(), CreateUserInput{Email: "lifter@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = users.db.Exec(`INSERT INTO exercises (id, name, category, muscle_groups, equipment, difficulty_level, met_value) VALUES
+		('db-press', 'Dumbbell Bench Press', 'strength', '["chest", "triceps", "shoulders"]', 'dumbbells', 'intermediate', 6),
+		('push-up', 'Push-up', 'strength', '["chest", "triceps"]', 'none', 'beginner', 8),
+		('db-row', 'Dumbbell Row', 'strength', '["back", "biceps"]', 'dumbbells', 'beginner', 6),
+		('pull-up', 'Pull-up', 'strength', '["back", "biceps"]', 'pull-up bar', 'intermediate', 8),
+		('db-ohp', 'Dumbbell Shoulder Press', 'strength', '["shoulders", "triceps"]', '["dumbbells"]', 'intermediate', 6),
+		('db-curl', 'Dumbbell Curl', 'strength', '["biceps"]', 'dumbbells', 'beginner', 4),
+		('bench-dip', 'Bench Dip', 'strength', '["triceps"]', 'none', 'beginner', 5),
+		('back-squat', 'Barbell Back Squat', 'strength', '["quadriceps", "glutes"]', 'barbell', 'advanced', 6),
+		('goblet-squat', 'Goblet Squat', 'strength', '["quadriceps", "glutes"]', 'dumbbells', 'beginner', 6),
+		('rdl', 'Romanian Deadlift', 'strength', '["hamstrings", "glutes", "lower back"]', 'dumbbells', 'intermediate', 6),
+		('bridge', 'Glute Bridge', 'strength', '["glutes"]', 'mat', 'beginner', 4),
+		('calf-raise', 'Calf Raise', 'strength', '["calves"]', 'none', 'beginner', 4),
+		('plank', 'Plank', 'core', '["core"]', 'none', 'beginner', 4),
+		('jacks', 'Jumping Jacks', 'cardio', '["full body"]', 'none', 'beginner', 8),
+		('arm-circles', 'Arm Circles', 'warm_up', '["shoulders"]', 'none', 'beginner', 3),
+		('hamstring-stretch', 'Hamstring Stretch', 'flexibility', '["hamstrings"]', 'none', 'beginner', 2)`)
+	require.NoError(t, err)
+
+	return NewFitnessService(users.db), user.ID
+}
+
+func mainExerciseNames(session models.WorkoutSession) []string {
+	names := []string{}
+	for _, exercise := range session.MainExercises {
+		names = append(names, exercise.ExerciseName)
+	}
+	return names
+}
+
+func TestFitnessService_GenerateWorkoutPlan(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestFitnessService(t)
+
+	_, err := svc.db.Exec(`INSERT INTO injuries (id, name, body_part, exercise_restrictions)
+		VALUES ('knee-sprain', 'Knee sprain', 'knee', '["lunges"]'), ('shoulder-strain', 'Shoulder strain', 'shoulder', '[]')`)
+	require.NoError(t, err)
+	_, err = svc.db.Exec(`INSERT INTO user_injuries (user_id, injury_id, exercise_limitations, current_status)
+		VALUES (?, 'knee-sprain', '["jump"]', 'healing'), (?, 'shoulder-strain', '[]', 'recovered')`, userID, userID)
+	require.NoError(t, err)
+	gdpr := NewGDPRCompliance(svc.db, nil)
+	svc.SetConsent(gdpr)
+	_, err = gdpr.GrantConsent(ctx, userID, models.ConsentHealthData, SessionClient{})
+	require.NoError(t, err)
+
+	program, err := svc.GenerateWorkoutPlan(ctx, userID, models.GenerateWorkoutProgramRequest{
+		Goal:        "muscle_gain",
+		Difficulty:  "intermediate",
+		DaysPerWeek: 4,
+		Weeks:       5,
+		Duration:    60,
+		Equipment:   []string{"Dumbbells"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "upper_lower", *program.ProgramType)
+	assert.Equal(t, []string{"jump", "knee", "lunges"}, program.Contraindications)
+	assert.Equal(t, []string{"dumbbell"}, program.EquipmentRequired)
+	require.Len(t, program.Sessions, 20)
+	require.Len(t, program.ProgressionPlan, 5)
+	assert.Contains(t, program.ProgressionPlan[3].Description, "Deload")
+
+	for _, session := range program.Sessions {
+		require.NotEmpty(t, session.MainExercises, session.Name)
+		// Barbell and pull-up bar are unavailable; squats and calf raises load the injured knee
+		for _, name := range mainExerciseNames(session) {
+			assert.NotContains(t, []string{"Barbell Back Squat", "Pull-up", "Goblet Squat", "Calf Raise", "Jumping Jacks"}, name)
+		}
+	}
+
+	upperA := program.Sessions[0]
+	assert.Equal(t, "Week 1 - Upper A", upperA.Name)
+	assert.Equal(t, []string{"Dumbbell Bench Press", "Dumbbell Row", "Dumbbell Shoulder Press", "Dumbbell Curl", "Bench Dip"}, mainExerciseNames(upperA))
+	require.Len(t, upperA.WarmUpExercises, 1)
+	assert.Equal(t, "Arm Circles", upperA.WarmUpExercises[0].ExerciseName)
+	require.Len(t, upperA.CoolDownExercises, 1)
+	assert.Equal(t, "Hamstring Stretch", upperA.CoolDownExercises[0].ExerciseName)
+	assert.Equal(t, []string{"Romanian Deadlift", "Glute Bridge", "Plank"}, mainExerciseNames(program.Sessions[1]))
+	assert.Equal(t, "Push-up", program.Sessions[2].MainExercises[0].ExerciseName)
+
+	// Progressive overload on the week's first exercise: load every week, a
+	// set every second week, and a lighter fourth week
+	cases := []struct {
+		week   int
+		sets   int
+		weight string
+	}{
+		{2, 3, "+2.5% load vs week 1"},
+		{3, 4, "+5% load vs week 1"},
+		{4, 2, "-10% load vs week 1"},
+		{5, 4, "+7.5% load vs week 1"},
+	}
+	for _, tc := range cases {
+		bench := program.Sessions[(tc.week-1)*4].MainExercises[0]
+		assert.Equal(t, tc.sets, bench.Sets, "week %d", tc.week)
+		assert.Equal(t, "8-12", bench.Reps, "week %d", tc.week)
+		require.NotNil(t, bench.Weight, "week %d", tc.week)
+		assert.Equal(t, tc.weight, *bench.Weight, "week %d", tc.week)
+	}
+	assert.Nil(t, program.Sessions[2].MainExercises[0].Weight)
+	assert.Equal(t, "9-13", program.Sessions[6].MainExercises[0].Reps) // push-ups add reps instead of load
+
+	stored, err := svc.GetWorkoutProgram(ctx, userID, program.ID)
+	require.NoError(t, err)
+	assert.Equal(t, program.ProgressionPlan, stored.ProgressionPlan)
+	require.Len(t, stored.Sessions, 20)
+	for i, session := range stored.Sessions {
+		assert.Equal(t, program.Sessions[i].Name, session.Name)
+		assert.Equal(t, program.Sessions[i].MainExercises, session.MainExercises)
+		assert.Equal(t, program.Sessions[i].WeekNumber, session.WeekNumber)
+	}
+
+	programs, err := svc.ListWorkoutPrograms(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, programs, 1)
+	assert.Empty(t, programs[0].Sessions)
+
+	_, err = svc.GetWorkoutProgram(ctx, "someone-else", program.ID)
+	assert.ErrorIs(t, err, ErrWorkoutProgramNotFound)
+}
+
+func TestFitnessService_GenerateWorkoutPlanNeedsConsentForInjuries(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestFitnessService(t)
+	gdpr := NewGDPRCompliance(svc.db, nil)
+	svc.SetConsent(gdpr)
+
+	_, err := svc.db.Exec(`INSERT INTO injuries (id, name, body_part) VALUES ('knee-sprain', 'Knee sprain', 'knee')`)
+	require.NoError(t, err)
+	_, err = svc.db.Exec(`INSERT INTO user_injuries (user_id, injury_id, current_status) VALUES (?, 'knee-sprain', 'healing')`, userID)
+	require.NoError(t, err)
+	req := models.GenerateWorkoutProgramRequest{Goal: "general_fitness", DaysPerWeek: 2, Weeks: 1, Restrictions: []string{"jump"}}
+
+	// Without consent the program is still generated, ignoring the injuries
+	program, err := svc.GenerateWorkoutPlan(ctx, userID, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jump"}, program.Contraindications)
+
+	_, err = gdpr.GrantConsent(ctx, userID, models.ConsentHealthData, SessionClient{})
+	require.NoError(t, err)
+	program, err = svc.GenerateWorkoutPlan(ctx, userID, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jump", "knee"}, program.Contraindications)
+
+	_, err = gdpr.WithdrawConsent(ctx, userID, models.ConsentHealthData, SessionClient{})
+	require.NoError(t, err)
+	program, err = svc.GenerateWorkoutPlan(ctx, userID, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jump"}, program.Contraindications)
+}
+
+func TestFitnessService_GenerateWorkoutPlanErrors(t *testing.T) {
+	ctx := context.Background()
+	svc, userID := newTestFitnessService(t)
+
+	program, err := svc.GenerateWorkoutPlan(ctx, userID, models.GenerateWorkoutProgramRequest{
+		Restrictions: []string{"push_up"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "full_body", *program.ProgramType)
+	assert.Empty(t, program.EquipmentRequired)
+	require.Len(t, program.Sessions, 12)
+	for _, session := range program.Sessions {
+		assert.NotContains(t, mainExerciseNames(session), "Push-up")
+		assert.Empty(t, session.EquipmentNeeded)
+	}
+
+	_, err = svc.GenerateWorkoutPlan(ctx, userID, models.GenerateWorkoutProgramRequest{MuscleGroups: []string{"wings"}})
+	assert.ErrorIs(t, err, ErrInvalidWorkoutProgram)
+
+	_, err = svc.db.Exec(`DELETE FROM exercises`)
+	require.NoError(t, err)
+	_, err = svc.GenerateWorkoutPlan(ctx, userID, models.GenerateWorkoutProgramRequest{})
+	assert.ErrorIs(t, err, ErrNoEligibleExercises)
+}
@@ -37,8 +37,8 @@ func (suite *ActionsTestSuite) SetupSuite() {
 
 	// Initialize handlers
 	suite.progressHandler = handlers.NewProgressActionsHandler(db)
-	suite.nutritionHandler = handlers.NewNutritionActionsHandler(db)
-	suite.fitnessHandler = handlers.NewFitnessActionsHandler(db)
+	suite.nutritionHandler = handlers.NewNutritionActionsHandler(db, nil, nil)
+	suite.fitnessHandler = handlers.NewFitnessActionsHandler(db, nil)
 
 	// Create test user and get token
 	suite.testUserID = 1